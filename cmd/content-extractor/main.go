@@ -2,20 +2,43 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/google"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
 	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/tlsconfig"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
 	"go.uber.org/zap"
 )
 
+var (
+	processorRegistry *processors.Registry
+	limits            processors.Limits
+)
+
 func main() {
+	// Sandboxed extractions run in a copy of this process
+	if processors.SandboxRequested() {
+		os.Exit(processors.RunSandbox())
+	}
+	if apispec.Requested() {
+		if err := apiSpec.Write(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
@@ -32,6 +55,22 @@ func main() {
 		zap.String("version", "1.0.0"),
 		zap.Int("port", 8082))
 
+	processorRegistry, err = processors.NewDefaultRegistry(context.Background(), logger.Log, cfg)
+	if err != nil {
+		logger.Fatal("Failed to create content processors", zap.Error(err))
+	}
+	defer processorRegistry.Close(context.Background()) //nolint:errcheck
+	// Images are OCRed on request when a vision backend is configured
+	if cfg.Google.VisionAPIKey != "" || cfg.Google.ApplicationCredentials != "" || cfg.Azure.OpenAIVisionDeployment != "" {
+		visionClient, err := google.NewVisionClient(cfg, logger.Log)
+		if err != nil {
+			logger.Warn("Failed to create Vision client, images will not be OCRed", zap.Error(err))
+		} else {
+			processorRegistry.SetTextDetector(visionClient)
+		}
+	}
+	limits = processors.LimitsFromConfig(cfg.Extraction)
+
 	router := gin.Default()
 
 	router.GET("/health", func(c *gin.Context) {
@@ -42,12 +81,16 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"ready": true})
 	})
 
+	router.GET("/openapi.json", apiSpec.Serve())
 	v1 := router.Group("/api/v1")
 	{
-		v1.POST("/extract", extractContent)
-		v1.GET("/formats", getSupportedFormats)
+		apiSpec.Register(v1)
 	}
 
+	certs, err := tlsconfig.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to load TLS certificates", zap.Error(err))
+	}
 	srv := &http.Server{
 		Addr:         ":8082",
 		Handler:      router,
@@ -57,7 +100,7 @@ func main() {
 
 	go func() {
 		logger.Info("Content Extractor service starting", zap.String("address", srv.Addr))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := tlsconfig.ListenAndServe(srv, certs); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
@@ -77,12 +120,27 @@ func main() {
 	logger.Info("Server exited")
 }
 
+// extractRequest is the request body of the extract endpoint
+type extractRequest struct {
+	FilePath string `json:"file_path" binding:"required"`
+	FileType string `json:"file_type" binding:"required"`
+	MimeType string `json:"mime_type,omitempty" description:"Detected MIME type, routing files whose type no processor handles to the processor of that MIME type"`
+	// Options must all be honoured by the file's processor, as listed in
+	// its extract_options on /formats
+	Options processors.ExtractOptions `json:"options"`
+}
+
+// extractResponse is the response of the extract endpoint
+type extractResponse struct {
+	Content     string    `json:"content"`
+	Size        int64     `json:"size" description:"Size of the extracted content in bytes"`
+	Truncated   bool      `json:"truncated" description:"Content was cut at EXTRACTION_MAX_IN_MEMORY bytes"`
+	Encoding    string    `json:"encoding,omitempty" description:"Encoding the content was decoded from when normalized"`
+	ExtractedAt time.Time `json:"extracted_at"`
+}
+
 func extractContent(c *gin.Context) {
-	var req struct {
-		FilePath string                 `json:"file_path" binding:"required"`
-		FileType string                 `json:"file_type" binding:"required"`
-		Options  map[string]interface{} `json:"options"`
-	}
+	var req extractRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -93,33 +151,71 @@ func extractContent(c *gin.Context) {
 		zap.String("file_path", req.FilePath),
 		zap.String("file_type", req.FileType))
 
-	// TODO: Implement content extraction logic
-	c.JSON(http.StatusOK, gin.H{
-		"content":      "Extracted content placeholder",
-		"extracted_at": time.Now(),
+	fileType := "." + strings.TrimPrefix(strings.ToLower(req.FileType), ".")
+	processor, fileType, ok := processorRegistry.Find(fileType, req.MimeType)
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("unsupported file type: %s", req.FileType)})
+		return
+	}
+
+	if err := processors.CheckOptions(processor, fileType, req.Options); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filePath := utils.LocalPath(utils.NormalizePath(req.FilePath))
+	ctx := processors.WithFileType(c.Request.Context(), fileType)
+	ctx = processors.WithOptions(ctx, req.Options)
+	content, err := processors.ExtractFile(ctx, processor, filePath, limits)
+	if err != nil {
+		var tooLarge *processors.FileTooLargeError
+		var limited *processors.LimitExceededError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": err.Error(),
+				"size":  tooLarge.Size,
+				"limit": tooLarge.Limit,
+			})
+		case errors.As(err, &limited):
+			logger.Warn("Extraction stopped at a limit", zap.String("file_path", req.FilePath), zap.Error(err))
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, os.ErrNotExist):
+			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		default:
+			logger.Error("Failed to extract content", zap.String("file_path", req.FilePath), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to extract content: %v", err)})
+		}
+		return
+	}
+	defer content.Close() //nolint:errcheck
+
+	// Responses carry at most the in-memory threshold of content
+	text, err := content.Prefix(int(limits.MaxInMemory))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, extractResponse{
+		Content:     text,
+		Size:        content.Size(),
+		Truncated:   int64(len(text)) < content.Size(),
+		Encoding:    content.Encoding(),
+		ExtractedAt: time.Now(),
 	})
 }
 
-func getSupportedFormats(c *gin.Context) {
-	// TODO: Return actual supported formats from processors
-	formats := []map[string]interface{}{
-		{
-			"category":   "image",
-			"extensions": []string{"png", "jpg", "jpeg", "svg", "gif"},
-		},
-		{
-			"category":   "document",
-			"extensions": []string{"pdf", "docx", "pptx", "txt", "md"},
-		},
-		{
-			"category":   "spreadsheet",
-			"extensions": []string{"xlsx", "xls", "csv"},
-		},
-		{
-			"category":   "code",
-			"extensions": []string{"go", "py", "js", "ts", "java"},
-		},
-	}
+// formatsResponse is the response of the formats endpoint
+type formatsResponse struct {
+	Formats    []processors.CategoryFormats `json:"formats" description:"Extensions by category, each under the processor that gets it"`
+	Processors []processors.Capabilities    `json:"processors" description:"Processors in the order they are consulted, with their formats and settings"`
+}
 
-	c.JSON(http.StatusOK, gin.H{"formats": formats})
+// getSupportedFormats lists the formats of the processor registry
+func getSupportedFormats(c *gin.Context) {
+	c.JSON(http.StatusOK, formatsResponse{
+		Formats:    processorRegistry.Categories(),
+		Processors: processorRegistry.Capabilities(),
+	})
 }
@@ -0,0 +1,17 @@
+package main
+
+import "github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+
+// apiSpec describes the endpoints served under /api/v1
+var apiSpec = apispec.New("Content Extractor Service", "1.0.0", "/api/v1",
+	apispec.Operation{
+		Method: "POST", Path: "/extract", Tag: "extraction", Handler: extractContent,
+		Summary: "Extract the text content of a file",
+		Request: extractRequest{}, Response: extractResponse{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/formats", Tag: "extraction", Handler: getSupportedFormats,
+		Summary:  "List supported file formats",
+		Response: formatsResponse{},
+	},
+)
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/scanner"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// changeScans serializes change scans, so two scans of a directory cannot
+// both compare against the same manifest and lose one's changes
+var changeScans sync.Mutex
+
+// scanChangesRequest is the request body of the change scan endpoint
+type scanChangesRequest struct {
+	Directory string `json:"directory" binding:"required"`
+	Since     string `json:"since,omitempty" description:"Version of the manifest the caller last saw; when it is not the last one, every file is reported as added"`
+}
+
+// scanChangesResponse lists the files changed since the last scan of a
+// directory
+type scanChangesResponse struct {
+	Directory string            `json:"directory"`
+	Version   string            `json:"version" description:"Version of this scan's manifest, also sent as the ETag"`
+	Since     string            `json:"since,omitempty" description:"Version of the manifest compared against; empty on a full listing"`
+	Full      bool              `json:"full" description:"No manifest was compared against, so every file is added and none deleted"`
+	Added     []fileMetadata    `json:"added"`
+	Modified  []fileMetadata    `json:"modified"`
+	Deleted   []string          `json:"deleted"`
+	Unchanged int               `json:"unchanged"`
+	Skipped   []scanner.Skipped `json:"skipped,omitempty"`
+}
+
+// scanChanges scans a directory recursively, compares it with the manifest
+// of the last change scan and stores the new manifest. Unchanged files are
+// not hashed again. A request whose If-None-Match header names the new
+// version gets 304 Not Modified.
+func scanChanges(c *gin.Context) {
+	var req scanChangesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if manifests == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "scan manifests are disabled (SCAN_MANIFEST_BACKEND=none)"})
+		return
+	}
+
+	directory := utils.NormalizePath(req.Directory)
+	ctx := c.Request.Context()
+	changeScans.Lock()
+	defer changeScans.Unlock()
+
+	previous, err := manifests.Get(ctx, directory)
+	if err != nil && !errors.Is(err, scanner.ErrNoManifest) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	scan, err := scanner.Scan(utils.LocalPath(directory), scanner.OptionsFromConfig(scanConfig))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current := scanner.NewManifest(directory, scanConfig.HashAlgorithm)
+	skipped := scan.Skipped
+	cached := make(map[string]scanner.File)
+	described := make(map[string]fileMetadata)
+	for _, f := range scan.Files {
+		path := utils.NormalizePath(f.Path)
+		if hash, ok := previous.CachedHash(path, f.Info, scanConfig.HashAlgorithm); ok {
+			current.Add(path, f.Info, hash)
+			cached[path] = f
+			continue
+		}
+		meta, err := describeFile(path, f.Info, "")
+		if err != nil {
+			skipped = append(skipped, scanner.Skipped{Path: path, Reason: scanner.SkipUnreadable, Error: err.Error()})
+			// A file that could not be read this time is kept as it was,
+			// rather than reported deleted now and added next time
+			if previous != nil && previous.HashAlgorithm == current.HashAlgorithm {
+				if entry, ok := previous.Files[path]; ok {
+					current.Files[path] = entry
+				}
+			}
+			continue
+		}
+		current.Add(path, f.Info, meta.Hash)
+		described[path] = meta
+	}
+	current.Seal()
+
+	logger.Info("Scanned directory for changes",
+		zap.String("directory", directory),
+		zap.Int("files", len(current.Files)),
+		zap.Int("hashed", len(described)),
+		zap.String("version", current.Version))
+
+	baseline := previous
+	if previous != nil && req.Since != "" && req.Since != previous.Version {
+		baseline = nil
+	}
+	changes := scanner.Compare(baseline, current)
+
+	if err := manifests.Put(ctx, current); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", `"`+current.Version+`"`)
+	if match := c.GetHeader("If-None-Match"); match != "" && strings.Trim(match, `"`) == current.Version {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	resp := scanChangesResponse{
+		Directory: directory,
+		Version:   current.Version,
+		Full:      baseline == nil,
+		Deleted:   changes.Deleted,
+		Unchanged: changes.Unchanged,
+		Skipped:   skipped,
+	}
+	if baseline != nil {
+		resp.Since = baseline.Version
+	}
+	if resp.Added, err = describeChanged(ctx, changes.Added, current, cached, described); err == nil {
+		resp.Modified, err = describeChanged(ctx, changes.Modified, current, cached, described)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// describeChanged returns the metadata of changed files: those described
+// while hashing, and those whose hash came from the previous manifest,
+// which are only listed as changed on a full listing
+func describeChanged(ctx context.Context, paths []string, manifest *scanner.Manifest, cached map[string]scanner.File, described map[string]fileMetadata) ([]fileMetadata, error) {
+	result := make([]fileMetadata, 0, len(paths))
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if meta, ok := described[path]; ok {
+			result = append(result, meta)
+			continue
+		}
+		f, ok := cached[path]
+		if !ok {
+			continue // unreadable, kept from the previous manifest
+		}
+		meta, err := describeFile(path, f.Info, manifest.Files[path].Hash)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, meta)
+	}
+	return result, nil
+}
+
+// lastManifest returns the manifest of the last change scan of a
+// directory, or nil when there is none
+func lastManifest(ctx context.Context, directory string) *scanner.Manifest {
+	if manifests == nil {
+		return nil
+	}
+	manifest, err := manifests.Get(ctx, directory)
+	if err != nil {
+		if !errors.Is(err, scanner.ErrNoManifest) {
+			logger.Warn("Failed to read scan manifest", zap.String("directory", directory), zap.Error(err))
+		}
+		return nil
+	}
+	return manifest
+}
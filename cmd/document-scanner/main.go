@@ -6,17 +6,35 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
 	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/scanner"
+	"github.com/nadeeshame/rag-knowledge-service/internal/tlsconfig"
 	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
 	"go.uber.org/zap"
 )
 
+var (
+	// scanConfig holds the default symlink and hard link handling
+	scanConfig config.ScanConfig
+	// manifests keeps the last scan of each directory; nil when disabled
+	manifests scanner.ManifestStore
+)
+
 func main() {
+	if apispec.Requested() {
+		if err := apiSpec.Write(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
@@ -29,6 +47,15 @@ func main() {
 	}
 	defer func() { _ = logger.Sync() }() //nolint:errcheck
 
+	scanConfig = cfg.Scan
+	manifests, err = scanner.NewManifestStore(context.Background(), cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create scan manifest store", zap.Error(err))
+	}
+	if manifests != nil {
+		defer manifests.Close() //nolint:errcheck
+	}
+
 	logger.Info("Starting Document Scanner Service",
 		zap.String("version", "1.0.0"),
 		zap.Int("port", 8081))
@@ -39,13 +66,16 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"healthy": true})
 	})
 
+	router.GET("/openapi.json", apiSpec.Serve())
 	v1 := router.Group("/api/v1")
 	{
-		v1.POST("/scan/directory", scanDirectory)
-		v1.GET("/metadata/:filePath", getFileMetadata)
-		v1.POST("/compute-hash", computeHash)
+		apiSpec.Register(v1)
 	}
 
+	certs, err := tlsconfig.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to load TLS certificates", zap.Error(err))
+	}
 	srv := &http.Server{
 		Addr:         ":8081",
 		Handler:      router,
@@ -55,7 +85,7 @@ func main() {
 
 	go func() {
 		logger.Info("Document Scanner service starting", zap.String("address", srv.Addr))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := tlsconfig.ListenAndServe(srv, certs); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
@@ -75,54 +105,231 @@ func main() {
 	logger.Info("Server exited")
 }
 
+// scanDirectoryRequest is the request body of the scan endpoint
+type scanDirectoryRequest struct {
+	Directory      string   `json:"directory" binding:"required"`
+	Recursive      bool     `json:"recursive"`
+	FileTypes      []string `json:"file_types"`
+	FollowSymlinks *bool    `json:"follow_symlinks,omitempty" description:"defaults to SCAN_FOLLOW_SYMLINKS"`
+}
+
+// scanDirectoryResponse lists the files found by a scan
+type scanDirectoryResponse struct {
+	Directory  string            `json:"directory"`
+	Files      []fileMetadata    `json:"files"`
+	TotalFiles int               `json:"total_files"`
+	TotalSize  int64             `json:"total_size"`
+	Skipped    []scanner.Skipped `json:"skipped,omitempty"`
+}
+
+// computeHashRequest is the request body of the hash endpoint
+type computeHashRequest struct {
+	FilePath  string `json:"file_path" binding:"required"`
+	Algorithm string `json:"algorithm,omitempty" description:"sha256, blake3 or xxh64; defaults to SCAN_HASH_ALGORITHM"`
+	ChunkSize int    `json:"chunk_size,omitempty" description:"Average size in bytes of content-defined chunks to hash separately; 0 hashes the whole file only"`
+}
+
+// computeHashResponse is the response of the hash endpoint
+type computeHashResponse struct {
+	FilePath  string               `json:"file_path"`
+	Hash      string               `json:"hash"`
+	Algorithm string               `json:"algorithm"`
+	Chunks    []utils.ContentChunk `json:"chunks,omitempty"`
+}
+
 func scanDirectory(c *gin.Context) {
-	var req struct {
-		Directory string `json:"directory" binding:"required"`
-	}
+	var req scanDirectoryRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	logger.Info("Scanning directory", zap.String("directory", req.Directory))
+	directory := utils.NormalizePath(req.Directory)
+	opts := scanner.OptionsFromConfig(scanConfig)
+	opts.Recursive = req.Recursive
+	if req.FollowSymlinks != nil {
+		opts.FollowSymlinks = *req.FollowSymlinks
+	}
+	logger.Info("Scanning directory",
+		zap.String("directory", directory),
+		zap.Bool("recursive", opts.Recursive),
+		zap.Bool("follow_symlinks", opts.FollowSymlinks))
+
+	scan, err := scanner.Scan(utils.LocalPath(directory), opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	types := make(map[string]bool, len(req.FileTypes))
+	for _, t := range req.FileTypes {
+		types[strings.ToLower(strings.TrimPrefix(t, "."))] = true
+	}
 
-	// TODO: Implement directory scanning
-	c.JSON(http.StatusOK, gin.H{
-		"directory": req.Directory,
-		"files":     []string{},
-	})
+	// Files unchanged since the last change scan keep their hashes
+	previous := lastManifest(c.Request.Context(), directory)
+	resp := scanDirectoryResponse{Directory: directory, Files: []fileMetadata{}, Skipped: scan.Skipped}
+	for _, f := range scan.Files {
+		path := utils.NormalizePath(f.Path)
+		if len(types) > 0 && !types[utils.GetFileExtension(path)] {
+			continue
+		}
+		hash, _ := previous.CachedHash(path, f.Info, scanConfig.HashAlgorithm)
+		meta, err := describeFile(path, f.Info, hash)
+		if err != nil {
+			resp.Skipped = append(resp.Skipped, scanner.Skipped{Path: path, Reason: scanner.SkipUnreadable, Error: err.Error()})
+			continue
+		}
+		resp.Files = append(resp.Files, meta)
+		resp.TotalSize += meta.Size
+	}
+	resp.TotalFiles = len(resp.Files)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// metadataRequest is the request body of the metadata endpoint
+type metadataRequest struct {
+	FilePath string `json:"file_path" binding:"required" description:"POSIX, Windows drive, UNC or long path"`
+}
+
+// fileMetadata describes a file on disk
+type fileMetadata struct {
+	Path          string    `json:"path"`
+	Name          string    `json:"name"`
+	Extension     string    `json:"extension"`
+	Size          int64     `json:"size"`
+	ModifiedTime  time.Time `json:"modified_time"`
+	Category      string    `json:"category"`
+	MimeType      string    `json:"mime_type" description:"MIME type sniffed from the file content"`
+	DetectedType  string    `json:"detected_type" description:"Extension the content is processed as"`
+	TypeMismatch  string    `json:"type_mismatch,omitempty" description:"Warning when the extension and content disagree"`
+	Hash          string    `json:"hash"`
+	HashAlgorithm string    `json:"hash_algorithm"`
 }
 
+// getFileMetadata serves /metadata/:filePath. The path parameter cannot
+// contain separators, so only file names relative to the working
+// directory work here; use the query or POST variants for full paths.
 func getFileMetadata(c *gin.Context) {
-	filePath := c.Param("filePath")
-	logger.Info("Getting file metadata", zap.String("file_path", filePath))
+	respondFileMetadata(c, c.Param("filePath"))
+}
 
-	// TODO: Implement metadata extraction
-	c.JSON(http.StatusOK, gin.H{
-		"path": filePath,
-		"type": utils.GetFileCategory(filePath),
-	})
+// queryFileMetadata serves GET /metadata?path=...
+func queryFileMetadata(c *gin.Context) {
+	filePath := c.Query("path")
+	if filePath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path query parameter is required"})
+		return
+	}
+	respondFileMetadata(c, filePath)
 }
 
-func computeHash(c *gin.Context) {
-	var req struct {
-		FilePath string `json:"file_path" binding:"required"`
+// postFileMetadata serves POST /metadata with the path in the body
+func postFileMetadata(c *gin.Context) {
+	var req metadataRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondFileMetadata(c, req.FilePath)
+}
+
+func respondFileMetadata(c *gin.Context, filePath string) {
+	normalized := utils.NormalizePath(filePath)
+	logger.Info("Getting file metadata", zap.String("file_path", normalized))
+
+	info, err := os.Stat(utils.LocalPath(normalized))
+	if os.IsNotExist(err) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found: " + normalized})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if info.IsDir() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": normalized + " is a directory"})
+		return
+	}
+
+	meta, err := describeFile(normalized, info, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
+	c.JSON(http.StatusOK, meta)
+}
+
+// describeFile builds the metadata of a file from its normalized path and
+// stat info. The category and MIME type come from the file content. The
+// file is hashed unless its hash is already known.
+func describeFile(normalized string, info os.FileInfo, hash string) (fileMetadata, error) {
+	if hash == "" {
+		hashes, err := utils.HashFile(utils.LocalPath(normalized), scanConfig.HashAlgorithm)
+		if err != nil {
+			return fileMetadata{}, err
+		}
+		hash = hashes[0]
+	}
+	detected, err := scanner.Detect(normalized)
+	if err != nil {
+		return fileMetadata{}, err
+	}
+
+	return fileMetadata{
+		Path:          normalized,
+		Name:          utils.BaseName(normalized),
+		Extension:     utils.GetFileExtension(normalized),
+		Size:          info.Size(),
+		ModifiedTime:  info.ModTime().UTC(),
+		Category:      utils.GetFileCategory(detected.Extension),
+		MimeType:      detected.MimeType,
+		DetectedType:  strings.TrimPrefix(detected.Extension, "."),
+		TypeMismatch:  detected.Mismatch,
+		Hash:          hash,
+		HashAlgorithm: scanConfig.HashAlgorithm,
+	}, nil
+}
+
+func computeHash(c *gin.Context) {
+	var req computeHashRequest
+
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	hash, err := utils.ComputeFileHash(req.FilePath)
+	filePath := utils.NormalizePath(req.FilePath)
+	algorithm := req.Algorithm
+	if algorithm == "" {
+		algorithm = scanConfig.HashAlgorithm
+	}
+	if _, err := utils.NewHash(algorithm); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ChunkSize != 0 && (req.ChunkSize < utils.MinChunkHashSize || req.ChunkSize > utils.MaxChunkHashSize) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("chunk_size must be between %d and %d", utils.MinChunkHashSize, utils.MaxChunkHashSize)})
+		return
+	}
+
+	hashes, err := utils.HashFile(utils.LocalPath(filePath), algorithm)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	resp := computeHashResponse{FilePath: filePath, Hash: hashes[0], Algorithm: algorithm}
+	if req.ChunkSize > 0 {
+		if resp.Chunks, err = utils.HashFileChunks(utils.LocalPath(filePath), algorithm, req.ChunkSize); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"file_path": req.FilePath,
-		"hash":      hash,
-	})
+	c.JSON(http.StatusOK, resp)
 }
@@ -0,0 +1,41 @@
+package main
+
+import "github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+
+// apiSpec describes the endpoints served under /api/v1
+var apiSpec = apispec.New("Document Scanner Service", "1.0.0", "/api/v1",
+	apispec.Operation{
+		Method: "POST", Path: "/scan/directory", Tag: "scanner", Handler: scanDirectory,
+		Summary:  "List the files of a directory",
+		Request:  scanDirectoryRequest{},
+		Response: scanDirectoryResponse{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/scan/changes", Tag: "scanner", Handler: scanChanges,
+		Summary:  "List the files added, modified and deleted since the last change scan of a directory",
+		Request:  scanChangesRequest{},
+		Response: scanChangesResponse{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/metadata", Tag: "scanner", Handler: queryFileMetadata,
+		Summary:  "Get the metadata of a file by path",
+		Response: fileMetadata{}, Query: []string{"path"},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/metadata", Tag: "scanner", Handler: postFileMetadata,
+		Summary:  "Get the metadata of a file by path",
+		Request:  metadataRequest{},
+		Response: fileMetadata{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/metadata/:filePath", Tag: "scanner", Handler: getFileMetadata,
+		Summary:  "Get the metadata of a file by name (deprecated, cannot express directories)",
+		Response: fileMetadata{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/compute-hash", Tag: "scanner", Handler: computeHash,
+		Summary:  "Compute the hash of a file, and optionally of its content-defined chunks",
+		Request:  computeHashRequest{},
+		Response: computeHashResponse{},
+	},
+)
@@ -10,12 +10,24 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/embedding"
 	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/tlsconfig"
 	"go.uber.org/zap"
 )
 
+var embeddingService *embedding.Service
+
 func main() {
+	if apispec.Requested() {
+		if err := apiSpec.Write(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
@@ -28,7 +40,14 @@ func main() {
 	defer func() { _ = logger.Sync() }() //nolint:errcheck
 	logger.Info("Starting Embedding Service",
 		zap.String("version", "1.0.0"),
-		zap.Int("port", 8085))
+		zap.Int("port", 8085),
+		zap.Int("max_batch_size", cfg.Embedding.MaxBatchSize),
+		zap.Duration("max_batch_wait", cfg.Embedding.MaxBatchWait))
+	embeddingService, err = embedding.NewService(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create embedding service", zap.Error(err))
+	}
+	defer embeddingService.Close()
 	router := gin.Default()
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"healthy": true})
@@ -36,12 +55,17 @@ func main() {
 	router.GET("/ready", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"ready": true})
 	})
+	router.GET("/openapi.json", apiSpec.Serve())
 	v1 := router.Group("/api/v1")
 	{
-		// TODO: Add service-specific endpoints
 		v1.GET("/status", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"status": "operational"})
 		})
+		apiSpec.Register(v1)
+	}
+	certs, err := tlsconfig.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to load TLS certificates", zap.Error(err))
 	}
 	srv := &http.Server{
 		Addr:         ":8085",
@@ -51,7 +75,7 @@ func main() {
 	}
 	go func() {
 		logger.Info("Embedding Service starting", zap.String("address", srv.Addr))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := tlsconfig.ListenAndServe(srv, certs); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
@@ -66,3 +90,55 @@ func main() {
 	}
 	logger.Info("Server exited")
 }
+
+// embedRequest carries either a single text or a batch of texts
+type embedRequest struct {
+	Text  string   `json:"text"`
+	Texts []string `json:"texts"`
+}
+
+// embed handles both single ({"text": ...}) and batch ({"texts": [...]}) requests
+func embed(c *gin.Context) {
+	var req embedRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if (req.Text == "") == (len(req.Texts) == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of text or texts is required"})
+		return
+	}
+
+	if req.Text != "" {
+		vector, err := embeddingService.GenerateEmbedding(c.Request.Context(), req.Text)
+		if err != nil {
+			logger.Error("Failed to generate embedding", zap.Error(err))
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"embedding": vector,
+			"dimension": len(vector),
+			"model":     embeddingService.Model(),
+		})
+		return
+	}
+
+	vectors, err := embeddingService.GenerateEmbeddings(c.Request.Context(), req.Texts)
+	if err != nil {
+		logger.Error("Failed to generate embeddings", zap.Int("count", len(req.Texts)), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"embeddings": vectors,
+		"count":      len(vectors),
+		"model":      embeddingService.Model(),
+	})
+}
+
+func dimension(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"dimension": embeddingService.GetDimension()})
+}
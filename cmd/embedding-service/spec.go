@@ -0,0 +1,21 @@
+package main
+
+import "github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+
+// apiSpec describes the endpoints served under /api/v1
+var apiSpec = apispec.New("Embedding Service", "1.0.0", "/api/v1",
+	apispec.Operation{
+		Method: "POST", Path: "/embed", Tag: "embeddings", Handler: embed,
+		Summary: "Create embeddings for a text or a batch of texts",
+		Request: embedRequest{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/embed/batch", Tag: "embeddings", Handler: embed,
+		Summary: "Create embeddings for a batch of texts (alias of /embed)",
+		Request: embedRequest{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/dimension", Tag: "embeddings", Handler: dimension,
+		Summary: "Get the embedding dimension",
+	},
+)
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/gateway"
+	"github.com/nadeeshame/rag-knowledge-service/internal/httpsec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/slackbot"
+	"github.com/nadeeshame/rag-knowledge-service/internal/tlsconfig"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"go.uber.org/zap"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
+	if err := logger.Initialize(cfg.App.LogLevel); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = logger.Sync() }() //nolint:errcheck
+	logger.Info("Starting API Gateway",
+		zap.String("version", "1.0.0"),
+		zap.Int("port", cfg.Gateway.Port),
+		zap.Bool("auth_enabled", cfg.Gateway.AuthEnabled()),
+		zap.Float64("rate_limit", cfg.Gateway.RateLimit))
+	gw, err := gateway.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create gateway", zap.Error(err))
+	}
+	stop := make(chan struct{})
+	gw.StartCleanup(stop)
+	router := gin.Default()
+	router.Use(httpsec.SecurityHeaders(cfg.SecurityHeaders), httpsec.CORS(cfg.CORS))
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"healthy": true})
+	})
+	router.GET("/ready", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ready": true})
+	})
+	gw.Register(router)
+	certs, err := tlsconfig.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to load TLS certificates", zap.Error(err))
+	}
+	if certs != nil {
+		gw.SetTransport(certs.Transport())
+	}
+	// The Slack app asks the query service directly, as the users it answers
+	if cfg.Slack.Enabled() {
+		opts := client.DefaultOptions()
+		opts.HTTPClient = tlsconfig.HTTPClient(certs)
+		opts.Timeout = cfg.Slack.Timeout
+		opts.UserAgent = "repograph-slack/1.0"
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, opts)
+		bot := slackbot.New(cfg.Slack, querier, &http.Client{Timeout: 30 * time.Second}, logger.Log)
+		bot.Register(router)
+		logger.Info("Slack app enabled", zap.Int("workspaces", len(cfg.Slack.Workspaces)))
+	}
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Gateway.Port),
+		Handler:      router,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+	go func() {
+		logger.Info("API Gateway starting", zap.String("address", srv.Addr))
+		if err := tlsconfig.ListenAndServe(srv, certs); err != nil && err != http.ErrServerClosed {
+			logger.Fatal("Failed to start server", zap.Error(err))
+		}
+	}()
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.Info("Shutting down server...")
+	close(stop)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Error("Server forced to shutdown", zap.Error(err))
+	}
+	logger.Info("Server exited")
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"github.com/nadeeshame/rag-knowledge-service/internal/quota"
+	"go.uber.org/zap"
+)
+
+// processBatchRequest is the request body of the batch processing endpoint
+type processBatchRequest struct {
+	// Items are the files to process, as local paths or file:// URLs
+	Items          []string `json:"items" binding:"required"`
+	ForceReprocess bool     `json:"force_reprocess"`
+	// Options change how the files are extracted; each file gets those
+	// its processor honours
+	Options processors.ExtractOptions `json:"options"`
+}
+
+// batchResponse is the response body of the batch processing endpoint
+type batchResponse struct {
+	Status string `json:"status"` // accepted, or none_accepted without a run
+	// RunID is the run processing the accepted items, followed at
+	// /runs/{id} and cancelled or resumed like a directory run
+	RunID    string                   `json:"run_id,omitempty"`
+	Accepted int                      `json:"accepted"`
+	Skipped  int                      `json:"skipped"`
+	Invalid  int                      `json:"invalid"`
+	Items    []orchestrator.BatchItem `json:"items"` // in request order
+}
+
+// processBatch checks each file of a batch and processes the accepted ones
+// in the background as a single run. Items that are skipped or invalid do
+// not fail the request; their status says why. It answers 202 when a run
+// started and 200 when no item was accepted.
+func processBatch(c *gin.Context) {
+	var req processBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Items) > orchestrator.MaxBatchItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("a batch holds at most %d items", orchestrator.MaxBatchItems)})
+		return
+	}
+	p, ok := runProcessor(c)
+	if !ok {
+		return
+	}
+
+	batch, err := p.PrepareBatch(c.Request.Context(), req.Items, req.ForceReprocess, func(files []string) error {
+		return quota.Charge(c, files)
+	})
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		exceeded.Respond(c)
+		return
+	}
+	resp := batchResponse{
+		RunID:    batch.RunID,
+		Accepted: batch.Count(orchestrator.BatchAccepted),
+		Skipped:  batch.Count(orchestrator.BatchSkipped),
+		Invalid:  batch.Count(orchestrator.BatchInvalid),
+		Items:    batch.Items,
+	}
+	if batch.RunID == "" {
+		resp.Status = "none_accepted"
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionProcessBatch, batch.RunID)
+	event.Details["client_ip"] = c.ClientIP()
+	event.Details["accepted"] = strconv.Itoa(resp.Accepted)
+	event.Details["skipped"] = strconv.Itoa(resp.Skipped)
+	event.Details["invalid"] = strconv.Itoa(resp.Invalid)
+
+	go func() {
+		ctx := context.Background()
+		result, err := p.ProcessBatch(processors.WithOptions(ctx, req.Options), batch)
+		if err != nil {
+			logger.Error("Failed to process batch", zap.String("run_id", batch.RunID), zap.Error(err))
+			event.Outcome = audit.OutcomeFailure
+			event.Details["error"] = err.Error()
+		} else {
+			event.Details["processed"] = strconv.Itoa(result.Processed)
+			event.Details["failed"] = strconv.Itoa(result.Failed)
+		}
+		auditRecorder.Record(ctx, event)
+	}()
+
+	resp.Status = "accepted"
+	c.JSON(http.StatusAccepted, resp)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/collections"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+// createCollectionRequest is the request body of the create collection endpoint
+type createCollectionRequest struct {
+	Name        string `json:"name" binding:"required" description:"Lowercase letters, digits, '.', '_' and '-', at most 64 characters"`
+	Description string `json:"description"`
+}
+
+// collectionDocumentsRequest is the request body of the add to collection endpoint
+type collectionDocumentsRequest struct {
+	DocumentIDs []string `json:"document_ids" binding:"required,min=1,max=1000" description:"Registry IDs of the documents, at most 1000"`
+}
+
+// collectionSummary describes a collection without its documents
+type collectionSummary struct {
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	DocumentCount int       `json:"document_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// collectionsResponse is the response body of the collection list endpoint
+type collectionsResponse struct {
+	Collections []collectionSummary `json:"collections"`
+	Count       int                 `json:"count"`
+}
+
+// collectionUpdateResponse is the response body of the add to collection endpoint
+type collectionUpdateResponse struct {
+	Collection *collections.Collection `json:"collection"`
+	Added      int                     `json:"added"`
+	Rejected   map[string]string       `json:"rejected,omitempty"` // document ID to the reason it was not added
+}
+
+// listCollections returns all collections with their document counts
+func listCollections(c *gin.Context) {
+	all, err := collectionStore.List(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to list collections", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := collectionsResponse{Collections: make([]collectionSummary, 0, len(all)), Count: len(all)}
+	for _, col := range all {
+		resp.Collections = append(resp.Collections, collectionSummary{
+			Name:          col.Name,
+			Description:   col.Description,
+			DocumentCount: len(col.Paths),
+			CreatedAt:     col.CreatedAt,
+			UpdatedAt:     col.UpdatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// createCollection creates an empty collection
+func createCollection(c *gin.Context) {
+	var req createCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if err := collections.ValidateName(name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	collection := &collections.Collection{
+		Name:        name,
+		Description: req.Description,
+		Paths:       []string{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	err := collectionStore.Create(c.Request.Context(), collection)
+	if errors.Is(err, collections.ErrExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "collection " + name + " already exists"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create collection", zap.String("collection", name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionCollectionCreate, name)
+	event.Details["client_ip"] = c.ClientIP()
+	auditRecorder.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusCreated, collection)
+}
+
+// getCollection returns a collection with the file paths of its documents
+func getCollection(c *gin.Context) {
+	collection, ok := lookupCollection(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, collection)
+}
+
+// deleteCollection removes a collection; its documents are not affected
+func deleteCollection(c *gin.Context) {
+	name := c.Param("name")
+	err := collectionStore.Delete(c.Request.Context(), name)
+	if errors.Is(err, collections.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "collection " + name + " not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete collection", zap.String("collection", name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionCollectionDelete, name)
+	event.Details["client_ip"] = c.ClientIP()
+	auditRecorder.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted", "collection": name})
+}
+
+// addCollectionDocuments adds documents to a collection by registry ID.
+// Membership is kept by file path, so later versions of the documents stay
+// in the collection; unknown documents and those the caller may not read
+// are rejected.
+func addCollectionDocuments(c *gin.Context) {
+	var req collectionDocumentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := c.Param("name")
+
+	resp := collectionUpdateResponse{Rejected: map[string]string{}}
+	ids := make([]string, 0, len(req.DocumentIDs))
+	paths := make([]string, 0, len(req.DocumentIDs))
+	for _, id := range req.DocumentIDs {
+		record, err := documentRegistry.Get(c.Request.Context(), id)
+		switch {
+		case errors.Is(err, registry.ErrNotFound), err == nil && !readableBy(c)(record):
+			resp.Rejected[id] = "not found"
+		case err != nil:
+			resp.Rejected[id] = err.Error()
+		default:
+			ids = append(ids, id)
+			paths = append(paths, record.FilePath)
+		}
+	}
+
+	collection, err := collectionStore.AddPaths(c.Request.Context(), name, paths)
+	if !writeCollectionError(c, name, err) {
+		return
+	}
+	resp.Collection = collection
+	resp.Added = len(paths)
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionCollectionUpdate, name)
+	event.DocumentIDs = ids
+	event.Details["added"] = strconv.Itoa(len(paths))
+	event.Details["client_ip"] = c.ClientIP()
+	auditRecorder.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// removeCollectionDocument removes a document from a collection
+func removeCollectionDocument(c *gin.Context) {
+	record, ok := lookupDocument(c)
+	if !ok {
+		return
+	}
+	name := c.Param("name")
+
+	collection, err := collectionStore.RemovePaths(c.Request.Context(), name, []string{record.FilePath})
+	if !writeCollectionError(c, name, err) {
+		return
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionCollectionUpdate, name)
+	event.DocumentIDs = []string{record.ID}
+	event.Details["removed"] = "1"
+	event.Details["client_ip"] = c.ClientIP()
+	auditRecorder.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// lookupCollection finds the collection for the :name parameter, writing a
+// 404 when absent
+func lookupCollection(c *gin.Context) (*collections.Collection, bool) {
+	name := c.Param("name")
+	collection, err := collectionStore.Get(c.Request.Context(), name)
+	if !writeCollectionError(c, name, err) {
+		return nil, false
+	}
+	return collection, true
+}
+
+// writeCollectionError writes the response for a failed collection store
+// call and reports whether the call succeeded
+func writeCollectionError(c *gin.Context, name string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, collections.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "collection " + name + " not found"})
+	case errors.Is(err, collections.ErrFull):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logger.Error("Failed to access collection", zap.String("collection", name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/digest"
+	"go.uber.org/zap"
+)
+
+// digestsResponse is the response body of the digest report list endpoint
+type digestsResponse struct {
+	Reports []digest.Status `json:"reports"`
+	Count   int             `json:"count"`
+}
+
+// listDigests returns the digest reports with their next and last runs
+func listDigests(c *gin.Context) {
+	reports := []digest.Status{}
+	if digestService != nil {
+		reports = digestService.Reports()
+	}
+	c.JSON(http.StatusOK, digestsResponse{Reports: reports, Count: len(reports)})
+}
+
+// runDigest generates a digest report now. With preview=true the digest is
+// returned without being delivered.
+func runDigest(c *gin.Context) {
+	if digestService == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "digest reports are disabled"})
+		return
+	}
+	preview := false
+	if value := c.Query("preview"); value != "" {
+		var err error
+		if preview, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "preview must be true or false"})
+			return
+		}
+	}
+
+	name := c.Param("name")
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionDigest, name)
+	event.Details["trigger"] = "manual"
+	event.Details["preview"] = strconv.FormatBool(preview)
+	event.Details["client_ip"] = c.ClientIP()
+
+	result, err := digestService.Generate(c.Request.Context(), name, !preview)
+	switch {
+	case errors.Is(err, digest.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "report " + name + " not found"})
+		return
+	case err != nil:
+		logger.Error("Failed to run digest report", zap.String("report", name), zap.Error(err))
+		event.Outcome = audit.OutcomeFailure
+		event.Details["error"] = err.Error()
+		auditRecorder.Record(c.Request.Context(), event)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditRecorder.Record(c.Request.Context(), event)
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/dlq"
+	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"go.uber.org/zap"
+)
+
+// dlqResponse is the response body of the dead-letter list endpoint
+type dlqResponse struct {
+	Entries []*dlq.Entry `json:"entries"`
+	Count   int          `json:"count"`
+}
+
+// dlqRetryRequest is the request body of the dead-letter retry endpoint
+type dlqRetryRequest struct {
+	IDs      []string `json:"ids" binding:"required_without=All,max=10000" description:"Entry IDs to retry"`
+	All      bool     `json:"all" description:"Retry every entry instead of the listed ones"`
+	Priority string   `json:"priority" binding:"omitempty,oneof=high normal low"`
+}
+
+// dlqRetryResponse is the response body of the dead-letter retry endpoint
+type dlqRetryResponse struct {
+	Status   string            `json:"status"`
+	Queued   []string          `json:"queued"`
+	Rejected map[string]string `json:"rejected,omitempty"` // entry ID to the reason it was not queued
+}
+
+// listDeadLetters returns the files that failed every retry, most recent
+// failure first
+func listDeadLetters(c *gin.Context) {
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := dlqStore.List(c.Request.Context(), limit)
+	if err != nil {
+		logger.Error("Failed to list dead-letter entries", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dlqResponse{Entries: entries, Count: len(entries)})
+}
+
+// retryDeadLetters queues dead-lettered files for processing again and
+// removes their entries. A file that fails every retry again gets a new
+// entry.
+func retryDeadLetters(c *gin.Context) {
+	var req dlqRetryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	priority, err := orchestrator.ParsePriority(req.Priority)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := runProcessor(c); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	resp := dlqRetryResponse{Status: "queued", Queued: []string{}, Rejected: map[string]string{}}
+	var entries []*dlq.Entry
+	if req.All {
+		if entries, err = dlqStore.List(ctx, 0); err != nil {
+			logger.Error("Failed to list dead-letter entries", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		for _, id := range req.IDs {
+			entry, err := dlqStore.Get(ctx, id)
+			switch {
+			case errors.Is(err, dlq.ErrNotFound):
+				resp.Rejected[id] = "not found"
+			case err != nil:
+				resp.Rejected[id] = err.Error()
+			default:
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	// Forced and unforced files are queued separately, all or none
+	for _, force := range []bool{false, true} {
+		var paths []string
+		for _, entry := range entries {
+			if entry.Force == force {
+				paths = append(paths, entry.FilePath)
+			}
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		if _, err := ingestQueue.Submit(ctx, paths, priority, force, processors.ExtractOptions{}); err != nil {
+			for _, entry := range entries {
+				if entry.Force == force {
+					resp.Rejected[entry.ID] = err.Error()
+				}
+			}
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Force != force {
+				continue
+			}
+			if err := dlqStore.Remove(ctx, entry.ID); err != nil && !errors.Is(err, dlq.ErrNotFound) {
+				logger.Warn("Failed to remove retried dead-letter entry", zap.String("entry_id", entry.ID), zap.Error(err))
+			}
+			resp.Queued = append(resp.Queued, entry.ID)
+		}
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionDLQRetry, c.Request.URL.Path)
+	event.Details["client_ip"] = c.ClientIP()
+	event.Details["priority"] = string(priority)
+	event.Details["queued"] = strconv.Itoa(len(resp.Queued))
+	event.Details["rejected"] = strconv.Itoa(len(resp.Rejected))
+	if len(resp.Queued) == 0 && len(resp.Rejected) > 0 {
+		event.Outcome = audit.OutcomeFailure
+	}
+	auditRecorder.Record(ctx, event)
+
+	status := http.StatusAccepted
+	if len(resp.Queued) == 0 && len(resp.Rejected) > 0 {
+		status = http.StatusServiceUnavailable
+		for _, reason := range resp.Rejected {
+			if reason != orchestrator.ErrQueueFull.Error() {
+				status = http.StatusBadRequest
+				break
+			}
+		}
+	}
+	c.JSON(status, resp)
+}
+
+// discardDeadLetter removes a dead-letter entry without retrying its file
+func discardDeadLetter(c *gin.Context) {
+	id := c.Param("id")
+	entry, err := dlqStore.Get(c.Request.Context(), id)
+	if err == nil {
+		err = dlqStore.Remove(c.Request.Context(), id)
+	}
+	switch {
+	case errors.Is(err, dlq.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("Failed to remove dead-letter entry", zap.String("entry_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionDLQDiscard, entry.FilePath)
+	event.Details["client_ip"] = c.ClientIP()
+	event.Details["entry_id"] = id
+	auditRecorder.Record(c.Request.Context(), event)
+	c.JSON(http.StatusOK, gin.H{"status": "discarded", "id": id})
+}
+
+// exportDeadLetters downloads every dead-letter entry as JSON lines or CSV
+func exportDeadLetters(c *gin.Context) {
+	format := c.DefaultQuery("format", dlq.FormatJSONL)
+	contentType := "application/x-ndjson"
+	switch format {
+	case dlq.FormatJSONL:
+	case dlq.FormatCSV:
+		contentType = "text/csv"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be jsonl or csv"})
+		return
+	}
+
+	entries, err := dlqStore.List(c.Request.Context(), 0)
+	if err != nil {
+		logger.Error("Failed to list dead-letter entries", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := "dlq-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+	if err := dlq.Export(c.Writer, entries, format); err != nil {
+		logger.Warn("Failed to write dead-letter export", zap.Error(err))
+	}
+}
@@ -0,0 +1,577 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/contentstore"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/httpsec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/longpoll"
+	"github.com/nadeeshame/rag-knowledge-service/internal/notes"
+	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+	"go.uber.org/zap"
+)
+
+var (
+	processorMu sync.Mutex
+	processor   *orchestrator.DocumentProcessor
+	// ingestQueue processes the files submitted to the processing
+	// endpoints; it is started with the processor
+	ingestQueue *orchestrator.IngestQueue
+
+	// rerunMu runs rechunk and resummarize requests one after another
+	rerunMu sync.Mutex
+)
+
+// documentProcessor returns the shared document processor, creating it on
+// first use. Creation is retried on the next call if it fails.
+func documentProcessor() (*orchestrator.DocumentProcessor, error) {
+	processorMu.Lock()
+	defer processorMu.Unlock()
+
+	if processor != nil {
+		return processor, nil
+	}
+	p, err := orchestrator.NewDocumentProcessor(appConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	p.SetRegistry(documentRegistry)
+	p.SetChunkStore(chunkStore)
+	p.SetWAL(upsertLog)
+	p.SetContentStore(contentStore)
+	p.SetImageIndex(imageIndex)
+	p.SetRouter(categoryRouter)
+	p.SetSparseEncoder(sparseEncoder)
+	p.SetRunStore(runStore)
+	p.SetEvents(eventBus)
+	p.SetLocker(locker)
+	p.SetDeadLetters(dlqStore)
+	processor = p
+	ingestQueue = orchestrator.NewIngestQueue(p, ingestBacklog, appConfig.App.IngestWorkers, logger)
+	ingestQueue.Start(context.Background())
+	if interval := appConfig.Enrichment.RepairInterval; interval > 0 {
+		go locker.Lead(context.Background(), "scheduler:repair", func(ctx context.Context) {
+			p.RunRepair(ctx, interval)
+		})
+	}
+	if interval := appConfig.WAL.ReplayInterval; upsertLog != nil && interval > 0 {
+		go p.RunReplay(context.Background(), interval)
+	}
+	return processor, nil
+}
+
+// replayUpsertLog stores the vectors left in the write-ahead log by a run
+// that stopped before upserting them
+func replayUpsertLog(ctx context.Context, p *orchestrator.DocumentProcessor) {
+	result, err := p.ReplayWAL(ctx)
+	if err != nil {
+		logger.Error("Failed to replay write-ahead log", zap.Error(err))
+		return
+	}
+	if result.Failed > 0 {
+		logger.Warn("Some write-ahead log entries could not be replayed",
+			zap.Int("failed", result.Failed))
+	}
+}
+
+// documentsResponse is the response body of the document list endpoint
+type documentsResponse struct {
+	Documents []*registry.Record `json:"documents"`
+	Count     int                `json:"count"`
+}
+
+// readableBy returns whether the caller may read a record, by the rule the
+// query service applies to chunks. Admin requests may read every record.
+func readableBy(c *gin.Context) func(*registry.Record) bool {
+	if httpsec.IsAdmin(c, appConfig.Services.AdminToken) {
+		return func(*registry.Record) bool { return true }
+	}
+	caller := httpsec.Identity(c)
+	return func(r *registry.Record) bool {
+		acl := orchestrator.RecordACL(appConfig, r)
+		return acl.Allows(caller)
+	}
+}
+
+// readableRecords returns the records the caller may read
+func readableRecords(c *gin.Context, records []*registry.Record) []*registry.Record {
+	readable := readableBy(c)
+	kept := make([]*registry.Record, 0, len(records))
+	for _, r := range records {
+		if readable(r) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// listDocuments returns the registry records the caller may read, filtered
+// by category, state, path prefix and whether stages were skipped
+func listDocuments(c *gin.Context) {
+	filter := registry.Filter{
+		Category:   c.Query("category"),
+		State:      models.ProcessingState(c.Query("state")),
+		PathPrefix: utils.NormalizePath(c.Query("path")),
+	}
+	if value := c.Query("needs_enrichment"); value != "" {
+		needs, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "needs_enrichment must be true or false"})
+			return
+		}
+		filter.NeedsEnrichment = needs
+	}
+	// The limit is applied after the records the caller may not read are
+	// left out
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+
+	records, err := documentRegistry.List(c.Request.Context(), filter)
+	if err != nil {
+		logger.Error("Failed to list documents", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	records = readableRecords(c, records)
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	c.JSON(http.StatusOK, documentsResponse{Documents: records, Count: len(records)})
+}
+
+// defaultStaleMonths is how long a document's file must go unmodified to
+// be reported as stale when the request does not say
+const defaultStaleMonths = 12
+
+// staleResponse is the response body of the stale document report
+type staleResponse struct {
+	Months    int             `json:"months"`
+	Before    time.Time       `json:"before"` // documents last modified before this are stale
+	Documents []staleDocument `json:"documents"`
+	Count     int             `json:"count"`
+}
+
+// staleDocument is an indexed document not modified for a while
+type staleDocument struct {
+	DocumentID string     `json:"document_id"`
+	FilePath   string     `json:"file_path"`
+	ModifiedAt *time.Time `json:"modified_at,omitempty"`
+	IndexedAt  *time.Time `json:"indexed_at,omitempty"`
+	// AgeDays counts the days since the file was last modified, or was
+	// indexed when its modification time is unknown
+	AgeDays int `json:"age_days"`
+}
+
+// staleDocuments lists the indexed documents the caller may read whose
+// files were last modified more than the given number of months ago,
+// oldest first, as those most likely to hold outdated answers. Documents indexed before
+// modification times were recorded are aged by when they were indexed.
+func staleDocuments(c *gin.Context) {
+	months := defaultStaleMonths
+	if value := c.Query("months"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "months must be a positive integer"})
+			return
+		}
+		months = n
+	}
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+
+	records, err := documentRegistry.List(c.Request.Context(), registry.Filter{
+		State:      models.StateIndexed,
+		PathPrefix: utils.NormalizePath(c.Query("path")),
+	})
+	if err != nil {
+		logger.Error("Failed to list documents", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	records = readableRecords(c, records)
+
+	now := time.Now()
+	before := now.AddDate(0, -months, 0)
+	stale := []staleDocument{}
+	for _, r := range records {
+		updated := r.ModifiedAt
+		if updated == nil {
+			updated = r.IndexedAt
+		}
+		if updated == nil || !updated.Before(before) {
+			continue
+		}
+		stale = append(stale, staleDocument{
+			DocumentID: r.ID,
+			FilePath:   r.FilePath,
+			ModifiedAt: r.ModifiedAt,
+			IndexedAt:  r.IndexedAt,
+			AgeDays:    int(now.Sub(*updated).Hours() / 24),
+		})
+	}
+	sort.SliceStable(stale, func(i, j int) bool {
+		if stale[i].AgeDays != stale[j].AgeDays {
+			return stale[i].AgeDays > stale[j].AgeDays
+		}
+		return stale[i].FilePath < stale[j].FilePath
+	})
+	if limit > 0 && len(stale) > limit {
+		stale = stale[:limit]
+	}
+
+	c.JSON(http.StatusOK, staleResponse{Months: months, Before: before, Documents: stale, Count: len(stale)})
+}
+
+// getDocument returns the registry record of a document
+func getDocument(c *gin.Context) {
+	record, ok := lookupDocument(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// documentContent returns the full extracted text of a document from the
+// content store
+func documentContent(c *gin.Context) {
+	record, ok := lookupDocument(c)
+	if !ok {
+		return
+	}
+	if contentStore == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "content store is disabled"})
+		return
+	}
+
+	r, info, err := contentStore.Get(c.Request.Context(), record.FileHash)
+	if errors.Is(err, contentstore.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no content stored for document " + record.ID})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to read document content", zap.String("document_id", record.ID), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer r.Close()
+
+	headers := map[string]string{}
+	if info.Encoding != "" {
+		headers["X-Source-Encoding"] = info.Encoding
+	}
+	c.DataFromReader(http.StatusOK, info.Size, "text/plain; charset=utf-8", r, headers)
+}
+
+// documentThumbnail returns the PNG thumbnail of an image document from
+// the content store
+func documentThumbnail(c *gin.Context) {
+	record, ok := lookupDocument(c)
+	if !ok {
+		return
+	}
+	if contentStore == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "content store is disabled"})
+		return
+	}
+	if record.Image == nil || !record.Image.Thumbnail {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no thumbnail stored for document " + record.ID})
+		return
+	}
+
+	data, err := contentStore.GetThumbnail(c.Request.Context(), record.FileHash)
+	if errors.Is(err, contentstore.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no thumbnail stored for document " + record.ID})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to read document thumbnail", zap.String("document_id", record.ID), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Data(http.StatusOK, "image/png", data)
+}
+
+// documentLink is a document linked to or from another by a wiki-link
+type documentLink struct {
+	Target     string `json:"target,omitempty"` // the link as written, for outgoing links
+	DocumentID string `json:"document_id"`
+	FilePath   string `json:"file_path"`
+	Title      string `json:"title,omitempty"`
+}
+
+// documentLinksResponse is the response body of the document links endpoint
+type documentLinksResponse struct {
+	DocumentID string         `json:"document_id"`
+	Links      []documentLink `json:"links"`
+	Backlinks  []documentLink `json:"backlinks"`
+	Unresolved []string       `json:"unresolved,omitempty"` // link targets matching no document
+}
+
+// documentLinks returns the documents a note's wiki-links point to and the
+// notes linking to it, resolved against the whole registry. Only documents
+// the caller may read are listed; links to others are listed as unresolved.
+func documentLinks(c *gin.Context) {
+	record, ok := lookupDocument(c)
+	if !ok {
+		return
+	}
+	records, err := documentRegistry.List(c.Request.Context(), registry.Filter{})
+	if err != nil {
+		logger.Error("Failed to list documents", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	readable := readableBy(c)
+	resolver := notes.NewResolver()
+	byID := make(map[string]*registry.Record, len(records))
+	for _, r := range records {
+		resolver.Add(r.ID, r.FilePath, r.Aliases)
+		byID[r.ID] = r
+	}
+	link := func(target string, r *registry.Record) documentLink {
+		return documentLink{Target: target, DocumentID: r.ID, FilePath: r.FilePath, Title: r.Title}
+	}
+
+	resp := documentLinksResponse{DocumentID: record.ID, Links: []documentLink{}, Backlinks: []documentLink{}}
+	for _, target := range record.Links {
+		if id, ok := resolver.Resolve(record.FilePath, target); ok && readable(byID[id]) {
+			resp.Links = append(resp.Links, link(target, byID[id]))
+		} else {
+			resp.Unresolved = append(resp.Unresolved, target)
+		}
+	}
+	for _, r := range records {
+		if r.ID == record.ID || !readable(r) {
+			continue
+		}
+		for _, target := range r.Links {
+			if id, ok := resolver.Resolve(r.FilePath, target); ok && id == record.ID {
+				resp.Backlinks = append(resp.Backlinks, link("", r))
+				break
+			}
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// documentStatus returns the processing state of a document. With a wait
+// it first waits up to that long for the state to change, unless the
+// document is already indexed or failed.
+func documentStatus(c *gin.Context) {
+	wait, err := longpoll.ParseWait(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	record, ok := lookupDocument(c)
+	if !ok {
+		return
+	}
+	if wait > 0 && record.State != models.StateIndexed && record.State != models.StateFailed {
+		longpoll.Extend(c, wait)
+		_, err := longpoll.Until(c.Request.Context(), wait, func(ctx context.Context) (bool, error) {
+			current, err := documentRegistry.Get(ctx, record.ID)
+			if err != nil {
+				return false, err
+			}
+			changed := current.State != record.State
+			record = current
+			return changed, nil
+		})
+		if err != nil {
+			logger.Error("Failed to read document registry", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": record.ID,
+		"status":      record.State,
+		"error":       record.Error,
+		"created_at":  record.CreatedAt,
+		"updated_at":  record.UpdatedAt,
+	})
+}
+
+// reindexDocument processes a document's file again in the background.
+// The new version gets its own document ID; chunk deduplication passes over
+// the vectors of the version it replaces, so the new version stores its
+// chunks before the old one is superseded.
+func reindexDocument(c *gin.Context) {
+	record, ok := lookupDocument(c)
+	if !ok {
+		return
+	}
+
+	p, err := documentProcessor()
+	if err != nil {
+		logger.Error("Failed to create document processor", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionReindex, record.FilePath)
+	event.DocumentIDs = []string{record.ID}
+	event.Details["client_ip"] = c.ClientIP()
+
+	go func() {
+		ctx := context.Background()
+		if err := p.ProcessFile(ctx, record.FilePath, true); err != nil {
+			logger.Error("Failed to reindex document",
+				zap.String("document_id", record.ID),
+				zap.String("file", record.FilePath),
+				zap.Error(err))
+			event.Outcome = audit.OutcomeFailure
+			event.Details["error"] = err.Error()
+		}
+		auditRecorder.Record(ctx, event)
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":      "accepted",
+		"document_id": record.ID,
+		"file_path":   record.FilePath,
+		"message":     "Reindexing started",
+	})
+}
+
+// rerunRequest is the request body of the rechunk and resummarize endpoints
+type rerunRequest struct {
+	DocumentIDs []string `json:"document_ids" binding:"required,min=1,max=1000" description:"Registry IDs of the documents, at most 1000"`
+}
+
+// rerunResponse is the response body of the rechunk and resummarize endpoints
+type rerunResponse struct {
+	Status   string            `json:"status"`
+	Accepted []string          `json:"accepted"`
+	Rejected map[string]string `json:"rejected,omitempty"` // document ID to the reason it was not accepted
+}
+
+// rechunkDocuments chunks and embeds documents again from their stored
+// content in the background
+func rechunkDocuments(c *gin.Context) {
+	rerunDocuments(c, audit.ActionRechunk, (*orchestrator.DocumentProcessor).Rechunk)
+}
+
+// resummarizeDocuments generates document summaries again from their
+// stored content in the background
+func resummarizeDocuments(c *gin.Context) {
+	rerunDocuments(c, audit.ActionResummarize, (*orchestrator.DocumentProcessor).Resummarize)
+}
+
+// rerunDocuments runs one processing stage again for documents whose
+// content is stored. Unknown documents and documents without stored content
+// are rejected; the rest are processed in order in the background, each
+// recorded as an audit event.
+func rerunDocuments(c *gin.Context, action audit.Action, stage func(*orchestrator.DocumentProcessor, context.Context, string) (*registry.Record, error)) {
+	var req rerunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if contentStore == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "content store is disabled"})
+		return
+	}
+
+	p, err := documentProcessor()
+	if err != nil {
+		logger.Error("Failed to create document processor", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := rerunResponse{Status: "accepted", Accepted: []string{}, Rejected: map[string]string{}}
+	paths := make(map[string]string, len(req.DocumentIDs))
+	for _, id := range req.DocumentIDs {
+		if _, seen := paths[id]; seen {
+			continue
+		}
+		record, err := documentRegistry.Get(c.Request.Context(), id)
+		switch {
+		case errors.Is(err, registry.ErrNotFound), err == nil && !readableBy(c)(record):
+			resp.Rejected[id] = "not found"
+		case err != nil:
+			resp.Rejected[id] = err.Error()
+		case !record.ContentStored:
+			resp.Rejected[id] = "content not stored"
+		default:
+			paths[id] = record.FilePath
+			resp.Accepted = append(resp.Accepted, id)
+		}
+	}
+
+	actor, clientIP := c.GetHeader("X-User-ID"), c.ClientIP()
+	go func() {
+		rerunMu.Lock()
+		defer rerunMu.Unlock()
+
+		ctx := context.Background()
+		for _, id := range resp.Accepted {
+			event := audit.NewEvent(actor, action, paths[id])
+			event.DocumentIDs = []string{id}
+			event.Details["client_ip"] = clientIP
+
+			record, err := stage(p, ctx, id)
+			if err != nil {
+				logger.Error("Failed to rerun document stage",
+					zap.String("action", string(action)),
+					zap.String("document_id", id),
+					zap.Error(err))
+				event.Outcome = audit.OutcomeFailure
+				event.Details["error"] = err.Error()
+			}
+			if record != nil && record.ID != id {
+				event.DocumentIDs = append(event.DocumentIDs, record.ID)
+			}
+			auditRecorder.Record(ctx, event)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// lookupDocument finds the record for the :id parameter, writing a 404 when
+// absent or when the caller may not read the document
+func lookupDocument(c *gin.Context) (*registry.Record, bool) {
+	id := c.Param("id")
+	record, err := documentRegistry.Get(c.Request.Context(), id)
+	if errors.Is(err, registry.ErrNotFound) || err == nil && !readableBy(c)(record) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document " + id + " not found"})
+		return nil, false
+	}
+	if err != nil {
+		logger.Error("Failed to read document registry", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return record, true
+}
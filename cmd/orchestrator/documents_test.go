@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/httpsec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+// documentsRouter serves the document endpoints from a registry holding a
+// public note linking to a private document of alice, and a document
+// indexed before ACLs were stored, under a rule for the hr group
+func documentsRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger = zap.NewNop()
+	appConfig = &config.Config{
+		ACL: config.ACLConfig{
+			DefaultVisibility: string(models.VisibilityPublic),
+			Rules:             []config.ACLRule{{PathPrefix: "/data/hr", Groups: []string{"hr"}, Visibility: string(models.VisibilityPrivate)}},
+		},
+		Services: config.ServicesConfig{AdminToken: "admin-secret"},
+	}
+
+	store := registry.NewMemoryStore()
+	records := []*registry.Record{
+		{ID: "note", FilePath: "/data/notes/index.md", Links: []string{"plans"}, ACL: &models.ACL{Visibility: models.VisibilityPublic}},
+		{ID: "private", FilePath: "/data/notes/plans.md", ACL: &models.ACL{Owner: "alice", Visibility: models.VisibilityPrivate}},
+		{ID: "legacy", FilePath: "/data/hr/salaries.md"},
+	}
+	for _, r := range records {
+		if err := store.Put(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	documentRegistry = store
+
+	router := gin.New()
+	router.GET("/documents", listDocuments)
+	router.GET("/documents/:id", getDocument)
+	router.GET("/documents/:id/links", documentLinks)
+	return router
+}
+
+// caller sets the identity headers of a request
+type caller struct {
+	user, groups, admin string
+}
+
+func (c caller) get(t *testing.T, router *gin.Engine, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if c.user != "" {
+		req.Header.Set("X-User-ID", c.user)
+	}
+	if c.groups != "" {
+		req.Header.Set("X-User-Groups", c.groups)
+	}
+	if c.admin != "" {
+		req.Header.Set(httpsec.HeaderAdminToken, c.admin)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetDocumentACL(t *testing.T) {
+	router := documentsRouter(t)
+
+	tests := []struct {
+		name   string
+		caller caller
+		id     string
+		want   int
+	}{
+		{"owner reads private document", caller{user: "alice"}, "private", http.StatusOK},
+		{"other user gets 404", caller{user: "bob"}, "private", http.StatusNotFound},
+		{"anonymous caller gets 404", caller{}, "private", http.StatusNotFound},
+		{"admin reads private document", caller{admin: "admin-secret"}, "private", http.StatusOK},
+		{"wrong admin token gets 404", caller{user: "bob", admin: "guess"}, "private", http.StatusNotFound},
+		{"group member reads document by rule", caller{user: "bob", groups: "hr"}, "legacy", http.StatusOK},
+		{"non-member gets 404 by rule", caller{user: "bob", groups: "eng"}, "legacy", http.StatusNotFound},
+		{"public document", caller{}, "note", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := tt.caller.get(t, router, "/documents/"+tt.id); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestListDocumentsACL(t *testing.T) {
+	router := documentsRouter(t)
+
+	tests := []struct {
+		name   string
+		caller caller
+		path   string
+		want   []string
+	}{
+		{"other user sees public documents", caller{user: "bob"}, "/documents", []string{"note"}},
+		{"owner sees own document", caller{user: "alice"}, "/documents", []string{"note", "private"}},
+		{"group member sees documents by rule", caller{user: "carol", groups: "hr"}, "/documents", []string{"legacy", "note"}},
+		{"limit counts readable documents", caller{user: "alice"}, "/documents?limit=1&path=/data/notes", []string{"note"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := tt.caller.get(t, router, tt.path)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+			}
+			var resp documentsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range resp.Documents {
+				got = append(got, r.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("documents = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("documents = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDocumentLinksHideUnreadableTargets(t *testing.T) {
+	router := documentsRouter(t)
+
+	var resp documentLinksResponse
+	w := caller{user: "bob"}.get(t, router, "/documents/note/links")
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Links) != 0 {
+		t.Errorf("links = %+v, want the private target left out", resp.Links)
+	}
+
+	w = caller{user: "alice"}.get(t, router, "/documents/note/links")
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Links) != 1 || resp.Links[0].DocumentID != "private" {
+		t.Errorf("links = %+v, want the private target", resp.Links)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"go.uber.org/zap"
+)
+
+// duplicateReport lists indexed documents at different paths with
+// identical hashes or highly similar summary embeddings
+func duplicateReport(c *gin.Context) {
+	threshold := orchestrator.DefaultDuplicateThreshold
+	if value := c.Query("threshold"); value != "" {
+		t, err := strconv.ParseFloat(value, 64)
+		if err != nil || t <= 0 || t > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be a number above 0 and at most 1"})
+			return
+		}
+		threshold = t
+	}
+
+	p, err := documentProcessor()
+	if err != nil {
+		logger.Error("Failed to create document processor", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := p.Duplicates(c.Request.Context(), threshold)
+	if err != nil {
+		logger.Error("Failed to generate duplicate report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
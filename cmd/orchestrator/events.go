@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/events"
+	"go.uber.org/zap"
+)
+
+// maxEventsPage caps the events returned by a request
+const maxEventsPage = 1000
+
+// eventsResponse is the response body of the event stream endpoint
+type eventsResponse struct {
+	Events []events.Event `json:"events"`
+	// Next is the ID to send as after to read on; it stays at after when
+	// no event follows yet
+	Next string `json:"next"`
+}
+
+// listEvents returns the pipeline events after a stream ID, oldest first,
+// for consumers that poll rather than read the Redis stream
+func listEvents(c *gin.Context) {
+	if !eventBus.Enabled() {
+		c.JSON(http.StatusConflict, gin.H{"error": "the event stream is disabled"})
+		return
+	}
+	limit := 100
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxEventsPage {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+	var types []string
+	if value := c.Query("type"); value != "" {
+		types = strings.Split(value, ",")
+	}
+
+	after := c.Query("after")
+	list, next, err := eventBus.Read(c.Request.Context(), after, int64(limit), types...)
+	if err != nil {
+		logger.Error("Failed to read events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, eventsResponse{Events: list, Next: next})
+}
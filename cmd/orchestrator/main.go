@@ -2,22 +2,82 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/chunkstore"
+	"github.com/nadeeshame/rag-knowledge-service/internal/collections"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/contentstore"
+	"github.com/nadeeshame/rag-knowledge-service/internal/digest"
+	"github.com/nadeeshame/rag-knowledge-service/internal/dlq"
+	"github.com/nadeeshame/rag-knowledge-service/internal/events"
+	"github.com/nadeeshame/rag-knowledge-service/internal/httpsec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/idempotency"
+	"github.com/nadeeshame/rag-knowledge-service/internal/imagesearch"
+	"github.com/nadeeshame/rag-knowledge-service/internal/locks"
 	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"github.com/nadeeshame/rag-knowledge-service/internal/quota"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/routing"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+	"github.com/nadeeshame/rag-knowledge-service/internal/sparse"
+	"github.com/nadeeshame/rag-knowledge-service/internal/tlsconfig"
+	"github.com/nadeeshame/rag-knowledge-service/internal/topics"
+	"github.com/nadeeshame/rag-knowledge-service/internal/wal"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/health"
 	"go.uber.org/zap"
 )
 
-var logger *zap.Logger
+var (
+	logger           *zap.Logger
+	appConfig        *config.Config
+	auditRecorder    *audit.Recorder
+	documentRegistry registry.Store
+	collectionStore  collections.Store
+	runStore         runs.Store
+	eventBus         *events.Bus
+	locker           *locks.Locker
+	ingestBacklog    orchestrator.Backlog
+	dlqStore         dlq.Store
+	chunkStore       chunkstore.Store
+	upsertLog        wal.Store
+	contentStore     contentstore.Store
+	imageIndex       *imagesearch.Index
+	categoryRouter   *routing.Router
+	sparseEncoder    sparse.Encoder
+	digestService    *digest.Service
+	topicsService    *topics.Service
+	scannerClient    client.Scanner
+)
+
+// errScanner wraps failures of the document scanner service
+var errScanner = errors.New("document scanner request failed")
+
+// queuedVersions holds, by directory, the version of the last change scan
+// whose files were all queued. It is sent as since, so that changes
+// scanned but not queued, as when the queue was full, are listed again.
+var queuedVersions sync.Map
 
 func main() {
+	// Sandboxed extractions run in a copy of this process
+	if processors.SandboxRequested() {
+		os.Exit(processors.RunSandbox())
+	}
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -25,6 +85,11 @@ func main() {
 }
 
 func run() error {
+	// Print the OpenAPI document instead of serving when asked
+	if apispec.Requested() {
+		return apiSpec.Write(os.Stdout)
+	}
+
 	// Initialize logger
 	var err error
 	logger, err = zap.NewProduction()
@@ -44,6 +109,185 @@ func run() error {
 		zap.String("version", "1.0.0"),
 		zap.Int("port", cfg.Server.Port))
 
+	// Initialize audit log
+	auditStore, err := audit.NewStore(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create audit store", zap.Error(err))
+		return fmt.Errorf("failed to create audit store: %w", err)
+	}
+	auditRecorder = audit.NewRecorder(auditStore, logger)
+
+	// Initialize document registry
+	documentRegistry, err = registry.NewStore(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create document registry", zap.Error(err))
+		return fmt.Errorf("failed to create document registry: %w", err)
+	}
+	defer documentRegistry.Close() //nolint:errcheck
+
+	// Initialize collections, kept beside the registry
+	collectionStore, err = collections.NewStore(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create collection store", zap.Error(err))
+		return fmt.Errorf("failed to create collection store: %w", err)
+	}
+	defer collectionStore.Close() //nolint:errcheck
+
+	// Initialize indexing run history, kept beside the registry
+	runStore, err = runs.NewStore(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create run store", zap.Error(err))
+		return fmt.Errorf("failed to create run store: %w", err)
+	}
+	defer runStore.Close() //nolint:errcheck
+
+	// Publish pipeline events to a Redis stream (optional)
+	eventBus, err = events.New(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create event bus", zap.Error(err))
+		return fmt.Errorf("failed to create event bus: %w", err)
+	}
+	defer eventBus.Close() //nolint:errcheck
+
+	// Coordinate replicas through Redis locks (optional)
+	locker, err = locks.New(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create locker", zap.Error(err))
+		return fmt.Errorf("failed to create locker: %w", err)
+	}
+	defer locker.Close() //nolint:errcheck
+
+	// Hold the ingest queue in memory, or in Redis shared by the replicas
+	ingestBacklog, err = orchestrator.NewBacklog(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create ingest queue", zap.Error(err))
+		return fmt.Errorf("failed to create ingest queue: %w", err)
+	}
+	defer ingestBacklog.Close() //nolint:errcheck
+
+	// Initialize the dead-letter list of files that failed every retry
+	dlqStore, err = dlq.NewStore(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create dead-letter store", zap.Error(err))
+		return fmt.Errorf("failed to create dead-letter store: %w", err)
+	}
+	defer dlqStore.Close() //nolint:errcheck
+
+	// Initialize the responses replayed to clients retrying a mutation
+	idempotencyStore, err := idempotency.NewStore(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create idempotency store", zap.Error(err))
+		return fmt.Errorf("failed to create idempotency store: %w", err)
+	}
+	if idempotencyStore != nil {
+		defer idempotencyStore.Close() //nolint:errcheck
+	}
+
+	// Count tenant ingestion quotas, shared by the replicas with the registry (optional)
+	quotaTracker, err := quota.New(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create quota tracker", zap.Error(err))
+		return fmt.Errorf("failed to create quota tracker: %w", err)
+	}
+	defer quotaTracker.Close() //nolint:errcheck
+
+	// Initialize chunk store for content too large for vector metadata (optional)
+	chunkStore, err = chunkstore.NewStore(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create chunk store", zap.Error(err))
+		return fmt.Errorf("failed to create chunk store: %w", err)
+	}
+	if chunkStore != nil {
+		defer chunkStore.Close() //nolint:errcheck
+	}
+
+	// Initialize write-ahead log of vectors waiting to be upserted (optional)
+	upsertLog, err = wal.NewStore(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create write-ahead log", zap.Error(err))
+		return fmt.Errorf("failed to create write-ahead log: %w", err)
+	}
+	if upsertLog != nil {
+		defer upsertLog.Close() //nolint:errcheck
+	}
+
+	// Initialize content store for extracted text (optional)
+	contentStore, err = contentstore.NewStore(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create content store", zap.Error(err))
+		return fmt.Errorf("failed to create content store: %w", err)
+	}
+	if contentStore != nil {
+		defer contentStore.Close() //nolint:errcheck
+	}
+
+	// Initialize multimodal image embeddings (optional)
+	imageIndex, err = imagesearch.New(cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create image index", zap.Error(err))
+		return fmt.Errorf("failed to create image index: %w", err)
+	}
+
+	// Route the chunk vectors of file categories to their own indexes (optional)
+	categoryRouter, err = routing.New(cfg, logger)
+	if err != nil {
+		logger.Error("Failed to create category router", zap.Error(err))
+		return fmt.Errorf("failed to create category router: %w", err)
+	}
+
+	// Store sparse term vectors beside chunk embeddings (optional)
+	sparseEncoder, err = sparse.New(cfg)
+	if err != nil {
+		logger.Error("Failed to create sparse encoder", zap.Error(err))
+		return fmt.Errorf("failed to create sparse encoder: %w", err)
+	}
+	appConfig = cfg
+
+	// Certificates for serving over TLS and calling the other services
+	certs, err := tlsconfig.New(cfg, logger)
+	if err != nil {
+		logger.Error("Failed to load TLS certificates", zap.Error(err))
+		return fmt.Errorf("failed to load TLS certificates: %w", err)
+	}
+	serviceClient := tlsconfig.HTTPClient(certs)
+
+	// The document scanner reports the changes incremental runs index
+	scannerOpts := client.DefaultOptions()
+	scannerOpts.HTTPClient = serviceClient
+	scannerOpts.Timeout = 10 * time.Minute // hashing changed files of a large tree
+	scannerOpts.UserAgent = "repograph-orchestrator/1.0"
+	scannerClient = client.NewScannerClient(cfg.Services.DocumentScannerURL, scannerOpts)
+
+	// Initialize scheduled digest reports (optional)
+	if cfg.Digest.Enabled {
+		opts := client.DefaultOptions()
+		opts.HTTPClient = serviceClient
+		opts.Timeout = 2 * time.Minute // answers can take a while
+		opts.UserAgent = "repograph-orchestrator/1.0"
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, opts)
+		digestService, err = digest.NewService(cfg, querier, documentRegistry, auditRecorder, logger)
+		if err != nil {
+			logger.Error("Failed to create digest service", zap.Error(err))
+			return fmt.Errorf("failed to create digest service: %w", err)
+		}
+		digestCtx, stopDigests := context.WithCancel(context.Background())
+		defer stopDigests()
+		// Only the elected replica delivers scheduled digests
+		go locker.Lead(digestCtx, "scheduler:digests", digestService.Run)
+	}
+
+	// Initialize the topic overview of the corpus (optional)
+	if cfg.Topics.Enabled {
+		topicsService, err = topics.NewService(cfg, documentRegistry, auditRecorder, logger)
+		if err != nil {
+			logger.Error("Failed to create topic service", zap.Error(err))
+			return fmt.Errorf("failed to create topic service: %w", err)
+		}
+		topicsCtx, stopTopics := context.WithCancel(context.Background())
+		defer stopTopics()
+		go locker.Lead(topicsCtx, "scheduler:topics", topicsService.Run)
+	}
+
 	// Setup HTTP router
 	router := gin.Default()
 
@@ -62,18 +306,11 @@ func run() error {
 	})
 
 	// API endpoints
+	router.GET("/openapi.json", apiSpec.Serve())
 	v1 := router.Group("/api/v1")
-	{
-		v1.POST("/process/document", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{"status": "processing"})
-		})
-		v1.POST("/process/directory", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{"status": "processing"})
-		})
-		v1.GET("/status/:documentId", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{"status": "unknown"})
-		})
-	}
+	v1.Use(ingestOnly(httpsec.LimitBody(cfg.Ingest.MaxRequestBytes)), ingestOnly(quotaTracker.Middleware()),
+		idempotency.Middleware(idempotencyStore, cfg.Idempotency.TTL, logger))
+	apiSpec.Register(v1)
 
 	// Create HTTP server
 	srv := &http.Server{
@@ -86,7 +323,7 @@ func run() error {
 	// Start server in goroutine
 	go func() {
 		logger.Info("Server starting", zap.String("address", srv.Addr))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := tlsconfig.ListenAndServe(srv, certs); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
@@ -94,32 +331,71 @@ func run() error {
 	// Start automatic indexing in background
 	if cfg.App.DataDirectory != "" {
 		go func() {
+			// Create document processor
+			processor, procErr := documentProcessor()
+			if procErr != nil {
+				logger.Error("Failed to create document processor", zap.Error(procErr))
+				return
+			}
+
 			// Wait for services to be ready
-			logger.Info("Waiting for services to be ready before indexing...")
-			time.Sleep(10 * time.Second)
+			ctx := context.Background()
+			waitForDependencies(ctx, cfg, processor, serviceClient)
 
 			logger.Info("Starting automatic document indexing",
 				zap.String("directory", cfg.App.DataDirectory),
 				zap.Bool("skip_existing", cfg.App.SkipExistingDocuments))
 
-			// Create document processor
-			processor, procErr := orchestrator.NewDocumentProcessor(cfg, logger)
-			if procErr != nil {
-				logger.Error("Failed to create document processor", zap.Error(procErr))
-				return
-			}
+			// Store vectors left unflushed by a previous run first, so
+			// their files are found as already indexed
+			replayUpsertLog(ctx, processor)
 
 			// Process directory
-			ctx := context.Background()
-			procErr = processor.ProcessDirectory(ctx, cfg.App.DataDirectory)
+			event := audit.NewEvent("system", audit.ActionProcessDirectory, cfg.App.DataDirectory)
+			event.Details["trigger"] = "startup"
+
+			// With a shared queue the replicas split the directory's files;
+			// files already queued by another replica are left out
+			if cfg.Sharding.Enabled {
+				jobs, queueErr := ingestQueue.SubmitDirectory(ctx, cfg.App.DataDirectory, orchestrator.PriorityLow, false, processors.ExtractOptions{})
+				if queueErr == nil {
+					logger.Info("Queued directory for indexing across replicas", zap.Int("queued", len(jobs)))
+					event.Details["queued"] = strconv.Itoa(len(jobs))
+					auditRecorder.Record(ctx, event)
+					return
+				}
+				logger.Warn("Failed to queue directory, indexing it on this replica", zap.Error(queueErr))
+			}
+			result, procErr := processor.ProcessDirectory(ctx, cfg.App.DataDirectory, false)
+			if errors.Is(procErr, orchestrator.ErrDirectoryLocked) {
+				logger.Info("Skipped automatic indexing, another replica is indexing the directory", zap.Error(procErr))
+				return
+			}
 			if procErr != nil {
 				logger.Error("Failed to process directory", zap.Error(procErr))
+				event.Outcome = audit.OutcomeFailure
+				event.Details["error"] = procErr.Error()
 			} else {
 				logger.Info("Automatic indexing completed successfully")
+				event.Details["run_id"] = result.RunID
+				event.Details["cancelled"] = strconv.FormatBool(result.Cancelled)
+				event.Details["processed"] = strconv.Itoa(result.Processed)
+				event.Details["failed"] = strconv.Itoa(result.Failed)
 			}
+			auditRecorder.Record(ctx, event)
 		}()
 	} else {
 		logger.Warn("DATA_DIRECTORY not set, automatic indexing disabled")
+		if upsertLog != nil {
+			go func() {
+				processor, procErr := documentProcessor()
+				if procErr != nil {
+					logger.Error("Failed to create document processor", zap.Error(procErr))
+					return
+				}
+				replayUpsertLog(context.Background(), processor)
+			}()
+		}
 	}
 
 	// Wait for interrupt signal
@@ -140,3 +416,297 @@ func run() error {
 	logger.Info("Server exited")
 	return nil
 }
+
+// waitForDependencies waits up to STARTUP_WAIT for Azure OpenAI, Pinecone
+// and the content extractor to answer. Indexing starts anyway when they do
+// not, and the files that fail are reported by the run.
+func waitForDependencies(ctx context.Context, cfg *config.Config, processor *orchestrator.DocumentProcessor, httpClient *http.Client) {
+	if cfg.App.StartupWait == 0 {
+		return
+	}
+
+	checker := health.NewChecker(cfg.Azure.OpenAIEndpoint, cfg.Pinecone.APIKey, cfg.Google.VisionAPIKey, nil, nil)
+	checker.AddProbe("pinecone", "Unable to reach Pinecone", func(ctx context.Context) bool {
+		return processor.PineconeReady(ctx) == nil
+	})
+	services := []string{"azure_openai", "pinecone"}
+	if cfg.Services.ContentExtractorURL != "" {
+		checker.AddProbe("content_extractor", "Unable to reach content extractor",
+			health.HTTPProbe(httpClient, strings.TrimSuffix(cfg.Services.ContentExtractorURL, "/")+"/health"))
+		services = append(services, "content_extractor")
+	}
+
+	logger.Info("Waiting for services to be ready before indexing",
+		zap.Strings("services", services),
+		zap.Duration("timeout", cfg.App.StartupWait))
+	start := time.Now()
+	if err := checker.WaitFor(ctx, cfg.App.StartupWait, services...); err != nil {
+		logger.Warn("Starting indexing without all services ready", zap.Error(err))
+		return
+	}
+	logger.Info("Services ready", zap.Duration("waited", time.Since(start)))
+}
+
+// processDocumentRequest is the request body of the document processing endpoint
+type processDocumentRequest struct {
+	FilePath       string `json:"file_path" binding:"required"`
+	ForceReprocess bool   `json:"force_reprocess"`
+	// Priority is high for interactive uploads and low for bulk backfills
+	Priority string `json:"priority" binding:"omitempty,oneof=high normal low"`
+	// Options change how the file is extracted; those its processor does
+	// not honour are ignored
+	Options processors.ExtractOptions `json:"options"`
+}
+
+// processDirectoryRequest is the request body of the directory processing endpoint
+type processDirectoryRequest struct {
+	Directory      string `json:"directory" binding:"required"`
+	Recursive      bool   `json:"recursive"`
+	ForceReprocess bool   `json:"force_reprocess"`
+	Priority       string `json:"priority" binding:"omitempty,oneof=high normal low"`
+	// Incremental queues only the files the document scanner reports added
+	// or modified since its last change scan of the directory
+	Incremental bool `json:"incremental"`
+	// Options change how the files are extracted; each file gets those
+	// its processor honours
+	Options processors.ExtractOptions `json:"options"`
+}
+
+// queuedResponse is the response body of the processing endpoints
+type queuedResponse struct {
+	Status   string                `json:"status"`
+	Priority orchestrator.Priority `json:"priority"`
+	Queued   int                   `json:"queued"`
+	JobID    string                `json:"job_id,omitempty"` // set when a single file is queued
+	// Deleted lists the files an incremental run found removed since the
+	// last change scan; their documents stay indexed
+	Deleted []string `json:"deleted,omitempty"`
+}
+
+// processDocument queues a file under the data roots for processing at
+// the requested priority
+func processDocument(c *gin.Context) {
+	var req processDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := orchestrator.ConfinePath(orchestrator.DataRoots(appConfig), req.FilePath); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, ok := queueFiles(c, audit.ActionProcessDocument, req.Priority, func(q *orchestrator.IngestQueue, priority orchestrator.Priority) ([]*orchestrator.Job, error) {
+		return submitCharged(c, q, []string{req.FilePath}, priority, req.ForceReprocess, req.Options)
+	})
+	if ok {
+		c.JSON(http.StatusAccepted, resp)
+	}
+}
+
+// processDirectory queues the files in a directory under the data roots
+// for processing at the requested priority. An incremental request queues the files changed
+// since the last one, as listed by a single change scan of the document
+// scanner.
+func processDirectory(c *gin.Context) {
+	var req processDirectoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := orchestrator.ConfinePath(orchestrator.DataRoots(appConfig), req.Directory); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var deleted []string
+	resp, ok := queueFiles(c, audit.ActionProcessDirectory, req.Priority, func(q *orchestrator.IngestQueue, priority orchestrator.Priority) ([]*orchestrator.Job, error) {
+		if !req.Incremental {
+			paths, err := q.ScanDirectory(req.Directory)
+			if err != nil {
+				return nil, err
+			}
+			return submitCharged(c, q, confinedPaths(paths), priority, req.ForceReprocess, req.Options)
+		}
+		var since string
+		if version, ok := queuedVersions.Load(req.Directory); ok {
+			since = version.(string)
+		}
+		changes, err := scannerClient.ScanChanges(c.Request.Context(), req.Directory, since)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errScanner, err)
+		}
+		deleted = changes.Deleted
+		logger.Info("Queueing directory changes",
+			zap.String("directory", changes.Directory),
+			zap.Int("added", len(changes.Added)),
+			zap.Int("modified", len(changes.Modified)),
+			zap.Int("deleted", len(changes.Deleted)),
+			zap.Int("unchanged", changes.Unchanged))
+		jobs, err := submitCharged(c, q, confinedPaths(changes.Changed()), priority, req.ForceReprocess, req.Options)
+		if err == nil {
+			queuedVersions.Store(req.Directory, changes.Version)
+		}
+		return jobs, err
+	})
+	if ok {
+		resp.Deleted = deleted
+		c.JSON(http.StatusAccepted, resp)
+	}
+}
+
+// confinedPaths drops the scanned files that resolve outside the data
+// roots, such as symlinks pointing out of the directory
+func confinedPaths(paths []string) []string {
+	roots := orchestrator.DataRoots(appConfig)
+	confined := paths[:0]
+	for _, path := range paths {
+		if orchestrator.ConfinePath(roots, path) == nil {
+			confined = append(confined, path)
+		}
+	}
+	return confined
+}
+
+// ingestPaths are the endpoints that queue files for ingestion, whose
+// request sizes and daily quotas are limited
+var ingestPaths = []string{"/api/v1/process/document", "/api/v1/process/directory", "/api/v1/process/batch"}
+
+// ingestOnly applies a middleware to the ingestion endpoints only
+func ingestOnly(middleware gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(ingestPaths, c.FullPath()) {
+			middleware(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// queueFiles submits files to the ingest queue and returns the response
+// to answer 202 with. It answers errors itself, with 503 when the queue is
+// full, and then returns false.
+func queueFiles(c *gin.Context, action audit.Action, name string, submit func(*orchestrator.IngestQueue, orchestrator.Priority) ([]*orchestrator.Job, error)) (*queuedResponse, bool) {
+	priority, err := orchestrator.ParsePriority(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if _, ok := runProcessor(c); !ok {
+		return nil, false
+	}
+
+	jobs, err := submit(ingestQueue, priority)
+	var exceeded *quota.ExceededError
+	switch {
+	case errors.As(err, &exceeded):
+		exceeded.Respond(c)
+		return nil, false
+	case errors.Is(err, orchestrator.ErrQueueFull):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return nil, false
+	case errors.Is(err, errScanner):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return nil, false
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), action, c.Request.URL.Path)
+	event.Details["client_ip"] = c.ClientIP()
+	event.Details["priority"] = string(priority)
+	event.Details["queued"] = strconv.Itoa(len(jobs))
+	auditRecorder.Record(c.Request.Context(), event)
+
+	resp := &queuedResponse{Status: "queued", Priority: priority, Queued: len(jobs)}
+	if len(jobs) == 1 {
+		resp.JobID = jobs[0].ID
+	}
+	return resp, true
+}
+
+// submitCharged charges files to the tenant quota of a request and queues
+// them, refunding those that were not queued
+func submitCharged(c *gin.Context, q *orchestrator.IngestQueue, paths []string, priority orchestrator.Priority, force bool, options processors.ExtractOptions) ([]*orchestrator.Job, error) {
+	if err := quota.Charge(c, paths); err != nil {
+		return nil, err
+	}
+	jobs, err := q.Submit(c.Request.Context(), paths, priority, force, options)
+	if err != nil {
+		quota.Refund(c, paths)
+		return nil, err
+	}
+	queued := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		queued[job.FilePath] = true
+	}
+	var unqueued []string
+	for _, path := range paths {
+		if !queued[path] {
+			unqueued = append(unqueued, path)
+		}
+	}
+	quota.Refund(c, unqueued)
+	return jobs, nil
+}
+
+// recordAdminAction records an administrative request in the audit log.
+// The acting user is taken from the X-User-ID header set by the auth proxy.
+func recordAdminAction(c *gin.Context, action audit.Action) {
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), action, c.Request.URL.Path)
+	event.Details["client_ip"] = c.ClientIP()
+	auditRecorder.Record(c.Request.Context(), event)
+}
+
+// adminOnly serves a handler only to requests carrying the admin token,
+// which the gateway sends for admin clients
+func adminOnly(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if httpsec.RequireAdmin(c, appConfig.Services.AdminToken) {
+			handler(c)
+		}
+	}
+}
+
+// listAuditEvents returns audit events filtered by actor, action and time
+// range. The log holds every user's queries, so it is served only to admin
+// requests.
+func listAuditEvents(c *gin.Context) {
+	if !auditRecorder.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "audit log is disabled"})
+		return
+	}
+
+	filter := audit.Filter{
+		Actor:  c.Query("actor"),
+		Action: audit.Action(c.Query("action")),
+	}
+	for param, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s: expected RFC 3339 timestamp", param)})
+			return
+		}
+		*target = &t
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	events, err := auditRecorder.Query(c.Request.Context(), filter)
+	if err != nil {
+		logger.Error("Failed to query audit log", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events, "count": len(events)})
+}
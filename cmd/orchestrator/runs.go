@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/longpoll"
+	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+	"go.uber.org/zap"
+)
+
+// runSummary describes an indexing run without its changes
+type runSummary struct {
+	ID         string    `json:"id"`
+	Directory  string    `json:"directory"`
+	Batch      bool      `json:"batch,omitempty"` // processes a list of files rather than a directory
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Total      int       `json:"total_files"`
+	Processed  int       `json:"processed"`
+	Skipped    int       `json:"skipped"`
+	Failed     int       `json:"failed"`
+	Added      int       `json:"added"`
+	Updated    int       `json:"updated"`
+	Remaining  int       `json:"remaining,omitempty"` // files a cancelled run did not reach
+	// DurationMs is the run's wall time; StageMs the time its files spent
+	// in each stage
+	DurationMs int64            `json:"duration_ms"`
+	StageMs    map[string]int64 `json:"stage_ms"`
+	Tokens     runs.TokenUsage  `json:"tokens"`
+}
+
+// newRunSummary describes a run without its changes and file reports
+func newRunSummary(run *runs.Run) runSummary {
+	added, updated := run.Counts()
+	tokens, stages := run.Usage()
+	summary := runSummary{
+		ID:         run.ID,
+		Directory:  run.Directory,
+		Batch:      run.Batch,
+		Status:     run.Status,
+		StartedAt:  run.StartedAt,
+		FinishedAt: run.FinishedAt,
+		Total:      run.Total,
+		Processed:  run.Processed,
+		Skipped:    run.Skipped,
+		Failed:     run.Failed,
+		Added:      added,
+		Updated:    updated,
+		Remaining:  len(run.Remaining),
+		StageMs:    stages,
+		Tokens:     tokens,
+	}
+	if !run.FinishedAt.IsZero() {
+		summary.DurationMs = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	}
+	return summary
+}
+
+// runsResponse is the response body of the run list endpoint
+type runsResponse struct {
+	Runs  []runSummary `json:"runs"`
+	Count int          `json:"count"`
+}
+
+// runChangesResponse is the response body of the run changes endpoint
+type runChangesResponse struct {
+	RunID      string        `json:"run_id"`
+	Directory  string        `json:"directory"`
+	Status     string        `json:"status"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Changes    []runs.Change `json:"changes"`
+	Count      int           `json:"count"`
+	Digest     string        `json:"digest,omitempty"`
+}
+
+// listRuns returns the most recent indexing runs, newest first
+func listRuns(c *gin.Context) {
+	limit := 20
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+
+	all, err := runStore.List(c.Request.Context(), limit)
+	if err != nil {
+		logger.Error("Failed to list runs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := runsResponse{Runs: make([]runSummary, 0, len(all)), Count: len(all)}
+	for _, run := range all {
+		resp.Runs = append(resp.Runs, newRunSummary(run))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// runReportResponse is the response body of the run report endpoint
+type runReportResponse struct {
+	runSummary
+	Files []runs.FileReport `json:"files"`
+}
+
+// getRun returns the report of an indexing run: its totals and the outcome,
+// stage durations and token usage of each file. With a wait it first waits
+// up to that long for a running run to finish a file or change status.
+func getRun(c *gin.Context) {
+	wait, err := longpoll.ParseWait(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	run, err := runStore.Get(c.Request.Context(), c.Param("id"))
+	if err == nil && wait > 0 && run.Status == runs.StatusRunning {
+		longpoll.Extend(c, wait)
+		_, err = longpoll.Until(c.Request.Context(), wait, func(ctx context.Context) (bool, error) {
+			current, err := runStore.Get(ctx, run.ID)
+			if err != nil {
+				return false, err
+			}
+			changed := current.Status != run.Status || len(current.Files) != len(run.Files)
+			run = current
+			return changed, nil
+		})
+	}
+	switch {
+	case errors.Is(err, runs.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("Failed to read run", zap.String("run_id", c.Param("id")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	files := run.Files
+	if files == nil {
+		files = []runs.FileReport{}
+	}
+	c.JSON(http.StatusOK, runReportResponse{runSummary: newRunSummary(run), Files: files})
+}
+
+// getRunChanges returns the documents an indexing run added or updated
+// with the digest of their new content
+func getRunChanges(c *gin.Context) {
+	run, err := runStore.Get(c.Request.Context(), c.Param("id"))
+	switch {
+	case errors.Is(err, runs.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("Failed to read run", zap.String("run_id", c.Param("id")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, runChangesResponse{
+		RunID:      run.ID,
+		Directory:  run.Directory,
+		Status:     run.Status,
+		StartedAt:  run.StartedAt,
+		FinishedAt: run.FinishedAt,
+		Changes:    run.Changes,
+		Count:      len(run.Changes),
+		Digest:     run.Digest,
+	})
+}
+
+// indexingResponse is the response body of the indexing control endpoints
+type indexingResponse struct {
+	Paused     bool                    `json:"paused"`
+	ActiveRuns []string                `json:"active_runs"`
+	Queue      orchestrator.QueueStats `json:"queue"`
+	// DegradedStages lists the optional stages skipped while their
+	// dependency keeps failing
+	DegradedStages []string `json:"degraded_stages"`
+}
+
+// newIndexingResponse describes the processor's indexing state
+func newIndexingResponse(ctx context.Context, p *orchestrator.DocumentProcessor) indexingResponse {
+	return indexingResponse{
+		Paused:         p.Paused(),
+		ActiveRuns:     p.ActiveRuns(),
+		Queue:          ingestQueue.Stats(ctx),
+		DegradedStages: p.DegradedStages(),
+	}
+}
+
+// indexingStatus reports whether indexing is paused, which runs are in
+// progress, how many queued files wait at each priority and which optional
+// stages are being skipped
+func indexingStatus(c *gin.Context) {
+	p, ok := runProcessor(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newIndexingResponse(c.Request.Context(), p))
+}
+
+// pauseIndexing makes directory runs wait before their next file
+func pauseIndexing(c *gin.Context) {
+	p, ok := runProcessor(c)
+	if !ok {
+		return
+	}
+	p.Pause()
+	recordAdminAction(c, audit.ActionIndexingPause)
+	c.JSON(http.StatusOK, newIndexingResponse(c.Request.Context(), p))
+}
+
+// resumeIndexing lets paused directory runs continue
+func resumeIndexing(c *gin.Context) {
+	p, ok := runProcessor(c)
+	if !ok {
+		return
+	}
+	p.Resume()
+	recordAdminAction(c, audit.ActionIndexingResume)
+	c.JSON(http.StatusOK, newIndexingResponse(c.Request.Context(), p))
+}
+
+// cancelRun stops a run in progress once its current file is done. The
+// run is recorded as cancelled with the files it did not reach.
+func cancelRun(c *gin.Context) {
+	p, ok := runProcessor(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	if err := p.CancelRun(id); err != nil {
+		if _, getErr := runStore.Get(c.Request.Context(), id); errors.Is(getErr, runs.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": getErr.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionRunCancel, id)
+	event.Details["client_ip"] = c.ClientIP()
+	auditRecorder.Record(c.Request.Context(), event)
+	c.JSON(http.StatusAccepted, gin.H{"status": "cancelling", "run_id": id})
+}
+
+// resumeRun continues a cancelled run in the background with the files it
+// did not reach
+func resumeRun(c *gin.Context) {
+	p, ok := runProcessor(c)
+	if !ok {
+		return
+	}
+	id := c.Param("id")
+	run, err := runStore.Get(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, runs.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("Failed to read run", zap.String("run_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	case run.Status != runs.StatusCancelled:
+		c.JSON(http.StatusConflict, gin.H{"error": orchestrator.ErrRunNotResumable.Error()})
+		return
+	}
+	if holder, err := p.RunHolder(c.Request.Context(), run); err == nil && holder != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": orchestrator.ErrDirectoryLocked.Error(), "holder": holder})
+		return
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionRunResume, id)
+	event.Details["directory"] = run.Directory
+	event.Details["client_ip"] = c.ClientIP()
+
+	go func() {
+		ctx := context.Background()
+		result, err := p.ResumeRun(ctx, id)
+		if err != nil {
+			logger.Error("Failed to resume run", zap.String("run_id", id), zap.Error(err))
+			event.Outcome = audit.OutcomeFailure
+			event.Details["error"] = err.Error()
+		} else {
+			event.Details["processed"] = strconv.Itoa(result.Processed)
+			event.Details["failed"] = strconv.Itoa(result.Failed)
+		}
+		auditRecorder.Record(ctx, event)
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":    "accepted",
+		"run_id":    id,
+		"remaining": len(run.Remaining),
+	})
+}
+
+// runProcessor returns the shared document processor, answering 503 when
+// it cannot be created
+func runProcessor(c *gin.Context) (*orchestrator.DocumentProcessor, bool) {
+	p, err := documentProcessor()
+	if err != nil {
+		logger.Error("Failed to create document processor", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return p, true
+}
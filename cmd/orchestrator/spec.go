@@ -0,0 +1,197 @@
+package main
+
+import (
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/collections"
+	"github.com/nadeeshame/rag-knowledge-service/internal/digest"
+	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/topics"
+)
+
+// apiSpec describes the endpoints served under /api/v1
+var apiSpec = apispec.New("Orchestrator Service", "1.0.0", "/api/v1",
+	apispec.Operation{
+		Method: "POST", Path: "/process/document", Tag: "ingest", Handler: processDocument,
+		Summary: "Queue a single document for processing",
+		Request: processDocumentRequest{}, Response: queuedResponse{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/process/directory", Tag: "ingest", Handler: processDirectory,
+		Summary: "Queue all documents in a directory for processing",
+		Request: processDirectoryRequest{}, Response: queuedResponse{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/process/batch", Tag: "ingest", Handler: processBatch,
+		Summary: "Process a list of files as one run, reporting the status of each",
+		Request: processBatchRequest{}, Response: batchResponse{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/status/:id", Tag: "ingest", Handler: documentStatus,
+		Summary: "Get the processing status of a document, waiting up to wait for it to change",
+		Query:   []string{"wait"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/documents", Tag: "documents", Handler: listDocuments,
+		Summary:  "List documents in the registry",
+		Response: documentsResponse{}, Query: []string{"category", "state", "path", "needs_enrichment", "limit"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/documents/stale", Tag: "documents", Handler: staleDocuments,
+		Summary:  "List indexed documents whose files have not been modified for months, oldest first",
+		Response: staleResponse{}, Query: []string{"months", "path", "limit"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/documents/:id", Tag: "documents", Handler: getDocument,
+		Summary:  "Get a document from the registry",
+		Response: registry.Record{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/documents/:id/content", Tag: "documents", Handler: documentContent,
+		Summary: "Get the extracted text of a document",
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/documents/:id/thumbnail", Tag: "documents", Handler: documentThumbnail,
+		Summary: "Get the PNG thumbnail of an image document",
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/documents/:id/links", Tag: "documents", Handler: documentLinks,
+		Summary:  "Get the wiki-links of a note and the notes linking to it",
+		Response: documentLinksResponse{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/documents/:id/reindex", Tag: "admin", Handler: adminOnly(reindexDocument),
+		Summary: "Process a document's file again",
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/documents/rechunk", Tag: "admin", Handler: adminOnly(rechunkDocuments),
+		Summary: "Chunk and embed documents again from their stored content",
+		Request: rerunRequest{}, Response: rerunResponse{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/documents/resummarize", Tag: "admin", Handler: adminOnly(resummarizeDocuments),
+		Summary: "Summarize documents again from their stored content",
+		Request: rerunRequest{}, Response: rerunResponse{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/collections", Tag: "collections", Handler: listCollections,
+		Summary:  "List collections",
+		Response: collectionsResponse{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/collections", Tag: "collections", Handler: createCollection,
+		Summary: "Create a collection",
+		Request: createCollectionRequest{}, Response: collections.Collection{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/collections/:name", Tag: "collections", Handler: getCollection,
+		Summary:  "Get a collection with the file paths of its documents",
+		Response: collections.Collection{},
+	},
+	apispec.Operation{
+		Method: "DELETE", Path: "/collections/:name", Tag: "admin", Handler: adminOnly(deleteCollection),
+		Summary: "Delete a collection",
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/collections/:name/documents", Tag: "collections", Handler: addCollectionDocuments,
+		Summary: "Add documents to a collection",
+		Request: collectionDocumentsRequest{}, Response: collectionUpdateResponse{},
+	},
+	apispec.Operation{
+		Method: "DELETE", Path: "/collections/:name/documents/:id", Tag: "collections", Handler: removeCollectionDocument,
+		Summary:  "Remove a document from a collection",
+		Response: collections.Collection{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/runs", Tag: "ingest", Handler: listRuns,
+		Summary:  "List recent indexing runs, newest first",
+		Response: runsResponse{}, Query: []string{"limit"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/runs/:id", Tag: "ingest", Handler: getRun,
+		Summary:  "Get the report of an indexing run: the outcome, stage durations and token usage of each file",
+		Response: runReportResponse{}, Query: []string{"wait"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/runs/:id/changes", Tag: "ingest", Handler: getRunChanges,
+		Summary:  "Get the documents an indexing run added or updated, with a digest of the new content",
+		Response: runChangesResponse{},
+	},
+	apispec.Operation{
+		Method: "DELETE", Path: "/runs/:id", Tag: "admin", Handler: adminOnly(cancelRun),
+		Summary: "Cancel a run in progress after its current file, keeping the files left for a resume",
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/runs/:id/resume", Tag: "admin", Handler: adminOnly(resumeRun),
+		Summary: "Resume a cancelled run with the files it did not reach",
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/indexing", Tag: "admin", Handler: adminOnly(indexingStatus),
+		Summary:  "Report whether indexing is paused and which runs are in progress",
+		Response: indexingResponse{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/indexing/pause", Tag: "admin", Handler: adminOnly(pauseIndexing),
+		Summary:  "Pause directory runs before their next file",
+		Response: indexingResponse{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/indexing/resume", Tag: "admin", Handler: adminOnly(resumeIndexing),
+		Summary:  "Resume paused directory runs",
+		Response: indexingResponse{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/dlq", Tag: "admin", Handler: adminOnly(listDeadLetters),
+		Summary:  "List files that failed every retry, most recent failure first",
+		Response: dlqResponse{}, Query: []string{"limit"},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/dlq/retry", Tag: "admin", Handler: adminOnly(retryDeadLetters),
+		Summary: "Queue dead-lettered files for processing again",
+		Request: dlqRetryRequest{}, Response: dlqRetryResponse{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/dlq/export", Tag: "admin", Handler: adminOnly(exportDeadLetters),
+		Summary: "Download the dead-letter list as JSON lines or CSV",
+		Query:   []string{"format"},
+	},
+	apispec.Operation{
+		Method: "DELETE", Path: "/dlq/:id", Tag: "admin", Handler: adminOnly(discardDeadLetter),
+		Summary: "Remove a dead-letter entry without retrying its file",
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/digests", Tag: "admin", Handler: adminOnly(listDigests),
+		Summary:  "List digest reports with their schedules",
+		Response: digestsResponse{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/digests/:name/run", Tag: "admin", Handler: adminOnly(runDigest),
+		Summary:  "Generate a digest report now and deliver it",
+		Response: digest.Result{}, Query: []string{"preview"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/duplicates", Tag: "admin", Handler: adminOnly(duplicateReport),
+		Summary:  "Report documents at different paths with identical or nearly identical content",
+		Response: orchestrator.DuplicateReport{}, Query: []string{"threshold"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/topics", Tag: "documents", Handler: getTopics,
+		Summary:  "Get the topic overview: clusters of similar documents, named by the model",
+		Response: topics.Overview{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/topics/run", Tag: "admin", Handler: adminOnly(runTopics),
+		Summary:  "Generate the topic overview now",
+		Response: topics.Overview{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/events", Tag: "admin", Handler: adminOnly(listEvents),
+		Summary:  "Read pipeline events (document state changes, run progress) after a stream ID",
+		Response: eventsResponse{}, Query: []string{"after", "limit", "type"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/audit", Tag: "admin", Handler: adminOnly(listAuditEvents),
+		Summary: "List audit log events",
+		Query:   []string{"actor", "action", "since", "until", "limit"},
+	},
+)
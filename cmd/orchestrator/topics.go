@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/topics"
+	"go.uber.org/zap"
+)
+
+// getTopics returns the last generated topic overview of the corpus
+func getTopics(c *gin.Context) {
+	if topicsService == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "the topic overview is disabled"})
+		return
+	}
+	overview, err := topicsService.Latest()
+	if errors.Is(err, topics.ErrNotGenerated) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, overview)
+}
+
+// runTopics generates the topic overview now
+func runTopics(c *gin.Context) {
+	if topicsService == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "the topic overview is disabled"})
+		return
+	}
+
+	event := audit.NewEvent(c.GetHeader("X-User-ID"), audit.ActionTopics, "")
+	event.Details["trigger"] = "manual"
+	event.Details["client_ip"] = c.ClientIP()
+
+	overview, err := topicsService.Generate(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to generate topic overview", zap.Error(err))
+		event.Outcome = audit.OutcomeFailure
+		event.Details["error"] = err.Error()
+		auditRecorder.Record(c.Request.Context(), event)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditRecorder.Record(c.Request.Context(), event)
+	c.JSON(http.StatusOK, overview)
+}
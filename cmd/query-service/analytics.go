@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/analytics"
+	"github.com/nadeeshame/rag-knowledge-service/internal/feedback"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"go.uber.org/zap"
+)
+
+// defaultAnalyticsPeriod is the period reported when since is not given
+const defaultAnalyticsPeriod = 7 * 24 * time.Hour
+
+// queryAnalytics aggregates the queries run between since (a week ago by
+// default) and until (now): the most frequent ones, those finding nothing,
+// latency percentiles and the ratings they got
+func queryAnalytics(c *gin.Context) {
+	if !analyticsRecorder.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "query analytics are disabled"})
+		return
+	}
+
+	until := time.Now().UTC()
+	since := until.Add(-defaultAnalyticsPeriod)
+	for param, target := range map[string]*time.Time{"since": &since, "until": &until} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s: expected RFC 3339 timestamp", param)})
+			return
+		}
+		*target = t
+	}
+	if !since.Before(until) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since must be before until"})
+		return
+	}
+	limit := 10
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	events, err := analyticsRecorder.List(c.Request.Context(), since, until)
+	if err != nil {
+		logger.Error("Failed to read query events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Ratings come after their query, so any given since it started count
+	ratings, err := feedbackStore.List(c.Request.Context(), feedback.Filter{Since: &since})
+	if err != nil {
+		logger.Error("Failed to list feedback", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics.Aggregate(events, ratings, since, until, limit))
+}
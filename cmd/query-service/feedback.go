@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/feedback"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"go.uber.org/zap"
+)
+
+// feedbackRequest is the request body of the feedback endpoint
+type feedbackRequest struct {
+	QueryID string `json:"query_id" binding:"required" description:"query_id of the rated answer"`
+	Rating  string `json:"rating" binding:"required" description:"up or down"`
+	Comment string `json:"comment"`
+	Query   string `json:"query" description:"question asked, kept so poorly rated queries can be evaluated again"`
+}
+
+// feedbackList is the response body of the feedback list endpoint
+type feedbackList struct {
+	Feedback []*feedback.Feedback `json:"feedback"`
+	Count    int                  `json:"count"`
+}
+
+// poorlyRatedList is the response body of the poorly rated queries endpoint
+type poorlyRatedList struct {
+	Queries []*feedback.RatedQuery `json:"queries"`
+	Count   int                    `json:"count"`
+}
+
+// sendFeedback records the caller's rating of an answer, replacing their
+// earlier rating of it
+func sendFeedback(c *gin.Context) {
+	var req feedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := uuid.Parse(req.QueryID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid query_id %q", req.QueryID)})
+		return
+	}
+	if req.Rating != feedback.RatingUp && req.Rating != feedback.RatingDown {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid rating %q: expected %s or %s", req.Rating, feedback.RatingUp, feedback.RatingDown)})
+		return
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(comment) > feedback.MaxCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("comment is longer than %d characters", feedback.MaxCommentLength)})
+		return
+	}
+
+	caller := callerIdentity(c)
+	fb := &feedback.Feedback{
+		QueryID:   req.QueryID,
+		Rating:    req.Rating,
+		Comment:   comment,
+		Query:     strings.TrimSpace(req.Query),
+		Actor:     caller.UserID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if fb.Actor == "" {
+		fb.Actor = "anonymous"
+	}
+
+	event := audit.NewEvent(caller.UserID, audit.ActionFeedback, req.QueryID)
+	event.Details["rating"] = req.Rating
+	previous, err := feedbackStore.Get(c.Request.Context(), fb.QueryID, fb.Actor)
+	if err == nil {
+		err = feedbackStore.Put(c.Request.Context(), fb)
+	}
+	if err != nil {
+		logger.Error("Failed to store feedback", zap.String("query_id", req.QueryID), zap.Error(err))
+		recordFailure(c.Request.Context(), event, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	auditRecorder.Record(c.Request.Context(), event)
+	recordRating(c.Request.Context(), fb, previous)
+
+	c.JSON(http.StatusCreated, fb)
+}
+
+// listFeedback returns ratings, newest first, filtered by rating and time
+func listFeedback(c *gin.Context) {
+	filter, ok := feedbackFilter(c)
+	if !ok {
+		return
+	}
+	list, err := feedbackStore.List(c.Request.Context(), filter)
+	if err != nil {
+		logger.Error("Failed to list feedback", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, feedbackList{Feedback: list, Count: len(list)})
+}
+
+// feedbackStats aggregates the ratings given since an optional time
+func feedbackStats(c *gin.Context) {
+	filter, ok := feedbackFilter(c)
+	if !ok {
+		return
+	}
+	filter.Rating, filter.Limit = "", 0
+	list, err := feedbackStore.List(c.Request.Context(), filter)
+	if err != nil {
+		logger.Error("Failed to list feedback", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, feedback.Summarize(list))
+}
+
+// poorlyRated returns the queries rated down more than up, with the
+// comments given, as cases for evaluation runs
+func poorlyRated(c *gin.Context) {
+	filter, ok := feedbackFilter(c)
+	if !ok {
+		return
+	}
+	limit := filter.Limit
+	filter.Rating, filter.Limit = "", 0
+	list, err := feedbackStore.List(c.Request.Context(), filter)
+	if err != nil {
+		logger.Error("Failed to list feedback", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	queries := feedback.PoorlyRated(list)
+	if limit > 0 && len(queries) > limit {
+		queries = queries[:limit]
+	}
+	c.JSON(http.StatusOK, poorlyRatedList{Queries: queries, Count: len(queries)})
+}
+
+// feedbackFilter reads the rating, since and limit query parameters and
+// writes a 400 response when one is invalid
+func feedbackFilter(c *gin.Context) (feedback.Filter, bool) {
+	filter := feedback.Filter{Rating: c.Query("rating")}
+	if filter.Rating != "" && filter.Rating != feedback.RatingUp && filter.Rating != feedback.RatingDown {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rating must be %s or %s", feedback.RatingUp, feedback.RatingDown)})
+		return filter, false
+	}
+	if value := c.Query("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 timestamp"})
+			return filter, false
+		}
+		filter.Since = &since
+	}
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return filter, false
+		}
+		filter.Limit = n
+	}
+	return filter, true
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/analytics"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/collections"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/httpsec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/jsonschema"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logtext"
+	"github.com/nadeeshame/rag-knowledge-service/internal/moderation"
+	"github.com/nadeeshame/rag-knowledge-service/internal/query"
+	"go.uber.org/zap"
+)
+
+// queryRequest is the request body for query endpoints
+type queryRequest struct {
+	Text              string          `json:"text" binding:"required"`
+	TopK              int             `json:"top_k"`
+	Namespace         string          `json:"namespace"`
+	Filter            models.Filter   `json:"filter"`
+	AsOf              string          `json:"as_of" description:"YYYY-MM-DD or RFC 3339 timestamp"`
+	Mode              string          `json:"mode" description:"chunks, two_stage or agent; defaults to RETRIEVAL_MODE"`
+	ContextWindow     *int            `json:"context_window" description:"neighbouring chunks added on each side of a match; defaults to RETRIEVAL_CONTEXT_WINDOW"`
+	Alpha             *float64        `json:"alpha" description:"weight of the dense embedding against the sparse terms in hybrid search, 0 to 1; defaults to SPARSE_ALPHA"`
+	Profile           string          `json:"profile" description:"retrieval profile giving the defaults of unset fields"`
+	MinScore          *float64        `json:"min_score" description:"matches scoring below this are dropped; defaults to RETRIEVAL_MIN_SCORE"`
+	MaxDistance       *float64        `json:"max_distance" description:"matches farther from the query than this are dropped; defaults to RETRIEVAL_MAX_DISTANCE"`
+	FreshnessHalfLife *int            `json:"freshness_half_life" description:"days at which the decaying part of a match score halves, ranking recently modified documents higher; 0 ranks by relevance alone; defaults to RETRIEVAL_FRESHNESS_HALF_LIFE"`
+	ResponseFormat    string          `json:"response_format" description:"text, or json_schema for an answer conforming to schema"`
+	Schema            json.RawMessage `json:"schema" description:"JSON schema of the answer when response_format is json_schema"`
+}
+
+// toQuery converts the request into a domain query. The as_of query
+// parameter takes precedence over the body field, and the fields of a
+// retrieval profile fill in the ones the request leaves unset.
+func (r *queryRequest) toQuery(c *gin.Context, defaultTopK int) (*models.Query, error) {
+	if r.Profile != "" {
+		profile, ok := queryService.Profile(r.Profile)
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", r.Profile)
+		}
+		r.applyProfile(profile)
+	}
+
+	topK := r.TopK
+	if topK <= 0 {
+		topK = defaultTopK
+	}
+
+	q := models.NewQuery(r.Text, topK)
+	q.Namespace = r.Namespace
+	q.Filter = r.Filter
+	q.Caller = callerIdentity(c)
+	q.Profile = r.Profile
+
+	switch r.Mode {
+	case "", models.RetrievalChunks, models.RetrievalTwoStage, models.RetrievalAgent:
+		q.Mode = r.Mode
+	default:
+		return nil, fmt.Errorf("invalid mode %q: expected %s, %s or %s", r.Mode, models.RetrievalChunks, models.RetrievalTwoStage, models.RetrievalAgent)
+	}
+	if w := r.ContextWindow; w != nil && (*w < 0 || *w > models.MaxContextWindow) {
+		return nil, fmt.Errorf("invalid context_window %d: expected 0 to %d", *w, models.MaxContextWindow)
+	}
+	q.ContextWindow = r.ContextWindow
+	if a := r.Alpha; a != nil && (*a < 0 || *a > 1) {
+		return nil, fmt.Errorf("invalid alpha %g: expected 0 to 1", *a)
+	}
+	q.Alpha = r.Alpha
+	q.MinScore = r.MinScore
+	if d := r.MaxDistance; d != nil && *d < 0 {
+		return nil, fmt.Errorf("invalid max_distance %g: expected 0 or more", *d)
+	}
+	q.MaxDistance = r.MaxDistance
+	if h := r.FreshnessHalfLife; h != nil && *h < 0 {
+		return nil, fmt.Errorf("invalid freshness_half_life %d: expected 0 or more days", *h)
+	}
+	q.FreshnessHalfLife = r.FreshnessHalfLife
+	switch r.ResponseFormat {
+	case "", models.ResponseText:
+		if len(r.Schema) > 0 {
+			return nil, fmt.Errorf("schema needs response_format %s", models.ResponseJSONSchema)
+		}
+	case models.ResponseJSONSchema:
+		if len(r.Schema) == 0 {
+			return nil, fmt.Errorf("response_format %s needs a schema", models.ResponseJSONSchema)
+		}
+		if _, err := jsonschema.Parse(r.Schema); err != nil {
+			return nil, fmt.Errorf("invalid schema: %w", err)
+		}
+		if q.Mode == models.RetrievalAgent {
+			return nil, fmt.Errorf("mode %s answers in text", models.RetrievalAgent)
+		}
+		q.ResponseFormat, q.Schema = r.ResponseFormat, r.Schema
+	default:
+		return nil, fmt.Errorf("invalid response_format %q: expected %s or %s", r.ResponseFormat, models.ResponseText, models.ResponseJSONSchema)
+	}
+	if level := r.Filter.LogLevel; level != "" && logtext.Level(level) == "" {
+		return nil, fmt.Errorf("invalid log_level %q: expected one of %s", level, strings.Join(logtext.Levels, ", "))
+	}
+
+	asOf := c.DefaultQuery("as_of", r.AsOf)
+	if asOf != "" {
+		t, err := models.ParseAsOf(asOf)
+		if err != nil {
+			return nil, err
+		}
+		q.AsOf = &t
+	}
+
+	return q, nil
+}
+
+// applyProfile sets the fields the request leaves unset to the profile's
+func (r *queryRequest) applyProfile(profile config.RetrievalProfile) {
+	if r.TopK <= 0 {
+		r.TopK = profile.TopK
+	}
+	if r.Mode == "" {
+		r.Mode = profile.Mode
+	}
+	if r.ContextWindow == nil {
+		r.ContextWindow = profile.ContextWindow
+	}
+	if r.MinScore == nil {
+		r.MinScore = profile.MinScore
+	}
+	if r.MaxDistance == nil {
+		r.MaxDistance = profile.MaxDistance
+	}
+	if r.FreshnessHalfLife == nil {
+		r.FreshnessHalfLife = profile.FreshnessHalfLife
+	}
+}
+
+// bindQuery binds the request body and writes a 400 response on failure
+func bindQuery(c *gin.Context, defaultTopK int) (*models.Query, bool) {
+	var req queryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	q, err := req.toQuery(c, defaultTopK)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return q, true
+}
+
+// ask answers a question using retrieved context
+func ask(c *gin.Context) {
+	q, ok := bindQuery(c, 5)
+	if !ok {
+		return
+	}
+
+	logger.Info("Running query",
+		zap.String("query_id", q.ID.String()),
+		zap.Int("top_k", q.TopK),
+		zap.Bool("time_travel", q.AsOf != nil))
+
+	event := newQueryEvent(q, audit.ActionQuery)
+	start := time.Now()
+
+	result, err := queryService.Query(c.Request.Context(), q)
+	if err != nil {
+		logger.Error("Query failed", zap.Error(err))
+		recordFailure(c.Request.Context(), event, err)
+		analyticsRecorder.Record(c.Request.Context(), q.ID.String(), analytics.KindQuery, q.Text, start, 0, err)
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	analyticsRecorder.Record(c.Request.Context(), q.ID.String(), analytics.KindQuery, q.Text, start, len(result.Sources), nil)
+
+	event.DocumentIDs = sourceDocumentIDs(result.Sources)
+	recordTrace(event, result.Trace)
+	auditRecorder.Record(c.Request.Context(), event)
+	recordUsage(c.Request.Context(), q.ID.String(), event.DocumentIDs)
+
+	c.JSON(http.StatusOK, result)
+}
+
+// search returns matching chunks without generating an answer
+func search(c *gin.Context) {
+	q, ok := bindQuery(c, 10)
+	if !ok {
+		return
+	}
+	if q.Mode == models.RetrievalAgent {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode agent answers questions; use /api/v1/query"})
+		return
+	}
+
+	logger.Info("Searching documents",
+		zap.String("query_id", q.ID.String()),
+		zap.Int("top_k", q.TopK))
+
+	event := newQueryEvent(q, audit.ActionSearch)
+	start := time.Now()
+
+	verdict, err := queryService.ModerateQuery(c.Request.Context(), q)
+	if err != nil {
+		logger.Error("Search failed", zap.Error(err))
+		recordFailure(c.Request.Context(), event, err)
+		analyticsRecorder.Record(c.Request.Context(), q.ID.String(), analytics.KindSearch, q.Text, start, 0, err)
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	results, err := queryService.SearchDocuments(c.Request.Context(), q)
+	if err != nil {
+		logger.Error("Search failed", zap.Error(err))
+		recordFailure(c.Request.Context(), event, err)
+		analyticsRecorder.Record(c.Request.Context(), q.ID.String(), analytics.KindSearch, q.Text, start, 0, err)
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+	analyticsRecorder.Record(c.Request.Context(), q.ID.String(), analytics.KindSearch, q.Text, start, len(results), nil)
+
+	sources := make([]models.SearchResult, 0, len(results))
+	for _, r := range results {
+		sources = append(sources, *r)
+	}
+	response := gin.H{
+		"query_id": q.ID,
+		"results":  sources,
+		"total":    len(sources),
+	}
+	if verdict != nil && verdict.Action == moderation.ActionWarn {
+		recordVerdicts(event, *verdict)
+		response["moderation"] = []models.ModerationVerdict{*verdict}
+	}
+	event.DocumentIDs = sourceDocumentIDs(sources)
+	auditRecorder.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// compareRequest is the request body of the compare endpoint
+type compareRequest struct {
+	Text   string                  `json:"text" binding:"required"`
+	Left   models.ComparisonTarget `json:"left" description:"document_id or file_path of the first document"`
+	Right  models.ComparisonTarget `json:"right" description:"document_id or file_path of the second document"`
+	TopK   int                     `json:"top_k" description:"passages retrieved from each document; defaults to 5"`
+	Filter models.Filter           `json:"filter"`
+	AsOf   string                  `json:"as_of" description:"YYYY-MM-DD or RFC 3339 timestamp"`
+}
+
+// compare answers a question comparing two documents with a structured
+// comparison
+func compare(c *gin.Context) {
+	var req compareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for i, target := range []models.ComparisonTarget{req.Left, req.Right} {
+		if (target.DocumentID == "") == (target.FilePath == "") {
+			side := [...]string{"left", "right"}[i]
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s needs either a document_id or a file_path", side)})
+			return
+		}
+	}
+	r := queryRequest{Text: req.Text, TopK: req.TopK, Filter: req.Filter, AsOf: req.AsOf}
+	q, err := r.toQuery(c, 5)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Comparing documents",
+		zap.String("query_id", q.ID.String()),
+		zap.Int("top_k", q.TopK))
+
+	event := newQueryEvent(q, audit.ActionCompare)
+	event.Details["left"] = req.Left.DocumentID + req.Left.FilePath
+	event.Details["right"] = req.Right.DocumentID + req.Right.FilePath
+
+	comparison, err := queryService.Compare(c.Request.Context(), q, req.Left, req.Right)
+	if err != nil {
+		logger.Error("Comparison failed", zap.Error(err))
+		recordFailure(c.Request.Context(), event, err)
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	event.DocumentIDs = sourceDocumentIDs(append(append([]models.SearchResult{}, comparison.LeftSources...), comparison.RightSources...))
+	recordTrace(event, comparison.Trace)
+	auditRecorder.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// similarResponse is the response body of the similar documents endpoint
+type similarResponse struct {
+	DocumentID string                   `json:"document_id"`
+	Method     string                   `json:"method"`
+	Documents  []models.SimilarDocument `json:"documents"`
+	Count      int                      `json:"count"`
+}
+
+// similarDocuments returns the documents most like a document, from its
+// chunk vectors
+func similarDocuments(c *gin.Context) {
+	id := c.Param("id")
+	method := c.DefaultQuery("method", query.SimilarMean)
+	if method != query.SimilarMean && method != query.SimilarSample {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("method must be %s or %s", query.SimilarMean, query.SimilarSample)})
+		return
+	}
+	limit := 10
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 50"})
+			return
+		}
+		limit = n
+	}
+
+	caller := callerIdentity(c)
+	event := audit.NewEvent(caller.UserID, audit.ActionSimilar, id)
+	event.Details["method"] = method
+
+	documents, err := queryService.SimilarDocuments(c.Request.Context(), caller, id, method, limit)
+	if err != nil {
+		logger.Error("Failed to find similar documents", zap.String("document_id", id), zap.Error(err))
+		recordFailure(c.Request.Context(), event, err)
+		c.JSON(errorStatus(err), errorBody(err))
+		return
+	}
+
+	event.DocumentIDs = make([]string, 0, len(documents))
+	for _, d := range documents {
+		event.DocumentIDs = append(event.DocumentIDs, d.DocumentID)
+	}
+	auditRecorder.Record(c.Request.Context(), event)
+
+	c.JSON(http.StatusOK, similarResponse{DocumentID: id, Method: method, Documents: documents, Count: len(documents)})
+}
+
+// stream answers a question as server-sent events: one "sources" event,
+// a series of "delta" events with answer fragments, then "done", with the
+// query trace, or "error"
+func stream(c *gin.Context) {
+	q, ok := bindQuery(c, 5)
+	if !ok {
+		return
+	}
+	if q.ResponseFormat == models.ResponseJSONSchema || q.Mode == models.RetrievalAgent {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "json_schema and agent answers cannot be streamed; use /api/v1/query"})
+		return
+	}
+
+	// Answers can take longer than the server write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warn("Failed to clear write deadline", zap.Error(err))
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	logger.Info("Streaming query", zap.String("query_id", q.ID.String()))
+
+	event := newQueryEvent(q, audit.ActionQuery)
+	event.Details["stream"] = "true"
+	start := time.Now()
+	found := 0
+
+	trace, err := queryService.QueryStream(c.Request.Context(), q,
+		func(results []*models.SearchResult) error {
+			found = len(results)
+			sources := make([]models.SearchResult, 0, len(results))
+			for _, r := range results {
+				sources = append(sources, *r)
+			}
+			event.DocumentIDs = sourceDocumentIDs(sources)
+			return sendEvent(c, "sources", gin.H{"query_id": q.ID, "sources": sources})
+		},
+		func(delta string) error {
+			return sendEvent(c, "delta", gin.H{"content": delta})
+		})
+	if err != nil {
+		logger.Error("Streaming query failed", zap.Error(err))
+		recordFailure(c.Request.Context(), event, err)
+		analyticsRecorder.Record(c.Request.Context(), q.ID.String(), analytics.KindQuery, q.Text, start, found, err)
+		sendEvent(c, "error", errorBody(err)) //nolint:errcheck
+		return
+	}
+	analyticsRecorder.Record(c.Request.Context(), q.ID.String(), analytics.KindQuery, q.Text, start, found, nil)
+
+	recordTrace(event, trace)
+	auditRecorder.Record(c.Request.Context(), event)
+	recordUsage(c.Request.Context(), q.ID.String(), event.DocumentIDs)
+	done := gin.H{"query_id": q.ID, "trace": trace}
+	if trace != nil {
+		if score := models.Faithfulness(trace.Citations); score != nil {
+			done["faithfulness"] = *score
+		}
+	}
+	sendEvent(c, "done", done) //nolint:errcheck
+}
+
+// sendEvent writes one server-sent event and flushes it to the client
+func sendEvent(c *gin.Context, name string, data interface{}) error {
+	if err := c.Request.Context().Err(); err != nil {
+		return fmt.Errorf("client disconnected: %w", err)
+	}
+	c.SSEvent(name, data)
+	c.Writer.Flush()
+	return nil
+}
+
+// readiness reports whether the service's dependencies are reachable
+func readiness(c *gin.Context) {
+	checks := map[string]bool{
+		"azure_openai": healthChecker.CheckAzureOpenAI(c.Request.Context()),
+		"pinecone":     true,
+	}
+	details := make(map[string]string)
+	if err := queryService.Ready(c.Request.Context()); err != nil {
+		checks["pinecone"] = false
+		details["pinecone"] = err.Error()
+	}
+	if !checks["azure_openai"] {
+		details["azure_openai"] = "Unable to reach Azure OpenAI endpoint"
+	}
+
+	ready := checks["azure_openai"] && checks["pinecone"]
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{"ready": ready, "services": checks, "details": details})
+}
+
+// newQueryEvent creates the audit event for a query or search
+func newQueryEvent(q *models.Query, action audit.Action) *audit.Event {
+	event := audit.NewEvent(q.Caller.UserID, action, q.Text)
+	event.Details["query_id"] = q.ID.String()
+	event.Details["top_k"] = strconv.Itoa(q.TopK)
+	if len(q.Caller.Groups) > 0 {
+		event.Details["groups"] = strings.Join(q.Caller.Groups, ",")
+	}
+	if q.Caller.Tenant != "" {
+		event.Details["tenant"] = q.Caller.Tenant
+	}
+	if q.Filter.Collection != "" {
+		event.Details["collection"] = q.Filter.Collection
+	}
+	if q.AsOf != nil {
+		event.Details["as_of"] = q.AsOf.Format(time.RFC3339)
+	}
+	return event
+}
+
+// recordTrace adds the prompt-injection findings and moderation verdicts of
+// an answer to its audit event
+func recordTrace(event *audit.Event, trace *models.QueryTrace) {
+	if trace == nil {
+		return
+	}
+	recordVerdicts(event, trace.Moderation...)
+	if len(trace.Injections) == 0 {
+		return
+	}
+	event.Details["injection_score"] = strconv.FormatFloat(trace.InjectionScore, 'f', -1, 64)
+	files := make([]string, len(trace.Injections))
+	for i, finding := range trace.Injections {
+		files[i] = finding.FilePath
+	}
+	event.Details["injection_sources"] = strings.Join(files, ",")
+}
+
+// recordVerdicts adds the categories moderation flagged a query or answer
+// for to its audit event, as moderation_query and moderation_answer
+func recordVerdicts(event *audit.Event, verdicts ...models.ModerationVerdict) {
+	for _, verdict := range verdicts {
+		categories := make([]string, len(verdict.Categories))
+		for i, c := range verdict.Categories {
+			categories[i] = fmt.Sprintf("%s:%d", c.Category, c.Severity)
+		}
+		event.Details["moderation_"+verdict.Subject] = strings.Join(categories, ",")
+	}
+}
+
+// errorStatus returns the response status of a failed query: 404 for an
+// unknown collection, 422 for a query or answer blocked by moderation, 500
+// otherwise
+func errorStatus(err error) int {
+	var blocked *moderation.BlockedError
+	switch {
+	case errors.Is(err, collections.ErrNotFound), errors.Is(err, query.ErrDocumentNotFound):
+		return http.StatusNotFound
+	case errors.As(err, &blocked), errors.Is(err, query.ErrInvalidAnswer):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// errorBody returns the response body of a failed query, with the verdict
+// of a query or answer blocked by moderation
+func errorBody(err error) gin.H {
+	body := gin.H{"error": err.Error()}
+	var blocked *moderation.BlockedError
+	if errors.As(err, &blocked) {
+		body["moderation"] = blocked.Verdict
+	}
+	return body
+}
+
+// recordFailure marks an audit event as failed and records it, with the
+// verdict of a query or answer blocked by moderation
+func recordFailure(ctx context.Context, event *audit.Event, err error) {
+	event.Outcome = audit.OutcomeFailure
+	event.Details["error"] = err.Error()
+	var blocked *moderation.BlockedError
+	if errors.As(err, &blocked) {
+		recordVerdicts(event, blocked.Verdict)
+	}
+	auditRecorder.Record(ctx, event)
+}
+
+// sourceDocumentIDs returns the distinct document IDs among query sources
+func sourceDocumentIDs(sources []models.SearchResult) []string {
+	seen := make(map[string]bool)
+	ids := make([]string, 0, len(sources))
+	for _, s := range sources {
+		id := s.DocumentID.String()
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// callerIdentity reads the caller identity from the headers set by the
+// authenticating proxy in front of the service
+func callerIdentity(c *gin.Context) *models.Identity {
+	return httpsec.Identity(c)
+}
@@ -10,12 +10,44 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/analytics"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/chunkstore"
+	"github.com/nadeeshame/rag-knowledge-service/internal/collections"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/feedback"
+	"github.com/nadeeshame/rag-knowledge-service/internal/httpsec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/imagesearch"
 	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/moderation"
+	"github.com/nadeeshame/rag-knowledge-service/internal/popularity"
+	"github.com/nadeeshame/rag-knowledge-service/internal/query"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/routing"
+	"github.com/nadeeshame/rag-knowledge-service/internal/sparse"
+	"github.com/nadeeshame/rag-knowledge-service/internal/tlsconfig"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/health"
 	"go.uber.org/zap"
 )
 
+var (
+	queryService      *query.Service
+	auditRecorder     *audit.Recorder
+	healthChecker     *health.Checker
+	feedbackStore     feedback.Store
+	analyticsRecorder *analytics.Recorder
+	popularityStore   popularity.Store
+)
+
 func main() {
+	if apispec.Requested() {
+		if err := apiSpec.Write(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
@@ -29,19 +61,107 @@ func main() {
 	logger.Info("Starting Query Service",
 		zap.String("version", "1.0.0"),
 		zap.Int("port", 8087))
+	queryService, err = query.NewService(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create query service", zap.Error(err))
+	}
+	chunkStore, err := chunkstore.NewStore(context.Background(), cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create chunk store", zap.Error(err))
+	}
+	if chunkStore != nil {
+		defer chunkStore.Close() //nolint:errcheck
+		queryService.SetChunkStore(chunkStore)
+	}
+	imageIndex, err := imagesearch.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create image index", zap.Error(err))
+	}
+	if imageIndex != nil {
+		queryService.SetImageIndex(imageIndex)
+	}
+	categoryRouter, err := routing.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create category router", zap.Error(err))
+	}
+	if categoryRouter != nil {
+		queryService.SetRouter(categoryRouter)
+	}
+	sparseEncoder, err := sparse.New(cfg)
+	if err != nil {
+		logger.Fatal("Failed to create sparse encoder", zap.Error(err))
+	}
+	if sparseEncoder != nil {
+		queryService.SetSparseEncoder(sparseEncoder)
+	}
+	moderator, err := moderation.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create content moderator", zap.Error(err))
+	}
+	if moderator != nil {
+		queryService.SetModerator(moderator)
+	}
+	// Document summaries and collections live beside the registry; a memory
+	// registry belongs to the orchestrator process and would always be empty here
+	if cfg.Registry.Backend == "redis" {
+		documentRegistry, err := registry.NewStore(context.Background(), cfg, logger.Log)
+		if err != nil {
+			logger.Fatal("Failed to create document registry", zap.Error(err))
+		}
+		defer documentRegistry.Close() //nolint:errcheck
+		queryService.SetRegistry(documentRegistry)
+
+		collectionStore, err := collections.NewStore(context.Background(), cfg, logger.Log)
+		if err != nil {
+			logger.Fatal("Failed to create collection store", zap.Error(err))
+		}
+		defer collectionStore.Close() //nolint:errcheck
+		queryService.SetCollections(collectionStore)
+	}
+	feedbackStore, err = feedback.NewStore(context.Background(), cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create feedback store", zap.Error(err))
+	}
+	defer feedbackStore.Close() //nolint:errcheck
+	popularityStore, err = popularity.NewStore(context.Background(), cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create popularity store", zap.Error(err))
+	}
+	if popularityStore != nil {
+		defer popularityStore.Close() //nolint:errcheck
+		queryService.SetPopularity(popularityStore)
+	}
+	analyticsStore, err := analytics.NewStore(context.Background(), cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create query analytics store", zap.Error(err))
+	}
+	if analyticsStore != nil {
+		defer analyticsStore.Close() //nolint:errcheck
+	}
+	analyticsRecorder = analytics.NewRecorder(analyticsStore, cfg.Analytics, logger.Log)
+	auditStore, err := audit.NewStore(context.Background(), cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create audit store", zap.Error(err))
+	}
+	auditRecorder = audit.NewRecorder(auditStore, logger.Log)
+	healthChecker = health.NewChecker(cfg.Azure.OpenAIEndpoint, cfg.Pinecone.APIKey, cfg.Google.VisionAPIKey, nil, nil)
 	router := gin.Default()
+	router.Use(httpsec.SecurityHeaders(cfg.SecurityHeaders), httpsec.CORS(cfg.CORS))
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"healthy": true})
 	})
-	router.GET("/ready", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"ready": true})
-	})
+	router.GET("/ready", readiness)
+	router.GET("/openapi.json", apiSpec.Serve())
 	v1 := router.Group("/api/v1")
 	{
-		// TODO: Add service-specific endpoints
 		v1.GET("/status", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"status": "operational"})
 		})
+		apiSpec.Register(v1)
+	}
+	certs, err := tlsconfig.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to load TLS certificates", zap.Error(err))
 	}
 	srv := &http.Server{
 		Addr:         ":8087",
@@ -51,7 +171,7 @@ func main() {
 	}
 	go func() {
 		logger.Info("Query Service starting", zap.String("address", srv.Addr))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := tlsconfig.ListenAndServe(srv, certs); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
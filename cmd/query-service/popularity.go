@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/feedback"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/query"
+	"go.uber.org/zap"
+)
+
+// popularResponse is the response body of the popular documents endpoint
+type popularResponse struct {
+	Documents []query.PopularDocument `json:"documents"`
+	Count     int                     `json:"count"`
+}
+
+// popularDocuments returns the documents used in the most answers
+func popularDocuments(c *gin.Context) {
+	limit := 20
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	documents, err := queryService.PopularDocuments(c.Request.Context(), limit)
+	if errors.Is(err, query.ErrPopularityDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to list popular documents", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, popularResponse{Documents: documents, Count: len(documents)})
+}
+
+// recordUsage counts an answer towards the popularity of the documents it
+// cites, logging instead of failing the answer
+func recordUsage(ctx context.Context, queryID string, documentIDs []string) {
+	if popularityStore == nil || len(documentIDs) == 0 {
+		return
+	}
+	if err := popularityStore.RecordAnswer(context.WithoutCancel(ctx), queryID, documentIDs); err != nil {
+		logger.Warn("Failed to record document usage", zap.String("query_id", queryID), zap.Error(err))
+	}
+}
+
+// recordRating counts a rating towards the popularity of the documents of
+// the rated answer, taking back the rating it replaces
+func recordRating(ctx context.Context, fb, previous *feedback.Feedback) {
+	if popularityStore == nil {
+		return
+	}
+	up, down := ratingCounts(fb.Rating)
+	if previous != nil {
+		prevUp, prevDown := ratingCounts(previous.Rating)
+		up, down = up-prevUp, down-prevDown
+	}
+	if up == 0 && down == 0 {
+		return
+	}
+	if err := popularityStore.RecordRating(ctx, fb.QueryID, up, down); err != nil {
+		logger.Warn("Failed to record document rating", zap.String("query_id", fb.QueryID), zap.Error(err))
+	}
+}
+
+// ratingCounts returns the up and down counts of a rating
+func ratingCounts(rating string) (int, int) {
+	if rating == feedback.RatingUp {
+		return 1, 0
+	}
+	return 0, 1
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/analytics"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/feedback"
+)
+
+// searchResponse is the response body of the search endpoint
+type searchResponse struct {
+	QueryID uuid.UUID             `json:"query_id"`
+	Results []models.SearchResult `json:"results"`
+	Total   int                   `json:"total"`
+}
+
+// apiSpec describes the endpoints served under /api/v1
+var apiSpec = apispec.New("Query Service", "1.0.0", "/api/v1",
+	apispec.Operation{
+		Method: "POST", Path: "/query", Tag: "query", Handler: ask,
+		Summary: "Answer a question using retrieved context",
+		Request: queryRequest{}, Response: models.QueryResult{}, Query: []string{"as_of"},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/ask", Tag: "query", Handler: ask,
+		Summary: "Answer a question using retrieved context (alias of /query)",
+		Request: queryRequest{}, Response: models.QueryResult{}, Query: []string{"as_of"},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/search", Tag: "query", Handler: search,
+		Summary: "Search for relevant document chunks",
+		Request: queryRequest{}, Response: searchResponse{}, Query: []string{"as_of"},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/compare", Tag: "query", Handler: compare,
+		Summary: "Compare two documents on a question",
+		Request: compareRequest{}, Response: models.Comparison{}, Query: []string{"as_of"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/documents/:id/similar", Tag: "documents", Handler: similarDocuments,
+		Summary:  "Find the documents most like a document",
+		Response: similarResponse{}, Query: []string{"method", "limit"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/documents/popular", Tag: "documents", Handler: popularDocuments,
+		Summary:  "List the documents used in the most answers, with their ratings",
+		Response: popularResponse{}, Query: []string{"limit"},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/feedback", Tag: "feedback", Handler: sendFeedback,
+		Summary: "Rate an answer up or down with an optional comment",
+		Request: feedbackRequest{}, Response: feedback.Feedback{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/feedback", Tag: "feedback", Handler: listFeedback,
+		Summary:  "List answer ratings, newest first",
+		Response: feedbackList{}, Query: []string{"rating", "since", "limit"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/feedback/stats", Tag: "feedback", Handler: feedbackStats,
+		Summary:  "Aggregate answer ratings",
+		Response: feedback.Stats{}, Query: []string{"since"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/feedback/poor", Tag: "feedback", Handler: poorlyRated,
+		Summary:  "List queries rated down more than up, for evaluation runs",
+		Response: poorlyRatedList{}, Query: []string{"since", "limit"},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/analytics/queries", Tag: "analytics", Handler: queryAnalytics,
+		Summary:  "Aggregate the queries run: the most frequent, those finding nothing and latency percentiles",
+		Response: analytics.Report{}, Query: []string{"since", "until", "limit"},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/stream", Tag: "query", Handler: stream,
+		Summary: "Stream an answer as server-sent events",
+		Request: queryRequest{}, Stream: true, Query: []string{"as_of"},
+	},
+)
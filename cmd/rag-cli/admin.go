@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+// indexPollInterval is how often create-index checks whether a new index
+// is ready
+const indexPollInterval = 2 * time.Second
+
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Manage the Pinecone index",
+	Long: `Create, describe and delete Pinecone serverless indexes through the Pinecone
+control plane, with the configured API key. The commands do not need the
+platform services, so they can set up the index before first start.`,
+}
+
+var adminCreateIndexCmd = &cobra.Command{
+	Use:   "create-index",
+	Short: "Create a serverless index, by default the configured one",
+	Long: `Create a serverless index named PINECONE_INDEX_NAME with PINECONE_DIMENSION,
+PINECONE_METRIC, PINECONE_CLOUD and PINECONE_REGION, or the values given by
+flags, and wait until it is ready unless --wait=false.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		admin, err := pinecone.NewIndexAdmin(cfg, logger.Log)
+		if err != nil {
+			return err
+		}
+
+		req := admin.Defaults()
+		flags := cmd.Flags()
+		for name, value := range map[string]*string{"name": &req.Name, "metric": &req.Metric, "cloud": &req.Cloud, "region": &req.Region} {
+			if flags.Changed(name) {
+				if *value, err = flags.GetString(name); err != nil {
+					return fmt.Errorf("failed to get %s flag: %w", name, err)
+				}
+			}
+		}
+		if flags.Changed("dimension") {
+			if req.Dimension, err = flags.GetInt("dimension"); err != nil {
+				return fmt.Errorf("failed to get dimension flag: %w", err)
+			}
+		}
+		req.DeletionProtection, err = flags.GetBool("deletion-protection")
+		if err != nil {
+			return fmt.Errorf("failed to get deletion-protection flag: %w", err)
+		}
+		wait, err := flags.GetBool("wait")
+		if err != nil {
+			return fmt.Errorf("failed to get wait flag: %w", err)
+		}
+		timeout, err := flags.GetDuration("timeout")
+		if err != nil {
+			return fmt.Errorf("failed to get timeout flag: %w", err)
+		}
+
+		index, err := admin.CreateServerless(cmd.Context(), req)
+		if err != nil {
+			return fmt.Errorf("failed to create index %s: %w", req.Name, err)
+		}
+		if wait && !index.Status.Ready {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+			index, err = admin.WaitReady(ctx, req.Name, indexPollInterval)
+			if err != nil {
+				return err
+			}
+		}
+		return printResult(pineconeIndexResult{Index: index})
+	},
+}
+
+var adminDescribeIndexCmd = &cobra.Command{
+	Use:   "describe-index [name]",
+	Short: "Describe an index, by default the configured one",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		admin, err := pinecone.NewIndexAdmin(cfg, logger.Log)
+		if err != nil {
+			return err
+		}
+		name := cfg.Pinecone.IndexName
+		if len(args) > 0 {
+			name = args[0]
+		}
+
+		index, err := admin.Describe(cmd.Context(), name)
+		if err != nil {
+			return fmt.Errorf("failed to describe index %s: %w", name, err)
+		}
+		return printResult(pineconeIndexResult{Index: index})
+	},
+}
+
+var adminDeleteIndexCmd = &cobra.Command{
+	Use:   "delete-index <name>",
+	Short: "Delete an index and all its vectors",
+	Long: `Delete an index and every vector in it. The name must be given even for the
+configured index, along with --yes.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return fmt.Errorf("failed to get yes flag: %w", err)
+		}
+		if !yes {
+			return fmt.Errorf("deleting index %s removes all its vectors; pass --yes to confirm", args[0])
+		}
+		admin, err := pinecone.NewIndexAdmin(cfg, logger.Log)
+		if err != nil {
+			return err
+		}
+
+		if err := admin.Delete(cmd.Context(), args[0]); err != nil {
+			return fmt.Errorf("failed to delete index %s: %w", args[0], err)
+		}
+		return printResult(pineconeIndexDeleteResult{Name: args[0], Deleted: true})
+	},
+}
+
+// pineconeIndexResult is the output of the create-index and describe-index commands
+type pineconeIndexResult struct {
+	*pinecone.Index
+}
+
+func (r pineconeIndexResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗂️  Index %s\n", r.Name)
+	fmt.Fprintf(w, "State: %s (ready: %t)\n", r.Status.State, r.Status.Ready)
+	fmt.Fprintf(w, "Dimension: %d  Metric: %s\n", r.Dimension, r.Metric)
+	if r.Spec.Serverless != nil {
+		fmt.Fprintf(w, "Serverless: %s %s\n", r.Spec.Serverless.Cloud, r.Spec.Serverless.Region)
+	}
+	if r.DeletionProtection != "" {
+		fmt.Fprintf(w, "Deletion protection: %s\n", r.DeletionProtection)
+	}
+	if r.Host != "" {
+		fmt.Fprintf(w, "Host: %s\n", r.Host)
+	}
+}
+
+func (r pineconeIndexResult) writeTable(w io.Writer) {
+	location := "pod"
+	if r.Spec.Serverless != nil {
+		location = r.Spec.Serverless.Cloud + "/" + r.Spec.Serverless.Region
+	}
+	writeRows(w, []string{"NAME", "STATE", "READY", "DIMENSION", "METRIC", "LOCATION", "HOST"}, [][]string{{
+		r.Name,
+		r.Status.State,
+		strconv.FormatBool(r.Status.Ready),
+		strconv.Itoa(r.Dimension),
+		r.Metric,
+		location,
+		r.Host,
+	}})
+}
+
+// pineconeIndexDeleteResult is the output of the delete-index command
+type pineconeIndexDeleteResult struct {
+	Name    string `json:"name"`
+	Deleted bool   `json:"deleted"`
+}
+
+func (r pineconeIndexDeleteResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗑️  Deleted index %s\n", r.Name)
+}
+
+func (r pineconeIndexDeleteResult) writeTable(w io.Writer) {
+	writeRows(w, []string{"NAME", "DELETED"}, [][]string{{r.Name, strconv.FormatBool(r.Deleted)}})
+}
+
+func init() {
+	adminCreateIndexCmd.Flags().String("name", "", "index name (default PINECONE_INDEX_NAME)")
+	adminCreateIndexCmd.Flags().Int("dimension", 0, "vector dimension (default PINECONE_DIMENSION)")
+	adminCreateIndexCmd.Flags().String("metric", "", "cosine, euclidean or dotproduct (default PINECONE_METRIC)")
+	adminCreateIndexCmd.Flags().String("cloud", "", "cloud of the serverless index (default PINECONE_CLOUD)")
+	adminCreateIndexCmd.Flags().String("region", "", "region of the serverless index (default PINECONE_REGION)")
+	adminCreateIndexCmd.Flags().Bool("deletion-protection", false, "refuse deletion until protection is disabled")
+	adminCreateIndexCmd.Flags().Bool("wait", true, "wait until the index is ready")
+	adminCreateIndexCmd.Flags().Duration("timeout", 5*time.Minute, "how long to wait for the index to be ready")
+	adminDeleteIndexCmd.Flags().Bool("yes", false, "confirm deleting the index and its vectors")
+
+	adminCmd.AddCommand(adminCreateIndexCmd)
+	adminCmd.AddCommand(adminDescribeIndexCmd)
+	adminCmd.AddCommand(adminDeleteIndexCmd)
+}
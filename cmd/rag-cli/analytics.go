@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/analytics"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var analyticsCmd = &cobra.Command{
+	Use:   "analytics",
+	Short: "Show what users ask and fail to find",
+	Long: `Aggregate the queries run over a period: the most frequent questions, those
+finding no results, latency percentiles and the ratings answers got. Queries
+are logged by the query service when ANALYTICS_ENABLED is set, as a hash of
+their normalized text unless ANALYTICS_KEEP_TEXT is set too.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := cmd.Flags().GetDuration("since")
+		if err != nil {
+			return fmt.Errorf("failed to get since flag: %w", err)
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("failed to get limit flag: %w", err)
+		}
+		var from time.Time
+		if since > 0 {
+			from = time.Now().Add(-since)
+		}
+
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, clientOptions())
+		report, err := querier.QueryAnalytics(cmd.Context(), from, limit)
+		if err != nil {
+			return fmt.Errorf("failed to get query analytics: %w", err)
+		}
+		return printResult(analyticsResult{report})
+	},
+}
+
+// analyticsResult is the output of the analytics command
+type analyticsResult struct {
+	*analytics.Report
+}
+
+func (r analyticsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "📊 %d queries (%d distinct) from %s to %s\n",
+		r.Queries, r.Distinct, formatTime(r.Since), formatTime(r.Until))
+	fmt.Fprintf(w, "   Zero results: %d (%.1f%%)  Failed: %d\n", r.ZeroResults, r.ZeroResultRate*100, r.Failed)
+	fmt.Fprintf(w, "   Latency: p50 %dms  p90 %dms  p95 %dms  p99 %dms  max %dms\n",
+		r.Latency.P50, r.Latency.P90, r.Latency.P95, r.Latency.P99, r.Latency.Max)
+	fmt.Fprintf(w, "   Ratings: 👍 %d  👎 %d\n", r.Up, r.Down)
+
+	fmt.Fprintln(w, "\n🔝 Top queries")
+	writeQueryStats(w, r.Top, func(s analytics.QueryStats) int { return s.Count })
+	fmt.Fprintln(w, "\n🕳️  Queries finding nothing")
+	writeQueryStats(w, r.ZeroResultQueries, func(s analytics.QueryStats) int { return s.ZeroResults })
+}
+
+func (r analyticsResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Top)+len(r.ZeroResultQueries))
+	for _, list := range []struct {
+		name    string
+		queries []analytics.QueryStats
+	}{{"top", r.Top}, {"zero_results", r.ZeroResultQueries}} {
+		for _, s := range list.queries {
+			rows = append(rows, []string{
+				list.name, queryLabel(s), strconv.Itoa(s.Count), strconv.Itoa(s.ZeroResults),
+				strconv.Itoa(s.Up), strconv.Itoa(s.Down),
+			})
+		}
+	}
+	writeRows(w, []string{"LIST", "QUERY", "COUNT", "ZERO RESULTS", "UP", "DOWN"}, rows)
+}
+
+// writeQueryStats lists queries with the count a list ranks them by
+func writeQueryStats(w io.Writer, queries []analytics.QueryStats, count func(analytics.QueryStats) int) {
+	if len(queries) == 0 {
+		fmt.Fprintln(w, "   none")
+		return
+	}
+	for i, s := range queries {
+		fmt.Fprintf(w, "  %2d. %5d  %s", i+1, count(s), queryLabel(s))
+		if s.Up+s.Down > 0 {
+			fmt.Fprintf(w, "  (👍 %d 👎 %d)", s.Up, s.Down)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// queryLabel returns the text of a query when kept, or its short hash
+func queryLabel(s analytics.QueryStats) string {
+	if s.Text != "" {
+		return s.Text
+	}
+	if len(s.Hash) > 12 {
+		return "#" + s.Hash[:12]
+	}
+	return "#" + s.Hash
+}
+
+func init() {
+	analyticsCmd.Flags().Duration("since", 0, "Period to report on, e.g. 24h; defaults to the last week")
+	analyticsCmd.Flags().IntP("limit", "n", 10, "Queries listed per list")
+}
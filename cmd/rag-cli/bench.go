@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure the throughput of the vector store",
+}
+
+var benchUpsertCmd = &cobra.Command{
+	Use:   "upsert",
+	Short: "Compare the upsert throughput of the Pinecone transports",
+	Long: `Upsert random vectors into a scratch namespace of the configured index over
+each transport in turn, with the configured batch size and concurrency, and
+report the vectors upserted per second. The vectors are deleted afterwards
+unless --keep is set.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		count, err := cmd.Flags().GetInt("vectors")
+		if err != nil {
+			return fmt.Errorf("failed to get vectors flag: %w", err)
+		}
+		namespace, err := cmd.Flags().GetString("namespace")
+		if err != nil {
+			return fmt.Errorf("failed to get namespace flag: %w", err)
+		}
+		transports, err := cmd.Flags().GetStringSlice("transports")
+		if err != nil {
+			return fmt.Errorf("failed to get transports flag: %w", err)
+		}
+		keep, err := cmd.Flags().GetBool("keep")
+		if err != nil {
+			return fmt.Errorf("failed to get keep flag: %w", err)
+		}
+		if count <= 0 {
+			return fmt.Errorf("--vectors must be positive")
+		}
+		if namespace == "" {
+			return fmt.Errorf("--namespace is required")
+		}
+
+		ctx := cmd.Context()
+		res := benchUpsertResult{Namespace: namespace, Vectors: count, BatchSize: cfg.Pinecone.UpsertBatchSize}
+		for _, transport := range transports {
+			if transport != "http" && transport != "grpc" {
+				return fmt.Errorf("unknown transport %q (want http or grpc)", transport)
+			}
+			benchCfg := *cfg
+			benchCfg.Pinecone.Transport = transport
+			client, err := pinecone.NewPineconeClient(&benchCfg, logger.Log)
+			if err != nil {
+				return fmt.Errorf("failed to create %s client: %w", transport, err)
+			}
+
+			vectors := benchVectors("bench-"+transport, count, cfg.Pinecone.Dimension)
+			start := time.Now()
+			err = client.UpsertVectorsInNamespace(ctx, namespace, vectors)
+			elapsed := time.Since(start)
+			if err == nil && !keep {
+				ids := make([]string, len(vectors))
+				for i, v := range vectors {
+					ids[i] = v.ID
+				}
+				err = client.DeleteVectorsInNamespace(ctx, namespace, ids)
+			}
+			client.Close() //nolint:errcheck
+			if err != nil {
+				return fmt.Errorf("%s upsert benchmark failed: %w", transport, err)
+			}
+
+			res.Runs = append(res.Runs, benchUpsertRun{
+				Transport:        transport,
+				Seconds:          elapsed.Seconds(),
+				VectorsPerSecond: float64(count) / elapsed.Seconds(),
+				Throttled:        client.UpsertStats().Throttled,
+			})
+		}
+		return printResult(res)
+	},
+}
+
+// benchVectors returns random vectors with about as much metadata text
+// as a chunk vector carries
+func benchVectors(prefix string, count, dimension int) []*pinecone.Vector {
+	text := strings.Repeat("benchmark chunk content ", 40)
+	vectors := make([]*pinecone.Vector, count)
+	for i := range vectors {
+		values := make([]float32, dimension)
+		for j := range values {
+			values[j] = rand.Float32()*2 - 1
+		}
+		vectors[i] = &pinecone.Vector{
+			ID:       fmt.Sprintf("%s-%d", prefix, i),
+			Values:   values,
+			Metadata: map[string]interface{}{"content": text, "chunk_index": i},
+		}
+	}
+	return vectors
+}
+
+// benchUpsertResult is the output of the bench upsert command
+type benchUpsertResult struct {
+	Namespace string           `json:"namespace"`
+	Vectors   int              `json:"vectors"`
+	BatchSize int              `json:"batch_size"`
+	Runs      []benchUpsertRun `json:"runs"`
+}
+
+// benchUpsertRun is the throughput of one transport
+type benchUpsertRun struct {
+	Transport        string  `json:"transport"`
+	Seconds          float64 `json:"seconds"`
+	VectorsPerSecond float64 `json:"vectors_per_second"`
+	Throttled        int64   `json:"throttled"`
+}
+
+func (r benchUpsertResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "⏱️  Upsert throughput (%d vectors, batches of %d, namespace %s)\n\n", r.Vectors, r.BatchSize, r.Namespace)
+	for _, run := range r.Runs {
+		fmt.Fprintf(w, "%-5s %8.2fs  %10.1f vectors/s", run.Transport, run.Seconds, run.VectorsPerSecond)
+		if run.Throttled > 0 {
+			fmt.Fprintf(w, "  (%d throttled batches)", run.Throttled)
+		}
+		fmt.Fprintln(w)
+	}
+	if len(r.Runs) == 2 && r.Runs[0].VectorsPerSecond > 0 {
+		fmt.Fprintf(w, "\n%s is %.2fx the throughput of %s\n",
+			r.Runs[1].Transport, r.Runs[1].VectorsPerSecond/r.Runs[0].VectorsPerSecond, r.Runs[0].Transport)
+	}
+}
+
+func (r benchUpsertResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Runs))
+	for _, run := range r.Runs {
+		rows = append(rows, []string{
+			run.Transport,
+			strconv.Itoa(r.Vectors),
+			strconv.FormatFloat(run.Seconds, 'f', 2, 64),
+			strconv.FormatFloat(run.VectorsPerSecond, 'f', 1, 64),
+			strconv.FormatInt(run.Throttled, 10),
+		})
+	}
+	writeRows(w, []string{"TRANSPORT", "VECTORS", "SECONDS", "VECTORS/S", "THROTTLED"}, rows)
+}
+
+func init() {
+	benchUpsertCmd.Flags().Int("vectors", 10000, "number of vectors to upsert over each transport")
+	benchUpsertCmd.Flags().String("namespace", "bench", "scratch namespace to upsert into")
+	benchUpsertCmd.Flags().StringSlice("transports", []string{"http", "grpc"}, "transports to measure, in order")
+	benchUpsertCmd.Flags().Bool("keep", false, "keep the vectors instead of deleting them")
+	benchCmd.AddCommand(benchUpsertCmd)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/collections"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// collectionBatchSize is the most document IDs added in one request
+const collectionBatchSize = 1000
+
+var collectionsCmd = &cobra.Command{
+	Use:   "collections",
+	Short: "Organize documents into collections",
+	Long: `Create named collections of documents and manage their members. Queries
+are scoped to a collection with "rag-cli query ask --collection NAME".
+Membership follows a document's file path, so new versions of a document
+stay in its collections.`,
+}
+
+var collectionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List collections",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		list, err := orchestrator.Collections(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list collections: %w", err)
+		}
+		return printResult(collectionListResult{Collections: list, Count: len(list)})
+	},
+}
+
+// collectionListResult is the output of the collections list command
+type collectionListResult struct {
+	Collections []client.CollectionSummary `json:"collections"`
+	Count       int                        `json:"count"`
+}
+
+func (r collectionListResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗂️  Collections (%d)\n\n", r.Count)
+	for _, c := range r.Collections {
+		fmt.Fprintf(w, "%-24s %d documents · updated %s\n", c.Name, c.DocumentCount, formatTime(c.UpdatedAt))
+		if c.Description != "" {
+			fmt.Fprintf(w, "   %s\n", c.Description)
+		}
+	}
+}
+
+func (r collectionListResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Collections))
+	for _, c := range r.Collections {
+		rows = append(rows, []string{c.Name, strconv.Itoa(c.DocumentCount), formatTime(c.UpdatedAt), c.Description})
+	}
+	writeRows(w, []string{"NAME", "DOCUMENTS", "UPDATED", "DESCRIPTION"}, rows)
+}
+
+var collectionsCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a collection",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		description, err := cmd.Flags().GetString("description")
+		if err != nil {
+			return fmt.Errorf("failed to get description flag: %w", err)
+		}
+
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		collection, err := orchestrator.CreateCollection(cmd.Context(), args[0], description)
+		if err != nil {
+			return fmt.Errorf("failed to create collection: %w", err)
+		}
+		return printResult(collectionResult{collection})
+	},
+}
+
+var collectionsShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show a collection and its documents",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		collection, err := orchestrator.Collection(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get collection: %w", err)
+		}
+		return printResult(collectionResult{collection})
+	},
+}
+
+// collectionResult is the output of the collection commands that show a
+// single collection
+type collectionResult struct {
+	*collections.Collection
+}
+
+func (r collectionResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗂️  %s (%d documents)\n", r.Name, len(r.Paths))
+	if r.Description != "" {
+		fmt.Fprintf(w, "   %s\n", r.Description)
+	}
+	if len(r.Paths) > 0 {
+		fmt.Fprintln(w)
+	}
+	for _, p := range r.Paths {
+		fmt.Fprintf(w, "   %s\n", p)
+	}
+}
+
+func (r collectionResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Paths))
+	for _, p := range r.Paths {
+		rows = append(rows, []string{r.Name, p})
+	}
+	writeRows(w, []string{"COLLECTION", "PATH"}, rows)
+}
+
+var collectionsDeleteCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Delete a collection",
+	Long:  `Delete a collection. Its documents stay indexed.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		if err := orchestrator.DeleteCollection(cmd.Context(), args[0]); err != nil {
+			return fmt.Errorf("failed to delete collection: %w", err)
+		}
+		return printResult(collectionDeleteResult{Collection: args[0], Status: "deleted"})
+	},
+}
+
+// collectionDeleteResult is the output of the collections delete command
+type collectionDeleteResult struct {
+	Collection string `json:"collection"`
+	Status     string `json:"status"`
+}
+
+func (r collectionDeleteResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗑️  Deleted collection %s\n", r.Collection)
+}
+
+func (r collectionDeleteResult) writeTable(w io.Writer) {
+	writeRows(w, []string{"COLLECTION", "STATUS"}, [][]string{{r.Collection, r.Status}})
+}
+
+var collectionsAddCmd = &cobra.Command{
+	Use:   "add [name] [id|path...]",
+	Short: "Add documents to a collection",
+	Long: `Add documents to a collection, given by ID or path, or selected with the
+filter flags. Exits with status 2 when some documents were rejected.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		ids, err := selectDocuments(cmd, orchestrator, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("no documents selected")
+		}
+
+		var update *client.CollectionUpdate
+		rejected := map[string]string{}
+		added := 0
+		for start := 0; start < len(ids); start += collectionBatchSize {
+			end := min(start+collectionBatchSize, len(ids))
+			update, err = orchestrator.AddToCollection(cmd.Context(), args[0], ids[start:end])
+			if err != nil {
+				return fmt.Errorf("failed to add documents: %w", err)
+			}
+			added += update.Added
+			for id, reason := range update.Rejected {
+				rejected[id] = reason
+			}
+		}
+		update.Added, update.Rejected = added, rejected
+
+		if err := printResult(collectionUpdateResult{update}); err != nil {
+			return err
+		}
+		return partialFailure(len(rejected), "document(s)")
+	},
+}
+
+// collectionUpdateResult is the output of the collections add command
+type collectionUpdateResult struct {
+	*client.CollectionUpdate
+}
+
+func (r collectionUpdateResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "✅ Added %d document(s) to %s (%d documents)\n", r.Added, r.Collection.Name, len(r.Collection.Paths))
+	for _, id := range sortedKeys(r.Rejected) {
+		fmt.Fprintf(w, "❌ %s: %s\n", id, r.Rejected[id])
+	}
+}
+
+func (r collectionUpdateResult) writeTable(w io.Writer) {
+	rows := [][]string{{r.Collection.Name, strconv.Itoa(r.Added), strconv.Itoa(len(r.Rejected)), strconv.Itoa(len(r.Collection.Paths))}}
+	writeRows(w, []string{"COLLECTION", "ADDED", "REJECTED", "DOCUMENTS"}, rows)
+}
+
+var collectionsRemoveCmd = &cobra.Command{
+	Use:   "remove [name] [id|path...]",
+	Short: "Remove documents from a collection",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		var collection *collections.Collection
+		for _, ref := range args[1:] {
+			record, err := findDocument(cmd, orchestrator, ref)
+			if err != nil {
+				return err
+			}
+			if collection, err = orchestrator.RemoveFromCollection(cmd.Context(), args[0], record.ID); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", record.FilePath, err)
+			}
+		}
+		return printResult(collectionResult{collection})
+	},
+}
+
+func init() {
+	collectionsCreateCmd.Flags().StringP("description", "d", "", "Description of the collection")
+	collectionsAddCmd.Flags().String("category", "", "Select documents by category")
+	collectionsAddCmd.Flags().String("state", "", "Select documents by processing state (e.g. INDEXED)")
+	collectionsAddCmd.Flags().String("path", "", "Select documents by file path prefix")
+
+	collectionsCmd.AddCommand(collectionsListCmd)
+	collectionsCmd.AddCommand(collectionsCreateCmd)
+	collectionsCmd.AddCommand(collectionsShowCmd)
+	collectionsCmd.AddCommand(collectionsDeleteCmd)
+	collectionsCmd.AddCommand(collectionsAddCmd)
+	collectionsCmd.AddCommand(collectionsRemoveCmd)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var digestsCmd = &cobra.Command{
+	Use:   "digests",
+	Short: "Run scheduled digest reports",
+	Long: `List the digest reports the orchestrator runs on a schedule, or generate one
+now. Reports are defined in DIGEST_REPORTS_FILE.`,
+}
+
+var digestsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List digest reports",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		reports, err := orchestrator.Digests(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list digest reports: %w", err)
+		}
+		return printResult(digestListResult{Reports: reports, Count: len(reports)})
+	},
+}
+
+// digestListResult is the output of the digests list command
+type digestListResult struct {
+	Reports []client.DigestReport `json:"reports"`
+	Count   int                   `json:"count"`
+}
+
+func (r digestListResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "📰 Digest reports (%d)\n\n", r.Count)
+	for _, d := range r.Reports {
+		fmt.Fprintf(w, "%-24s %s · %s\n", d.Name, d.Schedule, d.Title)
+		if d.NextRun != nil {
+			fmt.Fprintf(w, "   next run %s\n", formatTime(*d.NextRun))
+		}
+		if d.LastRun != nil {
+			fmt.Fprintf(w, "   last run %s\n", formatTime(*d.LastRun))
+		}
+		if d.LastError != "" {
+			fmt.Fprintf(w, "   ❌ %s\n", d.LastError)
+		}
+	}
+}
+
+func (r digestListResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Reports))
+	for _, d := range r.Reports {
+		next, last := "", ""
+		if d.NextRun != nil {
+			next = formatTime(*d.NextRun)
+		}
+		if d.LastRun != nil {
+			last = formatTime(*d.LastRun)
+		}
+		rows = append(rows, []string{d.Name, d.Schedule, d.Format, next, last, d.LastError})
+	}
+	writeRows(w, []string{"NAME", "SCHEDULE", "FORMAT", "NEXT RUN", "LAST RUN", "ERROR"}, rows)
+}
+
+var digestsRunCmd = &cobra.Command{
+	Use:   "run [name]",
+	Short: "Generate a digest report now",
+	Long: `Generate a digest report now and deliver it to its webhook and email
+recipients. With --preview the digest is printed instead of delivered.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		preview, err := cmd.Flags().GetBool("preview")
+		if err != nil {
+			return fmt.Errorf("failed to get preview flag: %w", err)
+		}
+
+		// Answering every saved query takes a while, and a retry would
+		// deliver the digest twice
+		opts := clientOptions()
+		opts.Timeout = 5 * time.Minute
+		opts.MaxRetries = 0
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, opts)
+		run, err := orchestrator.RunDigest(cmd.Context(), args[0], preview)
+		if err != nil {
+			return fmt.Errorf("failed to run digest report: %w", err)
+		}
+		return printResult(digestRunResult{Report: args[0], DigestRun: run})
+	},
+}
+
+// digestRunResult is the output of the digests run command
+type digestRunResult struct {
+	Report string `json:"report"`
+	*client.DigestRun
+}
+
+func (r digestRunResult) writeText(w io.Writer) {
+	if !r.Delivered {
+		fmt.Fprint(w, r.Body)
+		return
+	}
+	fmt.Fprintf(w, "📨 Delivered digest %s\n", r.Report)
+}
+
+func (r digestRunResult) writeTable(w io.Writer) {
+	writeRows(w, []string{"REPORT", "DELIVERED"}, [][]string{{r.Report, fmt.Sprint(r.Delivered)}})
+}
+
+func init() {
+	digestsRunCmd.Flags().Bool("preview", false, "Print the digest instead of delivering it")
+
+	digestsCmd.AddCommand(digestsListCmd)
+	digestsCmd.AddCommand(digestsRunCmd)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/dlq"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var dlqCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "Inspect and retry files that failed every attempt",
+	Long: `List the orchestrator's dead-letter entries: files that still failed after
+DLQ_MAX_RETRIES retries, with the error chain and the stage they reached.
+Entries can be queued for processing again, discarded or exported.`,
+}
+
+var dlqListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List dead-letter entries, most recent failure first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("failed to get limit flag: %w", err)
+		}
+
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		entries, err := orchestrator.DeadLetters(cmd.Context(), limit)
+		if err != nil {
+			return fmt.Errorf("failed to list dead-letter entries: %w", err)
+		}
+		return printResult(dlqListResult{Entries: entries, Count: len(entries)})
+	},
+}
+
+// dlqListResult is the output of the dlq list command
+type dlqListResult struct {
+	Entries []*dlq.Entry `json:"entries"`
+	Count   int          `json:"count"`
+}
+
+func (r dlqListResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "☠️  Dead-letter entries (%d)\n\n", r.Count)
+	for _, e := range r.Entries {
+		fmt.Fprintf(w, "%s  %s  %s\n", e.ID, formatTime(e.FailedAt), e.FilePath)
+		fmt.Fprintf(w, "   Attempts: %d  Source: %s", e.Attempts, e.Source)
+		if e.Stage != "" {
+			fmt.Fprintf(w, "  Stage: %s", e.Stage)
+		}
+		fmt.Fprintln(w)
+		for i, msg := range e.Errors {
+			prefix := "   Error: "
+			if i > 0 {
+				prefix = "   Cause: "
+			}
+			fmt.Fprintf(w, "%s%s\n", prefix, msg)
+		}
+	}
+}
+
+func (r dlqListResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Entries))
+	for _, e := range r.Entries {
+		lastErr := ""
+		if len(e.Errors) > 0 {
+			lastErr = e.Errors[len(e.Errors)-1]
+		}
+		rows = append(rows, []string{
+			e.ID,
+			formatTime(e.FailedAt),
+			e.FilePath,
+			e.Stage,
+			strconv.Itoa(e.Attempts),
+			lastErr,
+		})
+	}
+	writeRows(w, []string{"ID", "FAILED", "FILE", "STAGE", "ATTEMPTS", "CAUSE"}, rows)
+}
+
+var dlqRetryCmd = &cobra.Command{
+	Use:   "retry [id...]",
+	Short: "Queue dead-lettered files for processing again",
+	Long: `Queue the files of the given dead-letter entries, or of every entry with
+--all, for processing again and remove their entries. A file that fails every
+attempt again gets a new entry. Exits with status 2 when some entries were
+not queued.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			return fmt.Errorf("failed to get all flag: %w", err)
+		}
+		priority, err := cmd.Flags().GetString("priority")
+		if err != nil {
+			return fmt.Errorf("failed to get priority flag: %w", err)
+		}
+		if all == (len(args) > 0) {
+			return fmt.Errorf("give entry IDs or --all, not both or neither")
+		}
+
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		result, err := orchestrator.RetryDeadLetters(cmd.Context(), client.DeadLetterRetry{IDs: args, All: all, Priority: priority})
+		if err != nil {
+			return fmt.Errorf("failed to retry dead-letter entries: %w", err)
+		}
+		if err := printResult(dlqRetryResult{result}); err != nil {
+			return err
+		}
+		return partialFailure(len(result.Rejected), "entry(ies)")
+	},
+}
+
+// dlqRetryResult is the output of the dlq retry command
+type dlqRetryResult struct {
+	*client.DeadLetterRetryResult
+}
+
+func (r dlqRetryResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🔁 Queued %d file(s) for processing again\n", len(r.Queued))
+	for _, id := range sortedKeys(r.Rejected) {
+		fmt.Fprintf(w, "❌ %s: %s\n", id, r.Rejected[id])
+	}
+}
+
+func (r dlqRetryResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Queued)+len(r.Rejected))
+	for _, id := range r.Queued {
+		rows = append(rows, []string{id, "queued", ""})
+	}
+	for _, id := range sortedKeys(r.Rejected) {
+		rows = append(rows, []string{id, "rejected", r.Rejected[id]})
+	}
+	writeRows(w, []string{"ID", "STATUS", "REASON"}, rows)
+}
+
+var dlqDiscardCmd = &cobra.Command{
+	Use:   "discard [id]",
+	Short: "Remove a dead-letter entry without retrying it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		if err := orchestrator.DiscardDeadLetter(cmd.Context(), args[0]); err != nil {
+			return fmt.Errorf("failed to discard dead-letter entry: %w", err)
+		}
+		return printResult(dlqDiscardResult{ID: args[0], Status: "discarded"})
+	},
+}
+
+// dlqDiscardResult is the output of the dlq discard command
+type dlqDiscardResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func (r dlqDiscardResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗑️  Discarded dead-letter entry %s\n", r.ID)
+}
+
+func (r dlqDiscardResult) writeTable(w io.Writer) {
+	writeRows(w, []string{"ID", "STATUS"}, [][]string{{r.ID, r.Status}})
+}
+
+var dlqExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export every dead-letter entry as JSON lines or CSV",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("failed to get format flag: %w", err)
+		}
+		output, err := cmd.Flags().GetString("file")
+		if err != nil {
+			return fmt.Errorf("failed to get file flag: %w", err)
+		}
+		if format != dlq.FormatJSONL && format != dlq.FormatCSV {
+			return fmt.Errorf("format must be %s or %s", dlq.FormatJSONL, dlq.FormatCSV)
+		}
+
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		entries, err := orchestrator.DeadLetters(cmd.Context(), 0)
+		if err != nil {
+			return fmt.Errorf("failed to list dead-letter entries: %w", err)
+		}
+
+		w := io.Writer(os.Stdout)
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create export file: %w", err)
+			}
+			defer f.Close() //nolint:errcheck
+			w = f
+		}
+		if err := dlq.Export(w, entries, format); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		if output != "" {
+			fmt.Fprintf(os.Stderr, "Exported %d dead-letter entries to %s\n", len(entries), output)
+		}
+		return nil
+	},
+}
+
+func init() {
+	dlqListCmd.Flags().IntP("limit", "n", 50, "Maximum number of entries to list")
+	dlqRetryCmd.Flags().Bool("all", false, "Retry every entry")
+	dlqRetryCmd.Flags().String("priority", "", "Queue priority: high, normal or low")
+	dlqExportCmd.Flags().String("format", dlq.FormatJSONL, "Export format: jsonl or csv")
+	dlqExportCmd.Flags().StringP("file", "f", "", "Write to this file instead of standard output")
+
+	dlqCmd.AddCommand(dlqListCmd)
+	dlqCmd.AddCommand(dlqRetryCmd)
+	dlqCmd.AddCommand(dlqDiscardCmd)
+	dlqCmd.AddCommand(dlqExportCmd)
+}
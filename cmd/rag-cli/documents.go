@@ -0,0 +1,676 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"github.com/nadeeshame/rag-knowledge-service/internal/query"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var documentsCmd = &cobra.Command{
+	Use:   "documents",
+	Short: "Browse the document registry",
+	Long:  `List the documents known to the orchestrator and show their processing details.`,
+}
+
+var documentsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List documents",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := client.DocumentFilter{}
+		var err error
+		if filter.Category, err = cmd.Flags().GetString("category"); err != nil {
+			return fmt.Errorf("failed to get category flag: %w", err)
+		}
+		if filter.State, err = cmd.Flags().GetString("state"); err != nil {
+			return fmt.Errorf("failed to get state flag: %w", err)
+		}
+		if filter.PathPrefix, err = cmd.Flags().GetString("path"); err != nil {
+			return fmt.Errorf("failed to get path flag: %w", err)
+		}
+		if filter.Limit, err = cmd.Flags().GetInt("limit"); err != nil {
+			return fmt.Errorf("failed to get limit flag: %w", err)
+		}
+		if filter.NeedsEnrichment, err = cmd.Flags().GetBool("needs-enrichment"); err != nil {
+			return fmt.Errorf("failed to get needs-enrichment flag: %w", err)
+		}
+		filter.State = strings.ToUpper(filter.State)
+
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		records, err := orchestrator.Documents(cmd.Context(), filter)
+		if err != nil {
+			return fmt.Errorf("failed to list documents: %w", err)
+		}
+
+		return printResult(documentListResult{Documents: records, Count: len(records)})
+	},
+}
+
+// documentListResult is the output of the documents list command
+type documentListResult struct {
+	Documents []*registry.Record `json:"documents"`
+	Count     int                `json:"count"`
+}
+
+func (r documentListResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "📚 Documents (%d)\n\n", r.Count)
+	for _, d := range r.Documents {
+		fmt.Fprintf(w, "%s %-10s %s\n", stateIcon(d.State), d.State, d.FilePath)
+		fmt.Fprintf(w, "   %s · %d chunks · updated %s\n", d.ID, d.ChunkCount, formatTime(d.UpdatedAt))
+	}
+}
+
+func (r documentListResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Documents))
+	for _, d := range r.Documents {
+		rows = append(rows, []string{
+			d.ID,
+			string(d.State),
+			d.Category,
+			strconv.Itoa(d.ChunkCount),
+			formatTime(d.UpdatedAt),
+			d.FilePath,
+		})
+	}
+	writeRows(w, []string{"ID", "STATE", "CATEGORY", "CHUNKS", "UPDATED", "PATH"}, rows)
+}
+
+var documentsShowCmd = &cobra.Command{
+	Use:   "show [id|path]",
+	Short: "Show a document",
+	Long:  `Show the full registry record of a document, looked up by document ID or file path.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		record, err := findDocument(cmd, orchestrator, args[0])
+		if err != nil {
+			return err
+		}
+		return printResult(documentResult{record})
+	},
+}
+
+// findDocument looks a document up by ID, falling back to an exact file path match
+func findDocument(cmd *cobra.Command, orchestrator client.Orchestrator, ref string) (*registry.Record, error) {
+	record, err := orchestrator.Document(cmd.Context(), ref)
+	if err == nil {
+		return record, nil
+	}
+	if !client.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+
+	paths := []string{utils.NormalizePath(ref)}
+	if !utils.IsWindowsPath(ref) {
+		if abs, absErr := filepath.Abs(ref); absErr == nil && utils.NormalizePath(abs) != paths[0] {
+			paths = append(paths, utils.NormalizePath(abs))
+		}
+	}
+	for _, path := range paths {
+		records, err := orchestrator.Documents(cmd.Context(), client.DocumentFilter{PathPrefix: path})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up document by path: %w", err)
+		}
+		for _, r := range records {
+			if r.FilePath == path {
+				return r, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no document with ID or path %q", ref)
+}
+
+// documentResult is the output of the documents show command
+type documentResult struct {
+	*registry.Record
+}
+
+// fields lists the record's populated fields in display order
+func (r documentResult) fields() [][]string {
+	fields := [][]string{
+		{"ID", r.ID},
+		{"Path", r.FilePath},
+		{"Name", r.FileName},
+		{"Type", r.FileType},
+		{"Category", r.Category},
+		{"Content Type", r.ContentType},
+		{"State", string(r.State)},
+		{"Chunks", strconv.Itoa(r.ChunkCount)},
+		{"Deduplicated", strconv.Itoa(r.DedupedChunks)},
+		{"Hash", r.FileHash},
+		{"Created", formatTime(r.CreatedAt)},
+		{"Updated", formatTime(r.UpdatedAt)},
+	}
+	if r.IndexedAt != nil {
+		fields = append(fields, []string{"Indexed", formatTime(*r.IndexedAt)})
+	}
+	if r.Title != "" {
+		fields = append(fields, []string{"Title", r.Title})
+	}
+	if len(r.Tags) > 0 {
+		fields = append(fields, []string{"Tags", strings.Join(r.Tags, ", ")})
+	}
+	if len(r.Aliases) > 0 {
+		fields = append(fields, []string{"Aliases", strings.Join(r.Aliases, ", ")})
+	}
+	if r.Dataset != nil {
+		tables := make([]string, len(r.Dataset.Tables))
+		for i, t := range r.Dataset.Tables {
+			tables[i] = fmt.Sprintf("%s (%d rows, %d columns)", t.Name, t.Rows, len(t.Columns))
+			if t.Kind == "view" {
+				tables[i] = t.Name + " (view)"
+			}
+		}
+		fields = append(fields, []string{"Tables", strings.Join(tables, ", ")})
+	}
+	if r.Summary != "" {
+		fields = append(fields, []string{"Summary", r.Summary})
+	}
+	if r.TypeMismatch != "" {
+		fields = append(fields, []string{"Type Warning", r.TypeMismatch})
+	}
+	if len(r.NeedsEnrichment) > 0 {
+		fields = append(fields, []string{"Needs", strings.Join(r.NeedsEnrichment, ", ")})
+	}
+	if r.Error != "" {
+		fields = append(fields, []string{"Error", r.Error})
+	}
+	return fields
+}
+
+func (r documentResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "%s %s\n\n", stateIcon(r.State), r.FileName)
+	for _, f := range r.fields() {
+		fmt.Fprintf(w, "%-13s %s\n", f[0]+":", f[1])
+	}
+}
+
+func (r documentResult) writeTable(w io.Writer) {
+	rows := r.fields()
+	for _, row := range rows {
+		row[1] = strings.Join(strings.Fields(row[1]), " ")
+	}
+	writeRows(w, []string{"FIELD", "VALUE"}, rows)
+}
+
+var documentsLinksCmd = &cobra.Command{
+	Use:   "links [id|path]",
+	Short: "Show the wiki-links of a note",
+	Long: `Show the documents a Markdown note links to with [[wiki-links]], the notes
+linking to it, and the links that match no document.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		record, err := findDocument(cmd, orchestrator, args[0])
+		if err != nil {
+			return err
+		}
+		links, err := orchestrator.DocumentLinks(cmd.Context(), record.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get document links: %w", err)
+		}
+		return printResult(documentLinksResult{FilePath: record.FilePath, DocumentLinks: links})
+	},
+}
+
+// documentLinksResult is the output of the documents links command
+type documentLinksResult struct {
+	FilePath string `json:"file_path"`
+	*client.DocumentLinks
+}
+
+func (r documentLinksResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🔗 %s\n", r.FilePath)
+	fmt.Fprintf(w, "\nLinks (%d)\n", len(r.Links))
+	for _, l := range r.Links {
+		fmt.Fprintf(w, "  → [[%s]] %s\n", l.Target, l.FilePath)
+	}
+	fmt.Fprintf(w, "\nBacklinks (%d)\n", len(r.Backlinks))
+	for _, l := range r.Backlinks {
+		fmt.Fprintf(w, "  ← %s\n", l.FilePath)
+	}
+	if len(r.Unresolved) > 0 {
+		fmt.Fprintf(w, "\nUnresolved (%d)\n", len(r.Unresolved))
+		for _, target := range r.Unresolved {
+			fmt.Fprintf(w, "  ✗ [[%s]]\n", target)
+		}
+	}
+}
+
+func (r documentLinksResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Links)+len(r.Backlinks)+len(r.Unresolved))
+	for _, l := range r.Links {
+		rows = append(rows, []string{"link", l.Target, l.DocumentID, l.FilePath})
+	}
+	for _, l := range r.Backlinks {
+		rows = append(rows, []string{"backlink", "", l.DocumentID, l.FilePath})
+	}
+	for _, target := range r.Unresolved {
+		rows = append(rows, []string{"unresolved", target, "", ""})
+	}
+	writeRows(w, []string{"DIRECTION", "TARGET", "ID", "PATH"}, rows)
+}
+
+var documentsSimilarCmd = &cobra.Command{
+	Use:   "similar [id|path]",
+	Short: "Find the documents most like a document",
+	Long: `Find the documents most like a document by its chunk vectors, such as
+duplicate specs and related designs. --method mean searches with the mean of
+its chunks; --method sample searches with a sample of them one by one, which
+also finds documents sharing only a part of it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		method, err := cmd.Flags().GetString("method")
+		if err != nil {
+			return fmt.Errorf("failed to get method flag: %w", err)
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("failed to get limit flag: %w", err)
+		}
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		record, err := findDocument(cmd, orchestrator, args[0])
+		if err != nil {
+			return err
+		}
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, clientOptions())
+		documents, err := querier.SimilarDocuments(cmd.Context(), record.ID, method, limit)
+		if err != nil {
+			return fmt.Errorf("failed to find similar documents: %w", err)
+		}
+		return printResult(similarDocumentsResult{FilePath: record.FilePath, Documents: documents, Count: len(documents)})
+	},
+}
+
+// similarDocumentsResult is the output of the documents similar command
+type similarDocumentsResult struct {
+	FilePath  string                   `json:"file_path"`
+	Documents []models.SimilarDocument `json:"documents"`
+	Count     int                      `json:"count"`
+}
+
+func (r similarDocumentsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "📄 Documents like %s\n\n", r.FilePath)
+	if r.Count == 0 {
+		fmt.Fprintln(w, "No similar documents found")
+		return
+	}
+	for i, d := range r.Documents {
+		fmt.Fprintf(w, "  [%d] %s (score %.3f, %d matching chunks)\n      %s\n", i+1, d.FileName, d.Score, d.Matches, d.FilePath)
+	}
+}
+
+func (r similarDocumentsResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Documents))
+	for _, d := range r.Documents {
+		rows = append(rows, []string{d.DocumentID, fmt.Sprintf("%.3f", d.Score), strconv.Itoa(d.Matches), d.FilePath})
+	}
+	writeRows(w, []string{"ID", "SCORE", "MATCHES", "PATH"}, rows)
+}
+
+var documentsDuplicatesCmd = &cobra.Command{
+	Use:   "duplicates",
+	Short: "Report duplicate and near-duplicate documents",
+	Long: `Report indexed documents at different paths with identical file hashes, and
+those whose summary embeddings are at least --threshold alike, so redundant
+copies can be removed before they crowd out other sources in answers. Each
+group lists its oldest document first. Near duplicates need SUMMARY_VECTORS.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		threshold, err := cmd.Flags().GetFloat64("threshold")
+		if err != nil {
+			return fmt.Errorf("failed to get threshold flag: %w", err)
+		}
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		report, err := orchestrator.Duplicates(cmd.Context(), threshold)
+		if err != nil {
+			return fmt.Errorf("failed to get duplicate report: %w", err)
+		}
+		return printResult(duplicatesResult{DuplicateReport: report})
+	},
+}
+
+// duplicatesResult is the output of the documents duplicates command
+type duplicatesResult struct {
+	*orchestrator.DuplicateReport
+}
+
+func (r duplicatesResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "📑 %d exact and %d near-duplicate groups in %d documents (%d redundant)\n",
+		len(r.Exact), len(r.Near), r.Documents, r.Redundant)
+	if r.Unembedded > 0 {
+		fmt.Fprintf(w, "   %d documents without a summary vector were not compared\n", r.Unembedded)
+	}
+	for _, g := range r.Exact {
+		fmt.Fprintf(w, "\nIdentical (%s)\n", g.FileHash)
+		for _, d := range g.Documents {
+			fmt.Fprintf(w, "   %s\n", d.FilePath)
+		}
+	}
+	for _, g := range r.Near {
+		fmt.Fprintf(w, "\nNear duplicates (similarity %.3f or more)\n", g.Similarity)
+		for i, d := range g.Documents {
+			if i == 0 {
+				fmt.Fprintf(w, "   %s\n", d.FilePath)
+				continue
+			}
+			fmt.Fprintf(w, "   %s (%.3f)\n", d.FilePath, d.Similarity)
+		}
+	}
+}
+
+func (r duplicatesResult) writeTable(w io.Writer) {
+	var rows [][]string
+	for _, kind := range []string{"exact", "near"} {
+		groups := r.Exact
+		if kind == "near" {
+			groups = r.Near
+		}
+		for n, g := range groups {
+			for _, d := range g.Documents {
+				similarity := ""
+				if kind == "near" {
+					similarity = fmt.Sprintf("%.3f", d.Similarity)
+				}
+				rows = append(rows, []string{kind, strconv.Itoa(n + 1), d.DocumentID, similarity, d.FilePath})
+			}
+		}
+	}
+	writeRows(w, []string{"KIND", "GROUP", "ID", "SIMILARITY", "PATH"}, rows)
+}
+
+var documentsStaleCmd = &cobra.Command{
+	Use:   "stale",
+	Short: "List documents not updated for months",
+	Long: `List indexed documents whose files have not been modified for --months,
+oldest first, as those most likely to hold outdated answers. Documents
+indexed before modification times were recorded are aged by when they were
+indexed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		months, err := cmd.Flags().GetInt("months")
+		if err != nil {
+			return fmt.Errorf("failed to get months flag: %w", err)
+		}
+		path, err := cmd.Flags().GetString("path")
+		if err != nil {
+			return fmt.Errorf("failed to get path flag: %w", err)
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("failed to get limit flag: %w", err)
+		}
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		report, err := orchestrator.StaleDocuments(cmd.Context(), months, path, limit)
+		if err != nil {
+			return fmt.Errorf("failed to list stale documents: %w", err)
+		}
+		return printResult(staleDocumentsResult{StaleReport: report})
+	},
+}
+
+// staleDocumentsResult is the output of the documents stale command
+type staleDocumentsResult struct {
+	*client.StaleReport
+}
+
+func (r staleDocumentsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🕰️  %d documents not modified for %d months (before %s)\n\n", r.Count, r.Months, formatTime(r.Before))
+	for _, d := range r.Documents {
+		fmt.Fprintf(w, "  %5d days  %s\n", d.AgeDays, d.FilePath)
+	}
+}
+
+func (r staleDocumentsResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Documents))
+	for _, d := range r.Documents {
+		modified := ""
+		if d.ModifiedAt != nil {
+			modified = formatTime(*d.ModifiedAt)
+		}
+		rows = append(rows, []string{d.DocumentID, strconv.Itoa(d.AgeDays), modified, d.FilePath})
+	}
+	writeRows(w, []string{"ID", "AGE (DAYS)", "MODIFIED", "PATH"}, rows)
+}
+
+var documentsPopularCmd = &cobra.Command{
+	Use:   "popular",
+	Short: "List the documents used in the most answers",
+	Long: `List the documents whose chunks appear in the most answers, with the ratings
+those answers got. The query service tracks usage when POPULARITY_ENABLED is
+set, and with POPULARITY_BOOST ranks popular, well rated documents higher.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("failed to get limit flag: %w", err)
+		}
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, clientOptions())
+		documents, err := querier.PopularDocuments(cmd.Context(), limit)
+		if err != nil {
+			return fmt.Errorf("failed to list popular documents: %w", err)
+		}
+		return printResult(popularDocumentsResult{Documents: documents, Count: len(documents)})
+	},
+}
+
+// popularDocumentsResult is the output of the documents popular command
+type popularDocumentsResult struct {
+	Documents []query.PopularDocument `json:"documents"`
+	Count     int                     `json:"count"`
+}
+
+func (r popularDocumentsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🔥 Most used documents (%d)\n\n", r.Count)
+	for _, d := range r.Documents {
+		name := d.FilePath
+		if name == "" {
+			name = d.DocumentID
+		}
+		fmt.Fprintf(w, "  %5d answers  👍 %d 👎 %d  %.2f  %s\n", d.Answers, d.Up, d.Down, d.Popularity, name)
+	}
+}
+
+func (r popularDocumentsResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Documents))
+	for _, d := range r.Documents {
+		rows = append(rows, []string{
+			d.DocumentID, strconv.Itoa(d.Answers), strconv.Itoa(d.Up), strconv.Itoa(d.Down),
+			fmt.Sprintf("%.3f", d.Popularity), formatTime(d.LastUsedAt), d.FilePath,
+		})
+	}
+	writeRows(w, []string{"ID", "ANSWERS", "UP", "DOWN", "POPULARITY", "LAST USED", "PATH"}, rows)
+}
+
+var documentsRechunkCmd = &cobra.Command{
+	Use:   "rechunk [id|path...]",
+	Short: "Chunk and embed documents again",
+	Long: `Chunk and embed documents again from the text kept in the orchestrator's
+content store, for example after changing the chunking or embedding settings,
+without extracting the files again. Each document gets a new version that
+supersedes the previous one. Documents are given by ID or path, or selected
+with the filter flags. Exits with status 2 when some documents were rejected.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rerunDocuments(cmd, args, "Rechunking", client.Orchestrator.Rechunk)
+	},
+}
+
+var documentsResummarizeCmd = &cobra.Command{
+	Use:   "resummarize [id|path...]",
+	Short: "Summarize documents again",
+	Long: `Generate document summaries again from the text kept in the orchestrator's
+content store, for example after changing the summarization prompt or model,
+without extracting the files again. Chunk vectors are left unchanged.
+Documents are given by ID or path, or selected with the filter flags. Exits
+with status 2 when some documents were rejected.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rerunDocuments(cmd, args, "Resummarizing", client.Orchestrator.Resummarize)
+	},
+}
+
+// rerunBatchSize is the most document IDs sent in one request
+const rerunBatchSize = 1000
+
+// rerunDocuments starts a stage for the documents given as arguments or
+// selected by the filter flags
+func rerunDocuments(cmd *cobra.Command, args []string, verb string, stage func(client.Orchestrator, context.Context, []string) (*client.RerunResult, error)) error {
+	orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+	ids, err := selectDocuments(cmd, orchestrator, args)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("no documents selected")
+	}
+
+	result := rerunResult{Verb: verb, Accepted: []string{}, Rejected: map[string]string{}}
+	for start := 0; start < len(ids); start += rerunBatchSize {
+		end := min(start+rerunBatchSize, len(ids))
+		res, err := stage(orchestrator, cmd.Context(), ids[start:end])
+		if err != nil {
+			return fmt.Errorf("failed to start %s: %w", strings.ToLower(verb), err)
+		}
+		result.Accepted = append(result.Accepted, res.Accepted...)
+		for id, reason := range res.Rejected {
+			result.Rejected[id] = reason
+		}
+	}
+
+	if err := printResult(result); err != nil {
+		return err
+	}
+	return partialFailure(len(result.Rejected), "document(s)")
+}
+
+// selectDocuments resolves document arguments by ID or path, or lists the
+// documents matching the filter flags when there are none
+func selectDocuments(cmd *cobra.Command, orchestrator client.Orchestrator, args []string) ([]string, error) {
+	filter := client.DocumentFilter{}
+	var err error
+	if filter.Category, err = cmd.Flags().GetString("category"); err != nil {
+		return nil, fmt.Errorf("failed to get category flag: %w", err)
+	}
+	if filter.State, err = cmd.Flags().GetString("state"); err != nil {
+		return nil, fmt.Errorf("failed to get state flag: %w", err)
+	}
+	if filter.PathPrefix, err = cmd.Flags().GetString("path"); err != nil {
+		return nil, fmt.Errorf("failed to get path flag: %w", err)
+	}
+	filter.State = strings.ToUpper(filter.State)
+	filtered := filter != client.DocumentFilter{}
+
+	if len(args) > 0 {
+		if filtered {
+			return nil, fmt.Errorf("give documents as arguments or select them with filters, not both")
+		}
+		ids := make([]string, 0, len(args))
+		for _, ref := range args {
+			record, err := findDocument(cmd, orchestrator, ref)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, record.ID)
+		}
+		return ids, nil
+	}
+	if !filtered {
+		return nil, fmt.Errorf("give document IDs or paths, or select documents with --category, --state or --path")
+	}
+
+	records, err := orchestrator.Documents(cmd.Context(), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	return ids, nil
+}
+
+// rerunResult is the output of the rechunk and resummarize commands
+type rerunResult struct {
+	Verb     string            `json:"-"`
+	Accepted []string          `json:"accepted"`
+	Rejected map[string]string `json:"rejected,omitempty"`
+}
+
+func (r rerunResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🔁 %s %d document(s) in the background\n", r.Verb, len(r.Accepted))
+	for _, id := range sortedKeys(r.Rejected) {
+		fmt.Fprintf(w, "❌ %s: %s\n", id, r.Rejected[id])
+	}
+}
+
+func (r rerunResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Accepted)+len(r.Rejected))
+	for _, id := range r.Accepted {
+		rows = append(rows, []string{id, "accepted", ""})
+	}
+	for _, id := range sortedKeys(r.Rejected) {
+		rows = append(rows, []string{id, "rejected", r.Rejected[id]})
+	}
+	writeRows(w, []string{"ID", "STATUS", "REASON"}, rows)
+}
+
+// stateIcon marks finished, failed and in-progress documents
+func stateIcon(state models.ProcessingState) string {
+	switch state {
+	case models.StateIndexed:
+		return "✅"
+	case models.StateFailed:
+		return "❌"
+	default:
+		return "⏳"
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func init() {
+	documentsListCmd.Flags().String("category", "", "Filter by category (document, image, diagram, spreadsheet, code, structured, data)")
+	documentsListCmd.Flags().String("state", "", "Filter by processing state (e.g. INDEXED, FAILED)")
+	documentsListCmd.Flags().String("path", "", "Filter by file path prefix")
+	documentsListCmd.Flags().Int("limit", 0, "Maximum number of documents (0 for all)")
+	documentsListCmd.Flags().Bool("needs-enrichment", false, "Only documents indexed with vision or summarization skipped")
+	documentsSimilarCmd.Flags().String("method", "mean", "Search with the mean of the chunks (mean) or a sample of them (sample)")
+	documentsSimilarCmd.Flags().Int("limit", 10, "Maximum number of documents (1-50)")
+	documentsStaleCmd.Flags().Int("months", 12, "Months without modification after which a document is stale")
+	documentsStaleCmd.Flags().String("path", "", "Only documents under this path prefix")
+	documentsStaleCmd.Flags().Int("limit", 0, "Maximum number of documents (0 for all)")
+	documentsPopularCmd.Flags().Int("limit", 20, "Maximum number of documents (1-100)")
+	documentsDuplicatesCmd.Flags().Float64("threshold", orchestrator.DefaultDuplicateThreshold, "Summary similarity at or above which documents are near duplicates")
+
+	for _, c := range []*cobra.Command{documentsRechunkCmd, documentsResummarizeCmd} {
+		c.Flags().String("category", "", "Select documents by category")
+		c.Flags().String("state", "", "Select documents by processing state (e.g. INDEXED)")
+		c.Flags().String("path", "", "Select documents by file path prefix")
+	}
+
+	documentsCmd.AddCommand(documentsListCmd)
+	documentsCmd.AddCommand(documentsShowCmd)
+	documentsCmd.AddCommand(documentsLinksCmd)
+	documentsCmd.AddCommand(documentsSimilarCmd)
+	documentsCmd.AddCommand(documentsDuplicatesCmd)
+	documentsCmd.AddCommand(documentsStaleCmd)
+	documentsCmd.AddCommand(documentsPopularCmd)
+	documentsCmd.AddCommand(documentsRechunkCmd)
+	documentsCmd.AddCommand(documentsResummarizeCmd)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/events"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show pipeline events: document state changes and run progress",
+	Long: `Show the events the orchestrator publishes to its Redis stream when
+EVENTS_ENABLED is set: document.state-changed as documents move through the
+pipeline and run.progress as directory runs start, finish files and end.
+Events are read from the oldest kept, or after --after; --follow keeps
+polling for new events until interrupted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		after, err := cmd.Flags().GetString("after")
+		if err != nil {
+			return fmt.Errorf("failed to get after flag: %w", err)
+		}
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("failed to get limit flag: %w", err)
+		}
+		types, err := cmd.Flags().GetStringSlice("type")
+		if err != nil {
+			return fmt.Errorf("failed to get type flag: %w", err)
+		}
+		follow, err := cmd.Flags().GetBool("follow")
+		if err != nil {
+			return fmt.Errorf("failed to get follow flag: %w", err)
+		}
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			return fmt.Errorf("failed to get interval flag: %w", err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("interval must be positive")
+		}
+
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		for {
+			page, err := orchestrator.Events(cmd.Context(), after, limit, types...)
+			if err != nil {
+				return fmt.Errorf("failed to read events: %w", err)
+			}
+			if !follow {
+				return printResult(eventsResult{page})
+			}
+			if len(page.Events) > 0 {
+				if err := printResult(eventsResult{page}); err != nil {
+					return err
+				}
+			}
+			// A full page means more events are waiting
+			if page.Next != after && len(page.Events) == limit {
+				after = page.Next
+				continue
+			}
+			after = page.Next
+			select {
+			case <-cmd.Context().Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+	},
+}
+
+// eventsResult is the output of the events command
+type eventsResult struct {
+	*client.EventPage
+}
+
+func (r eventsResult) writeText(w io.Writer) {
+	for _, e := range r.Events {
+		fmt.Fprintf(w, "%s  %-22s  %s\n", formatTime(e.Timestamp), e.Type, describeEvent(e))
+	}
+}
+
+func (r eventsResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Events))
+	for _, e := range r.Events {
+		rows = append(rows, []string{e.ID, formatTime(e.Timestamp), e.Type, describeEvent(e)})
+	}
+	writeRows(w, []string{"ID", "TIME", "TYPE", "DETAILS"}, rows)
+}
+
+// describeEvent summarizes an event's data on one line
+func describeEvent(e events.Event) string {
+	switch e.Type {
+	case events.TypeDocumentState:
+		var change events.DocumentStateChanged
+		if err := json.Unmarshal(e.Data, &change); err != nil {
+			break
+		}
+		line := fmt.Sprintf("%s: %s", change.FilePath, change.State)
+		if change.PreviousState != "" {
+			line = fmt.Sprintf("%s: %s → %s", change.FilePath, change.PreviousState, change.State)
+		}
+		if change.Error != "" {
+			line += " (" + change.Error + ")"
+		}
+		return line
+	case events.TypeRunProgress:
+		var progress events.RunProgress
+		if err := json.Unmarshal(e.Data, &progress); err != nil {
+			break
+		}
+		line := fmt.Sprintf("run %s %s: %d/%d files (%d processed, %d skipped, %d failed)",
+			progress.RunID, progress.Status, progress.Done, progress.Total, progress.Processed, progress.Skipped, progress.Failed)
+		if progress.File != "" {
+			line += " " + progress.File
+		}
+		return line
+	}
+	return string(e.Data)
+}
+
+func init() {
+	eventsCmd.Flags().String("after", "", "Show events after this event ID")
+	eventsCmd.Flags().Int("limit", 100, "Maximum events read per request")
+	eventsCmd.Flags().StringSlice("type", nil, "Show only events of these types (document.state-changed, run.progress)")
+	eventsCmd.Flags().BoolP("follow", "f", false, "Keep polling for new events")
+	eventsCmd.Flags().Duration("interval", 2*time.Second, "Polling interval with --follow")
+}
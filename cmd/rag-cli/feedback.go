@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/feedback"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var feedbackCmd = &cobra.Command{
+	Use:   "feedback",
+	Short: "Rate answers and review their ratings",
+	Long: `Rate an answer up or down by the query ID printed with it, and review the
+ratings given: their totals, and the queries rated down more than up, which
+are the cases an evaluation run should check first.`,
+}
+
+var feedbackSendCmd = &cobra.Command{
+	Use:   "send [query-id] [up|down]",
+	Short: "Rate an answer up or down",
+	Long: `Rate the answer of a query up or down, with an optional comment. Rating the
+same answer again replaces the earlier rating.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		comment, err := cmd.Flags().GetString("comment")
+		if err != nil {
+			return fmt.Errorf("failed to get comment flag: %w", err)
+		}
+		question, err := cmd.Flags().GetString("query")
+		if err != nil {
+			return fmt.Errorf("failed to get query flag: %w", err)
+		}
+
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, clientOptions())
+		fb, err := querier.SendFeedback(cmd.Context(), &client.FeedbackRequest{
+			QueryID: args[0],
+			Rating:  strings.ToLower(args[1]),
+			Comment: comment,
+			Query:   question,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send feedback: %w", err)
+		}
+		return printResult(feedbackSendResult{fb})
+	},
+}
+
+// feedbackSendResult is the output of the feedback send command
+type feedbackSendResult struct {
+	*feedback.Feedback
+}
+
+func (r feedbackSendResult) writeText(w io.Writer) {
+	icon := "👍"
+	if r.Rating == feedback.RatingDown {
+		icon = "👎"
+	}
+	fmt.Fprintf(w, "%s Rated query %s %s\n", icon, r.QueryID, r.Rating)
+}
+
+func (r feedbackSendResult) writeTable(w io.Writer) {
+	writeRows(w, []string{"QUERY ID", "RATING", "COMMENT"}, [][]string{{r.QueryID, r.Rating, r.Comment}})
+}
+
+var feedbackListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List answer ratings, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := feedbackFilterFlags(cmd)
+		if err != nil {
+			return err
+		}
+		rating, err := cmd.Flags().GetString("rating")
+		if err != nil {
+			return fmt.Errorf("failed to get rating flag: %w", err)
+		}
+		filter.Rating = rating
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("failed to get limit flag: %w", err)
+		}
+		filter.Limit = limit
+
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, clientOptions())
+		list, err := querier.Feedback(cmd.Context(), filter)
+		if err != nil {
+			return fmt.Errorf("failed to list feedback: %w", err)
+		}
+		return printResult(feedbackListResult{Feedback: list, Count: len(list)})
+	},
+}
+
+// feedbackListResult is the output of the feedback list command
+type feedbackListResult struct {
+	Feedback []*feedback.Feedback `json:"feedback"`
+	Count    int                  `json:"count"`
+}
+
+func (r feedbackListResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗳️  Ratings (%d)\n\n", r.Count)
+	for _, fb := range r.Feedback {
+		fmt.Fprintf(w, "%s  %-4s  %s  %s\n", formatTime(fb.CreatedAt), fb.Rating, fb.QueryID, fb.Actor)
+		if fb.Query != "" {
+			fmt.Fprintf(w, "   Query: %s\n", fb.Query)
+		}
+		if fb.Comment != "" {
+			fmt.Fprintf(w, "   Comment: %s\n", fb.Comment)
+		}
+	}
+}
+
+func (r feedbackListResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Feedback))
+	for _, fb := range r.Feedback {
+		rows = append(rows, []string{formatTime(fb.CreatedAt), fb.Rating, fb.QueryID, fb.Actor, fb.Comment})
+	}
+	writeRows(w, []string{"RATED", "RATING", "QUERY ID", "ACTOR", "COMMENT"}, rows)
+}
+
+var feedbackStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the totals of answer ratings",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := feedbackFilterFlags(cmd)
+		if err != nil {
+			return err
+		}
+		var since time.Time
+		if filter.Since != nil {
+			since = *filter.Since
+		}
+
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, clientOptions())
+		stats, err := querier.FeedbackStats(cmd.Context(), since)
+		if err != nil {
+			return fmt.Errorf("failed to get feedback stats: %w", err)
+		}
+		return printResult(feedbackStatsResult{stats})
+	},
+}
+
+// feedbackStatsResult is the output of the feedback stats command
+type feedbackStatsResult struct {
+	*feedback.Stats
+}
+
+func (r feedbackStatsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗳️  %d ratings of %d queries\n", r.Total, r.Queries)
+	fmt.Fprintf(w, "   👍 %d  👎 %d  💬 %d commented\n", r.Up, r.Down, r.Commented)
+	if r.Satisfaction != nil {
+		fmt.Fprintf(w, "   Satisfaction: %.0f%%\n", *r.Satisfaction*100)
+	}
+	fmt.Fprintf(w, "   Poorly rated queries: %d\n", r.PoorlyRated)
+}
+
+func (r feedbackStatsResult) writeTable(w io.Writer) {
+	satisfaction := ""
+	if r.Satisfaction != nil {
+		satisfaction = fmt.Sprintf("%.2f", *r.Satisfaction)
+	}
+	writeRows(w, []string{"RATINGS", "UP", "DOWN", "COMMENTED", "SATISFACTION", "QUERIES", "POORLY RATED"}, [][]string{{
+		strconv.Itoa(r.Total), strconv.Itoa(r.Up), strconv.Itoa(r.Down), strconv.Itoa(r.Commented),
+		satisfaction, strconv.Itoa(r.Queries), strconv.Itoa(r.PoorlyRated),
+	}})
+}
+
+var feedbackPoorCmd = &cobra.Command{
+	Use:   "poor",
+	Short: "List queries rated down more than up",
+	Long: `List the queries rated down more than up, the most down ratings first, with
+the question asked and the comments given. With --json the list can
+seed an evaluation run.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("failed to get limit flag: %w", err)
+		}
+
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, clientOptions())
+		queries, err := querier.PoorlyRated(cmd.Context(), limit)
+		if err != nil {
+			return fmt.Errorf("failed to list poorly rated queries: %w", err)
+		}
+		return printResult(feedbackPoorResult{Queries: queries, Count: len(queries)})
+	},
+}
+
+// feedbackPoorResult is the output of the feedback poor command
+type feedbackPoorResult struct {
+	Queries []*feedback.RatedQuery `json:"queries"`
+	Count   int                    `json:"count"`
+}
+
+func (r feedbackPoorResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "👎 Poorly rated queries (%d)\n", r.Count)
+	for _, q := range r.Queries {
+		fmt.Fprintf(w, "\n%s  👍 %d  👎 %d  last rated %s\n", q.QueryID, q.Up, q.Down, formatTime(q.LastAt))
+		if q.Query != "" {
+			fmt.Fprintf(w, "   Query: %s\n", q.Query)
+		}
+		for _, comment := range q.Comments {
+			fmt.Fprintf(w, "   - %s\n", comment)
+		}
+	}
+}
+
+func (r feedbackPoorResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Queries))
+	for _, q := range r.Queries {
+		rows = append(rows, []string{q.QueryID, strconv.Itoa(q.Up), strconv.Itoa(q.Down), q.Query})
+	}
+	writeRows(w, []string{"QUERY ID", "UP", "DOWN", "QUERY"}, rows)
+}
+
+// feedbackFilterFlags reads the --since flag into a filter
+func feedbackFilterFlags(cmd *cobra.Command) (feedback.Filter, error) {
+	var filter feedback.Filter
+	since, err := cmd.Flags().GetDuration("since")
+	if err != nil {
+		return filter, fmt.Errorf("failed to get since flag: %w", err)
+	}
+	if since > 0 {
+		from := time.Now().Add(-since)
+		filter.Since = &from
+	}
+	return filter, nil
+}
+
+func init() {
+	feedbackSendCmd.Flags().StringP("comment", "c", "", "Comment on the answer")
+	feedbackSendCmd.Flags().String("query", "", "Question asked, kept so the query can be evaluated again")
+	feedbackListCmd.Flags().String("rating", "", "Only ratings of up or down")
+	feedbackListCmd.Flags().Duration("since", 0, "Only ratings given within this duration, e.g. 168h")
+	feedbackListCmd.Flags().IntP("limit", "n", 50, "Maximum number of ratings to list")
+	feedbackStatsCmd.Flags().Duration("since", 0, "Only ratings given within this duration, e.g. 168h")
+	feedbackPoorCmd.Flags().IntP("limit", "n", 20, "Maximum number of queries to list")
+
+	feedbackCmd.AddCommand(feedbackSendCmd)
+	feedbackCmd.AddCommand(feedbackListCmd)
+	feedbackCmd.AddCommand(feedbackStatsCmd)
+	feedbackCmd.AddCommand(feedbackPoorCmd)
+}
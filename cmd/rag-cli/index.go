@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/chunkstore"
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/contentstore"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+	"github.com/nadeeshame/rag-knowledge-service/internal/scanner"
+	"github.com/nadeeshame/rag-knowledge-service/internal/wal"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Index documents",
+	Long: `Scan and index documents from a directory into the vector database.
+Exits with status 2 when some files could not be indexed.
+
+The command writes the document registry itself, so it needs
+REGISTRY_BACKEND=redis for the services to see what it indexed; with the
+memory backend the records are lost when it exits. Pass --memory-registry
+to index that way anyway.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		directory, err := cmd.Flags().GetString("directory")
+		if err != nil {
+			return fmt.Errorf("failed to get directory flag: %w", err)
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("failed to get force flag: %w", err)
+		}
+		options, err := extractOptions(cmd)
+		if err != nil {
+			return err
+		}
+		memoryRegistry, err := cmd.Flags().GetBool("memory-registry")
+		if err != nil {
+			return fmt.Errorf("failed to get memory-registry flag: %w", err)
+		}
+		if cfg.Registry.Backend != "redis" {
+			if !memoryRegistry {
+				return fmt.Errorf("the %s registry is not shared with the orchestrator, which would not see the indexed documents: set REGISTRY_BACKEND=redis, or pass --memory-registry to index anyway", cfg.Registry.Backend)
+			}
+			logger.Warn("Indexing with a memory registry; the services will not see its records",
+				zap.String("backend", cfg.Registry.Backend))
+		}
+
+		logger.Info("Starting indexing",
+			zap.String("directory", directory),
+			zap.Bool("force", force))
+
+		ctx := cmd.Context()
+		store, err := registry.NewStore(ctx, cfg, logger.Log)
+		if err != nil {
+			return fmt.Errorf("failed to create document registry: %w", err)
+		}
+		defer store.Close() //nolint:errcheck
+
+		chunks, err := chunkstore.NewStore(ctx, cfg, logger.Log)
+		if err != nil {
+			return fmt.Errorf("failed to create chunk store: %w", err)
+		}
+		if chunks != nil {
+			defer chunks.Close() //nolint:errcheck
+		}
+
+		upsertLog, err := wal.NewStore(ctx, cfg, logger.Log)
+		if err != nil {
+			return fmt.Errorf("failed to create write-ahead log: %w", err)
+		}
+		if upsertLog != nil {
+			defer upsertLog.Close() //nolint:errcheck
+		}
+
+		contents, err := contentstore.NewStore(ctx, cfg, logger.Log)
+		if err != nil {
+			return fmt.Errorf("failed to create content store: %w", err)
+		}
+		if contents != nil {
+			defer contents.Close() //nolint:errcheck
+		}
+
+		history, err := runs.NewStore(ctx, cfg, logger.Log)
+		if err != nil {
+			return fmt.Errorf("failed to create run store: %w", err)
+		}
+		defer history.Close() //nolint:errcheck
+
+		processor, err := orchestrator.NewDocumentProcessor(cfg, logger.Log)
+		if err != nil {
+			return fmt.Errorf("failed to create document processor: %w", err)
+		}
+		processor.SetRegistry(store)
+		processor.SetChunkStore(chunks)
+		processor.SetWAL(upsertLog)
+		processor.SetContentStore(contents)
+		processor.SetRunStore(history)
+
+		// Vectors left unflushed by an interrupted run are stored first
+		if _, err := processor.ReplayWAL(ctx); err != nil {
+			return fmt.Errorf("failed to replay write-ahead log: %w", err)
+		}
+
+		res, err := processor.ProcessDirectory(processors.WithOptions(ctx, options), directory, force)
+		if err != nil {
+			return fmt.Errorf("failed to index %s: %w", directory, err)
+		}
+
+		if err := printResult(indexResult{res}); err != nil {
+			return err
+		}
+		return partialFailure(res.Failed, "file(s)")
+	},
+}
+
+// extractOptions reads the extraction options from the flags; the tables
+// option is only set when its flag is given
+func extractOptions(cmd *cobra.Command) (processors.ExtractOptions, error) {
+	var options processors.ExtractOptions
+	var err error
+	if options.OCRMode, err = cmd.Flags().GetString("ocr-mode"); err != nil {
+		return options, fmt.Errorf("failed to get ocr-mode flag: %w", err)
+	}
+	if options.MaxPages, err = cmd.Flags().GetInt("max-pages"); err != nil {
+		return options, fmt.Errorf("failed to get max-pages flag: %w", err)
+	}
+	if options.Language, err = cmd.Flags().GetString("language"); err != nil {
+		return options, fmt.Errorf("failed to get language flag: %w", err)
+	}
+	if cmd.Flags().Changed("include-tables") {
+		tables, err := cmd.Flags().GetBool("include-tables")
+		if err != nil {
+			return options, fmt.Errorf("failed to get include-tables flag: %w", err)
+		}
+		options.IncludeTables = &tables
+	}
+	return options, options.Validate()
+}
+
+// indexResult is the output of the index command
+type indexResult struct {
+	*orchestrator.DirectoryResult
+}
+
+func (r indexResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "📂 Indexed documents from: %s\n", r.Directory)
+	fmt.Fprintf(w, "   Files: %d  Processed: %d  Skipped: %d  Failed: %d  Ignored: %d\n\n",
+		r.Total, r.Processed, r.Skipped, r.Failed, len(r.Ignored))
+	for _, s := range r.Ignored {
+		if s.Reason == scanner.SkipTooLarge || s.Reason == scanner.SkipLimit {
+			fmt.Fprintf(w, "⏭️  %s: %s\n", s.Path, s.Error)
+		}
+	}
+	for _, f := range r.Failures {
+		fmt.Fprintf(w, "❌ %s: %s\n", f.FilePath, f.Error)
+	}
+	if len(r.Changes) > 0 {
+		fmt.Fprintln(w)
+		writeChanges(w, r.Changes, r.ChangeDigest)
+		fmt.Fprintf(w, "\n   Run %s\n", r.RunID)
+	}
+	if r.Failed > 0 {
+		fmt.Fprintf(w, "\n⚠️  Indexing finished with %d failure(s)\n", r.Failed)
+		return
+	}
+	fmt.Fprintln(w, "✨ Indexing complete!")
+}
+
+func (r indexResult) writeTable(w io.Writer) {
+	writeRows(w, []string{"DIRECTORY", "FILES", "PROCESSED", "SKIPPED", "FAILED"}, [][]string{{
+		r.Directory,
+		strconv.Itoa(r.Total),
+		strconv.Itoa(r.Processed),
+		strconv.Itoa(r.Skipped),
+		strconv.Itoa(r.Failed),
+	}})
+	if len(r.Changes) > 0 {
+		fmt.Fprintln(w)
+		writeChangeRows(w, r.Changes)
+	}
+	if len(r.Failures) == 0 {
+		return
+	}
+	rows := make([][]string, 0, len(r.Failures))
+	for _, f := range r.Failures {
+		rows = append(rows, []string{f.FilePath, f.Error})
+	}
+	fmt.Fprintln(w)
+	writeRows(w, []string{"FILE", "ERROR"}, rows)
+}
+
+func init() {
+	indexCmd.Flags().StringP("directory", "d", "./data/diagrams", "Directory to index")
+	indexCmd.Flags().BoolP("force", "f", false, "Force reprocess all documents")
+	indexCmd.Flags().Bool("memory-registry", false, "Index even though the registry is not shared with the services")
+	indexCmd.Flags().String("ocr-mode", "", "Text detection in images: auto, always or never")
+	indexCmd.Flags().Bool("include-tables", true, "Keep tables in the text of documents")
+	indexCmd.Flags().Int("max-pages", 0, "Pages of each PDF to extract (0 for all)")
+	indexCmd.Flags().String("language", "", "BCP 47 language tag hinting the language of text in images")
+}
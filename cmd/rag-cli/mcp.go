@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/httpsec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/mcp"
+	"github.com/nadeeshame/rag-knowledge-service/internal/tlsconfig"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/sdk"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// mcpPath is where the HTTP transport is served
+const mcpPath = "/mcp"
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve the knowledge base as MCP tools",
+	Long: `Serve the knowledge base over the Model Context Protocol, offering the
+search, ask and get_document tools to IDE assistants and chat clients. The
+tools call the gateway at mcp.gateway_url.
+
+By default messages are read from standard input and answered on standard
+output, for an assistant that starts this command; configure it as a stdio
+server running "rag-cli mcp". With --client, the tools and user of that
+configured client apply. With --http the server listens on mcp.port at
+/mcp instead, and clients authenticate with a key of mcp.api_keys or
+mcp.clients.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serveHTTP, err := cmd.Flags().GetBool("http")
+		if err != nil {
+			return fmt.Errorf("failed to get http flag: %w", err)
+		}
+		clientName, err := cmd.Flags().GetString("client")
+		if err != nil {
+			return fmt.Errorf("failed to get client flag: %w", err)
+		}
+		if serveHTTP && clientName != "" {
+			return errors.New("--client applies to the stdio transport; HTTP clients are known by their key")
+		}
+
+		opts := clientOptions()
+		gatewayClient := sdk.New(cfg.MCP.GatewayURL, sdk.Options{
+			APIKey:     cfg.MCP.GatewayAPIKey,
+			Timeout:    cfg.MCP.Timeout,
+			UserAgent:  "repograph-mcp/1.0",
+			HTTPClient: opts.HTTPClient,
+		})
+		server := mcp.New(cfg.MCP, gatewayClient, logger.Log)
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		if serveHTTP {
+			return serveMCP(ctx, server)
+		}
+
+		caller := &mcp.Caller{Name: "stdio"}
+		if clientName != "" {
+			if caller = server.ClientCaller(clientName); caller == nil {
+				return fmt.Errorf("no MCP client named %q in mcp.clients", clientName)
+			}
+		}
+		logger.Info("MCP server reading standard input",
+			zap.String("gateway", cfg.MCP.GatewayURL),
+			zap.String("caller", caller.Name),
+			zap.Strings("tools", server.Tools()))
+		return server.ServeStdio(ctx, caller, os.Stdin, os.Stdout)
+	},
+}
+
+// serveMCP serves the HTTP transport until ctx is done
+func serveMCP(ctx context.Context, server *mcp.Server) error {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.Recovery(), httpsec.SecurityHeaders(cfg.SecurityHeaders), httpsec.CORS(cfg.CORS))
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"healthy": true})
+	})
+	server.Register(router, mcpPath, cfg)
+
+	certs, err := tlsconfig.New(cfg, logger.Log)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificates: %w", err)
+	}
+	srv := &http.Server{
+		Addr:        fmt.Sprintf(":%d", cfg.MCP.Port),
+		Handler:     router,
+		ReadTimeout: 30 * time.Second,
+		// Tool calls may take up to the MCP timeout
+		WriteTimeout: cfg.MCP.Timeout + 10*time.Second,
+	}
+
+	if len(cfg.MCP.APIKeys) == 0 && len(cfg.MCP.Clients) == 0 {
+		logger.Warn("MCP server accepts requests without a key; set MCP_API_KEYS to require one")
+	}
+	logger.Info("MCP server listening",
+		zap.String("address", srv.Addr+mcpPath),
+		zap.String("gateway", cfg.MCP.GatewayURL),
+		zap.Strings("tools", server.Tools()),
+		zap.Int("clients", len(cfg.MCP.Clients)))
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- tlsconfig.ListenAndServe(srv, certs)
+	}()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("MCP server failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	logger.Info("Shutting down MCP server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+func init() {
+	mcpCmd.Flags().Bool("http", false, "serve over HTTP on mcp.port instead of standard input and output")
+	mcpCmd.Flags().String("client", "", "serve standard input with the tools and user of this client of mcp.clients")
+}
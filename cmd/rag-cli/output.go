@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Exit codes besides 0 for success
+const (
+	exitError   = 1 // the command could not run
+	exitPartial = 2 // the command ran but some items failed
+)
+
+// Output format flags; at most one may be set, and text is the default
+var (
+	jsonOutput  bool
+	yamlOutput  bool
+	tableOutput bool
+)
+
+// result is the outcome of a command. Results are marshaled as-is for
+// --json and --yaml and lay themselves out for text and --table output.
+type result interface {
+	writeText(w io.Writer)
+	writeTable(w io.Writer)
+}
+
+// printResult writes a command result to stdout in the selected format
+func printResult(r result) error {
+	switch {
+	case jsonOutput:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case yamlOutput:
+		return writeYAML(os.Stdout, r)
+	case tableOutput:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		r.writeTable(tw)
+		return tw.Flush()
+	default:
+		r.writeText(os.Stdout)
+		return nil
+	}
+}
+
+// writeYAML converts v through JSON so that YAML output uses the same
+// field names and field order as --json
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("failed to convert result: %w", err)
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return fmt.Errorf("failed to write result: %w", err)
+	}
+	return enc.Close()
+}
+
+// blockStyle drops the flow and quoting styles JSON input parses with
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// writeRows writes tab-separated rows under upper-case headers
+func writeRows(w io.Writer, headers []string, rows [][]string) {
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+}
+
+// flatten turns nested maps into dotted keys, sorted for stable output
+func flatten(prefix string, value interface{}, out map[string]string) {
+	if m, ok := value.(map[string]interface{}); ok {
+		for k, v := range m {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, v, out)
+		}
+		return
+	}
+	out[prefix] = fmt.Sprint(value)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// exitCodeError ends a command with a specific exit code. Commands return
+// it after printing their result, so scripts get both the output and the
+// failure signal.
+type exitCodeError struct {
+	code    int
+	message string
+}
+
+func (e *exitCodeError) Error() string {
+	return e.message
+}
+
+// partialFailure returns an exitPartial error when any items failed
+func partialFailure(failed int, items string) error {
+	if failed == 0 {
+		return nil
+	}
+	return &exitCodeError{code: exitPartial, message: fmt.Sprintf("%d %s failed", failed, items)}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// snippetLength is how much of a chunk the text output shows
+const snippetLength = 200
+
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Query the knowledge base",
+	Long:  `Query the knowledge base using natural language or search for documents.`,
+}
+
+var askCmd = &cobra.Command{
+	Use:   "ask [question]",
+	Short: "Ask a question",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		question := strings.Join(args, " ")
+		topK, err := cmd.Flags().GetInt("top-k")
+		if err != nil {
+			return fmt.Errorf("failed to get top-k flag: %w", err)
+		}
+
+		collection, err := cmd.Flags().GetString("collection")
+		if err != nil {
+			return fmt.Errorf("failed to get collection flag: %w", err)
+		}
+		hasMath, err := cmd.Flags().GetBool("math")
+		if err != nil {
+			return fmt.Errorf("failed to get math flag: %w", err)
+		}
+		tag, err := cmd.Flags().GetString("tag")
+		if err != nil {
+			return fmt.Errorf("failed to get tag flag: %w", err)
+		}
+
+		filter := models.Filter{Collection: collection, HasMath: hasMath, Tag: tag}
+		if err := applyLogFlags(cmd, &filter); err != nil {
+			return err
+		}
+
+		logger.Info("Asking question",
+			zap.String("question", question),
+			zap.Int("top_k", topK),
+			zap.String("collection", collection))
+
+		req := &client.QueryRequest{
+			Text:   question,
+			TopK:   topK,
+			Filter: filter,
+		}
+		if err := applyRelevanceFlags(cmd, req); err != nil {
+			return err
+		}
+		schemaFile, err := cmd.Flags().GetString("schema")
+		if err != nil {
+			return fmt.Errorf("failed to get schema flag: %w", err)
+		}
+		if schemaFile != "" {
+			schema, err := os.ReadFile(schemaFile)
+			if err != nil {
+				return fmt.Errorf("failed to read schema: %w", err)
+			}
+			req.ResponseFormat, req.Schema = models.ResponseJSONSchema, schema
+		}
+
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, clientOptions())
+		answer, err := querier.Ask(cmd.Context(), req)
+		if err != nil {
+			return fmt.Errorf("failed to get answer: %w", err)
+		}
+
+		return printResult(askResult{Question: question, QueryResult: answer})
+	},
+}
+
+// askResult is the output of the ask command
+type askResult struct {
+	Question string `json:"question"`
+	*models.QueryResult
+}
+
+func (r askResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🤔 Question: %s\n\n", r.Question)
+	fmt.Fprintf(w, "💡 Answer: %s\n", r.Answer)
+	fmt.Fprintf(w, "🆔 Query ID: %s (rate it with: rag-cli feedback send %s up|down)\n", r.QueryID, r.QueryID)
+	if r.Trace != nil {
+		for _, verdict := range r.Trace.Moderation {
+			categories := make([]string, len(verdict.Categories))
+			for i, c := range verdict.Categories {
+				categories[i] = fmt.Sprintf("%s (severity %d)", c.Category, c.Severity)
+			}
+			fmt.Fprintf(w, "\n⚠️  The %s was flagged by content moderation: %s\n", verdict.Subject, strings.Join(categories, ", "))
+		}
+	}
+	if len(r.Sources) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n📚 Sources:")
+	for i, s := range r.Sources {
+		fmt.Fprintf(w, "  [%d] %s (score %.3f)\n      %s\n", i+1, s.FileName, s.Score, s.FilePath)
+	}
+	if r.Trace != nil && len(r.Trace.Injections) > 0 {
+		fmt.Fprintln(w, "\n⚠️  Sources reading like prompt injections:")
+		for _, finding := range r.Trace.Injections {
+			fmt.Fprintf(w, "  [%d] score %.2f: %s\n", finding.Source, finding.Score, strings.Join(finding.Patterns, ", "))
+		}
+	}
+}
+
+func (r askResult) writeTable(w io.Writer) {
+	fmt.Fprintf(w, "ANSWER\t%s\n\n", strings.Join(strings.Fields(r.Answer), " "))
+	writeRows(w, []string{"#", "FILE", "SCORE", "PATH"}, sourceRows(r.Sources))
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search documents",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := strings.Join(args, " ")
+		topK, err := cmd.Flags().GetInt("top-k")
+		if err != nil {
+			return fmt.Errorf("failed to get top-k flag: %w", err)
+		}
+		fileType, err := cmd.Flags().GetString("type")
+		if err != nil {
+			return fmt.Errorf("failed to get type flag: %w", err)
+		}
+		collection, err := cmd.Flags().GetString("collection")
+		if err != nil {
+			return fmt.Errorf("failed to get collection flag: %w", err)
+		}
+		hasMath, err := cmd.Flags().GetBool("math")
+		if err != nil {
+			return fmt.Errorf("failed to get math flag: %w", err)
+		}
+		tag, err := cmd.Flags().GetString("tag")
+		if err != nil {
+			return fmt.Errorf("failed to get tag flag: %w", err)
+		}
+
+		filter := models.Filter{FileType: fileType, Collection: collection, HasMath: hasMath, Tag: tag}
+		if err := applyLogFlags(cmd, &filter); err != nil {
+			return err
+		}
+
+		logger.Info("Searching documents",
+			zap.String("query", query),
+			zap.Int("top_k", topK),
+			zap.String("file_type", fileType),
+			zap.String("collection", collection))
+
+		req := &client.QueryRequest{
+			Text:   query,
+			TopK:   topK,
+			Filter: filter,
+		}
+		if err := applyRelevanceFlags(cmd, req); err != nil {
+			return err
+		}
+
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, clientOptions())
+		results, err := querier.Search(cmd.Context(), req)
+		if err != nil {
+			return fmt.Errorf("failed to search documents: %w", err)
+		}
+
+		return printResult(searchResult{Query: query, Results: results, Count: len(results)})
+	},
+}
+
+// searchResult is the output of the search command
+type searchResult struct {
+	Query   string                `json:"query"`
+	Results []models.SearchResult `json:"results"`
+	Count   int                   `json:"count"`
+}
+
+func (r searchResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🔍 Searching for: %s\n\n", r.Query)
+	if r.Count == 0 {
+		fmt.Fprintln(w, "📄 No matching documents")
+		return
+	}
+	for i, s := range r.Results {
+		fmt.Fprintf(w, "📄 [%d] %s (score %.3f)\n    %s\n", i+1, s.FileName, s.Score, s.FilePath)
+		fmt.Fprintf(w, "    %s\n\n", snippet(s.Content))
+	}
+}
+
+func (r searchResult) writeTable(w io.Writer) {
+	writeRows(w, []string{"#", "FILE", "SCORE", "PATH"}, sourceRows(r.Results))
+}
+
+// sourceRows lays out search results as table rows
+func sourceRows(results []models.SearchResult) [][]string {
+	rows := make([][]string, 0, len(results))
+	for i, s := range results {
+		rows = append(rows, []string{strconv.Itoa(i + 1), s.FileName, fmt.Sprintf("%.3f", s.Score), s.FilePath})
+	}
+	return rows
+}
+
+// snippet shortens chunk content to a single line
+func snippet(content string) string {
+	text := []rune(strings.Join(strings.Fields(content), " "))
+	if len(text) <= snippetLength {
+		return string(text)
+	}
+	return string(text[:snippetLength]) + "…"
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [question]",
+	Short: "Compare two documents on a question",
+	Long: `Compare two documents on a question, such as "what changed between these
+design docs". --left and --right each take a document ID or a file path.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		question := strings.Join(args, " ")
+		topK, err := cmd.Flags().GetInt("top-k")
+		if err != nil {
+			return fmt.Errorf("failed to get top-k flag: %w", err)
+		}
+		left, err := cmd.Flags().GetString("left")
+		if err != nil {
+			return fmt.Errorf("failed to get left flag: %w", err)
+		}
+		right, err := cmd.Flags().GetString("right")
+		if err != nil {
+			return fmt.Errorf("failed to get right flag: %w", err)
+		}
+
+		logger.Info("Comparing documents",
+			zap.String("question", question),
+			zap.String("left", left),
+			zap.String("right", right))
+
+		req := &client.CompareRequest{
+			Text:  question,
+			Left:  comparisonTarget(left),
+			Right: comparisonTarget(right),
+			TopK:  topK,
+		}
+		querier := client.NewQueryClient(cfg.Services.QueryServiceURL, clientOptions())
+		comparison, err := querier.Compare(cmd.Context(), req)
+		if err != nil {
+			return fmt.Errorf("failed to compare documents: %w", err)
+		}
+
+		return printResult(compareResult{Question: question, Comparison: comparison})
+	},
+}
+
+// comparisonTarget takes a document ID for one and anything else for a
+// file path
+func comparisonTarget(value string) models.ComparisonTarget {
+	if _, err := uuid.Parse(value); err == nil {
+		return models.ComparisonTarget{DocumentID: value}
+	}
+	return models.ComparisonTarget{FilePath: value}
+}
+
+// compareResult is the output of the compare command
+type compareResult struct {
+	Question string `json:"question"`
+	*models.Comparison
+}
+
+func (r compareResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🤔 Question: %s\n\n", r.Question)
+	fmt.Fprintf(w, "💡 %s\n", r.Summary)
+	if len(r.Similarities) > 0 {
+		fmt.Fprintln(w, "\n🤝 In common:")
+		for _, similarity := range r.Similarities {
+			fmt.Fprintf(w, "  • %s\n", similarity)
+		}
+	}
+	if len(r.Differences) > 0 {
+		fmt.Fprintln(w, "\n↔️  Differences:")
+		for _, d := range r.Differences {
+			fmt.Fprintf(w, "  • %s %v\n      left:  %s\n      right: %s\n", d.Aspect, d.Sources, d.Left, d.Right)
+		}
+	}
+	sources := append(append([]models.SearchResult{}, r.LeftSources...), r.RightSources...)
+	if len(sources) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\n📚 Sources:")
+	for i, s := range sources {
+		side := "left"
+		if i >= len(r.LeftSources) {
+			side = "right"
+		}
+		fmt.Fprintf(w, "  [%d] %s, %s (score %.3f)\n", i+1, s.FileName, side, s.Score)
+	}
+}
+
+func (r compareResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Differences))
+	for _, d := range r.Differences {
+		rows = append(rows, []string{d.Aspect, d.Left, d.Right})
+	}
+	fmt.Fprintf(w, "SUMMARY\t%s\n\n", strings.Join(strings.Fields(r.Summary), " "))
+	writeRows(w, []string{"ASPECT", "LEFT", "RIGHT"}, rows)
+}
+
+var interactiveCmd = &cobra.Command{
+	Use:   "interactive",
+	Short: "Start interactive query mode",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("🎯 Interactive Query Mode")
+		fmt.Println("Type your questions or 'exit' to quit")
+
+		// TODO: Implement interactive mode
+		fmt.Println("Implementation pending...")
+	},
+}
+
+// applyLogFlags sets the log filters given with --log-from, --log-to and
+// --log-level
+func applyLogFlags(cmd *cobra.Command, filter *models.Filter) error {
+	from, err := logTimeFlag(cmd, "log-from", false)
+	if err != nil {
+		return err
+	}
+	to, err := logTimeFlag(cmd, "log-to", true)
+	if err != nil {
+		return err
+	}
+	level, err := cmd.Flags().GetString("log-level")
+	if err != nil {
+		return fmt.Errorf("failed to get log-level flag: %w", err)
+	}
+	filter.LogFrom, filter.LogTo, filter.LogLevel = from, to, level
+	return nil
+}
+
+// applyRelevanceFlags sets the retrieval profile given with --profile, the
+// score threshold given with --min-score and the freshness half-life given
+// with --freshness. With a profile, top_k is only sent when --top-k is
+// given, so the profile's takes effect otherwise.
+func applyRelevanceFlags(cmd *cobra.Command, req *client.QueryRequest) error {
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return fmt.Errorf("failed to get profile flag: %w", err)
+	}
+	req.Profile = profile
+	if profile != "" && !cmd.Flags().Changed("top-k") {
+		req.TopK = 0
+	}
+	if cmd.Flags().Changed("min-score") {
+		minScore, err := cmd.Flags().GetFloat64("min-score")
+		if err != nil {
+			return fmt.Errorf("failed to get min-score flag: %w", err)
+		}
+		req.MinScore = &minScore
+	}
+	if cmd.Flags().Changed("freshness") {
+		halfLife, err := cmd.Flags().GetInt("freshness")
+		if err != nil {
+			return fmt.Errorf("failed to get freshness flag: %w", err)
+		}
+		req.FreshnessHalfLife = &halfLife
+	}
+	return nil
+}
+
+// logTimeFlag parses a time flag given as YYYY-MM-DD or RFC 3339. A bare
+// date stands for the start of the day, or for its end with endOfDay.
+func logTimeFlag(cmd *cobra.Command, name string, endOfDay bool) (*time.Time, error) {
+	value, err := cmd.Flags().GetString(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s flag: %w", name, err)
+	}
+	if value == "" {
+		return nil, nil
+	}
+	t, err := models.ParseAsOf(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s %q: expected YYYY-MM-DD or RFC 3339", name, value)
+	}
+	if !endOfDay && len(value) == len("2006-01-02") {
+		t = t.Add(-(24*time.Hour - time.Second))
+	}
+	return &t, nil
+}
+
+func init() {
+	askCmd.Flags().IntP("top-k", "k", 5, "Number of sources to retrieve")
+	searchCmd.Flags().IntP("top-k", "k", 10, "Number of results to return")
+	searchCmd.Flags().StringP("type", "t", "", "Filter by file type")
+	askCmd.Flags().StringP("collection", "c", "", "Only use documents in this collection")
+	searchCmd.Flags().StringP("collection", "c", "", "Only search documents in this collection")
+	askCmd.Flags().Bool("math", false, "Only use passages containing formulas")
+	searchCmd.Flags().Bool("math", false, "Only search passages containing formulas")
+	askCmd.Flags().String("tag", "", "Only use notes with this frontmatter tag")
+	askCmd.Flags().String("schema", "", "JSON schema file the answer must conform to")
+	compareCmd.Flags().IntP("top-k", "k", 5, "Number of passages to retrieve from each document")
+	compareCmd.Flags().String("left", "", "Document ID or file path of the first document")
+	compareCmd.Flags().String("right", "", "Document ID or file path of the second document")
+	compareCmd.MarkFlagRequired("left")  //nolint:errcheck
+	compareCmd.MarkFlagRequired("right") //nolint:errcheck
+	searchCmd.Flags().String("tag", "", "Only search notes with this frontmatter tag")
+	for _, cmd := range []*cobra.Command{askCmd, searchCmd} {
+		cmd.Flags().String("log-from", "", "Only log entries at or after this time (YYYY-MM-DD or RFC 3339)")
+		cmd.Flags().String("log-to", "", "Only log entries at or before this time (YYYY-MM-DD or RFC 3339)")
+		cmd.Flags().String("log-level", "", "Only log entries at this severity or above (fatal, error, warn, info, debug)")
+		cmd.Flags().String("profile", "", "Retrieval profile giving the defaults of unset options")
+		cmd.Flags().Float64("min-score", 0, "Drop matches scoring below this (default RETRIEVAL_MIN_SCORE)")
+		cmd.Flags().Int("freshness", 0, "Rank recently modified documents higher, halving the decaying score every this many days; 0 ranks by relevance alone (default RETRIEVAL_FRESHNESS_HALF_LIFE)")
+	}
+
+	queryCmd.AddCommand(askCmd)
+	queryCmd.AddCommand(searchCmd)
+	queryCmd.AddCommand(compareCmd)
+	queryCmd.AddCommand(interactiveCmd)
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var runsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "Show what indexing runs changed",
+	Long: `List recent indexing runs, show their processing reports and the documents
+a run added or updated, with a generated digest of the new content. Runs in
+progress on the orchestrator can be cancelled and resumed later.`,
+}
+
+var runsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recent indexing runs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, err := cmd.Flags().GetInt("limit")
+		if err != nil {
+			return fmt.Errorf("failed to get limit flag: %w", err)
+		}
+
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		list, err := orchestrator.Runs(cmd.Context(), limit)
+		if err != nil {
+			return fmt.Errorf("failed to list runs: %w", err)
+		}
+		return printResult(runListResult{Runs: list, Count: len(list)})
+	},
+}
+
+// runListResult is the output of the runs list command
+type runListResult struct {
+	Runs  []client.RunSummary `json:"runs"`
+	Count int                 `json:"count"`
+}
+
+func (r runListResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗂️  Indexing runs (%d)\n\n", r.Count)
+	for _, run := range r.Runs {
+		fmt.Fprintf(w, "%s  %s  %-9s  %s\n", run.ID, formatTime(run.StartedAt), run.Status, runTarget(run.Directory))
+		fmt.Fprintf(w, "   Added: %d  Updated: %d  Skipped: %d  Failed: %d", run.Added, run.Updated, run.Skipped, run.Failed)
+		if run.Remaining > 0 {
+			fmt.Fprintf(w, "  Remaining: %d", run.Remaining)
+		}
+		if tokens := run.Tokens.Prompt + run.Tokens.Completion; tokens > 0 {
+			fmt.Fprintf(w, "  Tokens: %d", tokens)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func (r runListResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Runs))
+	for _, run := range r.Runs {
+		rows = append(rows, []string{
+			run.ID,
+			formatTime(run.StartedAt),
+			run.Status,
+			runTarget(run.Directory),
+			strconv.Itoa(run.Added),
+			strconv.Itoa(run.Updated),
+			strconv.Itoa(run.Skipped),
+			strconv.Itoa(run.Failed),
+		})
+	}
+	writeRows(w, []string{"ID", "STARTED", "STATUS", "DIRECTORY", "ADDED", "UPDATED", "SKIPPED", "FAILED"}, rows)
+}
+
+var runsReportCmd = &cobra.Command{
+	Use:   "report [run-id]",
+	Short: "Show the processing report of a run",
+	Long: `Show a run's processing report: the time its files spent in each stage, the
+Azure OpenAI tokens it used and the files that were skipped or failed. Use
+--table to list every file, or "runs export" to save the report. --wait
+reports a running run once it finishes, showing its progress meanwhile.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		wait, err := cmd.Flags().GetBool("wait")
+		if err != nil {
+			return fmt.Errorf("failed to get wait flag: %w", err)
+		}
+
+		opts := clientOptions()
+		if wait {
+			// Each request is held until the run makes progress
+			opts.Timeout = runWaitInterval + 15*time.Second
+		}
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, opts)
+		report, err := orchestrator.Run(cmd.Context(), args[0])
+		for err == nil && wait && report.Status == runs.StatusRunning {
+			fmt.Fprintf(os.Stderr, "⏳ %d/%d files (%d skipped, %d failed)\n",
+				len(report.Files), report.Total, report.Skipped, report.Failed)
+			report, err = orchestrator.WaitRun(cmd.Context(), args[0], runWaitInterval)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get run report: %w", err)
+		}
+		return printResult(runReportResult{report})
+	},
+}
+
+// runWaitInterval is the longest each request of "runs report --wait" is
+// held waiting for the run to make progress
+const runWaitInterval = 30 * time.Second
+
+// runReportResult is the output of the runs report command
+type runReportResult struct {
+	*client.RunReport
+}
+
+func (r runReportResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "📊 Run %s: %s (%s, %s)\n\n", r.ID, runTarget(r.Directory), r.Status, formatTime(r.StartedAt))
+	fmt.Fprintf(w, "Files:     %d processed, %d skipped, %d failed\n", r.Processed, r.Skipped, r.Failed)
+	if r.DurationMs > 0 {
+		fmt.Fprintf(w, "Duration:  %s\n", time.Duration(r.DurationMs)*time.Millisecond)
+	}
+	fmt.Fprintf(w, "Tokens:    %d prompt, %d completion\n", r.Tokens.Prompt, r.Tokens.Completion)
+	fmt.Fprintln(w, "\nTime per stage:")
+	for _, stage := range runs.Stages {
+		fmt.Fprintf(w, "   %-9s  %s\n", stage, time.Duration(r.StageMs[stage])*time.Millisecond)
+	}
+	for _, f := range r.Files {
+		switch f.Outcome {
+		case runs.OutcomeFailed:
+			fmt.Fprintf(w, "❌ %s (%d attempts): %s\n", f.FilePath, f.Attempts, f.Error)
+		case runs.OutcomeSkipped:
+			fmt.Fprintf(w, "⏭️  %s: %s\n", f.FilePath, f.Error)
+		}
+	}
+}
+
+func (r runReportResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Files))
+	for _, f := range r.Files {
+		rows = append(rows, []string{
+			f.FilePath,
+			f.Outcome,
+			strconv.Itoa(f.Attempts),
+			(time.Duration(f.DurationMs) * time.Millisecond).String(),
+			strconv.FormatInt(f.Tokens.Prompt+f.Tokens.Completion, 10),
+			f.Error,
+		})
+	}
+	writeRows(w, []string{"FILE", "OUTCOME", "ATTEMPTS", "DURATION", "TOKENS", "ERROR"}, rows)
+}
+
+var runsExportCmd = &cobra.Command{
+	Use:   "export [run-id]",
+	Short: "Export the processing report of a run as JSON or CSV",
+	Long: `Export a run's processing report. JSON holds the whole report; CSV has a row
+per file with its outcome, a column per stage duration and its token usage.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("failed to get format flag: %w", err)
+		}
+		output, err := cmd.Flags().GetString("file")
+		if err != nil {
+			return fmt.Errorf("failed to get file flag: %w", err)
+		}
+		if format != "json" && format != "csv" {
+			return fmt.Errorf("format must be json or csv")
+		}
+
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		report, err := orchestrator.Run(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get run report: %w", err)
+		}
+
+		w := io.Writer(os.Stdout)
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create export file: %w", err)
+			}
+			defer f.Close() //nolint:errcheck
+			w = f
+		}
+		if format == "csv" {
+			err = runs.WriteCSV(w, report.Files)
+		} else {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(report)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		if output != "" {
+			fmt.Fprintf(os.Stderr, "Exported the report of run %s to %s\n", report.ID, output)
+		}
+		return nil
+	},
+}
+
+var runsChangesCmd = &cobra.Command{
+	Use:   "changes [run-id]",
+	Short: "Show the documents a run added or updated",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		changes, err := orchestrator.RunChanges(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get run changes: %w", err)
+		}
+		return printResult(runChangesResult{changes})
+	},
+}
+
+// runChangesResult is the output of the runs changes command
+type runChangesResult struct {
+	*client.RunChanges
+}
+
+func (r runChangesResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗂️  Run %s: %s (%s, %s)\n\n", r.RunID, runTarget(r.Directory), r.Status, formatTime(r.StartedAt))
+	if len(r.Changes) == 0 {
+		fmt.Fprintln(w, "No documents were added or updated.")
+		return
+	}
+	writeChanges(w, r.Changes, r.Digest)
+}
+
+func (r runChangesResult) writeTable(w io.Writer) {
+	writeChangeRows(w, r.Changes)
+}
+
+var runsCancelCmd = &cobra.Command{
+	Use:   "cancel [run-id]",
+	Short: "Cancel a run in progress",
+	Long: `Cancel a run in progress on the orchestrator. The run stops once its current
+file is done and keeps the files it did not reach, so it can be resumed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		if err := orchestrator.CancelRun(cmd.Context(), args[0]); err != nil {
+			return fmt.Errorf("failed to cancel run: %w", err)
+		}
+		return printResult(runActionResult{RunID: args[0], Status: "cancelling"})
+	},
+}
+
+var runsResumeCmd = &cobra.Command{
+	Use:   "resume [run-id]",
+	Short: "Resume a cancelled run",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		resumed, err := orchestrator.ResumeRun(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to resume run: %w", err)
+		}
+		return printResult(runActionResult{RunID: resumed.RunID, Status: "resuming", Remaining: resumed.Remaining})
+	},
+}
+
+// runActionResult is the output of the runs cancel and resume commands
+type runActionResult struct {
+	RunID     string `json:"run_id"`
+	Status    string `json:"status"`
+	Remaining int    `json:"remaining,omitempty"`
+}
+
+func (r runActionResult) writeText(w io.Writer) {
+	if r.Status == "cancelling" {
+		fmt.Fprintf(w, "🛑 Cancelling run %s after its current file\n", r.RunID)
+		return
+	}
+	fmt.Fprintf(w, "▶️  Resuming run %s with %d file(s) left\n", r.RunID, r.Remaining)
+}
+
+func (r runActionResult) writeTable(w io.Writer) {
+	writeRows(w, []string{"RUN", "STATUS", "REMAINING"}, [][]string{{r.RunID, r.Status, strconv.Itoa(r.Remaining)}})
+}
+
+var runsBatchCmd = &cobra.Command{
+	Use:   "batch [file...]",
+	Short: "Process a list of files as one run",
+	Long: `Send a list of files, as paths or file:// URLs on the orchestrator's host, to
+be processed as one run, followed like a directory run with "runs report".
+Each file is reported accepted, skipped (repeated, or indexed and not modified
+since) or invalid. --from reads more files from a file, one per line, or from
+standard input with "-".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("failed to get force flag: %w", err)
+		}
+		from, err := cmd.Flags().GetString("from")
+		if err != nil {
+			return fmt.Errorf("failed to get from flag: %w", err)
+		}
+
+		items := args
+		if from != "" {
+			listed, err := readItems(from)
+			if err != nil {
+				return err
+			}
+			items = append(items, listed...)
+		}
+		if len(items) == 0 {
+			return fmt.Errorf("no files given")
+		}
+
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		result, err := orchestrator.ProcessBatch(cmd.Context(), items, force)
+		if err != nil {
+			return fmt.Errorf("failed to process batch: %w", err)
+		}
+		return printResult(batchResult{result})
+	},
+}
+
+// readItems reads the non-empty lines of a file, or of standard input for "-"
+func readItems(name string) ([]string, error) {
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file list: %w", err)
+	}
+	var items []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			items = append(items, line)
+		}
+	}
+	return items, nil
+}
+
+// batchResult is the output of the runs batch command
+type batchResult struct {
+	*client.BatchResult
+}
+
+func (r batchResult) writeText(w io.Writer) {
+	if r.RunID != "" {
+		fmt.Fprintf(w, "▶️  Run %s processing %d file(s)\n", r.RunID, r.Accepted)
+	} else {
+		fmt.Fprintln(w, "No files accepted")
+	}
+	fmt.Fprintf(w, "   Accepted: %d  Skipped: %d  Invalid: %d\n", r.Accepted, r.Skipped, r.Invalid)
+	for _, item := range r.Items {
+		if item.Reason != "" {
+			fmt.Fprintf(w, "   %-8s %s: %s\n", item.Status, item.Item, item.Reason)
+		}
+	}
+}
+
+func (r batchResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Items))
+	for _, item := range r.Items {
+		rows = append(rows, []string{item.Item, item.Status, item.Reason})
+	}
+	writeRows(w, []string{"ITEM", "STATUS", "REASON"}, rows)
+}
+
+// runTarget names what a run indexed: its directory, or a batch of files
+func runTarget(directory string) string {
+	if directory == "" {
+		return "(batch)"
+	}
+	return directory
+}
+
+var indexingCmd = &cobra.Command{
+	Use:   "indexing",
+	Short: "Pause or resume background indexing",
+	Long: `Pause directory runs and the ingest queue workers on the orchestrator
+before their next file, and resume them. The pause lasts until resumed or the
+orchestrator restarts. The status shows how many queued files wait at each
+priority.`,
+}
+
+var indexingStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether indexing is paused and the queue depth",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return controlIndexing(cmd, "get the status of", client.Orchestrator.Indexing)
+	},
+}
+
+var indexingPauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause background indexing",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return controlIndexing(cmd, "pause", client.Orchestrator.PauseIndexing)
+	},
+}
+
+var indexingResumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume paused indexing",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return controlIndexing(cmd, "resume", client.Orchestrator.ResumeIndexing)
+	},
+}
+
+// controlIndexing calls an indexing control endpoint and prints the status
+func controlIndexing(cmd *cobra.Command, verb string, call func(client.Orchestrator, context.Context) (*client.IndexingStatus, error)) error {
+	orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+	status, err := call(orchestrator, cmd.Context())
+	if err != nil {
+		return fmt.Errorf("failed to %s indexing: %w", verb, err)
+	}
+	return printResult(indexingResult{status})
+}
+
+// indexingResult is the output of the indexing commands
+type indexingResult struct {
+	*client.IndexingStatus
+}
+
+func (r indexingResult) writeText(w io.Writer) {
+	if r.Paused {
+		fmt.Fprintln(w, "⏸️  Indexing is paused")
+	} else {
+		fmt.Fprintln(w, "▶️  Indexing is running")
+	}
+	for _, id := range r.ActiveRuns {
+		fmt.Fprintf(w, "   Run in progress: %s\n", id)
+	}
+	if len(r.DegradedStages) > 0 {
+		fmt.Fprintf(w, "⚠️  Skipping %s until the dependency recovers\n", strings.Join(r.DegradedStages, " and "))
+	}
+
+	q := r.Queue
+	fmt.Fprintf(w, "\n📥 Ingest queue (%d workers, %d in flight)\n", q.Workers, q.InFlight)
+	if q.Shared {
+		fmt.Fprintf(w, "   Shared by the replicas, %d files being processed across them\n", q.Claimed)
+	}
+	for _, priority := range queuePriorities {
+		fmt.Fprintf(w, "   %-6s  %d waiting", priority, q.Depth[priority])
+		if wait := q.OldestWait[priority]; wait != "" {
+			fmt.Fprintf(w, ", oldest %s", wait)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "   Completed: %d  Skipped: %d  Failed: %d\n", q.Completed, q.Skipped, q.Failed)
+}
+
+func (r indexingResult) writeTable(w io.Writer) {
+	rows := [][]string{{fmt.Sprint(r.Paused), ""}}
+	if len(r.ActiveRuns) > 0 {
+		rows = rows[:0]
+		for _, id := range r.ActiveRuns {
+			rows = append(rows, []string{fmt.Sprint(r.Paused), id})
+		}
+	}
+	writeRows(w, []string{"PAUSED", "ACTIVE RUN"}, rows)
+
+	fmt.Fprintln(w)
+	queueRows := make([][]string, 0, len(queuePriorities))
+	for _, priority := range queuePriorities {
+		queueRows = append(queueRows, []string{priority, strconv.Itoa(r.Queue.Depth[priority]), r.Queue.OldestWait[priority]})
+	}
+	writeRows(w, []string{"PRIORITY", "WAITING", "OLDEST"}, queueRows)
+}
+
+// queuePriorities lists the ingest queue priorities from highest to lowest
+var queuePriorities = []string{"high", "normal", "low"}
+
+// writeChanges prints a run's change digest followed by the changed documents
+func writeChanges(w io.Writer, changes []runs.Change, digest string) {
+	if digest != "" {
+		fmt.Fprintln(w, "📝 What's new")
+		fmt.Fprintln(w, digest)
+		fmt.Fprintln(w)
+	}
+	for _, c := range changes {
+		marker := "🆕"
+		if c.Kind == runs.ChangeUpdated {
+			marker = "🔄"
+		}
+		fmt.Fprintf(w, "%s %s (%s)\n", marker, c.FilePath, c.DocumentID)
+	}
+}
+
+// writeChangeRows prints a table of changed documents
+func writeChangeRows(w io.Writer, changes []runs.Change) {
+	rows := make([][]string, 0, len(changes))
+	for _, c := range changes {
+		rows = append(rows, []string{c.Kind, c.FilePath, c.DocumentID, c.PreviousID})
+	}
+	writeRows(w, []string{"CHANGE", "FILE", "DOCUMENT", "PREVIOUS"}, rows)
+}
+
+func init() {
+	runsListCmd.Flags().IntP("limit", "n", 20, "Maximum number of runs to list")
+	runsReportCmd.Flags().Bool("wait", false, "Wait for a running run to finish before reporting")
+	runsExportCmd.Flags().String("format", "json", "Export format: json or csv")
+	runsExportCmd.Flags().StringP("file", "f", "", "Write to this file instead of standard output")
+	runsBatchCmd.Flags().Bool("force", false, "Process files even if already indexed and not modified since")
+	runsBatchCmd.Flags().String("from", "", "Read files to process from this file, one per line (- for standard input)")
+
+	runsCmd.AddCommand(runsListCmd)
+	runsCmd.AddCommand(runsReportCmd)
+	runsCmd.AddCommand(runsExportCmd)
+	runsCmd.AddCommand(runsChangesCmd)
+	runsCmd.AddCommand(runsCancelCmd)
+	runsCmd.AddCommand(runsResumeCmd)
+	runsCmd.AddCommand(runsBatchCmd)
+
+	indexingCmd.AddCommand(indexingStatusCmd)
+	indexingCmd.AddCommand(indexingPauseCmd)
+	indexingCmd.AddCommand(indexingResumeCmd)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// healthTimeout bounds each service health check
+const healthTimeout = 5 * time.Second
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Check indexing status",
+	Long: `Summarize the document registry by processing state.
+Exits with status 2 when some documents failed to process.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, clientOptions())
+		records, err := orchestrator.Documents(cmd.Context(), client.DocumentFilter{})
+		if err != nil {
+			return fmt.Errorf("failed to list documents: %w", err)
+		}
+
+		res := statusResult{Total: len(records), ByState: make(map[string]int)}
+		for _, record := range records {
+			res.ByState[string(record.State)]++
+			switch record.State {
+			case models.StateIndexed:
+				res.Indexed++
+			case models.StateFailed:
+				res.Failed++
+			default:
+				res.Pending++
+			}
+		}
+
+		if err := printResult(res); err != nil {
+			return err
+		}
+		return partialFailure(res.Failed, "document(s)")
+	},
+}
+
+// statusResult is the output of the status command
+type statusResult struct {
+	Total   int            `json:"total"`
+	Indexed int            `json:"indexed"`
+	Pending int            `json:"pending"`
+	Failed  int            `json:"failed"`
+	ByState map[string]int `json:"by_state"`
+}
+
+func (r statusResult) writeText(w io.Writer) {
+	fmt.Fprintln(w, "📊 RepoGraph AI Status")
+	fmt.Fprintf(w, "Total Documents: %d\n", r.Total)
+	fmt.Fprintf(w, "Indexed: %d\n", r.Indexed)
+	fmt.Fprintf(w, "Pending: %d\n", r.Pending)
+	fmt.Fprintf(w, "Failed: %d\n", r.Failed)
+}
+
+func (r statusResult) writeTable(w io.Writer) {
+	counts := make(map[string]string, len(r.ByState))
+	for state, n := range r.ByState {
+		counts[state] = strconv.Itoa(n)
+	}
+	rows := make([][]string, 0, len(counts)+1)
+	for _, state := range sortedKeys(counts) {
+		rows = append(rows, []string{state, counts[state]})
+	}
+	rows = append(rows, []string{"TOTAL", strconv.Itoa(r.Total)})
+	writeRows(w, []string{"STATE", "DOCUMENTS"}, rows)
+}
+
+var healthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Check service health",
+	Long: `Call the health endpoint of every platform service.
+Exits with status 2 when some services are unhealthy.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		services := []serviceHealth{
+			{Name: "Orchestrator", URL: cfg.Services.OrchestratorServiceURL},
+			{Name: "Document Scanner", URL: cfg.Services.DocumentScannerURL},
+			{Name: "Content Extractor", URL: cfg.Services.ContentExtractorURL},
+			{Name: "Vision Service", URL: cfg.Services.VisionServiceURL},
+			{Name: "Summarization", URL: cfg.Services.SummarizationServiceURL},
+			{Name: "Embedding Service", URL: cfg.Services.EmbeddingServiceURL},
+			{Name: "Vector Store", URL: cfg.Services.VectorStoreServiceURL},
+			{Name: "Query Service", URL: cfg.Services.QueryServiceURL},
+		}
+
+		opts := clientOptions()
+		opts.Timeout = healthTimeout
+		opts.MaxRetries = 0
+
+		var wg sync.WaitGroup
+		for i := range services {
+			wg.Add(1)
+			go func(s *serviceHealth) {
+				defer wg.Done()
+				s.check(cmd.Context(), opts)
+			}(&services[i])
+		}
+		wg.Wait()
+
+		res := healthResult{Healthy: true, Services: services}
+		for _, s := range services {
+			if !s.Healthy {
+				res.Healthy = false
+				res.Unhealthy++
+			}
+		}
+
+		if err := printResult(res); err != nil {
+			return err
+		}
+		return partialFailure(res.Unhealthy, "service(s)")
+	},
+}
+
+// serviceHealth is the health check outcome of one service
+type serviceHealth struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Healthy   bool   `json:"healthy"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (s *serviceHealth) check(ctx context.Context, opts client.Options) {
+	if s.URL == "" {
+		s.Error = "URL not configured"
+		return
+	}
+	start := time.Now()
+	err := client.Ping(ctx, s.URL, opts)
+	s.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		s.Error = err.Error()
+		return
+	}
+	s.Healthy = true
+}
+
+// healthResult is the output of the health command
+type healthResult struct {
+	Healthy   bool            `json:"healthy"`
+	Unhealthy int             `json:"unhealthy"`
+	Services  []serviceHealth `json:"services"`
+}
+
+func (r healthResult) writeText(w io.Writer) {
+	fmt.Fprintln(w, "🏥 Health Check")
+	fmt.Fprintln(w)
+	for _, s := range r.Services {
+		fmt.Fprintf(w, "%-20s ", s.Name+":")
+		if s.Healthy {
+			fmt.Fprintf(w, "✅ Healthy (%dms)\n", s.LatencyMs)
+		} else {
+			fmt.Fprintf(w, "❌ Unhealthy: %s\n", s.Error)
+		}
+	}
+}
+
+func (r healthResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Services))
+	for _, s := range r.Services {
+		status := "healthy"
+		if !s.Healthy {
+			status = "unhealthy"
+		}
+		rows = append(rows, []string{s.Name, status, strconv.FormatInt(s.LatencyMs, 10) + "ms", s.URL, s.Error})
+	}
+	writeRows(w, []string{"SERVICE", "STATUS", "LATENCY", "URL", "ERROR"}, rows)
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show vector index statistics",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := client.NewVectorStoreClient(cfg.Services.VectorStoreServiceURL, clientOptions())
+		stats, err := store.Stats(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get index statistics: %w", err)
+		}
+		return printResult(statsResult{Index: stats})
+	},
+}
+
+// statsResult is the output of the stats command
+type statsResult struct {
+	Index map[string]interface{} `json:"index"`
+}
+
+func (r statsResult) values() map[string]string {
+	values := make(map[string]string)
+	flatten("", r.Index, values)
+	return values
+}
+
+func (r statsResult) writeText(w io.Writer) {
+	fmt.Fprintln(w, "📈 Index Statistics")
+	values := r.values()
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s: %s\n", key, values[key])
+	}
+}
+
+func (r statsResult) writeTable(w io.Writer) {
+	values := r.values()
+	rows := make([][]string, 0, len(values))
+	for _, key := range sortedKeys(values) {
+		rows = append(rows, []string{key, values[key]})
+	}
+	writeRows(w, []string{"METRIC", "VALUE"}, rows)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/topics"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var topicsCmd = &cobra.Command{
+	Use:   "topics",
+	Short: "Show what the indexed documents are about",
+	Long: `Show the topic overview: the indexed documents clustered by their embeddings,
+each cluster named by the model, with the documents nearest its centre.
+The orchestrator generates it every TOPICS_INTERVAL when TOPICS_ENABLED is
+set; --refresh generates it now.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		refresh, err := cmd.Flags().GetBool("refresh")
+		if err != nil {
+			return fmt.Errorf("failed to get refresh flag: %w", err)
+		}
+		documents, err := cmd.Flags().GetInt("documents")
+		if err != nil {
+			return fmt.Errorf("failed to get documents flag: %w", err)
+		}
+
+		opts := clientOptions()
+		if refresh {
+			// Naming every cluster takes a model call each
+			opts.Timeout = 5 * time.Minute
+		}
+		orchestrator := client.NewOrchestratorClient(cfg.Services.OrchestratorServiceURL, opts)
+
+		var overview *topics.Overview
+		if refresh {
+			overview, err = orchestrator.RunTopics(cmd.Context())
+		} else {
+			overview, err = orchestrator.Topics(cmd.Context())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get topic overview: %w", err)
+		}
+		return printResult(topicsResult{Overview: overview, shown: documents})
+	},
+}
+
+// topicsResult is the output of the topics command
+type topicsResult struct {
+	*topics.Overview
+	shown int // documents listed per topic in text output
+}
+
+func (r topicsResult) writeText(w io.Writer) {
+	fmt.Fprintf(w, "🗂️  %d topics across %d documents (generated %s)\n",
+		len(r.Topics), r.Documents, formatTime(r.GeneratedAt))
+	if r.Skipped > 0 {
+		fmt.Fprintf(w, "   %d documents left out\n", r.Skipped)
+	}
+	for _, t := range r.Topics {
+		fmt.Fprintf(w, "\n%d. %s (%d documents)\n", t.ID, t.Label, t.Size)
+		if t.Description != "" {
+			fmt.Fprintf(w, "   %s\n", t.Description)
+		}
+		for i, d := range t.Documents {
+			if i == r.shown {
+				break
+			}
+			fmt.Fprintf(w, "   - %s (%.2f)\n", d.FilePath, d.Similarity)
+		}
+	}
+}
+
+func (r topicsResult) writeTable(w io.Writer) {
+	rows := make([][]string, 0, len(r.Topics))
+	for _, t := range r.Topics {
+		top := ""
+		if len(t.Documents) > 0 {
+			top = t.Documents[0].FilePath
+		}
+		rows = append(rows, []string{fmt.Sprint(t.ID), t.Label, fmt.Sprint(t.Size), top})
+	}
+	writeRows(w, []string{"ID", "LABEL", "DOCUMENTS", "CENTRAL DOCUMENT"}, rows)
+}
+
+func init() {
+	topicsCmd.Flags().Bool("refresh", false, "Generate the overview now instead of showing the last one")
+	topicsCmd.Flags().Int("documents", 3, "Documents listed per topic")
+}
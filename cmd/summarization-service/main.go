@@ -10,12 +10,27 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/azure"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
 	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/tlsconfig"
 	"go.uber.org/zap"
 )
 
+var (
+	summaryClient   *azure.OpenAIClient
+	summaryDefaults config.SummaryConfig
+)
+
 func main() {
+	if apispec.Requested() {
+		if err := apiSpec.Write(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
@@ -28,7 +43,14 @@ func main() {
 	defer func() { _ = logger.Sync() }() //nolint:errcheck
 	logger.Info("Starting Summarization Service",
 		zap.String("version", "1.0.0"),
-		zap.Int("port", 8084))
+		zap.Int("port", 8084),
+		zap.String("style", cfg.Summary.Style),
+		zap.Int("max_tokens", cfg.Summary.MaxTokens))
+	summaryClient, err = azure.NewOpenAIClient(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create Azure OpenAI client", zap.Error(err))
+	}
+	summaryDefaults = cfg.Summary
 	router := gin.Default()
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"healthy": true})
@@ -36,12 +58,17 @@ func main() {
 	router.GET("/ready", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"ready": true})
 	})
+	router.GET("/openapi.json", apiSpec.Serve())
 	v1 := router.Group("/api/v1")
 	{
-		// TODO: Add service-specific endpoints
 		v1.GET("/status", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"status": "operational"})
 		})
+		apiSpec.Register(v1)
+	}
+	certs, err := tlsconfig.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to load TLS certificates", zap.Error(err))
 	}
 	srv := &http.Server{
 		Addr:         ":8084",
@@ -51,7 +78,7 @@ func main() {
 	}
 	go func() {
 		logger.Info("Summarization Service starting", zap.String("address", srv.Addr))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := tlsconfig.ListenAndServe(srv, certs); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
@@ -66,3 +93,76 @@ func main() {
 	}
 	logger.Info("Server exited")
 }
+
+// summarizeRequest is the request body of the summarize endpoint. Unset
+// options take the configured defaults of the category.
+type summarizeRequest struct {
+	Content     string   `json:"content" binding:"required"`
+	Context     string   `json:"context" binding:"max=500" description:"What the content is, such as an architecture document"`
+	Category    string   `json:"category" description:"File category whose configured defaults apply, such as document or code"`
+	MaxTokens   int      `json:"max_tokens" binding:"omitempty,min=1,max=4096"`
+	Temperature *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	Style       string   `json:"style" binding:"omitempty,oneof=abstract bullet technical executive"`
+	Language    string   `json:"language" binding:"max=50" description:"Output language; defaults to the language of the content"`
+}
+
+// summarizeResponse is the response body of the summarize endpoint
+type summarizeResponse struct {
+	Summary     string  `json:"summary"`
+	Style       string  `json:"style"`
+	Language    string  `json:"language,omitempty"`
+	MaxTokens   int     `json:"max_tokens"`
+	Temperature float64 `json:"temperature"`
+	// PromptTokens and CompletionTokens are the tokens the model used
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+}
+
+// summarize generates a summary of the content with the requested length,
+// style and language
+func summarize(c *gin.Context) {
+	var req summarizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings := summaryDefaults.ForCategory(req.Category)
+	opts := azure.SummaryOptions{
+		MaxTokens:   settings.MaxTokens,
+		Temperature: settings.Temperature,
+		Style:       settings.Style,
+		Language:    settings.Language,
+		Context:     req.Context,
+	}
+	if req.MaxTokens > 0 {
+		opts.MaxTokens = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		opts.Temperature = *req.Temperature
+	}
+	if req.Style != "" {
+		opts.Style = req.Style
+	}
+	if req.Language != "" {
+		opts.Language = req.Language
+	}
+
+	var meter azure.TokenMeter
+	summary, err := summaryClient.GenerateSummary(azure.WithTokenMeter(c.Request.Context(), &meter), req.Content, opts)
+	if err != nil {
+		logger.Error("Failed to generate summary", zap.String("style", opts.Style), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	usage := meter.Usage()
+	c.JSON(http.StatusOK, summarizeResponse{
+		Summary:          summary,
+		Style:            opts.Style,
+		Language:         opts.Language,
+		MaxTokens:        opts.MaxTokens,
+		Temperature:      opts.Temperature,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	})
+}
@@ -0,0 +1,12 @@
+package main
+
+import "github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+
+// apiSpec describes the endpoints served under /api/v1
+var apiSpec = apispec.New("Summarization Service", "1.0.0", "/api/v1",
+	apispec.Operation{
+		Method: "POST", Path: "/summarize", Tag: "summarization", Handler: summarize,
+		Summary: "Summarize content with a given length, style and language",
+		Request: summarizeRequest{}, Response: summarizeResponse{},
+	},
+)
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/httpsec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"go.uber.org/zap"
+)
+
+var (
+	indexAdmin *pinecone.IndexAdmin
+	// adminToken is the token admin requests carry, sent by the gateway
+	// for admin clients
+	adminToken string
+)
+
+// adminOnly serves a handler only to requests carrying the admin token
+func adminOnly(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if httpsec.RequireAdmin(c, adminToken) {
+			handler(c)
+		}
+	}
+}
+
+// createIndex creates a serverless index; fields left out of the request
+// take the configured index name, dimension, metric, cloud and region
+func createIndex(c *gin.Context) {
+	req := indexAdmin.Defaults()
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	index, err := indexAdmin.CreateServerless(c.Request.Context(), req)
+	if err != nil {
+		indexError(c, "Failed to create index", req.Name, err)
+		return
+	}
+	c.JSON(http.StatusCreated, index)
+}
+
+func describeIndex(c *gin.Context) {
+	index, err := indexAdmin.Describe(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		indexError(c, "Failed to describe index", c.Param("name"), err)
+		return
+	}
+	c.JSON(http.StatusOK, index)
+}
+
+// deleteIndex deletes an index this deployment owns. The request must
+// repeat the name in the confirm query parameter.
+func deleteIndex(c *gin.Context) {
+	name := c.Param("name")
+	if c.Query("confirm") != name {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("deleting index %s removes all its vectors; pass confirm=%s to confirm", name, name)})
+		return
+	}
+	if !indexAdmin.Owns(name) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("index %s is not the configured index; list it in pinecone.deletable_indexes to delete it", name)})
+		return
+	}
+	if err := indexAdmin.Delete(c.Request.Context(), name); err != nil {
+		indexError(c, "Failed to delete index", name, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": true, "name": name})
+}
+
+// indexError answers with the status matching a control plane error
+func indexError(c *gin.Context, msg, name string, err error) {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, pinecone.ErrIndexNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, pinecone.ErrIndexExists):
+		status = http.StatusConflict
+	default:
+		logger.Error(msg, zap.String("index", name), zap.Error(err))
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -10,12 +10,29 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/chunkstore"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/idempotency"
+	"github.com/nadeeshame/rag-knowledge-service/internal/imagesearch"
 	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/routing"
+	"github.com/nadeeshame/rag-knowledge-service/internal/tlsconfig"
+	"github.com/nadeeshame/rag-knowledge-service/internal/vectorstore"
 	"go.uber.org/zap"
 )
 
+var store *vectorstore.Store
+
 func main() {
+	if apispec.Requested() {
+		if err := apiSpec.Write(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
@@ -29,19 +46,61 @@ func main() {
 	logger.Info("Starting Vector Store",
 		zap.String("version", "1.0.0"),
 		zap.Int("port", 8086))
+	store, err = vectorstore.NewStore(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create vector store", zap.Error(err))
+	}
+	adminToken = cfg.Services.AdminToken
+	indexAdmin, err = pinecone.NewIndexAdmin(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create index admin", zap.Error(err))
+	}
+	chunkStore, err := chunkstore.NewStore(context.Background(), cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create chunk store", zap.Error(err))
+	}
+	if chunkStore != nil {
+		defer chunkStore.Close() //nolint:errcheck
+		store.SetChunkStore(chunkStore)
+	}
+	imageIndex, err := imagesearch.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create image index", zap.Error(err))
+	}
+	if imageIndex != nil {
+		store.SetImageIndex(imageIndex)
+	}
+	categoryRouter, err := routing.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create category router", zap.Error(err))
+	}
+	if categoryRouter != nil {
+		store.SetRouter(categoryRouter)
+	}
+	idempotencyStore, err := idempotency.NewStore(context.Background(), cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create idempotency store", zap.Error(err))
+	}
+	if idempotencyStore != nil {
+		defer idempotencyStore.Close() //nolint:errcheck
+	}
 	router := gin.Default()
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"healthy": true})
 	})
-	router.GET("/ready", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"ready": true})
-	})
+	router.GET("/ready", readiness)
+	router.GET("/openapi.json", apiSpec.Serve())
 	v1 := router.Group("/api/v1")
+	v1.Use(idempotency.Middleware(idempotencyStore, cfg.Idempotency.TTL, logger.Log))
 	{
-		// TODO: Add service-specific endpoints
 		v1.GET("/status", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"status": "operational"})
 		})
+		apiSpec.Register(v1)
+	}
+	certs, err := tlsconfig.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to load TLS certificates", zap.Error(err))
 	}
 	srv := &http.Server{
 		Addr:         ":8086",
@@ -51,7 +110,7 @@ func main() {
 	}
 	go func() {
 		logger.Info("Vector Store starting", zap.String("address", srv.Addr))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := tlsconfig.ListenAndServe(srv, certs); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
@@ -66,3 +125,111 @@ func main() {
 	}
 	logger.Info("Server exited")
 }
+
+// readiness reports ready once the index answers a stats request
+func readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := store.GetIndexStats(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"ready": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ready": true})
+}
+
+// upsertRequest is the request body of the upsert endpoint
+type upsertRequest struct {
+	Vectors   []*pinecone.Vector `json:"vectors" binding:"required"`
+	Namespace string             `json:"namespace"`
+}
+
+// searchRequest is the request body of the search endpoint
+type searchRequest struct {
+	QueryVector []float32              `json:"query_vector" binding:"required"`
+	TopK        int                    `json:"top_k"`
+	Namespace   string                 `json:"namespace"`
+	Filter      map[string]interface{} `json:"filter"`
+}
+
+func upsert(c *gin.Context) {
+	var req upsertRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, v := range req.Vectors {
+		if v == nil || v.ID == "" || len(v.Values) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "every vector requires an id and values"})
+			return
+		}
+	}
+
+	if err := store.UpsertRaw(c.Request.Context(), req.Namespace, req.Vectors); err != nil {
+		logger.Error("Failed to upsert vectors", zap.Int("count", len(req.Vectors)), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"upserted_count": len(req.Vectors)})
+}
+
+func search(c *gin.Context) {
+	var req searchRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.TopK <= 0 {
+		req.TopK = 5
+	}
+
+	matches, err := store.QueryRaw(c.Request.Context(), req.Namespace, req.QueryVector, req.TopK, req.Filter)
+	if err != nil {
+		logger.Error("Failed to query vectors", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": matches})
+}
+
+func deleteDocument(c *gin.Context) {
+	documentID := c.Param("id")
+
+	count, err := store.DeleteDocument(c.Request.Context(), documentID)
+	if err != nil {
+		logger.Error("Failed to delete document", zap.String("document_id", documentID), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": count > 0, "count": count})
+}
+
+func stats(c *gin.Context) {
+	result, err := store.GetIndexStats(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to get index stats", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	result["upserts"] = store.UpsertStats()
+
+	c.JSON(http.StatusOK, result)
+}
+
+func exists(c *gin.Context) {
+	found, err := store.CheckDocumentExists(c.Request.Context(), c.Param("hash"))
+	if err != nil {
+		logger.Error("Failed to check document", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exists": found})
+}
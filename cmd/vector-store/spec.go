@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+)
+
+// apiSpec describes the endpoints served under /api/v1
+var apiSpec = apispec.New("Vector Store Service", "1.0.0", "/api/v1",
+	apispec.Operation{
+		Method: "POST", Path: "/upsert", Tag: "vectors", Handler: upsert,
+		Summary: "Store vectors in a namespace",
+		Request: upsertRequest{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/search", Tag: "vectors", Handler: search,
+		Summary: "Search for the nearest vectors",
+		Request: searchRequest{}, Response: struct {
+			Results []pinecone.Match `json:"results"`
+		}{},
+	},
+	apispec.Operation{
+		Method: "DELETE", Path: "/document/:id", Tag: "admin", Handler: adminOnly(deleteDocument),
+		Summary: "Delete all vectors of a document",
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/stats", Tag: "index", Handler: stats,
+		Summary: "Get index statistics",
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/exists/:hash", Tag: "documents", Handler: exists,
+		Summary: "Check whether a file hash is indexed",
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/indexes", Tag: "admin", Handler: adminOnly(createIndex),
+		Summary: "Create a serverless index, by default the configured one",
+		Request: pinecone.CreateIndexRequest{}, Response: pinecone.Index{},
+	},
+	apispec.Operation{
+		Method: "GET", Path: "/indexes/:name", Tag: "admin", Handler: adminOnly(describeIndex),
+		Summary:  "Describe an index and whether it is ready",
+		Response: pinecone.Index{},
+	},
+	apispec.Operation{
+		Method: "DELETE", Path: "/indexes/:name", Tag: "admin", Handler: adminOnly(deleteIndex),
+		Summary: "Delete an index and all its vectors; confirm must repeat the name, and only the configured index or those in pinecone.deletable_indexes are deleted",
+		Query:   []string{"confirm"},
+	},
+)
@@ -2,20 +2,39 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
 	"github.com/nadeeshame/rag-knowledge-service/internal/logger"
+	"github.com/nadeeshame/rag-knowledge-service/internal/tlsconfig"
+	"github.com/nadeeshame/rag-knowledge-service/internal/vision"
 	"go.uber.org/zap"
 )
 
+var (
+	visionService *vision.Service
+	maxImageBytes int64
+)
+
 func main() {
+	if apispec.Requested() {
+		if err := apiSpec.Write(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
@@ -28,7 +47,14 @@ func main() {
 	defer func() { _ = logger.Sync() }() //nolint:errcheck
 	logger.Info("Starting Vision Service",
 		zap.String("version", "1.0.0"),
-		zap.Int("port", 8083))
+		zap.Int("port", 8083),
+		zap.Int("max_concurrent", cfg.Vision.MaxConcurrent),
+		zap.Int("cache_size", cfg.Vision.CacheSize))
+	visionService, err = vision.NewService(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to create vision service", zap.Error(err))
+	}
+	maxImageBytes = cfg.Vision.MaxImageBytes
 	router := gin.Default()
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"healthy": true})
@@ -36,12 +62,17 @@ func main() {
 	router.GET("/ready", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"ready": true})
 	})
+	router.GET("/openapi.json", apiSpec.Serve())
 	v1 := router.Group("/api/v1")
 	{
-		// TODO: Add service-specific endpoints
 		v1.GET("/status", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{"status": "operational"})
+			c.JSON(http.StatusOK, gin.H{"status": "operational", "stats": visionService.Stats()})
 		})
+		apiSpec.Register(v1)
+	}
+	certs, err := tlsconfig.New(cfg, logger.Log)
+	if err != nil {
+		logger.Fatal("Failed to load TLS certificates", zap.Error(err))
 	}
 	srv := &http.Server{
 		Addr:         ":8083",
@@ -51,7 +82,7 @@ func main() {
 	}
 	go func() {
 		logger.Info("Vision Service starting", zap.String("address", srv.Addr))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := tlsconfig.ListenAndServe(srv, certs); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
@@ -66,3 +97,80 @@ func main() {
 	}
 	logger.Info("Server exited")
 }
+
+func analyze(c *gin.Context) {
+	handle(c, visionService.Analyze)
+}
+
+func ocr(c *gin.Context) {
+	handle(c, visionService.OCR)
+}
+
+// handle reads the image from the request and runs the given operation
+func handle(c *gin.Context, op func(ctx context.Context, name string, data []byte) (*vision.Result, error)) {
+	name, data, err := readImage(c)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := op(c.Request.Context(), name, data)
+	if err != nil {
+		logger.Error("Vision request failed", zap.String("file_name", name), zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// readImage accepts either a multipart upload in the "image" (or "file") field or a JSON
+// body with base64 "image" data and an optional "file_name"
+func readImage(c *gin.Context) (string, []byte, error) {
+	// Allow some headroom for multipart framing and base64 expansion
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageBytes*4/3+64*1024)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("image")
+		if err != nil {
+			header, err = c.FormFile("file")
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("image file is required: %w", err)
+		}
+		if header.Size > maxImageBytes {
+			return "", nil, fmt.Errorf("image exceeds %d bytes", maxImageBytes)
+		}
+		file, err := header.Open()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to open upload: %w", err)
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read upload: %w", err)
+		}
+		return header.Filename, data, nil
+	}
+
+	var req struct {
+		Image    string `json:"image" binding:"required"`
+		FileName string `json:"file_name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return "", nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(req.Image)
+	if err != nil {
+		return "", nil, fmt.Errorf("image must be base64 encoded: %w", err)
+	}
+	if int64(len(data)) > maxImageBytes {
+		return "", nil, fmt.Errorf("image exceeds %d bytes", maxImageBytes)
+	}
+	return req.FileName, data, nil
+}
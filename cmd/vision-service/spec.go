@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/vision"
+)
+
+// apiSpec describes the endpoints served under /api/v1. The image endpoints
+// accept multipart uploads as well as JSON, so their bodies are checked by
+// the handlers rather than a schema.
+var apiSpec = apispec.New("Vision Service", "1.0.0", "/api/v1",
+	apispec.Operation{
+		Method: "POST", Path: "/analyze", Tag: "vision", Handler: analyze,
+		Summary:  "Describe an image",
+		Response: vision.Result{},
+	},
+	apispec.Operation{
+		Method: "POST", Path: "/ocr", Tag: "vision", Handler: ocr,
+		Summary:  "Detect text in an image",
+		Response: vision.Result{},
+	},
+)
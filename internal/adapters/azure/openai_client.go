@@ -1,13 +1,16 @@
 package azure
 
 import (
-	"bytes"
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/httppool"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/ratelimit"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
 	"go.uber.org/zap"
 )
@@ -18,8 +21,10 @@ type OpenAIClient struct {
 	endpoint            string
 	embeddingDeployment string
 	chatDeployment      string
+	visionDeployment    string
 	apiVersion          string
 	httpClient          *http.Client
+	streamClient        *http.Client // without the timeout, which would cut long answers
 	logger              *zap.Logger
 }
 
@@ -32,29 +37,83 @@ type EmbeddingRequest struct {
 type EmbeddingResponse struct {
 	Data []struct {
 		Embedding []float32 `json:"embedding"`
+		Index     *int      `json:"index,omitempty"`
 	} `json:"data"`
+	Usage Usage `json:"usage"`
 }
 
 // ChatRequest represents the request body for chat completions
 type ChatRequest struct {
 	Messages    []ChatMessage `json:"messages"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
-	Temperature float32       `json:"temperature,omitempty"`
+	Temperature float32       `json:"temperature"`
+	Stream      bool          `json:"stream,omitempty"`
+	// ResponseFormat asks for a reply in JSON conforming to a schema
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	// Tools are the functions the model may call instead of replying
+	Tools []Tool `json:"tools,omitempty"`
+}
+
+// Tool is a function the model may call
+type Tool struct {
+	Type     string       `json:"type"` // function
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a function and its JSON schema parameters
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// ToolCall is a call of a tool requested by the model
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"` // function
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON object
+	} `json:"function"`
+}
+
+// ResponseFormat is the format a chat reply is generated in
+type ResponseFormat struct {
+	Type       string      `json:"type"` // json_schema
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema names the schema of a structured reply. Without strict the
+// model is guided by the schema but not held to it, so any schema can be
+// used and the reply must still be validated.
+type JSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	Strict bool            `json:"strict,omitempty"`
 }
 
 // ChatMessage represents a chat message
 type ChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// ToolCalls are the calls an assistant message requests
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID is the call a tool message answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 // ChatResponse represents the response from chat API
 type ChatResponse struct {
 	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
+		Message ChatMessage `json:"message"`
 	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+// Usage is the token usage reported with a response
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
 }
 
 // NewOpenAIClient creates a new Azure OpenAI client
@@ -66,13 +125,23 @@ func NewOpenAIClient(cfg *config.Config, logger *zap.Logger) (*OpenAIClient, err
 		return nil, fmt.Errorf("azure OpenAI endpoint is required")
 	}
 
+	httpClient, err := httppool.Client(cfg.ProviderHTTP, cfg.ProviderHTTP.Azure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	// Every Azure OpenAI client in the process shares the configured limits
+	limiter := ratelimit.Shared("azure", cfg.Limits.AzureMaxConcurrent, cfg.Limits.AzureRPM)
+	httpClient.Transport = ratelimit.Transport(limiter, httpClient.Transport)
+
 	return &OpenAIClient{
 		apiKey:              cfg.Azure.OpenAIAPIKey,
 		endpoint:            cfg.Azure.OpenAIEndpoint,
 		embeddingDeployment: cfg.Azure.OpenAIEmbeddingsDeployment,
 		chatDeployment:      cfg.Azure.OpenAIChatDeployment,
+		visionDeployment:    cfg.Azure.OpenAIVisionDeployment,
 		apiVersion:          cfg.Azure.OpenAIAPIVersion,
-		httpClient:          &http.Client{},
+		httpClient:          httpClient,
+		streamClient:        &http.Client{Transport: httpClient.Transport},
 		logger:              logger,
 	}, nil
 }
@@ -89,12 +158,13 @@ func (c *OpenAIClient) GenerateEmbedding(ctx context.Context, text string) ([]fl
 		c.endpoint, c.embeddingDeployment, c.apiVersion)
 
 	reqBody := EmbeddingRequest{Input: []string{text}}
-	jsonBody, err := json.Marshal(reqBody)
+	payload, err := httppool.JSONBody(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
+	defer payload.Release()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	req, err := payload.NewRequest(ctx, "POST", url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -114,9 +184,10 @@ func (c *OpenAIClient) GenerateEmbedding(ctx context.Context, text string) ([]fl
 	}
 
 	var embResp EmbeddingResponse
-	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+	if err := httppool.DecodeJSON(resp.Body, &embResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	meterFrom(ctx).add(embResp.Usage)
 
 	if len(embResp.Data) == 0 {
 		return nil, fmt.Errorf("no embeddings returned")
@@ -128,37 +199,141 @@ func (c *OpenAIClient) GenerateEmbedding(ctx context.Context, text string) ([]fl
 	return embResp.Data[0].Embedding, nil
 }
 
+// GenerateEmbeddings creates embeddings for multiple texts in a single request.
+// Embeddings are returned in the same order as the input texts.
+func (c *OpenAIClient) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("texts cannot be empty")
+	}
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("text %d cannot be empty", i)
+		}
+	}
+
+	c.logger.Debug("Generating embeddings", zap.Int("count", len(texts)))
+
+	url := fmt.Sprintf("%s/openai/deployments/%s/embeddings?api-version=%s",
+		c.endpoint, c.embeddingDeployment, c.apiVersion)
+
+	payload, err := httppool.JSONBody(EmbeddingRequest{Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	defer payload.Release()
+
+	req, err := payload.NewRequest(ctx, "POST", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("api-key", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var embResp EmbeddingResponse
+	if err := httppool.DecodeJSON(resp.Body, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	meterFrom(ctx).add(embResp.Usage)
+
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embResp.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i, d := range embResp.Data {
+		index := i
+		if d.Index != nil {
+			index = *d.Index
+		}
+		if index < 0 || index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", index)
+		}
+		embeddings[index] = d.Embedding
+	}
+
+	return embeddings, nil
+}
+
+// EndpointName returns the endpoint and embedding deployment, for logging
+func (c *OpenAIClient) EndpointName() string {
+	return c.endpoint + "/" + c.embeddingDeployment
+}
+
+// SummaryOptions controls the length, style and language of a summary
+type SummaryOptions struct {
+	MaxTokens   int
+	Temperature float64
+	Style       string // abstract, bullet, technical or executive
+	Language    string // empty for the language of the content
+	Context     string // what the content is, such as "an architecture document"
+}
+
+// summaryStyles are the instructions of each summary style
+var summaryStyles = map[string]string{
+	"abstract":  "Write a concise abstract of the content in one or two paragraphs, focusing on key points and main ideas.",
+	"bullet":    "Summarize the content as a bulleted list of its key points, one short line each.",
+	"technical": "Write a technical summary for engineers: the components, interfaces, data flows, configuration and constraints the content describes.",
+	"executive": "Write an executive summary for decision makers: the purpose, outcomes, risks and recommended actions, without technical detail.",
+}
+
 // GenerateSummary generates a summary for the given text
-func (c *OpenAIClient) GenerateSummary(ctx context.Context, text string) (string, error) {
+func (c *OpenAIClient) GenerateSummary(ctx context.Context, text string, opts SummaryOptions) (string, error) {
 	if text == "" {
 		return "", fmt.Errorf("text cannot be empty")
 	}
+	instructions, ok := summaryStyles[opts.Style]
+	if !ok {
+		return "", fmt.Errorf("unknown summary style %q", opts.Style)
+	}
+	if opts.Language != "" {
+		instructions += " Write the summary in " + opts.Language + "."
+	}
 
 	// Truncate text if too long
 	if len(text) > 10000 {
 		text = text[:10000] + "..."
 	}
 
-	c.logger.Debug("Generating summary", zap.Int("text_length", len(text)))
+	c.logger.Debug("Generating summary",
+		zap.Int("text_length", len(text)),
+		zap.String("style", opts.Style),
+		zap.Int("max_tokens", opts.MaxTokens))
 
 	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
 		c.endpoint, c.chatDeployment, c.apiVersion)
 
+	prompt := "Please summarize the following content"
+	if opts.Context != "" {
+		prompt += ", which is " + opts.Context
+	}
 	reqBody := ChatRequest{
 		Messages: []ChatMessage{
-			{Role: "system", Content: "You are a helpful assistant that creates concise, informative summaries. Focus on key points and main ideas."},
-			{Role: "user", Content: fmt.Sprintf("Please provide a comprehensive summary of the following content:\n\n%s", text)},
+			{Role: "system", Content: "You are a helpful assistant that creates informative summaries. " + instructions},
+			{Role: "user", Content: fmt.Sprintf("%s:\n\n%s", prompt, text)},
 		},
-		MaxTokens:   500,
-		Temperature: 0.3,
+		MaxTokens:   opts.MaxTokens,
+		Temperature: float32(opts.Temperature),
 	}
 
-	jsonBody, err := json.Marshal(reqBody)
+	payload, err := httppool.JSONBody(reqBody)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
+	defer payload.Release()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	req, err := payload.NewRequest(ctx, "POST", url)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -178,9 +353,10 @@ func (c *OpenAIClient) GenerateSummary(ctx context.Context, text string) (string
 	}
 
 	var chatResp ChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+	if err := httppool.DecodeJSON(resp.Body, &chatResp); err != nil {
 		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
+	meterFrom(ctx).add(chatResp.Usage)
 
 	if len(chatResp.Choices) == 0 {
 		return "", fmt.Errorf("no summary generated")
@@ -199,23 +375,63 @@ func (c *OpenAIClient) ChatCompletion(ctx context.Context, systemPrompt, userMes
 		{Role: "user", Content: userMessage},
 	}
 
-	reqBody := ChatRequest{
+	return c.chat(ctx, ChatRequest{
+		Messages:    messages,
+		MaxTokens:   1000,
+		Temperature: 0.7,
+	})
+}
+
+// ChatCompletionJSON performs a chat completion of a conversation whose
+// reply is JSON following a schema. The reply is not validated.
+func (c *OpenAIClient) ChatCompletionJSON(ctx context.Context, messages []ChatMessage, name string, schema json.RawMessage) (string, error) {
+	return c.chat(ctx, ChatRequest{
+		Messages:    messages,
+		MaxTokens:   1000,
+		Temperature: 0.2,
+		ResponseFormat: &ResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &JSONSchema{Name: name, Schema: schema},
+		},
+	})
+}
+
+// ChatCompletionTools performs a chat completion of a conversation in
+// which the model may call tools. The reply is the assistant message: its
+// content, or the tool calls to answer with tool messages before asking
+// again.
+func (c *OpenAIClient) ChatCompletionTools(ctx context.Context, messages []ChatMessage, tools []Tool) (*ChatMessage, error) {
+	return c.chatMessage(ctx, ChatRequest{
 		Messages:    messages,
 		MaxTokens:   1000,
 		Temperature: 0.7,
+		Tools:       tools,
+	})
+}
+
+// chat sends a chat completion request and returns the reply
+func (c *OpenAIClient) chat(ctx context.Context, reqBody ChatRequest) (string, error) {
+	message, err := c.chatMessage(ctx, reqBody)
+	if err != nil {
+		return "", err
 	}
+	return message.Content, nil
+}
 
+// chatMessage sends a chat completion request and returns the reply message
+func (c *OpenAIClient) chatMessage(ctx context.Context, reqBody ChatRequest) (*ChatMessage, error) {
 	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
 		c.endpoint, c.chatDeployment, c.apiVersion)
 
-	jsonBody, err := json.Marshal(reqBody)
+	payload, err := httppool.JSONBody(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
+	defer payload.Release()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	req, err := payload.NewRequest(ctx, "POST", url)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -223,23 +439,108 @@ func (c *OpenAIClient) ChatCompletion(ctx context.Context, systemPrompt, userMes
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	var chatResp ChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if err := httppool.DecodeJSON(resp.Body, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	meterFrom(ctx).add(chatResp.Usage)
 
 	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no response generated")
+		return nil, fmt.Errorf("no response generated")
 	}
 
-	return chatResp.Choices[0].Message.Content, nil
+	return &chatResp.Choices[0].Message, nil
+}
+
+// ChatStreamChunk represents a single server-sent event of a streamed chat completion
+type ChatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// ChatCompletionStream performs a streamed chat completion, calling onDelta
+// with each content fragment as it arrives. Returning an error from onDelta
+// stops the stream.
+func (c *OpenAIClient) ChatCompletionStream(ctx context.Context, systemPrompt, userMessage string, onDelta func(string) error) error {
+	reqBody := ChatRequest{
+		Messages: []ChatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userMessage},
+		},
+		MaxTokens:   1000,
+		Temperature: 0.7,
+		Stream:      true,
+	}
+
+	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		c.endpoint, c.chatDeployment, c.apiVersion)
+
+	payload, err := httppool.JSONBody(reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	defer payload.Release()
+
+	req, err := payload.NewRequest(ctx, "POST", url)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("api-key", c.apiKey)
+
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk ChatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,40 @@
+package azure
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// TokenMeter adds up the token usage of the requests made with a context
+// carrying it. It is safe for concurrent use.
+type TokenMeter struct {
+	prompt     atomic.Int64
+	completion atomic.Int64
+}
+
+type meterKey struct{}
+
+// WithTokenMeter returns a context whose requests add their token usage to
+// the meter
+func WithTokenMeter(ctx context.Context, meter *TokenMeter) context.Context {
+	return context.WithValue(ctx, meterKey{}, meter)
+}
+
+// meterFrom returns the context's meter, or nil when it has none
+func meterFrom(ctx context.Context) *TokenMeter {
+	meter, _ := ctx.Value(meterKey{}).(*TokenMeter)
+	return meter
+}
+
+// Usage returns the tokens counted so far
+func (m *TokenMeter) Usage() Usage {
+	return Usage{PromptTokens: m.prompt.Load(), CompletionTokens: m.completion.Load()}
+}
+
+func (m *TokenMeter) add(u Usage) {
+	if m == nil {
+		return
+	}
+	m.prompt.Add(u.PromptTokens)
+	m.completion.Add(u.CompletionTokens)
+}
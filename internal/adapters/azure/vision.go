@@ -0,0 +1,163 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/httppool"
+	"go.uber.org/zap"
+)
+
+// Image detail levels of vision chat requests
+const (
+	DetailLow  = "low"
+	DetailHigh = "high"
+)
+
+// describeImagePrompt asks for a description that indexes well: diagrams
+// are broken down into their parts so questions about components and flows
+// match the text
+const describeImagePrompt = `Describe this image for a search index, in Markdown.
+If it is a diagram (architecture, flowchart, sequence, entity-relationship, network, UI mockup or similar), use these sections:
+## Type: the kind of diagram and its title, if any.
+## Components: each box, node, actor or entity, with a one-line description of its role.
+## Connections: each arrow or line as "source -> target: label", including direction and any protocol or data named.
+## Flow: the steps the diagram shows, numbered in order, if it shows a process.
+## Text: any other visible labels, notes or legends.
+Otherwise describe what the image shows and transcribe any visible text.
+Do not speculate beyond what is shown.`
+
+// describePromptTokens is an upper bound on the tokens of the text of a
+// description request
+const describePromptTokens = 250
+
+// visionMessage is a chat message whose content mixes text and images
+type visionMessage struct {
+	Role    string        `json:"role"`
+	Content []contentPart `json:"content"`
+}
+
+type contentPart struct {
+	Type     string    `json:"type"` // text or image_url
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type visionChatRequest struct {
+	Messages    []visionMessage `json:"messages"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	Temperature float32         `json:"temperature"`
+}
+
+// DescribeImage sends an image to the vision chat deployment and returns a
+// structured description, at most maxTokens long. detail is DetailLow or
+// DetailHigh.
+func (c *OpenAIClient) DescribeImage(ctx context.Context, data []byte, contentType, detail string, maxTokens int) (string, error) {
+	if c.visionDeployment == "" {
+		return "", fmt.Errorf("no vision deployment configured")
+	}
+
+	c.logger.Debug("Describing image",
+		zap.Int("image_bytes", len(data)),
+		zap.String("detail", detail),
+		zap.Int("max_tokens", maxTokens))
+
+	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		c.endpoint, c.visionDeployment, c.apiVersion)
+
+	reqBody := visionChatRequest{
+		Messages: []visionMessage{{
+			Role: "user",
+			Content: []contentPart{
+				{Type: "text", Text: describeImagePrompt},
+				{Type: "image_url", ImageURL: &imageURL{
+					URL:    "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data),
+					Detail: detail,
+				}},
+			},
+		}},
+		MaxTokens:   maxTokens,
+		Temperature: 0.2,
+	}
+	payload, err := httppool.JSONBody(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	defer payload.Release()
+
+	req, err := payload.NewRequest(ctx, "POST", url)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("api-key", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var chatResp ChatResponse
+	if err := httppool.DecodeJSON(resp.Body, &chatResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	meterFrom(ctx).add(chatResp.Usage)
+
+	if len(chatResp.Choices) == 0 || chatResp.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("no description generated")
+	}
+	return chatResp.Choices[0].Message.Content, nil
+}
+
+// ImageTokens estimates the prompt tokens an image costs at a detail level.
+// Low detail is a flat 85 tokens. High detail scales the image to fit
+// 2048x2048 and then its shorter side down to 768 pixels, and costs 170
+// tokens per 512 pixel tile on top of the 85.
+func ImageTokens(width, height int, detail string) int {
+	const base, perTile = 85, 170
+	if detail != DetailHigh || width <= 0 || height <= 0 {
+		return base
+	}
+
+	w, h := float64(width), float64(height)
+	if longest := max(w, h); longest > 2048 {
+		w, h = w*2048/longest, h*2048/longest
+	}
+	if shortest := min(w, h); shortest > 768 {
+		w, h = w*768/shortest, h*768/shortest
+	}
+	tiles := ((int(w) + 511) / 512) * ((int(h) + 511) / 512)
+	return base + perTile*tiles
+}
+
+// DescribePlan chooses how to describe an image within a token budget:
+// high detail when it fits with the full description length, otherwise low
+// detail, with the description shortened to fit if needed. ok is false
+// when not even a short low detail description fits. Unknown dimensions
+// are treated as low detail only.
+func DescribePlan(width, height, budget, maxTokens int) (detail string, tokens int, ok bool) {
+	const minDescription = 100
+	if width > 0 && height > 0 && ImageTokens(width, height, DetailHigh)+describePromptTokens+maxTokens <= budget {
+		return DetailHigh, maxTokens, true
+	}
+	available := budget - ImageTokens(width, height, DetailLow) - describePromptTokens
+	if available < minDescription {
+		return "", 0, false
+	}
+	return DetailLow, min(maxTokens, available), true
+}
@@ -1,21 +1,45 @@
 package google
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/azure"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/imagemeta"
+	"github.com/nadeeshame/rag-knowledge-service/internal/safeparse"
+	"github.com/nadeeshame/rag-knowledge-service/internal/svgdiagram"
 	"go.uber.org/zap"
 )
 
-// VisionClient handles Google Vision API operations
+// ErrImageTooLarge is returned for images above the configured size limit
+var ErrImageTooLarge = errors.New("image is above the size limit")
+
+// describableExts are the image formats the vision chat deployment accepts
+var describableExts = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// VisionClient handles Google Vision API operations. Images are described
+// by an Azure OpenAI vision chat deployment when one is configured.
 type VisionClient struct {
-	apiKey string
-	logger *zap.Logger
+	apiKey         string
+	maxImageBytes  int64
+	describer      *azure.OpenAIClient
+	describeBudget int
+	describeTokens int
+	logger         *zap.Logger
 }
 
 // NewVisionClient creates a new Google Vision client
@@ -25,10 +49,56 @@ func NewVisionClient(cfg *config.Config, logger *zap.Logger) (*VisionClient, err
 		logger.Warn("Google Vision API key not configured, image analysis will be limited")
 	}
 
-	return &VisionClient{
-		apiKey: cfg.Google.VisionAPIKey,
-		logger: logger,
-	}, nil
+	client := &VisionClient{
+		apiKey:         cfg.Google.VisionAPIKey,
+		maxImageBytes:  cfg.Vision.MaxImageBytes,
+		describeBudget: cfg.Vision.DescribeTokenBudget,
+		describeTokens: cfg.Vision.DescribeMaxTokens,
+		logger:         logger,
+	}
+	if cfg.Azure.OpenAIVisionDeployment != "" {
+		describer, err := azure.NewOpenAIClient(cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure client for image descriptions: %w", err)
+		}
+		client.describer = describer
+		logger.Info("Describing images with vision chat deployment",
+			zap.String("deployment", cfg.Azure.OpenAIVisionDeployment),
+			zap.Int("token_budget", cfg.Vision.DescribeTokenBudget))
+	}
+	return client, nil
+}
+
+// readImage reads an image file, refusing files above the size limit
+// before loading them into memory
+func (c *VisionClient) readImage(imagePath string) ([]byte, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat image: %w", err)
+	}
+	if c.maxImageBytes > 0 && info.Size() > c.maxImageBytes {
+		return nil, fmt.Errorf("%w: %d bytes, limit %d", ErrImageTooLarge, info.Size(), c.maxImageBytes)
+	}
+
+	// Bound the read in case the file grows after the size check
+	reader := io.Reader(file)
+	if c.maxImageBytes > 0 {
+		reader = io.LimitReader(file, c.maxImageBytes+1)
+	}
+	imageData, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if c.maxImageBytes > 0 && int64(len(imageData)) > c.maxImageBytes {
+		return nil, fmt.Errorf("%w: limit %d bytes", ErrImageTooLarge, c.maxImageBytes)
+	}
+	return imageData, nil
 }
 
 // AnalyzeImage analyzes an image and returns a description
@@ -36,40 +106,78 @@ func (c *VisionClient) AnalyzeImage(ctx context.Context, imagePath string) (stri
 	c.logger.Debug("Analyzing image", zap.String("path", imagePath))
 
 	// Read the image file
-	imageData, err := os.ReadFile(imagePath)
+	imageData, err := c.readImage(imagePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read image: %w", err)
+		return "", err
 	}
 
+	return c.AnalyzeImageData(ctx, imagePath, imageData)
+}
+
+// AnalyzeImageData analyzes image bytes; name is only used to detect the file type
+func (c *VisionClient) AnalyzeImageData(ctx context.Context, name string, imageData []byte) (string, error) {
 	// Get file extension to determine type
-	ext := strings.ToLower(filepath.Ext(imagePath))
+	ext := strings.ToLower(filepath.Ext(name))
 
 	// For SVG files, just return the content as text
 	if ext == ".svg" {
 		return c.parseSVG(imageData), nil
 	}
 
+	if contentType, ok := describableExts[ext]; ok && c.describer != nil {
+		return c.describeImage(ctx, name, contentType, imageData)
+	}
+
 	// If no API key, return basic info
 	if c.apiKey == "" {
-		return c.getBasicImageInfo(imagePath, imageData), nil
+		return c.getBasicImageInfo(name, imageData), nil
 	}
 
 	// Use Vision API (simplified - just return basic analysis for now)
-	return c.getBasicImageInfo(imagePath, imageData), nil
+	return c.getBasicImageInfo(name, imageData), nil
+}
+
+// describeImage has the vision chat deployment describe an image, at the
+// highest detail the token budget allows. Images too large for the budget
+// get basic file information instead.
+func (c *VisionClient) describeImage(ctx context.Context, name, contentType string, imageData []byte) (string, error) {
+	info, err := imagemeta.Read(bytes.NewReader(imageData), filepath.Ext(name))
+	if err != nil {
+		c.logger.Debug("Failed to read image dimensions", zap.String("path", name), zap.Error(err))
+	}
+	detail, maxTokens, ok := azure.DescribePlan(info.Width, info.Height, c.describeBudget, c.describeTokens)
+	if !ok {
+		c.logger.Warn("Image description does not fit the token budget",
+			zap.String("path", name),
+			zap.Int("token_budget", c.describeBudget))
+		return c.getBasicImageInfo(name, imageData), nil
+	}
+
+	description, err := c.describer.DescribeImage(ctx, imageData, contentType, detail, maxTokens)
+	if err != nil {
+		return "", fmt.Errorf("failed to describe image: %w", err)
+	}
+	return fmt.Sprintf("Image: %s\n\n%s", filepath.Base(name), description), nil
 }
 
-// DetectText extracts text from an image using OCR
-func (c *VisionClient) DetectText(ctx context.Context, imagePath string) (string, error) {
+// DetectText extracts text from an image using OCR; languageHints are BCP
+// 47 tags of the languages the text is expected in
+func (c *VisionClient) DetectText(ctx context.Context, imagePath string, languageHints ...string) (string, error) {
 	c.logger.Debug("Detecting text in image", zap.String("path", imagePath))
 
 	// Read the image file
-	imageData, err := os.ReadFile(imagePath)
+	imageData, err := c.readImage(imagePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read image: %w", err)
+		return "", err
 	}
 
+	return c.DetectTextData(ctx, imagePath, imageData, languageHints...)
+}
+
+// DetectTextData extracts text from image bytes; name is only used to detect the file type
+func (c *VisionClient) DetectTextData(ctx context.Context, name string, imageData []byte, languageHints ...string) (string, error) {
 	// For SVG files, extract text content
-	ext := strings.ToLower(filepath.Ext(imagePath))
+	ext := strings.ToLower(filepath.Ext(name))
 	if ext == ".svg" {
 		return c.extractSVGText(imageData), nil
 	}
@@ -80,7 +188,8 @@ func (c *VisionClient) DetectText(ctx context.Context, imagePath string) (string
 		return "", nil
 	}
 
-	// Return empty for now (Vision API would be called here)
+	// Return empty for now (Vision API would be called here, with the
+	// language hints in its image context)
 	return "", nil
 }
 
@@ -109,65 +218,31 @@ func (c *VisionClient) AnalyzeDiagram(ctx context.Context, imagePath string) (st
 
 // Helper functions
 
+// parseSVG describes an SVG drawing: its title, components, the
+// connections between them and its text
 func (c *VisionClient) parseSVG(data []byte) string {
-	content := string(data)
-
-	// Extract text elements from SVG
-	var texts []string
-	// Simple text extraction - look for text between tags
-	parts := strings.Split(content, ">")
-	for _, part := range parts {
-		if idx := strings.Index(part, "<"); idx > 0 {
-			text := strings.TrimSpace(part[:idx])
-			if text != "" && !strings.HasPrefix(text, "<?") && !strings.HasPrefix(text, "<!") {
-				texts = append(texts, text)
-			}
-		}
-	}
-
-	if len(texts) > 0 {
-		return "SVG Diagram Content:\n" + strings.Join(texts, "\n")
+	diagram := c.readSVG(data)
+	if diagram.Empty() {
+		return "SVG Diagram (no text content extracted)"
 	}
-	return "SVG Diagram (no text content extracted)"
+	return diagram.Describe()
 }
 
+// extractSVGText returns the text of an SVG drawing
 func (c *VisionClient) extractSVGText(data []byte) string {
-	// Simple text extraction from SVG
-	content := string(data)
-	var texts []string
-
-	// Look for text between <text> tags
-	for {
-		startIdx := strings.Index(content, "<text")
-		if startIdx == -1 {
-			break
-		}
-		endTagStart := strings.Index(content[startIdx:], ">")
-		if endTagStart == -1 {
-			break
-		}
-		endIdx := strings.Index(content[startIdx:], "</text>")
-		if endIdx == -1 {
-			break
-		}
-
-		textContent := content[startIdx+endTagStart+1 : startIdx+endIdx]
-		// Remove nested tags
-		textContent = strings.ReplaceAll(textContent, "<tspan", "")
-		textContent = strings.ReplaceAll(textContent, "</tspan>", "")
-		for strings.Contains(textContent, ">") {
-			start := strings.Index(textContent, ">")
-			textContent = textContent[start+1:]
-		}
-		textContent = strings.TrimSpace(textContent)
-		if textContent != "" {
-			texts = append(texts, textContent)
-		}
+	return c.readSVG(data).AllText()
+}
 
-		content = content[startIdx+endIdx+7:]
+// readSVG parses an SVG drawing, keeping what was read of a malformed one
+func (c *VisionClient) readSVG(data []byte) *svgdiagram.Diagram {
+	diagram, err := svgdiagram.Parse(bytes.NewReader(data))
+	switch {
+	case errors.Is(err, safeparse.ErrLimit):
+		c.logger.Warn("SVG exceeds a parsing limit, read partially", zap.Error(err))
+	case err != nil:
+		c.logger.Debug("SVG parsed partially", zap.Error(err))
 	}
-
-	return strings.Join(texts, " ")
+	return diagram
 }
 
 func (c *VisionClient) getBasicImageInfo(path string, data []byte) string {
@@ -0,0 +1,171 @@
+// Package httppool builds the HTTP clients of the providers and cuts the
+// allocations of their requests: JSON request bodies are encoded into
+// pooled buffers, responses are read into pooled buffers before decoding,
+// and connections are kept for reuse. Each provider's client has its own
+// timeout, proxy and CA bundle.
+package httppool
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+)
+
+// maxPooledBytes is the largest buffer returned to a pool; larger ones,
+// such as those of big upsert batches, are left to the garbage collector
+// rather than held
+const maxPooledBytes = 4 << 20
+
+// Client returns the HTTP client of a provider, with a transport tuned by
+// the configuration and the provider's timeout
+func Client(c config.ProviderHTTPConfig, adapter config.AdapterHTTPConfig) (*http.Client, error) {
+	transport, err := Transport(c, adapter)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport, Timeout: adapter.Timeout}, nil
+}
+
+// Transport returns an HTTP transport tuned by the configuration, using
+// the provider's proxy and trusting its CA bundle
+func Transport(c config.ProviderHTTPConfig, adapter config.AdapterHTTPConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: c.DialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.MaxIdleConns = max(transport.MaxIdleConns, c.MaxIdleConnsPerHost)
+	transport.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = c.IdleConnTimeout
+	transport.TLSHandshakeTimeout = c.TLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = c.ResponseHeaderTimeout
+
+	if adapter.Proxy != "" {
+		proxy, err := url.Parse(adapter.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	tlsConfig, err := TLSConfig(adapter)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// TLSConfig returns the TLS configuration trusting the provider's CA bundle
+// besides the system roots, or nil to use the defaults when it has none
+func TLSConfig(adapter config.AdapterHTTPConfig) (*tls.Config, error) {
+	if adapter.CAFile == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(adapter.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in CA bundle %s", adapter.CAFile)
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: roots}, nil
+}
+
+// Body is a JSON request body in a pooled buffer. The transport may still
+// read a body after the request returns, or read it again to retry on a
+// fresh connection, so the buffer only goes back to the pool once the
+// caller released it and every reader of it was closed.
+type Body struct {
+	buf  bytes.Buffer
+	enc  *json.Encoder
+	refs atomic.Int32
+}
+
+var bodies = sync.Pool{New: func() interface{} {
+	b := &Body{}
+	b.enc = json.NewEncoder(&b.buf)
+	return b
+}}
+
+// JSONBody encodes v into a pooled body. The caller releases it once the
+// request was sent.
+func JSONBody(v interface{}) (*Body, error) {
+	b := bodies.Get().(*Body)
+	b.buf.Reset()
+	b.refs.Store(1)
+	if err := b.enc.Encode(v); err != nil {
+		b.Release()
+		return nil, err
+	}
+	return b, nil
+}
+
+// NewRequest creates a request sending the body. The transport can read
+// the body again to retry the request, as it can bodies in byte buffers.
+func (b *Body) NewRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(b.buf.Len())
+	req.Body = b.reader()
+	req.GetBody = func() (io.ReadCloser, error) { return b.reader(), nil }
+	return req, nil
+}
+
+// reader returns a reader of the body holding it until closed
+func (b *Body) reader() io.ReadCloser {
+	b.refs.Add(1)
+	return &bodyReader{Reader: bytes.NewReader(b.buf.Bytes()), body: b}
+}
+
+// Release gives up the caller's hold on the body
+func (b *Body) Release() {
+	if b.refs.Add(-1) == 0 && b.buf.Cap() <= maxPooledBytes {
+		bodies.Put(b)
+	}
+}
+
+type bodyReader struct {
+	*bytes.Reader
+	body *Body
+	once sync.Once
+}
+
+func (r *bodyReader) Close() error {
+	r.once.Do(r.body.Release)
+	return nil
+}
+
+var buffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// DecodeJSON reads a JSON response into a pooled buffer and decodes it
+// into v. Decoded strings and slices are copies, so nothing in v refers to
+// the buffer once it is reused.
+func DecodeJSON(r io.Reader, v interface{}) error {
+	buf := buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBytes {
+			buffers.Put(buf)
+		}
+	}()
+	if _, err := buf.ReadFrom(r); err != nil {
+		return err
+	}
+	return json.Unmarshal(buf.Bytes(), v)
+}
@@ -0,0 +1,180 @@
+package pinecone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/httppool"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/ratelimit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// grpcUpsertMethod is the upsert RPC of Pinecone's gRPC data plane, served
+// on port 443 of the index host
+const grpcUpsertMethod = "/VectorService/Upsert"
+
+// Field numbers of the data plane messages, from Pinecone's
+// vector_service.proto. Vector numbers sparse_values 4, after metadata;
+// 5 is its number in ScoredVector, a message upserts never send.
+const (
+	fieldUpsertVectors   protowire.Number = 1 // UpsertRequest.vectors
+	fieldUpsertNamespace protowire.Number = 2 // UpsertRequest.namespace
+	fieldVectorID        protowire.Number = 1 // Vector.id
+	fieldVectorValues    protowire.Number = 2 // Vector.values, packed floats
+	fieldVectorMetadata  protowire.Number = 3 // Vector.metadata, a google.protobuf.Struct
+	fieldVectorSparse    protowire.Number = 4 // Vector.sparse_values
+	fieldSparseIndices   protowire.Number = 1 // SparseValues.indices, packed uint32s
+	fieldSparseValues    protowire.Number = 2 // SparseValues.values, packed floats
+)
+
+// rawMessage is a protobuf message encoded by hand. Only upserts go over
+// gRPC, so the few messages involved are written with protowire rather
+// than generated from Pinecone's protos.
+type rawMessage []byte
+
+// rawCodec passes raw messages through unchanged
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(*rawMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return *msg, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(*rawMessage)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*msg = append((*msg)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+// dialGRPC opens the gRPC connection to an index host. Every upsert
+// batch in flight is a stream multiplexed over this one HTTP/2
+// connection, so concurrent batches share a single TLS session.
+func dialGRPC(host string, adapter config.AdapterHTTPConfig, limiter *ratelimit.Limiter) (*grpc.ClientConn, error) {
+	target := strings.TrimPrefix(host, "https://")
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(target, "443")
+	}
+
+	tlsConfig, err := httppool.TLSConfig(adapter)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.NewClient(target,
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+		grpc.WithUnaryInterceptor(ratelimit.UnaryInterceptor(limiter)),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(rawCodec{})))
+}
+
+// upsertBatchGRPC upserts one batch over the gRPC data plane
+func (c *PineconeClient) upsertBatchGRPC(ctx context.Context, namespace string, vectors []*Vector) error {
+	req, err := encodeUpsertRequest(namespace, vectors)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	if c.grpcTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.grpcTimeout)
+		defer cancel()
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "api-key", c.apiKey)
+
+	var resp rawMessage
+	if err := c.grpcConn.Invoke(ctx, grpcUpsertMethod, &req, &resp); err != nil {
+		st := status.Convert(err)
+		switch st.Code() {
+		case codes.ResourceExhausted:
+			return &throttledError{status: 429, body: st.Message()}
+		case codes.Unavailable:
+			return &throttledError{status: 503, body: st.Message()}
+		}
+		return fmt.Errorf("API error (%s): %s", st.Code(), st.Message())
+	}
+	return nil
+}
+
+// encodeUpsertRequest encodes an UpsertRequest message
+func encodeUpsertRequest(namespace string, vectors []*Vector) (rawMessage, error) {
+	var b []byte
+	var vec []byte
+	for _, v := range vectors {
+		vec = protowire.AppendTag(vec[:0], fieldVectorID, protowire.BytesType)
+		vec = protowire.AppendString(vec, v.ID)
+		if len(v.Values) > 0 {
+			vec = protowire.AppendTag(vec, fieldVectorValues, protowire.BytesType)
+			vec = protowire.AppendVarint(vec, uint64(4*len(v.Values)))
+			for _, value := range v.Values {
+				vec = protowire.AppendFixed32(vec, math.Float32bits(value))
+			}
+		}
+		if v.SparseValues != nil {
+			vec = protowire.AppendTag(vec, fieldVectorSparse, protowire.BytesType)
+			vec = protowire.AppendBytes(vec, encodeSparseValues(v.SparseValues))
+		}
+		if len(v.Metadata) > 0 {
+			meta, err := encodeMetadata(v.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("invalid metadata of vector %s: %w", v.ID, err)
+			}
+			vec = protowire.AppendTag(vec, fieldVectorMetadata, protowire.BytesType)
+			vec = protowire.AppendBytes(vec, meta)
+		}
+		b = protowire.AppendTag(b, fieldUpsertVectors, protowire.BytesType)
+		b = protowire.AppendBytes(b, vec)
+	}
+	if namespace != "" {
+		b = protowire.AppendTag(b, fieldUpsertNamespace, protowire.BytesType)
+		b = protowire.AppendString(b, namespace)
+	}
+	return b, nil
+}
+
+// encodeSparseValues encodes a SparseValues message
+func encodeSparseValues(sv *SparseValues) []byte {
+	var indices []byte
+	for _, index := range sv.Indices {
+		indices = protowire.AppendVarint(indices, uint64(index))
+	}
+	b := protowire.AppendTag(nil, fieldSparseIndices, protowire.BytesType)
+	b = protowire.AppendBytes(b, indices)
+	b = protowire.AppendTag(b, fieldSparseValues, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(4*len(sv.Values)))
+	for _, value := range sv.Values {
+		b = protowire.AppendFixed32(b, math.Float32bits(value))
+	}
+	return b
+}
+
+// encodeMetadata encodes metadata as a google.protobuf.Struct. It goes
+// through JSON so that values convert exactly as they do over HTTP.
+func encodeMetadata(m map[string]interface{}) ([]byte, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var s structpb.Struct
+	if err := s.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&s)
+}
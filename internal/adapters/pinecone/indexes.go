@@ -0,0 +1,257 @@
+package pinecone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/httppool"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/ratelimit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// controlPlaneURL is the base URL of Pinecone's control plane, which
+// manages indexes as opposed to the vectors in them
+const controlPlaneURL = "https://api.pinecone.io"
+
+var (
+	// ErrIndexNotFound is returned when no index has the given name
+	ErrIndexNotFound = errors.New("index not found")
+	// ErrIndexExists is returned when creating an index whose name is taken
+	ErrIndexExists = errors.New("index already exists")
+)
+
+// Index describes a Pinecone index as the control plane reports it
+type Index struct {
+	Name               string      `json:"name"`
+	Dimension          int         `json:"dimension"`
+	Metric             string      `json:"metric"`
+	Host               string      `json:"host"`
+	DeletionProtection string      `json:"deletion_protection,omitempty"` // enabled or disabled
+	Spec               IndexSpec   `json:"spec"`
+	Status             IndexStatus `json:"status"`
+}
+
+// IndexSpec is where an index runs
+type IndexSpec struct {
+	Serverless *ServerlessSpec `json:"serverless,omitempty"`
+	Pod        interface{}     `json:"pod,omitempty"` // pod-based indexes are described but not created
+}
+
+// ServerlessSpec places a serverless index in a cloud region
+type ServerlessSpec struct {
+	Cloud  string `json:"cloud"`
+	Region string `json:"region"`
+}
+
+// IndexStatus reports whether an index is ready for requests
+type IndexStatus struct {
+	Ready bool   `json:"ready"`
+	State string `json:"state"` // such as Initializing, Ready or Terminating
+}
+
+// CreateIndexRequest describes a serverless index to create
+type CreateIndexRequest struct {
+	Name               string `json:"name"`
+	Dimension          int    `json:"dimension"`
+	Metric             string `json:"metric"`
+	Cloud              string `json:"cloud"`
+	Region             string `json:"region"`
+	DeletionProtection bool   `json:"deletion_protection"`
+}
+
+// Validate checks that a request names an index, its dimension, metric,
+// cloud and region
+func (r CreateIndexRequest) Validate() error {
+	if r.Name == "" || r.Dimension <= 0 || r.Cloud == "" || r.Region == "" {
+		return fmt.Errorf("an index requires a name, a positive dimension, a cloud and a region")
+	}
+	switch r.Metric {
+	case "cosine", "euclidean", "dotproduct":
+		return nil
+	}
+	return fmt.Errorf("metric must be cosine, euclidean or dotproduct, got %q", r.Metric)
+}
+
+// IndexAdmin creates, describes and deletes indexes through the control
+// plane. Unlike PineconeClient it needs no existing index, so it serves
+// the first-time setup of one.
+type IndexAdmin struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+	config     *config.PineconeConfig
+	logger     *zap.Logger
+}
+
+// NewIndexAdmin creates a control plane client
+func NewIndexAdmin(cfg *config.Config, logger *zap.Logger) (*IndexAdmin, error) {
+	if cfg.Pinecone.APIKey == "" {
+		return nil, fmt.Errorf("pinecone API key is required")
+	}
+
+	limiter := ratelimit.Shared("pinecone", cfg.Limits.PineconeMaxConcurrent, cfg.Limits.PineconeRPM)
+	httpClient, err := httppool.Client(cfg.ProviderHTTP, cfg.ProviderHTTP.Pinecone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	httpClient.Transport = ratelimit.Transport(limiter, httpClient.Transport)
+
+	return &IndexAdmin{
+		apiKey:     cfg.Pinecone.APIKey,
+		baseURL:    controlPlaneURL,
+		httpClient: httpClient,
+		config:     &cfg.Pinecone,
+		logger:     logger,
+	}, nil
+}
+
+// Defaults returns a request for the configured index, dimension, metric,
+// cloud and region
+func (a *IndexAdmin) Defaults() CreateIndexRequest {
+	return CreateIndexRequest{
+		Name:      a.config.IndexName,
+		Dimension: a.config.Dimension,
+		Metric:    a.config.Metric,
+		Cloud:     a.config.Cloud,
+		Region:    a.config.Region,
+	}
+}
+
+// CreateServerless creates a serverless index. The index is returned
+// while it initializes; WaitReady waits until it takes requests.
+func (a *IndexAdmin) CreateServerless(ctx context.Context, req CreateIndexRequest) (*Index, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	protection := "disabled"
+	if req.DeletionProtection {
+		protection = "enabled"
+	}
+	body := map[string]interface{}{
+		"name":                req.Name,
+		"dimension":           req.Dimension,
+		"metric":              req.Metric,
+		"deletion_protection": protection,
+		"spec": IndexSpec{Serverless: &ServerlessSpec{
+			Cloud:  req.Cloud,
+			Region: req.Region,
+		}},
+	}
+
+	var index Index
+	if err := a.do(ctx, http.MethodPost, "/indexes", body, &index); err != nil {
+		return nil, err
+	}
+	a.logger.Info("Created Pinecone index",
+		zap.String("index", index.Name),
+		zap.String("cloud", req.Cloud),
+		zap.String("region", req.Region),
+		zap.Int("dimension", index.Dimension))
+	return &index, nil
+}
+
+// Describe returns an index
+func (a *IndexAdmin) Describe(ctx context.Context, name string) (*Index, error) {
+	var index Index
+	if err := a.do(ctx, http.MethodGet, "/indexes/"+url.PathEscape(name), nil, &index); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+// Owns reports whether the admin API may delete an index: the configured
+// one, or one listed in deletable_indexes
+func (a *IndexAdmin) Owns(name string) bool {
+	return name == a.config.IndexName || slices.Contains(a.config.DeletableIndexes, name)
+}
+
+// Delete deletes an index and every vector in it. Pinecone refuses while
+// the index has deletion protection enabled.
+func (a *IndexAdmin) Delete(ctx context.Context, name string) error {
+	if err := a.do(ctx, http.MethodDelete, "/indexes/"+url.PathEscape(name), nil, nil); err != nil {
+		return err
+	}
+	a.logger.Info("Deleted Pinecone index", zap.String("index", name))
+	return nil
+}
+
+// WaitReady polls an index until it is ready for requests
+func (a *IndexAdmin) WaitReady(ctx context.Context, name string, interval time.Duration) (*Index, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		index, err := a.Describe(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if index.Status.Ready {
+			return index, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return index, fmt.Errorf("index %s is still %s: %w", name, index.Status.State, ctx.Err())
+		}
+	}
+}
+
+// do sends a control plane request, decoding the response into out when
+// it is not nil
+func (a *IndexAdmin) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var req *http.Request
+	var err error
+	if in != nil {
+		payload, err := httppool.JSONBody(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		defer payload.Release()
+		req, err = payload.NewRequest(ctx, method, a.baseURL+path)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req, err = http.NewRequestWithContext(ctx, method, a.baseURL+path, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+	}
+	req.Header.Set("Api-Key", a.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("API error (status %d): failed to read response body: %w", resp.StatusCode, err)
+		}
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %s", ErrIndexNotFound, string(body))
+		case http.StatusConflict:
+			return fmt.Errorf("%w: %s", ErrIndexExists, string(body))
+		}
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := httppool.DecodeJSON(resp.Body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
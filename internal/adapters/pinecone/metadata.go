@@ -0,0 +1,78 @@
+package pinecone
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// fitFields are the metadata text fields shortened, in order, to bring
+// metadata within the size limit
+var fitFields = []string{"content", "summary"}
+
+// MetadataSize returns the size of vector metadata as sent to Pinecone
+func MetadataSize(metadata map[string]interface{}) int {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(metadata); err != nil {
+		return math.MaxInt
+	}
+	return buf.Len() - 1 // Encode appends a newline
+}
+
+// FitMetadata shortens the content and then the summary of vector metadata
+// until it fits in limit bytes. Text is cut on UTF-8 boundaries. It returns
+// an error if the metadata is too large even without them.
+func FitMetadata(metadata map[string]interface{}, limit int) error {
+	for _, key := range fitFields {
+		text, ok := metadata[key].(string)
+		if !ok || MetadataSize(metadata) <= limit {
+			continue
+		}
+		// Escaping makes encoded text longer than the text itself, so
+		// search for the longest prefix that fits
+		lo, hi := 0, len(text)
+		for lo < hi {
+			mid := (lo + hi + 1) / 2
+			metadata[key] = truncateUTF8(text, mid)
+			if MetadataSize(metadata) <= limit {
+				lo = mid
+			} else {
+				hi = mid - 1
+			}
+		}
+		metadata[key] = truncateUTF8(text, lo)
+	}
+
+	if size := MetadataSize(metadata); size > limit {
+		return fmt.Errorf("metadata is %d bytes after trimming, above the %d byte limit", size, limit)
+	}
+	return nil
+}
+
+// ValidateMetadata checks every vector's metadata against the size limit
+func ValidateMetadata(vectors []*Vector, limit int) error {
+	for _, v := range vectors {
+		if size := MetadataSize(v.Metadata); size > limit {
+			return fmt.Errorf("vector %s metadata is %d bytes, above the %d byte limit", v.ID, size, limit)
+		}
+	}
+	return nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -3,14 +3,19 @@ package pinecone
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/httppool"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/ratelimit"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // PineconeClient handles Pinecone vector database operations
@@ -19,14 +24,30 @@ type PineconeClient struct {
 	host       string
 	httpClient *http.Client
 	config     *config.PineconeConfig
+	summaries  bool   // documents also have summary vectors
+	chunks     string // chunk namespace set by SetNamespace
+	limiter    *adaptiveLimiter
+	upserts    upsertCounters
 	logger     *zap.Logger
+
+	// Upserts go over gRPC when the transport is grpc
+	grpcConn    *grpc.ClientConn
+	grpcTimeout time.Duration
 }
 
 // Vector represents a vector with metadata
 type Vector struct {
-	ID       string                 `json:"id"`
-	Values   []float32              `json:"values"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	ID           string                 `json:"id"`
+	Values       []float32              `json:"values"`
+	SparseValues *SparseValues          `json:"sparseValues,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// SparseValues is the sparse part of a sparse-dense vector: the weights of
+// the few dimensions, such as hashed terms, that are not zero
+type SparseValues struct {
+	Indices []uint32  `json:"indices"`
+	Values  []float32 `json:"values"`
 }
 
 // Match represents a search result
@@ -54,6 +75,7 @@ type QueryRequest struct {
 	IncludeMetadata bool                   `json:"includeMetadata"`
 	Filter          map[string]interface{} `json:"filter,omitempty"`
 	Namespace       string                 `json:"namespace,omitempty"`
+	SparseVector    *SparseValues          `json:"sparseVector,omitempty"`
 }
 
 // QueryResponse represents the query response
@@ -77,13 +99,20 @@ func NewPineconeClient(cfg *config.Config, logger *zap.Logger) (*PineconeClient,
 		return nil, fmt.Errorf("pinecone index name is required")
 	}
 
-	httpClient := &http.Client{}
+	// Every Pinecone client in the process shares the configured limits
+	limiter := ratelimit.Shared("pinecone", cfg.Limits.PineconeMaxConcurrent, cfg.Limits.PineconeRPM)
+	httpClient, err := httppool.Client(cfg.ProviderHTTP, cfg.ProviderHTTP.Pinecone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	httpClient.Transport = ratelimit.Transport(limiter, httpClient.Transport)
 	var host string
 
 	// Use provided host or fetch from Pinecone API
 	if cfg.Pinecone.Host != "" {
+		// An http:// host, as of a local emulator, is kept as given
 		host = cfg.Pinecone.Host
-		if !strings.HasPrefix(host, "https://") {
+		if !strings.HasPrefix(host, "https://") && !strings.HasPrefix(host, "http://") {
 			host = "https://" + host
 		}
 		logger.Info("Using provided Pinecone host", zap.String("host", host))
@@ -100,18 +129,37 @@ func NewPineconeClient(cfg *config.Config, logger *zap.Logger) (*PineconeClient,
 		zap.String("index", cfg.Pinecone.IndexName),
 		zap.String("host", host))
 
-	return &PineconeClient{
+	client := &PineconeClient{
 		apiKey:     cfg.Pinecone.APIKey,
 		host:       host,
 		httpClient: httpClient,
 		config:     &cfg.Pinecone,
+		summaries:  cfg.App.SummaryVectors,
+		limiter:    newAdaptiveLimiter(cfg.Pinecone.UpsertConcurrency),
 		logger:     logger,
-	}, nil
+	}
+	if cfg.Pinecone.Transport == "grpc" {
+		client.grpcConn, err = dialGRPC(host, cfg.ProviderHTTP.Pinecone, limiter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
+		}
+		client.grpcTimeout = cfg.ProviderHTTP.Pinecone.Timeout
+		logger.Info("Upserting to Pinecone over gRPC")
+	}
+	return client, nil
+}
+
+// Close closes the gRPC connection of upserts, if any
+func (c *PineconeClient) Close() error {
+	if c.grpcConn == nil {
+		return nil
+	}
+	return c.grpcConn.Close()
 }
 
 // fetchIndexHost fetches the actual host URL from Pinecone control plane API
 func fetchIndexHost(ctx context.Context, httpClient *http.Client, apiKey, indexName string, logger *zap.Logger) (string, error) {
-	url := fmt.Sprintf("https://api.pinecone.io/indexes/%s", indexName)
+	url := fmt.Sprintf("%s/indexes/%s", controlPlaneURL, indexName)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -140,7 +188,7 @@ func fetchIndexHost(ctx context.Context, httpClient *http.Client, apiKey, indexN
 	var indexInfo struct {
 		Host string `json:"host"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&indexInfo); err != nil {
+	if err := httppool.DecodeJSON(resp.Body, &indexInfo); err != nil {
 		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -159,49 +207,64 @@ func fetchIndexHost(ctx context.Context, httpClient *http.Client, apiKey, indexN
 
 // UpsertVectors upserts multiple vectors to Pinecone
 func (c *PineconeClient) UpsertVectors(ctx context.Context, vectors []*Vector) error {
+	return c.UpsertVectorsInNamespace(ctx, c.namespace(), vectors)
+}
+
+// UpsertVectorsInNamespace upserts multiple vectors into the given namespace
+func (c *PineconeClient) UpsertVectorsInNamespace(ctx context.Context, namespace string, vectors []*Vector) error {
 	if len(vectors) == 0 {
 		return nil
 	}
 
+	// Reject oversized metadata up front rather than failing mid-way
+	if c.config.MetadataLimit > 0 {
+		if err := ValidateMetadata(vectors, c.config.MetadataLimit); err != nil {
+			return err
+		}
+	}
+
 	c.logger.Debug("Upserting vectors", zap.Int("count", len(vectors)))
 
-	// Upsert in batches of 100
-	batchSize := 100
+	batchSize := c.config.UpsertBatchSize
+	batches := make([][]*Vector, 0, (len(vectors)+batchSize-1)/batchSize)
 	for i := 0; i < len(vectors); i += batchSize {
 		end := i + batchSize
 		if end > len(vectors) {
 			end = len(vectors)
 		}
+		batches = append(batches, vectors[i:end])
+	}
 
-		batch := vectors[i:end]
-		if err := c.upsertBatch(ctx, batch); err != nil {
-			return fmt.Errorf("failed to upsert batch: %w", err)
-		}
-
-		c.logger.Debug("Upserted batch",
-			zap.Int("from", i),
-			zap.Int("to", end))
+	start := time.Now()
+	err := c.upsertBatches(ctx, namespace, batches)
+	elapsed := time.Since(start)
+	c.upserts.busyNanos.Add(int64(elapsed))
+	if err != nil {
+		return err
 	}
 
 	c.logger.Info("Successfully upserted vectors",
-		zap.Int("total", len(vectors)))
+		zap.Int("total", len(vectors)),
+		zap.Int("batches", len(batches)),
+		zap.Float64("vectors_per_second", float64(len(vectors))/elapsed.Seconds()))
 
 	return nil
 }
 
-func (c *PineconeClient) upsertBatch(ctx context.Context, vectors []*Vector) error {
-	reqBody := UpsertRequest{Vectors: vectors}
-	if c.config.UseNamespaces {
-		reqBody.Namespace = "default"
+func (c *PineconeClient) upsertBatch(ctx context.Context, namespace string, vectors []*Vector) error {
+	if c.grpcConn != nil {
+		return c.upsertBatchGRPC(ctx, namespace, vectors)
 	}
+	reqBody := UpsertRequest{Vectors: vectors, Namespace: namespace}
 
-	jsonBody, err := json.Marshal(reqBody)
+	payload, err := httppool.JSONBody(reqBody)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
+	defer payload.Release()
 
 	url := fmt.Sprintf("%s/vectors/upsert", c.host)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	req, err := payload.NewRequest(ctx, "POST", url)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -220,6 +283,13 @@ func (c *PineconeClient) upsertBatch(ctx context.Context, vectors []*Vector) err
 		if err != nil {
 			return fmt.Errorf("API error (status %d): failed to read response body: %w", resp.StatusCode, err)
 		}
+		if isThrottleStatus(resp.StatusCode) {
+			return &throttledError{
+				status:     resp.StatusCode,
+				retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+				body:       string(body),
+			}
+		}
 		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
@@ -228,27 +298,48 @@ func (c *PineconeClient) upsertBatch(ctx context.Context, vectors []*Vector) err
 
 // QueryVectors searches for similar vectors
 func (c *PineconeClient) QueryVectors(ctx context.Context, embedding []float32, topK int, filter map[string]interface{}) ([]*Match, error) {
-	c.logger.Debug("Querying vectors",
-		zap.Int("topK", topK),
-		zap.Bool("has_filter", filter != nil))
+	return c.QueryVectorsInNamespace(ctx, c.namespace(), embedding, topK, filter)
+}
 
-	reqBody := QueryRequest{
+// QueryVectorsInNamespace searches for similar vectors in the given namespace
+func (c *PineconeClient) QueryVectorsInNamespace(ctx context.Context, namespace string, embedding []float32, topK int, filter map[string]interface{}) ([]*Match, error) {
+	return c.query(ctx, QueryRequest{
 		Vector:          embedding,
 		TopK:            topK,
 		IncludeMetadata: true,
 		Filter:          filter,
-	}
-	if c.config.UseNamespaces {
-		reqBody.Namespace = "default"
-	}
+		Namespace:       namespace,
+	})
+}
+
+// QueryHybrid searches the chunk namespace with a sparse-dense query. A
+// match scores the dot product of the dense parts plus that of the sparse
+// parts, so the caller weights the two by scaling them.
+func (c *PineconeClient) QueryHybrid(ctx context.Context, embedding []float32, sparse *SparseValues, topK int, filter map[string]interface{}) ([]*Match, error) {
+	return c.query(ctx, QueryRequest{
+		Vector:          embedding,
+		SparseVector:    sparse,
+		TopK:            topK,
+		IncludeMetadata: true,
+		Filter:          filter,
+		Namespace:       c.namespace(),
+	})
+}
 
-	jsonBody, err := json.Marshal(reqBody)
+func (c *PineconeClient) query(ctx context.Context, reqBody QueryRequest) ([]*Match, error) {
+	c.logger.Debug("Querying vectors",
+		zap.Int("topK", reqBody.TopK),
+		zap.Bool("has_filter", reqBody.Filter != nil),
+		zap.Bool("hybrid", reqBody.SparseVector != nil))
+
+	payload, err := httppool.JSONBody(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
+	defer payload.Release()
 
 	url := fmt.Sprintf("%s/query", c.host)
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	req, err := payload.NewRequest(ctx, "POST", url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -268,7 +359,7 @@ func (c *PineconeClient) QueryVectors(ctx context.Context, embedding []float32,
 	}
 
 	var queryResp QueryResponse
-	if err := json.NewDecoder(resp.Body).Decode(&queryResp); err != nil {
+	if err := httppool.DecodeJSON(resp.Body, &queryResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -286,16 +377,27 @@ func (c *PineconeClient) QueryVectors(ctx context.Context, embedding []float32,
 	return matches, nil
 }
 
-// CheckDocumentExists checks if a document with given hash exists
-func (c *PineconeClient) CheckDocumentExists(ctx context.Context, fileHash string) (bool, error) {
-	c.logger.Debug("Checking document existence", zap.String("hash", fileHash))
+// CheckDocumentExists checks if a document with given hash exists.
+// otherHashes are hashes of the same file with other algorithms, which
+// match documents indexed by deployments hashing with them.
+func (c *PineconeClient) CheckDocumentExists(ctx context.Context, fileHash string, otherHashes ...string) (bool, error) {
+	c.logger.Debug("Checking document existence", zap.String("hash", fileHash), zap.Strings("other_hashes", otherHashes))
+	hashes := append([]string{fileHash}, otherHashes...)
 
 	// Create a dummy vector for querying
 	dummyVector := make([]float32, c.config.Dimension)
 
+	// Documents whose chunks were all deduplicated have no vectors of their
+	// own, so also match canonical vectors that reference the file hash.
+	// Superseded vectors are left out: the content of a version that was
+	// replaced or rolled back is not what the index currently holds.
 	filter := map[string]interface{}{
-		"file_hash": map[string]interface{}{
-			"$eq": fileHash,
+		"$and": []interface{}{
+			map[string]interface{}{"superseded_at": map[string]interface{}{"$exists": false}},
+			map[string]interface{}{"$or": []interface{}{
+				map[string]interface{}{"file_hash": map[string]interface{}{"$in": hashes}},
+				map[string]interface{}{"referenced_by_hashes": map[string]interface{}{"$in": hashes}},
+			}},
 		},
 	}
 
@@ -336,9 +438,638 @@ func (c *PineconeClient) GetStats(ctx context.Context) (map[string]interface{},
 	}
 
 	var stats map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+	if err := httppool.DecodeJSON(resp.Body, &stats); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	return stats, nil
 }
+
+// FetchResponse represents the fetch response
+type FetchResponse struct {
+	Vectors map[string]*Vector `json:"vectors"`
+}
+
+// UpdateRequest represents the update request body
+type UpdateRequest struct {
+	ID          string                 `json:"id"`
+	SetMetadata map[string]interface{} `json:"setMetadata,omitempty"`
+	Namespace   string                 `json:"namespace,omitempty"`
+}
+
+// FetchVectors fetches vectors by ID
+func (c *PineconeClient) FetchVectors(ctx context.Context, ids []string) (map[string]*Vector, error) {
+	return c.FetchVectorsInNamespace(ctx, c.namespace(), ids)
+}
+
+// FetchVectorsInNamespace fetches vectors by ID from the given namespace
+func (c *PineconeClient) FetchVectorsInNamespace(ctx context.Context, namespace string, ids []string) (map[string]*Vector, error) {
+	if len(ids) == 0 {
+		return map[string]*Vector{}, nil
+	}
+
+	params := url.Values{}
+	for _, id := range ids {
+		params.Add("ids", id)
+	}
+	if namespace != "" {
+		params.Set("namespace", namespace)
+	}
+
+	endpoint := fmt.Sprintf("%s/vectors/fetch?%s", c.host, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Api-Key", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var fetchResp FetchResponse
+	if err := httppool.DecodeJSON(resp.Body, &fetchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return fetchResp.Vectors, nil
+}
+
+// UpdateMetadata sets metadata fields on an existing vector
+func (c *PineconeClient) UpdateMetadata(ctx context.Context, id string, metadata map[string]interface{}) error {
+	return c.updateMetadataInNamespace(ctx, c.namespace(), id, metadata)
+}
+
+// updateMetadataInNamespace sets metadata fields on a vector in the given namespace
+func (c *PineconeClient) updateMetadataInNamespace(ctx context.Context, namespace, id string, metadata map[string]interface{}) error {
+	reqBody := UpdateRequest{
+		ID:          id,
+		SetMetadata: metadata,
+		Namespace:   namespace,
+	}
+
+	payload, err := httppool.JSONBody(reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	defer payload.Release()
+
+	endpoint := fmt.Sprintf("%s/vectors/update", c.host)
+	req, err := payload.NewRequest(ctx, "POST", endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Api-Key", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// FindByContentHash returns a current vector whose chunk content hash
+// matches, with its metadata, or nil when none exists. Vectors of other
+// versions of filePath than documentID are passed over, as they are
+// superseded once that document is stored.
+func (c *PineconeClient) FindByContentHash(ctx context.Context, contentHash, filePath, documentID string) (*Match, error) {
+	dummyVector := make([]float32, c.config.Dimension)
+
+	filter := map[string]interface{}{
+		"$and": []map[string]interface{}{
+			{"content_hash": map[string]interface{}{"$eq": contentHash}},
+			{"superseded_at": map[string]interface{}{"$exists": false}},
+			{"$or": []map[string]interface{}{
+				{"file_path": map[string]interface{}{"$ne": filePath}},
+				{"document_id": map[string]interface{}{"$eq": documentID}},
+			}},
+		},
+	}
+
+	matches, err := c.QueryVectors(ctx, dummyVector, 1, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	return matches[0], nil
+}
+
+// ListResponse represents a page of vector IDs from the list endpoint
+type ListResponse struct {
+	Vectors []struct {
+		ID string `json:"id"`
+	} `json:"vectors"`
+	Pagination *struct {
+		Next string `json:"next"`
+	} `json:"pagination,omitempty"`
+}
+
+// DeleteRequest represents the delete request body
+type DeleteRequest struct {
+	IDs       []string `json:"ids"`
+	Namespace string   `json:"namespace,omitempty"`
+}
+
+// ListVectorIDs lists the IDs of all vectors whose ID starts with prefix
+func (c *PineconeClient) ListVectorIDs(ctx context.Context, prefix string) ([]string, error) {
+	var ids []string
+	next := ""
+
+	for {
+		params := url.Values{}
+		params.Set("prefix", prefix)
+		if ns := c.namespace(); ns != "" {
+			params.Set("namespace", ns)
+		}
+		if next != "" {
+			params.Set("paginationToken", next)
+		}
+
+		endpoint := fmt.Sprintf("%s/vectors/list?%s", c.host, params.Encode())
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Api-Key", c.apiKey)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+
+		var page ListResponse
+		err = decodeResponse(resp, &page)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range page.Vectors {
+			ids = append(ids, v.ID)
+		}
+		if page.Pagination == nil || page.Pagination.Next == "" {
+			return ids, nil
+		}
+		next = page.Pagination.Next
+	}
+}
+
+// DeleteVectors deletes vectors by ID, in batches of 1000
+func (c *PineconeClient) DeleteVectors(ctx context.Context, ids []string) error {
+	return c.DeleteVectorsInNamespace(ctx, c.namespace(), ids)
+}
+
+// DeleteVectorsInNamespace deletes vectors by ID from the given namespace
+func (c *PineconeClient) DeleteVectorsInNamespace(ctx context.Context, namespace string, ids []string) error {
+	const batchSize = 1000
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		payload, err := httppool.JSONBody(DeleteRequest{IDs: ids[start:end], Namespace: namespace})
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		endpoint := fmt.Sprintf("%s/vectors/delete", c.host)
+		req, err := payload.NewRequest(ctx, "POST", endpoint)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Api-Key", c.apiKey)
+
+		resp, err := c.httpClient.Do(req)
+		payload.Release()
+		if err != nil {
+			return fmt.Errorf("failed to send request: %w", err)
+		}
+		if err := decodeResponse(resp, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteByDocumentID deletes all vectors of a document, including its
+// summary vector. Vector IDs are prefixed with the document ID, which works
+// on serverless indexes where delete-by-metadata is unavailable. Chunk
+// vectors that other files reference through deduplication are kept,
+// stored again for one of those files. Returns the number of chunk vectors
+// deleted.
+func (c *PineconeClient) DeleteByDocumentID(ctx context.Context, documentID string) (int, error) {
+	ids, err := c.ListVectorIDs(ctx, documentID+"-")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list document vectors: %w", err)
+	}
+	ids, handed, err := c.transferReferenced(ctx, documentID, ids)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.DeleteVectors(ctx, ids); err != nil {
+		return 0, fmt.Errorf("failed to delete document vectors: %w", err)
+	}
+	deleted := len(ids) - handed
+	if ns := c.SummaryNamespace(); ns != "" {
+		if err := c.DeleteVectorsInNamespace(ctx, ns, []string{models.SummaryVectorID(documentID)}); err != nil {
+			return deleted, fmt.Errorf("failed to delete summary vector: %w", err)
+		}
+	}
+	return deleted, nil
+}
+
+// fetchBatchSize is the most vectors fetched per request, keeping the
+// query string of IDs within URL limits
+const fetchBatchSize = 100
+
+// transferReferenced hands the vectors among ids that other files
+// reference to the first of those files, so deleting the document storing
+// them leaves the content of the others. A handed-over vector is stored
+// again under an ID of the new owner's document, with that document's
+// metadata, so deleting it in turn hands the vector on or deletes it. It
+// returns the IDs left to delete, which include those of the handed-over
+// vectors, and how many vectors were handed over.
+func (c *PineconeClient) transferReferenced(ctx context.Context, documentID string, ids []string) ([]string, int, error) {
+	remaining := make([]string, 0, len(ids))
+	var handed []*Vector
+	for start := 0; start < len(ids); start += fetchBatchSize {
+		batch := ids[start:min(start+fetchBatchSize, len(ids))]
+		vectors, err := c.FetchVectors(ctx, batch)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to fetch document vectors: %w", err)
+		}
+		for _, id := range batch {
+			vector, ok := vectors[id]
+			if !ok {
+				remaining = append(remaining, id)
+				continue
+			}
+			refs := vectorReferences(vector.Metadata)
+			if len(refs) == 0 {
+				remaining = append(remaining, id)
+				continue
+			}
+			owner, rest, err := c.resolveOwner(ctx, refs)
+			if err != nil {
+				return nil, 0, err
+			}
+			if owner == nil {
+				// A file whose document cannot be found, as one whose
+				// chunks all reuse other vectors and that was referenced
+				// before document IDs were recorded, keeps the vector in
+				// place
+				if err := c.UpdateMetadata(ctx, id, map[string]interface{}{
+					"file_path":     refs[0].path,
+					"file_hash":     "",
+					"referenced_by": metadataPaths(refs[1:]),
+				}); err != nil {
+					return nil, 0, fmt.Errorf("failed to hand over referenced vector %s: %w", id, err)
+				}
+				c.logger.Warn("Kept referenced vector under the deleted document's ID",
+					zap.String("vector_id", id),
+					zap.String("file_path", refs[0].path))
+				continue
+			}
+			remaining = append(remaining, id)
+
+			metadata := make(map[string]interface{}, len(vector.Metadata))
+			for key, value := range vector.Metadata {
+				metadata[key] = value
+			}
+			metadata["document_id"] = owner.documentID
+			metadata["file_path"] = owner.path
+			metadata["file_hash"] = owner.hash
+			setReferences(metadata, rest)
+			handed = append(handed, &Vector{
+				ID:           handedOverID(id, documentID, owner.documentID),
+				Values:       vector.Values,
+				SparseValues: vector.SparseValues,
+				Metadata:     metadata,
+			})
+			c.logger.Debug("Handing vector over to another file",
+				zap.String("vector_id", id),
+				zap.String("file_path", owner.path),
+				zap.String("document_id", owner.documentID))
+		}
+	}
+	if err := c.UpsertVectors(ctx, handed); err != nil {
+		return nil, 0, fmt.Errorf("failed to hand over referenced vectors: %w", err)
+	}
+	return remaining, len(handed), nil
+}
+
+// sharedIDInfix marks the IDs of vectors handed over from another document
+const sharedIDInfix = "shared-"
+
+// handedOverID returns the ID a vector of documentID takes when handed to
+// the owner document. It starts with the owner's ID, which deleting that
+// document lists vectors by, and keeps the vector's first ID, so vectors
+// handed over from several documents do not collide.
+func handedOverID(id, documentID, owner string) string {
+	suffix := strings.TrimPrefix(id, documentID+"-")
+	if !strings.HasPrefix(suffix, sharedIDInfix) {
+		suffix = sharedIDInfix + id
+	}
+	return owner + "-" + suffix
+}
+
+// reference is a file reusing a vector stored by another document through
+// chunk deduplication
+type reference struct {
+	path       string
+	hash       string
+	documentID string
+}
+
+// vectorReferences returns the files referencing a vector other than its
+// own. The path, hash and document ID lists are kept in step; vectors
+// referenced before document IDs were recorded may have paths alone, and
+// their hashes and documents are looked up when needed.
+func vectorReferences(metadata map[string]interface{}) []reference {
+	filePath, _ := metadata["file_path"].(string)
+	paths := metadataStrings(metadata["referenced_by"])
+	hashes := metadataStrings(metadata["referenced_by_hashes"])
+	documents := metadataStrings(metadata["referenced_by_documents"])
+
+	refs := make([]reference, 0, len(paths))
+	for i, path := range paths {
+		if path == filePath {
+			continue
+		}
+		ref := reference{path: path}
+		if len(hashes) == len(paths) {
+			ref.hash = hashes[i]
+		}
+		if len(documents) == len(paths) {
+			ref.documentID = documents[i]
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// AddReference returns the metadata fields recording that a document of
+// a file reuses a vector, to update the vector with. A file already
+// referencing it is recorded with its new hash and document instead.
+func AddReference(metadata map[string]interface{}, filePath, fileHash, documentID string) map[string]interface{} {
+	refs := vectorReferences(metadata)
+	ref := reference{path: filePath, hash: fileHash, documentID: documentID}
+	found := false
+	for i := range refs {
+		if refs[i].path == filePath {
+			refs[i], found = ref, true
+		}
+	}
+	if !found {
+		refs = append(refs, ref)
+	}
+	fields := make(map[string]interface{}, 3)
+	setReferences(fields, refs)
+	return fields
+}
+
+// setReferences stores the reference lists of a vector
+func setReferences(metadata map[string]interface{}, refs []reference) {
+	hashes := make([]string, 0, len(refs))
+	documents := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		hashes = append(hashes, ref.hash)
+		documents = append(documents, ref.documentID)
+	}
+	metadata["referenced_by"] = metadataPaths(refs)
+	metadata["referenced_by_hashes"] = hashes
+	metadata["referenced_by_documents"] = documents
+}
+
+// metadataPaths returns the paths of references
+func metadataPaths(refs []reference) []string {
+	paths := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		paths = append(paths, ref.path)
+	}
+	return paths
+}
+
+// resolveOwner picks the first reference with a known document as the new
+// owner of a vector and returns it with the references left. References
+// recorded without their document are resolved from the file's current
+// vectors, and dropped when it has none.
+func (c *PineconeClient) resolveOwner(ctx context.Context, refs []reference) (*reference, []reference, error) {
+	resolved := make([]reference, 0, len(refs))
+	for _, ref := range refs {
+		if ref.documentID == "" {
+			match, err := c.currentVectorOf(ctx, ref.path)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to look up document of %s: %w", ref.path, err)
+			}
+			if match == nil {
+				continue
+			}
+			ref.documentID, _ = match.Metadata["document_id"].(string)
+			if ref.documentID == "" {
+				continue
+			}
+			if ref.hash == "" {
+				ref.hash, _ = match.Metadata["file_hash"].(string)
+			}
+		}
+		resolved = append(resolved, ref)
+	}
+	if len(resolved) == 0 {
+		return nil, nil, nil
+	}
+	return &resolved[0], resolved[1:], nil
+}
+
+// currentVectorOf returns a current vector stored for a file, or nil when
+// it has none
+func (c *PineconeClient) currentVectorOf(ctx context.Context, filePath string) (*Match, error) {
+	filter := map[string]interface{}{
+		"file_path":     map[string]interface{}{"$eq": filePath},
+		"superseded_at": map[string]interface{}{"$exists": false},
+	}
+	matches, err := c.QueryVectors(ctx, make([]float32, c.config.Dimension), 1, filter)
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	return matches[0], nil
+}
+
+// metadataStrings converts a list metadata value to a string slice
+func metadataStrings(value interface{}) []string {
+	switch items := value.(type) {
+	case []string:
+		return items
+	case []interface{}:
+		result := make([]string, 0, len(items))
+		for _, item := range items {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// decodeResponse checks the status code, decodes the JSON body into out
+// (when non-nil) and closes the body
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := httppool.DecodeJSON(resp.Body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// maxTopKWithMetadata is the largest topK Pinecone accepts for a query
+// that includes metadata
+const maxTopKWithMetadata = 1000
+
+// SupersedeVersions marks the current vectors of a file, other than those of
+// currentDocumentID, as superseded at the given time, so time-travel queries
+// can still see them while regular queries only return the latest version. Vectors referenced by other files through
+// chunk deduplication stay current. Summary vectors of the older versions
+// are marked too. Returns the number of chunk vectors marked.
+func (c *PineconeClient) SupersedeVersions(ctx context.Context, filePath, currentDocumentID string, at time.Time) (int, error) {
+	marked, err := c.supersedeInNamespace(ctx, c.namespace(), filePath, currentDocumentID, at)
+	if err != nil {
+		return marked, err
+	}
+	if ns := c.SummaryNamespace(); ns != "" {
+		if _, err := c.supersedeInNamespace(ctx, ns, filePath, currentDocumentID, at); err != nil {
+			return marked, fmt.Errorf("failed to supersede summary vectors: %w", err)
+		}
+	}
+	return marked, nil
+}
+
+// SupersedeVersionsInNamespace marks the current vectors of a file in a
+// namespace, other than those of currentDocumentID, as superseded
+func (c *PineconeClient) SupersedeVersionsInNamespace(ctx context.Context, namespace, filePath, currentDocumentID string, at time.Time) (int, error) {
+	return c.supersedeInNamespace(ctx, namespace, filePath, currentDocumentID, at)
+}
+
+// supersedeInNamespace marks the current vectors of a file in one
+// namespace. Marked vectors drop out of the filter, so it queries pages of
+// the largest topK allowed with metadata until one brings nothing new.
+func (c *PineconeClient) supersedeInNamespace(ctx context.Context, namespace, filePath, currentDocumentID string, at time.Time) (int, error) {
+	dummyVector := make([]float32, c.config.Dimension)
+
+	filter := map[string]interface{}{
+		"file_path":     map[string]interface{}{"$eq": filePath},
+		"document_id":   map[string]interface{}{"$ne": currentDocumentID},
+		"superseded_at": map[string]interface{}{"$exists": false},
+	}
+
+	marked := 0
+	// Vectors referenced elsewhere stay in the filter, as may marked ones
+	// until the update is visible, so each is handled once
+	seen := make(map[string]bool)
+	for {
+		matches, err := c.QueryVectorsInNamespace(ctx, namespace, dummyVector, maxTopKWithMetadata, filter)
+		if err != nil {
+			return marked, fmt.Errorf("failed to find current versions: %w", err)
+		}
+
+		progressed := false
+		for _, match := range matches {
+			if seen[match.ID] {
+				continue
+			}
+			seen[match.ID] = true
+			progressed = true
+			if referencedElsewhere(match.Metadata, filePath) {
+				continue
+			}
+			if err := c.updateMetadataInNamespace(ctx, namespace, match.ID, map[string]interface{}{
+				"superseded_at": at.Unix(),
+			}); err != nil {
+				return marked, fmt.Errorf("failed to supersede vector %s: %w", match.ID, err)
+			}
+			marked++
+		}
+
+		if len(matches) < maxTopKWithMetadata {
+			return marked, nil
+		}
+		if !progressed {
+			c.logger.Warn("Query pages return only vectors already handled; older versions may stay current",
+				zap.String("file_path", filePath),
+				zap.Int("marked", marked))
+			return marked, nil
+		}
+	}
+}
+
+// referencedElsewhere reports whether a vector is referenced by files other than filePath
+func referencedElsewhere(metadata map[string]interface{}, filePath string) bool {
+	refs, ok := metadata["referenced_by"].([]interface{})
+	if !ok {
+		return false
+	}
+	for _, ref := range refs {
+		if path, ok := ref.(string); ok && path != filePath {
+			return true
+		}
+	}
+	return false
+}
+
+// namespace returns the namespace used for data-plane requests
+func (c *PineconeClient) namespace() string {
+	if c.chunks != "" {
+		return c.chunks
+	}
+	if c.config.UseNamespaces {
+		return "default"
+	}
+	return ""
+}
+
+// SetNamespace makes the client keep chunk vectors in a namespace of the
+// given name, for a client whose index is shared with other chunk vectors
+func (c *PineconeClient) SetNamespace(namespace string) {
+	c.chunks = namespace
+}
+
+// SummaryNamespace returns the namespace holding document summary vectors,
+// kept apart from chunk vectors so each can be searched on its own. It is
+// empty when summary vectors are disabled.
+func (c *PineconeClient) SummaryNamespace() string {
+	if !c.summaries {
+		return ""
+	}
+	return c.config.SummaryNamespace
+}
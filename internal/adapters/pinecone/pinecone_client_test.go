@@ -0,0 +1,400 @@
+package pinecone
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// fakeIndex serves the data-plane endpoints the client uses from vectors
+// kept in memory, filtering queries on the metadata operators the client
+// sends
+type fakeIndex struct {
+	mu      sync.Mutex
+	vectors map[string]*Vector
+}
+
+func newFakeIndex(t *testing.T) (*fakeIndex, *PineconeClient) {
+	t.Helper()
+	index := &fakeIndex{vectors: make(map[string]*Vector)}
+	server := httptest.NewServer(index)
+	t.Cleanup(server.Close)
+	client := &PineconeClient{
+		host:       server.URL,
+		httpClient: server.Client(),
+		config:     &config.PineconeConfig{Dimension: 2, UpsertBatchSize: 100},
+		limiter:    newAdaptiveLimiter(1),
+		logger:     zap.NewNop(),
+	}
+	return index, client
+}
+
+func (f *fakeIndex) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/vectors/list":
+		prefix := r.URL.Query().Get("prefix")
+		var page ListResponse
+		for _, id := range f.ids() {
+			if strings.HasPrefix(id, prefix) {
+				page.Vectors = append(page.Vectors, struct {
+					ID string `json:"id"`
+				}{id})
+			}
+		}
+		writeJSON(w, page)
+	case "/vectors/fetch":
+		resp := FetchResponse{Vectors: make(map[string]*Vector)}
+		for _, id := range r.URL.Query()["ids"] {
+			if v, ok := f.vectors[id]; ok {
+				resp.Vectors[id] = v
+			}
+		}
+		writeJSON(w, resp)
+	case "/vectors/upsert":
+		var req UpsertRequest
+		decodeJSON(w, r, &req)
+		for _, v := range req.Vectors {
+			stored := *v
+			stored.Metadata = roundTrip(v.Metadata)
+			f.vectors[v.ID] = &stored
+		}
+		writeJSON(w, UpsertResponse{UpsertedCount: len(req.Vectors)})
+	case "/vectors/update":
+		var req UpdateRequest
+		decodeJSON(w, r, &req)
+		v, ok := f.vectors[req.ID]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		for key, value := range roundTrip(req.SetMetadata) {
+			v.Metadata[key] = value
+		}
+		writeJSON(w, struct{}{})
+	case "/vectors/delete":
+		var req DeleteRequest
+		decodeJSON(w, r, &req)
+		for _, id := range req.IDs {
+			delete(f.vectors, id)
+		}
+		writeJSON(w, struct{}{})
+	case "/query":
+		var req QueryRequest
+		decodeJSON(w, r, &req)
+		var resp QueryResponse
+		for _, id := range f.ids() {
+			v := f.vectors[id]
+			if len(resp.Matches) < req.TopK && matchesFilter(v.Metadata, roundTrip(req.Filter)) {
+				resp.Matches = append(resp.Matches, &QueryMatch{ID: id, Metadata: v.Metadata})
+			}
+		}
+		writeJSON(w, resp)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// ids returns the stored vector IDs in order
+func (f *fakeIndex) ids() []string {
+	ids := make([]string, 0, len(f.vectors))
+	for id := range f.vectors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// put stores a vector as the JSON API would return it
+func (f *fakeIndex) put(id string, metadata map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.vectors[id] = &Vector{ID: id, Values: []float32{0.6, 0.8}, Metadata: roundTrip(metadata)}
+}
+
+// matchesFilter evaluates the $and, $or, $eq, $ne, $in and $exists
+// operators of a metadata filter
+func matchesFilter(metadata map[string]interface{}, filter map[string]interface{}) bool {
+	for key, cond := range filter {
+		switch key {
+		case "$and", "$or":
+			clauses, _ := cond.([]interface{})
+			matched := false
+			for _, clause := range clauses {
+				ok := matchesFilter(metadata, clause.(map[string]interface{}))
+				if key == "$and" && !ok {
+					return false
+				}
+				matched = matched || ok
+			}
+			if key == "$or" && !matched {
+				return false
+			}
+			continue
+		}
+		value, present := metadata[key]
+		for op, operand := range cond.(map[string]interface{}) {
+			var ok bool
+			switch op {
+			case "$eq":
+				ok = present && containsValue(value, operand)
+			case "$ne":
+				ok = !present || !containsValue(value, operand)
+			case "$in":
+				for _, item := range operand.([]interface{}) {
+					ok = ok || (present && containsValue(value, item))
+				}
+			case "$exists":
+				ok = present == operand.(bool)
+			}
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// containsValue reports whether a metadata value is, or as a list holds,
+// the operand
+func containsValue(value, operand interface{}) bool {
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			if item == operand {
+				return true
+			}
+		}
+		return false
+	}
+	return value == operand
+}
+
+func roundTrip(metadata map[string]interface{}) map[string]interface{} {
+	data, _ := json.Marshal(metadata) //nolint:errcheck
+	out := make(map[string]interface{})
+	json.Unmarshal(data, &out) //nolint:errcheck
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// sharedChunk stores a chunk of document a that the files of documents b
+// and c reuse
+func sharedChunk(t *testing.T, index *fakeIndex, client *PineconeClient) {
+	t.Helper()
+	index.put("doc-a-chunk-0", map[string]interface{}{
+		"document_id":  "doc-a",
+		"file_path":    "/data/a.md",
+		"file_hash":    "hash-a",
+		"content_hash": "content",
+		"content":      "shared text",
+	})
+	ctx := context.Background()
+	for _, ref := range []struct{ path, hash, doc string }{
+		{"/data/b.md", "hash-b", "doc-b"},
+		{"/data/c.md", "hash-c", "doc-c"},
+	} {
+		vectors, err := client.FetchVectors(ctx, []string{"doc-a-chunk-0"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := AddReference(vectors["doc-a-chunk-0"].Metadata, ref.path, ref.hash, ref.doc)
+		if err := client.UpdateMetadata(ctx, "doc-a-chunk-0", fields); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// ownedChunk returns the single vector of a document, failing unless there
+// is exactly one
+func ownedChunk(t *testing.T, client *PineconeClient, documentID string) *Vector {
+	t.Helper()
+	ctx := context.Background()
+	ids, err := client.ListVectorIDs(ctx, documentID+"-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 {
+		t.Fatalf("vectors of %s = %v, want one", documentID, ids)
+	}
+	vectors, err := client.FetchVectors(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vectors[ids[0]]
+}
+
+func TestDeleteByDocumentIDHandsSharedChunkOn(t *testing.T) {
+	index, client := newFakeIndex(t)
+	ctx := context.Background()
+	sharedChunk(t, index, client)
+
+	// Deleting the storing document hands the chunk to b
+	if n, err := client.DeleteByDocumentID(ctx, "doc-a"); err != nil || n != 0 {
+		t.Fatalf("DeleteByDocumentID(doc-a) = %d, %v; want 0 deleted", n, err)
+	}
+	v := ownedChunk(t, client, "doc-b")
+	if v.ID != "doc-b-shared-doc-a-chunk-0" {
+		t.Errorf("handed-over ID = %s", v.ID)
+	}
+	wantOwner(t, v, "doc-b", "/data/b.md", "hash-b", []string{"/data/c.md"}, []string{"hash-c"}, []string{"doc-c"})
+	if exists, err := client.CheckDocumentExists(ctx, "hash-c"); err != nil || !exists {
+		t.Errorf("CheckDocumentExists(hash-c) = %v, %v; want the chunk still found", exists, err)
+	}
+
+	// Deleting b in turn hands it to c, keeping its original ID
+	if n, err := client.DeleteByDocumentID(ctx, "doc-b"); err != nil || n != 0 {
+		t.Fatalf("DeleteByDocumentID(doc-b) = %d, %v; want 0 deleted", n, err)
+	}
+	v = ownedChunk(t, client, "doc-c")
+	if v.ID != "doc-c-shared-doc-a-chunk-0" {
+		t.Errorf("handed-over ID = %s", v.ID)
+	}
+	wantOwner(t, v, "doc-c", "/data/c.md", "hash-c", []string{}, []string{}, []string{})
+	if v.Metadata["content"] != "shared text" {
+		t.Errorf("content = %v, want the shared text", v.Metadata["content"])
+	}
+	matches, err := client.QueryVectors(ctx, []float32{1, 0}, 10, map[string]interface{}{
+		"file_path": map[string]interface{}{"$eq": "/data/c.md"},
+	})
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("query for c = %v, %v; want the chunk", matches, err)
+	}
+
+	// Deleting the last referencing document deletes the chunk
+	if n, err := client.DeleteByDocumentID(ctx, "doc-c"); err != nil || n != 1 {
+		t.Fatalf("DeleteByDocumentID(doc-c) = %d, %v; want 1 deleted", n, err)
+	}
+	if ids := index.ids(); len(ids) != 0 {
+		t.Errorf("vectors left = %v", ids)
+	}
+}
+
+func TestDeleteByDocumentIDResolvesLegacyReferences(t *testing.T) {
+	index, client := newFakeIndex(t)
+	ctx := context.Background()
+
+	// Referenced before document IDs were recorded; b has a vector of its own
+	index.put("doc-a-chunk-0", map[string]interface{}{
+		"document_id":          "doc-a",
+		"file_path":            "/data/a.md",
+		"file_hash":            "hash-a",
+		"referenced_by":        []string{"/data/b.md"},
+		"referenced_by_hashes": []string{"hash-b"},
+	})
+	index.put("doc-b-chunk-1", map[string]interface{}{
+		"document_id": "doc-b",
+		"file_path":   "/data/b.md",
+		"file_hash":   "hash-b",
+	})
+
+	if _, err := client.DeleteByDocumentID(ctx, "doc-a"); err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := client.FetchVectors(ctx, []string{"doc-b-shared-doc-a-chunk-0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, ok := vectors["doc-b-shared-doc-a-chunk-0"]
+	if !ok {
+		t.Fatalf("vectors = %v, want the chunk handed to doc-b", index.ids())
+	}
+	wantOwner(t, v, "doc-b", "/data/b.md", "hash-b", []string{}, []string{}, []string{})
+}
+
+func TestAddReferenceReplacesFileEntry(t *testing.T) {
+	metadata := roundTrip(map[string]interface{}{
+		"file_path":               "/data/a.md",
+		"referenced_by":           []string{"/data/b.md", "/data/c.md"},
+		"referenced_by_hashes":    []string{"hash-b", "hash-c"},
+		"referenced_by_documents": []string{"doc-b", "doc-c"},
+	})
+	fields := roundTrip(AddReference(metadata, "/data/b.md", "hash-b2", "doc-b2"))
+	want := map[string][]string{
+		"referenced_by":           {"/data/b.md", "/data/c.md"},
+		"referenced_by_hashes":    {"hash-b2", "hash-c"},
+		"referenced_by_documents": {"doc-b2", "doc-c"},
+	}
+	for key, values := range want {
+		if got := metadataStrings(fields[key]); strings.Join(got, ",") != strings.Join(values, ",") {
+			t.Errorf("%s = %v, want %v", key, got, values)
+		}
+	}
+}
+
+func TestCheckDocumentExistsSkipsSupersededVectors(t *testing.T) {
+	index, client := newFakeIndex(t)
+	ctx := context.Background()
+	index.put("doc-old-chunk-0", map[string]interface{}{
+		"document_id":   "doc-old",
+		"file_path":     "/data/a.md",
+		"file_hash":     "hash-old",
+		"superseded_at": 1700000000,
+	})
+	index.put("doc-new-chunk-0", map[string]interface{}{
+		"document_id":             "doc-new",
+		"file_path":               "/data/a.md",
+		"file_hash":               "hash-new",
+		"referenced_by":           []string{"/data/b.md"},
+		"referenced_by_hashes":    []string{"hash-b"},
+		"referenced_by_documents": []string{"doc-b"},
+	})
+	index.put("doc-c-chunk-0", map[string]interface{}{
+		"document_id":             "doc-c",
+		"file_path":               "/data/c.md",
+		"file_hash":               "hash-c-old",
+		"superseded_at":           1700000000,
+		"referenced_by":           []string{"/data/d.md"},
+		"referenced_by_hashes":    []string{"hash-d"},
+		"referenced_by_documents": []string{"doc-d"},
+	})
+
+	tests := []struct {
+		hash string
+		want bool
+	}{
+		{"hash-new", true},
+		{"hash-b", true},
+		{"hash-old", false},
+		{"hash-d", false},
+		{"hash-unknown", false},
+	}
+	for _, tt := range tests {
+		if got, err := client.CheckDocumentExists(ctx, tt.hash); err != nil || got != tt.want {
+			t.Errorf("CheckDocumentExists(%s) = %v, %v; want %v", tt.hash, got, err, tt.want)
+		}
+	}
+}
+
+func wantOwner(t *testing.T, v *Vector, documentID, filePath, fileHash string, paths, hashes, documents []string) {
+	t.Helper()
+	for key, want := range map[string]string{"document_id": documentID, "file_path": filePath, "file_hash": fileHash} {
+		if got := v.Metadata[key]; got != want {
+			t.Errorf("%s = %v, want %s", key, got, want)
+		}
+	}
+	for key, want := range map[string][]string{"referenced_by": paths, "referenced_by_hashes": hashes, "referenced_by_documents": documents} {
+		if got := metadataStrings(v.Metadata[key]); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
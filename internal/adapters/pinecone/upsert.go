@@ -0,0 +1,264 @@
+package pinecone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Backoff bounds for retrying throttled upserts without a Retry-After header
+const (
+	minUpsertBackoff = 500 * time.Millisecond
+	maxUpsertBackoff = 30 * time.Second
+)
+
+// throttledError is returned for upsert responses asking the client to slow
+// down (429 Too Many Requests or 503 Service Unavailable)
+type throttledError struct {
+	status     int
+	retryAfter time.Duration // zero when the response did not say
+	body       string
+}
+
+func (e *throttledError) Error() string {
+	return fmt.Sprintf("API error (status %d): %s", e.status, e.body)
+}
+
+// isThrottleStatus reports whether a status code asks the client to back off
+func isThrottleStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// upsertBackoff returns the wait before retrying a throttled batch: the
+// server's Retry-After when given, otherwise exponential with jitter
+func upsertBackoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	backoff := minUpsertBackoff << attempt
+	if backoff <= 0 || backoff > maxUpsertBackoff {
+		backoff = maxUpsertBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)) //nolint:gosec // jitter needs no crypto randomness
+}
+
+// adaptiveLimiter bounds concurrent upsert batches. It halves the limit and
+// pauses all batches when Pinecone throttles, then raises the limit one step
+// at a time as batches succeed, up to the configured maximum.
+type adaptiveLimiter struct {
+	mu         sync.Mutex
+	maxLimit   int
+	limit      int
+	active     int
+	successes  int
+	pauseUntil time.Time
+	changed    chan struct{} // closed and replaced whenever a slot may have opened
+}
+
+func newAdaptiveLimiter(maxLimit int) *adaptiveLimiter {
+	maxLimit = max(1, maxLimit)
+	return &adaptiveLimiter{maxLimit: maxLimit, limit: maxLimit, changed: make(chan struct{})}
+}
+
+// acquire waits for a free slot outside any throttling pause
+func (l *adaptiveLimiter) acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		wait := time.Until(l.pauseUntil)
+		if wait <= 0 && l.active < l.limit {
+			l.active++
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+		case <-changed:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// release frees a slot. A throttled batch halves the limit and pauses new
+// batches for the backoff; enough successes in a row raise the limit again.
+func (l *adaptiveLimiter) release(throttled bool, backoff time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	if throttled {
+		l.limit = max(1, l.limit/2)
+		l.successes = 0
+		if until := time.Now().Add(backoff); until.After(l.pauseUntil) {
+			l.pauseUntil = until
+		}
+	} else if l.limit < l.maxLimit {
+		l.successes++
+		if l.successes >= l.limit {
+			l.limit++
+			l.successes = 0
+		}
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// current returns the current concurrency limit
+func (l *adaptiveLimiter) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// upsertCounters accumulates upsert throughput across calls
+type upsertCounters struct {
+	vectors   atomic.Int64
+	batches   atomic.Int64
+	retries   atomic.Int64
+	throttled atomic.Int64
+	busyNanos atomic.Int64
+}
+
+// UpsertStats describes upsert throughput since the client was created
+type UpsertStats struct {
+	Vectors          int64   `json:"vectors"`
+	Batches          int64   `json:"batches"`
+	Retries          int64   `json:"retries"`
+	Throttled        int64   `json:"throttled"` // batches answered with 429 or 503
+	BusySeconds      float64 `json:"busy_seconds"`
+	VectorsPerSecond float64 `json:"vectors_per_second"` // over the time spent upserting
+	Concurrency      int     `json:"concurrency"`        // current adaptive batch limit
+	MaxConcurrency   int     `json:"max_concurrency"`
+}
+
+// UpsertStats returns upsert throughput counters
+func (c *PineconeClient) UpsertStats() UpsertStats {
+	stats := UpsertStats{
+		Vectors:        c.upserts.vectors.Load(),
+		Batches:        c.upserts.batches.Load(),
+		Retries:        c.upserts.retries.Load(),
+		Throttled:      c.upserts.throttled.Load(),
+		BusySeconds:    time.Duration(c.upserts.busyNanos.Load()).Seconds(),
+		Concurrency:    c.limiter.current(),
+		MaxConcurrency: c.limiter.maxLimit,
+	}
+	if stats.BusySeconds > 0 {
+		stats.VectorsPerSecond = float64(stats.Vectors) / stats.BusySeconds
+	}
+	return stats
+}
+
+// upsertBatches sends batches through a pool of workers bounded by the
+// adaptive limiter, retrying throttled batches. The first failure cancels
+// the remaining batches.
+func (c *PineconeClient) upsertBatches(ctx context.Context, namespace string, batches [][]*Vector) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	work := make(chan []*Vector)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	workers := min(c.limiter.maxLimit, len(batches))
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range work {
+				if err := c.upsertWithRetry(ctx, namespace, batch); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+send:
+	for _, batch := range batches {
+		select {
+		case work <- batch:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// upsertWithRetry upserts one batch, backing off while Pinecone throttles
+func (c *PineconeClient) upsertWithRetry(ctx context.Context, namespace string, batch []*Vector) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.acquire(ctx); err != nil {
+			return err
+		}
+		err := c.upsertBatch(ctx, namespace, batch)
+
+		var throttled *throttledError
+		if !errors.As(err, &throttled) {
+			c.limiter.release(false, 0)
+			if err != nil {
+				return fmt.Errorf("failed to upsert batch: %w", err)
+			}
+			c.upserts.batches.Add(1)
+			c.upserts.vectors.Add(int64(len(batch)))
+			return nil
+		}
+
+		backoff := upsertBackoff(attempt, throttled.retryAfter)
+		c.limiter.release(true, backoff)
+		c.upserts.throttled.Add(1)
+		if attempt >= c.config.UpsertMaxRetries {
+			return fmt.Errorf("failed to upsert batch after %d retries: %w", attempt, err)
+		}
+		c.upserts.retries.Add(1)
+		c.logger.Warn("Pinecone throttled upsert, backing off",
+			zap.Int("status", throttled.status),
+			zap.Duration("backoff", backoff),
+			zap.Int("concurrency", c.limiter.current()))
+	}
+}
@@ -0,0 +1,158 @@
+// Package ratelimit bounds the requests sent to an external provider: how
+// many may be in flight at once and how many may start per minute. Limiters
+// are shared by name across the process, so every client of a provider
+// draws on the same quota.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Limiter bounds concurrent requests and the request rate. A zero limit
+// leaves that dimension unbounded.
+type Limiter struct {
+	slots    chan struct{} // nil without a concurrency limit
+	interval time.Duration // minimum spacing between request starts
+
+	mu   sync.Mutex
+	next time.Time // earliest start of the next request
+}
+
+// New creates a limiter allowing maxConcurrent requests in flight and rpm
+// requests per minute, spaced evenly
+func New(maxConcurrent, rpm int) *Limiter {
+	l := &Limiter{}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	if rpm > 0 {
+		l.interval = time.Minute / time.Duration(rpm)
+	}
+	return l
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = map[string]*Limiter{}
+)
+
+// Shared returns the process-wide limiter with the given name, creating it
+// with the limits on first use
+func Shared(name string, maxConcurrent, rpm int) *Limiter {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if l, ok := shared[name]; ok {
+		return l
+	}
+	l := New(maxConcurrent, rpm)
+	shared[name] = l
+	return l
+}
+
+// Acquire waits for a request slot and for the rate to allow another
+// request. The returned function releases the slot.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	if wait := l.reserve(); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// reserve claims the next start time and returns how long to wait for it
+func (l *Limiter) reserve() time.Duration {
+	if l.interval == 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	return start.Sub(now)
+}
+
+// Transport wraps an HTTP transport so every request waits for the
+// limiter. A request holds its slot until its response body is closed, so
+// streamed responses count as in flight while they are read.
+func Transport(l *Limiter, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{limiter: l, base: base}
+}
+
+type transport struct {
+	limiter *Limiter
+	base    http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.Acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody releases the request slot once when closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// UnaryInterceptor makes every unary gRPC call wait for the limiter and
+// hold its slot until the call returns
+func UnaryInterceptor(l *Limiter) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		release, err := l.Acquire(ctx)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
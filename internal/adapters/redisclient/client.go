@@ -0,0 +1,34 @@
+package redisclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// New creates a Redis client from configuration
+func New(cfg *config.Config) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.GetRedisAddr(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+}
+
+// Connect creates a Redis client and verifies the connection
+func Connect(ctx context.Context, cfg *config.Config) (*redis.Client, error) {
+	client := New(cfg)
+
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close() //nolint:errcheck
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %w", cfg.Redis.GetRedisAddr(), err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,143 @@
+// Package analytics logs the queries users run, without their text by
+// default: each event keeps a hash of the normalized question, its latency
+// and how many results it found. Aggregated, the events show the most
+// frequent questions, the ones finding nothing and how fast answers come,
+// so knowledge-base owners can see what users fail to find.
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// Kinds of query
+const (
+	KindQuery  = "query"
+	KindSearch = "search"
+)
+
+// Event is one query run
+type Event struct {
+	QueryID   string    `json:"query_id"`
+	Kind      string    `json:"kind"` // query or search
+	Hash      string    `json:"hash"` // of the normalized text
+	Text      string    `json:"text,omitempty"`
+	Results   int       `json:"results"`
+	LatencyMS int64     `json:"latency_ms"`
+	Failed    bool      `json:"failed,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Store persists query events
+type Store interface {
+	Append(ctx context.Context, event *Event) error
+	// List returns the events between since and until, oldest first
+	List(ctx context.Context, since, until time.Time) ([]*Event, error)
+	Close() error
+}
+
+// Compile-time checks that the stores implement the interface
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+// NewStore creates the query event store, beside the document registry in
+// the same backend. It returns nil when analytics are disabled.
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	if !cfg.Analytics.Enabled {
+		return nil, nil
+	}
+	switch cfg.Registry.Backend {
+	case "memory":
+		logger.Info("Query analytics enabled", zap.String("backend", "memory"))
+		return NewMemoryStore(cfg.Analytics.Retention), nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Query analytics enabled", zap.String("backend", "redis"), zap.String("key_prefix", cfg.Registry.KeyPrefix))
+		return NewRedisStore(client, cfg.Registry.KeyPrefix, cfg.Analytics.Retention), nil
+	default:
+		return nil, fmt.Errorf("unknown registry backend: %s", cfg.Registry.Backend)
+	}
+}
+
+// Recorder logs query events without failing the query
+type Recorder struct {
+	store    Store
+	hashKey  []byte
+	keepText bool
+	logger   *zap.Logger
+}
+
+// NewRecorder creates a recorder; a nil store makes recording a no-op
+func NewRecorder(store Store, cfg config.AnalyticsConfig, logger *zap.Logger) *Recorder {
+	return &Recorder{store: store, hashKey: []byte(cfg.HashKey), keepText: cfg.KeepText, logger: logger}
+}
+
+// Enabled reports whether events are persisted
+func (r *Recorder) Enabled() bool {
+	return r != nil && r.store != nil
+}
+
+// Record logs a query that started at start and found results; err marks
+// it failed. The text is kept only as its hash unless configured otherwise.
+func (r *Recorder) Record(ctx context.Context, queryID, kind, text string, start time.Time, results int, err error) {
+	if !r.Enabled() {
+		return
+	}
+	normalized := Normalize(text)
+	event := &Event{
+		QueryID:   queryID,
+		Kind:      kind,
+		Hash:      r.hash(normalized),
+		Results:   results,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Failed:    err != nil,
+		Timestamp: time.Now().UTC(),
+	}
+	if r.keepText {
+		event.Text = normalized
+	}
+	// The query's own context may be cancelled once the response is sent
+	if err := r.store.Append(context.WithoutCancel(ctx), event); err != nil {
+		r.logger.Warn("Failed to record query event", zap.String("query_id", queryID), zap.Error(err))
+	}
+}
+
+// List returns the events between since and until, oldest first
+func (r *Recorder) List(ctx context.Context, since, until time.Time) ([]*Event, error) {
+	if !r.Enabled() {
+		return nil, fmt.Errorf("query analytics are disabled")
+	}
+	return r.store.List(ctx, since, until)
+}
+
+// hash returns the hex SHA-256 of normalized text, keyed when a hash key
+// is configured
+func (r *Recorder) hash(normalized string) string {
+	if len(r.hashKey) == 0 {
+		sum := sha256.Sum256([]byte(normalized))
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(normalized))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Normalize lowercases text and collapses its whitespace, so the same
+// question typed differently counts as one
+func Normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
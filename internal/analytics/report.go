@@ -0,0 +1,160 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/feedback"
+)
+
+// Report aggregates the query events of a period
+type Report struct {
+	Since       time.Time `json:"since"`
+	Until       time.Time `json:"until"`
+	Queries     int       `json:"queries"`
+	Distinct    int       `json:"distinct"` // distinct normalized texts
+	Failed      int       `json:"failed"`
+	ZeroResults int       `json:"zero_results"` // successful queries finding nothing
+	// ZeroResultRate is the share of successful queries finding nothing
+	ZeroResultRate float64 `json:"zero_result_rate"`
+	Latency        Latency `json:"latency"`
+	Up             int     `json:"up"`   // ratings up of the queries
+	Down           int     `json:"down"` // ratings down of the queries
+	// Top are the most frequent queries
+	Top []QueryStats `json:"top"`
+	// ZeroResultQueries are the most frequent queries finding nothing
+	ZeroResultQueries []QueryStats `json:"zero_result_queries"`
+}
+
+// Latency gives percentiles of the milliseconds successful queries took
+type Latency struct {
+	P50 int64 `json:"p50_ms"`
+	P90 int64 `json:"p90_ms"`
+	P95 int64 `json:"p95_ms"`
+	P99 int64 `json:"p99_ms"`
+	Max int64 `json:"max_ms"`
+}
+
+// QueryStats aggregates the runs of one normalized query text
+type QueryStats struct {
+	Hash        string    `json:"hash"`
+	Text        string    `json:"text,omitempty"` // when text is kept
+	Count       int       `json:"count"`
+	ZeroResults int       `json:"zero_results"`
+	Up          int       `json:"up"`
+	Down        int       `json:"down"`
+	LastAt      time.Time `json:"last_at"`
+}
+
+// Aggregate reports on the events between since and until, listing the
+// limit most frequent queries; ratings are matched to the events by query
+// ID
+func Aggregate(events []*Event, ratings []*feedback.Feedback, since, until time.Time, limit int) *Report {
+	report := &Report{
+		Since:             since,
+		Until:             until,
+		Top:               []QueryStats{},
+		ZeroResultQueries: []QueryStats{},
+	}
+
+	byQueryID := make(map[string]*QueryStats, len(events))
+	byHash := make(map[string]*QueryStats)
+	var latencies []int64
+	for _, e := range events {
+		report.Queries++
+		stats, ok := byHash[e.Hash]
+		if !ok {
+			stats = &QueryStats{Hash: e.Hash}
+			byHash[e.Hash] = stats
+		}
+		stats.Count++
+		if e.Text != "" {
+			stats.Text = e.Text
+		}
+		if e.Timestamp.After(stats.LastAt) {
+			stats.LastAt = e.Timestamp
+		}
+		byQueryID[e.QueryID] = stats
+
+		if e.Failed {
+			report.Failed++
+			continue
+		}
+		latencies = append(latencies, e.LatencyMS)
+		if e.Results == 0 {
+			report.ZeroResults++
+			stats.ZeroResults++
+		}
+	}
+	report.Distinct = len(byHash)
+	if succeeded := report.Queries - report.Failed; succeeded > 0 {
+		report.ZeroResultRate = float64(report.ZeroResults) / float64(succeeded)
+	}
+	report.Latency = percentiles(latencies)
+
+	for _, fb := range ratings {
+		stats, ok := byQueryID[fb.QueryID]
+		if !ok {
+			continue
+		}
+		if fb.Rating == feedback.RatingUp {
+			stats.Up++
+			report.Up++
+		} else {
+			stats.Down++
+			report.Down++
+		}
+	}
+
+	all := make([]QueryStats, 0, len(byHash))
+	for _, stats := range byHash {
+		all = append(all, *stats)
+	}
+	report.Top = mostFrequent(all, limit, func(s QueryStats) int { return s.Count })
+	report.ZeroResultQueries = mostFrequent(all, limit, func(s QueryStats) int { return s.ZeroResults })
+	return report
+}
+
+// mostFrequent returns up to limit queries with a positive count, the
+// highest first
+func mostFrequent(all []QueryStats, limit int, count func(QueryStats) int) []QueryStats {
+	selected := []QueryStats{}
+	for _, s := range all {
+		if count(s) > 0 {
+			selected = append(selected, s)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if ci, cj := count(selected[i]), count(selected[j]); ci != cj {
+			return ci > cj
+		}
+		if !selected[i].LastAt.Equal(selected[j].LastAt) {
+			return selected[i].LastAt.After(selected[j].LastAt)
+		}
+		return selected[i].Hash < selected[j].Hash
+	})
+	if limit > 0 && len(selected) > limit {
+		selected = selected[:limit]
+	}
+	return selected
+}
+
+// percentiles returns the nearest-rank percentiles of latencies
+func percentiles(latencies []int64) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	rank := func(p float64) int64 {
+		i := int(math.Ceil(p/100*float64(len(latencies)))) - 1
+		return latencies[max(i, 0)]
+	}
+	return Latency{
+		P50: rank(50),
+		P90: rank(90),
+		P95: rank(95),
+		P99: rank(99),
+		Max: latencies[len(latencies)-1],
+	}
+}
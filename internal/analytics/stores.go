@@ -0,0 +1,120 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryStore keeps query events in process memory
+type MemoryStore struct {
+	mu        sync.Mutex
+	events    []*Event
+	retention time.Duration
+}
+
+// NewMemoryStore creates an in-memory store dropping events older than
+// retention
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{retention: retention}
+}
+
+// Append adds an event and drops expired ones
+func (s *MemoryStore) Append(_ context.Context, event *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-s.retention)
+	expired := 0
+	for expired < len(s.events) && s.events[expired].Timestamp.Before(cutoff) {
+		expired++
+	}
+	stored := *event
+	s.events = append(s.events[expired:], &stored)
+	return nil
+}
+
+// List returns the events between since and until, oldest first
+func (s *MemoryStore) List(_ context.Context, since, until time.Time) ([]*Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []*Event
+	for _, e := range s.events {
+		if e.Timestamp.Before(since) || e.Timestamp.After(until) {
+			continue
+		}
+		copied := *e
+		events = append(events, &copied)
+	}
+	return events, nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// RedisStore keeps query events in a Redis sorted set scored by their time
+// in milliseconds, trimming expired events as new ones are added
+type RedisStore struct {
+	client    *redis.Client
+	events    string
+	retention time.Duration
+}
+
+// NewRedisStore creates a Redis backed store
+func NewRedisStore(client *redis.Client, keyPrefix string, retention time.Duration) *RedisStore {
+	return &RedisStore{
+		client:    client,
+		events:    keyPrefix + ":analytics:queries",
+		retention: retention,
+	}
+}
+
+// Append adds an event and drops expired ones
+func (s *RedisStore) Append(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal query event: %w", err)
+	}
+	cutoff := time.Now().Add(-s.retention).UnixMilli()
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, s.events, redis.Z{Score: float64(event.Timestamp.UnixMilli()), Member: data})
+	pipe.ZRemRangeByScore(ctx, s.events, "-inf", "("+strconv.FormatInt(cutoff, 10))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to write query event: %w", err)
+	}
+	return nil
+}
+
+// List returns the events between since and until, oldest first
+func (s *RedisStore) List(ctx context.Context, since, until time.Time) ([]*Event, error) {
+	values, err := s.client.ZRangeByScore(ctx, s.events, &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixMilli(), 10),
+		Max: strconv.FormatInt(until.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read query events: %w", err)
+	}
+
+	events := make([]*Event, 0, len(values))
+	for _, data := range values {
+		var event Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+// Close closes the Redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,149 @@
+package apispec
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schema is the subset of the OpenAPI 3 schema object used by the services
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"` // *Schema or bool
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// SchemaFor derives a schema from a Go value using its json and binding
+// struct tags. Fields tagged binding:"required" are required and structs
+// reject unknown properties.
+func SchemaFor(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+	return schemaForType(reflect.TypeOf(v), make(map[reflect.Type]bool))
+}
+
+func schemaForType(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case rawType:
+		// Raw JSON holds any JSON value
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaForType(t.Elem(), visiting)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaForType(t.Elem(), visiting)}
+	case reflect.Struct:
+		return structSchema(t, visiting)
+	default:
+		// interface{} and anything else accepts any JSON value
+		return &Schema{}
+	}
+}
+
+func structSchema(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if visiting[t] {
+		return &Schema{Type: "object"}
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	schema := &Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+	addFields(schema, t, visiting)
+	return schema
+}
+
+func addFields(schema *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		// Embedded structs without a json name are flattened, as encoding/json does
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(schema, ft, visiting)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := schemaForType(field.Type, visiting)
+		if desc := field.Tag.Get("description"); desc != "" {
+			prop.Description = desc
+		}
+		schema.Properties[name] = prop
+		if hasBinding(field, "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// jsonName returns the JSON property name of a field and whether it is skipped
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, false
+}
+
+func hasBinding(field reflect.StructField, rule string) bool {
+	for _, r := range strings.Split(field.Tag.Get("binding"), ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+// Package apispec describes the HTTP endpoints of a service once and derives
+// both its OpenAPI 3 document and runtime request validation from that
+// description.
+package apispec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operation annotates a handler with the documentation and types of its endpoint
+type Operation struct {
+	Method  string
+	Path    string // relative to the spec base path, gin syntax
+	Summary string
+	Tag     string
+	// Request is a zero value of the JSON request body type; nil for no body
+	Request interface{}
+	// Response is a zero value of the JSON response type; nil when undocumented
+	Response interface{}
+	// Query lists the accepted query parameters
+	Query []string
+	// Stream marks server-sent event responses
+	Stream  bool
+	Handler gin.HandlerFunc
+}
+
+// Spec is the API description of one service
+type Spec struct {
+	title    string
+	version  string
+	basePath string
+	ops      []Operation
+}
+
+// New creates a spec whose operations are mounted under basePath
+func New(title, version, basePath string, ops ...Operation) *Spec {
+	return &Spec{title: title, version: version, basePath: strings.TrimRight(basePath, "/"), ops: ops}
+}
+
+// Register adds every operation to the router group, validating request
+// bodies against their schema before the handler runs
+func (s *Spec) Register(group gin.IRoutes) {
+	for _, op := range s.ops {
+		handlers := make([]gin.HandlerFunc, 0, 2)
+		if schema := SchemaFor(op.Request); schema != nil {
+			handlers = append(handlers, ValidateRequest(schema))
+		}
+		handlers = append(handlers, op.Handler)
+		group.Handle(op.Method, op.Path, handlers...)
+	}
+}
+
+// Serve returns a handler that serves the OpenAPI document
+func (s *Spec) Serve() gin.HandlerFunc {
+	doc, err := s.Document()
+	return func(c *gin.Context) {
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/json", doc)
+	}
+}
+
+// Document renders the OpenAPI 3 document
+func (s *Spec) Document() ([]byte, error) {
+	paths := make(map[string]map[string]interface{})
+	tags := make(map[string]bool)
+	for _, op := range s.ops {
+		path, params := openAPIPath(s.basePath + op.Path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(op.Method)] = s.operation(op, params)
+		if op.Tag != "" {
+			tags[op.Tag] = true
+		}
+	}
+
+	tagNames := make([]string, 0, len(tags))
+	for tag := range tags {
+		tagNames = append(tagNames, tag)
+	}
+	sort.Strings(tagNames)
+	tagList := make([]map[string]string, 0, len(tagNames))
+	for _, tag := range tagNames {
+		tagList = append(tagList, map[string]string{"name": tag})
+	}
+
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": s.title, "version": s.version},
+		"tags":    tagList,
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]*Schema{"Error": errorSchema()},
+		},
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func (s *Spec) operation(op Operation, params []string) map[string]interface{} {
+	result := map[string]interface{}{"summary": op.Summary}
+	if op.Tag != "" {
+		result["tags"] = []string{op.Tag}
+	}
+
+	parameters := make([]map[string]interface{}, 0, len(params)+len(op.Query))
+	for _, p := range params {
+		parameters = append(parameters, map[string]interface{}{
+			"name": p, "in": "path", "required": true, "schema": &Schema{Type: "string"},
+		})
+	}
+	for _, q := range op.Query {
+		parameters = append(parameters, map[string]interface{}{
+			"name": q, "in": "query", "required": false, "schema": &Schema{Type: "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+
+	if schema := SchemaFor(op.Request); schema != nil {
+		result["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}},
+		}
+	}
+
+	success := map[string]interface{}{"description": "Success"}
+	switch {
+	case op.Stream:
+		success["content"] = map[string]interface{}{"text/event-stream": map[string]interface{}{"schema": &Schema{Type: "string"}}}
+	case op.Response != nil:
+		success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": SchemaFor(op.Response)}}
+	}
+
+	errorContent := map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": map[string]string{"$ref": "#/components/schemas/Error"}},
+	}
+	responses := map[string]interface{}{"200": success}
+	if op.Request != nil || len(params) > 0 || len(op.Query) > 0 {
+		responses["400"] = map[string]interface{}{"description": "Invalid request", "content": errorContent}
+	}
+	if SchemaFor(op.Request) != nil {
+		responses["413"] = map[string]interface{}{"description": "Request body too large", "content": errorContent}
+	}
+	responses["500"] = map[string]interface{}{"description": "Internal error", "content": errorContent}
+	result["responses"] = responses
+
+	return result
+}
+
+func errorSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"error": {Type: "string"},
+			"details": {Type: "array", Items: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"field":   {Type: "string"},
+					"message": {Type: "string"},
+				},
+			}},
+		},
+		Required: []string{"error"},
+	}
+}
+
+// openAPIPath converts gin path parameters (:id, *path) to OpenAPI syntax ({id})
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// Requested reports whether the binary was invoked as "<service> openapi",
+// which prints the document instead of starting the server
+func Requested() bool {
+	return len(os.Args) > 1 && os.Args[1] == "openapi"
+}
+
+// Write renders the document to w
+func (s *Spec) Write(w io.Writer) error {
+	doc, err := s.Document()
+	if err != nil {
+		return fmt.Errorf("failed to render OpenAPI document: %w", err)
+	}
+	_, err = w.Write(append(doc, '\n'))
+	return err
+}
@@ -0,0 +1,228 @@
+package apispec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxRequestBytes caps the JSON bodies read for validation, which is room
+// for a batch of vectors of the largest embedding models
+const maxRequestBytes = 32 << 20
+
+// FieldError describes one schema violation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// Validate checks a JSON document against the schema and returns every violation
+func Validate(schema *Schema, data []byte) []FieldError {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return []FieldError{{Message: "request body is required"}}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []FieldError{{Message: fmt.Sprintf("malformed JSON: %v", err)}}
+	}
+	if decoder.More() {
+		return []FieldError{{Message: "request body must contain a single JSON value"}}
+	}
+
+	var errs []FieldError
+	validateValue(schema, value, "", &errs)
+	return errs
+}
+
+// ValidateRequest is middleware that rejects JSON bodies not matching the
+// schema with a 400 listing each violation, and bodies over
+// maxRequestBytes with a 413. The body is restored so handlers can bind it
+// as usual.
+func ValidateRequest(schema *Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var data []byte
+		if c.Request.Body != nil {
+			var err error
+			data, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestBytes))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)})
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("failed to read request body: %v", err)})
+				return
+			}
+		}
+
+		if errs := Validate(schema, data); len(errs) > 0 {
+			messages := make([]string, 0, len(errs))
+			for _, e := range errs {
+				messages = append(messages, e.String())
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request: " + strings.Join(messages, "; "),
+				"details": errs,
+			})
+			return
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+		c.Next()
+	}
+}
+
+func validateValue(schema *Schema, value interface{}, path string, errs *[]FieldError) {
+	if schema == nil || schema.Type == "" {
+		return
+	}
+	if value == nil {
+		// null is treated like an absent optional field
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			addError(errs, path, "expected object, got %s", jsonType(value))
+			return
+		}
+		validateObject(schema, obj, path, errs)
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			addError(errs, path, "expected array, got %s", jsonType(value))
+			return
+		}
+		for i, item := range arr {
+			validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			addError(errs, path, "expected string, got %s", jsonType(value))
+			return
+		}
+		validateFormat(schema.Format, s, path, errs)
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			addError(errs, path, "expected integer, got %s", jsonType(value))
+			return
+		}
+		if _, err := n.Int64(); err != nil {
+			addError(errs, path, "expected integer, got %s", n.String())
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			addError(errs, path, "expected number, got %s", jsonType(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			addError(errs, path, "expected boolean, got %s", jsonType(value))
+		}
+	}
+}
+
+func validateObject(schema *Schema, obj map[string]interface{}, path string, errs *[]FieldError) {
+	for _, name := range schema.Required {
+		if isEmpty(obj[name]) {
+			addError(errs, join(path, name), "is required")
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if prop, ok := schema.Properties[key]; ok {
+			validateValue(prop, obj[key], join(path, key), errs)
+			continue
+		}
+		switch extra := schema.AdditionalProperties.(type) {
+		case bool:
+			if !extra {
+				addError(errs, join(path, key), "unknown field")
+			}
+		case *Schema:
+			validateValue(extra, obj[key], join(path, key), errs)
+		}
+	}
+}
+
+func validateFormat(format, value, path string, errs *[]FieldError) {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			addError(errs, path, "expected RFC 3339 timestamp")
+		}
+	case "uuid":
+		if _, err := uuid.Parse(value); err != nil {
+			addError(errs, path, "expected UUID")
+		}
+	}
+}
+
+// isEmpty mirrors binding:"required", which rejects zero values
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func addError(errs *[]FieldError, path, format string, args ...interface{}) {
+	*errs = append(*errs, FieldError{Field: path, Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// Action identifies the kind of audited operation
+type Action string
+
+const (
+	ActionQuery            Action = "query"
+	ActionSearch           Action = "search"
+	ActionCompare          Action = "compare"
+	ActionSimilar          Action = "document.similar"
+	ActionFeedback         Action = "feedback"
+	ActionProcessDocument  Action = "process.document"
+	ActionProcessDirectory Action = "process.directory"
+	ActionProcessBatch     Action = "process.batch"
+	ActionDelete           Action = "document.delete"
+	ActionReindex          Action = "document.reindex"
+	ActionRechunk          Action = "document.rechunk"
+	ActionResummarize      Action = "document.resummarize"
+	ActionCollectionCreate Action = "collection.create"
+	ActionCollectionUpdate Action = "collection.update"
+	ActionCollectionDelete Action = "collection.delete"
+	ActionDigest           Action = "digest.run"
+	ActionTopics           Action = "topics.run"
+	ActionIndexingPause    Action = "indexing.pause"
+	ActionIndexingResume   Action = "indexing.resume"
+	ActionRunCancel        Action = "run.cancel"
+	ActionRunResume        Action = "run.resume"
+	ActionDLQRetry         Action = "dlq.retry"
+	ActionDLQDiscard       Action = "dlq.discard"
+	ActionAdmin            Action = "admin"
+)
+
+// Outcome values recorded on events
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Event is a single entry in the audit log
+type Event struct {
+	ID          string            `json:"id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Actor       string            `json:"actor"`
+	Action      Action            `json:"action"`
+	Resource    string            `json:"resource,omitempty"`
+	DocumentIDs []string          `json:"document_ids,omitempty"`
+	Outcome     string            `json:"outcome"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// NewEvent creates an event with generated ID and timestamp
+func NewEvent(actor string, action Action, resource string) *Event {
+	if actor == "" {
+		actor = "anonymous"
+	}
+	return &Event{
+		ID:        uuid.New().String(),
+		Timestamp: time.Now().UTC(),
+		Actor:     actor,
+		Action:    action,
+		Resource:  resource,
+		Outcome:   OutcomeSuccess,
+		Details:   make(map[string]string),
+	}
+}
+
+// Filter selects events from the audit log
+type Filter struct {
+	Actor  string
+	Action Action
+	Since  *time.Time
+	Until  *time.Time
+	Limit  int
+}
+
+// Matches reports whether an event satisfies the filter
+func (f *Filter) Matches(e *Event) bool {
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.Since != nil && e.Timestamp.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && e.Timestamp.After(*f.Until) {
+		return false
+	}
+	return true
+}
+
+// Store is an append-only audit event store
+type Store interface {
+	Append(ctx context.Context, event *Event) error
+	// Query returns matching events, newest first
+	Query(ctx context.Context, filter Filter) ([]*Event, error)
+	Close() error
+}
+
+// defaultQueryLimit caps query results when no limit is given
+const defaultQueryLimit = 100
+
+// NewStore creates the audit store selected by configuration. It returns
+// nil when auditing is disabled.
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	if !cfg.Audit.Enabled {
+		return nil, nil
+	}
+
+	switch cfg.Audit.Backend {
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Audit log enabled", zap.String("backend", "redis"), zap.String("stream", cfg.Audit.Stream))
+		return NewRedisStore(client, cfg.Audit.Stream), nil
+	case "file":
+		store, err := NewFileStore(cfg.Audit.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Audit log enabled", zap.String("backend", "file"), zap.String("path", cfg.Audit.FilePath))
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown audit backend: %s", cfg.Audit.Backend)
+	}
+}
+
+// Recorder appends events without failing the audited operation
+type Recorder struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// NewRecorder creates a recorder; a nil store makes recording a no-op
+func NewRecorder(store Store, logger *zap.Logger) *Recorder {
+	return &Recorder{store: store, logger: logger}
+}
+
+// Enabled reports whether events are persisted
+func (r *Recorder) Enabled() bool {
+	return r != nil && r.store != nil
+}
+
+// Record appends an event, logging instead of returning store errors
+func (r *Recorder) Record(ctx context.Context, event *Event) {
+	if !r.Enabled() {
+		return
+	}
+	if err := r.store.Append(ctx, event); err != nil {
+		r.logger.Error("Failed to write audit event",
+			zap.String("action", string(event.Action)),
+			zap.String("actor", event.Actor),
+			zap.Error(err))
+	}
+}
+
+// Query returns matching events from the underlying store
+func (r *Recorder) Query(ctx context.Context, filter Filter) ([]*Event, error) {
+	if !r.Enabled() {
+		return nil, fmt.Errorf("audit log is disabled")
+	}
+	return r.store.Query(ctx, filter)
+}
@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps audit events in a Redis stream. Streams are append-only
+// and the store never trims them.
+type RedisStore struct {
+	client *redis.Client
+	stream string
+}
+
+// NewRedisStore creates a Redis stream backed store
+func NewRedisStore(client *redis.Client, stream string) *RedisStore {
+	return &RedisStore{client: client, stream: stream}
+}
+
+// Append adds an event to the stream
+func (s *RedisStore) Append(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		Values: map[string]interface{}{"event": data},
+	}).Err()
+}
+
+// Query scans the stream backwards from the newest entry
+func (s *RedisStore) Query(ctx context.Context, filter Filter) ([]*Event, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+
+	// Stream IDs start with the millisecond timestamp, so the time range
+	// can be applied by the server
+	end, start := "+", "-"
+	if filter.Until != nil {
+		end = strconv.FormatInt(filter.Until.UnixMilli(), 10)
+	}
+	if filter.Since != nil {
+		start = strconv.FormatInt(filter.Since.UnixMilli(), 10)
+	}
+
+	const pageSize = 500
+	events := make([]*Event, 0, limit)
+	for len(events) < limit {
+		messages, err := s.client.XRevRangeN(ctx, s.stream, end, start, pageSize).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit stream: %w", err)
+		}
+
+		for _, msg := range messages {
+			event, err := decodeMessage(msg)
+			if err != nil {
+				continue
+			}
+			if filter.Matches(event) {
+				events = append(events, event)
+				if len(events) == limit {
+					break
+				}
+			}
+		}
+
+		if len(messages) < pageSize {
+			break
+		}
+		// Continue strictly before the oldest message of this page
+		end = "(" + messages[len(messages)-1].ID
+	}
+
+	return events, nil
+}
+
+// Close closes the Redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func decodeMessage(msg redis.XMessage) (*Event, error) {
+	raw, ok := msg.Values["event"].(string)
+	if !ok {
+		return nil, fmt.Errorf("message %s has no event payload", msg.ID)
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(raw), &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// FileStore appends audit events to a JSON Lines file
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewFileStore opens (or creates) the audit file in append-only mode
+func NewFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+
+	return &FileStore{path: path, file: file}, nil
+}
+
+// Append writes an event as a single line and syncs it to disk
+func (s *FileStore) Append(_ context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	return s.file.Sync()
+}
+
+// Query reads the file and returns matching events, newest first
+func (s *FileStore) Query(_ context.Context, filter Filter) ([]*Event, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultQueryLimit
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	var matched []*Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if filter.Matches(&event) {
+			matched = append(matched, &event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit file: %w", err)
+	}
+
+	// Reverse into newest-first order and apply the limit
+	events := make([]*Event, 0, limit)
+	for i := len(matched) - 1; i >= 0 && len(events) < limit; i-- {
+		events = append(events, matched[i])
+	}
+
+	return events, nil
+}
+
+// Close closes the audit file
+func (s *FileStore) Close() error {
+	return s.file.Close()
+}
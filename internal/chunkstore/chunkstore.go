@@ -0,0 +1,58 @@
+// Package chunkstore keeps the full text of chunks whose content is too
+// large to be stored in vector metadata. Vectors then carry a truncated
+// preview and the chunk ID to look the full text up by.
+package chunkstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/interfaces"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned when no chunk matches
+var ErrNotFound = errors.New("chunk not found")
+
+// Store persists chunks
+type Store interface {
+	interfaces.ChunkRepository
+	Close() error
+}
+
+// Compile-time checks that the stores implement the repository interface
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+// NewStore creates the chunk store selected by configuration. It returns
+// nil without an error when the backend is "none"; oversized chunk content
+// is then only truncated.
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	switch cfg.ChunkStore.Backend {
+	case "none":
+		return nil, nil
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Chunk store enabled", zap.String("backend", "redis"), zap.String("key_prefix", cfg.ChunkStore.KeyPrefix))
+		return NewRedisStore(client, cfg.ChunkStore.KeyPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown chunk store backend: %s", cfg.ChunkStore.Backend)
+	}
+}
+
+// sortChunks orders the chunks of a document by position
+func sortChunks(chunks []*models.Chunk) {
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ChunkIndex < chunks[j].ChunkIndex })
+}
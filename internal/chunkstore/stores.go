@@ -0,0 +1,249 @@
+package chunkstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryStore keeps chunks in process memory
+type MemoryStore struct {
+	mu    sync.RWMutex
+	byID  map[uuid.UUID]*models.Chunk
+	byDoc map[uuid.UUID]map[uuid.UUID]struct{}
+}
+
+// NewMemoryStore creates an empty in-memory chunk store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		byID:  make(map[uuid.UUID]*models.Chunk),
+		byDoc: make(map[uuid.UUID]map[uuid.UUID]struct{}),
+	}
+}
+
+// Create stores a copy of the chunk
+func (s *MemoryStore) Create(_ context.Context, chunk *models.Chunk) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *chunk
+	s.byID[chunk.ID] = &stored
+	if s.byDoc[chunk.DocumentID] == nil {
+		s.byDoc[chunk.DocumentID] = make(map[uuid.UUID]struct{})
+	}
+	s.byDoc[chunk.DocumentID][chunk.ID] = struct{}{}
+	return nil
+}
+
+// GetByID returns the chunk with the given ID
+func (s *MemoryStore) GetByID(_ context.Context, id uuid.UUID) (*models.Chunk, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	chunk, ok := s.byID[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	result := *chunk
+	return &result, nil
+}
+
+// GetByDocumentID returns the chunks of a document in order
+func (s *MemoryStore) GetByDocumentID(_ context.Context, documentID uuid.UUID) ([]*models.Chunk, error) {
+	s.mu.RLock()
+	chunks := make([]*models.Chunk, 0, len(s.byDoc[documentID]))
+	for id := range s.byDoc[documentID] {
+		result := *s.byID[id]
+		chunks = append(chunks, &result)
+	}
+	s.mu.RUnlock()
+
+	sortChunks(chunks)
+	return chunks, nil
+}
+
+// Update replaces a stored chunk
+func (s *MemoryStore) Update(ctx context.Context, chunk *models.Chunk) error {
+	s.mu.RLock()
+	_, ok := s.byID[chunk.ID]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrNotFound
+	}
+	return s.Create(ctx, chunk)
+}
+
+// Delete removes a chunk
+func (s *MemoryStore) Delete(_ context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if chunk, ok := s.byID[id]; ok {
+		delete(s.byDoc[chunk.DocumentID], id)
+		delete(s.byID, id)
+	}
+	return nil
+}
+
+// DeleteByDocumentID removes all chunks of a document
+func (s *MemoryStore) DeleteByDocumentID(_ context.Context, documentID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.byDoc[documentID] {
+		delete(s.byID, id)
+	}
+	delete(s.byDoc, documentID)
+	return nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// RedisStore keeps chunks in a Redis hash by chunk ID, with a set of chunk
+// IDs per document
+type RedisStore struct {
+	client    *redis.Client
+	chunks    string
+	keyPrefix string
+}
+
+// NewRedisStore creates a Redis backed chunk store
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	return &RedisStore{
+		client:    client,
+		chunks:    keyPrefix + ":chunks",
+		keyPrefix: keyPrefix,
+	}
+}
+
+func (s *RedisStore) documentKey(documentID uuid.UUID) string {
+	return s.keyPrefix + ":documents:" + documentID.String()
+}
+
+// Create stores the chunk
+func (s *RedisStore) Create(ctx context.Context, chunk *models.Chunk) error {
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chunk: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, s.chunks, chunk.ID.String(), data)
+	pipe.SAdd(ctx, s.documentKey(chunk.DocumentID), chunk.ID.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to write chunk: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns the chunk with the given ID
+func (s *RedisStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Chunk, error) {
+	data, err := s.client.HGet(ctx, s.chunks, id.String()).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk: %w", err)
+	}
+
+	var chunk models.Chunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return nil, fmt.Errorf("failed to decode chunk: %w", err)
+	}
+	return &chunk, nil
+}
+
+// GetByDocumentID returns the chunks of a document in order
+func (s *RedisStore) GetByDocumentID(ctx context.Context, documentID uuid.UUID) ([]*models.Chunk, error) {
+	ids, err := s.client.SMembers(ctx, s.documentKey(documentID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document chunks: %w", err)
+	}
+	if len(ids) == 0 {
+		return []*models.Chunk{}, nil
+	}
+
+	values, err := s.client.HMGet(ctx, s.chunks, ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunks: %w", err)
+	}
+	chunks := make([]*models.Chunk, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var chunk models.Chunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode chunk: %w", err)
+		}
+		chunks = append(chunks, &chunk)
+	}
+
+	sortChunks(chunks)
+	return chunks, nil
+}
+
+// Update replaces a stored chunk
+func (s *RedisStore) Update(ctx context.Context, chunk *models.Chunk) error {
+	exists, err := s.client.HExists(ctx, s.chunks, chunk.ID.String()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read chunk: %w", err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return s.Create(ctx, chunk)
+}
+
+// Delete removes a chunk
+func (s *RedisStore) Delete(ctx context.Context, id uuid.UUID) error {
+	chunk, err := s.GetByID(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.HDel(ctx, s.chunks, id.String())
+	pipe.SRem(ctx, s.documentKey(chunk.DocumentID), id.String())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete chunk: %w", err)
+	}
+	return nil
+}
+
+// DeleteByDocumentID removes all chunks of a document
+func (s *RedisStore) DeleteByDocumentID(ctx context.Context, documentID uuid.UUID) error {
+	key := s.documentKey(documentID)
+	ids, err := s.client.SMembers(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read document chunks: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	if len(ids) > 0 {
+		pipe.HDel(ctx, s.chunks, ids...)
+	}
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete document chunks: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,105 @@
+// Package collections organizes documents into named collections. A
+// collection holds the file paths of its documents, so membership carries
+// over to new versions of a document, and queries are scoped to it with a
+// metadata filter on those paths and the paths referencing deduplicated
+// chunks.
+package collections
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// MaxDocuments is the most documents a collection holds. Queries scoped to
+// a collection list its paths twice in their Pinecone filter, for the files
+// storing chunks and those referencing deduplicated ones, and this keeps
+// that filter within Pinecone's limits.
+const MaxDocuments = 1000
+
+var (
+	// ErrNotFound is returned when no collection has the name
+	ErrNotFound = errors.New("collection not found")
+	// ErrExists is returned when creating a collection whose name is taken
+	ErrExists = errors.New("collection already exists")
+	// ErrFull is returned when adding documents would exceed MaxDocuments
+	ErrFull = fmt.Errorf("collection would exceed %d documents", MaxDocuments)
+)
+
+// namePattern is the form of collection names
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// ValidateName checks that a name is lowercase letters, digits, dots,
+// underscores and hyphens, starting with a letter or digit, at most 64
+// characters
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid collection name %q: use up to 64 lowercase letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// Collection is a named set of documents
+type Collection struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Paths       []string  `json:"paths"` // file paths of the documents, sorted
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Store persists collections
+type Store interface {
+	// Create stores a new collection, failing with ErrExists if the name is taken
+	Create(ctx context.Context, collection *Collection) error
+	Get(ctx context.Context, name string) (*Collection, error)
+	// List returns all collections ordered by name
+	List(ctx context.Context) ([]*Collection, error)
+	Delete(ctx context.Context, name string) error
+	// AddPaths adds documents by file path; paths already present are ignored
+	AddPaths(ctx context.Context, name string, paths []string) (*Collection, error)
+	// RemovePaths removes documents by file path; absent paths are ignored
+	RemovePaths(ctx context.Context, name string, paths []string) (*Collection, error)
+	Close() error
+}
+
+// Compile-time checks that the stores implement the interface
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+// NewStore creates the collection store. Collections are kept beside the
+// document registry, in the same backend.
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	switch cfg.Registry.Backend {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Collections enabled", zap.String("backend", "redis"), zap.String("key_prefix", cfg.Registry.KeyPrefix))
+		return NewRedisStore(client, cfg.Registry.KeyPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown registry backend: %s", cfg.Registry.Backend)
+	}
+}
+
+// sortedPaths returns the members of a path set in order
+func sortedPaths(set map[string]struct{}) []string {
+	paths := make([]string, 0, len(set))
+	for p := range set {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,318 @@
+package collections
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryStore keeps collections in process memory
+type MemoryStore struct {
+	mu          sync.RWMutex
+	collections map[string]*memoryCollection
+}
+
+type memoryCollection struct {
+	collection Collection // Paths unused; members are in paths
+	paths      map[string]struct{}
+}
+
+// NewMemoryStore creates an empty in-memory collection store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{collections: make(map[string]*memoryCollection)}
+}
+
+// Create stores a new collection
+func (s *MemoryStore) Create(_ context.Context, collection *Collection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collections[collection.Name]; ok {
+		return ErrExists
+	}
+	stored := &memoryCollection{collection: *collection, paths: make(map[string]struct{})}
+	for _, p := range collection.Paths {
+		stored.paths[p] = struct{}{}
+	}
+	stored.collection.Paths = nil
+	s.collections[collection.Name] = stored
+	return nil
+}
+
+// Get returns the collection with the given name
+func (s *MemoryStore) Get(_ context.Context, name string) (*Collection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.collections[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return stored.snapshot(), nil
+}
+
+// List returns all collections
+func (s *MemoryStore) List(_ context.Context) ([]*Collection, error) {
+	s.mu.RLock()
+	result := make([]*Collection, 0, len(s.collections))
+	for _, stored := range s.collections {
+		result = append(result, stored.snapshot())
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Delete removes a collection
+func (s *MemoryStore) Delete(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.collections[name]; !ok {
+		return ErrNotFound
+	}
+	delete(s.collections, name)
+	return nil
+}
+
+// AddPaths adds documents to a collection
+func (s *MemoryStore) AddPaths(_ context.Context, name string, paths []string) (*Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.collections[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	added := 0
+	for _, p := range paths {
+		if _, ok := stored.paths[p]; !ok {
+			added++
+		}
+	}
+	if len(stored.paths)+added > MaxDocuments {
+		return nil, ErrFull
+	}
+	for _, p := range paths {
+		stored.paths[p] = struct{}{}
+	}
+	stored.collection.UpdatedAt = time.Now()
+	return stored.snapshot(), nil
+}
+
+// RemovePaths removes documents from a collection
+func (s *MemoryStore) RemovePaths(_ context.Context, name string, paths []string) (*Collection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.collections[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	for _, p := range paths {
+		delete(stored.paths, p)
+	}
+	stored.collection.UpdatedAt = time.Now()
+	return stored.snapshot(), nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (m *memoryCollection) snapshot() *Collection {
+	result := m.collection
+	result.Paths = sortedPaths(m.paths)
+	return &result
+}
+
+// RedisStore keeps collection details in a Redis hash by name and the file
+// paths of each collection in a set
+type RedisStore struct {
+	client    *redis.Client
+	details   string
+	keyPrefix string
+}
+
+// NewRedisStore creates a Redis backed collection store
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	return &RedisStore{
+		client:    client,
+		details:   keyPrefix + ":collections",
+		keyPrefix: keyPrefix,
+	}
+}
+
+// Create stores a new collection
+func (s *RedisStore) Create(ctx context.Context, collection *Collection) error {
+	details := *collection
+	details.Paths = nil
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal collection: %w", err)
+	}
+
+	created, err := s.client.HSetNX(ctx, s.details, collection.Name, data).Result()
+	if err != nil {
+		return fmt.Errorf("failed to write collection: %w", err)
+	}
+	if !created {
+		return ErrExists
+	}
+	if len(collection.Paths) > 0 {
+		if err := s.client.SAdd(ctx, s.pathsKey(collection.Name), toMembers(collection.Paths)...).Err(); err != nil {
+			return fmt.Errorf("failed to write collection: %w", err)
+		}
+	}
+	return nil
+}
+
+// Get returns the collection with the given name
+func (s *RedisStore) Get(ctx context.Context, name string) (*Collection, error) {
+	collection, err := s.getDetails(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if collection.Paths, err = s.client.SMembers(ctx, s.pathsKey(name)).Result(); err != nil {
+		return nil, fmt.Errorf("failed to read collection: %w", err)
+	}
+	sort.Strings(collection.Paths)
+	return collection, nil
+}
+
+// List returns all collections
+func (s *RedisStore) List(ctx context.Context) ([]*Collection, error) {
+	names, err := s.client.HKeys(ctx, s.details).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collections: %w", err)
+	}
+	sort.Strings(names)
+
+	result := make([]*Collection, 0, len(names))
+	for _, name := range names {
+		collection, err := s.Get(ctx, name)
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, collection)
+	}
+	return result, nil
+}
+
+// Delete removes a collection
+func (s *RedisStore) Delete(ctx context.Context, name string) error {
+	pipe := s.client.TxPipeline()
+	deleted := pipe.HDel(ctx, s.details, name)
+	pipe.Del(ctx, s.pathsKey(name))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	if deleted.Val() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddPaths adds documents to a collection
+func (s *RedisStore) AddPaths(ctx context.Context, name string, paths []string) (*Collection, error) {
+	collection, err := s.getDetails(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) > 0 {
+		key := s.pathsKey(name)
+		present, err := s.client.SMIsMember(ctx, key, toMembers(paths)...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read collection: %w", err)
+		}
+		count, err := s.client.SCard(ctx, key).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read collection: %w", err)
+		}
+		added := 0
+		for _, ok := range present {
+			if !ok {
+				added++
+			}
+		}
+		if int(count)+added > MaxDocuments {
+			return nil, ErrFull
+		}
+		if err := s.client.SAdd(ctx, key, toMembers(paths)...).Err(); err != nil {
+			return nil, fmt.Errorf("failed to write collection: %w", err)
+		}
+	}
+	return s.touch(ctx, collection)
+}
+
+// RemovePaths removes documents from a collection
+func (s *RedisStore) RemovePaths(ctx context.Context, name string, paths []string) (*Collection, error) {
+	collection, err := s.getDetails(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) > 0 {
+		if err := s.client.SRem(ctx, s.pathsKey(name), toMembers(paths)...).Err(); err != nil {
+			return nil, fmt.Errorf("failed to write collection: %w", err)
+		}
+	}
+	return s.touch(ctx, collection)
+}
+
+// Close closes the Redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) pathsKey(name string) string {
+	return s.keyPrefix + ":collection:" + name
+}
+
+// getDetails returns a collection without its paths
+func (s *RedisStore) getDetails(ctx context.Context, name string) (*Collection, error) {
+	data, err := s.client.HGet(ctx, s.details, name).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection: %w", err)
+	}
+
+	var collection Collection
+	if err := json.Unmarshal([]byte(data), &collection); err != nil {
+		return nil, fmt.Errorf("failed to decode collection: %w", err)
+	}
+	return &collection, nil
+}
+
+// touch records a membership change and returns the updated collection
+func (s *RedisStore) touch(ctx context.Context, collection *Collection) (*Collection, error) {
+	collection.UpdatedAt = time.Now()
+	data, err := json.Marshal(collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal collection: %w", err)
+	}
+	if err := s.client.HSet(ctx, s.details, collection.Name, data).Err(); err != nil {
+		return nil, fmt.Errorf("failed to write collection: %w", err)
+	}
+	return s.Get(ctx, collection.Name)
+}
+
+func toMembers(paths []string) []interface{} {
+	members := make([]interface{}, len(paths))
+	for i, p := range paths {
+		members[i] = p
+	}
+	return members
+}
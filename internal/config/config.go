@@ -2,6 +2,11 @@ package config
 
 import (
 	"fmt"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -9,14 +14,89 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Azure    AzureConfig    `mapstructure:"azure"`
-	Google   GoogleConfig   `mapstructure:"google"`
-	Pinecone PineconeConfig `mapstructure:"pinecone"`
-	GitHub   GitHubConfig   `mapstructure:"github"`
-	App      AppConfig      `mapstructure:"app"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	Services ServicesConfig `mapstructure:"services"`
-	Server   ServerConfig   `mapstructure:"server"`
+	Azure           AzureConfig           `mapstructure:"azure"`
+	Google          GoogleConfig          `mapstructure:"google"`
+	Pinecone        PineconeConfig        `mapstructure:"pinecone"`
+	GitHub          GitHubConfig          `mapstructure:"github"`
+	App             AppConfig             `mapstructure:"app"`
+	Redis           RedisConfig           `mapstructure:"redis"`
+	Services        ServicesConfig        `mapstructure:"services"`
+	Server          ServerConfig          `mapstructure:"server"`
+	TLS             TLSConfig             `mapstructure:"tls"`
+	CORS            CORSConfig            `mapstructure:"cors"`
+	SecurityHeaders SecurityHeadersConfig `mapstructure:"security_headers"`
+	Ingest          IngestConfig          `mapstructure:"ingest"`
+	Dedup           DedupConfig           `mapstructure:"dedup"`
+	ACL             ACLConfig             `mapstructure:"acl"`
+	Audit           AuditConfig           `mapstructure:"audit"`
+	Embedding       EmbeddingConfig       `mapstructure:"embedding"`
+	Vision          VisionConfig          `mapstructure:"vision"`
+	Gateway         GatewayConfig         `mapstructure:"gateway"`
+	Registry        RegistryConfig        `mapstructure:"registry"`
+	Scan            ScanConfig            `mapstructure:"scan"`
+	Extraction      ExtractionConfig      `mapstructure:"extraction"`
+	ChunkStore      ChunkStoreConfig      `mapstructure:"chunk_store"`
+	Retrieval       RetrievalConfig       `mapstructure:"retrieval"`
+	WAL             WALConfig             `mapstructure:"wal"`
+	ContentStore    ContentStoreConfig    `mapstructure:"content_store"`
+	Digest          DigestConfig          `mapstructure:"digest"`
+	Limits          LimitsConfig          `mapstructure:"limits"`
+	ProviderHTTP    ProviderHTTPConfig    `mapstructure:"provider_http"`
+	Enrichment      EnrichmentConfig      `mapstructure:"enrichment"`
+	DLQ             DLQConfig             `mapstructure:"dlq"`
+	Summary         SummaryConfig         `mapstructure:"summary"`
+	ImageSearch     ImageSearchConfig     `mapstructure:"image_search"`
+	Sparse          SparseConfig          `mapstructure:"sparse"`
+	Math            MathConfig            `mapstructure:"math"`
+	LogFiles        LogFilesConfig        `mapstructure:"log_files"`
+	DataFiles       DataFilesConfig       `mapstructure:"data_files"`
+	Conversion      ConversionConfig      `mapstructure:"conversion"`
+	Plugins         PluginsConfig         `mapstructure:"plugins"`
+	Malware         MalwareConfig         `mapstructure:"malware"`
+	Moderation      ModerationConfig      `mapstructure:"moderation"`
+	Routing         RoutingConfig         `mapstructure:"routing"`
+	Citations       CitationsConfig       `mapstructure:"citations"`
+	// StructuredOutput contains configuration of answers in JSON
+	StructuredOutput StructuredOutputConfig `mapstructure:"structured_output"`
+	// Tools contains configuration of the tools the model may call while
+	// answering
+	Tools ToolsConfig `mapstructure:"tools"`
+	// Agent contains configuration of the agent query mode
+	Agent AgentConfig `mapstructure:"agent"`
+	// Topics contains configuration of the topic overview of the corpus
+	Topics TopicsConfig `mapstructure:"topics"`
+	// Analytics contains configuration of the query analytics
+	Analytics AnalyticsConfig `mapstructure:"analytics"`
+	// Popularity contains configuration of document usage tracking and the
+	// popularity boost at rank time
+	Popularity PopularityConfig `mapstructure:"popularity"`
+	// Events contains configuration of the pipeline event stream
+	Events EventsConfig `mapstructure:"events"`
+	// Locks contains configuration of the locks coordinating orchestrator
+	// replicas
+	Locks LocksConfig `mapstructure:"locks"`
+	// Sharding contains configuration of the ingest queue shared by
+	// orchestrator replicas
+	Sharding ShardingConfig `mapstructure:"sharding"`
+	// Idempotency contains configuration of the Idempotency-Key header
+	// accepted by mutation endpoints
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	// MCP contains configuration of the MCP server of the CLI, which
+	// offers the knowledge base as tools to IDE assistants and chat clients
+	MCP MCPConfig `mapstructure:"mcp"`
+	// Slack contains configuration of the Slack app served by the gateway
+	Slack SlackConfig `mapstructure:"slack"`
+	// Policies change how files of a category (document, code, image, ...)
+	// or extension without the dot (gif, md) are processed; set in
+	// config.yaml under policies. Extension policies override category
+	// policies.
+	Policies map[string]ProcessingPolicy `mapstructure:"policies"`
+	// Extractors name the external extractor of files of an extension
+	// without the dot (dwg) no built-in processor handles; set in
+	// config.yaml under extractors
+	Extractors map[string]string `mapstructure:"extractors"`
+	// Pipeline declares webhook stages; set in config.yaml under pipeline
+	Pipeline PipelineConfig `mapstructure:"pipeline"`
 }
 
 // AzureConfig contains Azure OpenAI configuration
@@ -27,6 +107,9 @@ type AzureConfig struct {
 	OpenAIEmbeddingsDeployment string `mapstructure:"openai_embeddings_deployment"`
 	OpenAIAPIVersion           string `mapstructure:"openai_api_version"`
 	OpenAIChatDeployment       string `mapstructure:"openai_chat_deployment"`
+	// OpenAIVisionDeployment is a vision-capable chat deployment that
+	// describes images; empty leaves images with basic file information
+	OpenAIVisionDeployment string `mapstructure:"openai_vision_deployment"`
 }
 
 // GoogleConfig contains Google Vision API configuration
@@ -37,13 +120,23 @@ type GoogleConfig struct {
 
 // PineconeConfig contains Pinecone vector database configuration
 type PineconeConfig struct {
-	APIKey        string `mapstructure:"api_key"`
-	Host          string `mapstructure:"host"`
-	IndexName     string `mapstructure:"index_name"`
-	Dimension     int    `mapstructure:"dimension"`
-	Cloud         string `mapstructure:"cloud"`
-	Region        string `mapstructure:"region"`
-	UseNamespaces bool   `mapstructure:"use_namespaces"`
+	APIKey            string `mapstructure:"api_key"`
+	Host              string `mapstructure:"host"`
+	IndexName         string `mapstructure:"index_name"`
+	Dimension         int    `mapstructure:"dimension"`
+	Metric            string `mapstructure:"metric"` // similarity metric of indexes created by the admin commands
+	Cloud             string `mapstructure:"cloud"`
+	Region            string `mapstructure:"region"`
+	UseNamespaces     bool   `mapstructure:"use_namespaces"`
+	MetadataLimit     int    `mapstructure:"metadata_limit"`     // maximum metadata bytes per vector
+	SummaryNamespace  string `mapstructure:"summary_namespace"`  // namespace of document summary vectors
+	UpsertBatchSize   int    `mapstructure:"upsert_batch_size"`  // vectors per upsert request
+	UpsertConcurrency int    `mapstructure:"upsert_concurrency"` // upsert requests in flight; lowered while throttled
+	UpsertMaxRetries  int    `mapstructure:"upsert_max_retries"` // retries of a batch answered with 429 or 503
+	Transport         string `mapstructure:"transport"`          // data plane of upserts: http or grpc
+	// DeletableIndexes are indexes besides index_name that the vector
+	// store's admin API may delete
+	DeletableIndexes []string `mapstructure:"deletable_indexes"`
 }
 
 // GitHubConfig contains GitHub API configuration
@@ -58,6 +151,22 @@ type AppConfig struct {
 	ChunkSize             int    `mapstructure:"chunk_size"`
 	ChunkOverlap          int    `mapstructure:"chunk_overlap"`
 	SkipExistingDocuments bool   `mapstructure:"skip_existing_documents"`
+	SummaryVectors        bool   `mapstructure:"summary_vectors"` // index each document summary as its own vector
+	// StartupWait is how long the orchestrator waits for its dependencies
+	// to become ready before automatic indexing; zero skips the wait
+	StartupWait time.Duration `mapstructure:"startup_wait"`
+	// IngestWorkers files submitted to the processing endpoints are
+	// processed at once; IngestQueueSize is how many may wait
+	IngestWorkers   int `mapstructure:"ingest_workers"`
+	IngestQueueSize int `mapstructure:"ingest_queue_size"`
+	// StreamChunkingBytes is the content size above which a document's
+	// chunks are embedded and stored StreamBatchChunks at a time as they
+	// are read, rather than all held in memory; zero never streams
+	StreamChunkingBytes int64 `mapstructure:"stream_chunking_bytes"`
+	StreamBatchChunks   int   `mapstructure:"stream_batch_chunks"`
+	// IngestRoots are the directories the processing endpoints may read
+	// files from; empty for DataDirectory alone
+	IngestRoots []string `mapstructure:"ingest_roots"`
 }
 
 // RedisConfig contains Redis configuration
@@ -78,6 +187,11 @@ type ServicesConfig struct {
 	VectorStoreServiceURL   string `mapstructure:"vector_store_service_url"`
 	QueryServiceURL         string `mapstructure:"query_service_url"`
 	OrchestratorServiceURL  string `mapstructure:"orchestrator_service_url"`
+	// AdminToken is the secret the gateway sends in X-Admin-Token on the
+	// routes of admin clients; the services serve their admin endpoints,
+	// such as the audit log or deleting documents, only to requests
+	// carrying it
+	AdminToken string `mapstructure:"admin_token"`
 }
 
 // ServerConfig contains HTTP server configuration
@@ -87,6 +201,829 @@ type ServerConfig struct {
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 }
 
+// TLSConfig secures the HTTP servers of all services and the requests they
+// make to one another. Servers serve HTTPS when a certificate is set, and
+// with a client CA they also require a client certificate signed by it
+// (mutual TLS). Rotated files are picked up without a restart.
+type TLSConfig struct {
+	CertFile       string        `mapstructure:"cert_file"`        // PEM certificate servers present
+	KeyFile        string        `mapstructure:"key_file"`         // PEM private key of cert_file
+	ClientCAFile   string        `mapstructure:"client_ca_file"`   // CA bundle verifying client certificates; set to require them
+	CAFile         string        `mapstructure:"ca_file"`          // CA bundle verifying the services called; empty uses the system roots
+	ClientCertFile string        `mapstructure:"client_cert_file"` // certificate presented to the services called; defaults to cert_file
+	ClientKeyFile  string        `mapstructure:"client_key_file"`  // private key of client_cert_file; defaults to key_file
+	MinVersion     string        `mapstructure:"min_version"`      // 1.2 or 1.3
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`  // how often the files are checked for rotation
+}
+
+// DedupConfig contains chunk-level deduplication configuration
+type DedupConfig struct {
+	Enabled             bool    `mapstructure:"enabled"`
+	NearDuplicate       bool    `mapstructure:"near_duplicate"`
+	SimilarityThreshold float64 `mapstructure:"similarity_threshold"`
+	MinHashPermutations int     `mapstructure:"minhash_permutations"`
+	MinHashBands        int     `mapstructure:"minhash_bands"`
+}
+
+// ACLConfig contains the access control applied to documents at ingestion
+type ACLConfig struct {
+	DefaultOwner      string    `mapstructure:"default_owner"`
+	DefaultGroups     []string  `mapstructure:"default_groups"`
+	DefaultVisibility string    `mapstructure:"default_visibility"`
+	Rules             []ACLRule `mapstructure:"rules"`
+}
+
+// ACLRule assigns access control to documents under a path prefix
+type ACLRule struct {
+	PathPrefix string   `mapstructure:"path_prefix"`
+	Owner      string   `mapstructure:"owner"`
+	Groups     []string `mapstructure:"groups"`
+	Visibility string   `mapstructure:"visibility"`
+}
+
+// AuditConfig contains audit log configuration
+type AuditConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Backend  string `mapstructure:"backend"` // redis or file
+	Stream   string `mapstructure:"stream"`
+	FilePath string `mapstructure:"file_path"`
+}
+
+// EmbeddingConfig contains embedding service batching and failover configuration
+type EmbeddingConfig struct {
+	MaxBatchSize       int           `mapstructure:"max_batch_size"`
+	MaxBatchWait       time.Duration `mapstructure:"max_batch_wait"`
+	FallbackEndpoint   string        `mapstructure:"fallback_endpoint"`
+	FallbackAPIKey     string        `mapstructure:"fallback_api_key"`
+	FallbackDeployment string        `mapstructure:"fallback_deployment"`
+	MaxTokens          int           `mapstructure:"max_tokens"` // token limit of the embedding model
+	Overflow           string        `mapstructure:"overflow"`   // split, truncate_head, truncate_tail or skip
+}
+
+// VisionConfig contains vision service caching and concurrency configuration
+type VisionConfig struct {
+	MaxConcurrent int           `mapstructure:"max_concurrent"`
+	CacheSize     int           `mapstructure:"cache_size"`
+	CacheTTL      time.Duration `mapstructure:"cache_ttl"`
+	MaxImageBytes int64         `mapstructure:"max_image_bytes"`
+	// Image descriptions by the vision chat deployment: the tokens one
+	// image may use in total, and at most in the description
+	DescribeTokenBudget int `mapstructure:"describe_token_budget"`
+	DescribeMaxTokens   int `mapstructure:"describe_max_tokens"`
+}
+
+// ImageSearchConfig contains configuration of image embeddings, which put
+// images and query text in a shared vector space so images are found by
+// visual similarity
+type ImageSearchConfig struct {
+	Provider  string  `mapstructure:"provider"` // none, azure_vision or clip
+	Endpoint  string  `mapstructure:"endpoint"`
+	APIKey    string  `mapstructure:"api_key"`
+	Model     string  `mapstructure:"model"`     // Azure AI Vision model version or CLIP model name
+	Dimension int     `mapstructure:"dimension"` // length of the provider's vectors
+	Namespace string  `mapstructure:"namespace"`
+	IndexName string  `mapstructure:"index_name"` // Pinecone index for image vectors; empty for PINECONE_INDEX_NAME
+	IndexHost string  `mapstructure:"index_host"`
+	TopK      int     `mapstructure:"top_k"`     // image matches added to each search
+	MinScore  float64 `mapstructure:"min_score"` // image matches below this similarity are dropped
+}
+
+// Enabled reports whether images are embedded
+func (c ImageSearchConfig) Enabled() bool {
+	return c.Provider != "" && c.Provider != "none"
+}
+
+// SparseConfig contains configuration of sparse vectors, stored beside the
+// dense embeddings of chunks so hybrid queries also match exact terms such
+// as identifiers and error codes. Sparse-dense vectors need an index with
+// the dotproduct metric.
+type SparseConfig struct {
+	Encoder  string `mapstructure:"encoder"`  // none, bm25 or splade
+	Endpoint string `mapstructure:"endpoint"` // text-embeddings-inference server of the SPLADE model
+	APIKey   string `mapstructure:"api_key"`
+	// Alpha weights the dense part of hybrid queries, from 0 for sparse
+	// only to 1 for dense only; the sparse part gets 1 - alpha
+	Alpha float64 `mapstructure:"alpha"`
+	// K1 and B are the BM25 term frequency saturation and chunk length
+	// normalization; AvgTokens is the chunk length normalized against
+	K1        float64 `mapstructure:"k1"`
+	B         float64 `mapstructure:"b"`
+	AvgTokens int     `mapstructure:"avg_tokens"`
+}
+
+// CitationsConfig contains configuration of the verification of answers
+// against the sources they cite. Each sentence citing sources is checked by
+// the share of its words found in them, or by asking the chat model.
+type CitationsConfig struct {
+	Verify string `mapstructure:"verify"` // none, overlap or llm
+	// MinOverlap is the share of a sentence's words, from 0 to 1, a cited
+	// source must contain to support it in overlap checks
+	MinOverlap float64 `mapstructure:"min_overlap"`
+	// Unsupported is flag to list unsupported sentences in the query
+	// trace, or strip to also remove them from the answer
+	Unsupported string `mapstructure:"unsupported"`
+}
+
+// StructuredOutputConfig contains configuration of queries answered in
+// JSON conforming to a schema
+type StructuredOutputConfig struct {
+	// RepairRetries is how many times a reply that does not conform to the
+	// schema is sent back to the model with its violations
+	RepairRetries int `mapstructure:"repair_retries"`
+}
+
+// ToolsConfig contains configuration of tool calling during answer
+// generation: the model may search again, look up document metadata,
+// follow wiki-links and read index statistics before it answers
+type ToolsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxIterations caps the rounds of tool calls of an answer; the model
+	// then answers with what it has
+	MaxIterations int `mapstructure:"max_iterations"`
+}
+
+// AgentConfig contains configuration of the agent query mode, which breaks
+// a question into sub-questions, retrieves for each and answers them in turn
+type AgentConfig struct {
+	MaxSteps int `mapstructure:"max_steps"` // sub-questions a question is broken into at most
+	// MaxTokens is the budget of model tokens, prompt and completion, of an
+	// answer; sub-questions past it are not retrieved for
+	MaxTokens int `mapstructure:"max_tokens"`
+}
+
+// TopicsConfig contains configuration of the topic overview, which
+// clusters the documents by their embeddings and names each cluster
+type TopicsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is how often the overview is generated again; zero only
+	// generates it at startup and on demand
+	Interval time.Duration `mapstructure:"interval"`
+	// Clusters is the number of topics; zero picks it from the number of
+	// documents
+	Clusters     int `mapstructure:"clusters"`
+	MaxDocuments int `mapstructure:"max_documents"` // documents clustered at most
+}
+
+// AnalyticsConfig contains configuration of the query analytics, which log
+// each query with a hash of its text, its latency and its result count
+type AnalyticsConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Retention time.Duration `mapstructure:"retention"` // how long query events are kept
+	// HashKey keys the hash of query text, so hashes cannot be reversed by
+	// hashing guessed questions; empty hashes without a key
+	HashKey string `mapstructure:"hash_key"`
+	// KeepText keeps the normalized query text beside its hash, showing what
+	// users ask at the cost of anonymity
+	KeepText bool `mapstructure:"keep_text"`
+}
+
+// PopularityConfig contains configuration of document popularity: how often
+// each document's chunks appear in answers and how those answers are rated
+type PopularityConfig struct {
+	Enabled bool `mapstructure:"enabled"` // track document usage
+	// Boost raises the match scores of documents often used in well rated
+	// answers; disabling it keeps tracking without biasing ranking
+	Boost bool `mapstructure:"boost"`
+	// Weight is the most a match score is raised by, as a share of it
+	Weight float64 `mapstructure:"weight"`
+	// Saturation is the number of answers at which a document gets half
+	// the boost its ratings allow
+	Saturation int `mapstructure:"saturation"`
+}
+
+// EventsConfig contains configuration of the Redis stream the orchestrator
+// publishes pipeline events to
+type EventsConfig struct {
+	Enabled bool   `mapstructure:"enabled"` // publish events; needs Redis
+	Stream  string `mapstructure:"stream"`  // key of the stream
+	// MaxLen is about the most events the stream keeps; older ones are
+	// trimmed as new ones are added
+	MaxLen int64 `mapstructure:"max_len"`
+}
+
+// LocksConfig contains configuration of the Redis locks that keep
+// orchestrator replicas from indexing the same directory at once and elect
+// the replica running scheduled jobs
+type LocksConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`    // coordinate replicas; needs Redis
+	KeyPrefix string `mapstructure:"key_prefix"` // prefix of the lock keys
+	// TTL is how long a lock outlives a replica that stopped refreshing
+	// it, as when it crashed
+	TTL time.Duration `mapstructure:"ttl"`
+	// Owner names this replica in the locks it holds; empty uses the host
+	// name and process ID
+	Owner string `mapstructure:"owner"`
+}
+
+// ShardingConfig contains configuration of the ingest queue shared by the
+// orchestrator replicas through Redis, from which each replica's workers
+// claim files
+type ShardingConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`    // share the ingest queue; needs Redis
+	KeyPrefix string `mapstructure:"key_prefix"` // prefix of the queue keys
+	// VisibilityTimeout is how long a claimed file is hidden from the other
+	// replicas without its claim being extended; a file whose replica
+	// stopped is offered again once it passes
+	VisibilityTimeout time.Duration `mapstructure:"visibility_timeout"`
+	// MaxDeliveries is how many times a file is claimed before it is
+	// dead-lettered instead, as one whose processing keeps stopping its
+	// replica
+	MaxDeliveries int `mapstructure:"max_deliveries"`
+	// PollInterval is how often idle workers look for files queued on
+	// other replicas
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+// IdempotencyConfig contains configuration of the responses stored under
+// the Idempotency-Key header, replayed to clients retrying a mutation
+type IdempotencyConfig struct {
+	Enabled bool          `mapstructure:"enabled"` // accept Idempotency-Key headers
+	TTL     time.Duration `mapstructure:"ttl"`     // how long a response is replayed
+}
+
+// MCPTools are the tools the MCP server can offer
+var MCPTools = []string{"search", "ask", "get_document"}
+
+// MCPConfig contains configuration of the MCP (Model Context Protocol)
+// server run by rag-cli mcp. Its tools call the gateway, over standard
+// input and output for an assistant that starts the command, or over HTTP
+// for clients authenticating with a key.
+type MCPConfig struct {
+	GatewayURL string `mapstructure:"gateway_url"`
+	// GatewayAPIKey is the key the tools call the gateway with; calling
+	// them for the users of Clients needs a gateway client with the
+	// delegate scope
+	GatewayAPIKey string `mapstructure:"gateway_api_key"`
+	Port          int    `mapstructure:"port"` // port of the HTTP transport
+	// Tools are those offered, of MCPTools; empty offers all
+	Tools []string `mapstructure:"tools"`
+	// APIKeys are the keys of HTTP clients allowed every offered tool;
+	// without them or Clients, the HTTP transport needs no key
+	APIKeys []string `mapstructure:"api_keys"`
+	// Clients are HTTP clients allowed only some tools, or calling them
+	// for a user; set in config.yaml under mcp.clients
+	Clients        []MCPClient   `mapstructure:"clients"`
+	MaxResultChars int           `mapstructure:"max_result_chars"` // longer tool results are cut
+	MaxTopK        int           `mapstructure:"max_top_k"`        // most chunks a tool call retrieves
+	Timeout        time.Duration `mapstructure:"timeout"`          // longest a tool call may take
+}
+
+// MCPClient is an HTTP client of the MCP server with its own key
+type MCPClient struct {
+	Name  string   `mapstructure:"name"`
+	Key   string   `mapstructure:"key"`
+	Tools []string `mapstructure:"tools"` // tools it may call, of those offered; empty allows all
+	// UserID, Groups and Tenant are the identity its tool calls are made
+	// for, limiting them to the documents that user may read
+	UserID string   `mapstructure:"user_id"`
+	Groups []string `mapstructure:"groups"`
+	Tenant string   `mapstructure:"tenant"`
+}
+
+// SlackConfig contains configuration of the Slack app the gateway serves
+// at /slack/commands and /slack/events. Questions asked with its slash
+// command or by mentioning its bot are answered in a thread, with links
+// to the sources. The signing secret enables it.
+type SlackConfig struct {
+	SigningSecret string `mapstructure:"signing_secret"` // verifies that requests come from Slack
+	// BotToken is the token of the workspaces without their own
+	BotToken string `mapstructure:"bot_token"`
+	APIURL   string `mapstructure:"api_url"` // Slack Web API
+	// SourceURL links a source, with {document_id} and {file_path}
+	// replaced; empty names sources by their file without a link
+	SourceURL string        `mapstructure:"source_url"`
+	TopK      int           `mapstructure:"top_k"`   // chunks an answer is given
+	Timeout   time.Duration `mapstructure:"timeout"` // longest an answer may take
+	// Workspaces configure the app per Slack workspace; set in
+	// config.yaml under slack.workspaces
+	Workspaces []SlackWorkspace `mapstructure:"workspaces"`
+	// AnyWorkspace answers in workspaces Workspaces does not list, with
+	// BotToken and no groups or tenant; without it they are refused
+	AnyWorkspace bool `mapstructure:"any_workspace"`
+}
+
+// Enabled reports whether the Slack app is served
+func (c SlackConfig) Enabled() bool {
+	return c.SigningSecret != ""
+}
+
+// SlackWorkspace is the configuration of the Slack app in one workspace.
+// Unset fields take those of SlackConfig.
+type SlackWorkspace struct {
+	TeamID     string   `mapstructure:"team_id"` // such as T0123ABCD
+	Name       string   `mapstructure:"name"`
+	BotToken   string   `mapstructure:"bot_token"`
+	Channels   []string `mapstructure:"channels"`   // IDs of the channels it answers in; empty answers in any
+	Collection string   `mapstructure:"collection"` // only answer from documents in this collection
+	TopK       int      `mapstructure:"top_k"`
+	SourceURL  string   `mapstructure:"source_url"`
+	// Groups and Tenant are the identity questions from the workspace are
+	// asked with, limiting answers to the documents those groups may read
+	Groups []string `mapstructure:"groups"`
+	Tenant string   `mapstructure:"tenant"`
+}
+
+// Enabled reports whether answers are verified against their citations
+func (c CitationsConfig) Enabled() bool {
+	return c.Verify != "" && c.Verify != "none"
+}
+
+// Enabled reports whether chunks get sparse vectors
+func (c SparseConfig) Enabled() bool {
+	return c.Encoder != "" && c.Encoder != "none"
+}
+
+// RoutingConfig sends the chunk vectors of file categories to indexes or
+// namespaces of their own, each embedded by its own deployment, so the
+// embedding model and dimension can differ by content type. Searches fan
+// out to every route and merge the matches by score.
+type RoutingConfig struct {
+	// Categories route the chunks of the file categories named (code,
+	// document, diagram, ...); set in config.yaml under
+	// routing.categories. Categories not listed stay in the Pinecone index.
+	Categories map[string]CategoryRoute `mapstructure:"categories"`
+}
+
+// CategoryRoute is where the chunk vectors of a file category are stored
+type CategoryRoute struct {
+	Namespace            string `mapstructure:"namespace"`             // chunk namespace in the route's index
+	IndexName            string `mapstructure:"index_name"`            // empty for PINECONE_INDEX_NAME
+	IndexHost            string `mapstructure:"index_host"`            // empty to look up the host of index_name
+	Dimension            int    `mapstructure:"dimension"`             // zero for PINECONE_DIMENSION
+	EmbeddingsDeployment string `mapstructure:"embeddings_deployment"` // empty for AZURE_OPENAI_EMBEDDINGS_DEPLOYMENT
+}
+
+// SameIndex reports whether the route stores its vectors in the Pinecone index
+func (r CategoryRoute) SameIndex(pinecone PineconeConfig) bool {
+	return r.IndexHost == "" && (r.IndexName == "" || r.IndexName == pinecone.IndexName)
+}
+
+// MathConfig contains configuration of formula handling: chunks holding
+// LaTeX, MathML or Unicode math are tagged has_math, and formulas are
+// optionally described in words by the chat deployment
+type MathConfig struct {
+	Describe    bool `mapstructure:"describe"`
+	MaxFormulas int  `mapstructure:"max_formulas"` // formulas described per document
+}
+
+// LogFilesConfig contains configuration of log file ingestion: .log files
+// are split into sections of entries close in time, and their chunks carry
+// the time span and severity levels of their entries
+type LogFilesConfig struct {
+	Window      time.Duration `mapstructure:"window"`       // longest time span of a section, 0 splits by lines only
+	WindowLines int           `mapstructure:"window_lines"` // most lines of a section
+	MaxBytes    int64         `mapstructure:"max_bytes"`    // only the last MaxBytes of a log are indexed
+}
+
+// DataFilesConfig contains configuration of data file ingestion: the
+// tables of SQLite databases and Parquet files are described with their
+// columns and a sample of their rows
+type DataFilesConfig struct {
+	SampleRows int `mapstructure:"sample_rows"` // rows sampled per table, 0 describes schemas only
+}
+
+// ConversionConfig contains configuration of the external converter that
+// turns documents without a native extractor, such as legacy .doc, .ppt and
+// .xls files, into PDF before extraction: LibreOffice run headless, or a
+// conversion service. Conversion is disabled unless one of them is set.
+type ConversionConfig struct {
+	SofficePath   string        `mapstructure:"soffice_path"`   // LibreOffice soffice binary
+	URL           string        `mapstructure:"url"`            // conversion service, used instead of soffice when set
+	Timeout       time.Duration `mapstructure:"timeout"`        // longest a conversion may take
+	MaxConcurrent int           `mapstructure:"max_concurrent"` // conversions run at once
+	MaxOutput     int64         `mapstructure:"max_output"`     // largest converted PDF in bytes
+	Extensions    []string      `mapstructure:"extensions"`     // extensions converted, without the dot
+}
+
+// PluginsConfig contains configuration of the WebAssembly processor
+// plugins loaded at startup. Plugins are disabled when Dir is empty.
+type PluginsConfig struct {
+	Dir       string        `mapstructure:"dir"`        // directory of .wasm processor modules
+	Timeout   time.Duration `mapstructure:"timeout"`    // longest a plugin call may take
+	MaxMemory int64         `mapstructure:"max_memory"` // most memory a plugin instance may use, in bytes
+}
+
+// MalwareConfig contains configuration of the malware scan files pass
+// before they are processed: with ClamAV's clamd, or a scan API
+type MalwareConfig struct {
+	Scanner       string        `mapstructure:"scanner"`        // none, clamav or api
+	ClamAVAddress string        `mapstructure:"clamav_address"` // clamd unix socket path, or host:port
+	APIURL        string        `mapstructure:"api_url"`        // scan API taking a multipart "file" upload
+	APIKey        string        `mapstructure:"api_key"`
+	Timeout       time.Duration `mapstructure:"timeout"`        // longest a scan may take
+	Action        string        `mapstructure:"action"`         // reject or quarantine flagged files
+	QuarantineDir string        `mapstructure:"quarantine_dir"` // where quarantined files are moved
+	FailOpen      bool          `mapstructure:"fail_open"`      // process files the scanner could not scan
+}
+
+// ModerationConfig contains configuration of the safety classification of
+// queries and generated answers: with Azure AI Content Safety, or a
+// classifier API. Text with a category at or above the threshold severity
+// is blocked, answered with a warning, or only logged.
+type ModerationConfig struct {
+	Classifier string        `mapstructure:"classifier"` // none, azure or api
+	Endpoint   string        `mapstructure:"endpoint"`   // Content Safety resource endpoint, or the classifier API URL
+	APIKey     string        `mapstructure:"api_key"`
+	Timeout    time.Duration `mapstructure:"timeout"`    // longest a classification may take
+	FailOpen   bool          `mapstructure:"fail_open"`  // answer when the classifier fails
+	Action     string        `mapstructure:"action"`     // block, warn or log
+	Threshold  int           `mapstructure:"threshold"`  // severity, 1 to 7, from which a category flags text
+	Queries    bool          `mapstructure:"queries"`    // classify queries
+	Answers    bool          `mapstructure:"answers"`    // classify answers
+	Categories []string      `mapstructure:"categories"` // categories checked, such as hate or violence; empty checks all
+	// Tenants change the moderation of the tenants named by the
+	// X-Tenant-ID header; set in config.yaml under moderation.tenants
+	Tenants map[string]ModerationOverride `mapstructure:"tenants"`
+}
+
+// ModerationOverride changes the moderation of a tenant; fields left unset
+// keep the service-wide setting
+type ModerationOverride struct {
+	Action     string   `mapstructure:"action"`
+	Threshold  int      `mapstructure:"threshold"`
+	Queries    *bool    `mapstructure:"queries"`
+	Answers    *bool    `mapstructure:"answers"`
+	Categories []string `mapstructure:"categories"`
+}
+
+// PipelineConfig contains the custom stages added to the document pipeline
+type PipelineConfig struct {
+	Webhooks []WebhookStage `mapstructure:"webhooks"` // run in the order listed
+}
+
+// WebhookStage is a custom pipeline stage calling an HTTP endpoint after
+// one of the built-in stages. The endpoint may add vector metadata, skip
+// the document or, after the chunk stage, rewrite or drop chunks.
+type WebhookStage struct {
+	Name     string        `mapstructure:"name"`
+	After    string        `mapstructure:"after"` // scan, extract, enrich, chunk, embed or store
+	URL      string        `mapstructure:"url"`
+	Timeout  time.Duration `mapstructure:"timeout"`   // 0 is 30s
+	FailOpen bool          `mapstructure:"fail_open"` // keep processing when the endpoint fails
+}
+
+// PipelineStages lists the stages a webhook stage can follow, in order
+var PipelineStages = []string{"scan", "extract", "enrich", "chunk", "embed", "store"}
+
+// GatewayConfig contains API gateway authentication and rate limiting configuration
+type GatewayConfig struct {
+	Port int `mapstructure:"port"`
+	// APIKeys are keys calling without an identity or scopes; without
+	// them or Clients, authentication is disabled
+	APIKeys []string `mapstructure:"api_keys"`
+	// Clients are API clients with their own key, scopes and identity;
+	// set in config.yaml under gateway.clients
+	Clients   []GatewayClient `mapstructure:"clients"`
+	RateLimit float64         `mapstructure:"rate_limit"` // requests per second per client, 0 disables
+	RateBurst int             `mapstructure:"rate_burst"`
+}
+
+// AuthEnabled reports whether the gateway requires an API key
+func (c GatewayConfig) AuthEnabled() bool {
+	return len(c.APIKeys) > 0 || len(c.Clients) > 0
+}
+
+// Scopes a gateway client can be granted
+const (
+	// ScopeAdmin allows the /v1/admin routes
+	ScopeAdmin = "admin"
+	// ScopeDelegate lets a client name the user it calls for in the
+	// X-User-ID, X-User-Groups and X-Tenant-ID headers, as the MCP server
+	// and authenticating proxies do; other clients call as their own
+	// identity
+	ScopeDelegate = "delegate"
+)
+
+// GatewayScopes are the scopes a gateway client can be granted
+var GatewayScopes = []string{ScopeAdmin, ScopeDelegate}
+
+// GatewayClient is an API client of the gateway with its own key
+type GatewayClient struct {
+	Name   string   `mapstructure:"name"`
+	Key    string   `mapstructure:"key"`
+	Scopes []string `mapstructure:"scopes"` // of GatewayScopes
+	// UserID, Groups and Tenant are the identity its requests are made
+	// for, unless it has the delegate scope and names another
+	UserID string   `mapstructure:"user_id"`
+	Groups []string `mapstructure:"groups"`
+	Tenant string   `mapstructure:"tenant"`
+}
+
+// HasScope reports whether the client was granted scope
+func (c GatewayClient) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// CORSConfig lets browser frontends on other origins call the gateway and
+// query APIs
+type CORSConfig struct {
+	AllowedOrigins   []string      `mapstructure:"allowed_origins"`   // such as https://app.example.com, or * for any; empty disables CORS
+	AllowedMethods   []string      `mapstructure:"allowed_methods"`   // methods allowed in cross-origin requests
+	AllowedHeaders   []string      `mapstructure:"allowed_headers"`   // request headers allowed in cross-origin requests
+	ExposedHeaders   []string      `mapstructure:"exposed_headers"`   // response headers scripts may read
+	AllowCredentials bool          `mapstructure:"allow_credentials"` // let requests carry cookies and client certificates
+	MaxAge           time.Duration `mapstructure:"max_age"`           // how long browsers cache a preflight response
+}
+
+// SecurityHeadersConfig contains the security headers set on responses of
+// the gateway and query APIs
+type SecurityHeadersConfig struct {
+	Enabled               bool          `mapstructure:"enabled"`
+	ContentSecurityPolicy string        `mapstructure:"content_security_policy"` // empty sends none
+	FrameOptions          string        `mapstructure:"frame_options"`           // DENY or SAMEORIGIN
+	HSTSMaxAge            time.Duration `mapstructure:"hsts_max_age"`            // Strict-Transport-Security on HTTPS responses; 0 sends none
+}
+
+// IngestConfig bounds the requests of the orchestrator's ingestion
+// endpoints and the files each tenant, named by the X-Tenant-ID header, may
+// queue for indexing per UTC day. Zero is unlimited.
+type IngestConfig struct {
+	MaxRequestBytes int64 `mapstructure:"max_request_bytes"` // request body size
+	DailyFiles      int   `mapstructure:"daily_files"`       // files queued per tenant
+	DailyBytes      int64 `mapstructure:"daily_bytes"`       // total size of the files queued per tenant
+	// Tenants give tenants quotas of their own, replacing daily_files and
+	// daily_bytes, and refuse tenants not listed; set in config.yaml under
+	// ingest.tenants
+	Tenants map[string]IngestQuota `mapstructure:"tenants"`
+}
+
+// IngestQuota is the daily quota of a tenant; zero is unlimited
+type IngestQuota struct {
+	DailyFiles int   `mapstructure:"daily_files"`
+	DailyBytes int64 `mapstructure:"daily_bytes"`
+}
+
+// RegistryConfig contains document registry configuration
+type RegistryConfig struct {
+	Backend   string `mapstructure:"backend"` // memory or redis
+	KeyPrefix string `mapstructure:"key_prefix"`
+}
+
+// ScanConfig contains directory scanning configuration
+type ScanConfig struct {
+	FollowSymlinks bool `mapstructure:"follow_symlinks"` // index symlink targets instead of skipping links
+	DedupHardlinks bool `mapstructure:"dedup_hardlinks"` // index a file with several hard links once
+	// ExcludeDirs are directories left out of scans, such as the templates
+	// and daily notes of a vault: a name or a path relative to the scanned
+	// directory, either of which may be a glob pattern
+	ExcludeDirs []string `mapstructure:"exclude_dirs"`
+	// HashAlgorithm hashes files for change detection and duplicate
+	// matching: sha256, or blake3 or xxh64 for speed on huge files
+	HashAlgorithm string `mapstructure:"hash_algorithm"`
+	// MatchHashAlgorithms are also computed, in the same read, to match
+	// documents indexed by deployments using them, such as sha256 while
+	// moving to blake3
+	MatchHashAlgorithms []string `mapstructure:"match_hash_algorithms"`
+	// ManifestBackend keeps the last scan manifest of each directory, which
+	// change scans compare against: none, memory, file or redis
+	ManifestBackend string `mapstructure:"manifest_backend"`
+	ManifestDir     string `mapstructure:"manifest_dir"` // directory of the file backend
+	ManifestKey     string `mapstructure:"manifest_key"` // Redis hash of the redis backend
+}
+
+// ExtractionConfig contains content extraction size limits
+type ExtractionConfig struct {
+	MaxFileSize int64  `mapstructure:"max_file_size"` // larger files are skipped, 0 disables the limit
+	MaxInMemory int64  `mapstructure:"max_in_memory"` // extracted content above this spills to a temp file
+	TempDir     string `mapstructure:"temp_dir"`      // empty uses the system temp directory
+	Normalize   bool   `mapstructure:"normalize"`     // decode, NFC-normalize and clean extracted text
+	// ExternalTimeout is the longest an external extractor may take
+	ExternalTimeout time.Duration `mapstructure:"external_timeout"`
+	// Timeout is the longest the extraction of a file may take, 0 for no
+	// limit; Timeouts override it for the processor categories they name
+	// (config file only)
+	Timeout  time.Duration            `mapstructure:"timeout"`
+	Timeouts map[string]time.Duration `mapstructure:"timeouts"`
+	// Sandbox names the processors, such as document, whose extractions
+	// run in a subprocess of at most SandboxMaxMemory bytes, so a file
+	// crashing or exhausting the parser does not take the service down
+	Sandbox          []string `mapstructure:"sandbox"`
+	SandboxMaxMemory int64    `mapstructure:"sandbox_max_memory"`
+}
+
+// ChunkStoreConfig contains configuration of the store holding chunk
+// content too large for vector metadata
+type ChunkStoreConfig struct {
+	Backend   string `mapstructure:"backend"` // none, memory or redis; none only truncates
+	KeyPrefix string `mapstructure:"key_prefix"`
+}
+
+// RetrievalConfig contains configuration of how queries find chunks
+type RetrievalConfig struct {
+	Mode          string `mapstructure:"mode"`           // chunks, or two_stage to find documents by summary first
+	Documents     int    `mapstructure:"documents"`      // documents kept by the first two_stage step
+	ContextWindow int    `mapstructure:"context_window"` // neighbouring chunks added on each side of a match
+	// InjectionThreshold is the prompt-injection score, from 0 to 1, from
+	// which a source is reported in the query trace
+	InjectionThreshold float64 `mapstructure:"injection_threshold"`
+	// SanitizeInjections removes the instructing phrases of reported
+	// sources before they are given to the model
+	SanitizeInjections bool `mapstructure:"sanitize_injections"`
+	// MinScore drops chunk matches scoring below it before the prompt is
+	// assembled; zero keeps every match
+	MinScore float64 `mapstructure:"min_score"`
+	// MaxDistance drops chunk matches farther from the query than it:
+	// 1 - score for cosine indexes, the score for euclidean ones; zero
+	// keeps every match
+	MaxDistance float64 `mapstructure:"max_distance"`
+	// ContextTokens is the token budget of the sources in an answer
+	// prompt; zero puts every source in
+	ContextTokens int `mapstructure:"context_tokens"`
+	// FreshnessHalfLife is the age in days, since a document's file was
+	// last modified, at which the decaying part of its match scores halves;
+	// zero ranks by relevance alone
+	FreshnessHalfLife int `mapstructure:"freshness_half_life"`
+	// FreshnessWeight is the share of a match score that decays with age,
+	// from 0 to 1; the oldest documents keep the rest
+	FreshnessWeight float64 `mapstructure:"freshness_weight"`
+	// Profiles are named sets of defaults a query selects with its
+	// profile field; set in config.yaml under retrieval.profiles
+	Profiles map[string]RetrievalProfile `mapstructure:"profiles"`
+}
+
+// RetrievalProfile holds the retrieval defaults of a query profile. Unset
+// fields keep the request's value or the service default.
+type RetrievalProfile struct {
+	TopK          int      `mapstructure:"top_k"`
+	Mode          string   `mapstructure:"mode"`
+	ContextWindow *int     `mapstructure:"context_window"`
+	MinScore      *float64 `mapstructure:"min_score"`
+	MaxDistance   *float64 `mapstructure:"max_distance"`
+	// FreshnessHalfLife is in days; zero turns freshness weighting off
+	FreshnessHalfLife *int `mapstructure:"freshness_half_life"`
+}
+
+// WALConfig contains configuration of the write-ahead log of vectors
+// waiting to be upserted
+type WALConfig struct {
+	Backend  string `mapstructure:"backend"` // none, file or redis
+	FilePath string `mapstructure:"file_path"`
+	Stream   string `mapstructure:"stream"`
+	// ReplayInterval is how often the orchestrator replays the entries of
+	// writers that stopped since it started, as a replica that crashed;
+	// 0 replays at startup only
+	ReplayInterval time.Duration `mapstructure:"replay_interval"`
+}
+
+// ContentStoreConfig contains configuration of the store keeping the full
+// extracted text of documents
+type ContentStoreConfig struct {
+	Backend         string `mapstructure:"backend"` // none, file or s3
+	Directory       string `mapstructure:"directory"`
+	S3Bucket        string `mapstructure:"s3_bucket"`
+	S3Region        string `mapstructure:"s3_region"`
+	S3Endpoint      string `mapstructure:"s3_endpoint"` // S3 compatible service such as MinIO; empty for AWS
+	S3Prefix        string `mapstructure:"s3_prefix"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	SessionToken    string `mapstructure:"session_token"`
+	ThumbnailSize   int    `mapstructure:"thumbnail_size"` // longest side of image thumbnails in pixels; 0 disables them
+}
+
+// DigestConfig contains configuration of scheduled digest reports and
+// their email delivery
+type DigestConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	ReportsFile  string `mapstructure:"reports_file"` // YAML file defining the reports
+	SMTPHost     string `mapstructure:"smtp_host"`
+	SMTPPort     int    `mapstructure:"smtp_port"`
+	SMTPUsername string `mapstructure:"smtp_username"`
+	SMTPPassword string `mapstructure:"smtp_password"`
+	SMTPFrom     string `mapstructure:"smtp_from"`
+}
+
+// LimitsConfig bounds the requests each process sends to a provider: how
+// many may be in flight and how many may start per minute. Zero is unlimited.
+type LimitsConfig struct {
+	AzureMaxConcurrent    int `mapstructure:"azure_max_concurrent"`
+	AzureRPM              int `mapstructure:"azure_rpm"`
+	PineconeMaxConcurrent int `mapstructure:"pinecone_max_concurrent"`
+	PineconeRPM           int `mapstructure:"pinecone_rpm"`
+}
+
+// ProviderHTTPConfig tunes the connections of the Azure OpenAI and
+// Pinecone clients. Idle connections are kept for reuse, so bulk indexing
+// does not open one per request. Zero timeouts do not time out.
+type ProviderHTTPConfig struct {
+	MaxIdleConnsPerHost   int               `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout       time.Duration     `mapstructure:"idle_conn_timeout"`
+	DialTimeout           time.Duration     `mapstructure:"dial_timeout"`
+	TLSHandshakeTimeout   time.Duration     `mapstructure:"tls_handshake_timeout"`
+	ResponseHeaderTimeout time.Duration     `mapstructure:"response_header_timeout"` // until a response starts
+	Azure                 AdapterHTTPConfig `mapstructure:"azure"`
+	Pinecone              AdapterHTTPConfig `mapstructure:"pinecone"`
+}
+
+// AdapterHTTPConfig configures the client of one provider
+type AdapterHTTPConfig struct {
+	Timeout time.Duration `mapstructure:"timeout"` // of a whole request, zero for none
+	Proxy   string        `mapstructure:"proxy"`   // proxy URL; empty uses HTTPS_PROXY and NO_PROXY
+	CAFile  string        `mapstructure:"ca_file"` // PEM bundle trusted besides the system roots
+}
+
+// EnrichmentConfig controls how indexing degrades when the optional vision
+// and summarization stages fail, and how skipped stages are repaired
+type EnrichmentConfig struct {
+	// FailureThreshold consecutive failures of a stage skip it for Cooldown
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	Cooldown         time.Duration `mapstructure:"cooldown"`
+	// RepairInterval is how often skipped stages are run again; zero
+	// disables the repair job
+	RepairInterval time.Duration `mapstructure:"repair_interval"`
+}
+
+// DLQConfig controls retries of failed files before they are added to the
+// dead-letter list
+type DLQConfig struct {
+	MaxRetries   int           `mapstructure:"max_retries"`   // retries after the first failure
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"` // wait before the first retry, doubling
+}
+
+// SummaryStyles are the styles of generated summaries
+var SummaryStyles = []string{"abstract", "bullet", "technical", "executive"}
+
+// SummaryConfig controls the length, style and language of generated
+// document summaries
+type SummaryConfig struct {
+	MaxTokens   int     `mapstructure:"max_tokens"`
+	Temperature float64 `mapstructure:"temperature"`
+	Style       string  `mapstructure:"style"`    // one of SummaryStyles
+	Language    string  `mapstructure:"language"` // empty for the language of the content
+	// Categories overrides the defaults for documents of a file category
+	// (document, code, image, ...); set in config.yaml under
+	// summary.categories
+	Categories map[string]SummaryOverride `mapstructure:"categories"`
+}
+
+// SummaryOverride changes the summary defaults for a file category; unset
+// fields keep the defaults
+type SummaryOverride struct {
+	MaxTokens   int      `mapstructure:"max_tokens"`
+	Temperature *float64 `mapstructure:"temperature"`
+	Style       string   `mapstructure:"style"`
+	Language    string   `mapstructure:"language"`
+}
+
+// ForCategory returns the summary settings of a file category
+func (c SummaryConfig) ForCategory(category string) SummaryConfig {
+	settings := c
+	settings.Categories = nil
+	override, ok := c.Categories[category]
+	if !ok {
+		return settings
+	}
+	if override.MaxTokens > 0 {
+		settings.MaxTokens = override.MaxTokens
+	}
+	if override.Temperature != nil {
+		settings.Temperature = *override.Temperature
+	}
+	if override.Style != "" {
+		settings.Style = override.Style
+	}
+	if override.Language != "" {
+		settings.Language = override.Language
+	}
+	return settings
+}
+
+// ProcessingPolicy changes how the orchestrator processes some files;
+// unset fields keep the default behavior
+type ProcessingPolicy struct {
+	Summarize    *bool `mapstructure:"summarize"`     // false skips summarization
+	Vision       *bool `mapstructure:"vision"`        // false skips image analysis
+	OCR          *bool `mapstructure:"ocr"`           // true adds the text detected in images
+	ChunkSize    int   `mapstructure:"chunk_size"`    // zero keeps CHUNK_SIZE
+	ChunkOverlap *int  `mapstructure:"chunk_overlap"` // unset keeps CHUNK_OVERLAP
+}
+
+// Policy returns the processing policy of a file of a category with an
+// extension: the category policy overridden by the extension policy
+func (c *Config) Policy(category, ext string) ProcessingPolicy {
+	policy := c.Policies[category]
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	override, ok := c.Policies[ext]
+	if !ok || ext == "" || ext == category {
+		return policy
+	}
+	if override.Summarize != nil {
+		policy.Summarize = override.Summarize
+	}
+	if override.Vision != nil {
+		policy.Vision = override.Vision
+	}
+	if override.OCR != nil {
+		policy.OCR = override.OCR
+	}
+	if override.ChunkSize > 0 {
+		policy.ChunkSize = override.ChunkSize
+	}
+	if override.ChunkOverlap != nil {
+		policy.ChunkOverlap = override.ChunkOverlap
+	}
+	return policy
+}
+
 // Load loads configuration from environment and config files
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
@@ -131,16 +1068,31 @@ func setDefaults() {
 
 	// Pinecone defaults
 	viper.SetDefault("pinecone.dimension", 1536)
+	viper.SetDefault("pinecone.metric", "cosine")
 	viper.SetDefault("pinecone.cloud", "aws")
 	viper.SetDefault("pinecone.region", "us-east-1")
 	viper.SetDefault("pinecone.use_namespaces", true)
+	viper.SetDefault("pinecone.metadata_limit", 40*1024)
+	viper.SetDefault("pinecone.summary_namespace", "summaries")
+	viper.SetDefault("pinecone.upsert_batch_size", 100)
+	viper.SetDefault("pinecone.upsert_concurrency", 4)
+	viper.SetDefault("pinecone.upsert_max_retries", 5)
+	viper.SetDefault("pinecone.transport", "http")
+	viper.SetDefault("pinecone.deletable_indexes", []string{})
 
 	// Application defaults
 	viper.SetDefault("app.data_directory", "./data/diagrams")
+	viper.SetDefault("app.ingest_roots", []string{})
 	viper.SetDefault("app.log_level", "info")
 	viper.SetDefault("app.chunk_size", 1000)
 	viper.SetDefault("app.chunk_overlap", 200)
 	viper.SetDefault("app.skip_existing_documents", true)
+	viper.SetDefault("app.summary_vectors", false)
+	viper.SetDefault("app.startup_wait", 2*time.Minute)
+	viper.SetDefault("app.ingest_workers", 2)
+	viper.SetDefault("app.ingest_queue_size", 10000)
+	viper.SetDefault("app.stream_chunking_bytes", 64*1024*1024)
+	viper.SetDefault("app.stream_batch_chunks", 100)
 
 	// Redis defaults
 	viper.SetDefault("redis.host", "localhost")
@@ -152,6 +1104,289 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", 30*time.Second)
 	viper.SetDefault("server.write_timeout", 30*time.Second)
 
+	// Dedup defaults
+	viper.SetDefault("dedup.enabled", true)
+	viper.SetDefault("dedup.near_duplicate", false)
+	viper.SetDefault("dedup.similarity_threshold", 0.9)
+	viper.SetDefault("dedup.minhash_permutations", 128)
+	viper.SetDefault("dedup.minhash_bands", 32)
+
+	// ACL defaults
+	viper.SetDefault("acl.default_visibility", "public")
+
+	// Audit defaults
+	viper.SetDefault("audit.enabled", false)
+	viper.SetDefault("audit.backend", "redis")
+	viper.SetDefault("audit.stream", "repograph:audit")
+	viper.SetDefault("audit.file_path", "./data/audit/audit.log")
+
+	// Embedding defaults
+	viper.SetDefault("embedding.max_batch_size", 16)
+	viper.SetDefault("embedding.max_batch_wait", 20*time.Millisecond)
+	viper.SetDefault("embedding.max_tokens", 8191)
+	viper.SetDefault("embedding.overflow", "split")
+
+	// Registry defaults
+	viper.SetDefault("registry.backend", "memory")
+	viper.SetDefault("registry.key_prefix", "registry:documents")
+
+	// Scan defaults
+	viper.SetDefault("scan.follow_symlinks", false)
+	viper.SetDefault("scan.dedup_hardlinks", true)
+	viper.SetDefault("scan.exclude_dirs", []string{".obsidian", ".trash"})
+	viper.SetDefault("scan.hash_algorithm", "sha256")
+	viper.SetDefault("scan.match_hash_algorithms", []string{})
+	viper.SetDefault("scan.manifest_backend", "file")
+	viper.SetDefault("scan.manifest_dir", "./data/manifests")
+	viper.SetDefault("scan.manifest_key", "scan:manifests")
+
+	// Chunk store defaults
+	viper.SetDefault("chunk_store.backend", "none")
+	viper.SetDefault("chunk_store.key_prefix", "chunks")
+
+	// Retrieval defaults
+	viper.SetDefault("retrieval.mode", "chunks")
+	viper.SetDefault("retrieval.documents", 10)
+	viper.SetDefault("retrieval.context_window", 0)
+	viper.SetDefault("retrieval.injection_threshold", 0.5)
+	viper.SetDefault("retrieval.sanitize_injections", false)
+	viper.SetDefault("retrieval.min_score", 0)
+	viper.SetDefault("retrieval.max_distance", 0)
+	viper.SetDefault("retrieval.context_tokens", 6000)
+	viper.SetDefault("retrieval.freshness_half_life", 0)
+	viper.SetDefault("retrieval.freshness_weight", 0.5)
+
+	// Write-ahead log defaults
+	viper.SetDefault("wal.backend", "none")
+	viper.SetDefault("wal.file_path", "./data/wal/vectors.wal")
+	viper.SetDefault("wal.stream", "repograph:wal")
+	viper.SetDefault("wal.replay_interval", 5*time.Minute)
+
+	// Content store defaults
+	viper.SetDefault("content_store.backend", "none")
+	viper.SetDefault("content_store.directory", "./data/content")
+	viper.SetDefault("content_store.s3_region", "us-east-1")
+	viper.SetDefault("content_store.s3_prefix", "content/")
+	viper.SetDefault("content_store.thumbnail_size", 256)
+
+	// Digest defaults
+	viper.SetDefault("digest.enabled", false)
+	viper.SetDefault("digest.reports_file", "./configs/digests.yaml")
+	viper.SetDefault("digest.smtp_port", 587)
+
+	// Provider limit defaults
+	viper.SetDefault("limits.azure_max_concurrent", 0)
+	viper.SetDefault("limits.azure_rpm", 0)
+	viper.SetDefault("limits.pinecone_max_concurrent", 0)
+	viper.SetDefault("limits.pinecone_rpm", 0)
+
+	// Provider connection defaults
+	viper.SetDefault("provider_http.max_idle_conns_per_host", 32)
+	viper.SetDefault("provider_http.idle_conn_timeout", 90*time.Second)
+	viper.SetDefault("provider_http.dial_timeout", 10*time.Second)
+	viper.SetDefault("provider_http.tls_handshake_timeout", 10*time.Second)
+	viper.SetDefault("provider_http.response_header_timeout", 0)
+	viper.SetDefault("provider_http.azure.timeout", 3*time.Minute)
+	viper.SetDefault("provider_http.pinecone.timeout", time.Minute)
+
+	// Enrichment defaults
+	viper.SetDefault("enrichment.failure_threshold", 3)
+	viper.SetDefault("enrichment.cooldown", time.Minute)
+	viper.SetDefault("enrichment.repair_interval", 5*time.Minute)
+
+	// Dead-letter defaults
+	viper.SetDefault("dlq.max_retries", 2)
+	viper.SetDefault("dlq.retry_backoff", time.Second)
+
+	// Summary defaults
+	viper.SetDefault("summary.max_tokens", 500)
+	viper.SetDefault("summary.temperature", 0.3)
+	viper.SetDefault("summary.style", "abstract")
+	viper.SetDefault("summary.language", "")
+
+	// Extraction defaults
+	viper.SetDefault("extraction.max_file_size", 100*1024*1024)
+	viper.SetDefault("extraction.max_in_memory", 8*1024*1024)
+	viper.SetDefault("extraction.temp_dir", "")
+	viper.SetDefault("extraction.normalize", true)
+	viper.SetDefault("extraction.external_timeout", 2*time.Minute)
+	viper.SetDefault("extraction.timeout", 5*time.Minute)
+	viper.SetDefault("extraction.sandbox", []string{})
+	viper.SetDefault("extraction.sandbox_max_memory", 1024*1024*1024)
+
+	// Gateway defaults
+	viper.SetDefault("gateway.port", 8080)
+	viper.SetDefault("gateway.api_keys", []string{})
+	viper.SetDefault("gateway.rate_limit", 10.0)
+	viper.SetDefault("gateway.rate_burst", 20)
+
+	// CORS and security header defaults
+	viper.SetDefault("cors.allowed_origins", []string{})
+	viper.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PUT", "PATCH", "DELETE"})
+	viper.SetDefault("cors.allowed_headers", []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID", "Idempotency-Key"})
+	viper.SetDefault("cors.exposed_headers", []string{"X-Request-ID", "Retry-After", "Idempotent-Replayed"})
+	viper.SetDefault("cors.allow_credentials", false)
+	viper.SetDefault("cors.max_age", 10*time.Minute)
+	viper.SetDefault("security_headers.enabled", true)
+	viper.SetDefault("security_headers.content_security_policy", "frame-ancestors 'none'")
+	viper.SetDefault("security_headers.frame_options", "DENY")
+	viper.SetDefault("security_headers.hsts_max_age", 180*24*time.Hour)
+
+	// Ingestion limit defaults
+	viper.SetDefault("ingest.max_request_bytes", 1024*1024)
+	viper.SetDefault("ingest.daily_files", 0)
+	viper.SetDefault("ingest.daily_bytes", 0)
+
+	// Vision defaults
+	viper.SetDefault("vision.max_concurrent", 4)
+	viper.SetDefault("vision.cache_size", 1000)
+	viper.SetDefault("vision.cache_ttl", 24*time.Hour)
+	viper.SetDefault("vision.max_image_bytes", 20*1024*1024)
+	viper.SetDefault("vision.describe_token_budget", 2500)
+	viper.SetDefault("vision.describe_max_tokens", 800)
+
+	// Image search defaults
+	viper.SetDefault("image_search.provider", "none")
+	viper.SetDefault("image_search.dimension", 1024)
+	viper.SetDefault("image_search.namespace", "images")
+	viper.SetDefault("image_search.top_k", 3)
+	viper.SetDefault("image_search.min_score", 0.2)
+
+	// Sparse vector defaults
+	viper.SetDefault("sparse.encoder", "none")
+	viper.SetDefault("sparse.alpha", 0.75)
+	viper.SetDefault("sparse.k1", 1.2)
+	viper.SetDefault("sparse.b", 0.75)
+	viper.SetDefault("sparse.avg_tokens", 200)
+
+	// Citations defaults
+	viper.SetDefault("citations.verify", "none")
+	viper.SetDefault("citations.min_overlap", 0.5)
+	viper.SetDefault("citations.unsupported", "flag")
+
+	// Structured output defaults
+	viper.SetDefault("structured_output.repair_retries", 2)
+
+	// Tools defaults
+	viper.SetDefault("tools.enabled", false)
+	viper.SetDefault("tools.max_iterations", 4)
+
+	// Agent defaults
+	viper.SetDefault("agent.max_steps", 4)
+	viper.SetDefault("agent.max_tokens", 30000)
+
+	// Topics defaults
+	viper.SetDefault("topics.enabled", false)
+	viper.SetDefault("topics.interval", 24*time.Hour)
+	viper.SetDefault("topics.clusters", 0)
+	viper.SetDefault("topics.max_documents", 5000)
+
+	// Analytics defaults
+	viper.SetDefault("analytics.enabled", false)
+	viper.SetDefault("analytics.retention", 30*24*time.Hour)
+	viper.SetDefault("analytics.keep_text", false)
+
+	// Popularity defaults
+	viper.SetDefault("popularity.enabled", false)
+	viper.SetDefault("popularity.boost", true)
+	viper.SetDefault("popularity.weight", 0.1)
+	viper.SetDefault("popularity.saturation", 20)
+
+	// Event stream defaults
+	viper.SetDefault("events.enabled", false)
+	viper.SetDefault("events.stream", "repograph:events")
+	viper.SetDefault("events.max_len", 100000)
+
+	// Lock defaults
+	viper.SetDefault("locks.enabled", false)
+	viper.SetDefault("locks.key_prefix", "repograph:locks")
+	viper.SetDefault("locks.ttl", 30*time.Second)
+
+	// Sharding defaults
+	viper.SetDefault("sharding.enabled", false)
+	viper.SetDefault("sharding.key_prefix", "repograph:queue")
+	viper.SetDefault("sharding.visibility_timeout", 5*time.Minute)
+	viper.SetDefault("sharding.max_deliveries", 3)
+	viper.SetDefault("sharding.poll_interval", 2*time.Second)
+
+	// Idempotency defaults
+	viper.SetDefault("idempotency.enabled", true)
+	viper.SetDefault("idempotency.ttl", 24*time.Hour)
+
+	// MCP defaults
+	viper.SetDefault("mcp.gateway_url", "http://localhost:8080")
+	viper.SetDefault("mcp.port", 8089)
+	viper.SetDefault("mcp.tools", []string{})
+	viper.SetDefault("mcp.api_keys", []string{})
+	viper.SetDefault("mcp.max_result_chars", 20000)
+	viper.SetDefault("mcp.max_top_k", 20)
+	viper.SetDefault("mcp.timeout", 60*time.Second)
+
+	// Slack defaults
+	viper.SetDefault("slack.api_url", "https://slack.com/api")
+	viper.SetDefault("slack.any_workspace", false)
+	viper.SetDefault("slack.top_k", 5)
+	viper.SetDefault("slack.timeout", 2*time.Minute)
+
+	// Math defaults
+	viper.SetDefault("math.describe", false)
+	viper.SetDefault("math.max_formulas", 20)
+
+	// Log file defaults
+	viper.SetDefault("log_files.window", 5*time.Minute)
+	viper.SetDefault("log_files.window_lines", 200)
+	viper.SetDefault("log_files.max_bytes", 16*1024*1024)
+
+	// Data file defaults
+	viper.SetDefault("data_files.sample_rows", 5)
+
+	// Conversion defaults
+	viper.SetDefault("conversion.soffice_path", "")
+	viper.SetDefault("conversion.url", "")
+	viper.SetDefault("conversion.timeout", 2*time.Minute)
+	viper.SetDefault("conversion.max_concurrent", 2)
+	viper.SetDefault("conversion.max_output", 100*1024*1024)
+	viper.SetDefault("conversion.extensions", []string{
+		"doc", "dot", "ppt", "pps", "xls", "xlt", "rtf", "odt", "odp", "ods", "odg",
+		"pptx", "xlsx", "wpd", "wps", "vsd", "pub", "pages", "key", "numbers",
+	})
+
+	// Processor plugin defaults
+	viper.SetDefault("plugins.dir", "")
+	viper.SetDefault("plugins.timeout", time.Minute)
+	viper.SetDefault("plugins.max_memory", 256*1024*1024)
+
+	// Malware scan defaults
+	viper.SetDefault("malware.scanner", "none")
+	viper.SetDefault("malware.clamav_address", "/var/run/clamav/clamd.ctl")
+	viper.SetDefault("malware.api_url", "")
+	viper.SetDefault("malware.api_key", "")
+	viper.SetDefault("malware.timeout", 2*time.Minute)
+	viper.SetDefault("malware.action", "reject")
+	viper.SetDefault("malware.quarantine_dir", "")
+	viper.SetDefault("malware.fail_open", false)
+
+	// Moderation defaults
+	viper.SetDefault("tls.cert_file", "")
+	viper.SetDefault("tls.key_file", "")
+	viper.SetDefault("tls.client_ca_file", "")
+	viper.SetDefault("tls.ca_file", "")
+	viper.SetDefault("tls.client_cert_file", "")
+	viper.SetDefault("tls.client_key_file", "")
+	viper.SetDefault("tls.min_version", "1.2")
+	viper.SetDefault("tls.reload_interval", time.Minute)
+
+	viper.SetDefault("moderation.classifier", "none")
+	viper.SetDefault("moderation.endpoint", "")
+	viper.SetDefault("moderation.api_key", "")
+	viper.SetDefault("moderation.timeout", 10*time.Second)
+	viper.SetDefault("moderation.fail_open", false)
+	viper.SetDefault("moderation.action", "block")
+	viper.SetDefault("moderation.threshold", 4)
+	viper.SetDefault("moderation.queries", true)
+	viper.SetDefault("moderation.answers", true)
+	viper.SetDefault("moderation.categories", []string{})
+
 	// Service URLs defaults
 	viper.SetDefault("services.document_scanner_url", "http://localhost:8081")
 	viper.SetDefault("services.content_extractor_url", "http://localhost:8082")
@@ -174,29 +1409,45 @@ func bindEnvVariables() {
 	viper.BindEnv("azure.openai_embeddings_deployment", "AZURE_OPENAI_EMBEDDINGS_DEPLOYMENT") //nolint:errcheck
 	viper.BindEnv("azure.openai_api_version", "AZURE_OPENAI_API_VERSION")                     //nolint:errcheck
 	viper.BindEnv("azure.openai_chat_deployment", "AZURE_OPENAI_CHAT_DEPLOYMENT")             //nolint:errcheck
+	viper.BindEnv("azure.openai_vision_deployment", "AZURE_OPENAI_VISION_DEPLOYMENT")         //nolint:errcheck
 
 	// Google
 	viper.BindEnv("google.vision_api_key", "GOOGLE_VISION_API_KEY")                   //nolint:errcheck
 	viper.BindEnv("google.application_credentials", "GOOGLE_APPLICATION_CREDENTIALS") //nolint:errcheck
 
 	// Pinecone
-	viper.BindEnv("pinecone.api_key", "PINECONE_API_KEY")               //nolint:errcheck
-	viper.BindEnv("pinecone.host", "PINECONE_HOST")                     //nolint:errcheck
-	viper.BindEnv("pinecone.index_name", "PINECONE_INDEX_NAME")         //nolint:errcheck
-	viper.BindEnv("pinecone.dimension", "PINECONE_DIMENSION")           //nolint:errcheck
-	viper.BindEnv("pinecone.cloud", "PINECONE_CLOUD")                   //nolint:errcheck
-	viper.BindEnv("pinecone.region", "PINECONE_REGION")                 //nolint:errcheck
-	viper.BindEnv("pinecone.use_namespaces", "PINECONE_USE_NAMESPACES") //nolint:errcheck
+	viper.BindEnv("pinecone.api_key", "PINECONE_API_KEY")                       //nolint:errcheck
+	viper.BindEnv("pinecone.host", "PINECONE_HOST")                             //nolint:errcheck
+	viper.BindEnv("pinecone.index_name", "PINECONE_INDEX_NAME")                 //nolint:errcheck
+	viper.BindEnv("pinecone.dimension", "PINECONE_DIMENSION")                   //nolint:errcheck
+	viper.BindEnv("pinecone.metric", "PINECONE_METRIC")                         //nolint:errcheck
+	viper.BindEnv("pinecone.cloud", "PINECONE_CLOUD")                           //nolint:errcheck
+	viper.BindEnv("pinecone.region", "PINECONE_REGION")                         //nolint:errcheck
+	viper.BindEnv("pinecone.use_namespaces", "PINECONE_USE_NAMESPACES")         //nolint:errcheck
+	viper.BindEnv("pinecone.metadata_limit", "PINECONE_METADATA_LIMIT")         //nolint:errcheck
+	viper.BindEnv("pinecone.summary_namespace", "PINECONE_SUMMARY_NAMESPACE")   //nolint:errcheck
+	viper.BindEnv("pinecone.upsert_batch_size", "PINECONE_UPSERT_BATCH_SIZE")   //nolint:errcheck
+	viper.BindEnv("pinecone.upsert_concurrency", "PINECONE_UPSERT_CONCURRENCY") //nolint:errcheck
+	viper.BindEnv("pinecone.upsert_max_retries", "PINECONE_UPSERT_MAX_RETRIES") //nolint:errcheck
+	viper.BindEnv("pinecone.transport", "PINECONE_TRANSPORT")                   //nolint:errcheck
+	viper.BindEnv("pinecone.deletable_indexes", "PINECONE_DELETABLE_INDEXES")   //nolint:errcheck
 
 	// GitHub
 	viper.BindEnv("github.token", "GITHUB_TOKEN") //nolint:errcheck
 
 	// App
 	viper.BindEnv("app.data_directory", "DATA_DIRECTORY")                   //nolint:errcheck
+	viper.BindEnv("app.ingest_roots", "INGEST_ROOTS")                       //nolint:errcheck
 	viper.BindEnv("app.log_level", "LOG_LEVEL")                             //nolint:errcheck
 	viper.BindEnv("app.chunk_size", "CHUNK_SIZE")                           //nolint:errcheck
 	viper.BindEnv("app.chunk_overlap", "CHUNK_OVERLAP")                     //nolint:errcheck
 	viper.BindEnv("app.skip_existing_documents", "SKIP_EXISTING_DOCUMENTS") //nolint:errcheck
+	viper.BindEnv("app.summary_vectors", "SUMMARY_VECTORS")                 //nolint:errcheck
+	viper.BindEnv("app.startup_wait", "STARTUP_WAIT")                       //nolint:errcheck
+	viper.BindEnv("app.ingest_workers", "INGEST_WORKERS")                   //nolint:errcheck
+	viper.BindEnv("app.ingest_queue_size", "INGEST_QUEUE_SIZE")             //nolint:errcheck
+	viper.BindEnv("app.stream_chunking_bytes", "STREAM_CHUNKING_BYTES")     //nolint:errcheck
+	viper.BindEnv("app.stream_batch_chunks", "STREAM_BATCH_CHUNKS")         //nolint:errcheck
 
 	// Redis
 	viper.BindEnv("redis.host", "REDIS_HOST")         //nolint:errcheck
@@ -215,6 +1466,305 @@ func bindEnvVariables() {
 	viper.BindEnv("services.vector_store_service_url", "VECTOR_STORE_SERVICE_URL")   //nolint:errcheck
 	viper.BindEnv("services.query_service_url", "QUERY_SERVICE_URL")                 //nolint:errcheck
 	viper.BindEnv("services.orchestrator_service_url", "ORCHESTRATOR_SERVICE_URL")   //nolint:errcheck
+	viper.BindEnv("services.admin_token", "ADMIN_TOKEN")                             //nolint:errcheck
+
+	// Dedup
+	viper.BindEnv("dedup.enabled", "DEDUP_ENABLED")                           //nolint:errcheck
+	viper.BindEnv("dedup.near_duplicate", "DEDUP_NEAR_DUPLICATE")             //nolint:errcheck
+	viper.BindEnv("dedup.similarity_threshold", "DEDUP_SIMILARITY_THRESHOLD") //nolint:errcheck
+
+	// ACL
+	viper.BindEnv("acl.default_owner", "ACL_DEFAULT_OWNER")           //nolint:errcheck
+	viper.BindEnv("acl.default_groups", "ACL_DEFAULT_GROUPS")         //nolint:errcheck
+	viper.BindEnv("acl.default_visibility", "ACL_DEFAULT_VISIBILITY") //nolint:errcheck
+
+	// Audit
+	viper.BindEnv("audit.enabled", "AUDIT_ENABLED")     //nolint:errcheck
+	viper.BindEnv("audit.backend", "AUDIT_BACKEND")     //nolint:errcheck
+	viper.BindEnv("audit.file_path", "AUDIT_FILE_PATH") //nolint:errcheck
+
+	// Embedding
+	viper.BindEnv("embedding.max_batch_size", "EMBEDDING_MAX_BATCH_SIZE")           //nolint:errcheck
+	viper.BindEnv("embedding.max_batch_wait", "EMBEDDING_MAX_BATCH_WAIT")           //nolint:errcheck
+	viper.BindEnv("embedding.fallback_endpoint", "EMBEDDING_FALLBACK_ENDPOINT")     //nolint:errcheck
+	viper.BindEnv("embedding.fallback_api_key", "EMBEDDING_FALLBACK_API_KEY")       //nolint:errcheck
+	viper.BindEnv("embedding.fallback_deployment", "EMBEDDING_FALLBACK_DEPLOYMENT") //nolint:errcheck
+	viper.BindEnv("embedding.max_tokens", "EMBEDDING_MAX_TOKENS")                   //nolint:errcheck
+	viper.BindEnv("embedding.overflow", "EMBEDDING_OVERFLOW")                       //nolint:errcheck
+
+	// Registry
+	viper.BindEnv("registry.backend", "REGISTRY_BACKEND") //nolint:errcheck
+
+	// Scan
+	viper.BindEnv("scan.follow_symlinks", "SCAN_FOLLOW_SYMLINKS")             //nolint:errcheck
+	viper.BindEnv("scan.dedup_hardlinks", "SCAN_DEDUP_HARDLINKS")             //nolint:errcheck
+	viper.BindEnv("scan.exclude_dirs", "SCAN_EXCLUDE_DIRS")                   //nolint:errcheck
+	viper.BindEnv("scan.hash_algorithm", "SCAN_HASH_ALGORITHM")               //nolint:errcheck
+	viper.BindEnv("scan.match_hash_algorithms", "SCAN_MATCH_HASH_ALGORITHMS") //nolint:errcheck
+	viper.BindEnv("scan.manifest_backend", "SCAN_MANIFEST_BACKEND")           //nolint:errcheck
+	viper.BindEnv("scan.manifest_dir", "SCAN_MANIFEST_DIR")                   //nolint:errcheck
+	viper.BindEnv("scan.manifest_key", "SCAN_MANIFEST_KEY")                   //nolint:errcheck
+
+	// Chunk store
+	viper.BindEnv("chunk_store.backend", "CHUNK_STORE_BACKEND") //nolint:errcheck
+
+	// Retrieval
+	viper.BindEnv("retrieval.mode", "RETRIEVAL_MODE")                               //nolint:errcheck
+	viper.BindEnv("retrieval.documents", "RETRIEVAL_DOCUMENTS")                     //nolint:errcheck
+	viper.BindEnv("retrieval.context_window", "RETRIEVAL_CONTEXT_WINDOW")           //nolint:errcheck
+	viper.BindEnv("retrieval.injection_threshold", "RETRIEVAL_INJECTION_THRESHOLD") //nolint:errcheck
+	viper.BindEnv("retrieval.sanitize_injections", "RETRIEVAL_SANITIZE_INJECTIONS") //nolint:errcheck
+	viper.BindEnv("retrieval.min_score", "RETRIEVAL_MIN_SCORE")                     //nolint:errcheck
+	viper.BindEnv("retrieval.max_distance", "RETRIEVAL_MAX_DISTANCE")               //nolint:errcheck
+	viper.BindEnv("retrieval.freshness_half_life", "RETRIEVAL_FRESHNESS_HALF_LIFE") //nolint:errcheck
+	viper.BindEnv("retrieval.freshness_weight", "RETRIEVAL_FRESHNESS_WEIGHT")       //nolint:errcheck
+
+	// Write-ahead log
+	viper.BindEnv("wal.backend", "WAL_BACKEND")                 //nolint:errcheck
+	viper.BindEnv("wal.file_path", "WAL_FILE_PATH")             //nolint:errcheck
+	viper.BindEnv("wal.stream", "WAL_STREAM")                   //nolint:errcheck
+	viper.BindEnv("wal.replay_interval", "WAL_REPLAY_INTERVAL") //nolint:errcheck
+
+	// Content store
+	viper.BindEnv("content_store.backend", "CONTENT_STORE_BACKEND")               //nolint:errcheck
+	viper.BindEnv("content_store.directory", "CONTENT_STORE_DIRECTORY")           //nolint:errcheck
+	viper.BindEnv("content_store.s3_bucket", "CONTENT_STORE_S3_BUCKET")           //nolint:errcheck
+	viper.BindEnv("content_store.s3_region", "CONTENT_STORE_S3_REGION")           //nolint:errcheck
+	viper.BindEnv("content_store.s3_endpoint", "CONTENT_STORE_S3_ENDPOINT")       //nolint:errcheck
+	viper.BindEnv("content_store.s3_prefix", "CONTENT_STORE_S3_PREFIX")           //nolint:errcheck
+	viper.BindEnv("content_store.access_key_id", "AWS_ACCESS_KEY_ID")             //nolint:errcheck
+	viper.BindEnv("content_store.secret_access_key", "AWS_SECRET_ACCESS_KEY")     //nolint:errcheck
+	viper.BindEnv("content_store.session_token", "AWS_SESSION_TOKEN")             //nolint:errcheck
+	viper.BindEnv("content_store.thumbnail_size", "CONTENT_STORE_THUMBNAIL_SIZE") //nolint:errcheck
+
+	// Digest reports
+	viper.BindEnv("digest.enabled", "DIGEST_ENABLED")           //nolint:errcheck
+	viper.BindEnv("digest.reports_file", "DIGEST_REPORTS_FILE") //nolint:errcheck
+	viper.BindEnv("digest.smtp_host", "SMTP_HOST")              //nolint:errcheck
+	viper.BindEnv("digest.smtp_port", "SMTP_PORT")              //nolint:errcheck
+	viper.BindEnv("digest.smtp_username", "SMTP_USERNAME")      //nolint:errcheck
+	viper.BindEnv("digest.smtp_password", "SMTP_PASSWORD")      //nolint:errcheck
+	viper.BindEnv("digest.smtp_from", "SMTP_FROM")              //nolint:errcheck
+
+	// Provider limits
+	viper.BindEnv("limits.azure_max_concurrent", "LIMITS_AZURE_MAX_CONCURRENT")                     //nolint:errcheck
+	viper.BindEnv("limits.azure_rpm", "LIMITS_AZURE_RPM")                                           //nolint:errcheck
+	viper.BindEnv("limits.pinecone_max_concurrent", "LIMITS_PINECONE_MAX_CONCURRENT")               //nolint:errcheck
+	viper.BindEnv("limits.pinecone_rpm", "LIMITS_PINECONE_RPM")                                     //nolint:errcheck
+	viper.BindEnv("provider_http.max_idle_conns_per_host", "PROVIDER_HTTP_MAX_IDLE_CONNS_PER_HOST") //nolint:errcheck
+	viper.BindEnv("provider_http.idle_conn_timeout", "PROVIDER_HTTP_IDLE_CONN_TIMEOUT")             //nolint:errcheck
+	viper.BindEnv("provider_http.dial_timeout", "PROVIDER_HTTP_DIAL_TIMEOUT")                       //nolint:errcheck
+	viper.BindEnv("provider_http.tls_handshake_timeout", "PROVIDER_HTTP_TLS_HANDSHAKE_TIMEOUT")     //nolint:errcheck
+	viper.BindEnv("provider_http.response_header_timeout", "PROVIDER_HTTP_RESPONSE_HEADER_TIMEOUT") //nolint:errcheck
+	viper.BindEnv("provider_http.azure.timeout", "PROVIDER_HTTP_AZURE_TIMEOUT")                     //nolint:errcheck
+	viper.BindEnv("provider_http.azure.proxy", "PROVIDER_HTTP_AZURE_PROXY")                         //nolint:errcheck
+	viper.BindEnv("provider_http.azure.ca_file", "PROVIDER_HTTP_AZURE_CA_FILE")                     //nolint:errcheck
+	viper.BindEnv("provider_http.pinecone.timeout", "PROVIDER_HTTP_PINECONE_TIMEOUT")               //nolint:errcheck
+	viper.BindEnv("provider_http.pinecone.proxy", "PROVIDER_HTTP_PINECONE_PROXY")                   //nolint:errcheck
+	viper.BindEnv("provider_http.pinecone.ca_file", "PROVIDER_HTTP_PINECONE_CA_FILE")               //nolint:errcheck
+
+	// Enrichment
+	viper.BindEnv("enrichment.failure_threshold", "ENRICHMENT_FAILURE_THRESHOLD") //nolint:errcheck
+	viper.BindEnv("enrichment.cooldown", "ENRICHMENT_COOLDOWN")                   //nolint:errcheck
+	viper.BindEnv("enrichment.repair_interval", "ENRICHMENT_REPAIR_INTERVAL")     //nolint:errcheck
+
+	// Dead-letter list
+	viper.BindEnv("dlq.max_retries", "DLQ_MAX_RETRIES")     //nolint:errcheck
+	viper.BindEnv("dlq.retry_backoff", "DLQ_RETRY_BACKOFF") //nolint:errcheck
+
+	// Summaries
+	viper.BindEnv("summary.max_tokens", "SUMMARY_MAX_TOKENS")   //nolint:errcheck
+	viper.BindEnv("summary.temperature", "SUMMARY_TEMPERATURE") //nolint:errcheck
+	viper.BindEnv("summary.style", "SUMMARY_STYLE")             //nolint:errcheck
+	viper.BindEnv("summary.language", "SUMMARY_LANGUAGE")       //nolint:errcheck
+
+	// Extraction
+	viper.BindEnv("extraction.max_file_size", "EXTRACTION_MAX_FILE_SIZE")           //nolint:errcheck
+	viper.BindEnv("extraction.max_in_memory", "EXTRACTION_MAX_IN_MEMORY")           //nolint:errcheck
+	viper.BindEnv("extraction.temp_dir", "EXTRACTION_TEMP_DIR")                     //nolint:errcheck
+	viper.BindEnv("extraction.normalize", "EXTRACTION_NORMALIZE")                   //nolint:errcheck
+	viper.BindEnv("extraction.external_timeout", "EXTRACTION_EXTERNAL_TIMEOUT")     //nolint:errcheck
+	viper.BindEnv("extraction.timeout", "EXTRACTION_TIMEOUT")                       //nolint:errcheck
+	viper.BindEnv("extraction.sandbox", "EXTRACTION_SANDBOX")                       //nolint:errcheck
+	viper.BindEnv("extraction.sandbox_max_memory", "EXTRACTION_SANDBOX_MAX_MEMORY") //nolint:errcheck
+
+	// Gateway
+	viper.BindEnv("gateway.port", "GATEWAY_PORT")             //nolint:errcheck
+	viper.BindEnv("gateway.api_keys", "GATEWAY_API_KEYS")     //nolint:errcheck
+	viper.BindEnv("gateway.rate_limit", "GATEWAY_RATE_LIMIT") //nolint:errcheck
+	viper.BindEnv("gateway.rate_burst", "GATEWAY_RATE_BURST") //nolint:errcheck
+
+	viper.BindEnv("ingest.max_request_bytes", "INGEST_MAX_REQUEST_BYTES") //nolint:errcheck
+	viper.BindEnv("ingest.daily_files", "INGEST_DAILY_FILES")             //nolint:errcheck
+	viper.BindEnv("ingest.daily_bytes", "INGEST_DAILY_BYTES")             //nolint:errcheck
+
+	viper.BindEnv("cors.allowed_origins", "CORS_ALLOWED_ORIGINS")                     //nolint:errcheck
+	viper.BindEnv("cors.allowed_methods", "CORS_ALLOWED_METHODS")                     //nolint:errcheck
+	viper.BindEnv("cors.allowed_headers", "CORS_ALLOWED_HEADERS")                     //nolint:errcheck
+	viper.BindEnv("cors.exposed_headers", "CORS_EXPOSED_HEADERS")                     //nolint:errcheck
+	viper.BindEnv("cors.allow_credentials", "CORS_ALLOW_CREDENTIALS")                 //nolint:errcheck
+	viper.BindEnv("cors.max_age", "CORS_MAX_AGE")                                     //nolint:errcheck
+	viper.BindEnv("security_headers.enabled", "SECURITY_HEADERS_ENABLED")             //nolint:errcheck
+	viper.BindEnv("security_headers.content_security_policy", "SECURITY_HEADERS_CSP") //nolint:errcheck
+	viper.BindEnv("security_headers.frame_options", "SECURITY_HEADERS_FRAME_OPTIONS") //nolint:errcheck
+	viper.BindEnv("security_headers.hsts_max_age", "SECURITY_HEADERS_HSTS_MAX_AGE")   //nolint:errcheck
+
+	// Vision
+	viper.BindEnv("vision.max_concurrent", "VISION_MAX_CONCURRENT")               //nolint:errcheck
+	viper.BindEnv("vision.cache_size", "VISION_CACHE_SIZE")                       //nolint:errcheck
+	viper.BindEnv("vision.cache_ttl", "VISION_CACHE_TTL")                         //nolint:errcheck
+	viper.BindEnv("vision.max_image_bytes", "VISION_MAX_IMAGE_BYTES")             //nolint:errcheck
+	viper.BindEnv("vision.describe_token_budget", "VISION_DESCRIBE_TOKEN_BUDGET") //nolint:errcheck
+	viper.BindEnv("vision.describe_max_tokens", "VISION_DESCRIBE_MAX_TOKENS")     //nolint:errcheck
+
+	// Image search
+	viper.BindEnv("image_search.provider", "IMAGE_SEARCH_PROVIDER")     //nolint:errcheck
+	viper.BindEnv("image_search.endpoint", "IMAGE_SEARCH_ENDPOINT")     //nolint:errcheck
+	viper.BindEnv("image_search.api_key", "IMAGE_SEARCH_API_KEY")       //nolint:errcheck
+	viper.BindEnv("image_search.model", "IMAGE_SEARCH_MODEL")           //nolint:errcheck
+	viper.BindEnv("image_search.dimension", "IMAGE_SEARCH_DIMENSION")   //nolint:errcheck
+	viper.BindEnv("image_search.namespace", "IMAGE_SEARCH_NAMESPACE")   //nolint:errcheck
+	viper.BindEnv("image_search.index_name", "IMAGE_SEARCH_INDEX_NAME") //nolint:errcheck
+	viper.BindEnv("image_search.index_host", "IMAGE_SEARCH_INDEX_HOST") //nolint:errcheck
+	viper.BindEnv("image_search.top_k", "IMAGE_SEARCH_TOP_K")           //nolint:errcheck
+	viper.BindEnv("image_search.min_score", "IMAGE_SEARCH_MIN_SCORE")   //nolint:errcheck
+	viper.BindEnv("sparse.encoder", "SPARSE_ENCODER")                   //nolint:errcheck
+	viper.BindEnv("sparse.endpoint", "SPARSE_ENDPOINT")                 //nolint:errcheck
+	viper.BindEnv("sparse.api_key", "SPARSE_API_KEY")                   //nolint:errcheck
+	viper.BindEnv("sparse.alpha", "SPARSE_ALPHA")                       //nolint:errcheck
+	viper.BindEnv("sparse.k1", "SPARSE_BM25_K1")                        //nolint:errcheck
+	viper.BindEnv("sparse.b", "SPARSE_BM25_B")                          //nolint:errcheck
+	viper.BindEnv("sparse.avg_tokens", "SPARSE_BM25_AVG_TOKENS")        //nolint:errcheck
+
+	// Citations
+	viper.BindEnv("citations.verify", "CITATIONS_VERIFY")           //nolint:errcheck
+	viper.BindEnv("citations.min_overlap", "CITATIONS_MIN_OVERLAP") //nolint:errcheck
+	viper.BindEnv("citations.unsupported", "CITATIONS_UNSUPPORTED") //nolint:errcheck
+
+	// Structured output
+	viper.BindEnv("structured_output.repair_retries", "STRUCTURED_OUTPUT_REPAIR_RETRIES") //nolint:errcheck
+
+	// Tools
+	viper.BindEnv("tools.enabled", "TOOLS_ENABLED")               //nolint:errcheck
+	viper.BindEnv("tools.max_iterations", "TOOLS_MAX_ITERATIONS") //nolint:errcheck
+
+	// Agent
+	viper.BindEnv("agent.max_steps", "AGENT_MAX_STEPS")   //nolint:errcheck
+	viper.BindEnv("agent.max_tokens", "AGENT_MAX_TOKENS") //nolint:errcheck
+
+	// Topics
+	viper.BindEnv("topics.enabled", "TOPICS_ENABLED")             //nolint:errcheck
+	viper.BindEnv("topics.interval", "TOPICS_INTERVAL")           //nolint:errcheck
+	viper.BindEnv("topics.clusters", "TOPICS_CLUSTERS")           //nolint:errcheck
+	viper.BindEnv("topics.max_documents", "TOPICS_MAX_DOCUMENTS") //nolint:errcheck
+
+	// Analytics
+	viper.BindEnv("analytics.enabled", "ANALYTICS_ENABLED")     //nolint:errcheck
+	viper.BindEnv("analytics.retention", "ANALYTICS_RETENTION") //nolint:errcheck
+	viper.BindEnv("analytics.hash_key", "ANALYTICS_HASH_KEY")   //nolint:errcheck
+	viper.BindEnv("analytics.keep_text", "ANALYTICS_KEEP_TEXT") //nolint:errcheck
+
+	// Popularity
+	viper.BindEnv("popularity.enabled", "POPULARITY_ENABLED")       //nolint:errcheck
+	viper.BindEnv("popularity.boost", "POPULARITY_BOOST")           //nolint:errcheck
+	viper.BindEnv("popularity.weight", "POPULARITY_WEIGHT")         //nolint:errcheck
+	viper.BindEnv("popularity.saturation", "POPULARITY_SATURATION") //nolint:errcheck
+
+	// Event stream
+	viper.BindEnv("events.enabled", "EVENTS_ENABLED") //nolint:errcheck
+	viper.BindEnv("events.stream", "EVENTS_STREAM")   //nolint:errcheck
+	viper.BindEnv("events.max_len", "EVENTS_MAX_LEN") //nolint:errcheck
+
+	// Locks
+	viper.BindEnv("locks.enabled", "LOCKS_ENABLED")       //nolint:errcheck
+	viper.BindEnv("locks.key_prefix", "LOCKS_KEY_PREFIX") //nolint:errcheck
+	viper.BindEnv("locks.ttl", "LOCKS_TTL")               //nolint:errcheck
+	viper.BindEnv("locks.owner", "LOCKS_OWNER")           //nolint:errcheck
+
+	// Sharding
+	viper.BindEnv("sharding.enabled", "SHARDING_ENABLED")                       //nolint:errcheck
+	viper.BindEnv("sharding.key_prefix", "SHARDING_KEY_PREFIX")                 //nolint:errcheck
+	viper.BindEnv("sharding.visibility_timeout", "SHARDING_VISIBILITY_TIMEOUT") //nolint:errcheck
+	viper.BindEnv("sharding.max_deliveries", "SHARDING_MAX_DELIVERIES")         //nolint:errcheck
+	viper.BindEnv("sharding.poll_interval", "SHARDING_POLL_INTERVAL")           //nolint:errcheck
+
+	// Idempotency
+	viper.BindEnv("idempotency.enabled", "IDEMPOTENCY_ENABLED") //nolint:errcheck
+	viper.BindEnv("idempotency.ttl", "IDEMPOTENCY_TTL")         //nolint:errcheck
+
+	// MCP
+	viper.BindEnv("mcp.gateway_url", "MCP_GATEWAY_URL")           //nolint:errcheck
+	viper.BindEnv("mcp.gateway_api_key", "MCP_GATEWAY_API_KEY")   //nolint:errcheck
+	viper.BindEnv("mcp.port", "MCP_PORT")                         //nolint:errcheck
+	viper.BindEnv("mcp.tools", "MCP_TOOLS")                       //nolint:errcheck
+	viper.BindEnv("mcp.api_keys", "MCP_API_KEYS")                 //nolint:errcheck
+	viper.BindEnv("mcp.max_result_chars", "MCP_MAX_RESULT_CHARS") //nolint:errcheck
+	viper.BindEnv("mcp.max_top_k", "MCP_MAX_TOP_K")               //nolint:errcheck
+	viper.BindEnv("mcp.timeout", "MCP_TIMEOUT")                   //nolint:errcheck
+
+	// Slack
+	viper.BindEnv("slack.signing_secret", "SLACK_SIGNING_SECRET") //nolint:errcheck
+	viper.BindEnv("slack.bot_token", "SLACK_BOT_TOKEN")           //nolint:errcheck
+	viper.BindEnv("slack.any_workspace", "SLACK_ANY_WORKSPACE")   //nolint:errcheck
+	viper.BindEnv("slack.api_url", "SLACK_API_URL")               //nolint:errcheck
+	viper.BindEnv("slack.source_url", "SLACK_SOURCE_URL")         //nolint:errcheck
+	viper.BindEnv("slack.top_k", "SLACK_TOP_K")                   //nolint:errcheck
+	viper.BindEnv("slack.timeout", "SLACK_TIMEOUT")               //nolint:errcheck
+
+	// Math
+	viper.BindEnv("math.describe", "MATH_DESCRIBE")         //nolint:errcheck
+	viper.BindEnv("math.max_formulas", "MATH_MAX_FORMULAS") //nolint:errcheck
+
+	// Log files
+	viper.BindEnv("log_files.window", "LOG_FILES_WINDOW")             //nolint:errcheck
+	viper.BindEnv("log_files.window_lines", "LOG_FILES_WINDOW_LINES") //nolint:errcheck
+	viper.BindEnv("log_files.max_bytes", "LOG_FILES_MAX_BYTES")       //nolint:errcheck
+
+	// Data files
+	viper.BindEnv("data_files.sample_rows", "DATA_FILES_SAMPLE_ROWS") //nolint:errcheck
+
+	// Conversion
+	viper.BindEnv("conversion.soffice_path", "CONVERSION_SOFFICE_PATH")     //nolint:errcheck
+	viper.BindEnv("conversion.url", "CONVERSION_URL")                       //nolint:errcheck
+	viper.BindEnv("conversion.timeout", "CONVERSION_TIMEOUT")               //nolint:errcheck
+	viper.BindEnv("conversion.max_concurrent", "CONVERSION_MAX_CONCURRENT") //nolint:errcheck
+	viper.BindEnv("conversion.max_output", "CONVERSION_MAX_OUTPUT")         //nolint:errcheck
+	viper.BindEnv("conversion.extensions", "CONVERSION_EXTENSIONS")         //nolint:errcheck
+
+	viper.BindEnv("plugins.dir", "PLUGINS_DIR")               //nolint:errcheck
+	viper.BindEnv("plugins.timeout", "PLUGINS_TIMEOUT")       //nolint:errcheck
+	viper.BindEnv("plugins.max_memory", "PLUGINS_MAX_MEMORY") //nolint:errcheck
+
+	// Malware scan
+	viper.BindEnv("malware.scanner", "MALWARE_SCANNER")               //nolint:errcheck
+	viper.BindEnv("malware.clamav_address", "MALWARE_CLAMAV_ADDRESS") //nolint:errcheck
+	viper.BindEnv("malware.api_url", "MALWARE_API_URL")               //nolint:errcheck
+	viper.BindEnv("malware.api_key", "MALWARE_API_KEY")               //nolint:errcheck
+	viper.BindEnv("malware.timeout", "MALWARE_TIMEOUT")               //nolint:errcheck
+	viper.BindEnv("malware.action", "MALWARE_ACTION")                 //nolint:errcheck
+	viper.BindEnv("malware.quarantine_dir", "MALWARE_QUARANTINE_DIR") //nolint:errcheck
+	viper.BindEnv("malware.fail_open", "MALWARE_FAIL_OPEN")           //nolint:errcheck
+
+	// Moderation
+	viper.BindEnv("tls.cert_file", "TLS_CERT_FILE")               //nolint:errcheck
+	viper.BindEnv("tls.key_file", "TLS_KEY_FILE")                 //nolint:errcheck
+	viper.BindEnv("tls.client_ca_file", "TLS_CLIENT_CA_FILE")     //nolint:errcheck
+	viper.BindEnv("tls.ca_file", "TLS_CA_FILE")                   //nolint:errcheck
+	viper.BindEnv("tls.client_cert_file", "TLS_CLIENT_CERT_FILE") //nolint:errcheck
+	viper.BindEnv("tls.client_key_file", "TLS_CLIENT_KEY_FILE")   //nolint:errcheck
+	viper.BindEnv("tls.min_version", "TLS_MIN_VERSION")           //nolint:errcheck
+	viper.BindEnv("tls.reload_interval", "TLS_RELOAD_INTERVAL")   //nolint:errcheck
+
+	viper.BindEnv("moderation.classifier", "MODERATION_CLASSIFIER") //nolint:errcheck
+	viper.BindEnv("moderation.endpoint", "MODERATION_ENDPOINT")     //nolint:errcheck
+	viper.BindEnv("moderation.api_key", "MODERATION_API_KEY")       //nolint:errcheck
+	viper.BindEnv("moderation.timeout", "MODERATION_TIMEOUT")       //nolint:errcheck
+	viper.BindEnv("moderation.fail_open", "MODERATION_FAIL_OPEN")   //nolint:errcheck
+	viper.BindEnv("moderation.action", "MODERATION_ACTION")         //nolint:errcheck
+	viper.BindEnv("moderation.threshold", "MODERATION_THRESHOLD")   //nolint:errcheck
+	viper.BindEnv("moderation.queries", "MODERATION_QUERIES")       //nolint:errcheck
+	viper.BindEnv("moderation.answers", "MODERATION_ANSWERS")       //nolint:errcheck
+	viper.BindEnv("moderation.categories", "MODERATION_CATEGORIES") //nolint:errcheck
 }
 
 func validate(config *Config) error {
@@ -249,17 +1799,923 @@ func validate(config *Config) error {
 	if config.App.ChunkOverlap >= config.App.ChunkSize {
 		return fmt.Errorf("chunk_overlap must be less than chunk_size")
 	}
+	if config.App.StartupWait < 0 {
+		return fmt.Errorf("STARTUP_WAIT cannot be negative")
+	}
+	if config.App.IngestWorkers <= 0 {
+		return fmt.Errorf("INGEST_WORKERS must be positive")
+	}
+	if config.App.IngestQueueSize <= 0 {
+		return fmt.Errorf("INGEST_QUEUE_SIZE must be positive")
+	}
+	if config.App.StreamChunkingBytes < 0 {
+		return fmt.Errorf("STREAM_CHUNKING_BYTES cannot be negative")
+	}
+	if config.App.StreamBatchChunks <= 0 {
+		return fmt.Errorf("STREAM_BATCH_CHUNKS must be positive")
+	}
 	if config.Pinecone.Dimension <= 0 {
 		return fmt.Errorf("pinecone dimension must be positive")
 	}
+	switch config.Pinecone.Metric {
+	case "cosine", "euclidean", "dotproduct":
+	default:
+		return fmt.Errorf("pinecone metric must be cosine, euclidean or dotproduct, got %q", config.Pinecone.Metric)
+	}
+	if config.Pinecone.MetadataLimit <= 0 {
+		return fmt.Errorf("pinecone metadata_limit must be positive")
+	}
+	if config.Pinecone.UpsertBatchSize <= 0 || config.Pinecone.UpsertBatchSize > 1000 {
+		return fmt.Errorf("pinecone upsert_batch_size must be between 1 and 1000")
+	}
+	if config.Pinecone.UpsertConcurrency <= 0 {
+		return fmt.Errorf("pinecone upsert_concurrency must be positive")
+	}
+	if config.Pinecone.UpsertMaxRetries < 0 {
+		return fmt.Errorf("pinecone upsert_max_retries cannot be negative")
+	}
+	if config.Pinecone.Transport != "http" && config.Pinecone.Transport != "grpc" {
+		return fmt.Errorf("pinecone transport must be http or grpc, got %q", config.Pinecone.Transport)
+	}
+	if config.Pinecone.Transport == "grpc" && config.ProviderHTTP.Pinecone.Proxy != "" {
+		return fmt.Errorf("PROVIDER_HTTP_PINECONE_PROXY is not supported with the grpc transport; set HTTPS_PROXY instead")
+	}
+	if config.App.SummaryVectors && config.Pinecone.SummaryNamespace == "" {
+		return fmt.Errorf("pinecone summary_namespace is required when summary vectors are enabled")
+	}
 
-	// Note: Google Vision API key is optional
-	// Note: GitHub token is optional
-
-	return nil
+	if config.Dedup.NearDuplicate {
+		if config.Dedup.SimilarityThreshold <= 0 || config.Dedup.SimilarityThreshold > 1 {
+			return fmt.Errorf("dedup similarity_threshold must be in (0, 1]")
+		}
+		if config.Dedup.MinHashBands <= 0 || config.Dedup.MinHashPermutations%config.Dedup.MinHashBands != 0 {
+			return fmt.Errorf("dedup minhash_permutations must be a positive multiple of minhash_bands")
+		}
+	}
+
+	if !isValidVisibility(config.ACL.DefaultVisibility) {
+		return fmt.Errorf("acl default_visibility must be public, internal or private")
+	}
+	for _, rule := range config.ACL.Rules {
+		if rule.PathPrefix == "" {
+			return fmt.Errorf("acl rules require a path_prefix")
+		}
+		if rule.Visibility != "" && !isValidVisibility(rule.Visibility) {
+			return fmt.Errorf("acl rule %q has invalid visibility %q", rule.PathPrefix, rule.Visibility)
+		}
+	}
+
+	if config.Audit.Enabled && config.Audit.Backend != "redis" && config.Audit.Backend != "file" {
+		return fmt.Errorf("audit backend must be redis or file")
+	}
+
+	if config.Embedding.MaxBatchSize <= 0 {
+		return fmt.Errorf("embedding max_batch_size must be positive")
+	}
+	if config.Embedding.MaxTokens < 16 {
+		return fmt.Errorf("embedding max_tokens must be at least 16")
+	}
+	switch config.Embedding.Overflow {
+	case "split", "truncate_head", "truncate_tail", "skip":
+	default:
+		return fmt.Errorf("embedding overflow must be split, truncate_head, truncate_tail or skip")
+	}
+
+	if config.Vision.MaxConcurrent <= 0 {
+		return fmt.Errorf("vision max_concurrent must be positive")
+	}
+	if config.Vision.DescribeMaxTokens <= 0 {
+		return fmt.Errorf("vision describe_max_tokens must be positive")
+	}
+	if config.Vision.DescribeTokenBudget <= config.Vision.DescribeMaxTokens {
+		return fmt.Errorf("vision describe_token_budget must be greater than describe_max_tokens")
+	}
+	if err := validateImageSearch(config); err != nil {
+		return err
+	}
+	if err := validateSparse(config); err != nil {
+		return err
+	}
+	if err := validateRouting(config); err != nil {
+		return err
+	}
+	if err := validateCitations(config.Citations); err != nil {
+		return err
+	}
+	if config.StructuredOutput.RepairRetries < 0 {
+		return fmt.Errorf("structured_output repair_retries cannot be negative")
+	}
+	if config.Tools.Enabled && (config.Tools.MaxIterations < 1 || config.Tools.MaxIterations > 10) {
+		return fmt.Errorf("tools max_iterations must be between 1 and 10")
+	}
+	if config.Agent.MaxSteps < 1 || config.Agent.MaxSteps > 10 {
+		return fmt.Errorf("agent max_steps must be between 1 and 10")
+	}
+	if config.Agent.MaxTokens <= 0 {
+		return fmt.Errorf("agent max_tokens must be positive")
+	}
+	if config.Topics.Interval < 0 {
+		return fmt.Errorf("topics interval cannot be negative")
+	}
+	if config.Topics.Clusters < 0 || config.Topics.Clusters > 100 {
+		return fmt.Errorf("topics clusters must be between 0 and 100")
+	}
+	if config.Topics.MaxDocuments <= 0 {
+		return fmt.Errorf("topics max_documents must be positive")
+	}
+	if config.Analytics.Retention <= 0 {
+		return fmt.Errorf("analytics retention must be positive")
+	}
+	if config.Popularity.Weight < 0 || config.Popularity.Weight > 1 {
+		return fmt.Errorf("popularity weight must be between 0 and 1")
+	}
+	if config.Popularity.Saturation <= 0 {
+		return fmt.Errorf("popularity saturation must be positive")
+	}
+	if config.Events.Enabled && config.Events.Stream == "" {
+		return fmt.Errorf("events stream is required when events are enabled")
+	}
+	if config.Events.MaxLen <= 0 {
+		return fmt.Errorf("events max_len must be positive")
+	}
+	if config.Locks.TTL < 3*time.Second {
+		return fmt.Errorf("locks ttl must be at least 3s")
+	}
+	if config.Sharding.VisibilityTimeout < 3*time.Second {
+		return fmt.Errorf("sharding visibility_timeout must be at least 3s")
+	}
+	if config.Sharding.MaxDeliveries <= 0 {
+		return fmt.Errorf("sharding max_deliveries must be positive")
+	}
+	if config.Sharding.PollInterval <= 0 {
+		return fmt.Errorf("sharding poll_interval must be positive")
+	}
+	if config.Idempotency.TTL <= 0 {
+		return fmt.Errorf("idempotency ttl must be positive")
+	}
+	if config.Math.MaxFormulas <= 0 {
+		return fmt.Errorf("math max_formulas must be positive")
+	}
+	if config.LogFiles.Window < 0 {
+		return fmt.Errorf("log_files window cannot be negative")
+	}
+	if config.LogFiles.WindowLines <= 0 {
+		return fmt.Errorf("log_files window_lines must be positive")
+	}
+	if config.LogFiles.MaxBytes <= 0 {
+		return fmt.Errorf("log_files max_bytes must be positive")
+	}
+	if config.DataFiles.SampleRows < 0 {
+		return fmt.Errorf("data_files sample_rows cannot be negative")
+	}
+	if err := validateConversion(config); err != nil {
+		return err
+	}
+	if config.Plugins.Timeout <= 0 {
+		return fmt.Errorf("plugins timeout must be positive")
+	}
+	if config.Plugins.MaxMemory < 64*1024 || config.Plugins.MaxMemory > 4*1024*1024*1024 {
+		return fmt.Errorf("plugins max_memory must be between 64 KiB and 4 GiB")
+	}
+	if err := validateMalware(config); err != nil {
+		return err
+	}
+	if err := validateTLS(config.TLS); err != nil {
+		return err
+	}
+
+	if err := validateCORS(config.CORS); err != nil {
+		return err
+	}
+
+	if err := validateIngest(config.Ingest); err != nil {
+		return err
+	}
+
+	if err := validateMCP(config.MCP); err != nil {
+		return err
+	}
+
+	if err := validateSlack(config.Slack); err != nil {
+		return err
+	}
+
+	if config.SecurityHeaders.Enabled && config.SecurityHeaders.FrameOptions != "DENY" && config.SecurityHeaders.FrameOptions != "SAMEORIGIN" {
+		return fmt.Errorf("security_headers frame_options must be DENY or SAMEORIGIN")
+	}
+
+	if err := validateModeration(config.Moderation); err != nil {
+		return err
+	}
+
+	if err := validatePipeline(config.Pipeline); err != nil {
+		return err
+	}
+
+	if config.Extraction.MaxFileSize < 0 {
+		return fmt.Errorf("extraction max_file_size cannot be negative")
+	}
+	if config.Extraction.MaxInMemory <= 0 {
+		return fmt.Errorf("extraction max_in_memory must be positive")
+	}
+	if config.Extraction.ExternalTimeout <= 0 {
+		return fmt.Errorf("extraction external_timeout must be positive")
+	}
+	if err := validateExtractionLimits(config.Extraction); err != nil {
+		return err
+	}
+	for ext, url := range config.Extractors {
+		if ext == "" || strings.ContainsAny(ext, "./") {
+			return fmt.Errorf("extractor extension %q must be an extension without the dot", ext)
+		}
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return fmt.Errorf("extractor for %s must be an http or https URL", ext)
+		}
+	}
+
+	hashAlgorithms := []string{"sha256", "blake3", "xxh64"}
+	for _, algorithm := range append([]string{config.Scan.HashAlgorithm}, config.Scan.MatchHashAlgorithms...) {
+		if !slices.Contains(hashAlgorithms, algorithm) {
+			return fmt.Errorf("scan hash algorithm must be sha256, blake3 or xxh64, not %q", algorithm)
+		}
+	}
+	for _, pattern := range config.Scan.ExcludeDirs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("scan exclude_dirs pattern %q is invalid", pattern)
+		}
+	}
+	if !slices.Contains([]string{"none", "memory", "file", "redis"}, config.Scan.ManifestBackend) {
+		return fmt.Errorf("scan manifest_backend must be none, memory, file or redis")
+	}
+
+	if b := config.ChunkStore.Backend; b != "none" && b != "memory" && b != "redis" {
+		return fmt.Errorf("chunk_store backend must be none, memory or redis")
+	}
+
+	if b := config.WAL.Backend; b != "none" && b != "file" && b != "redis" {
+		return fmt.Errorf("wal backend must be none, file or redis")
+	}
+
+	switch config.ContentStore.Backend {
+	case "none", "file":
+	case "s3":
+		if config.ContentStore.S3Bucket == "" {
+			return fmt.Errorf("content_store s3_bucket is required for the s3 backend")
+		}
+		if config.ContentStore.AccessKeyID == "" || config.ContentStore.SecretAccessKey == "" {
+			return fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for the s3 content store")
+		}
+	default:
+		return fmt.Errorf("content_store backend must be none, file or s3")
+	}
+	if config.ContentStore.ThumbnailSize < 0 {
+		return fmt.Errorf("content_store thumbnail_size cannot be negative")
+	}
+
+	if config.Digest.Enabled && config.Digest.ReportsFile == "" {
+		return fmt.Errorf("digest reports_file is required when digests are enabled")
+	}
+	if config.Digest.SMTPPort <= 0 || config.Digest.SMTPPort > 65535 {
+		return fmt.Errorf("digest smtp_port must be between 1 and 65535")
+	}
+	for name, limit := range map[string]int{
+		"LIMITS_AZURE_MAX_CONCURRENT":    config.Limits.AzureMaxConcurrent,
+		"LIMITS_AZURE_RPM":               config.Limits.AzureRPM,
+		"LIMITS_PINECONE_MAX_CONCURRENT": config.Limits.PineconeMaxConcurrent,
+		"LIMITS_PINECONE_RPM":            config.Limits.PineconeRPM,
+	} {
+		if limit < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	if config.ProviderHTTP.MaxIdleConnsPerHost <= 0 {
+		return fmt.Errorf("PROVIDER_HTTP_MAX_IDLE_CONNS_PER_HOST must be positive")
+	}
+	for name, timeout := range map[string]time.Duration{
+		"PROVIDER_HTTP_IDLE_CONN_TIMEOUT":       config.ProviderHTTP.IdleConnTimeout,
+		"PROVIDER_HTTP_DIAL_TIMEOUT":            config.ProviderHTTP.DialTimeout,
+		"PROVIDER_HTTP_TLS_HANDSHAKE_TIMEOUT":   config.ProviderHTTP.TLSHandshakeTimeout,
+		"PROVIDER_HTTP_RESPONSE_HEADER_TIMEOUT": config.ProviderHTTP.ResponseHeaderTimeout,
+	} {
+		if timeout < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	if err := validateAdapterHTTP("PROVIDER_HTTP_AZURE", config.ProviderHTTP.Azure); err != nil {
+		return err
+	}
+	if err := validateAdapterHTTP("PROVIDER_HTTP_PINECONE", config.ProviderHTTP.Pinecone); err != nil {
+		return err
+	}
+	if config.Enrichment.FailureThreshold <= 0 {
+		return fmt.Errorf("ENRICHMENT_FAILURE_THRESHOLD must be positive")
+	}
+	if config.Enrichment.Cooldown <= 0 {
+		return fmt.Errorf("ENRICHMENT_COOLDOWN must be positive")
+	}
+	if config.Enrichment.RepairInterval < 0 {
+		return fmt.Errorf("ENRICHMENT_REPAIR_INTERVAL cannot be negative")
+	}
+	if config.DLQ.MaxRetries < 0 {
+		return fmt.Errorf("DLQ_MAX_RETRIES cannot be negative")
+	}
+	if config.DLQ.RetryBackoff < 0 {
+		return fmt.Errorf("DLQ_RETRY_BACKOFF cannot be negative")
+	}
+	if err := validateSummary(config.Summary); err != nil {
+		return err
+	}
+	for name, policy := range config.Policies {
+		size, overlap := config.App.ChunkSize, config.App.ChunkOverlap
+		if policy.ChunkSize < 0 {
+			return fmt.Errorf("policy %q chunk_size cannot be negative", name)
+		}
+		if policy.ChunkSize > 0 {
+			size = policy.ChunkSize
+		}
+		if policy.ChunkOverlap != nil {
+			overlap = *policy.ChunkOverlap
+		}
+		if overlap < 0 || overlap >= size {
+			return fmt.Errorf("policy %q chunk_overlap must be at least 0 and less than its chunk_size", name)
+		}
+	}
+
+	if config.Retrieval.Mode != "chunks" && config.Retrieval.Mode != "two_stage" {
+		return fmt.Errorf("retrieval mode must be chunks or two_stage")
+	}
+	if config.Retrieval.Documents <= 0 {
+		return fmt.Errorf("retrieval documents must be positive")
+	}
+	if config.Retrieval.ContextWindow < 0 || config.Retrieval.ContextWindow > 10 {
+		return fmt.Errorf("retrieval context_window must be between 0 and 10")
+	}
+	if config.Retrieval.InjectionThreshold < 0 || config.Retrieval.InjectionThreshold > 1 {
+		return fmt.Errorf("retrieval injection_threshold must be between 0 and 1")
+	}
+	if config.Retrieval.ContextTokens < 0 {
+		return fmt.Errorf("retrieval context_tokens cannot be negative")
+	}
+	if config.Retrieval.FreshnessHalfLife < 0 {
+		return fmt.Errorf("retrieval freshness_half_life cannot be negative")
+	}
+	if config.Retrieval.FreshnessWeight < 0 || config.Retrieval.FreshnessWeight > 1 {
+		return fmt.Errorf("retrieval freshness_weight must be between 0 and 1")
+	}
+	if err := validateThresholds("retrieval", config.Pinecone.Metric, config.Retrieval.MinScore, config.Retrieval.MaxDistance); err != nil {
+		return err
+	}
+	for name, profile := range config.Retrieval.Profiles {
+		if err := validateRetrievalProfile(config, name, profile); err != nil {
+			return err
+		}
+	}
+
+	if config.Registry.Backend != "memory" && config.Registry.Backend != "redis" {
+		return fmt.Errorf("registry backend must be memory or redis")
+	}
+
+	if err := validateGateway(config.Gateway); err != nil {
+		return err
+	}
+
+	// Note: Google Vision API key is optional
+	// Note: GitHub token is optional
+
+	return nil
+}
+
+// validateSummary checks the summary defaults and each category override
+func validateSummary(c SummaryConfig) error {
+	for category, override := range c.Categories {
+		if override.Style != "" && !slices.Contains(SummaryStyles, override.Style) {
+			return fmt.Errorf("summary category %q has invalid style %q", category, override.Style)
+		}
+		if t := override.Temperature; t != nil && (*t < 0 || *t > 2) {
+			return fmt.Errorf("summary category %q temperature must be between 0 and 2", category)
+		}
+		if override.MaxTokens < 0 {
+			return fmt.Errorf("summary category %q max_tokens cannot be negative", category)
+		}
+	}
+	if c.MaxTokens <= 0 {
+		return fmt.Errorf("SUMMARY_MAX_TOKENS must be positive")
+	}
+	if c.Temperature < 0 || c.Temperature > 2 {
+		return fmt.Errorf("SUMMARY_TEMPERATURE must be between 0 and 2")
+	}
+	if !slices.Contains(SummaryStyles, c.Style) {
+		return fmt.Errorf("SUMMARY_STYLE must be one of %s", strings.Join(SummaryStyles, ", "))
+	}
+	return nil
+}
+
+func isValidVisibility(v string) bool {
+	return v == "public" || v == "internal" || v == "private"
 }
 
 // GetRedisAddr returns the Redis connection address
 func (c *RedisConfig) GetRedisAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
+
+func validateImageSearch(config *Config) error {
+	c := config.ImageSearch
+	switch c.Provider {
+	case "none":
+		return nil
+	case "azure_vision", "clip":
+	default:
+		return fmt.Errorf("image_search provider must be none, azure_vision or clip")
+	}
+	if c.Endpoint == "" {
+		return fmt.Errorf("IMAGE_SEARCH_ENDPOINT is required for image search")
+	}
+	if c.Dimension <= 0 {
+		return fmt.Errorf("image_search dimension must be positive")
+	}
+	if c.Namespace == "" {
+		return fmt.Errorf("image_search namespace is required")
+	}
+	if c.TopK <= 0 {
+		return fmt.Errorf("image_search top_k must be positive")
+	}
+	// Every namespace of a Pinecone index has the index's dimension
+	if c.IndexName == "" && c.IndexHost == "" && c.Dimension != config.Pinecone.Dimension {
+		return fmt.Errorf("image_search dimension %d differs from the Pinecone index dimension %d; set IMAGE_SEARCH_INDEX_NAME or IMAGE_SEARCH_INDEX_HOST to an index of dimension %d",
+			c.Dimension, config.Pinecone.Dimension, c.Dimension)
+	}
+	return nil
+}
+
+func validateSparse(config *Config) error {
+	c := config.Sparse
+	switch c.Encoder {
+	case "none":
+		return nil
+	case "bm25":
+	case "splade":
+		if c.Endpoint == "" {
+			return fmt.Errorf("SPARSE_ENDPOINT is required for the splade encoder")
+		}
+	default:
+		return fmt.Errorf("sparse encoder must be none, bm25 or splade")
+	}
+	if c.Alpha < 0 || c.Alpha > 1 {
+		return fmt.Errorf("sparse alpha must be between 0 and 1")
+	}
+	if c.K1 <= 0 || c.B < 0 || c.B > 1 || c.AvgTokens <= 0 {
+		return fmt.Errorf("sparse k1 and avg_tokens must be positive and b between 0 and 1")
+	}
+	// Pinecone only stores sparse values in dotproduct indexes
+	if config.Pinecone.Metric != "dotproduct" {
+		return fmt.Errorf("sparse vectors need a dotproduct index; set PINECONE_METRIC=dotproduct for an index created with that metric")
+	}
+	return nil
+}
+
+// FileCategories are the categories files are sorted into by extension,
+// which chunk vectors can be routed by
+var FileCategories = []string{"image", "diagram", "document", "spreadsheet", "code", "structured", "data", "unknown"}
+
+// validateRouting checks that every category route names a known category
+// and a place of its own with a dimension its index can hold
+func validateRouting(config *Config) error {
+	targets := make(map[string]string)
+	for _, category := range slices.Sorted(maps.Keys(config.Routing.Categories)) {
+		route := config.Routing.Categories[category]
+		if !slices.Contains(FileCategories, category) {
+			return fmt.Errorf("routing has unknown category %q; use one of %s", category, strings.Join(FileCategories, ", "))
+		}
+		if route.Dimension < 0 {
+			return fmt.Errorf("routing category %s dimension cannot be negative", category)
+		}
+		if route.SameIndex(config.Pinecone) {
+			// Every namespace of a Pinecone index has the index's dimension
+			if route.Dimension != 0 && route.Dimension != config.Pinecone.Dimension {
+				return fmt.Errorf("routing category %s dimension %d differs from the Pinecone index dimension %d; set its index_name or index_host to an index of dimension %d",
+					category, route.Dimension, config.Pinecone.Dimension, route.Dimension)
+			}
+			reserved := []string{"", "default", config.Pinecone.SummaryNamespace}
+			if config.ImageSearch.Enabled() && config.ImageSearch.IndexName == "" && config.ImageSearch.IndexHost == "" {
+				reserved = append(reserved, config.ImageSearch.Namespace)
+			}
+			if slices.Contains(reserved, route.Namespace) {
+				return fmt.Errorf("routing category %s needs a namespace of its own in the Pinecone index", category)
+			}
+		}
+
+		target := route.IndexName + "|" + route.IndexHost + "|" + route.Namespace
+		if route.SameIndex(config.Pinecone) {
+			target = "|" + route.Namespace
+		}
+		if other, ok := targets[target]; ok {
+			return fmt.Errorf("routing categories %s and %s store their vectors in the same namespace", other, category)
+		}
+		targets[target] = category
+	}
+	return nil
+}
+
+// ExtractionCategories are the categories of the content processors, which
+// extraction timeouts are set for
+var ExtractionCategories = []string{"text", "image", "document", "spreadsheet", "code", "specification", "log", "data", "external", "plugin"}
+
+// sandboxedProcessors are the built-in processors that can run in a
+// sandbox; plugins are sandboxed by their runtime and external extractors
+// run elsewhere
+var sandboxedProcessors = []string{"text", "document", "spreadsheet", "code", "specification", "log", "data", "convert"}
+
+func validateExtractionLimits(c ExtractionConfig) error {
+	if c.Timeout < 0 {
+		return fmt.Errorf("extraction timeout cannot be negative")
+	}
+	for category, timeout := range c.Timeouts {
+		if !slices.Contains(ExtractionCategories, category) {
+			return fmt.Errorf("extraction timeouts has unknown category %q; use one of %s", category, strings.Join(ExtractionCategories, ", "))
+		}
+		if timeout < 0 {
+			return fmt.Errorf("extraction timeout of %s cannot be negative", category)
+		}
+	}
+	for _, name := range c.Sandbox {
+		if !slices.Contains(sandboxedProcessors, name) {
+			return fmt.Errorf("extraction sandbox has unknown processor %q; use one of %s", name, strings.Join(sandboxedProcessors, ", "))
+		}
+	}
+	if c.SandboxMaxMemory < 64*1024*1024 {
+		return fmt.Errorf("extraction sandbox_max_memory must be at least 64 MiB")
+	}
+	return nil
+}
+
+func validateConversion(config *Config) error {
+	c := config.Conversion
+	if c.URL != "" && !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+		return fmt.Errorf("conversion url must be an http or https URL")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("conversion timeout must be positive")
+	}
+	if c.MaxConcurrent <= 0 {
+		return fmt.Errorf("conversion max_concurrent must be positive")
+	}
+	if c.MaxOutput <= 0 {
+		return fmt.Errorf("conversion max_output must be positive")
+	}
+	for _, ext := range c.Extensions {
+		if ext == "" || strings.ContainsAny(ext, "./\\ ") {
+			return fmt.Errorf("conversion extension %q must be an extension without the dot", ext)
+		}
+	}
+	return nil
+}
+
+func validateMalware(config *Config) error {
+	c := config.Malware
+	switch c.Scanner {
+	case "none":
+		return nil
+	case "clamav":
+		if c.ClamAVAddress == "" {
+			return fmt.Errorf("MALWARE_CLAMAV_ADDRESS is required for the clamav scanner")
+		}
+	case "api":
+		if !strings.HasPrefix(c.APIURL, "http://") && !strings.HasPrefix(c.APIURL, "https://") {
+			return fmt.Errorf("MALWARE_API_URL must be an http or https URL for the api scanner")
+		}
+	default:
+		return fmt.Errorf("malware scanner must be none, clamav or api")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("malware timeout must be positive")
+	}
+	switch c.Action {
+	case "reject":
+	case "quarantine":
+		if c.QuarantineDir == "" {
+			return fmt.Errorf("MALWARE_QUARANTINE_DIR is required to quarantine files")
+		}
+	default:
+		return fmt.Errorf("malware action must be reject or quarantine")
+	}
+	return nil
+}
+
+// validateTLS checks that certificates come with their keys and that
+// mutual TLS has a server certificate to go with it
+func validateTLS(c TLSConfig) error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+		return fmt.Errorf("TLS_CLIENT_CERT_FILE and TLS_CLIENT_KEY_FILE must be set together")
+	}
+	if c.ClientCAFile != "" && c.CertFile == "" {
+		return fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE")
+	}
+	if c.MinVersion != "1.2" && c.MinVersion != "1.3" {
+		return fmt.Errorf("tls min_version must be 1.2 or 1.3")
+	}
+	if c.ReloadInterval <= 0 {
+		return fmt.Errorf("tls reload_interval must be positive")
+	}
+	return nil
+}
+
+// validateAdapterHTTP checks the timeout and proxy of a provider's client
+func validateAdapterHTTP(prefix string, c AdapterHTTPConfig) error {
+	if c.Timeout < 0 {
+		return fmt.Errorf("%s_TIMEOUT cannot be negative", prefix)
+	}
+	if c.Proxy == "" {
+		return nil
+	}
+	u, err := url.Parse(c.Proxy)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%s_PROXY must be a URL such as http://proxy:3128", prefix)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	default:
+		return fmt.Errorf("%s_PROXY must use http, https or socks5", prefix)
+	}
+}
+
+// validateIngest checks that ingestion limits and quotas are not negative
+func validateIngest(c IngestConfig) error {
+	if c.MaxRequestBytes < 0 {
+		return fmt.Errorf("ingest max_request_bytes must not be negative")
+	}
+	if c.DailyFiles < 0 || c.DailyBytes < 0 {
+		return fmt.Errorf("ingest daily_files and daily_bytes must not be negative")
+	}
+	for tenant, q := range c.Tenants {
+		if q.DailyFiles < 0 || q.DailyBytes < 0 {
+			return fmt.Errorf("ingest tenant %q daily_files and daily_bytes must not be negative", tenant)
+		}
+	}
+	return nil
+}
+
+// validateGateway checks that the gateway clients have distinct keys and
+// known scopes, and that rate limiting has a burst
+func validateGateway(c GatewayConfig) error {
+	if c.RateLimit > 0 && c.RateBurst <= 0 {
+		return fmt.Errorf("gateway rate_burst must be positive when rate limiting is enabled")
+	}
+	keys := make(map[string]bool)
+	for _, key := range c.APIKeys {
+		keys[key] = true
+	}
+	for i, client := range c.Clients {
+		if client.Key == "" {
+			return fmt.Errorf("gateway client %d (%s) needs a key", i+1, client.Name)
+		}
+		if keys[client.Key] {
+			return fmt.Errorf("gateway client %d (%s) repeats the key of another client or of api_keys", i+1, client.Name)
+		}
+		keys[client.Key] = true
+		for _, scope := range client.Scopes {
+			if !slices.Contains(GatewayScopes, scope) {
+				return fmt.Errorf("gateway client %d (%s) has unknown scope %q; use one of %s",
+					i+1, client.Name, scope, strings.Join(GatewayScopes, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// validateMCP checks that the MCP server offers known tools, that its
+// clients have distinct keys and are allowed offered tools, and that its
+// limits are positive
+func validateMCP(c MCPConfig) error {
+	if !strings.HasPrefix(c.GatewayURL, "http://") && !strings.HasPrefix(c.GatewayURL, "https://") {
+		return fmt.Errorf("mcp gateway_url must be an http or https URL")
+	}
+	offered := c.Tools
+	if len(offered) == 0 {
+		offered = MCPTools
+	}
+	for _, tool := range offered {
+		if !slices.Contains(MCPTools, tool) {
+			return fmt.Errorf("mcp tools has unknown tool %q; use one of %s", tool, strings.Join(MCPTools, ", "))
+		}
+	}
+	keys := make(map[string]bool)
+	for _, key := range c.APIKeys {
+		keys[key] = true
+	}
+	for i, client := range c.Clients {
+		if client.Key == "" {
+			return fmt.Errorf("mcp client %d (%s) needs a key", i+1, client.Name)
+		}
+		if keys[client.Key] {
+			return fmt.Errorf("mcp client %d (%s) repeats the key of another client or of api_keys", i+1, client.Name)
+		}
+		keys[client.Key] = true
+		for _, tool := range client.Tools {
+			if !slices.Contains(offered, tool) {
+				return fmt.Errorf("mcp client %d (%s) is allowed tool %q, which is not offered", i+1, client.Name, tool)
+			}
+		}
+	}
+	if c.MaxResultChars <= 0 {
+		return fmt.Errorf("mcp max_result_chars must be positive")
+	}
+	if c.MaxTopK <= 0 {
+		return fmt.Errorf("mcp max_top_k must be positive")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("mcp timeout must be positive")
+	}
+	return nil
+}
+
+// validateSlack checks that the Slack app answers somewhere, and that each
+// workspace is named once and has a bot token to answer with
+func validateSlack(c SlackConfig) error {
+	if !c.Enabled() {
+		return nil
+	}
+	if !strings.HasPrefix(c.APIURL, "http://") && !strings.HasPrefix(c.APIURL, "https://") {
+		return fmt.Errorf("slack api_url must be an http or https URL")
+	}
+	if c.TopK <= 0 {
+		return fmt.Errorf("slack top_k must be positive")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("slack timeout must be positive")
+	}
+	if c.AnyWorkspace && c.BotToken == "" {
+		return fmt.Errorf("slack any_workspace needs a bot_token to answer with")
+	}
+	if !c.AnyWorkspace && len(c.Workspaces) == 0 {
+		return fmt.Errorf("slack needs workspaces to answer in, or any_workspace to answer in all")
+	}
+	teams := make(map[string]bool)
+	for i, ws := range c.Workspaces {
+		if ws.TeamID == "" {
+			return fmt.Errorf("slack workspace %d (%s) needs a team_id", i+1, ws.Name)
+		}
+		if teams[ws.TeamID] {
+			return fmt.Errorf("slack workspace %s is configured twice", ws.TeamID)
+		}
+		teams[ws.TeamID] = true
+		if ws.BotToken == "" && c.BotToken == "" {
+			return fmt.Errorf("slack workspace %s needs a bot_token", ws.TeamID)
+		}
+		if ws.TopK < 0 {
+			return fmt.Errorf("slack workspace %s top_k cannot be negative", ws.TeamID)
+		}
+	}
+	return nil
+}
+
+// validateCORS checks that allowed origins are * or a scheme and host, and
+// that credentials are not allowed from any origin, which browsers refuse
+func validateCORS(c CORSConfig) error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				return fmt.Errorf("cors allow_credentials cannot be used with the * origin")
+			}
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("cors origin %q must be * or a scheme and host, such as https://app.example.com", origin)
+		}
+	}
+	if len(c.AllowedOrigins) > 0 && len(c.AllowedMethods) == 0 {
+		return fmt.Errorf("cors allowed_methods must not be empty when origins are allowed")
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("cors max_age must not be negative")
+	}
+	return nil
+}
+
+// validateModeration checks the classifier and the moderation policies
+func validateModeration(c ModerationConfig) error {
+	switch c.Classifier {
+	case "none":
+		return nil
+	case "azure", "api":
+		if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
+			return fmt.Errorf("MODERATION_ENDPOINT must be an http or https URL for the %s classifier", c.Classifier)
+		}
+		if c.Classifier == "azure" && c.APIKey == "" {
+			return fmt.Errorf("MODERATION_API_KEY is required for the azure classifier")
+		}
+	default:
+		return fmt.Errorf("moderation classifier must be none, azure or api")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("moderation timeout must be positive")
+	}
+	if err := validateModerationPolicy("moderation", c.Action, c.Threshold); err != nil {
+		return err
+	}
+	for tenant, o := range c.Tenants {
+		action, threshold := o.Action, o.Threshold
+		if action == "" {
+			action = c.Action
+		}
+		if threshold == 0 {
+			threshold = c.Threshold
+		}
+		if err := validateModerationPolicy(fmt.Sprintf("moderation tenant %q", tenant), action, threshold); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateModerationPolicy(name, action string, threshold int) error {
+	if action != "block" && action != "warn" && action != "log" {
+		return fmt.Errorf("%s action must be block, warn or log", name)
+	}
+	if threshold < 1 || threshold > 7 {
+		return fmt.Errorf("%s threshold must be between 1 and 7", name)
+	}
+	return nil
+}
+
+// validatePipeline checks the webhook stages
+func validatePipeline(c PipelineConfig) error {
+	for i, webhook := range c.Webhooks {
+		name := webhook.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if !strings.HasPrefix(webhook.URL, "http://") && !strings.HasPrefix(webhook.URL, "https://") {
+			return fmt.Errorf("pipeline webhook %s url must be an http or https URL", name)
+		}
+		if !slices.Contains(PipelineStages, webhook.After) {
+			return fmt.Errorf("pipeline webhook %s after must be one of %s", name, strings.Join(PipelineStages, ", "))
+		}
+		if webhook.Timeout < 0 {
+			return fmt.Errorf("pipeline webhook %s timeout cannot be negative", name)
+		}
+	}
+	return nil
+}
+
+// validateCitations checks the verification of answer citations
+func validateCitations(c CitationsConfig) error {
+	switch c.Verify {
+	case "none", "overlap", "llm":
+	default:
+		return fmt.Errorf("citations verify must be none, overlap or llm, got %q", c.Verify)
+	}
+	if c.MinOverlap <= 0 || c.MinOverlap > 1 {
+		return fmt.Errorf("citations min_overlap must be greater than 0 and at most 1")
+	}
+	if c.Unsupported != "flag" && c.Unsupported != "strip" {
+		return fmt.Errorf("citations unsupported must be flag or strip, got %q", c.Unsupported)
+	}
+	return nil
+}
+
+// validateRetrievalProfile checks the defaults of a query profile
+func validateRetrievalProfile(config *Config, name string, profile RetrievalProfile) error {
+	if profile.TopK < 0 {
+		return fmt.Errorf("retrieval profile %q top_k cannot be negative", name)
+	}
+	if profile.Mode != "" && profile.Mode != "chunks" && profile.Mode != "two_stage" && profile.Mode != "agent" {
+		return fmt.Errorf("retrieval profile %q mode must be chunks, two_stage or agent", name)
+	}
+	if w := profile.ContextWindow; w != nil && (*w < 0 || *w > 10) {
+		return fmt.Errorf("retrieval profile %q context_window must be between 0 and 10", name)
+	}
+	if h := profile.FreshnessHalfLife; h != nil && *h < 0 {
+		return fmt.Errorf("retrieval profile %q freshness_half_life cannot be negative", name)
+	}
+	var minScore, maxDistance float64
+	if profile.MinScore != nil {
+		minScore = *profile.MinScore
+	}
+	if profile.MaxDistance != nil {
+		maxDistance = *profile.MaxDistance
+	}
+	return validateThresholds(fmt.Sprintf("retrieval profile %q", name), config.Pinecone.Metric, minScore, maxDistance)
+}
+
+// validateThresholds checks a score threshold and a distance limit against
+// the index metric: euclidean scores are distances, so only a distance
+// limit applies, and dotproduct scores are neither bounded nor distances,
+// so only a score threshold does
+func validateThresholds(scope, metric string, minScore, maxDistance float64) error {
+	if maxDistance < 0 {
+		return fmt.Errorf("%s max_distance cannot be negative", scope)
+	}
+	if minScore != 0 && metric == "euclidean" {
+		return fmt.Errorf("%s min_score does not apply to a euclidean index; use max_distance", scope)
+	}
+	if maxDistance != 0 && metric == "dotproduct" {
+		return fmt.Errorf("%s max_distance does not apply to a dotproduct index; use min_score", scope)
+	}
+	return nil
+}
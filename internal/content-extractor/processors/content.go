@@ -0,0 +1,127 @@
+package processors
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Content holds extracted text. It stays in memory up to a threshold and
+// spills to a temporary file beyond that, so large files can be extracted
+// without holding them in memory. Close removes the temporary file.
+type Content struct {
+	maxInMemory int64
+	tempDir     string
+	buf         bytes.Buffer
+	file        *os.File
+	size        int64
+	encoding    string
+	sectioned   bool
+}
+
+// SectionBreak separates self-contained sections of extracted text, such
+// as the operations of an API specification. Chunking starts a new chunk
+// at every break, so no chunk mixes two sections.
+const SectionBreak = '\x1e' // ASCII record separator
+
+// NewContent creates an empty content buffer that keeps up to maxInMemory
+// bytes in memory. An empty tempDir uses the system temp directory.
+func NewContent(maxInMemory int64, tempDir string) *Content {
+	return &Content{maxInMemory: maxInMemory, tempDir: tempDir}
+}
+
+// Write appends extracted text, spilling to a temporary file once the
+// in-memory threshold is exceeded
+func (c *Content) Write(p []byte) (int, error) {
+	if c.file == nil && int64(c.buf.Len()+len(p)) > c.maxInMemory {
+		if err := c.spill(); err != nil {
+			return 0, err
+		}
+	}
+
+	if !c.sectioned && bytes.IndexByte(p, SectionBreak) >= 0 {
+		c.sectioned = true
+	}
+
+	var n int
+	var err error
+	if c.file != nil {
+		n, err = c.file.Write(p)
+	} else {
+		n, err = c.buf.Write(p)
+	}
+	c.size += int64(n)
+	return n, err
+}
+
+// Encoding returns the encoding the text was decoded from, or an empty
+// string when it was not normalized
+func (c *Content) Encoding() string {
+	return c.encoding
+}
+
+func (c *Content) spill() error {
+	file, err := os.CreateTemp(c.tempDir, "extract-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create spillover file: %w", err)
+	}
+	if _, err := file.Write(c.buf.Bytes()); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return fmt.Errorf("failed to write spillover file: %w", err)
+	}
+	c.file = file
+	c.buf = bytes.Buffer{}
+	return nil
+}
+
+// Sectioned reports whether the text holds section breaks
+func (c *Content) Sectioned() bool {
+	return c.sectioned
+}
+
+// Size returns the number of bytes extracted
+func (c *Content) Size() int64 {
+	return c.size
+}
+
+// Spilled reports whether the content was moved to a temporary file
+func (c *Content) Spilled() bool {
+	return c.file != nil
+}
+
+// Reader returns a reader over the whole content from the start
+func (c *Content) Reader() io.Reader {
+	if c.file != nil {
+		return io.NewSectionReader(c.file, 0, c.size)
+	}
+	return bytes.NewReader(c.buf.Bytes())
+}
+
+// Prefix returns up to the first n bytes of the content
+func (c *Content) Prefix(n int) (string, error) {
+	if int64(n) > c.size {
+		n = int(c.size)
+	}
+	if c.file == nil {
+		return string(c.buf.Bytes()[:n]), nil
+	}
+	data := make([]byte, n)
+	if _, err := c.file.ReadAt(data, 0); err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read spillover file: %w", err)
+	}
+	return string(data), nil
+}
+
+// Close releases the content, removing the temporary file if there is one
+func (c *Content) Close() error {
+	c.buf = bytes.Buffer{}
+	if c.file == nil {
+		return nil
+	}
+	name := c.file.Name()
+	c.file.Close()
+	c.file = nil
+	return os.Remove(name)
+}
@@ -0,0 +1,263 @@
+package processors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// convertWaitDelay is how long a converter that has been killed or has
+// exited may keep its output open before it is abandoned
+const convertWaitDelay = 5 * time.Second
+
+// ConvertProcessor handles documents without a native extractor, such as
+// legacy .doc, .ppt and .xls files, by converting them to PDF with an
+// external converter and extracting the PDF. The converter is LibreOffice
+// run headless in a private temp directory, or a conversion service that
+// takes the document as a multipart upload in a "files" field and returns
+// the PDF, as Gotenberg's LibreOffice route does.
+type ConvertProcessor struct {
+	logger  *zap.Logger
+	config  config.ConversionConfig
+	tempDir string
+	pdf     *DocumentProcessor
+	client  *http.Client
+	slots   chan struct{}
+}
+
+// NewConvertProcessor creates a new conversion processor; tempDir holds
+// the working directories of conversions, empty for the system temp directory
+func NewConvertProcessor(logger *zap.Logger, cfg config.ConversionConfig, tempDir string) *ConvertProcessor {
+	return &ConvertProcessor{
+		logger:  logger,
+		config:  cfg,
+		tempDir: tempDir,
+		pdf:     NewDocumentProcessor(logger),
+		client:  &http.Client{},
+		slots:   make(chan struct{}, max(cfg.MaxConcurrent, 1)),
+	}
+}
+
+// CanProcess checks if this processor can handle the file type: one of
+// the configured extensions, when a converter is configured
+func (p *ConvertProcessor) CanProcess(fileType string) bool {
+	if p.config.SofficePath == "" && p.config.URL == "" {
+		return false
+	}
+	ext := strings.TrimPrefix(fileType, ".")
+	for _, e := range p.config.Extensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe returns the formats the processor converts and its settings. It
+// handles none while no converter is configured.
+func (p *ConvertProcessor) Describe() Capabilities {
+	c := Capabilities{
+		Name:           "convert",
+		Category:       "document",
+		Formats:        []Format{},
+		ExtractOptions: []string{OptionIncludeTables, OptionMaxPages},
+		Options: []Option{
+			{"CONVERSION_SOFFICE_PATH", "LibreOffice soffice binary", p.config.SofficePath},
+			{"CONVERSION_URL", "Conversion service, used instead of soffice when set", p.config.URL},
+			{"CONVERSION_TIMEOUT", "Longest a conversion may take", p.config.Timeout.String()},
+			{"CONVERSION_MAX_CONCURRENT", "Conversions run at once", p.config.MaxConcurrent},
+			{"CONVERSION_MAX_OUTPUT", "Largest converted PDF in bytes", p.config.MaxOutput},
+		},
+	}
+	if p.config.SofficePath != "" || p.config.URL != "" {
+		c.Formats = extensionFormats(p.config.Extensions)
+	}
+	return c
+}
+
+// Extract converts a document to PDF and extracts the PDF's text
+func (p *ConvertProcessor) Extract(ctx context.Context, filePath string) (string, error) {
+	p.logger.Debug("Converting document", zap.String("file", filePath))
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	case <-ctx.Done():
+		return "", fmt.Errorf("conversion did not start within %s: %w", p.config.Timeout, ctx.Err())
+	}
+
+	dir, err := os.MkdirTemp(p.tempDir, "convert-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create conversion directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The copy is named for the detected type, which picks the converter's
+	// import filter: a Word document named .txt still converts as Word
+	stem := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	if stem == "" || strings.HasPrefix(stem, ".") {
+		stem = "document"
+	}
+	input := filepath.Join(dir, stem+fileType(ctx, filePath))
+	if err := copyTo(ctx, filePath, input); err != nil {
+		return "", fmt.Errorf("failed to copy document for conversion: %w", err)
+	}
+
+	output := filepath.Join(dir, "out", stem+".pdf")
+	if p.config.URL != "" {
+		err = p.convertRemote(ctx, input, output)
+	} else {
+		err = p.convertLocal(ctx, dir, input, output)
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("conversion timed out after %s", p.config.Timeout)
+		}
+		return "", err
+	}
+
+	p.logger.Debug("Converted document", zap.String("file", filePath))
+	return p.pdf.extractPDF(output, OptionsFrom(ctx))
+}
+
+// convertLocal converts input to the output PDF with LibreOffice. It runs
+// with a profile, home and temp directory inside dir, a minimal environment
+// and in its own process group, so that a timeout kills every process it
+// started and nothing it writes outlives the conversion.
+func (p *ConvertProcessor) convertLocal(ctx context.Context, dir, input, output string) error {
+	profile := filepath.ToSlash(filepath.Join(dir, "profile"))
+	if !strings.HasPrefix(profile, "/") {
+		profile = "/" + profile
+	}
+	cmd := exec.CommandContext(ctx, p.config.SofficePath,
+		"-env:UserInstallation=file://"+profile,
+		"--headless", "--invisible", "--norestore", "--nolockcheck",
+		"--nodefault", "--nologo", "--nofirststartwizard",
+		"--convert-to", "pdf", "--outdir", filepath.Dir(output), input)
+	cmd.Dir = dir
+	sandboxCommand(cmd, dir)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = convertWaitDelay
+
+	var log bytes.Buffer
+	cmd.Stdout = &limitedWriter{w: &log, n: 4096}
+	cmd.Stderr = cmd.Stdout
+	err := cmd.Run()
+	if cmd.Process != nil {
+		// Helper processes may outlive soffice itself
+		_ = killProcessGroup(cmd)
+	}
+	if err != nil {
+		return fmt.Errorf("soffice failed: %w: %s", err, strings.TrimSpace(log.String()))
+	}
+	return p.checkOutput(output, log.String())
+}
+
+// convertRemote converts input to the output PDF with the conversion service
+func (p *ConvertProcessor) convertRemote(ctx context.Context, input, output string) error {
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeUpload(form, input))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, body)
+	if err != nil {
+		return fmt.Errorf("failed to create conversion request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("conversion request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("conversion service returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0o700); err != nil {
+		return fmt.Errorf("failed to create conversion output: %w", err)
+	}
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create conversion output: %w", err)
+	}
+	defer file.Close()
+	n, err := io.Copy(file, io.LimitReader(resp.Body, p.config.MaxOutput+1))
+	if err != nil {
+		return fmt.Errorf("failed to read converted document: %w", err)
+	}
+	if n > p.config.MaxOutput {
+		return fmt.Errorf("converted document is larger than %d bytes", p.config.MaxOutput)
+	}
+	return nil
+}
+
+// checkOutput checks that a conversion wrote a PDF of an allowed size
+func (p *ConvertProcessor) checkOutput(output, log string) error {
+	info, err := os.Stat(output)
+	if err != nil {
+		return fmt.Errorf("converter produced no PDF: %s", strings.TrimSpace(log))
+	}
+	if info.Size() > p.config.MaxOutput {
+		return fmt.Errorf("converted document is larger than %d bytes", p.config.MaxOutput)
+	}
+	return nil
+}
+
+// writeUpload writes a file as the "files" field of a multipart form
+func writeUpload(form *multipart.Writer, filePath string) error {
+	part, err := form.CreateFormFile("files", filepath.Base(filePath))
+	if err != nil {
+		return err
+	}
+	if err := copyFile(context.Background(), filePath, part); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// copyTo copies a file to a new file at dst
+func copyTo(ctx context.Context, src, dst string) error {
+	file, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := copyFile(ctx, src, file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// limitedWriter keeps the first n bytes written to it and discards the rest
+type limitedWriter struct {
+	w io.Writer
+	n int
+}
+
+func (l *limitedWriter) Write(b []byte) (int, error) {
+	if l.n > 0 {
+		keep := b[:min(len(b), l.n)]
+		l.n -= len(keep)
+		if _, err := l.w.Write(keep); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
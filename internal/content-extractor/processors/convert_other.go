@@ -0,0 +1,32 @@
+//go:build !windows
+
+package processors
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// sandboxCommand gives a converter a minimal environment with its home and
+// temp directory in dir, and starts it in a process group of its own
+func sandboxCommand(cmd *exec.Cmd, dir string) {
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"LANG=C.UTF-8",
+		"SAL_USE_VCLPLUGIN=svp",
+	}
+	ownProcessGroup(cmd)
+}
+
+// ownProcessGroup starts a command in a process group of its own
+func ownProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills a command and every process it started
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
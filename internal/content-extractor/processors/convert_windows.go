@@ -0,0 +1,28 @@
+//go:build windows
+
+package processors
+
+import (
+	"os"
+	"os/exec"
+)
+
+// sandboxCommand gives a converter a minimal environment with its home and
+// temp directory in dir
+func sandboxCommand(cmd *exec.Cmd, dir string) {
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"SystemRoot=" + os.Getenv("SystemRoot"),
+		"USERPROFILE=" + dir,
+		"TEMP=" + dir,
+		"TMP=" + dir,
+	}
+}
+
+// ownProcessGroup does nothing on Windows, where commands are killed alone
+func ownProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills a command
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
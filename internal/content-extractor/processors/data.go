@@ -0,0 +1,159 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/datafile"
+	"go.uber.org/zap"
+)
+
+// DataProcessor handles data files: SQLite databases and Parquet files.
+// An overview of the file's tables comes first, then a section per table
+// with its columns and indexes, followed by a section of its first rows,
+// so questions about what data a dataset holds find the tables that hold it.
+type DataProcessor struct {
+	logger *zap.Logger
+	config config.DataFilesConfig
+}
+
+// NewDataProcessor creates a new data file processor
+func NewDataProcessor(logger *zap.Logger, cfg config.DataFilesConfig) *DataProcessor {
+	return &DataProcessor{logger: logger, config: cfg}
+}
+
+// CanProcess checks if this processor can handle the file type
+func (p *DataProcessor) CanProcess(fileType string) bool {
+	return datafile.IsDataFile(fileType)
+}
+
+// Describe returns the formats the processor handles and its settings
+func (p *DataProcessor) Describe() Capabilities {
+	return Capabilities{
+		Name:     "data",
+		Category: "data",
+		Formats: []Format{
+			{"parquet", "application/vnd.apache.parquet"}, {"sqlite", "application/vnd.sqlite3"},
+			{"sqlite3", "application/vnd.sqlite3"}, {"db", ""}, {"db3", ""},
+		},
+		Options: []Option{
+			{"DATA_FILES_SAMPLE_ROWS", "Rows sampled per table, 0 describes schemas only", p.config.SampleRows},
+		},
+	}
+}
+
+// Extract extracts the description of a data file
+func (p *DataProcessor) Extract(ctx context.Context, filePath string) (string, error) {
+	var b strings.Builder
+	if err := p.ExtractTo(ctx, filePath, &b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ExtractTo writes the description of a data file into w, in sections
+// separated by SectionBreak
+func (p *DataProcessor) ExtractTo(ctx context.Context, filePath string, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	info, err := datafile.Read(filePath, p.config.SampleRows)
+	if err != nil {
+		return fmt.Errorf("failed to read data file: %w", err)
+	}
+
+	p.logger.Debug("Extracted data file",
+		zap.String("file", filePath),
+		zap.String("format", info.Format),
+		zap.Int("tables", len(info.Tables)))
+	_, err = io.WriteString(w, writeDataFile(filepath.Base(filePath), info))
+	return err
+}
+
+// writeDataFile returns the text of a data file's description
+func writeDataFile(name string, info *datafile.Info) string {
+	format := map[string]string{datafile.FormatSQLite: "SQLite", datafile.FormatParquet: "Parquet"}[info.Format]
+
+	var overview strings.Builder
+	fmt.Fprintf(&overview, "Dataset: %s\n", name)
+	writeField(&overview, "Format", format)
+	writeField(&overview, "Written by", info.Creator)
+	var tables, views []string
+	for _, table := range info.Tables {
+		if table.Kind == "view" {
+			views = append(views, table.Name)
+		} else {
+			tables = append(tables, fmt.Sprintf("%s (%s, %d columns)", table.Name, rowCount(table.Rows), len(table.Columns)))
+		}
+	}
+	writeField(&overview, "Tables", strings.Join(tables, ", "))
+	writeField(&overview, "Views", strings.Join(views, ", "))
+
+	sections := []string{overview.String()}
+	for _, table := range info.Tables {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %s: %s\n", format, table.Kind, table.Name)
+		writeField(&b, "Dataset", name)
+		if table.Kind != "view" {
+			writeField(&b, "Rows", strconv.FormatInt(table.Rows, 10))
+		}
+		writeField(&b, "Definition", table.Definition)
+		columns := make([]string, len(table.Columns))
+		for i, column := range table.Columns {
+			columns[i] = columnLine(column)
+		}
+		writeList(&b, "Columns", columns)
+		writeList(&b, "Indexes", table.Indexes)
+		writeField(&b, "Note", table.Note)
+		sections = append(sections, b.String())
+
+		if len(table.Sample) == 0 {
+			continue
+		}
+		b.Reset()
+		fmt.Fprintf(&b, "Sample rows of %s %s: %s (first %d of %d)\n", format, table.Kind, table.Name, len(table.Sample), table.Rows)
+		header := make([]string, len(table.Columns))
+		for i, column := range table.Columns {
+			header[i] = column.Name
+		}
+		writeTable(&b, append([][]string{header}, table.Sample...))
+		sections = append(sections, b.String())
+	}
+	return strings.Join(sections, "\n"+string(SectionBreak)+"\n")
+}
+
+// columnLine describes a column on one line, such as
+// "customer_id INTEGER, not null, references customers(id)"
+func columnLine(column datafile.Column) string {
+	line := strings.TrimSpace(column.Name + " " + column.Type)
+	if column.PrimaryKey {
+		line += ", primary key"
+	}
+	if column.NotNull && !column.PrimaryKey {
+		line += ", not null"
+	}
+	if column.References != "" {
+		line += ", references " + column.References
+	}
+	if column.Min != "" || column.Max != "" {
+		line += ", values " + column.Min + " to " + column.Max
+	}
+	if column.Nulls == 1 {
+		line += ", 1 null"
+	} else if column.Nulls > 1 {
+		line += fmt.Sprintf(", %d nulls", column.Nulls)
+	}
+	return line
+}
+
+func rowCount(n int64) string {
+	if n == 1 {
+		return "1 row"
+	}
+	return strconv.FormatInt(n, 10) + " rows"
+}
@@ -0,0 +1,161 @@
+package processors
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/safeparse"
+)
+
+// docxTable is a table of a DOCX document as it is read
+type docxTable struct {
+	rows [][]string
+}
+
+// addCell starts a cell in the last row. A cell spanning columns leaves
+// the columns after it empty, so the cells of later rows stay under their
+// headers.
+func (t *docxTable) addCell(span int) {
+	if len(t.rows) == 0 {
+		t.rows = append(t.rows, nil)
+	}
+	row := &t.rows[len(t.rows)-1]
+	for i := 0; i < span; i++ {
+		*row = append(*row, "")
+	}
+}
+
+// appendText adds a paragraph to the cell being read, which spans the
+// last span columns of its row
+func (t *docxTable) appendText(text string, span int) {
+	if len(t.rows) == 0 || len(t.rows[len(t.rows)-1]) == 0 {
+		return
+	}
+	row := t.rows[len(t.rows)-1]
+	i := len(row) - span
+	row[i] = strings.TrimSpace(row[i] + " " + text)
+}
+
+// extractDOCXText reads the main document part of a DOCX file: paragraphs
+// one per line, and tables as rows of named cells, or left out without
+// includeTables. Tables nested in a cell are flattened into its text. The
+// archive and the part are read within the safeparse limits, so zip and
+// XML bombs fail with safeparse.ErrLimit.
+func extractDOCXText(filePath string, includeTables bool) (string, int, error) {
+	archive, err := safeparse.OpenZip(filePath, safeparse.DefaultZipLimits)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open DOCX: %w", err)
+	}
+	defer archive.Close()
+
+	var part *zip.File
+	for _, f := range archive.File {
+		if f.Name == "word/document.xml" {
+			part = f
+			break
+		}
+	}
+	if part == nil {
+		return "", 0, fmt.Errorf("failed to read DOCX: word/document.xml is missing")
+	}
+	r, err := part.Open()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read DOCX: %w", err)
+	}
+	defer r.Close()
+
+	var (
+		b         strings.Builder
+		paragraph strings.Builder
+		tables    []*docxTable
+		spans     []int // span of the cell being read in each open table
+		count     int
+		inText    bool
+	)
+	decoder := safeparse.NewXMLDecoder(r, safeparse.DefaultXMLLimits)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to parse DOCX: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				paragraph.WriteByte('\t')
+			case "br", "cr":
+				paragraph.WriteByte(' ')
+			case "tbl":
+				tables = append(tables, &docxTable{})
+				spans = append(spans, 1)
+			case "tr":
+				if n := len(tables); n > 0 {
+					tables[n-1].rows = append(tables[n-1].rows, nil)
+				}
+			case "tc":
+				if n := len(tables); n > 0 {
+					spans[n-1] = 1
+					tables[n-1].addCell(1)
+				}
+			case "gridSpan":
+				if n := len(tables); n > 0 {
+					for _, attr := range t.Attr {
+						if span, err := strconv.Atoi(attr.Value); attr.Name.Local == "val" && err == nil && span > 1 && span <= 64 {
+							tables[n-1].addCell(span - 1)
+							spans[n-1] = span
+						}
+					}
+				}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(paragraph.String())
+				paragraph.Reset()
+				if n := len(tables); n > 0 {
+					tables[n-1].appendText(text, spans[n-1])
+				} else if text != "" {
+					b.WriteString(text + "\n")
+				}
+			case "tbl":
+				n := len(tables)
+				if n == 0 {
+					continue
+				}
+				table := tables[n-1]
+				tables, spans = tables[:n-1], spans[:n-1]
+				if n > 1 {
+					var nested strings.Builder
+					writeTable(&nested, table.rows)
+					tables[n-2].appendText(strings.ReplaceAll(strings.TrimSpace(nested.String()), "\n", " / "), spans[n-2])
+					continue
+				}
+				if includeTables {
+					b.WriteString("\n")
+					writeTable(&b, table.rows)
+					b.WriteString("\n")
+				}
+				count++
+			}
+
+		case xml.CharData:
+			if inText {
+				paragraph.Write(t)
+			}
+		}
+	}
+	return b.String(), count, nil
+}
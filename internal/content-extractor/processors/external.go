@@ -0,0 +1,148 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ExternalProcessor hands files of configured extensions to external
+// extractors over HTTP, for formats no built-in processor handles. The
+// file is posted as the "file" field of a multipart form, with its
+// extension, without the dot, in the "file_type" field. The extractor
+// answers 200 with the extracted text, UTF-8 encoded, as the response body.
+type ExternalProcessor struct {
+	logger     *zap.Logger
+	extractors map[string]string // extractor URL by lower-case extension without the dot
+	timeout    time.Duration
+	client     *http.Client
+}
+
+// NewExternalProcessor creates a processor for the extractors configured
+// by extension
+func NewExternalProcessor(logger *zap.Logger, extractors map[string]string, timeout time.Duration) *ExternalProcessor {
+	byExt := make(map[string]string, len(extractors))
+	for ext, url := range extractors {
+		byExt[strings.ToLower(strings.TrimPrefix(ext, "."))] = url
+	}
+	return &ExternalProcessor{
+		logger:     logger,
+		extractors: byExt,
+		timeout:    timeout,
+		client:     &http.Client{},
+	}
+}
+
+// CanProcess checks if an external extractor is configured for the file type
+func (p *ExternalProcessor) CanProcess(fileType string) bool {
+	_, ok := p.extractors[strings.ToLower(strings.TrimPrefix(fileType, "."))]
+	return ok
+}
+
+// Describe returns the formats with an external extractor and its settings
+func (p *ExternalProcessor) Describe() Capabilities {
+	c := Capabilities{
+		Name:     "external",
+		Category: "external",
+		Formats:  extensionFormats(p.Extensions()),
+		Options: []Option{
+			{"EXTRACTION_EXTERNAL_TIMEOUT", "Longest an external extractor may take", p.timeout.String()},
+		},
+	}
+	for _, ext := range p.Extensions() {
+		c.Options = append(c.Options, Option{"extractors." + ext, "Extractor of ." + ext + " files", p.extractors[ext]})
+	}
+	return c
+}
+
+// Extensions lists the extensions with an external extractor, sorted
+func (p *ExternalProcessor) Extensions() []string {
+	extensions := make([]string, 0, len(p.extractors))
+	for ext := range p.extractors {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// Extract returns the text the external extractor extracts from a file
+func (p *ExternalProcessor) Extract(ctx context.Context, filePath string) (string, error) {
+	var text strings.Builder
+	if err := p.ExtractTo(ctx, filePath, &text); err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
+
+// ExtractTo streams the file to its external extractor and the extracted
+// text into w
+func (p *ExternalProcessor) ExtractTo(ctx context.Context, filePath string, w io.Writer) error {
+	ext := strings.TrimPrefix(fileType(ctx, filePath), ".")
+	url, ok := p.extractors[ext]
+	if !ok {
+		return fmt.Errorf("no external extractor for %s files", ext)
+	}
+	p.logger.Debug("Extracting with external extractor",
+		zap.String("file", filePath),
+		zap.String("extractor", url))
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeExtractUpload(ctx, form, filePath, ext))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return fmt.Errorf("failed to create extraction request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("external extractor timed out after %s", p.timeout)
+		}
+		return fmt.Errorf("extraction request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("external extractor returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("external extractor timed out after %s", p.timeout)
+		}
+		return fmt.Errorf("failed to read extracted text: %w", err)
+	}
+	return nil
+}
+
+// writeExtractUpload writes a file as the "file" field of a multipart
+// form, after its type in the "file_type" field
+func writeExtractUpload(ctx context.Context, form *multipart.Writer, filePath, ext string) error {
+	if err := form.WriteField("file_type", ext); err != nil {
+		return err
+	}
+	part, err := form.CreateFormFile("file", filepath.Base(filePath))
+	if err != nil {
+		return err
+	}
+	if err := copyFile(ctx, filePath, part); err != nil {
+		return err
+	}
+	return form.Close()
+}
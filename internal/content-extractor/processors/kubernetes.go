@@ -0,0 +1,342 @@
+package processors
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// maxAnnotationValue is the longest annotation value written as it is;
+// longer values, such as embedded configuration, are cut
+const maxAnnotationValue = 120
+
+// parseManifests reads the Kubernetes objects of a YAML stream or a JSON
+// document, expanding List objects into their items. It returns nil when
+// the data holds no object with an apiVersion and a kind.
+func parseManifests(data []byte) []*yaml.Node {
+	var objects []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil
+		}
+		objects = appendManifest(objects, yamlNode(&doc))
+	}
+	return objects
+}
+
+func appendManifest(objects []*yaml.Node, n *yaml.Node) []*yaml.Node {
+	if yamlString(yamlKey(n, "apiVersion")) == "" || yamlString(yamlKey(n, "kind")) == "" {
+		return objects
+	}
+	if strings.HasSuffix(yamlString(yamlKey(n, "kind")), "List") {
+		for _, item := range yamlItems(yamlKey(n, "items")) {
+			objects = appendManifest(objects, item)
+		}
+		return objects
+	}
+	return append(objects, n)
+}
+
+// writeManifests returns the text of Kubernetes objects, one section per
+// object separated by SectionBreak, each naming the object and its key
+// attributes. Secret values are never written.
+func writeManifests(objects []*yaml.Node) string {
+	exposedBy := make(map[string][]string) // service to the ingresses routing to it
+	for _, obj := range objects {
+		if yamlString(yamlKey(obj, "kind")) == "Ingress" {
+			ns := yamlString(yamlKey(yamlKey(obj, "metadata"), "namespace"))
+			for _, svc := range ingressServices(yamlKey(obj, "spec")) {
+				key := qualifyName(ns, svc)
+				exposedBy[key] = appendOnce(exposedBy[key], objectName(obj))
+			}
+		}
+	}
+
+	sections := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		var b strings.Builder
+		kind := yamlString(yamlKey(obj, "kind"))
+		meta := yamlKey(obj, "metadata")
+		fmt.Fprintf(&b, "Kubernetes %s: %s\n", kind, objectName(obj))
+		writeField(&b, "API version", yamlString(yamlKey(obj, "apiVersion")))
+		writeField(&b, "Labels", keyValues(yamlKey(meta, "labels"), 0))
+		writeField(&b, "Annotations", keyValues(yamlKey(meta, "annotations"), maxAnnotationValue))
+
+		spec := yamlKey(obj, "spec")
+		switch kind {
+		case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController":
+			writeField(&b, "Replicas", yamlString(yamlKey(spec, "replicas")))
+			writeField(&b, "Selector", keyValues(yamlKey(yamlKey(spec, "selector"), "matchLabels"), 0))
+			writeField(&b, "Service name", yamlString(yamlKey(spec, "serviceName")))
+			writePodSpec(&b, yamlKey(yamlKey(spec, "template"), "spec"))
+		case "Job":
+			writePodSpec(&b, yamlKey(yamlKey(spec, "template"), "spec"))
+		case "CronJob":
+			writeField(&b, "Schedule", yamlString(yamlKey(spec, "schedule")))
+			writePodSpec(&b, yamlKey(yamlKey(yamlKey(yamlKey(spec, "jobTemplate"), "spec"), "template"), "spec"))
+		case "Pod":
+			writePodSpec(&b, spec)
+		case "Service":
+			writeService(&b, spec)
+			writeField(&b, "Exposed by ingresses", strings.Join(exposedBy[objectName(obj)], ", "))
+		case "Ingress":
+			writeIngress(&b, spec)
+		case "ConfigMap":
+			writeField(&b, "Data keys", strings.Join(mapKeys(yamlKey(obj, "data"), yamlKey(obj, "binaryData")), ", "))
+		case "Secret":
+			writeField(&b, "Type", yamlString(yamlKey(obj, "type")))
+			writeField(&b, "Data keys", strings.Join(mapKeys(yamlKey(obj, "data"), yamlKey(obj, "stringData")), ", "))
+		case "HorizontalPodAutoscaler":
+			target := yamlKey(spec, "scaleTargetRef")
+			writeField(&b, "Scales", yamlString(yamlKey(target, "kind"))+" "+yamlString(yamlKey(target, "name")))
+			writeField(&b, "Min replicas", yamlString(yamlKey(spec, "minReplicas")))
+			writeField(&b, "Max replicas", yamlString(yamlKey(spec, "maxReplicas")))
+		case "PersistentVolumeClaim":
+			writeField(&b, "Storage class", yamlString(yamlKey(spec, "storageClassName")))
+			writeField(&b, "Access modes", strings.Join(yamlStrings(yamlKey(spec, "accessModes")), ", "))
+			writeField(&b, "Storage", yamlString(yamlKey(yamlKey(yamlKey(spec, "resources"), "requests"), "storage")))
+		case "RoleBinding", "ClusterRoleBinding":
+			ref := yamlKey(obj, "roleRef")
+			writeField(&b, "Role", yamlString(yamlKey(ref, "kind"))+" "+yamlString(yamlKey(ref, "name")))
+			var subjects []string
+			for _, s := range yamlItems(yamlKey(obj, "subjects")) {
+				subjects = append(subjects, yamlString(yamlKey(s, "kind"))+" "+yamlString(yamlKey(s, "name")))
+			}
+			writeField(&b, "Subjects", strings.Join(subjects, ", "))
+		default:
+			writeField(&b, "Spec fields", strings.Join(mapKeys(spec), ", "))
+		}
+		sections = append(sections, b.String())
+	}
+	return strings.Join(sections, "\n"+string(SectionBreak)+"\n")
+}
+
+// objectName is the name of an object, qualified by its namespace
+func objectName(obj *yaml.Node) string {
+	meta := yamlKey(obj, "metadata")
+	name := yamlString(yamlKey(meta, "name"))
+	if name == "" {
+		name = yamlString(yamlKey(meta, "generateName")) + "*"
+	}
+	return qualifyName(yamlString(yamlKey(meta, "namespace")), name)
+}
+
+// qualifyName prefixes a name with its namespace, if any
+func qualifyName(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// writePodSpec writes the containers of a pod template with their images,
+// ports, environment and the config maps, secrets and volumes they use
+func writePodSpec(b *strings.Builder, spec *yaml.Node) {
+	if spec == nil {
+		return
+	}
+	writeField(b, "Service account", yamlString(yamlKey(spec, "serviceAccountName")))
+	var containers []string
+	for _, key := range []string{"initContainers", "containers"} {
+		for _, c := range yamlItems(yamlKey(spec, key)) {
+			line := yamlString(yamlKey(c, "name")) + ": " + yamlString(yamlKey(c, "image"))
+			if key == "initContainers" {
+				line += " (init)"
+			}
+			var ports []string
+			for _, p := range yamlItems(yamlKey(c, "ports")) {
+				port := yamlString(yamlKey(p, "containerPort"))
+				if name := yamlString(yamlKey(p, "name")); name != "" {
+					port = name + " " + port
+				}
+				ports = append(ports, port)
+			}
+			if len(ports) > 0 {
+				line += "; ports " + strings.Join(ports, ", ")
+			}
+			var env []string
+			for _, e := range yamlItems(yamlKey(c, "env")) {
+				env = append(env, yamlString(yamlKey(e, "name")))
+			}
+			if len(env) > 0 {
+				line += "; env " + strings.Join(env, ", ")
+			}
+			for _, from := range yamlItems(yamlKey(c, "envFrom")) {
+				if ref := yamlString(yamlKey(yamlKey(from, "configMapRef"), "name")); ref != "" {
+					line += "; env from config map " + ref
+				}
+				if ref := yamlString(yamlKey(yamlKey(from, "secretRef"), "name")); ref != "" {
+					line += "; env from secret " + ref
+				}
+			}
+			containers = append(containers, line)
+		}
+	}
+	writeList(b, "Containers", containers)
+
+	var volumes []string
+	for _, v := range yamlItems(yamlKey(spec, "volumes")) {
+		line := yamlString(yamlKey(v, "name"))
+		switch {
+		case yamlKey(v, "configMap") != nil:
+			line += ": config map " + yamlString(yamlKey(yamlKey(v, "configMap"), "name"))
+		case yamlKey(v, "secret") != nil:
+			line += ": secret " + yamlString(yamlKey(yamlKey(v, "secret"), "secretName"))
+		case yamlKey(v, "persistentVolumeClaim") != nil:
+			line += ": claim " + yamlString(yamlKey(yamlKey(v, "persistentVolumeClaim"), "claimName"))
+		}
+		volumes = append(volumes, line)
+	}
+	writeList(b, "Volumes", volumes)
+}
+
+func writeService(b *strings.Builder, spec *yaml.Node) {
+	t := yamlString(yamlKey(spec, "type"))
+	if t == "" {
+		t = "ClusterIP"
+	}
+	writeField(b, "Type", t)
+	writeField(b, "External name", yamlString(yamlKey(spec, "externalName")))
+	writeField(b, "Selector", keyValues(yamlKey(spec, "selector"), 0))
+	var ports []string
+	for _, p := range yamlItems(yamlKey(spec, "ports")) {
+		line := yamlString(yamlKey(p, "port"))
+		if target := yamlString(yamlKey(p, "targetPort")); target != "" {
+			line += " -> " + target
+		}
+		if proto := yamlString(yamlKey(p, "protocol")); proto != "" {
+			line += "/" + proto
+		}
+		if np := yamlString(yamlKey(p, "nodePort")); np != "" {
+			line += ", node port " + np
+		}
+		if name := yamlString(yamlKey(p, "name")); name != "" {
+			line = name + " " + line
+		}
+		ports = append(ports, line)
+	}
+	writeList(b, "Ports", ports)
+}
+
+func writeIngress(b *strings.Builder, spec *yaml.Node) {
+	writeField(b, "Ingress class", yamlString(yamlKey(spec, "ingressClassName")))
+	var routes []string
+	if backend := ingressBackend(yamlKey(spec, "defaultBackend")); backend != "" {
+		routes = append(routes, "default -> "+backend)
+	}
+	if backend := ingressBackend(yamlKey(spec, "backend")); backend != "" {
+		routes = append(routes, "default -> "+backend)
+	}
+	for _, rule := range yamlItems(yamlKey(spec, "rules")) {
+		host := yamlString(yamlKey(rule, "host"))
+		if host == "" {
+			host = "*"
+		}
+		for _, p := range yamlItems(yamlKey(yamlKey(rule, "http"), "paths")) {
+			line := host + yamlString(yamlKey(p, "path"))
+			if t := yamlString(yamlKey(p, "pathType")); t != "" {
+				line += " (" + t + ")"
+			}
+			routes = append(routes, line+" -> "+ingressBackend(yamlKey(p, "backend")))
+		}
+	}
+	writeList(b, "Routes", routes)
+	writeField(b, "Exposes services", strings.Join(ingressServices(spec), ", "))
+
+	var tls []string
+	for _, t := range yamlItems(yamlKey(spec, "tls")) {
+		line := strings.Join(yamlStrings(yamlKey(t, "hosts")), ", ")
+		if secret := yamlString(yamlKey(t, "secretName")); secret != "" {
+			line += " (secret " + secret + ")"
+		}
+		tls = append(tls, line)
+	}
+	writeField(b, "TLS", strings.Join(tls, "; "))
+}
+
+// ingressBackend describes the backend of an ingress route, written the
+// networking.k8s.io/v1 way or the older serviceName/servicePort way
+func ingressBackend(backend *yaml.Node) string {
+	if backend == nil {
+		return ""
+	}
+	if svc := yamlKey(backend, "service"); svc != nil {
+		port := yamlKey(svc, "port")
+		p := yamlString(yamlKey(port, "number"))
+		if p == "" {
+			p = yamlString(yamlKey(port, "name"))
+		}
+		return "service " + joinPort(yamlString(yamlKey(svc, "name")), p)
+	}
+	if name := yamlString(yamlKey(backend, "serviceName")); name != "" {
+		return "service " + joinPort(name, yamlString(yamlKey(backend, "servicePort")))
+	}
+	if res := yamlKey(backend, "resource"); res != nil {
+		return yamlString(yamlKey(res, "kind")) + " " + yamlString(yamlKey(res, "name"))
+	}
+	return ""
+}
+
+func joinPort(name, port string) string {
+	if port == "" {
+		return name
+	}
+	return name + ":" + port
+}
+
+// ingressServices returns the services an ingress spec routes to
+func ingressServices(spec *yaml.Node) []string {
+	var services []string
+	add := func(backend *yaml.Node) {
+		name := yamlString(yamlKey(yamlKey(backend, "service"), "name"))
+		if name == "" {
+			name = yamlString(yamlKey(backend, "serviceName"))
+		}
+		if name != "" {
+			services = appendOnce(services, name)
+		}
+	}
+	add(yamlKey(spec, "defaultBackend"))
+	add(yamlKey(spec, "backend"))
+	for _, rule := range yamlItems(yamlKey(spec, "rules")) {
+		for _, p := range yamlItems(yamlKey(yamlKey(rule, "http"), "paths")) {
+			add(yamlKey(p, "backend"))
+		}
+	}
+	return services
+}
+
+// keyValues writes a mapping of scalars as "key=value" pairs, cutting
+// values longer than maxValue when it is positive
+func keyValues(n *yaml.Node, maxValue int) string {
+	var pairs []string
+	for _, pair := range yamlPairs(n) {
+		value := oneLine(yamlString(pair[1]))
+		if maxValue > 0 && len(value) > maxValue {
+			value = strings.ToValidUTF8(value[:maxValue], "") + "..."
+		}
+		pairs = append(pairs, pair[0].Value+"="+value)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// mapKeys returns the keys of mappings in order
+func mapKeys(maps ...*yaml.Node) []string {
+	var keys []string
+	for _, m := range maps {
+		for _, pair := range yamlPairs(m) {
+			keys = append(keys, pair[0].Value)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,143 @@
+package processors
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/logtext"
+	"go.uber.org/zap"
+)
+
+// LogProcessor handles log files. Entries are written in sections that
+// span at most the configured time window and number of lines, so each
+// chunk holds entries close in time. Lines without a timestamp, such as
+// stack traces, stay with the entry before them. Of logs above the size
+// cap only the most recent entries, at the end, are read.
+type LogProcessor struct {
+	logger *zap.Logger
+	config config.LogFilesConfig
+}
+
+// NewLogProcessor creates a new log processor
+func NewLogProcessor(logger *zap.Logger, cfg config.LogFilesConfig) *LogProcessor {
+	return &LogProcessor{logger: logger, config: cfg}
+}
+
+// CanProcess checks if this processor can handle the file type
+func (p *LogProcessor) CanProcess(fileType string) bool {
+	return strings.EqualFold(fileType, ".log")
+}
+
+// Describe returns the formats the processor handles and its settings
+func (p *LogProcessor) Describe() Capabilities {
+	return Capabilities{
+		Name:     "log",
+		Category: "log",
+		Formats:  []Format{{"log", "text/x-log"}},
+		Options: []Option{
+			{"LOG_FILES_WINDOW", "Longest time span of a section, 0 splits by lines only", p.config.Window.String()},
+			{"LOG_FILES_WINDOW_LINES", "Most lines of a section", p.config.WindowLines},
+			{"LOG_FILES_MAX_BYTES", "Only the last bytes of a log up to this size are indexed", p.config.MaxBytes},
+		},
+	}
+}
+
+// Extract extracts the entries of a log file
+func (p *LogProcessor) Extract(ctx context.Context, filePath string) (string, error) {
+	var b strings.Builder
+	if err := p.ExtractTo(ctx, filePath, &b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ExtractTo writes the entries of a log file into w, in sections separated
+// by SectionBreak
+func (p *LogProcessor) ExtractTo(ctx context.Context, filePath string, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+
+	// The first line read after seeking is most likely cut
+	partial := false
+	if info.Size() > p.config.MaxBytes {
+		if _, err := file.Seek(info.Size()-p.config.MaxBytes, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to read log file: %w", err)
+		}
+		partial = true
+		p.logger.Info("Log file above the ingestion cap, indexing its most recent entries",
+			zap.String("file", filePath),
+			zap.Int64("size", info.Size()),
+			zap.Int64("max_bytes", p.config.MaxBytes))
+	}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	var start time.Time // start of the current section's window
+	lines, sections := 0, 0
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("failed to read log file: %w", readErr)
+		}
+		if partial {
+			partial = false
+		} else if line != "" {
+			at := logtext.Parse(line, info.ModTime()).Time
+			if lines > 0 && p.newSection(lines, start, at) {
+				if _, err := io.WriteString(w, string(SectionBreak)+"\n"); err != nil {
+					return err
+				}
+				lines, start = 0, time.Time{}
+			}
+			if start.IsZero() && !at.IsZero() {
+				start = at
+				if p.config.Window > 0 {
+					start = at.Truncate(p.config.Window)
+				}
+			}
+			if lines == 0 {
+				sections++
+			}
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+			lines++
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	p.logger.Debug("Extracted log file",
+		zap.String("file", filePath),
+		zap.Int("sections", sections))
+	return nil
+}
+
+// newSection reports whether an entry written at the given time starts a
+// new section after lines entries of a section whose window starts at start
+func (p *LogProcessor) newSection(lines int, start, at time.Time) bool {
+	if lines >= p.config.WindowLines {
+		return true
+	}
+	if at.IsZero() || start.IsZero() || p.config.Window <= 0 {
+		return false
+	}
+	// Entries out of order, as after a log rotation, start over
+	return at.Before(start) || !at.Before(start.Add(p.config.Window))
+}
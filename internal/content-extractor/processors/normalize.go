@@ -0,0 +1,264 @@
+package processors
+
+import (
+	"bytes"
+	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	xunicode "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Source encodings reported by DetectEncoding
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingUTF16LE     = "utf-16le"
+	EncodingUTF16BE     = "utf-16be"
+	EncodingShiftJIS    = "shift_jis"
+	EncodingWindows1252 = "windows-1252"
+)
+
+// encodingSniffLen is how much leading content is buffered to detect its encoding
+const encodingSniffLen = 64 * 1024
+
+// maxIndent bounds the leading whitespace kept on a line
+const maxIndent = 64
+
+// DetectEncoding guesses the character encoding of text from its leading
+// bytes. Byte order marks are trusted; otherwise valid UTF-8 is UTF-8, text
+// made of well-formed Shift-JIS pairs including kana is Shift-JIS, and
+// anything else is read as Windows-1252, which decodes every byte.
+func DetectEncoding(head []byte) (string, encoding.Encoding) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return EncodingUTF8, nil
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE, xunicode.UTF16(xunicode.LittleEndian, xunicode.ExpectBOM)
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE, xunicode.UTF16(xunicode.BigEndian, xunicode.ExpectBOM)
+	case validUTF8Prefix(head):
+		return EncodingUTF8, nil
+	case isShiftJIS(head):
+		return EncodingShiftJIS, japanese.ShiftJIS
+	default:
+		return EncodingWindows1252, charmap.Windows1252
+	}
+}
+
+// validUTF8Prefix reports whether head is valid UTF-8, allowing it to end
+// in the middle of a character
+func validUTF8Prefix(head []byte) bool {
+	for i := 1; i < utf8.UTFMax && i <= len(head); i++ {
+		if utf8.RuneStart(head[len(head)-i]) {
+			if !utf8.FullRune(head[len(head)-i:]) {
+				head = head[:len(head)-i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(head)
+}
+
+// isShiftJIS reports whether every non-ASCII byte of head forms a valid
+// Shift-JIS character and some of them are kana. Accented Latin text in
+// Windows-1252 can look like Shift-JIS pairs but rarely contains kana.
+func isShiftJIS(head []byte) bool {
+	kana := 0
+	for i := 0; i < len(head); i++ {
+		b := head[i]
+		switch {
+		case b < 0x80:
+		case b >= 0xA1 && b <= 0xDF: // half-width katakana
+			kana++
+		case (b >= 0x81 && b <= 0x9F) || (b >= 0xE0 && b <= 0xFC):
+			if i+1 == len(head) {
+				return kana > 0 // the pair may continue past the sniffed bytes
+			}
+			t := head[i+1]
+			if t < 0x40 || t == 0x7F || t > 0xFC {
+				return false
+			}
+			if b == 0x82 || b == 0x83 { // hiragana and katakana
+				kana++
+			}
+			i++
+		default:
+			return false
+		}
+	}
+	return kana > 0
+}
+
+// Normalizer is a writer that cleans extracted text before it is stored:
+// it decodes the source encoding to UTF-8, applies Unicode NFC
+// normalization, strips control and invisible format characters, unifies
+// line endings and collapses runs of whitespace. The first bytes are
+// buffered to detect the encoding. Close must be called to flush.
+type Normalizer struct {
+	dst      io.Writer
+	head     []byte
+	w        io.WriteCloser
+	encoding string
+}
+
+// NewNormalizer creates a normalizer writing clean text to dst
+func NewNormalizer(dst io.Writer) *Normalizer {
+	return &Normalizer{dst: dst}
+}
+
+// Write normalizes p into the destination
+func (n *Normalizer) Write(p []byte) (int, error) {
+	if n.w != nil {
+		return n.w.Write(p)
+	}
+	n.head = append(n.head, p...)
+	if len(n.head) >= encodingSniffLen {
+		if err := n.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close flushes the remaining text
+func (n *Normalizer) Close() error {
+	if n.w == nil {
+		if err := n.start(); err != nil {
+			return err
+		}
+	}
+	return n.w.Close()
+}
+
+// Encoding returns the detected source encoding once content was written
+func (n *Normalizer) Encoding() string {
+	return n.encoding
+}
+
+// start detects the encoding from the buffered bytes and sets up the
+// transformation chain
+func (n *Normalizer) start() error {
+	name, enc := DetectEncoding(n.head)
+	n.encoding = name
+
+	var chain []transform.Transformer
+	if enc != nil {
+		chain = append(chain, enc.NewDecoder())
+	}
+	chain = append(chain, norm.NFC, &cleaner{lineStart: true})
+	n.w = transform.NewWriter(n.dst, transform.Chain(chain...))
+
+	head := n.head
+	n.head = nil
+	_, err := n.w.Write(head)
+	return err
+}
+
+// cleaner strips control characters other than section breaks and
+// collapses whitespace. Leading indentation is kept, up to maxIndent bytes,
+// so code stays readable; other runs of spaces become one space, trailing
+// spaces are dropped and more than one blank line in a row becomes one.
+type cleaner struct {
+	lineStart bool   // no visible character yet on the current line
+	started   bool   // a visible character was written
+	cr        bool   // the last character was a carriage return
+	newlines  int    // line breaks waiting for the next visible character
+	space     bool   // a space is waiting for the next visible character
+	indent    []byte // leading whitespace of the current line
+}
+
+// Reset implements transform.Transformer
+func (c *cleaner) Reset() {
+	*c = cleaner{lineStart: true}
+}
+
+// Transform implements transform.Transformer
+func (c *cleaner) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError && size <= 1 {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			nSrc += size // drop bytes that are not UTF-8
+			continue
+		}
+
+		cr := c.cr
+		c.cr = r == '\r'
+		switch {
+		case r == '\r' || r == '\n':
+			if r == '\n' && cr {
+				break // second half of \r\n
+			}
+			if c.started {
+				c.newlines++
+			}
+			c.lineStart, c.space, c.indent = true, false, c.indent[:0]
+		case r == '\t' || r == ' ' || unicode.Is(unicode.Zs, r):
+			if !c.lineStart {
+				c.space = true
+			} else if len(c.indent) < maxIndent {
+				if r != '\t' {
+					r = ' '
+				}
+				c.indent = append(c.indent, byte(r))
+			}
+		case r == SectionBreak:
+			if !c.started {
+				break // nothing to separate yet
+			}
+			pending := bytes.Repeat([]byte{'\n'}, min(c.newlines, 1))
+			if nDst+len(pending)+size > len(dst) {
+				c.cr = cr
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += copy(dst[nDst:], pending)
+			nDst += copy(dst[nDst:], src[nSrc:nSrc+size])
+			c.lineStart, c.space, c.newlines = true, false, 0
+			c.indent = c.indent[:0]
+		case unicode.IsControl(r) || isInvisible(r):
+		default:
+			pending := c.pending()
+			if nDst+len(pending)+size > len(dst) {
+				c.cr = cr
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += copy(dst[nDst:], pending)
+			nDst += copy(dst[nDst:], src[nSrc:nSrc+size])
+			c.started, c.lineStart, c.space, c.newlines = true, false, false, 0
+			c.indent = c.indent[:0]
+		}
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+// pending returns the whitespace to write before the next visible character
+func (c *cleaner) pending() []byte {
+	var out []byte
+	if c.newlines > 2 {
+		c.newlines = 2
+	}
+	for i := 0; i < c.newlines; i++ {
+		out = append(out, '\n')
+	}
+	if c.lineStart {
+		return append(out, c.indent...)
+	}
+	if c.space {
+		out = append(out, ' ')
+	}
+	return out
+}
+
+// isInvisible reports format characters that carry no text: the byte order
+// mark, zero-width space, word joiner and soft hyphen
+func isInvisible(r rune) bool {
+	return r == '\uFEFF' || r == '\u200B' || r == '\u2060' || r == '\u00AD'
+}
@@ -0,0 +1,491 @@
+package processors
+
+import (
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// openAPIMethods are the operations of a path item, in the order the
+// OpenAPI specification lists them
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// maxSchemaProperties is how many property names an inline object schema
+// is summarized with
+const maxSchemaProperties = 8
+
+// parseOpenAPI parses an OpenAPI 3 or Swagger 2 document, in YAML or JSON,
+// and returns its root mapping, or nil when the data is not one
+func parseOpenAPI(data []byte) *yaml.Node {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	if yamlKey(root, "openapi") == nil && yamlKey(root, "swagger") == nil {
+		return nil
+	}
+	return root
+}
+
+// openAPIWriter writes an API specification as sections: an overview, one
+// section per operation and one per schema, each readable on its own
+type openAPIWriter struct {
+	root       *yaml.Node
+	sections   []string
+	returnedBy map[string][]string // schema name to the operations returning it
+	acceptedBy map[string][]string // schema name to the operations taking it
+}
+
+// writeOpenAPI returns the text of an API specification with its sections
+// separated by SectionBreak, and the number of operations and schemas
+func writeOpenAPI(root *yaml.Node) (string, int, int) {
+	w := &openAPIWriter{
+		root:       root,
+		returnedBy: make(map[string][]string),
+		acceptedBy: make(map[string][]string),
+	}
+	w.overview()
+	operations := w.operations()
+	schemas := w.schemas()
+	return strings.Join(w.sections, "\n"+string(SectionBreak)+"\n"), operations, schemas
+}
+
+func (w *openAPIWriter) overview() {
+	var b strings.Builder
+	info := yamlKey(w.root, "info")
+	title := yamlString(yamlKey(info, "title"))
+	if title == "" {
+		title = "(untitled)"
+	}
+	version := yamlString(yamlKey(w.root, "openapi"))
+	format := "OpenAPI " + version
+	if version == "" {
+		format = "Swagger " + yamlString(yamlKey(w.root, "swagger"))
+	}
+	fmt.Fprintf(&b, "API: %s", title)
+	if v := yamlString(yamlKey(info, "version")); v != "" {
+		fmt.Fprintf(&b, " %s", v)
+	}
+	fmt.Fprintf(&b, " (%s)\n", format)
+	writeField(&b, "Description", yamlString(yamlKey(info, "description")))
+
+	var servers []string
+	for _, server := range yamlItems(yamlKey(w.root, "servers")) {
+		if url := yamlString(yamlKey(server, "url")); url != "" {
+			servers = append(servers, url)
+		}
+	}
+	if host := yamlString(yamlKey(w.root, "host")); host != "" {
+		servers = append(servers, host+yamlString(yamlKey(w.root, "basePath")))
+	}
+	writeField(&b, "Servers", strings.Join(servers, ", "))
+
+	var tags []string
+	for _, tag := range yamlItems(yamlKey(w.root, "tags")) {
+		name := yamlString(yamlKey(tag, "name"))
+		if d := oneLine(yamlString(yamlKey(tag, "description"))); d != "" {
+			name += " (" + d + ")"
+		}
+		if name != "" {
+			tags = append(tags, name)
+		}
+	}
+	writeField(&b, "Tags", strings.Join(tags, "; "))
+	w.sections = append(w.sections, b.String())
+}
+
+// operations writes a section per operation and returns their number
+func (w *openAPIWriter) operations() int {
+	count := 0
+	for _, pair := range yamlPairs(yamlKey(w.root, "paths")) {
+		path, item := pair[0].Value, w.resolve(pair[1])
+		shared := yamlItems(yamlKey(item, "parameters"))
+		for _, method := range openAPIMethods {
+			if op := yamlKey(item, method); op != nil {
+				w.operation(strings.ToUpper(method)+" "+path, op, shared)
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func (w *openAPIWriter) operation(name string, op *yaml.Node, shared []*yaml.Node) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Endpoint: %s\n", name)
+	writeField(&b, "Operation ID", yamlString(yamlKey(op, "operationId")))
+	writeField(&b, "Summary", yamlString(yamlKey(op, "summary")))
+	writeField(&b, "Description", yamlString(yamlKey(op, "description")))
+	writeField(&b, "Tags", strings.Join(yamlStrings(yamlKey(op, "tags")), ", "))
+	if yamlString(yamlKey(op, "deprecated")) == "true" {
+		b.WriteString("Deprecated: yes\n")
+	}
+
+	var params []string
+	for _, p := range append(shared, yamlItems(yamlKey(op, "parameters"))...) {
+		p = w.resolve(p)
+		in := yamlString(yamlKey(p, "in"))
+		if in == "body" {
+			// Swagger 2 request body
+			w.requestBody(&b, name, yamlString(yamlKey(p, "description")), w.consumes(op), yamlKey(p, "schema"), yamlString(yamlKey(p, "required")) == "true")
+			continue
+		}
+		line := yamlString(yamlKey(p, "name")) + " (" + in
+		if yamlString(yamlKey(p, "required")) == "true" {
+			line += ", required"
+		}
+		if schema := yamlKey(p, "schema"); schema != nil {
+			line += ", " + w.schemaText(schema)
+		} else if t := yamlString(yamlKey(p, "type")); t != "" {
+			line += ", " + w.schemaText(p)
+		}
+		line += ")"
+		if d := oneLine(yamlString(yamlKey(p, "description"))); d != "" {
+			line += ": " + d
+		}
+		params = append(params, line)
+	}
+	writeList(&b, "Parameters", params)
+
+	if body := w.resolve(yamlKey(op, "requestBody")); body != nil {
+		types, schema := w.media(yamlKey(body, "content"))
+		w.requestBody(&b, name, yamlString(yamlKey(body, "description")), types, schema, yamlString(yamlKey(body, "required")) == "true")
+	}
+
+	var responses []string
+	for _, pair := range yamlPairs(yamlKey(op, "responses")) {
+		status, resp := pair[0].Value, w.resolve(pair[1])
+		types, schema := w.media(yamlKey(resp, "content"))
+		if s := yamlKey(resp, "schema"); s != nil {
+			types, schema = w.produces(op), s
+		}
+		line := status
+		if len(types) > 0 {
+			line += " (" + strings.Join(types, ", ") + ")"
+		}
+		if d := oneLine(yamlString(yamlKey(resp, "description"))); d != "" {
+			line += ": " + d
+		}
+		if schema != nil {
+			line += "; returns " + w.schemaText(schema)
+			if status == "default" || strings.HasPrefix(status, "2") {
+				for _, ref := range w.schemaRefs(schema) {
+					w.returnedBy[ref] = appendOnce(w.returnedBy[ref], name)
+				}
+			}
+		}
+		responses = append(responses, line)
+	}
+	writeList(&b, "Responses", responses)
+	w.sections = append(w.sections, b.String())
+}
+
+// requestBody writes the request body of an operation and records the
+// schemas it takes
+func (w *openAPIWriter) requestBody(b *strings.Builder, name, description string, types []string, schema *yaml.Node, required bool) {
+	line := "Request body"
+	var notes []string
+	notes = append(notes, types...)
+	if required {
+		notes = append(notes, "required")
+	}
+	if len(notes) > 0 {
+		line += " (" + strings.Join(notes, ", ") + ")"
+	}
+	if schema != nil {
+		line += ": " + w.schemaText(schema)
+		for _, ref := range w.schemaRefs(schema) {
+			w.acceptedBy[ref] = appendOnce(w.acceptedBy[ref], name)
+		}
+	}
+	if d := oneLine(description); d != "" {
+		line += ". " + d
+	}
+	b.WriteString(line + "\n")
+}
+
+// media returns the media types of an OpenAPI 3 content map and the schema
+// of the first that has one
+func (w *openAPIWriter) media(content *yaml.Node) ([]string, *yaml.Node) {
+	var types []string
+	var schema *yaml.Node
+	for _, pair := range yamlPairs(content) {
+		types = append(types, pair[0].Value)
+		if s := yamlKey(pair[1], "schema"); s != nil && schema == nil {
+			schema = s
+		}
+	}
+	return types, schema
+}
+
+// consumes and produces return the Swagger 2 media types of an operation
+func (w *openAPIWriter) consumes(op *yaml.Node) []string {
+	if types := yamlStrings(yamlKey(op, "consumes")); len(types) > 0 {
+		return types
+	}
+	return yamlStrings(yamlKey(w.root, "consumes"))
+}
+
+func (w *openAPIWriter) produces(op *yaml.Node) []string {
+	if types := yamlStrings(yamlKey(op, "produces")); len(types) > 0 {
+		return types
+	}
+	return yamlStrings(yamlKey(w.root, "produces"))
+}
+
+// schemas writes a section per named schema and returns their number
+func (w *openAPIWriter) schemas() int {
+	defs := yamlKey(yamlKey(w.root, "components"), "schemas")
+	if defs == nil {
+		defs = yamlKey(w.root, "definitions")
+	}
+	pairs := yamlPairs(defs)
+	for _, pair := range pairs {
+		name, schema := pair[0].Value, pair[1]
+		var b strings.Builder
+		fmt.Fprintf(&b, "Schema: %s\n", name)
+		writeField(&b, "Title", yamlString(yamlKey(schema, "title")))
+		writeField(&b, "Description", yamlString(yamlKey(schema, "description")))
+		writeField(&b, "Type", w.schemaText(schema))
+
+		required := make(map[string]bool)
+		for _, r := range yamlStrings(yamlKey(schema, "required")) {
+			required[r] = true
+		}
+		var props []string
+		for _, prop := range yamlPairs(yamlKey(schema, "properties")) {
+			line := prop[0].Value + " (" + w.schemaText(prop[1])
+			if required[prop[0].Value] {
+				line += ", required"
+			}
+			line += ")"
+			if d := oneLine(yamlString(yamlKey(w.resolve(prop[1]), "description"))); d != "" {
+				line += ": " + d
+			}
+			props = append(props, line)
+		}
+		writeList(&b, "Properties", props)
+		writeField(&b, "Returned by", strings.Join(w.returnedBy[name], ", "))
+		writeField(&b, "Accepted by", strings.Join(w.acceptedBy[name], ", "))
+		w.sections = append(w.sections, b.String())
+	}
+	return len(pairs)
+}
+
+// schemaText summarizes a schema in a few words: its referenced name, or
+// its type with format, items, enum values or properties
+func (w *openAPIWriter) schemaText(n *yaml.Node) string {
+	return w.describeSchema(n, 0)
+}
+
+func (w *openAPIWriter) describeSchema(n *yaml.Node, depth int) string {
+	n = yamlNode(n)
+	if n == nil || depth > 4 {
+		return "any"
+	}
+	if ref := yamlString(yamlKey(n, "$ref")); ref != "" {
+		return refName(ref)
+	}
+	for _, combo := range []struct{ key, join string }{{"allOf", " and "}, {"oneOf", " or "}, {"anyOf", " or "}} {
+		if parts := yamlItems(yamlKey(n, combo.key)); len(parts) > 0 {
+			texts := make([]string, len(parts))
+			for i, part := range parts {
+				texts[i] = w.describeSchema(part, depth+1)
+			}
+			return strings.Join(texts, combo.join)
+		}
+	}
+
+	t := yamlString(yamlKey(n, "type"))
+	if t == "" {
+		if types := yamlStrings(yamlKey(n, "type")); len(types) > 0 {
+			t = strings.Join(types, " or ")
+		}
+	}
+	switch {
+	case t == "array":
+		return "array of " + w.describeSchema(yamlKey(n, "items"), depth+1)
+	case yamlKey(n, "properties") != nil:
+		var names []string
+		for i, prop := range yamlPairs(yamlKey(n, "properties")) {
+			if i == maxSchemaProperties {
+				names = append(names, "...")
+				break
+			}
+			names = append(names, prop[0].Value)
+		}
+		return "object with " + strings.Join(names, ", ")
+	case yamlKey(n, "additionalProperties") != nil && yamlKey(n, "additionalProperties").Kind == yaml.MappingNode:
+		return "map of " + w.describeSchema(yamlKey(n, "additionalProperties"), depth+1)
+	}
+	if t == "" {
+		t = "any"
+	}
+	if f := yamlString(yamlKey(n, "format")); f != "" {
+		t += " " + f
+	}
+	if values := yamlStrings(yamlKey(n, "enum")); len(values) > 0 {
+		t += ", one of " + strings.Join(values, ", ")
+	}
+	return t
+}
+
+// schemaRefs returns the named schemas a schema is or holds an array of
+func (w *openAPIWriter) schemaRefs(n *yaml.Node) []string {
+	n = yamlNode(n)
+	if n == nil {
+		return nil
+	}
+	if ref := yamlString(yamlKey(n, "$ref")); ref != "" {
+		return []string{refName(ref)}
+	}
+	if items := yamlKey(n, "items"); items != nil {
+		return w.schemaRefs(items)
+	}
+	var refs []string
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		for _, part := range yamlItems(yamlKey(n, key)) {
+			refs = append(refs, w.schemaRefs(part)...)
+		}
+	}
+	return refs
+}
+
+// resolve follows a $ref within the document to the node it points to, so
+// shared parameters, responses and request bodies are read in place
+func (w *openAPIWriter) resolve(n *yaml.Node) *yaml.Node {
+	for i := 0; i < 10; i++ {
+		n = yamlNode(n)
+		ref := yamlString(yamlKey(n, "$ref"))
+		if !strings.HasPrefix(ref, "#/") {
+			return n
+		}
+		target := w.root
+		for _, part := range strings.Split(ref[2:], "/") {
+			part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+			target = yamlKey(target, part)
+		}
+		if target == nil {
+			return n
+		}
+		n = target
+	}
+	return n
+}
+
+// refName is the name a $ref points to: its last path segment
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// yamlNode follows aliases and unwraps documents
+func yamlNode(n *yaml.Node) *yaml.Node {
+	for n != nil && (n.Kind == yaml.AliasNode || n.Kind == yaml.DocumentNode) {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		} else if len(n.Content) > 0 {
+			n = n.Content[0]
+		} else {
+			return nil
+		}
+	}
+	return n
+}
+
+// yamlKey returns the value of a key of a mapping, or nil
+func yamlKey(n *yaml.Node, key string) *yaml.Node {
+	n = yamlNode(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return yamlNode(n.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// yamlPairs returns the key and value nodes of a mapping in order
+func yamlPairs(n *yaml.Node) [][2]*yaml.Node {
+	n = yamlNode(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	pairs := make([][2]*yaml.Node, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{n.Content[i], yamlNode(n.Content[i+1])})
+	}
+	return pairs
+}
+
+// yamlItems returns the items of a sequence
+func yamlItems(n *yaml.Node) []*yaml.Node {
+	n = yamlNode(n)
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	items := make([]*yaml.Node, len(n.Content))
+	for i, item := range n.Content {
+		items[i] = yamlNode(item)
+	}
+	return items
+}
+
+// yamlString returns the value of a scalar, or an empty string
+func yamlString(n *yaml.Node) string {
+	n = yamlNode(n)
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return strings.TrimSpace(n.Value)
+}
+
+// yamlStrings returns the scalar items of a sequence
+func yamlStrings(n *yaml.Node) []string {
+	var out []string
+	for _, item := range yamlItems(n) {
+		if s := yamlString(item); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// writeField writes a "Name: value" line for a non-empty value
+func writeField(b *strings.Builder, name, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "%s: %s\n", name, value)
+	}
+}
+
+// writeList writes a heading followed by one "- item" line per item
+func writeList(b *strings.Builder, heading string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	b.WriteString(heading + ":\n")
+	for _, item := range items {
+		b.WriteString("- " + item + "\n")
+	}
+}
+
+// oneLine collapses text to a single line
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// appendOnce appends a value not in the list yet
+func appendOnce(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,164 @@
+package processors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Extraction option names, as they appear in requests and capabilities
+const (
+	OptionOCRMode       = "ocr_mode"
+	OptionIncludeTables = "include_tables"
+	OptionMaxPages      = "max_pages"
+	OptionLanguage      = "language"
+)
+
+// OCR modes
+const (
+	OCRAuto   = "auto"   // text is detected in images as the processing policy says
+	OCRAlways = "always" // text is detected in every image
+	OCRNever  = "never"  // text is never detected
+)
+
+// languageTag matches BCP 47 language tags such as "en", "de-CH" or "zh-Hant"
+var languageTag = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ExtractOptions change how a file is extracted. The zero value extracts
+// files the default way. Processors list the options they honour in their
+// capabilities.
+type ExtractOptions struct {
+	// OCRMode is auto, always or never; empty is auto
+	OCRMode string `json:"ocr_mode,omitempty" description:"Text detection in images: auto (as the processing policy says), always or never"`
+	// IncludeTables leaves tables out of documents when false; nil keeps them
+	IncludeTables *bool `json:"include_tables,omitempty" description:"Keep tables in the text of documents; defaults to true"`
+	// MaxPages stops PDF extraction after that many pages; 0 extracts all
+	MaxPages int `json:"max_pages,omitempty" description:"Pages of a PDF to extract; 0 extracts all"`
+	// Language is a BCP 47 tag of the document's language, passed to OCR
+	Language string `json:"language,omitempty" description:"BCP 47 language tag hinting the language of text detected in images"`
+}
+
+// UnmarshalJSON decodes options, rejecting unknown ones and invalid values
+func (o *ExtractOptions) UnmarshalJSON(data []byte) error {
+	type plain ExtractOptions
+	var decoded plain
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&decoded); err != nil {
+		return fmt.Errorf("invalid extraction options: %w", err)
+	}
+	if err := ExtractOptions(decoded).Validate(); err != nil {
+		return err
+	}
+	*o = ExtractOptions(decoded)
+	return nil
+}
+
+// Validate checks the option values
+func (o ExtractOptions) Validate() error {
+	switch o.OCRMode {
+	case "", OCRAuto, OCRAlways, OCRNever:
+	default:
+		return fmt.Errorf("invalid %s %q: use auto, always or never", OptionOCRMode, o.OCRMode)
+	}
+	if o.MaxPages < 0 {
+		return fmt.Errorf("%s must not be negative", OptionMaxPages)
+	}
+	if o.Language != "" && !languageTag.MatchString(o.Language) {
+		return fmt.Errorf("invalid %s %q: use a BCP 47 tag such as en or pt-BR", OptionLanguage, o.Language)
+	}
+	return nil
+}
+
+// Names lists the options that are set
+func (o ExtractOptions) Names() []string {
+	var names []string
+	if o.OCRMode != "" {
+		names = append(names, OptionOCRMode)
+	}
+	if o.IncludeTables != nil {
+		names = append(names, OptionIncludeTables)
+	}
+	if o.MaxPages != 0 {
+		names = append(names, OptionMaxPages)
+	}
+	if o.Language != "" {
+		names = append(names, OptionLanguage)
+	}
+	return names
+}
+
+// IsZero reports whether no option is set
+func (o ExtractOptions) IsZero() bool {
+	return len(o.Names()) == 0
+}
+
+// Tables reports whether tables are kept
+func (o ExtractOptions) Tables() bool {
+	return o.IncludeTables == nil || *o.IncludeTables
+}
+
+// Supported returns the options a processor honours, leaving out the rest
+func (o ExtractOptions) Supported(p ProcessorInterface) ExtractOptions {
+	honoured := supportedOptions(p)
+	if !slices.Contains(honoured, OptionOCRMode) {
+		o.OCRMode = ""
+	}
+	if !slices.Contains(honoured, OptionIncludeTables) {
+		o.IncludeTables = nil
+	}
+	if !slices.Contains(honoured, OptionMaxPages) {
+		o.MaxPages = 0
+	}
+	if !slices.Contains(honoured, OptionLanguage) {
+		o.Language = ""
+	}
+	return o
+}
+
+// CheckOptions fails when an option is set that the processor of a file
+// type does not honour
+func CheckOptions(p ProcessorInterface, fileType string, o ExtractOptions) error {
+	honoured := supportedOptions(p)
+	var unsupported []string
+	for _, name := range o.Names() {
+		if !slices.Contains(honoured, name) {
+			unsupported = append(unsupported, name)
+		}
+	}
+	switch len(unsupported) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("%s files do not support the %s option", fileType, unsupported[0])
+	}
+	return fmt.Errorf("%s files do not support the %s options", fileType, strings.Join(unsupported, ", "))
+}
+
+// supportedOptions lists the options a processor honours; processors that
+// do not describe themselves honour none
+func supportedOptions(p ProcessorInterface) []string {
+	if d, ok := p.(Describer); ok {
+		return d.Describe().ExtractOptions
+	}
+	return nil
+}
+
+type extractOptionsKey struct{}
+
+// WithOptions returns a context telling processors to extract files with
+// the given options
+func WithOptions(ctx context.Context, o ExtractOptions) context.Context {
+	return context.WithValue(ctx, extractOptionsKey{}, o)
+}
+
+// OptionsFrom returns the extraction options of a context, the zero value
+// when none are set
+func OptionsFrom(ctx context.Context) ExtractOptions {
+	o, _ := ctx.Value(extractOptionsKey{}).(ExtractOptions)
+	return o
+}
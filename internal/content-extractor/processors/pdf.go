@@ -0,0 +1,612 @@
+package processors
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/safeparse"
+)
+
+// The PDF reader below covers what text extraction needs: objects (also in
+// object streams), the page tree, Flate, ASCIIHex and ASCII85 streams, and
+// fonts. Cross-reference tables are not used: objects are found by
+// scanning, with later definitions replacing earlier ones as incremental
+// updates do.
+
+// maxStreamBytes bounds the decoded size of one stream
+const maxStreamBytes = 64 << 20
+
+// maxDecodedBytes bounds the decoded size of all streams of a document,
+// counting a stream each time it is decoded, so a stream drawn on every
+// page or many streams inflating to the stream limit cannot decompress
+// without bound
+const maxDecodedBytes = 1 << 30
+
+// maxNesting bounds nested arrays, dictionaries, page trees and forms
+const maxNesting = 64
+
+var errEncryptedPDF = errors.New("encrypted PDFs are not supported")
+
+type pdfName string
+
+type pdfKeyword string
+
+type pdfRef struct{ num, gen int }
+
+type pdfDict map[pdfName]any
+
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte
+}
+
+// pdfDocument is the objects of a PDF file by number
+type pdfDocument struct {
+	objects map[int]any
+	trailer pdfDict
+	decoded int64 // bytes decoded so far
+	err     error // set once maxDecodedBytes is exceeded
+}
+
+var (
+	objHeader     = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	trailerHeader = regexp.MustCompile(`trailer\s*<<`)
+)
+
+// parsePDF reads the objects of a PDF file
+func parsePDF(data []byte) (*pdfDocument, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+
+	doc := &pdfDocument{objects: map[int]any{}, trailer: pdfDict{}}
+	end := 0
+	for _, m := range objHeader.FindAllSubmatchIndex(data, -1) {
+		// Headers inside the previous object are stream data
+		if m[0] < end {
+			continue
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		lex := &pdfLexer{data: data, pos: m[1]}
+		value, err := lex.object(0)
+		if err != nil {
+			continue
+		}
+		if dict, ok := value.(pdfDict); ok {
+			if stream, ok := lex.stream(dict); ok {
+				value = stream
+			}
+		}
+		doc.objects[num] = value
+		end = lex.pos
+	}
+
+	// Trailers are the dictionaries after "trailer" keywords, or the
+	// dictionaries of cross-reference streams
+	for _, i := range trailerHeader.FindAllIndex(data, -1) {
+		lex := &pdfLexer{data: data, pos: i[0] + len("trailer")}
+		if dict, err := lex.object(0); err == nil {
+			if d, ok := dict.(pdfDict); ok {
+				doc.mergeTrailer(d)
+			}
+		}
+	}
+	for _, value := range doc.objects {
+		if stream, ok := value.(*pdfStream); ok && stream.dict["Type"] == pdfName("XRef") {
+			doc.mergeTrailer(stream.dict)
+		}
+	}
+	if _, ok := doc.trailer["Encrypt"]; ok {
+		return nil, errEncryptedPDF
+	}
+
+	doc.expandObjectStreams()
+	if doc.err != nil {
+		return nil, doc.err
+	}
+	return doc, nil
+}
+
+func (doc *pdfDocument) mergeTrailer(d pdfDict) {
+	for _, key := range []pdfName{"Root", "Encrypt"} {
+		if value, ok := d[key]; ok {
+			doc.trailer[key] = value
+		}
+	}
+}
+
+// expandObjectStreams adds the objects compressed in object streams that
+// are not also defined directly
+func (doc *pdfDocument) expandObjectStreams() {
+	var streams []*pdfStream
+	for _, value := range doc.objects {
+		if stream, ok := value.(*pdfStream); ok && stream.dict["Type"] == pdfName("ObjStm") {
+			streams = append(streams, stream)
+		}
+	}
+	for _, stream := range streams {
+		data, err := doc.decode(stream)
+		if err != nil {
+			continue
+		}
+		n, _ := doc.resolve(stream.dict["N"]).(float64)
+		first, _ := doc.resolve(stream.dict["First"]).(float64)
+		header := &pdfLexer{data: data}
+		for i := 0; i < int(n); i++ {
+			num, err1 := header.object(0)
+			offset, err2 := header.object(0)
+			if err1 != nil || err2 != nil {
+				break
+			}
+			objNum, ok1 := num.(float64)
+			objOffset, ok2 := offset.(float64)
+			start := int(first) + int(objOffset)
+			if !ok1 || !ok2 || start < 0 || start >= len(data) {
+				continue
+			}
+			if _, exists := doc.objects[int(objNum)]; exists {
+				continue
+			}
+			if value, err := (&pdfLexer{data: data, pos: start}).object(0); err == nil {
+				doc.objects[int(objNum)] = value
+			}
+		}
+	}
+}
+
+// resolve follows references to the object they name
+func (doc *pdfDocument) resolve(value any) any {
+	for i := 0; i < maxNesting; i++ {
+		ref, ok := value.(pdfRef)
+		if !ok {
+			return value
+		}
+		value = doc.objects[ref.num]
+	}
+	return nil
+}
+
+func (doc *pdfDocument) dict(value any) pdfDict {
+	switch v := doc.resolve(value).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+func (doc *pdfDocument) array(value any) []any {
+	a, _ := doc.resolve(value).([]any)
+	return a
+}
+
+func (doc *pdfDocument) number(value any, fallback float64) float64 {
+	if n, ok := doc.resolve(value).(float64); ok {
+		return n
+	}
+	return fallback
+}
+
+// decode returns the decoded data of a stream; unsupported filters are an
+// error, as is decoding more than maxDecodedBytes in the document
+func (doc *pdfDocument) decode(stream *pdfStream) ([]byte, error) {
+	if doc.err != nil {
+		return nil, doc.err
+	}
+	var filters []any
+	switch f := doc.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+
+	data := stream.raw
+	for _, f := range filters {
+		var err error
+		switch doc.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data, err = inflate(data)
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data, err = decodeASCIIHex(data)
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %v", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if doc.decoded += int64(len(data)); doc.decoded > maxDecodedBytes {
+		doc.err = fmt.Errorf("%w: streams decode to more than %d bytes", safeparse.ErrLimit, maxDecodedBytes)
+		return nil, doc.err
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, keeping what was read of a truncated or
+// damaged stream
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate stream: %w", err)
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, maxStreamBytes))
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("failed to inflate stream: %w", err)
+	}
+	return out, nil
+}
+
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if isHexDigit(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	return hex.DecodeString(string(digits))
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	out := make([]byte, 4*len(data))
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// pages returns the page dictionaries in order, each with the resources it
+// inherits
+func (doc *pdfDocument) pages() []pdfDict {
+	root := doc.dict(doc.trailer["Root"])
+	if root == nil {
+		for _, value := range doc.objects {
+			if d, ok := value.(pdfDict); ok && d["Type"] == pdfName("Catalog") {
+				root = d
+				break
+			}
+		}
+	}
+	if root == nil {
+		return nil
+	}
+
+	var pages []pdfDict
+	visited := map[int]bool{}
+	var walk func(node any, resources any, depth int)
+	walk = func(node any, resources any, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref.num] {
+				return
+			}
+			visited[ref.num] = true
+		}
+		d := doc.dict(node)
+		if d == nil || depth > maxNesting {
+			return
+		}
+		if r, ok := d["Resources"]; ok {
+			resources = r
+		}
+		if kids, ok := d["Kids"]; ok {
+			for _, kid := range doc.array(kids) {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		page := pdfDict{"Contents": d["Contents"], "Resources": resources}
+		pages = append(pages, page)
+	}
+	walk(root["Pages"], nil, 0)
+	return pages
+}
+
+// contents returns the decoded content streams of a page
+func (doc *pdfDocument) contents(page pdfDict) []byte {
+	var parts []any
+	switch c := doc.resolve(page["Contents"]).(type) {
+	case *pdfStream:
+		parts = []any{c}
+	case []any:
+		parts = c
+	}
+
+	var out []byte
+	for _, part := range parts {
+		stream, ok := doc.resolve(part).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := doc.decode(stream)
+		if err != nil {
+			continue
+		}
+		out = append(append(out, data...), '\n')
+	}
+	return out
+}
+
+// pdfLexer reads PDF tokens and objects
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+var errEndOfData = errors.New("unexpected end of PDF data")
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// token reads the next token: a number (float64), string ([]byte), name,
+// keyword or one of the delimiters "<<", ">>", "[" and "]" as a keyword
+func (l *pdfLexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errEndOfData
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		var name []byte
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			if l.data[l.pos] == '#' && l.pos+2 < len(l.data) && isHexDigit(l.data[l.pos+1]) && isHexDigit(l.data[l.pos+2]) {
+				b, _ := hex.DecodeString(string(l.data[l.pos+1 : l.pos+3]))
+				name = append(name, b...)
+				l.pos += 3
+				continue
+			}
+			name = append(name, l.data[l.pos])
+			l.pos++
+		}
+		return pdfName(name), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), nil
+		}
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && l.data[l.pos] != '>' {
+			l.pos++
+		}
+		s, _ := decodeASCIIHex(l.data[start:l.pos])
+		l.pos++
+		return s, nil
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), nil
+		}
+		l.pos++
+		return pdfKeyword(">"), nil
+	case c == '[' || c == ']' || c == '{' || c == '}' || c == ')':
+		l.pos++
+		return pdfKeyword(string(rune(c))), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil && (word[0] == '-' || word[0] == '+' || word[0] == '.' || (word[0] >= '0' && word[0] <= '9')) {
+		return n, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func (l *pdfLexer) literalString() []byte {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for k := 0; k < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; k++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(n)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// object reads a complete object: arrays and dictionaries are read whole,
+// and "num gen R" is read as a reference
+func (l *pdfLexer) object(depth int) (any, error) {
+	if depth > maxNesting {
+		return nil, errors.New("PDF objects nested too deeply")
+	}
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case float64:
+		// A reference is "num gen R"
+		save := l.pos
+		if gen, err := l.token(); err == nil {
+			if g, ok := gen.(float64); ok {
+				if r, err := l.token(); err == nil && r == pdfKeyword("R") {
+					return pdfRef{num: int(t), gen: int(g)}, nil
+				}
+			}
+		}
+		l.pos = save
+		return t, nil
+	case pdfKeyword:
+		switch t {
+		case "<<":
+			dict := pdfDict{}
+			for {
+				key, err := l.object(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				if key == pdfKeyword(">>") {
+					return dict, nil
+				}
+				name, ok := key.(pdfName)
+				if !ok {
+					continue
+				}
+				value, err := l.object(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				if value == pdfKeyword(">>") {
+					return dict, nil
+				}
+				dict[name] = value
+			}
+		case "[":
+			var array []any
+			for {
+				value, err := l.object(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				if value == pdfKeyword("]") {
+					return array, nil
+				}
+				array = append(array, value)
+			}
+		case "true", "false":
+			return t == "true", nil
+		case "null":
+			return nil, nil
+		}
+		return t, nil
+	}
+	return tok, nil
+}
+
+// stream reads the data of a stream following its dictionary, when there
+// is one. A missing or wrong direct length falls back to the endstream
+// keyword.
+func (l *pdfLexer) stream(dict pdfDict) (*pdfStream, bool) {
+	save := l.pos
+	if tok, err := l.token(); err != nil || tok != pdfKeyword("stream") {
+		l.pos = save
+		return nil, false
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+
+	if n, ok := dict["Length"].(float64); ok && n >= 0 && start+int(n) <= len(l.data) {
+		end := start + int(n)
+		rest := bytes.TrimLeft(l.data[end:min(end+32, len(l.data))], "\r\n \t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = end
+			return &pdfStream{dict: dict, raw: l.data[start:end]}, true
+		}
+	}
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		return &pdfStream{dict: dict, raw: l.data[start:]}, true
+	}
+	raw := bytes.TrimRight(l.data[start:start+end], "\r\n")
+	l.pos = start + end
+	return &pdfStream{dict: dict, raw: raw}, true
+}
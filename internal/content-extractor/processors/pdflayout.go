@@ -0,0 +1,216 @@
+package processors
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Layout thresholds, in multiples of the font size
+const (
+	// sameLineRise is how far apart baselines of one line may be
+	sameLineRise = 0.5
+	// wordGap is the gap between text runs taken as a space
+	wordGap = 0.15
+	// columnGap is the gap between text runs taken as a column break
+	columnGap = 1.5
+	// rowGap is the line spacing above which a table ends
+	rowGap = 2.5
+	// paragraphGap is the line spacing above which a paragraph ends
+	paragraphGap = 1.8
+	// scriptSize and scriptRise are how much smaller and how far above or
+	// below the baseline text is to be a superscript or subscript
+	scriptSize = 0.85
+	scriptRise = 0.15
+)
+
+// maxTableCell is the average cell length above which lines with column
+// breaks are taken for a multi-column page layout rather than a table
+const maxTableCell = 40
+
+// pdfLine is a line of a page, split into cells at column gaps
+type pdfLine struct {
+	y, size float64
+	cells   []pdfCell
+}
+
+type pdfCell struct {
+	x, endX float64
+	text    string
+}
+
+// layoutPage orders the text runs of a page into lines, top to bottom
+func layoutPage(texts []pdfText) []pdfLine {
+	sort.SliceStable(texts, func(i, j int) bool { return texts[i].y > texts[j].y })
+
+	var groups [][]pdfText
+	for _, t := range texts {
+		if n := len(groups); n > 0 {
+			last := groups[n-1][0]
+			if math.Abs(t.y-last.y) <= sameLineRise*math.Max(math.Max(t.size, last.size), 1) {
+				groups[n-1] = append(groups[n-1], t)
+				continue
+			}
+		}
+		groups = append(groups, []pdfText{t})
+	}
+
+	lines := make([]pdfLine, 0, len(groups))
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool { return group[i].x < group[j].x })
+		base := group[0]
+		for _, t := range group {
+			if t.size > base.size {
+				base = t
+			}
+		}
+		line := pdfLine{y: base.y}
+		for _, t := range group {
+			size := math.Max(t.size, 1)
+			line.size = math.Max(line.size, size)
+			// Smaller raised or lowered text is a superscript or subscript,
+			// marked as in formulas: x^2, a_i
+			text, script := t.text, false
+			if t.size < scriptSize*base.size {
+				switch rise := t.y - base.y; {
+				case rise > scriptRise*base.size:
+					text, script = "^"+strings.TrimSpace(text), true
+				case rise < -scriptRise*base.size:
+					text, script = "_"+strings.TrimSpace(text), true
+				}
+			}
+			n := len(line.cells)
+			if n == 0 || t.x-line.cells[n-1].endX > columnGap*size {
+				line.cells = append(line.cells, pdfCell{x: t.x, endX: t.endX, text: text})
+				continue
+			}
+			cell := &line.cells[n-1]
+			if !script && t.x-cell.endX > wordGap*size && !strings.HasSuffix(cell.text, " ") && !strings.HasPrefix(text, " ") {
+				cell.text += " "
+			}
+			cell.text += text
+			cell.endX = math.Max(cell.endX, t.endX)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// writePage writes the lines of a page, with tables as rows of named
+// cells or left out without includeTables, and returns the number of tables
+func writePage(b *strings.Builder, lines []pdfLine, includeTables bool) int {
+	tables := 0
+	for i := 0; i < len(lines); {
+		if end := tableEnd(lines, i); end > i {
+			if includeTables {
+				writeTable(b, tableRows(lines[i:end]))
+				b.WriteString("\n")
+			}
+			tables++
+			i = end
+			continue
+		}
+
+		line := lines[i]
+		texts := make([]string, len(line.cells))
+		for k, cell := range line.cells {
+			texts[k] = strings.TrimSpace(cell.text)
+		}
+		b.WriteString(strings.Join(texts, " ") + "\n")
+		if i+1 < len(lines) && line.y-lines[i+1].y > paragraphGap*line.size {
+			b.WriteString("\n")
+		}
+		i++
+	}
+	return tables
+}
+
+// tableEnd returns the end of a table starting at line i, or i when no
+// table starts there: a table is two or more consecutive lines with column
+// breaks, short cells and columns lining up
+func tableEnd(lines []pdfLine, i int) int {
+	end := i
+	for end < len(lines) && len(lines[end].cells) >= 2 {
+		if end > i && lines[end-1].y-lines[end].y > rowGap*lines[end-1].size {
+			break
+		}
+		end++
+	}
+	if end-i < 2 {
+		return i
+	}
+
+	run := lines[i:end]
+	cells, length := 0, 0
+	for _, line := range run {
+		for _, cell := range line.cells {
+			cells++
+			length += utf8.RuneCountInString(cell.text)
+		}
+	}
+	if length/cells > maxTableCell {
+		return i
+	}
+
+	columns := tableColumns(run)
+	aligned := 0
+	for _, line := range run {
+		for _, cell := range line.cells {
+			if _, overlaps := columnOf(columns, cell); overlaps {
+				aligned++
+			}
+		}
+	}
+	if aligned*10 < cells*7 {
+		return i
+	}
+	return end
+}
+
+// tableColumns takes the columns of a table from its line with the most
+// cells
+func tableColumns(run []pdfLine) []pdfCell {
+	widest := run[0]
+	for _, line := range run[1:] {
+		if len(line.cells) > len(widest.cells) {
+			widest = line
+		}
+	}
+	return widest.cells
+}
+
+// columnOf returns the column a cell overlaps most, or the nearest one
+// when it overlaps none
+func columnOf(columns []pdfCell, cell pdfCell) (int, bool) {
+	best, bestOverlap := 0, 0.0
+	bestDistance := math.Inf(1)
+	for k, col := range columns {
+		overlap := math.Min(cell.endX, col.endX) - math.Max(cell.x, col.x)
+		if overlap > bestOverlap {
+			best, bestOverlap = k, overlap
+		}
+		if bestOverlap == 0 {
+			distance := math.Abs((cell.x+cell.endX)/2 - (col.x+col.endX)/2)
+			if distance < bestDistance {
+				best, bestDistance = k, distance
+			}
+		}
+	}
+	return best, bestOverlap > 0
+}
+
+// tableRows places the cells of each line of a table in their columns
+func tableRows(run []pdfLine) [][]string {
+	columns := tableColumns(run)
+	rows := make([][]string, len(run))
+	for r, line := range run {
+		row := make([]string, len(columns))
+		for _, cell := range line.cells {
+			k, _ := columnOf(columns, cell)
+			row[k] = strings.TrimSpace(row[k] + " " + cell.text)
+		}
+		rows[r] = row
+	}
+	return rows
+}
@@ -0,0 +1,581 @@
+package processors
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// maxFormDepth bounds form XObjects drawn inside each other
+const maxFormDepth = 8
+
+// pdfText is a run of text shown on a page, at its start and end on the
+// baseline in page space
+type pdfText struct {
+	x, y, endX float64
+	size       float64
+	text       string
+}
+
+// pdfMatrix is a PDF transformation matrix [a b c d e f], mapping (x, y)
+// to (a*x + c*y + e, b*x + d*y + f)
+type pdfMatrix [6]float64
+
+var pdfIdentity = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// then returns the transform applying n and then m
+func (m pdfMatrix) then(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translation(x, y float64) pdfMatrix {
+	return pdfMatrix{1, 0, 0, 1, x, y}
+}
+
+// pdfFont decodes the strings shown with a font
+type pdfFont struct {
+	codeBytes    int // 1 for simple fonts, 2 for composite fonts
+	toUnicode    map[uint32]string
+	encoding     [256]string
+	widths       map[uint32]float64 // in thousandths of the font size
+	defaultWidth float64
+}
+
+// pdfGlyph is one character code of a shown string
+type pdfGlyph struct {
+	code  uint32
+	text  string
+	width float64
+}
+
+// glyphs splits a shown string into character codes
+func (f *pdfFont) glyphs(s []byte) []pdfGlyph {
+	var out []pdfGlyph
+	for i := 0; i+f.codeBytes <= len(s); i += f.codeBytes {
+		var code uint32
+		for _, b := range s[i : i+f.codeBytes] {
+			code = code<<8 | uint32(b)
+		}
+		text, ok := f.toUnicode[code]
+		if !ok && f.codeBytes == 1 {
+			text = f.encoding[code]
+		}
+		width, ok := f.widths[code]
+		if !ok {
+			width = f.defaultWidth
+		}
+		out = append(out, pdfGlyph{code: code, text: text, width: width})
+	}
+	return out
+}
+
+// defaultFont decodes text shown without a usable font as WinAnsi
+var defaultFont = newSimpleFont(nil)
+
+func newSimpleFont(base *charmap.Charmap) *pdfFont {
+	if base == nil {
+		base = charmap.Windows1252
+	}
+	f := &pdfFont{codeBytes: 1, defaultWidth: 500}
+	for i := range f.encoding {
+		if r := base.DecodeByte(byte(i)); r >= ' ' && r != 0xFFFD {
+			f.encoding[i] = string(r)
+		}
+	}
+	return f
+}
+
+// font loads a font dictionary
+func (doc *pdfDocument) font(d pdfDict) *pdfFont {
+	var f *pdfFont
+	if d["Subtype"] == pdfName("Type0") {
+		f = &pdfFont{codeBytes: 2, defaultWidth: 1000, widths: map[uint32]float64{}}
+		if descendants := doc.array(d["DescendantFonts"]); len(descendants) > 0 {
+			descendant := doc.dict(descendants[0])
+			f.defaultWidth = doc.number(descendant["DW"], 1000)
+			doc.compositeWidths(f, doc.array(descendant["W"]))
+		}
+	} else {
+		base := charmap.Windows1252
+		var differences []any
+		switch e := doc.resolve(d["Encoding"]).(type) {
+		case pdfName:
+			if e == "MacRomanEncoding" {
+				base = charmap.Macintosh
+			}
+		case pdfDict:
+			if doc.resolve(e["BaseEncoding"]) == pdfName("MacRomanEncoding") {
+				base = charmap.Macintosh
+			}
+			differences = doc.array(e["Differences"])
+		}
+		f = newSimpleFont(base)
+		if baseFont, _ := doc.resolve(d["BaseFont"]).(pdfName); strings.Contains(string(baseFont), "Symbol") && d["Encoding"] == nil {
+			for code, text := range symbolEncoding {
+				f.encoding[code] = text
+			}
+		}
+		code := 0
+		for _, item := range differences {
+			switch v := doc.resolve(item).(type) {
+			case float64:
+				code = int(v)
+			case pdfName:
+				if code >= 0 && code < 256 {
+					f.encoding[code] = glyphText(string(v))
+				}
+				code++
+			}
+		}
+
+		f.widths = map[uint32]float64{}
+		first := int(doc.number(d["FirstChar"], 0))
+		for i, w := range doc.array(d["Widths"]) {
+			f.widths[uint32(first+i)] = doc.number(w, 0)
+		}
+		if descriptor := doc.dict(d["FontDescriptor"]); descriptor != nil {
+			if missing := doc.number(descriptor["MissingWidth"], 0); missing > 0 {
+				f.defaultWidth = missing
+			}
+		}
+	}
+
+	if stream, ok := doc.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if data, err := doc.decode(stream); err == nil {
+			f.toUnicode, f.codeBytes = parseCMap(data, f.codeBytes)
+		}
+	}
+	return f
+}
+
+// compositeWidths reads the W array of a CIDFont: "c [w1 w2 ...]" gives
+// the widths of consecutive codes from c, "first last w" one width for a
+// range
+func (doc *pdfDocument) compositeWidths(f *pdfFont, w []any) {
+	for i := 0; i < len(w); {
+		first, ok := doc.resolve(w[i]).(float64)
+		if !ok || i+1 >= len(w) {
+			return
+		}
+		if list, ok := doc.resolve(w[i+1]).([]any); ok {
+			for k, width := range list {
+				f.widths[uint32(int(first)+k)] = doc.number(width, f.defaultWidth)
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			return
+		}
+		last := doc.number(w[i+1], first)
+		width := doc.number(w[i+2], f.defaultWidth)
+		for code := int(first); code <= int(last) && code-int(first) < 0x10000; code++ {
+			f.widths[uint32(code)] = width
+		}
+		i += 3
+	}
+}
+
+var (
+	cmapSection = regexp.MustCompile(`(?s)begin(codespacerange|bfchar|bfrange)(.*?)end(?:codespacerange|bfchar|bfrange)`)
+	cmapToken   = regexp.MustCompile(`<([0-9A-Fa-f\s]*)>|\[|\]`)
+)
+
+// parseCMap reads a ToUnicode CMap: the code length from its codespace
+// range, and the text of codes from its bfchar and bfrange mappings
+func parseCMap(data []byte, codeBytes int) (map[uint32]string, int) {
+	mapping := map[uint32]string{}
+	for _, section := range cmapSection.FindAllSubmatch(data, -1) {
+		var tokens []string
+		for _, m := range cmapToken.FindAllSubmatch(section[2], -1) {
+			if m[1] != nil {
+				tokens = append(tokens, strings.Join(strings.Fields(string(m[1])), ""))
+			} else {
+				tokens = append(tokens, string(m[0]))
+			}
+		}
+
+		switch string(section[1]) {
+		case "codespacerange":
+			if len(tokens) >= 1 && len(tokens[0]) >= 2 {
+				codeBytes = len(tokens[0]) / 2
+			}
+		case "bfchar":
+			for i := 0; i+1 < len(tokens); i += 2 {
+				mapping[hexCode(tokens[i])] = utf16Text(tokens[i+1])
+			}
+		case "bfrange":
+			for i := 0; i+2 < len(tokens); {
+				lo, hi := hexCode(tokens[i]), hexCode(tokens[i+1])
+				if hi < lo || hi-lo > 0xFFFF {
+					hi = lo
+				}
+				if tokens[i+2] == "[" {
+					k := i + 3
+					for code := lo; k < len(tokens) && tokens[k] != "]"; code, k = code+1, k+1 {
+						if code <= hi {
+							mapping[code] = utf16Text(tokens[k])
+						}
+					}
+					i = k + 1
+					continue
+				}
+				dst := utf16Units(tokens[i+2])
+				for code := lo; code <= hi && len(dst) > 0; code++ {
+					mapping[code] = string(utf16.Decode(dst))
+					dst[len(dst)-1]++
+				}
+				i += 3
+			}
+		}
+	}
+	return mapping, codeBytes
+}
+
+func hexCode(h string) uint32 {
+	n, _ := strconv.ParseUint(h, 16, 32)
+	return uint32(n)
+}
+
+func utf16Units(h string) []uint16 {
+	b, _ := decodeASCIIHex([]byte(h))
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return units
+}
+
+func utf16Text(h string) string {
+	return string(utf16.Decode(utf16Units(h)))
+}
+
+// glyphNames are the text of common glyph names in font encoding
+// differences; single letters and uniXXXX names are read directly
+var glyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quotesingle": "'", "parenleft": "(", "parenright": ")",
+	"asterisk": "*", "plus": "+", "comma": ",", "hyphen": "-", "period": ".", "slash": "/",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4", "five": "5", "six": "6",
+	"seven": "7", "eight": "8", "nine": "9", "colon": ":", "semicolon": ";", "less": "<",
+	"equal": "=", "greater": ">", "question": "?", "at": "@", "bracketleft": "[",
+	"backslash": "\\", "bracketright": "]", "asciicircum": "^", "underscore": "_",
+	"grave": "`", "braceleft": "{", "bar": "|", "braceright": "}", "asciitilde": "~",
+	"quoteleft": "‘", "quoteright": "’", "quotedblleft": "“", "quotedblright": "”",
+	"endash": "–", "emdash": "—", "bullet": "•", "ellipsis": "…",
+	"minus": "-", "nbspace": " ", "nonbreakingspace": " ", "degree": "°",
+	"copyright": "©", "registered": "®", "trademark": "™", "Euro": "€",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+}
+
+// Greek letter glyph names, in the order of their letters from α and Α
+var greekNames = strings.Fields("alpha beta gamma delta epsilon zeta eta theta iota kappa lambda mu nu xi omicron pi rho sigma tau upsilon phi chi psi omega")
+
+// mathGlyphNames are the text of the glyph names of math symbols
+var mathGlyphNames = map[string]string{
+	"summation": "∑", "product": "∏", "integral": "∫", "infinity": "∞", "partialdiff": "∂",
+	"gradient": "∇", "radical": "√", "lessequal": "≤", "greaterequal": "≥", "notequal": "≠",
+	"approxequal": "≈", "equivalence": "≡", "plusminus": "±", "multiply": "×", "divide": "÷",
+	"element": "∈", "notelement": "∉", "arrowright": "→", "arrowdblright": "⇒", "arrowdblboth": "⇔",
+	"proportional": "∝", "universal": "∀", "existential": "∃", "intersection": "∩", "union": "∪",
+	"propersubset": "⊂", "reflexsubset": "⊆", "logicaland": "∧", "logicalor": "∨", "logicalnot": "¬",
+	"dotmath": "⋅", "prime": "′", "circlemultiply": "⊗", "circleplus": "⊕", "angle": "∠",
+}
+
+func init() {
+	for i, name := range greekNames {
+		lower, upper := 'α'+rune(i), 'Α'+rune(i)
+		if lower >= 'ς' {
+			lower++ // final sigma has no glyph name of its own
+		}
+		if upper >= '\u03A2' {
+			upper++ // unassigned between Ρ and Σ
+		}
+		glyphNames[name] = string(lower)
+		glyphNames[strings.ToUpper(name[:1])+name[1:]] = string(upper)
+	}
+	for name, text := range mathGlyphNames {
+		glyphNames[name] = text
+	}
+}
+
+// symbolEncoding is the built-in encoding of the Symbol font where it
+// differs from WinAnsi: Greek letters and math symbols
+var symbolEncoding = map[byte]string{
+	0x22: "∀", 0x24: "∃", 0x27: "∋", 0x2A: "∗", 0x2D: "−", 0x40: "≅", 0x5E: "⊥", 0x7E: "∼",
+	0x41: "Α", 0x42: "Β", 0x43: "Χ", 0x44: "Δ", 0x45: "Ε", 0x46: "Φ", 0x47: "Γ", 0x48: "Η",
+	0x49: "Ι", 0x4A: "ϑ", 0x4B: "Κ", 0x4C: "Λ", 0x4D: "Μ", 0x4E: "Ν", 0x4F: "Ο", 0x50: "Π",
+	0x51: "Θ", 0x52: "Ρ", 0x53: "Σ", 0x54: "Τ", 0x55: "Υ", 0x56: "ς", 0x57: "Ω", 0x58: "Ξ",
+	0x59: "Ψ", 0x5A: "Ζ",
+	0x61: "α", 0x62: "β", 0x63: "χ", 0x64: "δ", 0x65: "ε", 0x66: "φ", 0x67: "γ", 0x68: "η",
+	0x69: "ι", 0x6A: "ϕ", 0x6B: "κ", 0x6C: "λ", 0x6D: "μ", 0x6E: "ν", 0x6F: "ο", 0x70: "π",
+	0x71: "θ", 0x72: "ρ", 0x73: "σ", 0x74: "τ", 0x75: "υ", 0x76: "ϖ", 0x77: "ω", 0x78: "ξ",
+	0x79: "ψ", 0x7A: "ζ",
+	0xA3: "≤", 0xA5: "∞", 0xAE: "→", 0xB0: "°", 0xB1: "±", 0xB3: "≥", 0xB4: "×", 0xB5: "∝",
+	0xB6: "∂", 0xB7: "•", 0xB8: "÷", 0xB9: "≠", 0xBA: "≡", 0xBB: "≈", 0xC4: "⊗", 0xC5: "⊕",
+	0xC7: "∩", 0xC8: "∪", 0xC9: "⊃", 0xCC: "⊂", 0xCD: "⊆", 0xCE: "∈", 0xCF: "∉", 0xD0: "∠",
+	0xD1: "∇", 0xD5: "∏", 0xD6: "√", 0xD7: "⋅", 0xD8: "¬", 0xD9: "∧", 0xDA: "∨", 0xDB: "⇔",
+	0xDE: "⇒", 0xE5: "∑", 0xF2: "∫",
+}
+
+// glyphText returns the text of a glyph name, or nothing for unknown names
+func glyphText(name string) string {
+	if text, ok := glyphNames[name]; ok {
+		return text
+	}
+	if len(name) == 1 {
+		return name
+	}
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if n, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return string(rune(n))
+		}
+	}
+	if strings.HasPrefix(name, "u") && (len(name) == 5 || len(name) == 7) {
+		if n, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return string(rune(n))
+		}
+	}
+	return ""
+}
+
+// textState is the graphics and text state of a content stream
+type textState struct {
+	ctm       pdfMatrix
+	font      *pdfFont
+	size      float64
+	charSpace float64
+	wordSpace float64
+	scale     float64
+	leading   float64
+}
+
+// contentReader collects the text shown by content streams
+type contentReader struct {
+	doc   *pdfDocument
+	fonts map[any]*pdfFont
+	out   []pdfText
+}
+
+var inlineImageEnd = regexp.MustCompile(`\sEI(\s|$)`)
+
+// pageText returns the text shown on a page
+func (doc *pdfDocument) pageText(page pdfDict) []pdfText {
+	r := &contentReader{doc: doc, fonts: map[any]*pdfFont{}}
+	r.run(doc.contents(page), doc.dict(page["Resources"]), pdfIdentity, 0)
+	return r.out
+}
+
+// run interprets a content stream
+func (r *contentReader) run(content []byte, resources pdfDict, ctm pdfMatrix, depth int) {
+	state := textState{ctm: ctm, font: defaultFont, scale: 1}
+	var stack []textState
+	var tm, tlm pdfMatrix
+	var operands []any
+
+	lex := &pdfLexer{data: content}
+	for {
+		value, err := lex.object(0)
+		if err != nil {
+			return
+		}
+		op, ok := value.(pdfKeyword)
+		if !ok {
+			operands = append(operands, value)
+			continue
+		}
+		num := func(i int) float64 {
+			if i < len(operands) {
+				if n, ok := operands[i].(float64); ok {
+					return n
+				}
+			}
+			return 0
+		}
+		str := func(i int) []byte {
+			if i < len(operands) {
+				if s, ok := operands[i].([]byte); ok {
+					return s
+				}
+			}
+			return nil
+		}
+		nextLine := func(tx, ty float64) {
+			tlm = translation(tx, ty).then(tlm)
+			tm = tlm
+		}
+
+		switch op {
+		case "q":
+			stack = append(stack, state)
+		case "Q":
+			if len(stack) > 0 {
+				state, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(operands) == 6 {
+				state.ctm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}.then(state.ctm)
+			}
+		case "BT":
+			tm, tlm = pdfIdentity, pdfIdentity
+		case "Tf":
+			if len(operands) == 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					state.font = r.font(resources, name)
+				}
+				state.size = num(1)
+			}
+		case "Tc":
+			state.charSpace = num(0)
+		case "Tw":
+			state.wordSpace = num(0)
+		case "Tz":
+			state.scale = num(0) / 100
+		case "TL":
+			state.leading = num(0)
+		case "Td":
+			nextLine(num(0), num(1))
+		case "TD":
+			state.leading = -num(1)
+			nextLine(num(0), num(1))
+		case "Tm":
+			if len(operands) == 6 {
+				tlm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+				tm = tlm
+			}
+		case "T*":
+			nextLine(0, -state.leading)
+		case "Tj":
+			tm = r.show(&state, tm, [][]byte{str(0)}, nil)
+		case "'":
+			nextLine(0, -state.leading)
+			tm = r.show(&state, tm, [][]byte{str(0)}, nil)
+		case "\"":
+			state.wordSpace, state.charSpace = num(0), num(1)
+			nextLine(0, -state.leading)
+			tm = r.show(&state, tm, [][]byte{str(2)}, nil)
+		case "TJ":
+			if len(operands) > 0 {
+				var parts [][]byte
+				var adjust []float64
+				items, _ := operands[0].([]any)
+				for _, item := range items {
+					switch v := item.(type) {
+					case []byte:
+						parts = append(parts, v)
+						adjust = append(adjust, 0)
+					case float64:
+						if len(parts) == 0 {
+							parts, adjust = append(parts, nil), append(adjust, 0)
+						}
+						adjust[len(adjust)-1] += v
+					}
+				}
+				tm = r.show(&state, tm, parts, adjust)
+			}
+		case "Do":
+			if len(operands) == 1 && depth < maxFormDepth {
+				if name, ok := operands[0].(pdfName); ok {
+					r.form(resources, name, state.ctm, depth)
+				}
+			}
+		case "ID":
+			// Inline image data runs to EI
+			if loc := inlineImageEnd.FindIndex(content[lex.pos:]); loc != nil {
+				lex.pos += loc[1]
+			} else {
+				return
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// show records the text of strings shown at the text matrix, and returns
+// the text matrix after them. adjust holds the TJ position adjustment
+// after each string, in thousandths of the font size; large ones separate
+// words.
+func (r *contentReader) show(state *textState, tm pdfMatrix, parts [][]byte, adjust []float64) pdfMatrix {
+	trm := tm.then(state.ctm)
+	start := pdfText{x: trm[4], y: trm[5], size: state.size * math.Hypot(trm[2], trm[3])}
+
+	var b strings.Builder
+	for i, s := range parts {
+		for _, g := range state.font.glyphs(s) {
+			b.WriteString(g.text)
+			tx := g.width/1000*state.size + state.charSpace
+			if state.font.codeBytes == 1 && g.code == ' ' {
+				tx += state.wordSpace
+			}
+			tm = translation(tx*state.scale, 0).then(tm)
+		}
+		if adjust != nil && adjust[i] != 0 {
+			tx := -adjust[i] / 1000 * state.size
+			if tx > 0.2*state.size {
+				b.WriteByte(' ')
+			}
+			tm = translation(tx*state.scale, 0).then(tm)
+		}
+	}
+
+	start.text = b.String()
+	start.endX = tm.then(state.ctm)[4]
+	if strings.TrimSpace(start.text) != "" {
+		r.out = append(r.out, start)
+	}
+	return tm
+}
+
+// font returns a font of the resources, loading it on first use
+func (r *contentReader) font(resources pdfDict, name pdfName) *pdfFont {
+	ref := r.doc.dict(resources["Font"])[name]
+	key := any(ref)
+	if _, ok := ref.(pdfRef); !ok {
+		key = name
+	}
+	if f, ok := r.fonts[key]; ok {
+		return f
+	}
+	f := defaultFont
+	if d := r.doc.dict(ref); d != nil {
+		f = r.doc.font(d)
+	}
+	r.fonts[key] = f
+	return f
+}
+
+// form interprets a form XObject drawn with Do
+func (r *contentReader) form(resources pdfDict, name pdfName, ctm pdfMatrix, depth int) {
+	stream, ok := r.doc.resolve(r.doc.dict(resources["XObject"])[name]).(*pdfStream)
+	if !ok || stream.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := r.doc.decode(stream)
+	if err != nil || !bytes.Contains(data, []byte("BT")) {
+		return
+	}
+	if m := r.doc.array(stream.dict["Matrix"]); len(m) == 6 {
+		var fm pdfMatrix
+		for i := range fm {
+			fm[i] = r.doc.number(m[i], 0)
+		}
+		ctm = fm.then(ctm)
+	}
+	formResources := r.doc.dict(stream.dict["Resources"])
+	if formResources == nil {
+		formResources = resources
+	}
+	r.run(data, formResources, ctm, depth+1)
+}
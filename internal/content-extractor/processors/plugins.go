@@ -0,0 +1,348 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"go.uber.org/zap"
+)
+
+// Processor plugins are WebAssembly modules. A plugin exports:
+//
+//	memory
+//	alloc(size i32) i32
+//	can_process(type_ptr, type_len i32) i32
+//	extract(type_ptr, type_len, data_ptr, data_len i32) i64
+//
+// alloc returns the address of size bytes of the plugin's memory, which the
+// host writes the arguments of a call to. can_process answers nonzero when
+// the plugin extracts files of a type, given as a lower-case extension with
+// the dot (".dwg"). extract is given the type and the file's content and
+// returns the address of the extracted UTF-8 text in its upper 32 bits and
+// its length in the lower 32 bits. To fail, a plugin calls the host function
+// fail(msg_ptr, msg_len i32) it may import from the "repograph" module; the
+// result of extract is then ignored. Plugins may also import WASI preview 1,
+// without access to files, the environment or the network.
+//
+// Every call runs in a new instance of the module, whose "_initialize"
+// export, if any, runs first, so plugins keep no state between calls and
+// need not free what they allocate.
+const pluginHostModule = "repograph"
+
+// PluginHost runs the processor plugins loaded from a directory
+type PluginHost struct {
+	runtime wazero.Runtime
+	plugins []*PluginProcessor
+}
+
+// LoadPlugins compiles every .wasm module in the configured plugin
+// directory, in name order. It returns nil without an error when plugins
+// are disabled.
+func LoadPlugins(ctx context.Context, logger *zap.Logger, cfg config.PluginsConfig) (*PluginHost, error) {
+	if cfg.Dir == "" {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(cfg.Dir, "*.wasm"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugins: %w", err)
+	}
+	sort.Strings(paths)
+
+	pages := uint32(min(cfg.MaxMemory/65536, 65536))
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(pages).
+		WithCloseOnContextDone(true))
+	host := &PluginHost{runtime: runtime}
+	if err := host.instantiateImports(ctx); err != nil {
+		runtime.Close(ctx) //nolint:errcheck
+		return nil, err
+	}
+
+	for _, path := range paths {
+		plugin, err := host.compile(ctx, logger, path, cfg)
+		if err != nil {
+			runtime.Close(ctx) //nolint:errcheck
+			return nil, err
+		}
+		host.plugins = append(host.plugins, plugin)
+		logger.Info("Loaded processor plugin", zap.String("plugin", plugin.name), zap.String("path", path))
+	}
+	if len(paths) == 0 {
+		logger.Warn("No processor plugins found", zap.String("directory", cfg.Dir))
+	}
+	return host, nil
+}
+
+// instantiateImports provides the host functions and WASI to plugins
+func (h *PluginHost) instantiateImports(ctx context.Context) error {
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, h.runtime); err != nil {
+		return fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+	_, err := h.runtime.NewHostModuleBuilder(pluginHostModule).
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, mod api.Module, ptr, length uint32) {
+			failure, ok := ctx.Value(pluginFailureKey{}).(*string)
+			if !ok {
+				return
+			}
+			message, ok := mod.Memory().Read(ptr, length)
+			if !ok {
+				*failure = "failed with a message out of memory bounds"
+				return
+			}
+			*failure = string(message)
+			if *failure == "" {
+				*failure = "failed"
+			}
+		}).
+		Export("fail").
+		Instantiate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate plugin host functions: %w", err)
+	}
+	return nil
+}
+
+// pluginExports are the functions a plugin must export, with their
+// parameter and result types
+var pluginExports = map[string][2][]api.ValueType{
+	"alloc":       {{api.ValueTypeI32}, {api.ValueTypeI32}},
+	"can_process": {{api.ValueTypeI32, api.ValueTypeI32}, {api.ValueTypeI32}},
+	"extract":     {{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32}, {api.ValueTypeI64}},
+}
+
+// compile compiles a plugin and checks that it implements the ABI
+func (h *PluginHost) compile(ctx context.Context, logger *zap.Logger, path string, cfg config.PluginsConfig) (*PluginProcessor, error) {
+	name := strings.TrimSuffix(filepath.Base(path), ".wasm")
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin %s: %w", name, err)
+	}
+	module, err := h.runtime.CompileModule(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile plugin %s: %w", name, err)
+	}
+	if _, ok := module.ExportedMemories()["memory"]; !ok {
+		return nil, fmt.Errorf("plugin %s does not export its memory", name)
+	}
+	functions := module.ExportedFunctions()
+	for export, signature := range pluginExports {
+		fn, ok := functions[export]
+		if !ok {
+			return nil, fmt.Errorf("plugin %s does not export %s", name, export)
+		}
+		if !sameTypes(fn.ParamTypes(), signature[0]) || !sameTypes(fn.ResultTypes(), signature[1]) {
+			return nil, fmt.Errorf("plugin %s exports %s with the wrong signature", name, export)
+		}
+	}
+	return &PluginProcessor{
+		name:      name,
+		runtime:   h.runtime,
+		module:    module,
+		timeout:   cfg.Timeout,
+		maxMemory: cfg.MaxMemory,
+		logger:    logger,
+		accepts:   make(map[string]bool),
+	}, nil
+}
+
+func sameTypes(a, b []api.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Processors returns the plugins as processors, in load order. A nil host
+// has none.
+func (h *PluginHost) Processors() []ProcessorInterface {
+	if h == nil {
+		return nil
+	}
+	processors := make([]ProcessorInterface, len(h.plugins))
+	for i, plugin := range h.plugins {
+		processors[i] = plugin
+	}
+	return processors
+}
+
+// Close releases the plugins
+func (h *PluginHost) Close(ctx context.Context) error {
+	if h == nil {
+		return nil
+	}
+	return h.runtime.Close(ctx)
+}
+
+// pluginFailureKey holds, in the context of a call, where the fail host
+// function records the plugin's message
+type pluginFailureKey struct{}
+
+// PluginProcessor extracts content with a WebAssembly plugin
+type PluginProcessor struct {
+	name      string
+	runtime   wazero.Runtime
+	module    wazero.CompiledModule
+	timeout   time.Duration
+	maxMemory int64
+	logger    *zap.Logger
+
+	mu      sync.Mutex
+	accepts map[string]bool // can_process answers by file type
+}
+
+// Name returns the plugin's name, its file name without .wasm
+func (p *PluginProcessor) Name() string {
+	return p.name
+}
+
+// Describe returns the formats the plugin has accepted so far; a plugin is
+// only asked about a type when a file of the type is processed
+func (p *PluginProcessor) Describe() Capabilities {
+	p.mu.Lock()
+	var extensions []string
+	for fileType, accepts := range p.accepts {
+		if accepts {
+			extensions = append(extensions, fileType)
+		}
+	}
+	p.mu.Unlock()
+	sort.Strings(extensions)
+	return Capabilities{
+		Name:     "plugin:" + p.name,
+		Category: "plugin",
+		Formats:  extensionFormats(extensions),
+		Dynamic:  true,
+	}
+}
+
+// CanProcess asks the plugin whether it handles the file type. Answers are
+// remembered, so the plugin is asked once per type.
+func (p *PluginProcessor) CanProcess(fileType string) bool {
+	fileType = strings.ToLower(fileType)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if accepts, ok := p.accepts[fileType]; ok {
+		return accepts
+	}
+
+	var accepts bool
+	err := p.call(context.Background(), func(ctx context.Context, mod api.Module) error {
+		ptr, length, err := pluginWrite(ctx, mod, []byte(fileType))
+		if err != nil {
+			return err
+		}
+		results, err := mod.ExportedFunction("can_process").Call(ctx, ptr, length)
+		if err != nil {
+			return err
+		}
+		accepts = uint32(results[0]) != 0
+		return nil
+	})
+	if err != nil {
+		p.logger.Warn("Processor plugin failed to answer for a file type",
+			zap.String("plugin", p.name),
+			zap.String("file_type", fileType),
+			zap.Error(err))
+	}
+	p.accepts[fileType] = accepts
+	return accepts
+}
+
+// Extract hands the file's content to the plugin and returns the text it
+// extracts
+func (p *PluginProcessor) Extract(ctx context.Context, filePath string) (string, error) {
+	p.logger.Debug("Extracting with processor plugin", zap.String("file", filePath), zap.String("plugin", p.name))
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(data)) > p.maxMemory {
+		return "", fmt.Errorf("file is larger than the %d bytes of memory plugin %s may use", p.maxMemory, p.name)
+	}
+
+	var text string
+	err = p.call(ctx, func(ctx context.Context, mod api.Module) error {
+		typePtr, typeLen, err := pluginWrite(ctx, mod, []byte(fileType(ctx, filePath)))
+		if err != nil {
+			return err
+		}
+		dataPtr, dataLen, err := pluginWrite(ctx, mod, data)
+		if err != nil {
+			return err
+		}
+		results, err := mod.ExportedFunction("extract").Call(ctx, typePtr, typeLen, dataPtr, dataLen)
+		if err != nil {
+			return err
+		}
+		out, ok := mod.Memory().Read(uint32(results[0]>>32), uint32(results[0]))
+		if !ok {
+			return fmt.Errorf("extracted text is out of memory bounds")
+		}
+		text = string(out)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// call runs fn on a new instance of the plugin, within the plugin timeout
+func (p *PluginProcessor) call(ctx context.Context, fn func(ctx context.Context, mod api.Module) error) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	var failure string
+	ctx = context.WithValue(ctx, pluginFailureKey{}, &failure)
+
+	mod, err := p.runtime.InstantiateModule(ctx, p.module,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+	if err == nil {
+		defer mod.Close(context.Background()) //nolint:errcheck
+		err = fn(ctx, mod)
+	}
+	switch {
+	case failure != "":
+		return fmt.Errorf("plugin %s: %s", p.name, failure)
+	case err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("plugin %s timed out after %s", p.name, p.timeout)
+	case err != nil:
+		return fmt.Errorf("plugin %s failed: %w", p.name, err)
+	}
+	return nil
+}
+
+// pluginWrite copies data into memory the plugin allocates and returns its
+// address and length as call arguments
+func pluginWrite(ctx context.Context, mod api.Module, data []byte) (uint64, uint64, error) {
+	if len(data) > math.MaxInt32 {
+		return 0, 0, fmt.Errorf("argument of %d bytes is too large", len(data))
+	}
+	results, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(data)))
+	if err != nil {
+		return 0, 0, fmt.Errorf("alloc failed: %w", err)
+	}
+	ptr := uint32(results[0])
+	if !mod.Memory().Write(ptr, data) {
+		return 0, 0, fmt.Errorf("alloc returned memory out of bounds")
+	}
+	return uint64(ptr), uint64(len(data)), nil
+}
@@ -3,6 +3,7 @@ package processors
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -16,6 +17,34 @@ type ProcessorInterface interface {
 	Extract(ctx context.Context, filePath string) (string, error)
 }
 
+type fileTypeKey struct{}
+
+// WithFileType returns a context telling processors to treat files as the
+// given type (an extension such as ".pdf") rather than by their own
+// extension, for files whose content does not match their name
+func WithFileType(ctx context.Context, fileType string) context.Context {
+	return context.WithValue(ctx, fileTypeKey{}, fileType)
+}
+
+// fileType returns the lower-case extension a file is processed as
+func fileType(ctx context.Context, filePath string) string {
+	if t, ok := ctx.Value(fileTypeKey{}).(string); ok && t != "" {
+		return strings.ToLower(t)
+	}
+	return strings.ToLower(filepath.Ext(filePath))
+}
+
+// textCapabilities are the formats of the text processor
+var textCapabilities = Capabilities{
+	Name:     "text",
+	Category: "text",
+	Formats: []Format{
+		{"txt", "text/plain"}, {"md", "text/markdown"}, {"csv", "text/csv"},
+		{"json", "application/json"}, {"yaml", "application/yaml"}, {"yml", "application/yaml"},
+		{"xml", "application/xml"}, {"toml", "application/toml"},
+	},
+}
+
 // TextProcessor handles plain text files
 type TextProcessor struct {
 	logger *zap.Logger
@@ -28,13 +57,12 @@ func NewTextProcessor(logger *zap.Logger) *TextProcessor {
 
 // CanProcess checks if this processor can handle the file type
 func (p *TextProcessor) CanProcess(fileType string) bool {
-	textTypes := []string{".txt", ".md", ".log", ".csv", ".json", ".yaml", ".yml", ".xml", ".toml"}
-	for _, t := range textTypes {
-		if strings.EqualFold(fileType, t) {
-			return true
-		}
-	}
-	return false
+	return textCapabilities.handles(fileType)
+}
+
+// Describe returns the formats the processor handles
+func (p *TextProcessor) Describe() Capabilities {
+	return textCapabilities
 }
 
 // Extract extracts content from text files
@@ -49,9 +77,36 @@ func (p *TextProcessor) Extract(ctx context.Context, filePath string) (string, e
 	return string(content), nil
 }
 
+// ExtractTo streams text file content into w
+func (p *TextProcessor) ExtractTo(ctx context.Context, filePath string, w io.Writer) error {
+	p.logger.Debug("Streaming text content", zap.String("file", filePath))
+
+	if err := copyFile(ctx, filePath, w); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	return nil
+}
+
+// imageCapabilities are the formats of the image processor
+var imageCapabilities = Capabilities{
+	Name:     "image",
+	Category: "image",
+	Formats: []Format{
+		{"png", "image/png"}, {"jpg", "image/jpeg"}, {"jpeg", "image/jpeg"}, {"gif", "image/gif"},
+		{"bmp", "image/bmp"}, {"svg", "image/svg+xml"}, {"webp", "image/webp"},
+	},
+}
+
+// TextDetector reads the text in an image, with optional BCP 47 language
+// hints
+type TextDetector interface {
+	DetectText(ctx context.Context, imagePath string, languageHints ...string) (string, error)
+}
+
 // ImageProcessor handles image files
 type ImageProcessor struct {
-	logger *zap.Logger
+	logger   *zap.Logger
+	detector TextDetector
 }
 
 // NewImageProcessor creates a new image processor
@@ -59,22 +114,65 @@ func NewImageProcessor(logger *zap.Logger) *ImageProcessor {
 	return &ImageProcessor{logger: logger}
 }
 
+// SetTextDetector lets the processor detect the text in images when the
+// always OCR mode is requested
+func (p *ImageProcessor) SetTextDetector(detector TextDetector) {
+	p.detector = detector
+}
+
 // CanProcess checks if this processor can handle the file type
 func (p *ImageProcessor) CanProcess(fileType string) bool {
-	imageTypes := []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".svg", ".webp"}
-	for _, t := range imageTypes {
-		if strings.EqualFold(fileType, t) {
-			return true
-		}
+	return imageCapabilities.handles(fileType)
+}
+
+// Describe returns the formats the processor handles; it honours the OCR
+// options once it has a text detector
+func (p *ImageProcessor) Describe() Capabilities {
+	c := imageCapabilities
+	if p.detector != nil {
+		c.ExtractOptions = []string{OptionOCRMode, OptionLanguage}
 	}
-	return false
+	return c
 }
 
-// Extract extracts content from image files (returns path for vision API)
+// Extract extracts content from image files (returns path for vision API).
+// With the always OCR mode, the text detected in the image follows.
 func (p *ImageProcessor) Extract(ctx context.Context, filePath string) (string, error) {
 	p.logger.Debug("Image file detected", zap.String("file", filePath))
 	// Return file path - actual analysis will be done by Vision service
-	return fmt.Sprintf("[IMAGE FILE: %s]", filepath.Base(filePath)), nil
+	content := fmt.Sprintf("[IMAGE FILE: %s]", filepath.Base(filePath))
+
+	options := OptionsFrom(ctx)
+	if options.OCRMode != OCRAlways || p.detector == nil {
+		return content, nil
+	}
+	var hints []string
+	if options.Language != "" {
+		hints = append(hints, options.Language)
+	}
+	text, err := p.detector.DetectText(ctx, filePath, hints...)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect text: %w", err)
+	}
+	if text = strings.TrimSpace(text); text != "" {
+		content += "\n\nExtracted Text:\n" + text
+	}
+	return content, nil
+}
+
+// documentCapabilities are the formats of the document processor
+var documentCapabilities = Capabilities{
+	Name:     "document",
+	Category: "document",
+	Formats: []Format{
+		{"pdf", "application/pdf"},
+		{"docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"doc", "application/msword"},
+		{"pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+		{"ppt", "application/vnd.ms-powerpoint"},
+		{"odt", "application/vnd.oasis.opendocument.text"},
+	},
+	ExtractOptions: []string{OptionIncludeTables, OptionMaxPages},
 }
 
 // DocumentProcessor handles PDF and DOCX files
@@ -89,26 +187,25 @@ func NewDocumentProcessor(logger *zap.Logger) *DocumentProcessor {
 
 // CanProcess checks if this processor can handle the file type
 func (p *DocumentProcessor) CanProcess(fileType string) bool {
-	docTypes := []string{".pdf", ".docx", ".doc", ".pptx", ".ppt", ".odt"}
-	for _, t := range docTypes {
-		if strings.EqualFold(fileType, t) {
-			return true
-		}
-	}
-	return false
+	return documentCapabilities.handles(fileType)
+}
+
+// Describe returns the formats the processor handles
+func (p *DocumentProcessor) Describe() Capabilities {
+	return documentCapabilities
 }
 
 // Extract extracts content from documents
 func (p *DocumentProcessor) Extract(ctx context.Context, filePath string) (string, error) {
 	p.logger.Debug("Extracting document content", zap.String("file", filePath))
 
-	ext := strings.ToLower(filepath.Ext(filePath))
+	ext := fileType(ctx, filePath)
 
 	switch ext {
 	case ".pdf":
-		return p.extractPDF(filePath)
+		return p.extractPDF(filePath, OptionsFrom(ctx))
 	case ".docx":
-		return p.extractDOCX(filePath)
+		return p.extractDOCX(filePath, OptionsFrom(ctx))
 	case ".pptx":
 		return p.extractPPTX(filePath)
 	default:
@@ -116,22 +213,71 @@ func (p *DocumentProcessor) Extract(ctx context.Context, filePath string) (strin
 	}
 }
 
-func (p *DocumentProcessor) extractPDF(filePath string) (string, error) {
-	// For now, return placeholder
-	// In production, use a library like github.com/ledongthuc/pdf
-	return fmt.Sprintf("[PDF Document: %s]\n(PDF extraction not yet implemented - placeholder)", filepath.Base(filePath)), nil
+// extractPDF extracts the text of a PDF page by page, up to the pages the
+// options allow, with tables as rows of named cells unless the options
+// leave them out. PDFs without text, such as scans, get a placeholder.
+func (p *DocumentProcessor) extractPDF(filePath string, options ExtractOptions) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF: %w", err)
+	}
+	doc, err := parsePDF(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	var b strings.Builder
+	pages := doc.pages()
+	if options.MaxPages > 0 && len(pages) > options.MaxPages {
+		pages = pages[:options.MaxPages]
+	}
+	tables := 0
+	for i, page := range pages {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		tables += writePage(&b, layoutPage(doc.pageText(page)), options.Tables())
+	}
+	if doc.err != nil {
+		return "", fmt.Errorf("failed to extract PDF: %w", doc.err)
+	}
+	p.logger.Debug("Extracted PDF",
+		zap.String("file", filePath),
+		zap.Int("pages", len(pages)),
+		zap.Int("tables", tables))
+
+	if strings.TrimSpace(b.String()) == "" {
+		return fmt.Sprintf("[PDF Document: %s]\n(no extractable text)", filepath.Base(filePath)), nil
+	}
+	return b.String(), nil
 }
 
-func (p *DocumentProcessor) extractDOCX(filePath string) (string, error) {
-	// For now, return placeholder
-	// In production, use a library like github.com/nguyenthenguyen/docx
-	return fmt.Sprintf("[DOCX Document: %s]\n(DOCX extraction not yet implemented - placeholder)", filepath.Base(filePath)), nil
+// extractDOCX extracts the paragraphs and, unless the options leave them
+// out, the tables of a DOCX document
+func (p *DocumentProcessor) extractDOCX(filePath string, options ExtractOptions) (string, error) {
+	text, tables, err := extractDOCXText(filePath, options.Tables())
+	if err != nil {
+		return "", err
+	}
+	p.logger.Debug("Extracted DOCX", zap.String("file", filePath), zap.Int("tables", tables))
+	return text, nil
 }
 
 func (p *DocumentProcessor) extractPPTX(filePath string) (string, error) {
 	return fmt.Sprintf("[PPTX Presentation: %s]\n(PPTX extraction not yet implemented - placeholder)", filepath.Base(filePath)), nil
 }
 
+// spreadsheetCapabilities are the formats of the spreadsheet processor
+var spreadsheetCapabilities = Capabilities{
+	Name:     "spreadsheet",
+	Category: "spreadsheet",
+	Formats: []Format{
+		{"xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{"xls", "application/vnd.ms-excel"},
+		{"csv", "text/csv"},
+	},
+}
+
 // SpreadsheetProcessor handles XLSX and CSV files
 type SpreadsheetProcessor struct {
 	logger *zap.Logger
@@ -144,20 +290,19 @@ func NewSpreadsheetProcessor(logger *zap.Logger) *SpreadsheetProcessor {
 
 // CanProcess checks if this processor can handle the file type
 func (p *SpreadsheetProcessor) CanProcess(fileType string) bool {
-	spreadsheetTypes := []string{".xlsx", ".xls", ".csv"}
-	for _, t := range spreadsheetTypes {
-		if strings.EqualFold(fileType, t) {
-			return true
-		}
-	}
-	return false
+	return spreadsheetCapabilities.handles(fileType)
+}
+
+// Describe returns the formats the processor handles
+func (p *SpreadsheetProcessor) Describe() Capabilities {
+	return spreadsheetCapabilities
 }
 
 // Extract extracts content from spreadsheets
 func (p *SpreadsheetProcessor) Extract(ctx context.Context, filePath string) (string, error) {
 	p.logger.Debug("Extracting spreadsheet content", zap.String("file", filePath))
 
-	ext := strings.ToLower(filepath.Ext(filePath))
+	ext := fileType(ctx, filePath)
 
 	if ext == ".csv" {
 		return p.extractCSV(filePath)
@@ -175,6 +320,36 @@ func (p *SpreadsheetProcessor) extractCSV(filePath string) (string, error) {
 	return string(content), nil
 }
 
+// ExtractTo streams CSV content into w; other spreadsheets are extracted whole
+func (p *SpreadsheetProcessor) ExtractTo(ctx context.Context, filePath string, w io.Writer) error {
+	if fileType(ctx, filePath) != ".csv" {
+		content, err := p.Extract(ctx, filePath)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, content)
+		return err
+	}
+
+	p.logger.Debug("Streaming spreadsheet content", zap.String("file", filePath))
+	if err := copyFile(ctx, filePath, w); err != nil {
+		return fmt.Errorf("failed to read CSV: %w", err)
+	}
+	return nil
+}
+
+// codeCapabilities are the formats of the code processor
+var codeCapabilities = Capabilities{
+	Name:     "code",
+	Category: "code",
+	Formats: []Format{
+		{"go", "text/x-go"}, {"py", "text/x-python"}, {"js", "text/javascript"},
+		{"ts", "application/typescript"}, {"java", "text/x-java"}, {"c", "text/x-c"},
+		{"cpp", "text/x-c++"}, {"h", "text/x-c"}, {"rs", "text/rust"}, {"rb", "text/x-ruby"},
+		{"php", "application/x-httpd-php"}, {"sql", "application/sql"},
+	},
+}
+
 // CodeProcessor handles source code files
 type CodeProcessor struct {
 	logger *zap.Logger
@@ -187,13 +362,12 @@ func NewCodeProcessor(logger *zap.Logger) *CodeProcessor {
 
 // CanProcess checks if this processor can handle the file type
 func (p *CodeProcessor) CanProcess(fileType string) bool {
-	codeTypes := []string{".go", ".py", ".js", ".ts", ".java", ".c", ".cpp", ".h", ".rs", ".rb", ".php", ".sql"}
-	for _, t := range codeTypes {
-		if strings.EqualFold(fileType, t) {
-			return true
-		}
-	}
-	return false
+	return codeCapabilities.handles(fileType)
+}
+
+// Describe returns the formats the processor handles
+func (p *CodeProcessor) Describe() Capabilities {
+	return codeCapabilities
 }
 
 // Extract extracts content from code files
@@ -207,3 +381,13 @@ func (p *CodeProcessor) Extract(ctx context.Context, filePath string) (string, e
 
 	return string(content), nil
 }
+
+// ExtractTo streams code file content into w
+func (p *CodeProcessor) ExtractTo(ctx context.Context, filePath string, w io.Writer) error {
+	p.logger.Debug("Streaming code content", zap.String("file", filePath))
+
+	if err := copyFile(ctx, filePath, w); err != nil {
+		return fmt.Errorf("failed to read code file: %w", err)
+	}
+	return nil
+}
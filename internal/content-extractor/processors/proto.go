@@ -0,0 +1,544 @@
+package processors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// protoToken is a token of a .proto file with the comments before it
+type protoToken struct {
+	text     string
+	line     int
+	prevLine int // the line of the token before, 0 for the first
+	comments []protoComment
+}
+
+type protoComment struct {
+	text      string
+	line, end int // the lines the comment starts and ends on
+}
+
+// tokenizeProto splits a .proto file into identifiers, numbers, strings
+// and punctuation, keeping comments with the token that follows them
+func tokenizeProto(src string) []protoToken {
+	var tokens []protoToken
+	var comments []protoComment
+	line := 1
+	emit := func(text string) {
+		prev := 0
+		if len(tokens) > 0 {
+			prev = tokens[len(tokens)-1].line
+		}
+		tokens = append(tokens, protoToken{text: text, line: line, prevLine: prev, comments: comments})
+		comments = nil
+	}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			text := strings.TrimSpace(strings.TrimLeft(src[i+2:i+end], "/"))
+			comments = append(comments, protoComment{text: text, line: line, end: line})
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				end = len(src) - i - 2
+			}
+			body := src[i+2 : i+2+end]
+			var lines []string
+			for _, l := range strings.Split(body, "\n") {
+				l = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(l), "*"))
+				if l != "" {
+					lines = append(lines, l)
+				}
+			}
+			start := line
+			line += strings.Count(body, "\n")
+			comments = append(comments, protoComment{text: strings.Join(lines, " "), line: start, end: line})
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c && src[j] != '\n' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j > len(src) {
+				j = len(src)
+			}
+			emit(src[i:min(j+1, len(src))])
+			i = j + 1
+		case isProtoIdent(c):
+			j := i
+			for j < len(src) && isProtoIdent(src[j]) {
+				j++
+			}
+			emit(src[i:j])
+			i = j
+		default:
+			emit(string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+// isProtoIdent reports whether c is part of an identifier, a qualified
+// type name or a number
+func isProtoIdent(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '+' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+type protoFile struct {
+	syntax   string
+	pkg      string
+	services []*protoService
+	messages []*protoMessage
+	enums    []*protoEnum
+}
+
+type protoService struct {
+	name, doc string
+	rpcs      []protoRPC
+}
+
+type protoRPC struct {
+	name, doc                  string
+	request, response          string
+	clientStream, serverStream bool
+}
+
+type protoMessage struct {
+	name, doc string // name is qualified by the enclosing messages
+	fields    []protoField
+}
+
+type protoField struct {
+	label, typ, name, number, oneof, doc string
+}
+
+type protoEnum struct {
+	name, doc string
+	values    []protoField
+}
+
+// protoParser reads the declarations of a .proto file. It is lenient:
+// what it does not understand is skipped to the end of its statement.
+type protoParser struct {
+	tokens []protoToken
+	pos    int
+	file   protoFile
+}
+
+// parseProto reads the package, services, messages and enums of a .proto
+// file, with the comments documenting them
+func parseProto(src string) *protoFile {
+	p := &protoParser{tokens: tokenizeProto(src)}
+	for !p.done() {
+		switch tok := p.next(); tok.text {
+		case "syntax", "edition":
+			p.accept("=")
+			p.file.syntax = strings.Trim(p.next().text, `"'`)
+			p.skipStatement()
+		case "package":
+			p.file.pkg = p.next().text
+			p.skipStatement()
+		case "message":
+			p.message(tok, "")
+		case "enum":
+			p.enum(tok, "")
+		case "service":
+			p.service(tok)
+		case ";":
+		default:
+			p.skipStatement()
+		}
+	}
+	return &p.file
+}
+
+func (p *protoParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *protoParser) peek() protoToken {
+	if p.done() {
+		return protoToken{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *protoParser) next() protoToken {
+	tok := p.peek()
+	if !p.done() {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is text
+func (p *protoParser) accept(text string) bool {
+	if p.peek().text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// skipStatement skips to the end of a statement: past its ';' or its
+// block, whichever comes first
+func (p *protoParser) skipStatement() {
+	depth := 0
+	for !p.done() {
+		switch p.next().text {
+		case "{", "[", "(", "<":
+			depth++
+		case "}", "]", ")", ">":
+			depth--
+			if depth <= 0 && p.tokens[p.pos-1].text == "}" {
+				return
+			}
+		case ";":
+			if depth <= 0 {
+				return
+			}
+		}
+		if depth < 0 {
+			return
+		}
+	}
+}
+
+// skipOptions skips the [...] options of a field
+func (p *protoParser) skipOptions() {
+	if !p.accept("[") {
+		return
+	}
+	for depth := 1; depth > 0 && !p.done(); {
+		switch p.next().text {
+		case "[":
+			depth++
+		case "]":
+			depth--
+		}
+	}
+}
+
+// doc returns the leading comments of a declaration: those on the lines
+// right above it. Comments separated from it by a blank line, such as a
+// license header, and comments trailing the statement before are left out.
+func (p *protoParser) doc(tok protoToken) string {
+	var lines []string
+	next := tok.line
+	for i := len(tok.comments) - 1; i >= 0; i-- {
+		c := tok.comments[i]
+		if c.line == tok.prevLine || next-c.end > 1 {
+			break
+		}
+		lines = append([]string{c.text}, lines...)
+		next = c.line
+	}
+	return strings.Join(lines, " ")
+}
+
+// trailingDoc returns the comment on the same line after the statement
+// just read
+func (p *protoParser) trailingDoc() string {
+	if p.pos == 0 || p.done() {
+		return ""
+	}
+	end := p.tokens[p.pos-1].line
+	for _, c := range p.peek().comments {
+		if c.line == end {
+			return c.text
+		}
+	}
+	return ""
+}
+
+// withTrailing adds the trailing comment of a declaration to its doc
+func withTrailing(doc, trailing string) string {
+	if doc == "" {
+		return trailing
+	}
+	if trailing == "" {
+		return doc
+	}
+	return doc + " " + trailing
+}
+
+func (p *protoParser) message(tok protoToken, outer string) {
+	msg := &protoMessage{name: qualify(outer, p.next().text), doc: p.doc(tok)}
+	p.file.messages = append(p.file.messages, msg)
+	if !p.accept("{") {
+		return
+	}
+	p.messageBody(msg, "")
+}
+
+// messageBody reads the fields and nested declarations of a message, or
+// of one of its oneofs, up to the closing brace
+func (p *protoParser) messageBody(msg *protoMessage, oneof string) {
+	for !p.done() {
+		tok := p.next()
+		switch tok.text {
+		case "}":
+			return
+		case ";":
+		case "message":
+			p.message(tok, msg.name)
+		case "enum":
+			p.enum(tok, msg.name)
+		case "oneof":
+			name := p.next().text
+			if p.accept("{") {
+				p.messageBody(msg, name)
+			}
+		case "option", "reserved", "extensions", "extend", "group":
+			p.skipStatement()
+		default:
+			field := protoField{oneof: oneof, doc: p.doc(tok)}
+			switch tok.text {
+			case "optional", "repeated", "required":
+				field.label = tok.text
+				tok = p.next()
+			}
+			field.typ = tok.text
+			if tok.text == "map" && p.accept("<") {
+				key := p.next().text
+				p.accept(",")
+				value := p.next().text
+				p.accept(">")
+				field.typ = "map<" + key + ", " + value + ">"
+			}
+			field.name = p.next().text
+			if p.accept("=") {
+				field.number = p.next().text
+			}
+			p.skipOptions()
+			if !p.accept(";") {
+				p.skipStatement()
+			}
+			field.doc = withTrailing(field.doc, p.trailingDoc())
+			msg.fields = append(msg.fields, field)
+		}
+	}
+}
+
+func (p *protoParser) enum(tok protoToken, outer string) {
+	enum := &protoEnum{name: qualify(outer, p.next().text), doc: p.doc(tok)}
+	p.file.enums = append(p.file.enums, enum)
+	if !p.accept("{") {
+		return
+	}
+	for !p.done() {
+		tok := p.next()
+		switch tok.text {
+		case "}":
+			return
+		case ";":
+		case "option", "reserved":
+			p.skipStatement()
+		default:
+			value := protoField{name: tok.text, doc: p.doc(tok)}
+			if p.accept("=") {
+				value.number = p.next().text
+			}
+			p.skipOptions()
+			if !p.accept(";") {
+				p.skipStatement()
+			}
+			value.doc = withTrailing(value.doc, p.trailingDoc())
+			enum.values = append(enum.values, value)
+		}
+	}
+}
+
+func (p *protoParser) service(tok protoToken) {
+	svc := &protoService{name: p.next().text, doc: p.doc(tok)}
+	p.file.services = append(p.file.services, svc)
+	if !p.accept("{") {
+		return
+	}
+	for !p.done() {
+		tok := p.next()
+		switch tok.text {
+		case "}":
+			return
+		case ";":
+		case "rpc":
+			rpc := protoRPC{name: p.next().text, doc: p.doc(tok)}
+			rpc.request, rpc.clientStream = p.rpcType()
+			p.accept("returns")
+			rpc.response, rpc.serverStream = p.rpcType()
+			p.skipStatement() // the ';' or the block of options
+			rpc.doc = withTrailing(rpc.doc, p.trailingDoc())
+			svc.rpcs = append(svc.rpcs, rpc)
+		default:
+			p.skipStatement()
+		}
+	}
+}
+
+// rpcType reads the parenthesized request or response type of an RPC
+func (p *protoParser) rpcType() (string, bool) {
+	if !p.accept("(") {
+		return "", false
+	}
+	stream := p.accept("stream")
+	typ := p.next().text
+	p.accept(")")
+	return typ, stream
+}
+
+func qualify(outer, name string) string {
+	if outer == "" {
+		return name
+	}
+	return outer + "." + name
+}
+
+// writeProto returns the text of a .proto file with a section for the
+// file, each service, RPC, message and enum, separated by SectionBreak
+func writeProto(f *protoFile) string {
+	var sections []string
+	full := func(name string) string {
+		return qualify(f.pkg, name)
+	}
+
+	var b strings.Builder
+	b.WriteString("Protocol Buffers definitions\n")
+	writeField(&b, "Package", f.pkg)
+	writeField(&b, "Syntax", f.syntax)
+	var names []string
+	for _, svc := range f.services {
+		names = append(names, svc.name)
+	}
+	writeField(&b, "Services", strings.Join(names, ", "))
+	names = nil
+	for _, msg := range f.messages {
+		names = append(names, msg.name)
+	}
+	writeField(&b, "Messages", strings.Join(names, ", "))
+	names = nil
+	for _, enum := range f.enums {
+		names = append(names, enum.name)
+	}
+	writeField(&b, "Enums", strings.Join(names, ", "))
+	sections = append(sections, b.String())
+
+	returnedBy := make(map[string][]string)
+	acceptedBy := make(map[string][]string)
+	for _, svc := range f.services {
+		b.Reset()
+		fmt.Fprintf(&b, "Service: %s\n", full(svc.name))
+		writeField(&b, "Description", svc.doc)
+		var rpcs []string
+		for _, rpc := range svc.rpcs {
+			line := rpc.name
+			if rpc.doc != "" {
+				line += ": " + rpc.doc
+			}
+			rpcs = append(rpcs, line)
+		}
+		writeList(&b, "RPCs", rpcs)
+		sections = append(sections, b.String())
+
+		for _, rpc := range svc.rpcs {
+			name := svc.name + "." + rpc.name
+			b.Reset()
+			fmt.Fprintf(&b, "RPC: %s\n", full(name))
+			writeField(&b, "Description", rpc.doc)
+			writeField(&b, "Request", streamType(rpc.request, rpc.clientStream))
+			writeField(&b, "Response", streamType(rpc.response, rpc.serverStream))
+			sections = append(sections, b.String())
+
+			if msg := f.lookup(rpc.request); msg != "" {
+				acceptedBy[msg] = appendOnce(acceptedBy[msg], name)
+			}
+			if msg := f.lookup(rpc.response); msg != "" {
+				returnedBy[msg] = appendOnce(returnedBy[msg], name)
+			}
+		}
+	}
+
+	for _, msg := range f.messages {
+		b.Reset()
+		fmt.Fprintf(&b, "Message: %s\n", full(msg.name))
+		writeField(&b, "Description", msg.doc)
+		var fields []string
+		for _, field := range msg.fields {
+			line := field.typ + " " + field.name
+			if field.label != "" {
+				line = field.label + " " + line
+			}
+			if field.number != "" {
+				line += " = " + field.number
+			}
+			if field.oneof != "" {
+				line += " (oneof " + field.oneof + ")"
+			}
+			if field.doc != "" {
+				line += ": " + field.doc
+			}
+			fields = append(fields, line)
+		}
+		writeList(&b, "Fields", fields)
+		writeField(&b, "Returned by", strings.Join(returnedBy[msg.name], ", "))
+		writeField(&b, "Accepted by", strings.Join(acceptedBy[msg.name], ", "))
+		sections = append(sections, b.String())
+	}
+
+	for _, enum := range f.enums {
+		b.Reset()
+		fmt.Fprintf(&b, "Enum: %s\n", full(enum.name))
+		writeField(&b, "Description", enum.doc)
+		var values []string
+		for _, value := range enum.values {
+			line := value.name
+			if value.number != "" {
+				line += " = " + value.number
+			}
+			if value.doc != "" {
+				line += ": " + value.doc
+			}
+			values = append(values, line)
+		}
+		writeList(&b, "Values", values)
+		sections = append(sections, b.String())
+	}
+	return strings.Join(sections, "\n"+string(SectionBreak)+"\n")
+}
+
+// lookup returns the name of the message of the file a type refers to, or
+// an empty string for types defined elsewhere
+func (f *protoFile) lookup(typ string) string {
+	typ = strings.TrimPrefix(typ, ".")
+	if f.pkg != "" {
+		typ = strings.TrimPrefix(typ, f.pkg+".")
+	}
+	for _, msg := range f.messages {
+		if msg.name == typ {
+			return msg.name
+		}
+	}
+	return ""
+}
+
+func streamType(typ string, stream bool) string {
+	if stream && typ != "" {
+		return "stream " + typ
+	}
+	return typ
+}
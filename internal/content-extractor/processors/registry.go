@@ -0,0 +1,220 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"slices"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// Capabilities describes the files a processor handles and the settings
+// that change how it handles them
+type Capabilities struct {
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	Formats  []Format `json:"formats"`
+	Options  []Option `json:"options,omitempty"`
+	// ExtractOptions are the extraction options the processor honours
+	ExtractOptions []string `json:"extract_options,omitempty"`
+	// Dynamic is set for processors that decide which files they handle
+	// when asked, such as plugins; Formats lists those known so far
+	Dynamic bool `json:"dynamic,omitempty"`
+}
+
+// Format is a file type a processor handles
+type Format struct {
+	Extension string `json:"extension"`           // without the dot
+	MimeType  string `json:"mime_type,omitempty"` // files detected as this type are routed to the processor
+}
+
+// Option is a setting of a processor, named by its environment variable or
+// configuration key, with its current value
+type Option struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Value       interface{} `json:"value"`
+}
+
+// Describer is implemented by processors that describe their capabilities
+type Describer interface {
+	Describe() Capabilities
+}
+
+// handles reports whether a file type, an extension with the dot, is one
+// of the formats
+func (c Capabilities) handles(fileType string) bool {
+	ext := strings.TrimPrefix(fileType, ".")
+	for _, f := range c.Formats {
+		if strings.EqualFold(f.Extension, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// extensionFormats returns formats for extensions without the dot, with the
+// MIME types the system knows them by
+func extensionFormats(extensions []string) []Format {
+	formats := make([]Format, 0, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+		mimeType, _, _ := mime.ParseMediaType(mime.TypeByExtension("." + ext)) //nolint:errcheck // empty when unknown
+		formats = append(formats, Format{Extension: ext, MimeType: mimeType})
+	}
+	return formats
+}
+
+// Registry holds the content processors in the order they are consulted,
+// and routes files to them
+type Registry struct {
+	processors []ProcessorInterface
+	plugins    *PluginHost
+}
+
+// NewRegistry creates a registry of the given processors, consulted in order
+func NewRegistry(processors ...ProcessorInterface) *Registry {
+	return &Registry{processors: processors}
+}
+
+// NewDefaultRegistry creates the registry of the configured processors:
+// plugins first, so they can take over types the built-in processors
+// handle, then the built-in processors, those configured to run in a
+// sandbox wrapped in one, then the external extractors, which only get
+// files no other processor handles
+func NewDefaultRegistry(ctx context.Context, logger *zap.Logger, cfg *config.Config) (*Registry, error) {
+	plugins, err := LoadPlugins(ctx, logger, cfg.Plugins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load processor plugins: %w", err)
+	}
+	processors := plugins.Processors()
+	for _, p := range builtinProcessors(logger, cfg) {
+		if slices.Contains(cfg.Extraction.Sandbox, p.(Describer).Describe().Name) {
+			if p, err = NewSandboxProcessor(logger, p, cfg.Extraction.SandboxMaxMemory); err != nil {
+				plugins.Close(ctx) //nolint:errcheck
+				return nil, err
+			}
+		}
+		processors = append(processors, p)
+	}
+	r := NewRegistry(append(processors,
+		NewExternalProcessor(logger, cfg.Extractors, cfg.Extraction.ExternalTimeout),
+	)...)
+	r.plugins = plugins
+	return r, nil
+}
+
+// builtinProcessors creates the built-in processors, in the order they are
+// consulted
+func builtinProcessors(logger *zap.Logger, cfg *config.Config) []ProcessorInterface {
+	return []ProcessorInterface{
+		NewSpecProcessor(logger),
+		NewLogProcessor(logger, cfg.LogFiles),
+		NewDataProcessor(logger, cfg.DataFiles),
+		NewConvertProcessor(logger, cfg.Conversion, cfg.Extraction.TempDir),
+		NewTextProcessor(logger),
+		NewImageProcessor(logger),
+		NewDocumentProcessor(logger),
+		NewSpreadsheetProcessor(logger),
+		NewCodeProcessor(logger),
+	}
+}
+
+// Processors returns the processors in the order they are consulted
+func (r *Registry) Processors() []ProcessorInterface {
+	return r.processors
+}
+
+// SetTextDetector lets the image processors detect text, so they honour
+// the OCR options
+func (r *Registry) SetTextDetector(detector TextDetector) {
+	for _, p := range r.processors {
+		if image, ok := p.(*ImageProcessor); ok {
+			image.SetTextDetector(detector)
+		}
+	}
+}
+
+// Find returns the processor of a file and the type it is processed as.
+// The first processor handling the file type gets it; a file no processor
+// handles by type goes to the first processor listing its MIME type, as
+// that format's extension.
+func (r *Registry) Find(fileType, mimeType string) (ProcessorInterface, string, bool) {
+	for _, p := range r.processors {
+		if p.CanProcess(fileType) {
+			return p, fileType, true
+		}
+	}
+
+	mimeType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil || mimeType == "application/octet-stream" {
+		return nil, "", false
+	}
+	for _, p := range r.processors {
+		d, ok := p.(Describer)
+		if !ok {
+			continue
+		}
+		for _, f := range d.Describe().Formats {
+			if f.MimeType == mimeType && p.CanProcess("."+f.Extension) {
+				return p, "." + f.Extension, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// Capabilities describes every processor, in the order they are consulted.
+// Processors that do not describe themselves are left out.
+func (r *Registry) Capabilities() []Capabilities {
+	capabilities := make([]Capabilities, 0, len(r.processors))
+	for _, p := range r.processors {
+		if d, ok := p.(Describer); ok {
+			capabilities = append(capabilities, d.Describe())
+		}
+	}
+	return capabilities
+}
+
+// Categories lists the extensions of each category, in the order the
+// categories first appear. An extension is listed under the category of
+// the processor that gets it, so a type several processors handle is
+// listed once.
+func (r *Registry) Categories() []CategoryFormats {
+	var categories []CategoryFormats
+	index := make(map[string]int)
+	for _, p := range r.processors {
+		d, ok := p.(Describer)
+		if !ok {
+			continue
+		}
+		c := d.Describe()
+		for _, f := range c.Formats {
+			if found, _, _ := r.Find("."+f.Extension, ""); found != p {
+				continue
+			}
+			i, ok := index[c.Category]
+			if !ok {
+				i = len(categories)
+				index[c.Category] = i
+				categories = append(categories, CategoryFormats{Category: c.Category, Extensions: []string{}})
+			}
+			categories[i].Extensions = append(categories[i].Extensions, f.Extension)
+		}
+	}
+	return categories
+}
+
+// CategoryFormats lists the extensions of a category of files
+type CategoryFormats struct {
+	Category   string   `json:"category"`
+	Extensions []string `json:"extensions"`
+}
+
+// Close releases the plugins
+func (r *Registry) Close(ctx context.Context) error {
+	return r.plugins.Close(ctx)
+}
@@ -0,0 +1,208 @@
+package processors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/safeparse"
+	"go.uber.org/zap"
+)
+
+// sandboxEnv is set in the environment of a process started to run one
+// extraction in a sandbox
+const sandboxEnv = "REPOGRAPH_EXTRACTION_SANDBOX"
+
+// sandboxLimitExit is the exit code of a sandbox process whose file
+// exceeded a parsing limit
+const sandboxLimitExit = 2
+
+// sandboxWaitDelay is how long a sandbox process that has been killed may
+// keep its output open before it is abandoned
+const sandboxWaitDelay = 5 * time.Second
+
+// sandboxRequest is the extraction a sandbox process runs, given as JSON
+// on its stdin
+type sandboxRequest struct {
+	Processor string         `json:"processor"`
+	FilePath  string         `json:"file_path"`
+	FileType  string         `json:"file_type"`
+	Options   ExtractOptions `json:"options"`
+	MaxMemory int64          `json:"max_memory"`
+}
+
+// SandboxProcessor runs the extractions of a built-in processor in a
+// subprocess: the service's own executable, started again to run a single
+// extraction. The subprocess may use at most maxMemory bytes and is killed
+// with every process it started when the extraction is cancelled or times
+// out, so a file that hangs, crashes or exhausts its parser only fails
+// its own extraction.
+type SandboxProcessor struct {
+	logger     *zap.Logger
+	processor  ProcessorInterface
+	name       string
+	maxMemory  int64
+	executable string
+}
+
+// NewSandboxProcessor wraps a built-in processor to run its extractions in
+// a sandbox
+func NewSandboxProcessor(logger *zap.Logger, processor ProcessorInterface, maxMemory int64) (*SandboxProcessor, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the executable to run sandboxed extractions with: %w", err)
+	}
+	return &SandboxProcessor{
+		logger:     logger,
+		processor:  processor,
+		name:       processor.(Describer).Describe().Name,
+		maxMemory:  maxMemory,
+		executable: executable,
+	}, nil
+}
+
+// CanProcess checks if the sandboxed processor can handle the file type
+func (p *SandboxProcessor) CanProcess(fileType string) bool {
+	return p.processor.CanProcess(fileType)
+}
+
+// Describe returns the capabilities of the sandboxed processor and the
+// sandbox's memory limit
+func (p *SandboxProcessor) Describe() Capabilities {
+	c := p.processor.(Describer).Describe()
+	c.Options = append(slices.Clip(c.Options),
+		Option{"EXTRACTION_SANDBOX_MAX_MEMORY", "Most memory the sandbox process of an extraction may use, in bytes", p.maxMemory})
+	return c
+}
+
+// Extract returns the text the sandboxed processor extracts from a file
+func (p *SandboxProcessor) Extract(ctx context.Context, filePath string) (string, error) {
+	var text strings.Builder
+	if err := p.ExtractTo(ctx, filePath, &text); err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
+
+// ExtractTo runs the extraction in a sandbox process and streams the text
+// it extracts into w
+func (p *SandboxProcessor) ExtractTo(ctx context.Context, filePath string, w io.Writer) error {
+	p.logger.Debug("Extracting in sandbox",
+		zap.String("file", filePath),
+		zap.String("processor", p.name))
+
+	// A missing file fails here rather than with a message from the sandbox
+	if _, err := os.Stat(filePath); err != nil {
+		return err
+	}
+	request, err := json.Marshal(sandboxRequest{
+		Processor: p.name,
+		FilePath:  filePath,
+		FileType:  fileType(ctx, filePath),
+		Options:   OptionsFrom(ctx),
+		MaxMemory: p.maxMemory,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal sandbox request: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.executable)
+	cmd.Env = append(os.Environ(), sandboxEnv+"=1")
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = w
+	cmd.Stderr = &limitedWriter{w: &stderr, n: 4096}
+	ownProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = sandboxWaitDelay
+
+	err = cmd.Run()
+	if err == nil {
+		return nil
+	}
+	message := strings.TrimSpace(stderr.String())
+	var exit *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.As(err, &exit) && exit.ExitCode() == sandboxLimitExit:
+		return &LimitExceededError{Path: filePath, Err: errors.New(message)}
+	case strings.Contains(message, "out of memory") || strings.Contains(message, "cannot allocate memory"):
+		return &LimitExceededError{Path: filePath, Memory: p.maxMemory}
+	case message != "":
+		return errors.New(message)
+	}
+	return fmt.Errorf("sandboxed %s processor failed: %w", p.name, err)
+}
+
+// SandboxRequested reports whether the process was started to run an
+// extraction in a sandbox; services check it first thing and hand over
+// to RunSandbox
+func SandboxRequested() bool {
+	return os.Getenv(sandboxEnv) != ""
+}
+
+// RunSandbox runs the extraction a sandbox process was started for: it
+// reads the request from stdin, caps its own memory, extracts the file
+// with the named built-in processor and writes the text to stdout. Errors
+// are written to stderr. It returns the process exit code.
+func RunSandbox() int {
+	err := runSandbox(os.Stdin, os.Stdout)
+	if err == nil {
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, err)
+	if errors.Is(err, safeparse.ErrLimit) {
+		return sandboxLimitExit
+	}
+	return 1
+}
+
+func runSandbox(in io.Reader, out io.Writer) error {
+	var req sandboxRequest
+	if err := json.NewDecoder(in).Decode(&req); err != nil {
+		return fmt.Errorf("invalid sandbox request: %w", err)
+	}
+	if err := limitMemory(req.MaxMemory); err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	var processor ProcessorInterface
+	for _, p := range builtinProcessors(zap.NewNop(), cfg) {
+		if p.(Describer).Describe().Name == req.Processor {
+			processor = p
+		}
+	}
+	if processor == nil {
+		return fmt.Errorf("unknown processor %q", req.Processor)
+	}
+
+	ctx := WithOptions(WithFileType(context.Background(), req.FileType), req.Options)
+	buffered := bufio.NewWriter(out)
+	if sp, ok := processor.(StreamProcessor); ok {
+		err = sp.ExtractTo(ctx, req.FilePath, buffered)
+	} else {
+		var text string
+		if text, err = processor.Extract(ctx, req.FilePath); err == nil {
+			_, err = io.WriteString(buffered, text)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
@@ -0,0 +1,18 @@
+package processors
+
+import (
+	"fmt"
+	"runtime/debug"
+	"syscall"
+)
+
+// limitMemory caps the memory of the process: the Go runtime collects
+// garbage harder as it nears the limit, and the kernel refuses allocations
+// beyond it
+func limitMemory(limit int64) error {
+	debug.SetMemoryLimit(limit)
+	if err := syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: uint64(limit), Max: uint64(limit)}); err != nil {
+		return fmt.Errorf("failed to limit memory: %w", err)
+	}
+	return nil
+}
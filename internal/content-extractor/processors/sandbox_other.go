@@ -0,0 +1,13 @@
+//go:build !linux
+
+package processors
+
+import "runtime/debug"
+
+// limitMemory caps the memory of the process as far as the Go runtime
+// can: it collects garbage harder as it nears the limit, but the limit is
+// only enforced by the kernel on Linux
+func limitMemory(limit int64) error {
+	debug.SetMemoryLimit(limit)
+	return nil
+}
@@ -0,0 +1,189 @@
+package processors
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// specSniffBytes is how much of a JSON or YAML file is searched for the
+	// keys that mark an API specification or a Kubernetes manifest
+	specSniffBytes = 4096
+	// maxSpecBytes bounds the size of a specification parsed as a whole;
+	// larger files are indexed as plain text
+	maxSpecBytes = 32 << 20
+)
+
+// specCapabilities are the formats of the specification processor
+var specCapabilities = Capabilities{
+	Name:     "specification",
+	Category: "specification",
+	Formats: []Format{
+		{"proto", "text/x-protobuf"}, {"tf", "text/x-terraform"}, {"tfvars", "text/x-terraform"},
+		{"json", "application/json"}, {"yaml", "application/yaml"}, {"yml", "application/yaml"},
+	},
+}
+
+// SpecProcessor handles API, schema and infrastructure specifications:
+// OpenAPI and Swagger documents and Kubernetes manifests in JSON or YAML,
+// Protocol Buffers .proto files and Terraform configurations. Each
+// endpoint, RPC, schema, message, enum, Kubernetes object and Terraform
+// block becomes its own section, so it is chunked on its own with its
+// description. Other JSON and YAML files are passed through as plain text.
+type SpecProcessor struct {
+	logger *zap.Logger
+}
+
+// NewSpecProcessor creates a new specification processor
+func NewSpecProcessor(logger *zap.Logger) *SpecProcessor {
+	return &SpecProcessor{logger: logger}
+}
+
+// CanProcess checks if this processor can handle the file type
+func (p *SpecProcessor) CanProcess(fileType string) bool {
+	return specCapabilities.handles(fileType)
+}
+
+// Describe returns the formats the processor handles
+func (p *SpecProcessor) Describe() Capabilities {
+	return specCapabilities
+}
+
+// Extract extracts the content of a specification
+func (p *SpecProcessor) Extract(ctx context.Context, filePath string) (string, error) {
+	var b strings.Builder
+	if err := p.ExtractTo(ctx, filePath, &b); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// ExtractTo writes the sections of a specification into w
+func (p *SpecProcessor) ExtractTo(ctx context.Context, filePath string, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	switch fileType(ctx, filePath) {
+	case ".proto":
+		return p.extractProto(filePath, w)
+	case ".tf", ".tfvars":
+		return p.extractTerraform(filePath, w)
+	}
+	return p.extractStructured(filePath, w)
+}
+
+// extractProto writes the services, RPCs, messages and enums of a .proto
+// file, or its text when it declares none
+func (p *SpecProcessor) extractProto(filePath string, w io.Writer) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read proto file: %w", err)
+	}
+	file := parseProto(string(data))
+	if len(file.services) == 0 && len(file.messages) == 0 && len(file.enums) == 0 {
+		p.logger.Debug("No protobuf declarations found, extracting as text", zap.String("file", filePath))
+		_, err = w.Write(data)
+		return err
+	}
+
+	p.logger.Debug("Extracted protobuf definitions",
+		zap.String("file", filePath),
+		zap.Int("services", len(file.services)),
+		zap.Int("messages", len(file.messages)),
+		zap.Int("enums", len(file.enums)))
+	_, err = io.WriteString(w, writeProto(file))
+	return err
+}
+
+// extractTerraform writes the blocks of a Terraform configuration, or its
+// text when it has none
+func (p *SpecProcessor) extractTerraform(filePath string, w io.Writer) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read Terraform file: %w", err)
+	}
+	root := parseHCL(string(data))
+	if len(root.blocks) == 0 && len(root.attrs) == 0 {
+		p.logger.Debug("No Terraform blocks found, extracting as text", zap.String("file", filePath))
+		_, err = w.Write(data)
+		return err
+	}
+
+	text, blocks := writeTerraform(root)
+	p.logger.Debug("Extracted Terraform configuration",
+		zap.String("file", filePath),
+		zap.Int("blocks", blocks))
+	_, err = io.WriteString(w, text)
+	return err
+}
+
+// extractStructured writes the operations and schemas of an OpenAPI or
+// Swagger document, or the objects of Kubernetes manifests. Other JSON and
+// YAML files are streamed as they are.
+func (p *SpecProcessor) extractStructured(filePath string, w io.Writer) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, specSniffBytes)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	head = head[:n]
+	rest := io.MultiReader(bytes.NewReader(head), file)
+	api := bytes.Contains(head, []byte("openapi")) || bytes.Contains(head, []byte("swagger"))
+	manifest := bytes.Contains(head, []byte("apiVersion")) && bytes.Contains(head, []byte("kind"))
+	if !api && !manifest {
+		_, err = io.Copy(w, rest)
+		return err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(rest, maxSpecBytes+1))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) > maxSpecBytes {
+		p.logger.Warn("Specification too large to parse, extracting as text", zap.String("file", filePath))
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		_, err = io.Copy(w, file)
+		return err
+	}
+
+	if api {
+		if root := parseOpenAPI(data); root != nil {
+			text, operations, schemas := writeOpenAPI(root)
+			p.logger.Debug("Extracted API specification",
+				zap.String("file", filePath),
+				zap.Int("operations", operations),
+				zap.Int("schemas", schemas))
+			_, err = io.WriteString(w, text)
+			return err
+		}
+	}
+	if manifest {
+		if objects := parseManifests(data); len(objects) > 0 {
+			p.logger.Debug("Extracted Kubernetes manifests",
+				zap.String("file", filePath),
+				zap.Int("objects", len(objects)))
+			_, err = io.WriteString(w, writeManifests(objects))
+			return err
+		}
+	}
+
+	// Templated manifests, such as Helm charts, and files that only
+	// mention the keys are indexed as they are
+	p.logger.Debug("No specification found, extracting as text", zap.String("file", filePath))
+	_, err = w.Write(data)
+	return err
+}
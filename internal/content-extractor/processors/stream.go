@@ -0,0 +1,212 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/safeparse"
+)
+
+// StreamProcessor is implemented by processors that can write extracted
+// content incrementally instead of returning it as a single string
+type StreamProcessor interface {
+	ExtractTo(ctx context.Context, filePath string, w io.Writer) error
+}
+
+// Limits bounds the memory, file sizes and time used for extraction
+type Limits struct {
+	MaxFileSize int64 // 0 disables the file size limit
+	MaxInMemory int64
+	TempDir     string
+	Normalize   bool                     // clean extracted text with a Normalizer
+	Timeout     time.Duration            // longest an extraction may take, 0 for no limit
+	Timeouts    map[string]time.Duration // timeouts of processor categories, overriding Timeout
+}
+
+// LimitsFromConfig returns the extraction limits from the configuration
+func LimitsFromConfig(cfg config.ExtractionConfig) Limits {
+	return Limits{
+		MaxFileSize: cfg.MaxFileSize,
+		MaxInMemory: cfg.MaxInMemory,
+		TempDir:     cfg.TempDir,
+		Normalize:   cfg.Normalize,
+		Timeout:     cfg.Timeout,
+		Timeouts:    cfg.Timeouts,
+	}
+}
+
+// timeout returns the longest the processor may take to extract a file,
+// the timeout of its category when one is set; 0 is no limit
+func (l Limits) timeout(p ProcessorInterface) time.Duration {
+	if d, ok := p.(Describer); ok {
+		if timeout, ok := l.Timeouts[d.Describe().Category]; ok {
+			return timeout
+		}
+	}
+	return l.Timeout
+}
+
+// FileTooLargeError is returned for files above the configured size limit
+type FileTooLargeError struct {
+	Path  string
+	Size  int64
+	Limit int64
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("file %s is %d bytes, above the %d byte limit", e.Path, e.Size, e.Limit)
+}
+
+// LimitExceededError is returned when the extraction of a file is stopped
+// for taking longer than its timeout, for running out of the memory of its
+// sandbox or for exceeding a parsing limit, as XML and zip bombs do
+type LimitExceededError struct {
+	Path    string
+	Timeout time.Duration // set when the extraction timed out
+	Memory  int64         // set when the sandbox ran out of memory
+	Err     error         // set when the file exceeded a parsing limit
+}
+
+func (e *LimitExceededError) Error() string {
+	switch {
+	case e.Timeout > 0:
+		return fmt.Sprintf("extraction of %s timed out after %s", e.Path, e.Timeout)
+	case e.Err != nil:
+		return fmt.Sprintf("extraction of %s stopped: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("extraction of %s ran out of its %d bytes of memory", e.Path, e.Memory)
+}
+
+func (e *LimitExceededError) Unwrap() error {
+	return e.Err
+}
+
+// CheckSize returns a FileTooLargeError when a file exceeds the size limit
+func (l Limits) CheckSize(filePath string) error {
+	if l.MaxFileSize <= 0 {
+		return nil
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.Size() > l.MaxFileSize {
+		return &FileTooLargeError{Path: filePath, Size: info.Size(), Limit: l.MaxFileSize}
+	}
+	return nil
+}
+
+// ExtractFile extracts a file within the limits. Processors that support
+// streaming write straight into the returned content, which spills to a
+// temporary file once it outgrows the in-memory threshold. With
+// normalization enabled the text is cleaned on the way in. The caller must
+// close the content.
+//
+// An extraction taking longer than the timeout of the processor's category
+// fails with a LimitExceededError. Its context is cancelled, but a
+// processor that does not stop keeps running in the background until it
+// finishes, so only a sandboxed processor is sure to be stopped.
+func ExtractFile(ctx context.Context, p ProcessorInterface, filePath string, limits Limits) (*Content, error) {
+	if err := limits.CheckSize(filePath); err != nil {
+		return nil, err
+	}
+	timeout := limits.timeout(p)
+	if timeout <= 0 {
+		return extractFile(ctx, p, filePath, limits)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	type result struct {
+		content *Content
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		content, err := extractFile(ctx, p, filePath, limits)
+		done <- result{content, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, &LimitExceededError{Path: filePath, Timeout: timeout}
+		}
+		return r.content, r.err
+	case <-ctx.Done():
+		// The content of an extraction finishing late is discarded
+		go func() {
+			if r := <-done; r.content != nil {
+				r.content.Close()
+			}
+		}()
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ctx.Err()
+		}
+		return nil, &LimitExceededError{Path: filePath, Timeout: timeout}
+	}
+}
+
+// extractFile extracts a file into new content
+func extractFile(ctx context.Context, p ProcessorInterface, filePath string, limits Limits) (*Content, error) {
+	content := NewContent(limits.MaxInMemory, limits.TempDir)
+	var w io.Writer = content
+	var normalizer *Normalizer
+	if limits.Normalize {
+		normalizer = NewNormalizer(content)
+		w = normalizer
+	}
+
+	var err error
+	if sp, ok := p.(StreamProcessor); ok {
+		err = sp.ExtractTo(ctx, filePath, w)
+	} else {
+		var text string
+		if text, err = p.Extract(ctx, filePath); err == nil {
+			_, err = io.WriteString(w, text)
+		}
+	}
+	if err == nil && normalizer != nil {
+		err = normalizer.Close()
+		content.encoding = normalizer.Encoding()
+	}
+	if err != nil {
+		content.Close()
+		if errors.Is(err, safeparse.ErrLimit) {
+			err = &LimitExceededError{Path: filePath, Err: err}
+		}
+		return nil, err
+	}
+	return content, nil
+}
+
+// ReadContent loads text extracted earlier, decoded from the given source
+// encoding, within the memory limit. The caller must close the content.
+func ReadContent(r io.Reader, encoding string, limits Limits) (*Content, error) {
+	content := NewContent(limits.MaxInMemory, limits.TempDir)
+	if _, err := io.Copy(content, r); err != nil {
+		content.Close()
+		return nil, err
+	}
+	content.encoding = encoding
+	return content, nil
+}
+
+// copyFile streams a file into w
+func copyFile(ctx context.Context, filePath string, w io.Writer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return err
+}
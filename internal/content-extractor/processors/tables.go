@@ -0,0 +1,84 @@
+package processors
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// writeTable writes a table as one line per row, each cell named by its
+// column ("Region: EMEA; Q1: 120"), so a chunk holding any row of the table
+// still says what its figures are. The first row is the header; columns
+// without a usable header are named "Column N".
+func writeTable(b *strings.Builder, rows [][]string) {
+	rows = trimTable(rows)
+	if len(rows) == 0 {
+		return
+	}
+	if len(rows) == 1 {
+		b.WriteString(strings.Join(rows[0], " | ") + "\n")
+		return
+	}
+
+	header := make([]string, len(rows[0]))
+	for i, name := range rows[0] {
+		if name == "" || isNumber(name) {
+			name = "Column " + strconv.Itoa(i+1)
+		}
+		header[i] = name
+	}
+	fmt.Fprintf(b, "Table: %s\n", strings.Join(header, " | "))
+	for _, row := range rows[1:] {
+		var cells []string
+		for i, value := range row {
+			if value != "" {
+				cells = append(cells, header[i]+": "+value)
+			}
+		}
+		b.WriteString(strings.Join(cells, "; ") + "\n")
+	}
+}
+
+// trimTable collapses the whitespace of cells, drops empty rows and columns,
+// and pads rows to the same width
+func trimTable(rows [][]string) [][]string {
+	width := 0
+	var out [][]string
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		empty := true
+		for i, cell := range row {
+			cells[i] = strings.Join(strings.Fields(cell), " ")
+			empty = empty && cells[i] == ""
+		}
+		if !empty {
+			out = append(out, cells)
+			width = max(width, len(cells))
+		}
+	}
+
+	var keep []int
+	for col := 0; col < width; col++ {
+		for _, row := range out {
+			if col < len(row) && row[col] != "" {
+				keep = append(keep, col)
+				break
+			}
+		}
+	}
+	for i, row := range out {
+		cells := make([]string, len(keep))
+		for k, col := range keep {
+			if col < len(row) {
+				cells[k] = row[col]
+			}
+		}
+		out[i] = cells
+	}
+	return out
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(strings.NewReplacer(",", "", "%", "", "$", "").Replace(s), 64)
+	return err == nil
+}
@@ -0,0 +1,410 @@
+package processors
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxHCLValue is the longest attribute value written as it is; longer
+// values, such as inline policies, are cut
+const maxHCLValue = 200
+
+// hclReference matches the references Terraform expressions make to other
+// objects of a configuration: resources, data sources, modules, variables
+// and locals
+var hclReference = regexp.MustCompile(`\b(data\.[a-z][a-z0-9_]*\.[A-Za-z_][\w-]*|(?:var|local|module)\.[A-Za-z_][\w-]*|[a-z][a-z0-9]*_[a-z0-9_]+\.[A-Za-z_][\w-]*)`)
+
+// hclBlock is a block of a Terraform configuration, or its whole body
+type hclBlock struct {
+	typ    string
+	labels []string
+	doc    string // the comments right above the block
+	attrs  []hclAttr
+	blocks []*hclBlock
+}
+
+type hclAttr struct {
+	name, value string
+}
+
+// hclParser reads the blocks and attributes of HCL, the Terraform
+// configuration language. Expressions are kept as written, not evaluated.
+type hclParser struct {
+	src string
+	pos int
+}
+
+// parseHCL reads a Terraform configuration into the block of its body
+func parseHCL(src string) *hclBlock {
+	p := &hclParser{src: src}
+	root := &hclBlock{}
+	p.body(root, false)
+	return root
+}
+
+func (p *hclParser) peek(offset int) byte {
+	if p.pos+offset >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos+offset]
+}
+
+// body reads attributes and blocks up to the brace closing a nested body,
+// or to the end of the file
+func (p *hclParser) body(block *hclBlock, nested bool) {
+	for {
+		doc := p.space()
+		if p.pos >= len(p.src) {
+			return
+		}
+		if p.peek(0) == '}' {
+			p.pos++
+			if nested {
+				return
+			}
+			continue
+		}
+		name := p.ident()
+		if name == "" {
+			p.pos++ // not something HCL starts a line with
+			continue
+		}
+		p.inlineSpace()
+		if p.peek(0) == '=' && p.peek(1) != '=' {
+			p.pos++
+			block.attrs = append(block.attrs, hclAttr{name: name, value: p.expr()})
+			continue
+		}
+
+		child := &hclBlock{typ: name, doc: doc}
+		for {
+			p.inlineSpace()
+			if p.peek(0) == '"' {
+				start := p.pos
+				p.skipString()
+				child.labels = append(child.labels, strings.Trim(p.src[start:p.pos], `"`))
+			} else if label := p.ident(); label != "" {
+				child.labels = append(child.labels, label)
+			} else {
+				break
+			}
+		}
+		if p.peek(0) != '{' {
+			p.expr() // not a block; skip the rest of the line
+			continue
+		}
+		p.pos++
+		p.body(child, true)
+		block.blocks = append(block.blocks, child)
+	}
+}
+
+// space skips whitespace and comments, returning the comments on the lines
+// right above what follows. A comment after code on its line belongs to
+// that code, and a blank line separates comments from what follows.
+func (p *hclParser) space() string {
+	var doc []string
+	newlines := 0
+	sameLine := p.pos > 0
+	for p.pos < len(p.src) {
+		switch c := p.peek(0); {
+		case c == '\n':
+			newlines++
+			if newlines > 1 {
+				doc = nil
+			}
+			sameLine = false
+			p.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '#' || c == '/' && p.peek(1) == '/':
+			end := strings.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				end = len(p.src) - p.pos
+			}
+			text := strings.TrimSpace(strings.TrimLeft(p.src[p.pos:p.pos+end], "#/"))
+			if !sameLine {
+				doc = append(doc, text)
+			}
+			newlines = 0
+			p.pos += end
+		case c == '/' && p.peek(1) == '*':
+			end := strings.Index(p.src[p.pos+2:], "*/")
+			if end < 0 {
+				end = len(p.src) - p.pos - 2
+			}
+			var lines []string
+			for _, l := range strings.Split(p.src[p.pos+2:p.pos+2+end], "\n") {
+				if l = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(l), "*")); l != "" {
+					lines = append(lines, l)
+				}
+			}
+			if !sameLine {
+				doc = append(doc, strings.Join(lines, " "))
+			}
+			newlines = 0
+			p.pos = min(p.pos+end+4, len(p.src))
+		default:
+			return strings.Join(doc, " ")
+		}
+	}
+	return strings.Join(doc, " ")
+}
+
+func (p *hclParser) inlineSpace() {
+	for p.peek(0) == ' ' || p.peek(0) == '\t' {
+		p.pos++
+	}
+}
+
+func (p *hclParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			p.pos > start && (c == '-' || c >= '0' && c <= '9') {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+// expr reads an expression up to the end of its line, or the brace closing
+// the block it is in, and returns it on one line
+func (p *hclParser) expr() string {
+	start := p.pos
+	depth := 0
+loop:
+	for p.pos < len(p.src) {
+		switch c := p.peek(0); {
+		case c == '"':
+			p.skipString()
+			continue
+		case c == '<' && p.peek(1) == '<' && depth == 0:
+			p.skipHeredoc()
+			continue
+		case c == '#' || c == '/' && p.peek(1) == '/':
+			if depth == 0 {
+				break loop
+			}
+			for p.pos < len(p.src) && p.peek(0) != '\n' {
+				p.pos++
+			}
+			continue
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		case c == '\n':
+			if depth == 0 {
+				break loop
+			}
+		}
+		p.pos++
+	}
+	value := oneLine(p.src[start:p.pos])
+	if len(value) > maxHCLValue {
+		value = strings.ToValidUTF8(value[:maxHCLValue], "") + "..."
+	}
+	return value
+}
+
+// skipString skips a quoted string, with the ${...} and %{...} templates
+// in it, which may hold strings of their own
+func (p *hclParser) skipString() {
+	p.pos++
+	for p.pos < len(p.src) {
+		switch c := p.peek(0); {
+		case c == '\\':
+			p.pos += 2
+		case c == '"':
+			p.pos++
+			return
+		case c == '\n':
+			return
+		case (c == '$' || c == '%') && p.peek(1) == '{':
+			p.pos += 2
+			for depth := 1; depth > 0 && p.pos < len(p.src); {
+				switch p.peek(0) {
+				case '"':
+					p.skipString()
+					continue
+				case '{':
+					depth++
+				case '}':
+					depth--
+				case '\n':
+					return
+				}
+				p.pos++
+			}
+		default:
+			p.pos++
+		}
+	}
+}
+
+// skipHeredoc skips a <<ID or <<-ID heredoc up to the line closing it
+func (p *hclParser) skipHeredoc() {
+	p.pos += 2
+	if p.peek(0) == '-' {
+		p.pos++
+	}
+	marker := p.ident()
+	if marker == "" {
+		return
+	}
+	for p.pos < len(p.src) {
+		end := strings.IndexByte(p.src[p.pos:], '\n')
+		if end < 0 {
+			p.pos = len(p.src)
+			return
+		}
+		p.pos += end + 1
+		line := p.src[p.pos:]
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == marker {
+			p.pos += len(line)
+			return
+		}
+	}
+}
+
+// hclAddress is how Terraform expressions refer to a top-level block, and
+// the heading its section starts with
+func hclAddress(block *hclBlock) (string, string) {
+	label := func(i int) string {
+		if i < len(block.labels) {
+			return block.labels[i]
+		}
+		return ""
+	}
+	switch block.typ {
+	case "resource":
+		return label(0) + "." + label(1), "Terraform resource"
+	case "data":
+		return "data." + label(0) + "." + label(1), "Terraform data source"
+	case "module":
+		return "module." + label(0), "Terraform module"
+	case "variable":
+		return "var." + label(0), "Terraform variable"
+	case "output":
+		return "output." + label(0), "Terraform output"
+	case "provider":
+		return label(0), "Terraform provider"
+	case "locals":
+		return "locals", "Terraform locals"
+	case "terraform":
+		return "terraform", "Terraform settings"
+	}
+	return strings.TrimSpace(block.typ + " " + strings.Join(block.labels, " ")), "Terraform block"
+}
+
+// hclRefs returns the references made by the attributes of a block and
+// the blocks nested in it, each once
+func hclRefs(block *hclBlock, refs []string) []string {
+	for _, attr := range block.attrs {
+		for _, ref := range hclReference.FindAllString(attr.value, -1) {
+			refs = appendOnce(refs, ref)
+		}
+	}
+	for _, child := range block.blocks {
+		refs = hclRefs(child, refs)
+	}
+	return refs
+}
+
+// writeTerraform returns the text of a Terraform configuration with a
+// section per resource, data source, module, variable, output, provider
+// and locals block, separated by SectionBreak. Each section says what the
+// block references and what references it.
+func writeTerraform(root *hclBlock) (string, int) {
+	refs := make([][]string, len(root.blocks))
+	referencedBy := make(map[string][]string)
+	for i, block := range root.blocks {
+		address, _ := hclAddress(block)
+		for _, ref := range hclRefs(block, nil) {
+			if ref != address {
+				refs[i] = append(refs[i], ref)
+				referencedBy[ref] = appendOnce(referencedBy[ref], address)
+			}
+		}
+	}
+
+	var sections []string
+	if len(root.attrs) > 0 {
+		var b strings.Builder
+		b.WriteString("Terraform values\n")
+		writeList(&b, "Attributes", hclAttrLines(root.attrs))
+		sections = append(sections, b.String())
+	}
+	for i, block := range root.blocks {
+		var b strings.Builder
+		address, heading := hclAddress(block)
+		fmt.Fprintf(&b, "%s: %s\n", heading, address)
+		switch block.typ {
+		case "resource", "data":
+			if len(block.labels) > 0 {
+				writeField(&b, "Type", block.labels[0])
+				provider := strings.SplitN(block.labels[0], "_", 2)[0]
+				for _, attr := range block.attrs {
+					if attr.name == "provider" {
+						provider = attr.value
+					}
+				}
+				writeField(&b, "Provider", provider)
+			}
+		}
+		writeField(&b, "Description", block.doc)
+		writeList(&b, "Attributes", hclAttrLines(block.attrs))
+		var nested []string
+		for _, child := range block.blocks {
+			nested = hclBlockLines(child, "", nested)
+		}
+		writeList(&b, "Nested blocks", nested)
+		writeField(&b, "References", strings.Join(refs[i], ", "))
+		writeField(&b, "Referenced by", strings.Join(referencedBy[address], ", "))
+		if block.typ == "locals" {
+			for _, attr := range block.attrs {
+				name := "local." + attr.name
+				writeField(&b, name+" referenced by", strings.Join(referencedBy[name], ", "))
+			}
+		}
+		sections = append(sections, b.String())
+	}
+	return strings.Join(sections, "\n"+string(SectionBreak)+"\n"), len(root.blocks)
+}
+
+func hclAttrLines(attrs []hclAttr) []string {
+	lines := make([]string, len(attrs))
+	for i, attr := range attrs {
+		lines[i] = attr.name + " = " + attr.value
+	}
+	return lines
+}
+
+// hclBlockLines writes a nested block as one line of its attributes under
+// its path, such as "spec.rule.http: ...", followed by its own blocks
+func hclBlockLines(block *hclBlock, parent string, lines []string) []string {
+	path := strings.Join(append([]string{block.typ}, block.labels...), " ")
+	if parent != "" {
+		path = parent + "." + path
+	}
+	if len(block.attrs) > 0 {
+		lines = append(lines, path+": "+strings.Join(hclAttrLines(block.attrs), "; "))
+	} else if len(block.blocks) == 0 {
+		lines = append(lines, path)
+	}
+	for _, child := range block.blocks {
+		lines = hclBlockLines(child, path, lines)
+	}
+	return lines
+}
@@ -0,0 +1,85 @@
+// Package contentstore keeps the full extracted text of documents, so they
+// can be chunked, summarized and embedded again without running extraction
+// or OCR again, and so the source text can be inspected. Content is keyed by
+// file hash: identical files and unchanged files share one copy. Image
+// thumbnails are kept beside the text under the same key.
+package contentstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned when no content is stored for a file hash
+var ErrNotFound = errors.New("content not found")
+
+// Info describes stored content
+type Info struct {
+	Size     int64
+	Encoding string // source encoding the text was decoded from, if known
+}
+
+// Store persists extracted text by file hash
+type Store interface {
+	// Put stores size bytes of text read from r
+	Put(ctx context.Context, fileHash string, r io.Reader, info Info) error
+	// Get opens the stored text; the caller must close it
+	Get(ctx context.Context, fileHash string) (io.ReadCloser, Info, error)
+	// PutThumbnail stores the PNG thumbnail of an image
+	PutThumbnail(ctx context.Context, fileHash string, data []byte) error
+	// GetThumbnail returns the stored PNG thumbnail of an image
+	GetThumbnail(ctx context.Context, fileHash string) ([]byte, error)
+	// Delete removes the text and any thumbnail
+	Delete(ctx context.Context, fileHash string) error
+	Close() error
+}
+
+// Compile-time checks that the stores implement the interface
+var (
+	_ Store = (*FileStore)(nil)
+	_ Store = (*S3Store)(nil)
+)
+
+// NewStore creates the content store selected by configuration. It returns
+// nil without an error when the backend is "none".
+func NewStore(_ context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	switch cfg.ContentStore.Backend {
+	case "none":
+		return nil, nil
+	case "file":
+		store, err := NewFileStore(cfg.ContentStore.Directory)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Content store enabled", zap.String("backend", "file"), zap.String("directory", cfg.ContentStore.Directory))
+		return store, nil
+	case "s3":
+		logger.Info("Content store enabled", zap.String("backend", "s3"), zap.String("bucket", cfg.ContentStore.S3Bucket))
+		return NewS3Store(cfg.ContentStore), nil
+	default:
+		return nil, fmt.Errorf("unknown content store backend: %s", cfg.ContentStore.Backend)
+	}
+}
+
+// Suffixes of the objects stored for a file hash
+const (
+	textSuffix      = ".txt"
+	thumbnailSuffix = ".thumb.png"
+)
+
+// objectName returns the name an object is stored under: the file hash with
+// its algorithm prefix made safe for file and object names, and the suffix
+// of the kind of object
+func objectName(fileHash, suffix string) (string, error) {
+	name := strings.ReplaceAll(fileHash, ":", "-")
+	if name == "" || strings.ContainsAny(name, `/\.`) {
+		return "", fmt.Errorf("invalid file hash: %q", fileHash)
+	}
+	return name + suffix, nil
+}
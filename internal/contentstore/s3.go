@@ -0,0 +1,291 @@
+package contentstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty request body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Store keeps content as objects in an S3 bucket, or any service with an
+// S3 compatible API such as MinIO, signing requests with AWS Signature
+// Version 4. The source encoding is kept as object metadata, and thumbnails
+// are objects beside the text.
+type S3Store struct {
+	bucket       string
+	region       string
+	endpoint     string // empty for AWS
+	prefix       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	httpClient   *http.Client
+}
+
+// NewS3Store creates an S3 backed content store
+func NewS3Store(cfg config.ContentStoreConfig) *S3Store {
+	return &S3Store{
+		bucket:       cfg.S3Bucket,
+		region:       cfg.S3Region,
+		endpoint:     strings.TrimSuffix(cfg.S3Endpoint, "/"),
+		prefix:       cfg.S3Prefix,
+		accessKey:    cfg.AccessKeyID,
+		secretKey:    cfg.SecretAccessKey,
+		sessionToken: cfg.SessionToken,
+		httpClient:   &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Put uploads the content in a single request
+func (s *S3Store) Put(ctx context.Context, fileHash string, r io.Reader, info Info) error {
+	req, err := s.newRequest(ctx, http.MethodPut, fileHash, textSuffix, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if info.Encoding != "" {
+		req.Header.Set("X-Amz-Meta-Encoding", info.Encoding)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload content: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads the content
+func (s *S3Store) Get(ctx context.Context, fileHash string) (io.ReadCloser, Info, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, fileHash, textSuffix, nil)
+	if err != nil {
+		return nil, Info{}, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	info := Info{Size: resp.ContentLength, Encoding: resp.Header.Get("X-Amz-Meta-Encoding")}
+	return resp.Body, info, nil
+}
+
+// PutThumbnail uploads the thumbnail
+func (s *S3Store) PutThumbnail(ctx context.Context, fileHash string, data []byte) error {
+	req, err := s.newRequest(ctx, http.MethodPut, fileHash, thumbnailSuffix, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", "image/png")
+
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload thumbnail: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetThumbnail downloads the thumbnail
+func (s *S3Store) GetThumbnail(ctx context.Context, fileHash string) ([]byte, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, fileHash, thumbnailSuffix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thumbnail: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes the text and thumbnail objects; deleting a missing object
+// succeeds
+func (s *S3Store) Delete(ctx context.Context, fileHash string) error {
+	for _, suffix := range []string{textSuffix, thumbnailSuffix} {
+		req, err := s.newRequest(ctx, http.MethodDelete, fileHash, suffix, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := s.do(req)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete content: %w", err)
+		}
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// Close is a no-op for the S3 store
+func (s *S3Store) Close() error {
+	return nil
+}
+
+// objectURL returns the URL of an object: virtual-hosted style on AWS and
+// path style on a custom endpoint
+func (s *S3Store) objectURL(key string) string {
+	if s.endpoint == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, uriEncode(key, false))
+	}
+	return fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, uriEncode(key, false))
+}
+
+// newRequest creates a signed request for an object of a file hash
+func (s *S3Store) newRequest(ctx context.Context, method, fileHash, suffix string, body io.Reader) (*http.Request, error) {
+	name, err := objectName(fileHash, suffix)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(s.prefix+name), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	// Uploads stream the body, so the payload is not part of the signature
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		payloadHash = "UNSIGNED-PAYLOAD"
+	}
+	s.sign(req, payloadHash, time.Now().UTC())
+	return req, nil
+}
+
+// do sends a request, returning ErrNotFound for missing objects and an
+// error for any other unsuccessful status
+func (s *S3Store) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096)) //nolint:errcheck
+	return nil, fmt.Errorf("S3 error (status %d): %s", resp.StatusCode, string(body))
+}
+
+// sign adds AWS Signature Version 4 headers to the request
+func (s *S3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	// Host and every x-amz-* header are signed
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by name
+func canonicalQuery(values url.Values) string {
+	pairs := make([]string, 0, len(values))
+	for name, vs := range values {
+		for _, v := range vs {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and
+// slashes unless encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9'),
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,161 @@
+package contentstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps content as text files in a directory, each with a JSON
+// file beside it holding its Info
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create content directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Put writes the content to a temporary file and renames it into place, so
+// readers never see partial content
+func (s *FileStore) Put(_ context.Context, fileHash string, r io.Reader, info Info) error {
+	name, err := objectName(fileHash, textSuffix)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".content-*")
+	if err != nil {
+		return fmt.Errorf("failed to create content file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after the rename
+
+	size, err := io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write content: %w", err)
+	}
+
+	info.Size = size
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal content info: %w", err)
+	}
+	if err := os.WriteFile(s.infoPath(name), data, 0600); err != nil {
+		return fmt.Errorf("failed to write content info: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("failed to store content: %w", err)
+	}
+	return nil
+}
+
+// Get opens the content file
+func (s *FileStore) Get(_ context.Context, fileHash string) (io.ReadCloser, Info, error) {
+	name, err := objectName(fileHash, textSuffix)
+	if err != nil {
+		return nil, Info{}, err
+	}
+
+	file, err := os.Open(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, Info{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Info{}, fmt.Errorf("failed to open content: %w", err)
+	}
+
+	var info Info
+	if data, err := os.ReadFile(s.infoPath(name)); err == nil {
+		json.Unmarshal(data, &info) //nolint:errcheck // the size is taken from the file below
+	}
+	if stat, err := file.Stat(); err == nil {
+		info.Size = stat.Size()
+	}
+	return file, info, nil
+}
+
+// PutThumbnail writes the thumbnail to a temporary file and renames it into
+// place
+func (s *FileStore) PutThumbnail(_ context.Context, fileHash string, data []byte) error {
+	name, err := objectName(fileHash, thumbnailSuffix)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".thumbnail-*")
+	if err != nil {
+		return fmt.Errorf("failed to create thumbnail file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after the rename
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+	return nil
+}
+
+// GetThumbnail reads the thumbnail file
+func (s *FileStore) GetThumbnail(_ context.Context, fileHash string) ([]byte, error) {
+	name, err := objectName(fileHash, thumbnailSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thumbnail: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes the content, its info and any thumbnail
+func (s *FileStore) Delete(_ context.Context, fileHash string) error {
+	name, err := objectName(fileHash, textSuffix)
+	if err != nil {
+		return err
+	}
+	thumbnail, err := objectName(fileHash, thumbnailSuffix)
+	if err != nil {
+		return err
+	}
+	for _, path := range []string{filepath.Join(s.dir, name), s.infoPath(name), filepath.Join(s.dir, thumbnail)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete content: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close is a no-op for the file store
+func (s *FileStore) Close() error {
+	return nil
+}
+
+func (s *FileStore) infoPath(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
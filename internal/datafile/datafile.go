@@ -0,0 +1,139 @@
+// Package datafile reads what data files hold: the tables of SQLite
+// databases and the schema of Parquet files, with their columns, row
+// counts and a sample of their rows. Both formats are read from their
+// on-disk layout, without a database driver.
+package datafile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Formats data files are read in
+const (
+	FormatSQLite  = "sqlite"
+	FormatParquet = "parquet"
+)
+
+// maxValue is the longest value sampled as it is; longer text is cut
+const maxValue = 200
+
+// ErrUnknownFormat is returned for files that are neither SQLite databases
+// nor Parquet files, such as other formats using the .db extension
+var ErrUnknownFormat = errors.New("not a SQLite database or Parquet file")
+
+var (
+	sqliteMagic  = []byte("SQLite format 3\x00")
+	parquetMagic = []byte("PAR1")
+)
+
+// extensions lists the extensions of the data files read
+var extensions = []string{".parquet", ".sqlite", ".sqlite3", ".db", ".db3"}
+
+// Info describes the tables of a data file
+type Info struct {
+	Format  string  `json:"format"`            // sqlite or parquet
+	Creator string  `json:"creator,omitempty"` // the application that wrote a Parquet file
+	Tables  []Table `json:"tables"`            // a Parquet file is a single table
+}
+
+// Table is a table or view of a data file
+type Table struct {
+	Name       string     `json:"name"`
+	Kind       string     `json:"kind"` // table or view
+	Rows       int64      `json:"rows"` // zero for views
+	Columns    []Column   `json:"columns"`
+	Indexes    []string   `json:"indexes,omitempty"`    // name and columns of SQLite indexes
+	Definition string     `json:"definition,omitempty"` // the query of a view
+	Sample     [][]string `json:"-"`                    // values of the first rows, one per column
+	Note       string     `json:"-"`                    // why rows were not sampled or counted
+}
+
+// Column is a column of a table
+type Column struct {
+	Name       string `json:"name"`
+	Type       string `json:"type,omitempty"`
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+	NotNull    bool   `json:"not_null,omitempty"`
+	References string `json:"references,omitempty"` // table(column) of a foreign key
+	Min        string `json:"min,omitempty"`        // from Parquet statistics
+	Max        string `json:"max,omitempty"`
+	Nulls      int64  `json:"nulls,omitempty"`
+}
+
+// IsDataFile reports whether an extension belongs to the data files read.
+// Files with the .db extension are only read when they are SQLite
+// databases.
+func IsDataFile(ext string) bool {
+	for _, e := range extensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Read returns the tables of a SQLite database or Parquet file, with up to
+// sampleRows rows of each. The format is told by the file's header.
+func Read(path string, sampleRows int) (*Info, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data file: %w", err)
+	}
+	defer file.Close() //nolint:errcheck
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open data file: %w", err)
+	}
+
+	head := make([]byte, len(sqliteMagic))
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read data file: %w", err)
+	}
+	head = head[:n]
+
+	var info *Info
+	switch {
+	case bytes.HasPrefix(head, sqliteMagic):
+		info, err = readSQLite(file, stat.Size(), sampleRows)
+	case bytes.HasPrefix(head, parquetMagic):
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		info, err = readParquet(file, stat.Size(), name, sampleRows)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ColumnNames returns the names of the columns of all tables, each once
+func (info *Info) ColumnNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, table := range info.Tables {
+		for _, column := range table.Columns {
+			if !seen[column.Name] {
+				seen[column.Name] = true
+				names = append(names, column.Name)
+			}
+		}
+	}
+	return names
+}
+
+// clip cuts a sampled value to maxValue bytes and one line
+func clip(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > maxValue {
+		s = strings.ToValidUTF8(s[:maxValue], "") + "..."
+	}
+	return s
+}
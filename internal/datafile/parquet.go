@@ -0,0 +1,842 @@
+package datafile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Parquet files are read from their footer, described at
+// https://parquet.apache.org/docs/file-format/: the schema, row groups and
+// column statistics. Rows are sampled from the first data pages of the
+// first row group, for columns that are not repeated.
+const (
+	// maxFooterBytes bounds the size of the metadata read
+	maxFooterBytes = 64 << 20
+	// maxPageBytes bounds the size of a page read for sampling
+	maxPageBytes = 16 << 20
+	// pageHeaderBytes is how much is read for a page header
+	pageHeaderBytes = 64 << 10
+	// maxSamplePages bounds the pages read per column for sampling
+	maxSamplePages = 16
+)
+
+// Physical types
+const (
+	typeBoolean = iota
+	typeInt32
+	typeInt64
+	typeInt96
+	typeFloat
+	typeDouble
+	typeByteArray
+	typeFixedLenByteArray
+)
+
+var physicalTypes = []string{"boolean", "int32", "int64", "int96", "float", "double", "binary", "fixed_len_byte_array"}
+
+// Page types
+const (
+	pageData       = 0
+	pageDictionary = 2
+	pageDataV2     = 3
+)
+
+// Encodings
+const (
+	encodingPlain         = 0
+	encodingPlainDict     = 2
+	encodingRLE           = 3
+	encodingRLEDictionary = 8
+)
+
+// Compression codecs
+const (
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+)
+
+// Repetitions and the converted types of groups
+const (
+	repetitionRequired = 0
+	repetitionRepeated = 2
+	convertedMap       = 1
+	convertedList      = 3
+)
+
+const (
+	parquetTrailerSize = 8 // footer length and magic
+	julianUnixEpoch    = 2440588
+	secondsPerDay      = 86400
+)
+
+var (
+	codecNames    = []string{"uncompressed", "Snappy", "gzip", "LZO", "Brotli", "LZ4", "Zstandard", "LZ4"}
+	encodingNames = map[int64]string{1: "group var int", 4: "bit-packed", 5: "delta binary packed",
+		6: "delta length byte array", 7: "delta byte array", 9: "byte stream split"}
+)
+
+// errUnsupported is returned for pages encoded or compressed in ways not
+// read; the file's schema is still read
+var errUnsupported = errors.New("not supported")
+
+// parquetColumn is a leaf column of a Parquet schema
+type parquetColumn struct {
+	name     string
+	typ      string // as described to readers
+	physical int64
+	length   int    // of fixed-length byte arrays
+	kind     string // how values are written: string, date, time, timestamp, decimal, uuid or unsigned
+	unit     int64  // units per second of times and timestamps
+	scale    int    // of decimals
+	maxDef   int
+	maxRep   int
+}
+
+type parquetFile struct {
+	r    io.ReaderAt
+	size int64
+}
+
+func readParquet(r io.ReaderAt, size int64, name string, sampleRows int) (*Info, error) {
+	if size < int64(len(parquetMagic))+parquetTrailerSize {
+		return nil, fmt.Errorf("invalid Parquet file: too short")
+	}
+	trail := make([]byte, parquetTrailerSize)
+	if _, err := r.ReadAt(trail, size-parquetTrailerSize); err != nil {
+		return nil, fmt.Errorf("failed to read Parquet footer: %w", err)
+	}
+	if !bytes.Equal(trail[4:], parquetMagic) {
+		return nil, fmt.Errorf("invalid Parquet file: no footer")
+	}
+	length := int64(binary.LittleEndian.Uint32(trail))
+	if length > maxFooterBytes || length > size-int64(len(parquetMagic))-parquetTrailerSize {
+		return nil, fmt.Errorf("invalid Parquet file: footer of %d bytes", length)
+	}
+	footer := make([]byte, length)
+	if _, err := r.ReadAt(footer, size-parquetTrailerSize-length); err != nil {
+		return nil, fmt.Errorf("failed to read Parquet footer: %w", err)
+	}
+	meta, err := (&thriftReader{buf: footer}).readStruct(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Parquet metadata: %w", err)
+	}
+
+	columns := parquetColumns(meta.structs(2))
+	table := Table{Name: name, Kind: "table", Rows: meta.int(3), Columns: make([]Column, len(columns))}
+	rowGroups := meta.structs(4)
+	for i, c := range columns {
+		table.Columns[i] = Column{Name: c.name, Type: c.typ, NotNull: c.maxDef == 0}
+		c.statistics(&table.Columns[i], rowGroups, i)
+	}
+
+	if sampleRows > 0 && len(rowGroups) > 0 && table.Rows > 0 {
+		f := &parquetFile{r: r, size: size}
+		table.Sample, table.Note = f.sample(columns, rowGroups[0], sampleRows)
+	}
+	return &Info{Format: FormatParquet, Creator: meta.string(6), Tables: []Table{table}}, nil
+}
+
+// parquetColumns returns the leaf columns of a schema, which lists its
+// elements depth first. Leaves are named by their path, leaving out the
+// groups lists and maps are made of.
+func parquetColumns(schema []thriftStruct) []*parquetColumn {
+	var columns []*parquetColumn
+	// unnamed counts the elements down from this one left out of names
+	var walk func(i int, path []string, def, rep, unnamed int) int
+	walk = func(i int, path []string, def, rep, unnamed int) int {
+		element := schema[i]
+		switch element.int(3) {
+		case repetitionRequired:
+		case repetitionRepeated:
+			def++
+			rep++
+		default:
+			def++
+		}
+		if unnamed == 0 {
+			path = append(path[:len(path):len(path)], element.string(4))
+		}
+		children := int(element.int(5))
+		i++
+		if children <= 0 {
+			if element.has(1) {
+				columns = append(columns, newParquetColumn(element, strings.Join(path, "."), def, rep))
+			}
+			return i
+		}
+
+		next := 0
+		switch converted, logical := element.int(6), element.child(10); {
+		case converted == convertedList || logical.has(3):
+			next = 2 // the repeated group and the element in it
+		case converted == convertedMap || logical.has(2):
+			next = 1 // the repeated key-value group
+		case unnamed == 2 && children == 1:
+			next = 1
+		}
+		for c := 0; c < children && i < len(schema); c++ {
+			i = walk(i, path, def, rep, next)
+		}
+		return i
+	}
+	// The first element is the schema's root
+	for i := 1; i < len(schema); {
+		i = walk(i, nil, 0, 0, 0)
+	}
+	return columns
+}
+
+// newParquetColumn describes a leaf column from its schema element
+func newParquetColumn(element thriftStruct, name string, def, rep int) *parquetColumn {
+	c := &parquetColumn{
+		name:     name,
+		physical: element.int(1),
+		length:   int(element.int(2)),
+		maxDef:   def,
+		maxRep:   rep,
+	}
+	if c.physical >= 0 && c.physical < int64(len(physicalTypes)) {
+		c.typ = physicalTypes[c.physical]
+	}
+	if logical := element.child(10); logical != nil {
+		c.logicalType(logical)
+	} else if element.has(6) {
+		c.convertedType(element)
+	}
+	if c.physical == typeInt96 {
+		// Timestamps written by older versions of Impala, Hive and Spark
+		c.kind, c.typ = "int96", "timestamp[int96]"
+	}
+	if rep > 0 {
+		c.typ = "list<" + c.typ + ">"
+	}
+	return c
+}
+
+// timeUnits are the units of times and timestamps, by the field setting
+// them in the TimeUnit union, with their units per second
+var timeUnits = []struct {
+	name      string
+	perSecond int64
+}{{"ms", 1_000}, {"us", 1_000_000}, {"ns", 1_000_000_000}}
+
+// logicalType sets the type and kind of a column from its logical type
+// annotation, a union of one field per type
+func (c *parquetColumn) logicalType(logical thriftStruct) {
+	switch {
+	case logical.has(1):
+		c.kind, c.typ = "string", "string"
+	case logical.has(4):
+		c.kind, c.typ = "string", "enum"
+	case logical.has(12):
+		c.kind, c.typ = "string", "json"
+	case logical.has(5):
+		decimal := logical.child(5)
+		c.kind, c.scale = "decimal", int(decimal.int(1))
+		c.typ = fmt.Sprintf("decimal(%d,%d)", decimal.int(2), decimal.int(1))
+	case logical.has(6):
+		c.kind, c.typ = "date", "date"
+	case logical.has(7), logical.has(8):
+		c.kind = "time"
+		field := int16(7)
+		if logical.has(8) {
+			c.kind, field = "timestamp", 8
+		}
+		unit := timeUnits[0]
+		for i, u := range timeUnits {
+			if logical.child(field).child(2).has(int16(i + 1)) {
+				unit = u
+			}
+		}
+		c.unit = unit.perSecond
+		c.typ = c.kind + "[" + unit.name + "]"
+		if logical.child(field).bool(1, false) {
+			c.typ = c.kind + "[" + unit.name + ", UTC]"
+		}
+	case logical.has(10):
+		integer := logical.child(10)
+		c.typ = fmt.Sprintf("int%d", integer.int(1))
+		if !integer.bool(2, true) {
+			c.kind, c.typ = "unsigned", "u"+c.typ
+		}
+	case logical.has(13):
+		c.typ = "bson"
+	case logical.has(14):
+		c.kind, c.typ = "uuid", "uuid"
+	case logical.has(15):
+		c.typ = "float16"
+	}
+}
+
+// convertedType sets the type and kind of a column from the converted
+// type annotation older writers use
+func (c *parquetColumn) convertedType(element thriftStruct) {
+	switch converted := element.int(6); {
+	case converted == 0:
+		c.kind, c.typ = "string", "string"
+	case converted == 4:
+		c.kind, c.typ = "string", "enum"
+	case converted == 19:
+		c.kind, c.typ = "string", "json"
+	case converted == 5:
+		c.kind, c.scale = "decimal", int(element.int(7))
+		c.typ = fmt.Sprintf("decimal(%d,%d)", element.int(8), element.int(7))
+	case converted == 6:
+		c.kind, c.typ = "date", "date"
+	case converted >= 7 && converted <= 10:
+		// TIME_MILLIS, TIME_MICROS, TIMESTAMP_MILLIS, TIMESTAMP_MICROS
+		c.kind = "time"
+		if converted >= 9 {
+			c.kind = "timestamp"
+		}
+		unit := timeUnits[1-converted%2]
+		c.unit = unit.perSecond
+		c.typ = c.kind + "[" + unit.name + "]"
+	case converted >= 11 && converted <= 14:
+		c.kind = "unsigned"
+		c.typ = fmt.Sprintf("uint%d", 8<<(converted-11))
+	case converted >= 15 && converted <= 18:
+		c.typ = fmt.Sprintf("int%d", 8<<(converted-15))
+	case converted == 20:
+		c.typ = "bson"
+	case converted == 21:
+		c.typ = "interval"
+	}
+}
+
+// statistics sets the smallest and largest values and the number of nulls
+// of a column from the statistics of its chunks in all row groups, when
+// every chunk has them
+func (c *parquetColumn) statistics(column *Column, rowGroups []thriftStruct, i int) {
+	var lo, hi any
+	nulls := int64(0)
+	haveNulls, haveRange := true, true
+	for _, rowGroup := range rowGroups {
+		chunks := rowGroup.structs(1)
+		if i >= len(chunks) {
+			return
+		}
+		stats := chunks[i].child(3).child(12)
+		if stats == nil {
+			return
+		}
+		if stats.has(3) {
+			nulls += stats.int(3)
+		} else {
+			haveNulls = false
+		}
+
+		minimum, maximum := stats.bytes(6), stats.bytes(5)
+		if !stats.has(6) && c.physical != typeByteArray && c.physical != typeFixedLenByteArray && c.kind != "unsigned" {
+			// The deprecated fields are signed, which is only right for
+			// signed numbers
+			minimum, maximum = stats.bytes(2), stats.bytes(1)
+		}
+		if minimum == nil || maximum == nil || c.maxRep > 0 {
+			haveRange = false
+			continue
+		}
+		a, err1 := c.plain(minimum, 1, true)
+		b, err2 := c.plain(maximum, 1, true)
+		if err1 != nil || err2 != nil || len(a) == 0 || len(b) == 0 {
+			haveRange = false
+			continue
+		}
+		if lo == nil || c.less(a[0], lo) {
+			lo = a[0]
+		}
+		if hi == nil || c.less(hi, b[0]) {
+			hi = b[0]
+		}
+	}
+	if haveNulls {
+		column.Nulls = nulls
+	}
+	if haveRange && lo != nil {
+		column.Min, column.Max = c.format(lo), c.format(hi)
+	}
+}
+
+// sample reads the first rows of a row group, or notes why it could not.
+// Values of repeated columns are left empty.
+func (f *parquetFile) sample(columns []*parquetColumn, rowGroup thriftStruct, rows int) ([][]string, string) {
+	rows = int(min(int64(rows), rowGroup.int(3)))
+	chunks := rowGroup.structs(1)
+	sample := make([][]string, rows)
+	for r := range sample {
+		sample[r] = make([]string, len(columns))
+	}
+	note := ""
+	for i, c := range columns {
+		if c.maxRep > 0 {
+			note = "values of repeated columns are not sampled"
+			continue
+		}
+		if i >= len(chunks) || chunks[i].string(1) != "" {
+			// Chunks stored in other files
+			return nil, "rows not sampled: column chunks are in other files"
+		}
+		values, err := f.columnValues(c, chunks[i].child(3), rows)
+		if err != nil {
+			return nil, "rows not sampled: " + err.Error()
+		}
+		for r := 0; r < rows && r < len(values); r++ {
+			if values[r] != nil {
+				sample[r][i] = c.format(values[r])
+			}
+		}
+	}
+	return sample, note
+}
+
+// columnValues reads the first values of a column chunk, nil for nulls
+func (f *parquetFile) columnValues(c *parquetColumn, meta thriftStruct, rows int) ([]any, error) {
+	codec := meta.int(4)
+	if codec != codecUncompressed && codec != codecSnappy && codec != codecGzip {
+		name := "codec " + strconv.FormatInt(codec, 10)
+		if codec >= 0 && codec < int64(len(codecNames)) {
+			name = codecNames[codec]
+		}
+		return nil, fmt.Errorf("%s compression is %w", name, errUnsupported)
+	}
+	offset := meta.int(9)
+	if dictionary := meta.int(11); dictionary > 0 && dictionary < offset {
+		offset = dictionary
+	}
+	end := min(offset+meta.int(7), f.size)
+
+	var dictionary, values []any
+	for pages := 0; len(values) < rows && offset < end && pages < maxSamplePages; pages++ {
+		header, data, next, err := f.page(offset, end)
+		if err != nil {
+			return nil, err
+		}
+		offset = next
+		switch header.int(1) {
+		case pageDictionary:
+			raw, err := decompress(codec, data, header.int(2))
+			if err != nil {
+				return nil, err
+			}
+			dictionary, err = c.plain(raw, int(header.child(7).int(1)), false)
+			if err != nil {
+				return nil, err
+			}
+		case pageData, pageDataV2:
+			page, err := c.dataPage(header, data, codec, dictionary)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, page...)
+		}
+	}
+	return values, nil
+}
+
+// page reads the page at offset, returning its header, its data and the
+// offset of the next page
+func (f *parquetFile) page(offset, end int64) (thriftStruct, []byte, int64, error) {
+	buf := make([]byte, min(pageHeaderBytes, end-offset))
+	if _, err := f.r.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return nil, nil, 0, fmt.Errorf("failed to read page: %w", err)
+	}
+	reader := &thriftReader{buf: buf}
+	header, err := reader.readStruct(0)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to read page header: %w", err)
+	}
+	size := header.int(3)
+	start := offset + int64(reader.pos)
+	if size < 0 || size > maxPageBytes || header.int(2) > maxPageBytes || start+size > end {
+		return nil, nil, 0, fmt.Errorf("page of %d bytes is too large to sample", size)
+	}
+	data := make([]byte, size)
+	if _, err := f.r.ReadAt(data, start); err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to read page: %w", err)
+	}
+	return header, data, start + size, nil
+}
+
+// dataPage decodes the values of a data page, nil for nulls
+func (c *parquetColumn) dataPage(header thriftStruct, data []byte, codec int64, dictionary []any) ([]any, error) {
+	var count int
+	var encoding int64
+	var levels, values []byte
+	if header.int(1) == pageDataV2 {
+		v2 := header.child(8)
+		count, encoding = int(v2.int(1)), v2.int(4)
+		repLength, defLength := v2.int(6), v2.int(5)
+		if repLength < 0 || defLength < 0 || repLength+defLength > int64(len(data)) {
+			return nil, errThrift
+		}
+		levels = data[repLength : repLength+defLength]
+		values = data[repLength+defLength:]
+		if v2.bool(7, true) {
+			var err error
+			if values, err = decompress(codec, values, header.int(2)-repLength-defLength); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		v1 := header.child(5)
+		count, encoding = int(v1.int(1)), v1.int(2)
+		raw, err := decompress(codec, data, header.int(2))
+		if err != nil {
+			return nil, err
+		}
+		values = raw
+		if c.maxDef > 0 {
+			if v1.int(3) != encodingRLE {
+				return nil, fmt.Errorf("bit-packed definition levels are %w", errUnsupported)
+			}
+			levels, values, err = lengthPrefixed(raw)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	present := count
+	var defs []int
+	if c.maxDef > 0 {
+		var err error
+		if defs, err = hybrid(levels, bitWidth(c.maxDef), count); err != nil {
+			return nil, err
+		}
+		present = 0
+		for _, d := range defs {
+			if d == c.maxDef {
+				present++
+			}
+		}
+	}
+
+	var decoded []any
+	var err error
+	switch {
+	case encoding == encodingPlain:
+		decoded, err = c.plain(values, present, false)
+	case encoding == encodingPlainDict || encoding == encodingRLEDictionary:
+		if dictionary == nil || len(values) == 0 {
+			return nil, errThrift
+		}
+		var indexes []int
+		if indexes, err = hybrid(values[1:], int(values[0]), present); err == nil {
+			decoded = make([]any, 0, len(indexes))
+			for _, index := range indexes {
+				if index >= len(dictionary) {
+					return nil, errThrift
+				}
+				decoded = append(decoded, dictionary[index])
+			}
+		}
+	case encoding == encodingRLE && c.physical == typeBoolean:
+		var runs []byte
+		if runs, _, err = lengthPrefixed(values); err == nil {
+			var bits []int
+			if bits, err = hybrid(runs, 1, present); err == nil {
+				for _, bit := range bits {
+					decoded = append(decoded, bit == 1)
+				}
+			}
+		}
+	default:
+		name := encodingNames[encoding]
+		if name == "" {
+			name = "encoding " + strconv.FormatInt(encoding, 10)
+		}
+		return nil, fmt.Errorf("%s encoding is %w", name, errUnsupported)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if defs == nil {
+		return decoded, nil
+	}
+	out := make([]any, 0, count)
+	for _, d := range defs {
+		if d == c.maxDef && len(decoded) > 0 {
+			out = append(out, decoded[0])
+			decoded = decoded[1:]
+		} else {
+			out = append(out, nil)
+		}
+	}
+	return out, nil
+}
+
+// plain decodes count values in the plain encoding. A single statistics
+// value of a byte array type has no length prefix.
+func (c *parquetColumn) plain(data []byte, count int, single bool) ([]any, error) {
+	values := make([]any, 0, min(count, len(data)+1))
+	fixed := map[int64]int{typeInt32: 4, typeInt64: 8, typeInt96: 12, typeFloat: 4, typeDouble: 8, typeFixedLenByteArray: c.length}
+	pos := 0
+	for i := 0; i < count; i++ {
+		if c.physical == typeBoolean {
+			if i/8 >= len(data) {
+				return nil, errThrift
+			}
+			values = append(values, data[i/8]>>(i%8)&1 == 1)
+			continue
+		}
+		size, ok := fixed[c.physical]
+		switch {
+		case single && !ok:
+			size = len(data)
+		case !ok:
+			if pos+4 > len(data) {
+				return nil, errThrift
+			}
+			size = int(binary.LittleEndian.Uint32(data[pos:]))
+			pos += 4
+		}
+		if size <= 0 && c.physical != typeByteArray || size < 0 || pos+size > len(data) {
+			return nil, errThrift
+		}
+		value := data[pos : pos+size]
+		pos += size
+
+		switch c.physical {
+		case typeInt32:
+			v := int32(binary.LittleEndian.Uint32(value))
+			if c.kind == "unsigned" {
+				values = append(values, uint64(uint32(v)))
+			} else {
+				values = append(values, int64(v))
+			}
+		case typeInt64:
+			v := binary.LittleEndian.Uint64(value)
+			if c.kind == "unsigned" {
+				values = append(values, v)
+			} else {
+				values = append(values, int64(v))
+			}
+		case typeInt96:
+			nanos := int64(binary.LittleEndian.Uint64(value))
+			day := int64(binary.LittleEndian.Uint32(value[8:]))
+			values = append(values, time.Unix((day-julianUnixEpoch)*secondsPerDay, nanos).UTC())
+		case typeFloat:
+			values = append(values, float64(math.Float32frombits(binary.LittleEndian.Uint32(value))))
+		case typeDouble:
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(value)))
+		default:
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// less orders two values of the column
+func (c *parquetColumn) less(a, b any) bool {
+	if c.kind == "decimal" {
+		return c.unscaled(a).Cmp(c.unscaled(b)) < 0
+	}
+	switch x := a.(type) {
+	case int64:
+		y, _ := b.(int64)
+		return x < y
+	case uint64:
+		y, _ := b.(uint64)
+		return x < y
+	case float64:
+		y, _ := b.(float64)
+		return x < y
+	case bool:
+		y, _ := b.(bool)
+		return !x && y
+	case time.Time:
+		y, _ := b.(time.Time)
+		return x.Before(y)
+	case []byte:
+		y, _ := b.([]byte)
+		return bytes.Compare(x, y) < 0
+	}
+	return false
+}
+
+// unscaled returns the unscaled value of a decimal, stored as an integer
+// or as big-endian two's complement bytes
+func (c *parquetColumn) unscaled(value any) *big.Int {
+	switch v := value.(type) {
+	case int64:
+		return big.NewInt(v)
+	case []byte:
+		n := new(big.Int).SetBytes(v)
+		if len(v) > 0 && v[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*len(v))))
+		}
+		return n
+	}
+	return new(big.Int)
+}
+
+// format writes a value as its logical type reads
+func (c *parquetColumn) format(value any) string {
+	switch c.kind {
+	case "decimal":
+		digits := c.unscaled(value).String()
+		if c.scale <= 0 {
+			return digits
+		}
+		sign := ""
+		if strings.HasPrefix(digits, "-") {
+			sign, digits = "-", digits[1:]
+		}
+		if len(digits) <= c.scale {
+			digits = strings.Repeat("0", c.scale-len(digits)+1) + digits
+		}
+		return sign + digits[:len(digits)-c.scale] + "." + digits[len(digits)-c.scale:]
+	case "date":
+		if days, ok := value.(int64); ok {
+			return time.Unix(days*secondsPerDay, 0).UTC().Format(time.DateOnly)
+		}
+	case "timestamp":
+		if v, ok := value.(int64); ok && c.unit > 0 {
+			return time.Unix(v/c.unit, v%c.unit*(1_000_000_000/c.unit)).UTC().Format(time.RFC3339Nano)
+		}
+	case "time":
+		if v, ok := value.(int64); ok && c.unit > 0 {
+			return time.Time{}.Add(time.Duration(v * (1_000_000_000 / c.unit))).Format("15:04:05.999999999")
+		}
+	case "uuid":
+		if v, ok := value.([]byte); ok && len(v) == 16 {
+			s := hex.EncodeToString(v)
+			return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+		}
+	}
+
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		if c.kind == "string" || utf8.Valid(v) && printable(v) {
+			return clip(strings.ToValidUTF8(string(v), ""))
+		}
+		return fmt.Sprintf("(%d-byte binary)", len(v))
+	}
+	return ""
+}
+
+// printable reports whether bytes not annotated as text read as text
+func printable(b []byte) bool {
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// hybrid decodes count values of the RLE and bit-packing hybrid encoding
+// Parquet stores levels and dictionary indexes in
+func hybrid(data []byte, width, count int) ([]int, error) {
+	if width < 0 || width > 32 {
+		return nil, errThrift
+	}
+	values := make([]int, 0, count)
+	for pos := 0; len(values) < count; {
+		header, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return nil, errThrift
+		}
+		pos += n
+		if header&1 == 0 {
+			// A run of one value
+			size := (width + 7) / 8
+			if pos+size > len(data) {
+				return nil, errThrift
+			}
+			value := 0
+			for i := size - 1; i >= 0; i-- {
+				value = value<<8 | int(data[pos+i])
+			}
+			pos += size
+			for run := int(header >> 1); run > 0 && len(values) < count; run-- {
+				values = append(values, value)
+			}
+			continue
+		}
+		// Groups of eight values packed from the least significant bit
+		groups := int(header >> 1)
+		if groups > len(data) || pos+groups*width > len(data) {
+			return nil, errThrift
+		}
+		packed := data[pos : pos+groups*width]
+		pos += groups * width
+		for i := 0; i < groups*8 && len(values) < count; i++ {
+			value := 0
+			for b := 0; b < width; b++ {
+				bit := i*width + b
+				value |= int(packed[bit/8]>>(bit%8)&1) << b
+			}
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// bitWidth is the number of bits levels up to max are packed in
+func bitWidth(max int) int {
+	width := 0
+	for ; max > 0; max >>= 1 {
+		width++
+	}
+	return width
+}
+
+// lengthPrefixed splits data after the RLE data its four first bytes give
+// the length of
+func lengthPrefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, errThrift
+	}
+	length := int(binary.LittleEndian.Uint32(data))
+	if length < 0 || 4+length > len(data) {
+		return nil, nil, errThrift
+	}
+	return data[4 : 4+length], data[4+length:], nil
+}
+
+// decompress decompresses a page to its uncompressed size
+func decompress(codec int64, data []byte, size int64) ([]byte, error) {
+	if size < 0 || size > maxPageBytes {
+		return nil, fmt.Errorf("page of %d bytes is too large to sample", size)
+	}
+	switch codec {
+	case codecSnappy:
+		return unsnappy(data, int(size))
+	case codecGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress page: %w", err)
+		}
+		out, err := io.ReadAll(io.LimitReader(reader, size))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress page: %w", err)
+		}
+		return out, nil
+	}
+	return data, nil
+}
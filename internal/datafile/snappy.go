@@ -0,0 +1,78 @@
+package datafile
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errSnappy = errors.New("invalid Snappy data")
+
+// unsnappy decompresses a Snappy block, the format Parquet pages are
+// compressed in by default: the decompressed length, then literals and
+// copies of earlier output
+func unsnappy(src []byte, limit int) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 || length > uint64(limit) {
+		return nil, errSnappy
+	}
+	dst := make([]byte, 0, length)
+	for pos := n; pos < len(src); {
+		tag := src[pos]
+		pos++
+		var size, offset int
+		switch tag & 0x03 {
+		case 0: // literal
+			size = int(tag >> 2)
+			if size >= 60 {
+				extra := size - 59
+				if pos+extra > len(src) {
+					return nil, errSnappy
+				}
+				size = 0
+				for i := extra - 1; i >= 0; i-- {
+					size = size<<8 | int(src[pos+i])
+				}
+				pos += extra
+			}
+			size++
+			if size > len(src)-pos || len(dst)+size > int(length) {
+				return nil, errSnappy
+			}
+			dst = append(dst, src[pos:pos+size]...)
+			pos += size
+			continue
+		case 1:
+			if pos+1 > len(src) {
+				return nil, errSnappy
+			}
+			size = 4 + int(tag>>2&0x07)
+			offset = int(tag&0xe0)<<3 | int(src[pos])
+			pos++
+		case 2:
+			if pos+2 > len(src) {
+				return nil, errSnappy
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[pos:]))
+			pos += 2
+		case 3:
+			if pos+4 > len(src) {
+				return nil, errSnappy
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[pos:]))
+			pos += 4
+		}
+		if offset <= 0 || offset > len(dst) || len(dst)+size > int(length) {
+			return nil, errSnappy
+		}
+		// Copies may overlap the bytes they append
+		for i := 0; i < size; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	if len(dst) != int(length) {
+		return nil, errSnappy
+	}
+	return dst, nil
+}
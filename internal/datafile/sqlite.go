@@ -0,0 +1,595 @@
+package datafile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// SQLite databases are read from their file format, described at
+// https://www.sqlite.org/fileformat2.html: the schema table on the first
+// page names each table's b-tree, whose leaves hold the rows as records.
+// Changes still in a write-ahead log file are not read.
+const (
+	sqliteHeaderSize = 100
+
+	pageIndexInterior = 0x02
+	pageTableInterior = 0x05
+	pageIndexLeaf     = 0x0a
+	pageTableLeaf     = 0x0d
+
+	// maxRecordBytes bounds how much of a sampled row is read; values past
+	// it, such as large blobs, are cut
+	maxRecordBytes = 64 << 10
+	// maxTreeDepth bounds the depth of b-trees followed, against corrupt files
+	maxTreeDepth = 40
+	// maxDefinition is the longest view query kept
+	maxDefinition = 1000
+)
+
+// errCorrupt is returned for pages that do not hold what the file says
+var errCorrupt = errors.New("corrupt SQLite database")
+
+var (
+	primaryKey     = regexp.MustCompile(`(?i)\bPRIMARY\s+KEY\b`)
+	notNull        = regexp.MustCompile(`(?i)\bNOT\s+NULL\b`)
+	references     = regexp.MustCompile(`(?i)\bREFERENCES\s+("[^"]+"|\[[^\]]+\]|` + "`[^`]+`" + `|[\w.]+)\s*(?:\(([^)]*)\))?`)
+	generated      = regexp.MustCompile(`(?i)\b(?:GENERATED\s+ALWAYS\s+)?AS\s*\(`)
+	stored         = regexp.MustCompile(`(?i)\bSTORED\b`)
+	withoutRowid   = regexp.MustCompile(`(?i)\bWITHOUT\s+ROWID\b`)
+	columnKeywords = regexp.MustCompile(`(?i)\b(?:CONSTRAINT|PRIMARY|NOT|NULL|UNIQUE|CHECK|DEFAULT|COLLATE|REFERENCES|GENERATED|AS)\b`)
+	indexTarget    = regexp.MustCompile(`(?is)\bON\s+(?:"[^"]*"|\[[^\]]*\]|` + "`[^`]*`" + `|[\w.]+)\s*\(`)
+)
+
+// sqliteFile reads the pages of a SQLite database
+type sqliteFile struct {
+	r        io.ReaderAt
+	pageSize int
+	usable   int // page bytes not reserved for extensions
+	pages    uint32
+	encoding uint32 // text encoding: 1 UTF-8, 2 UTF-16le, 3 UTF-16be
+}
+
+// blob is a sampled blob value, of which only the size is written
+type blob int
+
+func readSQLite(r io.ReaderAt, size int64, sampleRows int) (*Info, error) {
+	header := make([]byte, sqliteHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("failed to read SQLite header: %w", err)
+	}
+	pageSize := int(binary.BigEndian.Uint16(header[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 || int(header[20]) >= pageSize-480 {
+		return nil, fmt.Errorf("%w: invalid page size %d", errCorrupt, pageSize)
+	}
+	db := &sqliteFile{
+		r:        r,
+		pageSize: pageSize,
+		usable:   pageSize - int(header[20]),
+		pages:    uint32(size / int64(pageSize)),
+		encoding: binary.BigEndian.Uint32(header[56:60]),
+	}
+
+	// The schema table: type, name, table name, root page, SQL
+	var objects [][]any
+	if _, err := db.scan(1, -1, func(_ int64, record []any) {
+		if len(record) >= 5 {
+			objects = append(objects, record)
+		}
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read SQLite schema: %w", err)
+	}
+
+	info := &Info{Format: FormatSQLite}
+	tables := make(map[string]int)
+	for _, object := range objects {
+		kind, name, sql := text(object[0]), text(object[1]), text(object[4])
+		root, _ := object[3].(int64)
+		switch {
+		case strings.HasPrefix(name, "sqlite_"):
+			// Internal tables, such as sqlite_sequence
+		case kind == "table":
+			tables[name] = len(info.Tables)
+			info.Tables = append(info.Tables, db.readTable(name, sql, root, sampleRows))
+		case kind == "view":
+			definition := oneLine(sql)
+			if len(definition) > maxDefinition {
+				definition = strings.ToValidUTF8(definition[:maxDefinition], "") + "..."
+			}
+			info.Tables = append(info.Tables, Table{Name: name, Kind: "view", Definition: definition})
+		}
+	}
+	for _, object := range objects {
+		// Indexes SQLite creates for constraints have no SQL
+		if text(object[0]) != "index" || text(object[4]) == "" {
+			continue
+		}
+		if i, ok := tables[text(object[2])]; ok {
+			info.Tables[i].Indexes = append(info.Tables[i].Indexes, indexDescription(text(object[1]), text(object[4])))
+		}
+	}
+	return info, nil
+}
+
+// readTable counts the rows of a table and samples the first of them.
+// Rows that cannot be read are noted on the table, which keeps its schema.
+func (db *sqliteFile) readTable(name, sql string, root int64, sampleRows int) Table {
+	columns, storage, rowid, noRowid := parseCreateTable(sql)
+	table := Table{Name: name, Kind: "table", Columns: columns}
+	if root <= 0 || root > math.MaxUint32 {
+		// Virtual tables, such as full-text indexes, keep rows elsewhere
+		table.Note = "virtual table, rows are not read"
+		return table
+	}
+	limit := sampleRows
+	if noRowid && sampleRows > 0 {
+		// Rows of WITHOUT ROWID tables are stored in key order, not
+		// column order
+		limit = 0
+		table.Note = "WITHOUT ROWID table, rows are not sampled"
+	}
+
+	rows, err := db.scan(uint32(root), limit, func(id int64, record []any) {
+		row := make([]string, len(columns))
+		for i, value := range record {
+			if i < len(storage) {
+				row[storage[i]] = format(value)
+			}
+		}
+		if rowid >= 0 {
+			row[rowid] = strconv.FormatInt(id, 10)
+		}
+		table.Sample = append(table.Sample, row)
+	})
+	table.Rows = rows
+	if err != nil {
+		table.Note = "rows could not be read: " + err.Error()
+	}
+	return table
+}
+
+// page reads page n, counted from 1
+func (db *sqliteFile) page(n uint32) ([]byte, error) {
+	if n == 0 || n > db.pages {
+		return nil, fmt.Errorf("%w: page %d out of range", errCorrupt, n)
+	}
+	page := make([]byte, db.pageSize)
+	if _, err := db.r.ReadAt(page, int64(n-1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("failed to read page %d: %w", n, err)
+	}
+	return page, nil
+}
+
+// scan walks the b-tree rooted at page root in key order, counting its
+// rows. The records of the first limit rows of a table b-tree are passed
+// to fn with their row IDs, and those of all rows when limit is negative.
+func (db *sqliteFile) scan(root uint32, limit int, fn func(rowid int64, record []any)) (int64, error) {
+	s := &sqliteScan{db: db, limit: limit, fn: fn, visited: make(map[uint32]bool)}
+	err := s.walk(root, 0)
+	return s.rows, err
+}
+
+type sqliteScan struct {
+	db      *sqliteFile
+	limit   int
+	fn      func(int64, []any)
+	rows    int64
+	sampled int
+	visited map[uint32]bool // against pages linked twice in corrupt files
+}
+
+func (s *sqliteScan) walk(n uint32, depth int) error {
+	if s.visited[n] || depth > maxTreeDepth {
+		return fmt.Errorf("%w: b-tree loops at page %d", errCorrupt, n)
+	}
+	s.visited[n] = true
+	page, err := s.db.page(n)
+	if err != nil {
+		return err
+	}
+	hdr := 0
+	if n == 1 {
+		hdr = sqliteHeaderSize
+	}
+	cells := int(binary.BigEndian.Uint16(page[hdr+3:]))
+
+	switch page[hdr] {
+	case pageTableLeaf, pageIndexLeaf:
+		s.rows += int64(cells)
+		if page[hdr] == pageIndexLeaf {
+			return nil
+		}
+		for i := 0; i < cells && (s.limit < 0 || s.sampled < s.limit); i++ {
+			off, err := cellOffset(page, hdr+8, i)
+			if err != nil {
+				return err
+			}
+			rowid, record, err := s.db.tableCell(page, off)
+			if err != nil {
+				return err
+			}
+			s.fn(rowid, record)
+			s.sampled++
+		}
+		return nil
+
+	case pageTableInterior, pageIndexInterior:
+		for i := 0; i < cells; i++ {
+			off, err := cellOffset(page, hdr+12, i)
+			if err != nil {
+				return err
+			}
+			if off+4 > len(page) {
+				return errCorrupt
+			}
+			if err := s.walk(binary.BigEndian.Uint32(page[off:]), depth+1); err != nil {
+				return err
+			}
+			if page[hdr] == pageIndexInterior {
+				// Index b-trees keep rows in their interior cells too
+				s.rows++
+			}
+		}
+		return s.walk(binary.BigEndian.Uint32(page[hdr+8:]), depth+1)
+	}
+	return fmt.Errorf("%w: page %d is not a b-tree page", errCorrupt, n)
+}
+
+// cellOffset returns the offset of cell i of a page whose cell pointers
+// start at ptrs
+func cellOffset(page []byte, ptrs, i int) (int, error) {
+	if ptrs+2*i+2 > len(page) {
+		return 0, errCorrupt
+	}
+	off := int(binary.BigEndian.Uint16(page[ptrs+2*i:]))
+	if off >= len(page) {
+		return 0, errCorrupt
+	}
+	return off, nil
+}
+
+// tableCell reads the row ID and record of a table leaf cell, following
+// the overflow pages of records larger than a page
+func (db *sqliteFile) tableCell(page []byte, off int) (int64, []any, error) {
+	size, n := varint(page[off:])
+	if n == 0 {
+		return 0, nil, errCorrupt
+	}
+	off += n
+	rowid, n := varint(page[off:])
+	if n == 0 {
+		return 0, nil, errCorrupt
+	}
+	off += n
+
+	// How much of the record is on the page, as the file format defines it
+	u, p := int64(db.usable), int64(size)
+	local := p
+	if p > u-35 {
+		m := (u-12)*32/255 - 23
+		local = m + (p-m)%(u-4)
+		if local > u-35 {
+			local = m
+		}
+	}
+	if int64(off)+local > int64(len(page)) {
+		return 0, nil, errCorrupt
+	}
+	want := min(p, maxRecordBytes)
+	payload := append([]byte(nil), page[off:off+int(min(local, want))]...)
+	if local < p && off+int(local)+4 <= len(page) {
+		next := binary.BigEndian.Uint32(page[off+int(local):])
+		for hops := 0; int64(len(payload)) < want && next != 0 && hops < maxRecordBytes; hops++ {
+			overflow, err := db.page(next)
+			if err != nil {
+				return 0, nil, err
+			}
+			next = binary.BigEndian.Uint32(overflow)
+			chunk := overflow[4:db.usable]
+			payload = append(payload, chunk[:min(int64(len(chunk)), want-int64(len(payload)))]...)
+		}
+	}
+	return int64(rowid), db.record(payload), nil
+}
+
+// record decodes the values of a record: nil, int64, float64, string or
+// blob. Values past the end of a cut record are nil, or cut themselves.
+func (db *sqliteFile) record(payload []byte) []any {
+	headerSize, n := varint(payload)
+	if n == 0 || headerSize > uint64(len(payload)) {
+		return nil
+	}
+	var types []uint64
+	for pos := n; pos < int(headerSize); {
+		t, k := varint(payload[pos:int(headerSize)])
+		if k == 0 {
+			break
+		}
+		types = append(types, t)
+		pos += k
+	}
+
+	body := payload[headerSize:]
+	values := make([]any, len(types))
+	for i, t := range types {
+		size := 0
+		switch {
+		case t >= 12:
+			size = int((t - 12) / 2)
+		case t == 7:
+			size = 8
+		case t <= 6:
+			size = []int{0, 1, 2, 3, 4, 6, 8}[t]
+		}
+		data := body[:min(size, len(body))]
+		body = body[len(data):]
+
+		switch {
+		case t == 8, t == 9:
+			values[i] = int64(t - 8)
+		case len(data) < size && t < 12:
+			// cut off
+		case t >= 1 && t <= 6:
+			v := int64(0)
+			for _, b := range data {
+				v = v<<8 | int64(b)
+			}
+			shift := 64 - 8*size
+			values[i] = v << shift >> shift
+		case t == 7:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(data))
+		case t >= 12 && t%2 == 0:
+			values[i] = blob(size)
+		case t >= 13:
+			values[i] = db.text(data)
+		}
+	}
+	return values
+}
+
+// text decodes a text value in the database's encoding
+func (db *sqliteFile) text(data []byte) string {
+	var order binary.ByteOrder
+	switch db.encoding {
+	case 2:
+		order = binary.LittleEndian
+	case 3:
+		order = binary.BigEndian
+	default:
+		return strings.ToValidUTF8(string(data), "")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// varint reads a SQLite variable-length integer, returning its length, or
+// zero when buf ends before it does
+func varint(buf []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(buf); i++ {
+		if i == 8 {
+			return v<<8 | uint64(buf[i]), 9
+		}
+		v = v<<7 | uint64(buf[i]&0x7f)
+		if buf[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+func text(value any) string {
+	s, _ := value.(string)
+	return s
+}
+
+// format writes a sampled value
+func format(value any) string {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return clip(v)
+	case blob:
+		return fmt.Sprintf("(%d-byte blob)", int(v))
+	}
+	return ""
+}
+
+// parseCreateTable reads the columns of a CREATE TABLE statement. storage
+// maps the values of the table's records to columns, as generated columns
+// that are not stored have none; rowid is the column aliasing the row ID,
+// or -1.
+func parseCreateTable(sql string) (columns []Column, storage []int, rowid int, noRowid bool) {
+	rowid = -1
+	open := strings.IndexByte(sql, '(')
+	if open < 0 {
+		return nil, nil, rowid, false
+	}
+	definitions, end := splitDefinitions(sql, open+1)
+	noRowid = withoutRowid.MatchString(sql[end:])
+
+	index := func(name string) int {
+		for i, column := range columns {
+			if strings.EqualFold(column.Name, name) {
+				return i
+			}
+		}
+		return -1
+	}
+	var tablePK []string
+	for _, def := range definitions {
+		keyword := strings.ToUpper(strings.Fields(def + " x")[0])
+		switch keyword {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			if keyword == "CONSTRAINT" {
+				_, def = sqlName(strings.TrimSpace(def[len(keyword):]))
+			}
+			open := strings.IndexByte(def, '(')
+			if open < 0 {
+				continue
+			}
+			names, end := splitDefinitions(def, open+1)
+			switch {
+			case primaryKey.MatchString(def[:open]):
+				for _, name := range names {
+					name, _ = sqlName(name)
+					tablePK = append(tablePK, name)
+					if i := index(name); i >= 0 {
+						columns[i].PrimaryKey = true
+					}
+				}
+			case strings.HasPrefix(strings.ToUpper(strings.TrimSpace(def)), "FOREIGN"):
+				m := references.FindStringSubmatch(def[end:])
+				if m == nil {
+					continue
+				}
+				target, _ := sqlName(m[1])
+				targets := strings.Split(m[2], ",")
+				for k, name := range names {
+					name, _ = sqlName(name)
+					if i := index(name); i >= 0 {
+						columns[i].References = reference(target, targets, k)
+					}
+				}
+			}
+
+		default:
+			name, rest := sqlName(def)
+			column := Column{Name: name}
+			typ := rest
+			if loc := columnKeywords.FindStringIndex(rest); loc != nil {
+				typ = rest[:loc[0]]
+			}
+			column.Type = oneLine(typ)
+			column.PrimaryKey = primaryKey.MatchString(rest)
+			column.NotNull = notNull.MatchString(rest)
+			if m := references.FindStringSubmatch(rest); m != nil {
+				target, _ := sqlName(m[1])
+				column.References = reference(target, strings.Split(m[2], ","), 0)
+			}
+			if column.PrimaryKey && strings.EqualFold(column.Type, "INTEGER") && !noRowid {
+				rowid = len(columns)
+			}
+			if !generated.MatchString(rest) || stored.MatchString(rest) {
+				storage = append(storage, len(columns))
+			}
+			columns = append(columns, column)
+		}
+	}
+	if len(tablePK) == 1 && rowid < 0 && !noRowid {
+		if i := index(tablePK[0]); i >= 0 && strings.EqualFold(columns[i].Type, "INTEGER") {
+			rowid = i
+		}
+	}
+	return columns, storage, rowid, noRowid
+}
+
+// reference writes the target of foreign key column k as table(column)
+func reference(table string, columns []string, k int) string {
+	if k < len(columns) {
+		if column, _ := sqlName(columns[k]); column != "" {
+			return table + "(" + column + ")"
+		}
+	}
+	return table
+}
+
+// indexDescription writes an index as its name and columns, such as
+// "idx_orders_customer (customer_id, created_at), unique"
+func indexDescription(name, sql string) string {
+	loc := indexTarget.FindStringIndex(sql)
+	if loc == nil {
+		return name
+	}
+	columns, _ := splitDefinitions(sql, loc[1])
+	description := name + " (" + strings.Join(columns, ", ") + ")"
+	if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(sql)), "CREATE UNIQUE") {
+		description += ", unique"
+	}
+	return description
+}
+
+// splitDefinitions splits the comma-separated list that starts at start,
+// right after an opening parenthesis, and returns it with the offset past
+// the closing parenthesis
+func splitDefinitions(s string, start int) ([]string, int) {
+	var items []string
+	depth := 0
+	item := start
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\'', '"', '`', '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			if end := strings.IndexByte(s[i+1:], closing); end >= 0 {
+				i += end + 1
+			}
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				if part := oneLine(s[item:i]); part != "" {
+					items = append(items, part)
+				}
+				return items, i + 1
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, oneLine(s[item:i]))
+				item = i + 1
+			}
+		case '-':
+			if i+1 < len(s) && s[i+1] == '-' {
+				// A comment up to the end of the line
+				if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+					s = s[:i] + strings.Repeat(" ", end) + s[i+end:]
+				}
+			}
+		}
+	}
+	if part := oneLine(s[item:]); part != "" {
+		items = append(items, part)
+	}
+	return items, len(s)
+}
+
+// sqlName reads the name starting s, unquoting it, and returns it with
+// what follows
+func sqlName(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ""
+	}
+	if closing := map[byte]byte{'"': '"', '`': '`', '[': ']', '\'': '\''}[s[0]]; closing != 0 {
+		if end := strings.IndexByte(s[1:], closing); end >= 0 {
+			return s[1 : end+1], s[end+2:]
+		}
+	}
+	end := strings.IndexAny(s, " \t\r\n(")
+	if end < 0 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,230 @@
+package datafile
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Parquet metadata is serialized with the Thrift compact protocol. It is
+// decoded into generic values: structs as field maps, lists as slices,
+// integers as int64, strings and binaries as []byte.
+
+// Types of the compact protocol
+const (
+	ctStop   = 0
+	ctTrue   = 1
+	ctFalse  = 2
+	ctByte   = 3
+	ctI16    = 4
+	ctI32    = 5
+	ctI64    = 6
+	ctDouble = 7
+	ctBinary = 8
+	ctList   = 9
+	ctSet    = 10
+	ctMap    = 11
+	ctStruct = 12
+)
+
+// maxThriftDepth bounds the nesting of decoded structs and lists
+const maxThriftDepth = 32
+
+var errThrift = errors.New("invalid Parquet metadata")
+
+// thriftStruct is a decoded struct, by field ID
+type thriftStruct map[int16]any
+
+func (s thriftStruct) int(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStruct) has(id int16) bool {
+	_, ok := s[id]
+	return ok
+}
+
+func (s thriftStruct) bytes(id int16) []byte {
+	v, _ := s[id].([]byte)
+	return v
+}
+
+func (s thriftStruct) string(id int16) string {
+	return string(s.bytes(id))
+}
+
+func (s thriftStruct) bool(id int16, fallback bool) bool {
+	if v, ok := s[id].(bool); ok {
+		return v
+	}
+	return fallback
+}
+
+func (s thriftStruct) child(id int16) thriftStruct {
+	v, _ := s[id].(thriftStruct)
+	return v
+}
+
+func (s thriftStruct) list(id int16) []any {
+	v, _ := s[id].([]any)
+	return v
+}
+
+// structs returns the elements of a list of structs
+func (s thriftStruct) structs(id int16) []thriftStruct {
+	var out []thriftStruct
+	for _, v := range s.list(id) {
+		if elem, ok := v.(thriftStruct); ok {
+			out = append(out, elem)
+		}
+	}
+	return out
+}
+
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+// readStruct decodes a struct, leaving the reader after it
+func (r *thriftReader) readStruct(depth int) (thriftStruct, error) {
+	if depth > maxThriftDepth {
+		return nil, errThrift
+	}
+	s := make(thriftStruct)
+	var id int16
+	for {
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		typ := header & 0x0f
+		if typ == ctStop {
+			return s, nil
+		}
+		if delta := header >> 4; delta != 0 {
+			id += int16(delta)
+		} else {
+			v, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(zigzag(v))
+		}
+		switch typ {
+		case ctTrue:
+			s[id] = true
+		case ctFalse:
+			s[id] = false
+		default:
+			if s[id], err = r.value(typ, depth); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// value decodes a value of a type other than a struct field's boolean,
+// which is part of the field header
+func (r *thriftReader) value(typ byte, depth int) (any, error) {
+	switch typ {
+	case ctTrue, ctFalse:
+		// Booleans of lists are a byte each
+		b, err := r.byte()
+		return b == ctTrue, err
+	case ctByte:
+		b, err := r.byte()
+		return int64(int8(b)), err
+	case ctI16, ctI32, ctI64:
+		v, err := r.varint()
+		return zigzag(v), err
+	case ctDouble:
+		if r.pos+8 > len(r.buf) {
+			return nil, errThrift
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.buf[r.pos:]))
+		r.pos += 8
+		return v, nil
+	case ctBinary:
+		n, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(r.buf)-r.pos) {
+			return nil, errThrift
+		}
+		v := r.buf[r.pos : r.pos+int(n)]
+		r.pos += int(n)
+		return v, nil
+	case ctList, ctSet:
+		header, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = r.varint(); err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(len(r.buf)-r.pos) {
+			// Every element takes at least a byte
+			return nil, errThrift
+		}
+		list := make([]any, 0, size)
+		for i := uint64(0); i < size; i++ {
+			v, err := r.value(header&0x0f, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case ctMap:
+		size, err := r.varint()
+		if err != nil || size == 0 {
+			return nil, err
+		}
+		types, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < size; i++ {
+			// Maps are not used by Parquet metadata; skip them
+			if _, err := r.value(types>>4, depth+1); err != nil {
+				return nil, err
+			}
+			if _, err := r.value(types&0x0f, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case ctStruct:
+		return r.readStruct(depth + 1)
+	}
+	return nil, errThrift
+}
+
+func (r *thriftReader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errThrift
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+// varint reads an unsigned LEB128 integer
+func (r *thriftReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, errThrift
+	}
+	r.pos += n
+	return v, nil
+}
+
+func zigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
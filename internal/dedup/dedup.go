@@ -0,0 +1,235 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+)
+
+// shingleSize is the number of words per shingle used for MinHash signatures
+const shingleSize = 3
+
+// Entry identifies the canonical vector stored for a piece of content
+type Entry struct {
+	VectorID    string
+	DocumentID  string
+	FilePath    string // file of the document storing the vector
+	ContentHash string
+	Scope       string
+	Signature   []uint64
+}
+
+// Index tracks chunk content hashes and MinHash signatures seen during indexing
+type Index struct {
+	mu        sync.RWMutex
+	exact     map[string]*Entry
+	buckets   map[string][]*Entry
+	hasher    *MinHasher
+	bands     int
+	threshold float64
+}
+
+// NewIndex creates a new dedup index. Near-duplicate detection is enabled
+// when permutations and bands are both positive.
+func NewIndex(permutations, bands int, threshold float64) *Index {
+	idx := &Index{
+		exact:     make(map[string]*Entry),
+		buckets:   make(map[string][]*Entry),
+		bands:     bands,
+		threshold: threshold,
+	}
+	if permutations > 0 && bands > 0 {
+		idx.hasher = NewMinHasher(permutations)
+	}
+	return idx
+}
+
+// NearDuplicateEnabled reports whether the index performs MinHash lookups
+func (idx *Index) NearDuplicateEnabled() bool {
+	return idx.hasher != nil
+}
+
+// Signature computes the MinHash signature for text, or nil when
+// near-duplicate detection is disabled
+func (idx *Index) Signature(text string) []uint64 {
+	if idx.hasher == nil {
+		return nil
+	}
+	return idx.hasher.Signature(text)
+}
+
+// Lookup returns the canonical entry for an exact hash match or, when a
+// signature is given, the most similar near-duplicate in the same scope
+// above the threshold. Entries for which skip returns true are passed
+// over; skip may be nil.
+func (idx *Index) Lookup(contentHash, scope string, signature []uint64, skip func(*Entry) bool) (*Entry, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if entry, ok := idx.exact[contentHash]; ok && (skip == nil || !skip(entry)) {
+		return entry, true
+	}
+
+	if signature == nil {
+		return nil, false
+	}
+
+	var best *Entry
+	bestScore := 0.0
+	for _, key := range idx.bandKeys(signature) {
+		for _, candidate := range idx.buckets[key] {
+			if candidate.Scope != scope || skip != nil && skip(candidate) {
+				continue
+			}
+			score := Similarity(signature, candidate.Signature)
+			if score >= idx.threshold && score > bestScore {
+				best = candidate
+				bestScore = score
+			}
+		}
+	}
+
+	return best, best != nil
+}
+
+// Add registers a canonical entry in the index. An entry replaces the one
+// of an earlier version of the same file, whose vectors are superseded.
+func (idx *Index) Add(entry *Entry) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	existing, ok := idx.exact[entry.ContentHash]
+	if !ok || entry.FilePath != "" && existing.FilePath == entry.FilePath {
+		idx.exact[entry.ContentHash] = entry
+	}
+
+	if entry.Signature == nil {
+		return
+	}
+	for _, key := range idx.bandKeys(entry.Signature) {
+		idx.buckets[key] = append(idx.buckets[key], entry)
+	}
+}
+
+// Len returns the number of distinct content hashes in the index
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.exact)
+}
+
+// bandKeys splits a signature into LSH band keys
+func (idx *Index) bandKeys(signature []uint64) []string {
+	rows := len(signature) / idx.bands
+	keys := make([]string, 0, idx.bands)
+	for b := 0; b < idx.bands; b++ {
+		h := fnv.New64a()
+		for _, v := range signature[b*rows : (b+1)*rows] {
+			var buf [8]byte
+			for i := 0; i < 8; i++ {
+				buf[i] = byte(v >> (8 * i))
+			}
+			h.Write(buf[:]) //nolint:errcheck
+		}
+		keys = append(keys, fmt.Sprintf("%d:%x", b, h.Sum64()))
+	}
+	return keys
+}
+
+// ContentHash returns a whitespace-insensitive SHA-256 hash of chunk content
+func ContentHash(text string) string {
+	return ScopedContentHash("", text)
+}
+
+// ScopedContentHash hashes chunk content within a scope, so identical content
+// in different scopes (e.g. documents with different access control) is never
+// treated as a duplicate. An empty scope yields the same hash as ContentHash.
+func ScopedContentHash(scope, text string) string {
+	normalized := strings.Join(strings.Fields(text), " ")
+	if scope != "" {
+		normalized = scope + "\x00" + normalized
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(normalized)))
+}
+
+// MinHasher computes MinHash signatures over word shingles
+type MinHasher struct {
+	seeds []uint64
+}
+
+// NewMinHasher creates a MinHasher with the given number of permutations
+func NewMinHasher(permutations int) *MinHasher {
+	seeds := make([]uint64, permutations)
+	// splitmix64 gives well-distributed, deterministic seeds so signatures
+	// stay comparable across process restarts
+	state := uint64(0x9E3779B97F4A7C15)
+	for i := range seeds {
+		state += 0x9E3779B97F4A7C15
+		z := state
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		seeds[i] = z ^ (z >> 31)
+	}
+	return &MinHasher{seeds: seeds}
+}
+
+// Signature computes the MinHash signature of text
+func (m *MinHasher) Signature(text string) []uint64 {
+	signature := make([]uint64, len(m.seeds))
+	for i := range signature {
+		signature[i] = math.MaxUint64
+	}
+
+	for _, shingle := range shingles(text) {
+		h := fnv.New64a()
+		h.Write([]byte(shingle)) //nolint:errcheck
+		base := h.Sum64()
+		for i, seed := range m.seeds {
+			v := mix(base ^ seed)
+			if v < signature[i] {
+				signature[i] = v
+			}
+		}
+	}
+
+	return signature
+}
+
+// Similarity estimates the Jaccard similarity of two MinHash signatures
+func Similarity(a, b []uint64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	matches := 0
+	for i := range a {
+		if a[i] == b[i] {
+			matches++
+		}
+	}
+	return float64(matches) / float64(len(a))
+}
+
+func shingles(text string) []string {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) < shingleSize {
+		if len(words) == 0 {
+			return nil
+		}
+		return []string{strings.Join(words, " ")}
+	}
+
+	result := make([]string, 0, len(words)-shingleSize+1)
+	for i := 0; i+shingleSize <= len(words); i++ {
+		result = append(result, strings.Join(words[i:i+shingleSize], " "))
+	}
+	return result
+}
+
+func mix(z uint64) uint64 {
+	z = (z ^ (z >> 33)) * 0xFF51AFD7ED558CCD
+	z = (z ^ (z >> 33)) * 0xC4CEB3CA1A85EC53
+	return z ^ (z >> 33)
+}
@@ -0,0 +1,196 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+)
+
+// webhookPayload is the JSON body posted to a report's webhook. The
+// rendered digest is in "text", which chat incoming webhooks display.
+type webhookPayload struct {
+	Report      string    `json:"report"`
+	Subject     string    `json:"subject"`
+	Format      string    `json:"format"`
+	Text        string    `json:"text"`
+	Since       time.Time `json:"since"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// deliveryTimeout bounds a webhook call and an SMTP session, from dialing
+// the server to its reply to the message
+const deliveryTimeout = 30 * time.Second
+
+// deliverer sends rendered digests
+type deliverer struct {
+	smtp       config.DigestConfig
+	httpClient *http.Client
+}
+
+func newDeliverer(cfg config.DigestConfig) *deliverer {
+	return &deliverer{smtp: cfg, httpClient: &http.Client{Timeout: deliveryTimeout}}
+}
+
+// deliver sends the digest to every destination of the report, returning
+// an error naming each destination that failed
+func (d *deliverer) deliver(ctx context.Context, r *Report, digest *Digest, body string) error {
+	var errs []string
+	if r.Deliver.Webhook != "" {
+		if err := d.postWebhook(ctx, r, digest, body); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(r.Deliver.Email) > 0 {
+		if err := d.sendEmail(ctx, r, digest, body); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to deliver digest: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// postWebhook posts the digest as JSON
+func (d *deliverer) postWebhook(ctx context.Context, r *Report, digest *Digest, body string) error {
+	data, err := json.Marshal(webhookPayload{
+		Report:      r.Name,
+		Subject:     digest.Subject(),
+		Format:      r.Format,
+		Text:        body,
+		Since:       digest.Since,
+		GeneratedAt: digest.GeneratedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.Deliver.Webhook, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024)) //nolint:errcheck
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// sendEmail sends the digest to the report's recipients through the
+// configured SMTP server, authenticating when a username is set
+func (d *deliverer) sendEmail(ctx context.Context, r *Report, digest *Digest, body string) error {
+	if d.smtp.SMTPHost == "" || d.smtp.SMTPFrom == "" {
+		return fmt.Errorf("email delivery needs SMTP_HOST and SMTP_FROM")
+	}
+
+	contentType := "text/plain"
+	if r.Format == FormatHTML {
+		contentType = "text/html"
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", d.smtp.SMTPFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(r.Deliver.Email, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", digest.Subject()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", digest.GeneratedAt.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&msg)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return fmt.Errorf("failed to encode email: %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return fmt.Errorf("failed to encode email: %w", err)
+	}
+
+	if err := d.sendMail(ctx, r.Deliver.Email, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// sendMail sends a message the way smtp.SendMail does, upgrading to TLS
+// when the server offers it, but bounds the session by deliveryTimeout and
+// ctx so a server that stops answering cannot hold the scheduler
+func (d *deliverer) sendMail(ctx context.Context, to []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+
+	addr := net.JoinHostPort(d.smtp.SMTPHost, strconv.Itoa(d.smtp.SMTPPort))
+	dialer := net.Dialer{Timeout: deliveryTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close() //nolint:errcheck
+		return err
+	}
+	// Cancelling ctx ends the session at once rather than at the deadline
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close() //nolint:errcheck
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, d.smtp.SMTPHost)
+	if err != nil {
+		conn.Close() //nolint:errcheck
+		return err
+	}
+	defer client.Close() //nolint:errcheck
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: d.smtp.SMTPHost, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if d.smtp.SMTPUsername != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp server does not support authentication")
+		}
+		if err := client.Auth(smtp.PlainAuth("", d.smtp.SMTPUsername, d.smtp.SMTPPassword, d.smtp.SMTPHost)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(d.smtp.SMTPFrom); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
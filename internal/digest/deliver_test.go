@@ -0,0 +1,44 @@
+package digest
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+)
+
+func TestSendMailGivesUpOnStalledServer(t *testing.T) {
+	// The server accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close() //nolint:errcheck
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close() //nolint:errcheck
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String()) //nolint:errcheck
+	portNumber, _ := strconv.Atoi(port)                          //nolint:errcheck
+	d := newDeliverer(config.DigestConfig{SMTPHost: host, SMTPPort: portNumber, SMTPFrom: "digest@example.com"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = d.sendMail(ctx, []string{"ops@example.com"}, []byte("Subject: test\r\n\r\nbody"))
+	if err == nil {
+		t.Fatal("sendMail to a stalled server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("sendMail returned after %v, want it bounded by the context", elapsed)
+	}
+}
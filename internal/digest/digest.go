@@ -0,0 +1,191 @@
+// Package digest generates scheduled reports from saved queries. Each
+// report runs its queries against the query service, lists the documents
+// indexed since the previous run, renders the results as a Markdown or HTML
+// digest and delivers it by webhook or email.
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.yaml.in/yaml/v3"
+)
+
+// Output formats
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Saved query modes
+const (
+	ModeAsk    = "ask"    // answer the question with sources
+	ModeSearch = "search" // list matching chunks only
+)
+
+// ErrNotFound is returned for an unknown report name
+var ErrNotFound = errors.New("report not found")
+
+// Report is a digest definition from the reports file
+type Report struct {
+	Name           string       `yaml:"name" json:"name"`
+	Title          string       `yaml:"title" json:"title"`
+	Schedule       string       `yaml:"schedule" json:"schedule"`
+	Timezone       string       `yaml:"timezone" json:"timezone,omitempty"`
+	Format         string       `yaml:"format" json:"format"`
+	IncludeChanges bool         `yaml:"include_changes" json:"include_changes"`
+	ChangesPath    string       `yaml:"changes_path" json:"changes_path,omitempty"` // only list changes under this path
+	RunAs          RunAs        `yaml:"run_as" json:"run_as"`
+	Queries        []SavedQuery `yaml:"queries" json:"queries"`
+	Deliver        Delivery     `yaml:"deliver" json:"deliver"`
+
+	schedule *Schedule
+}
+
+// RunAs is the identity a report's queries run as, so digests only include
+// documents that identity may read. Without a user only public documents
+// are included.
+type RunAs struct {
+	UserID string   `yaml:"user_id" json:"user_id,omitempty"`
+	Groups []string `yaml:"groups" json:"groups,omitempty"`
+}
+
+// SavedQuery is a query run for every digest of a report
+type SavedQuery struct {
+	Title       string `yaml:"title" json:"title"`
+	Text        string `yaml:"text" json:"text"`
+	Mode        string `yaml:"mode" json:"mode"`
+	TopK        int    `yaml:"top_k" json:"top_k,omitempty"`
+	Collection  string `yaml:"collection" json:"collection,omitempty"`
+	FileType    string `yaml:"file_type" json:"file_type,omitempty"`
+	ChangedOnly bool   `yaml:"changed_only" json:"changed_only,omitempty"` // only chunks indexed since the previous run
+}
+
+// Delivery lists where a report's digests are sent
+type Delivery struct {
+	Webhook string   `yaml:"webhook" json:"webhook,omitempty"`
+	Email   []string `yaml:"email" json:"email,omitempty"`
+}
+
+// LoadReports reads and validates the report definitions of a YAML file
+// with a top-level "reports" list
+func LoadReports(path string) ([]*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reports file: %w", err)
+	}
+
+	var file struct {
+		Reports []*Report `yaml:"reports"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse reports file: %w", err)
+	}
+
+	seen := make(map[string]bool, len(file.Reports))
+	for i, r := range file.Reports {
+		if err := r.init(); err != nil {
+			return nil, fmt.Errorf("report %d: %w", i+1, err)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("report %q is defined twice", r.Name)
+		}
+		seen[r.Name] = true
+	}
+	return file.Reports, nil
+}
+
+// init applies defaults, validates the report and parses its schedule
+func (r *Report) init() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if r.Title == "" {
+		r.Title = r.Name
+	}
+
+	loc := time.Local
+	if r.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(r.Timezone); err != nil {
+			return fmt.Errorf("%s: invalid timezone: %w", r.Name, err)
+		}
+	}
+	schedule, err := ParseSchedule(r.Schedule, loc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.Name, err)
+	}
+	r.schedule = schedule
+
+	r.Format = strings.ToLower(r.Format)
+	switch r.Format {
+	case "":
+		r.Format = FormatMarkdown
+	case FormatMarkdown, FormatHTML:
+	default:
+		return fmt.Errorf("%s: format must be %s or %s", r.Name, FormatMarkdown, FormatHTML)
+	}
+
+	if len(r.Queries) == 0 && !r.IncludeChanges {
+		return fmt.Errorf("%s: a report needs queries or include_changes", r.Name)
+	}
+	for i := range r.Queries {
+		q := &r.Queries[i]
+		if strings.TrimSpace(q.Text) == "" {
+			return fmt.Errorf("%s: query %d has no text", r.Name, i+1)
+		}
+		if q.Title == "" {
+			q.Title = q.Text
+		}
+		switch q.Mode {
+		case "":
+			q.Mode = ModeAsk
+		case ModeAsk, ModeSearch:
+		default:
+			return fmt.Errorf("%s: query %d mode must be %s or %s", r.Name, i+1, ModeAsk, ModeSearch)
+		}
+		if q.TopK <= 0 {
+			q.TopK = 5
+		}
+	}
+	return nil
+}
+
+// Digest is one generated report
+type Digest struct {
+	Report         string     `json:"report"`
+	Title          string     `json:"title"`
+	Since          time.Time  `json:"since"`
+	GeneratedAt    time.Time  `json:"generated_at"`
+	IncludeChanges bool       `json:"include_changes"`
+	Changes        []Change   `json:"changes,omitempty"` // newest first, at most maxChanges
+	TotalChanges   int        `json:"total_changes"`
+	Sections       []*Section `json:"sections"`
+}
+
+// Change is a document indexed since the previous run
+type Change struct {
+	DocumentID string    `json:"document_id"`
+	FilePath   string    `json:"file_path"`
+	Category   string    `json:"category,omitempty"`
+	IndexedAt  time.Time `json:"indexed_at"`
+	Summary    string    `json:"summary,omitempty"`
+}
+
+// Section holds the results of one saved query
+type Section struct {
+	Title   string                `json:"title"`
+	Query   string                `json:"query"`
+	Answer  string                `json:"answer,omitempty"`
+	Sources []models.SearchResult `json:"sources"`
+	Error   string                `json:"error,omitempty"` // the query failed; the rest of the digest is still sent
+}
+
+// Subject is the email subject and webhook title of the digest
+func (d *Digest) Subject() string {
+	return fmt.Sprintf("%s (%s)", d.Title, d.GeneratedAt.Format("2006-01-02"))
+}
@@ -0,0 +1,129 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// snippetLength is how much of a search result the digest shows
+const snippetLength = 300
+
+var templateFuncs = map[string]interface{}{
+	"date": func(t time.Time) string { return t.Format("2006-01-02 15:04 MST") },
+	"day":  func(t time.Time) string { return t.Format("2006-01-02") },
+	"score": func(f float32) string {
+		return fmt.Sprintf("%.2f", f)
+	},
+	"snippet": snippet,
+	"inc":     func(i int) int { return i + 1 },
+	"more":    func(d *Digest) int { return d.TotalChanges - len(d.Changes) },
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(templateFuncs).Parse(`# {{.Title}}
+
+_{{date .Since}} to {{date .GeneratedAt}}_
+{{- if .IncludeChanges}}
+
+## What changed
+{{if .Changes}}
+{{range .Changes}}- **{{.FilePath}}** indexed {{day .IndexedAt}}{{if .Summary}}: {{snippet .Summary}}{{end}}
+{{end}}
+{{- if gt (more .) 0}}- …and {{more .}} more
+{{end}}
+{{- else}}
+No documents were indexed in this period.
+{{end}}
+{{- end}}
+{{- range $sec := .Sections}}
+
+## {{.Title}}
+
+> {{.Query}}
+{{if .Error}}
+_This query failed: {{.Error}}_
+{{else}}{{if .Answer}}
+{{.Answer}}
+{{end}}{{if .Sources}}
+**Sources**
+
+{{range $i, $s := .Sources}}{{inc $i}}. {{$s.FileName}} ({{score $s.Score}}) — {{$s.FilePath}}
+{{if not $sec.Answer}}   {{snippet $s.Content}}
+{{end}}{{end}}{{else}}
+No matching documents.
+{{end}}{{end}}{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif; max-width: 720px;">
+<h1>{{.Title}}</h1>
+<p><em>{{date .Since}} to {{date .GeneratedAt}}</em></p>
+{{- if .IncludeChanges}}
+<h2>What changed</h2>
+{{- if .Changes}}
+<ul>
+{{- range .Changes}}
+<li><strong>{{.FilePath}}</strong> indexed {{day .IndexedAt}}{{if .Summary}}: {{snippet .Summary}}{{end}}</li>
+{{- end}}
+{{- if gt (more .) 0}}
+<li>…and {{more .}} more</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>No documents were indexed in this period.</p>
+{{- end}}
+{{- end}}
+{{- range $sec := .Sections}}
+<h2>{{.Title}}</h2>
+<blockquote>{{.Query}}</blockquote>
+{{- if .Error}}
+<p><em>This query failed: {{.Error}}</em></p>
+{{- else}}
+{{- if .Answer}}
+<p style="white-space: pre-wrap;">{{.Answer}}</p>
+{{- end}}
+{{- if .Sources}}
+<ol>
+{{- range .Sources}}
+<li>{{.FileName}} ({{score .Score}}) — <code>{{.FilePath}}</code>{{if not $sec.Answer}}<br>{{snippet .Content}}{{end}}</li>
+{{- end}}
+</ol>
+{{- else}}
+<p>No matching documents.</p>
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// Render writes the digest in the given format
+func Render(d *Digest, format string) (string, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case FormatMarkdown:
+		err = markdownTemplate.Execute(&buf, d)
+	case FormatHTML:
+		err = htmlTemplate.Execute(&buf, d)
+	default:
+		return "", fmt.Errorf("unknown digest format: %s", format)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to render digest: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// snippet shortens text to a single line
+func snippet(text string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= snippetLength {
+		return string(runes)
+	}
+	return string(runes[:snippetLength]) + "…"
+}
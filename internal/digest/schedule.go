@@ -0,0 +1,110 @@
+package digest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is when a report runs: "every <duration>", "daily HH:MM" or
+// "weekly <day> HH:MM", with times in the report's time zone
+type Schedule struct {
+	text    string
+	every   time.Duration // for "every"; zero for daily and weekly
+	weekday *time.Weekday // for "weekly"
+	hour    int
+	minute  int
+	loc     *time.Location // for daily and weekly
+}
+
+// weekdays maps day names and their three letter forms to weekdays
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseSchedule parses a schedule, taking daily and weekly times in loc
+func ParseSchedule(text string, loc *time.Location) (*Schedule, error) {
+	fields := strings.Fields(strings.ToLower(text))
+	s := &Schedule{text: text, loc: loc}
+	switch {
+	case len(fields) == 2 && fields[0] == "every":
+		d, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", text, err)
+		}
+		if d < time.Minute {
+			return nil, fmt.Errorf("invalid schedule %q: the interval must be at least one minute", text)
+		}
+		s.every = d
+		return s, nil
+	case len(fields) == 2 && fields[0] == "daily":
+		return s, s.parseTime(fields[1])
+	case len(fields) == 3 && fields[0] == "weekly":
+		day, ok := weekdays[fields[1]]
+		if !ok {
+			return nil, fmt.Errorf("invalid schedule %q: unknown day %q", text, fields[1])
+		}
+		s.weekday = &day
+		return s, s.parseTime(fields[2])
+	default:
+		return nil, fmt.Errorf("invalid schedule %q: expected \"every <duration>\", \"daily HH:MM\" or \"weekly <day> HH:MM\"", text)
+	}
+}
+
+// parseTime parses the HH:MM time of day
+func (s *Schedule) parseTime(value string) error {
+	hour, minute, ok := strings.Cut(value, ":")
+	h, herr := strconv.Atoi(hour)
+	m, merr := strconv.Atoi(minute)
+	if !ok || herr != nil || merr != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return fmt.Errorf("invalid schedule %q: expected a time of day as HH:MM", s.text)
+	}
+	s.hour, s.minute = h, m
+	return nil
+}
+
+// String returns the schedule as written
+func (s *Schedule) String() string {
+	return s.text
+}
+
+// Period is the time between two runs
+func (s *Schedule) Period() time.Duration {
+	switch {
+	case s.every > 0:
+		return s.every
+	case s.weekday != nil:
+		return 7 * 24 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// Next returns the first run after t
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	local := t.In(s.loc)
+	days := 0
+	if s.weekday != nil {
+		days = (int(*s.weekday) - int(local.Weekday()) + 7) % 7
+	}
+	next := time.Date(local.Year(), local.Month(), local.Day()+days, s.hour, s.minute, 0, 0, s.loc)
+	if !next.After(t) {
+		step := 1
+		if s.weekday != nil {
+			step = 7
+		}
+		next = time.Date(local.Year(), local.Month(), local.Day()+days+step, s.hour, s.minute, 0, 0, s.loc)
+	}
+	return next
+}
@@ -0,0 +1,246 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/audit"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"go.uber.org/zap"
+)
+
+// maxChanges caps the changed documents listed in a digest
+const maxChanges = 50
+
+// Status is a report with its schedule state
+type Status struct {
+	*Report
+	NextRun   *time.Time `json:"next_run,omitempty"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Result is a generated digest and its rendering
+type Result struct {
+	Digest    *Digest `json:"digest"`
+	Body      string  `json:"body"`
+	Delivered bool    `json:"delivered"`
+}
+
+// Service runs reports on their schedules and on demand
+type Service struct {
+	reports   map[string]*Report
+	querier   client.Querier
+	registry  registry.Store
+	deliverer *deliverer
+	recorder  *audit.Recorder
+	logger    *zap.Logger
+
+	mu     sync.Mutex
+	status map[string]*Status
+}
+
+// NewService loads the reports file. Changed documents are read from the
+// registry and queries are sent to the query service.
+func NewService(cfg *config.Config, querier client.Querier, store registry.Store, recorder *audit.Recorder, logger *zap.Logger) (*Service, error) {
+	reports, err := LoadReports(cfg.Digest.ReportsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Service{
+		reports:   make(map[string]*Report, len(reports)),
+		querier:   querier,
+		registry:  store,
+		deliverer: newDeliverer(cfg.Digest),
+		recorder:  recorder,
+		logger:    logger,
+		status:    make(map[string]*Status, len(reports)),
+	}
+	for _, r := range reports {
+		if len(r.Deliver.Email) > 0 && (cfg.Digest.SMTPHost == "" || cfg.Digest.SMTPFrom == "") {
+			return nil, fmt.Errorf("report %q sends email, which needs SMTP_HOST and SMTP_FROM", r.Name)
+		}
+		s.reports[r.Name] = r
+		s.status[r.Name] = &Status{Report: r}
+	}
+	logger.Info("Digest reports loaded", zap.Int("reports", len(reports)), zap.String("file", cfg.Digest.ReportsFile))
+	return s, nil
+}
+
+// Reports returns every report with its schedule state, ordered by name
+func (s *Service) Reports() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]Status, 0, len(s.status))
+	for _, st := range s.status {
+		result = append(result, *st)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Run runs every report on its schedule until the context is cancelled.
+// Runs missed while the service was stopped are not caught up.
+func (s *Service) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, r := range s.reports {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runScheduled(ctx, r)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Service) runScheduled(ctx context.Context, r *Report) {
+	for {
+		next := r.schedule.Next(time.Now())
+		s.mu.Lock()
+		s.status[r.Name].NextRun = &next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		event := audit.NewEvent("system", audit.ActionDigest, r.Name)
+		event.Details["trigger"] = "schedule"
+		if _, err := s.Generate(ctx, r.Name, true); err != nil {
+			s.logger.Error("Failed to run digest report", zap.String("report", r.Name), zap.Error(err))
+			event.Outcome = audit.OutcomeFailure
+			event.Details["error"] = err.Error()
+		}
+		s.recorder.Record(ctx, event)
+	}
+}
+
+// Generate builds a report's digest covering the last schedule period and,
+// when deliver is set, sends it to the report's destinations. A failed
+// query is noted in its section rather than failing the digest.
+func (s *Service) Generate(ctx context.Context, name string, deliver bool) (*Result, error) {
+	r, ok := s.reports[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	now := time.Now()
+	digest := &Digest{
+		Report:         r.Name,
+		Title:          r.Title,
+		Since:          now.Add(-r.schedule.Period()),
+		GeneratedAt:    now,
+		IncludeChanges: r.IncludeChanges,
+		Sections:       make([]*Section, 0, len(r.Queries)),
+	}
+
+	if r.IncludeChanges {
+		if err := s.addChanges(ctx, r, digest); err != nil {
+			return nil, err
+		}
+	}
+
+	queryCtx := client.WithIdentity(ctx, &models.Identity{UserID: r.RunAs.UserID, Groups: r.RunAs.Groups})
+	for _, q := range r.Queries {
+		digest.Sections = append(digest.Sections, s.runQuery(queryCtx, q, digest.Since))
+	}
+
+	body, err := Render(digest, r.Format)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Digest: digest, Body: body}
+
+	if deliver {
+		err = s.deliverer.deliver(ctx, r, digest, body)
+		result.Delivered = err == nil
+		s.mu.Lock()
+		s.status[r.Name].LastRun = &now
+		s.status[r.Name].LastError = ""
+		if err != nil {
+			s.status[r.Name].LastError = err.Error()
+		}
+		s.mu.Unlock()
+		if err != nil {
+			return result, err
+		}
+		s.logger.Info("Delivered digest report",
+			zap.String("report", r.Name),
+			zap.Int("changes", digest.TotalChanges),
+			zap.Int("sections", len(digest.Sections)))
+	}
+	return result, nil
+}
+
+// addChanges lists the documents indexed since the start of the digest
+func (s *Service) addChanges(ctx context.Context, r *Report, digest *Digest) error {
+	if s.registry == nil {
+		return nil
+	}
+	records, err := s.registry.List(ctx, registry.Filter{State: models.StateIndexed, PathPrefix: r.ChangesPath})
+	if err != nil {
+		return fmt.Errorf("failed to list changed documents: %w", err)
+	}
+
+	for _, record := range records {
+		if record.IndexedAt == nil || record.IndexedAt.Before(digest.Since) {
+			continue
+		}
+		digest.Changes = append(digest.Changes, Change{
+			DocumentID: record.ID,
+			FilePath:   record.FilePath,
+			Category:   record.Category,
+			IndexedAt:  *record.IndexedAt,
+			Summary:    record.Summary,
+		})
+	}
+	sort.Slice(digest.Changes, func(i, j int) bool { return digest.Changes[i].IndexedAt.After(digest.Changes[j].IndexedAt) })
+	digest.TotalChanges = len(digest.Changes)
+	if len(digest.Changes) > maxChanges {
+		digest.Changes = digest.Changes[:maxChanges]
+	}
+	return nil
+}
+
+// runQuery runs one saved query
+func (s *Service) runQuery(ctx context.Context, q SavedQuery, since time.Time) *Section {
+	section := &Section{Title: q.Title, Query: q.Text, Sources: []models.SearchResult{}}
+	req := &client.QueryRequest{
+		Text:   q.Text,
+		TopK:   q.TopK,
+		Filter: models.Filter{FileType: q.FileType, Collection: q.Collection},
+	}
+	if q.ChangedOnly {
+		req.Filter.DateFrom = &since
+	}
+
+	var err error
+	switch q.Mode {
+	case ModeSearch:
+		section.Sources, err = s.querier.Search(ctx, req)
+	default:
+		var answer *models.QueryResult
+		if answer, err = s.querier.Ask(ctx, req); err == nil {
+			section.Answer = answer.Answer
+			section.Sources = answer.Sources
+		}
+	}
+	if err != nil {
+		s.logger.Warn("Digest query failed", zap.String("query", q.Title), zap.Error(err))
+		section.Error = err.Error()
+		section.Sources = []models.SearchResult{}
+	}
+	return section
+}
@@ -0,0 +1,148 @@
+// Package dlq keeps the dead-letter list: documents that still failed
+// after every retry, with the error chain and the stage they reached, so
+// they can be inspected, retried in bulk or exported.
+package dlq
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned when no entry has the ID
+var ErrNotFound = errors.New("dead-letter entry not found")
+
+// Entry is a file that failed processing after every retry. A file has at
+// most one entry; failing again replaces it.
+type Entry struct {
+	ID       string `json:"id"`
+	FilePath string `json:"file_path"`
+	// DocumentID is the registry ID of the failed version; empty when the
+	// file failed before it was tracked
+	DocumentID string `json:"document_id,omitempty"`
+	// Stage is the last processing state the document reached before
+	// failing; empty when it failed before it was tracked
+	Stage string `json:"stage,omitempty"`
+	// Errors is the error chain of the last attempt, outermost first
+	Errors   []string  `json:"errors"`
+	Attempts int       `json:"attempts"`
+	Force    bool      `json:"force,omitempty"`
+	Source   string    `json:"source"` // queue, or the ID of the directory run
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// EntryID returns the ID of a file's entry
+func EntryID(filePath string) string {
+	sum := sha256.Sum256([]byte(filePath))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Chain lists the messages of an error and the errors it wraps, outermost
+// first. Joined errors contribute each of their errors.
+func Chain(err error) []string {
+	var chain []string
+	for err != nil {
+		chain = append(chain, err.Error())
+		switch wrapped := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapped.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, e := range wrapped.Unwrap() {
+				chain = append(chain, Chain(e)...)
+			}
+			err = nil
+		default:
+			err = nil
+		}
+	}
+	return chain
+}
+
+// Export formats
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// Export writes entries as JSON lines, one entry per line, or as CSV with a
+// header row and the error chain joined into one column
+func Export(w io.Writer, entries []*Entry, format string) error {
+	switch format {
+	case FormatJSONL:
+		encoder := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "file_path", "document_id", "stage", "attempts", "force", "source", "failed_at", "errors"}) //nolint:errcheck
+		for _, entry := range entries {
+			cw.Write([]string{ //nolint:errcheck
+				entry.ID,
+				entry.FilePath,
+				entry.DocumentID,
+				entry.Stage,
+				strconv.Itoa(entry.Attempts),
+				strconv.FormatBool(entry.Force),
+				entry.Source,
+				entry.FailedAt.UTC().Format(time.RFC3339),
+				strings.Join(entry.Errors, " | "),
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown export format %q: must be %s or %s", format, FormatJSONL, FormatCSV)
+	}
+}
+
+// Store persists dead-letter entries
+type Store interface {
+	// Add creates or replaces the entry of its file
+	Add(ctx context.Context, entry *Entry) error
+	Get(ctx context.Context, id string) (*Entry, error)
+	// List returns up to limit entries, most recent failure first; limit 0
+	// returns all
+	List(ctx context.Context, limit int) ([]*Entry, error)
+	Remove(ctx context.Context, id string) error
+	Close() error
+}
+
+// Compile-time checks that the stores implement the interface
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+// NewStore creates the dead-letter store. Entries are kept beside the
+// document registry, in the same backend.
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	switch cfg.Registry.Backend {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Dead-letter list enabled", zap.String("backend", "redis"), zap.String("key_prefix", cfg.Registry.KeyPrefix))
+		return NewRedisStore(client, cfg.Registry.KeyPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown registry backend: %s", cfg.Registry.Backend)
+	}
+}
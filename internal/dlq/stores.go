@@ -0,0 +1,162 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryStore keeps entries in process memory
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]*Entry
+}
+
+// NewMemoryStore creates an empty in-memory dead-letter store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*Entry)}
+}
+
+// Add creates or replaces the entry of its file
+func (s *MemoryStore) Add(_ context.Context, entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *entry
+	stored.Errors = append([]string(nil), entry.Errors...)
+	s.entries[entry.ID] = &stored
+	return nil
+}
+
+// Get returns the entry with the given ID
+func (s *MemoryStore) Get(_ context.Context, id string) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	result := *entry
+	return &result, nil
+}
+
+// List returns entries, most recent failure first
+func (s *MemoryStore) List(_ context.Context, limit int) ([]*Entry, error) {
+	s.mu.RLock()
+	all := make([]*Entry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		copied := *entry
+		all = append(all, &copied)
+	}
+	s.mu.RUnlock()
+
+	return newestFirst(all, limit), nil
+}
+
+// Remove deletes an entry
+func (s *MemoryStore) Remove(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.entries, id)
+	return nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// RedisStore keeps entries in a Redis hash by ID
+type RedisStore struct {
+	client  *redis.Client
+	entries string
+}
+
+// NewRedisStore creates a Redis backed dead-letter store
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	return &RedisStore{client: client, entries: keyPrefix + ":dlq"}
+}
+
+// Add creates or replaces the entry of its file
+func (s *RedisStore) Add(ctx context.Context, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead-letter entry: %w", err)
+	}
+	if err := s.client.HSet(ctx, s.entries, entry.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to write dead-letter entry: %w", err)
+	}
+	return nil
+}
+
+// Get returns the entry with the given ID
+func (s *RedisStore) Get(ctx context.Context, id string) (*Entry, error) {
+	data, err := s.client.HGet(ctx, s.entries, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter entry: %w", err)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode dead-letter entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// List returns entries, most recent failure first
+func (s *RedisStore) List(ctx context.Context, limit int) ([]*Entry, error) {
+	values, err := s.client.HVals(ctx, s.entries).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter list: %w", err)
+	}
+
+	all := make([]*Entry, 0, len(values))
+	for _, data := range values {
+		var entry Entry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			continue
+		}
+		all = append(all, &entry)
+	}
+	return newestFirst(all, limit), nil
+}
+
+// Remove deletes an entry
+func (s *RedisStore) Remove(ctx context.Context, id string) error {
+	removed, err := s.client.HDel(ctx, s.entries, id).Result()
+	if err != nil {
+		return fmt.Errorf("failed to remove dead-letter entry: %w", err)
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close closes the Redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// newestFirst sorts entries by failure time, newest first, and keeps up to
+// limit of them
+func newestFirst(entries []*Entry, limit int) []*Entry {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].FailedAt.After(entries[j].FailedAt) })
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries
+}
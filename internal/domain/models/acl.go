@@ -0,0 +1,83 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Visibility controls who may read a document
+type Visibility string
+
+const (
+	// VisibilityPublic documents are readable by every caller, including anonymous ones
+	VisibilityPublic Visibility = "public"
+	// VisibilityInternal documents are readable by any identified caller
+	VisibilityInternal Visibility = "internal"
+	// VisibilityPrivate documents are readable only by their owner and groups
+	VisibilityPrivate Visibility = "private"
+)
+
+// ACL is the access control list attached to a document at ingestion
+type ACL struct {
+	Owner      string     `json:"owner,omitempty"`
+	Groups     []string   `json:"groups,omitempty"`
+	Visibility Visibility `json:"visibility"`
+}
+
+// Identity identifies the caller of a query
+type Identity struct {
+	UserID string   `json:"user_id,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	Tenant string   `json:"tenant,omitempty"` // selects the tenant's moderation policy
+}
+
+// Anonymous reports whether the identity carries no user
+func (i *Identity) Anonymous() bool {
+	return i == nil || i.UserID == ""
+}
+
+// Allows reports whether the identity may read a document with this ACL
+func (a *ACL) Allows(identity *Identity) bool {
+	switch a.Visibility {
+	case "", VisibilityPublic:
+		return true
+	case VisibilityInternal:
+		if !identity.Anonymous() {
+			return true
+		}
+	}
+
+	if identity.Anonymous() {
+		return false
+	}
+	if a.Owner != "" && a.Owner == identity.UserID {
+		return true
+	}
+	for _, g := range identity.Groups {
+		for _, allowed := range a.Groups {
+			if g == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Key returns a stable string identifying the ACL, empty for public documents
+func (a *ACL) Key() string {
+	if a.Visibility == "" || a.Visibility == VisibilityPublic {
+		return ""
+	}
+	groups := append([]string(nil), a.Groups...)
+	sort.Strings(groups)
+	return string(a.Visibility) + "|" + a.Owner + "|" + strings.Join(groups, ",")
+}
+
+// IsValid reports whether the visibility is a known value
+func (v Visibility) IsValid() bool {
+	switch v {
+	case VisibilityPublic, VisibilityInternal, VisibilityPrivate:
+		return true
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,7 +14,7 @@ type Document struct {
 	FilePath        string            `json:"file_path"`
 	FileType        string            `json:"file_type"`
 	FileSize        int64             `json:"file_size"`
-	FileHash        string            `json:"file_hash"` // SHA256 hash for deduplication
+	FileHash        string            `json:"file_hash"` // hash for deduplication, prefixed with its algorithm
 	Content         string            `json:"content"`
 	RawContent      []byte            `json:"raw_content,omitempty"`
 	Metadata        map[string]string `json:"metadata"`
@@ -53,6 +54,31 @@ type Chunk struct {
 	CreatedAt  time.Time         `json:"created_at"`
 }
 
+// ChunkVectorID returns the ID of the vector holding a document's chunk.
+// IDs are prefixed with the document ID so a document's vectors can be
+// listed and deleted together.
+func ChunkVectorID(documentID string, index int) string {
+	return fmt.Sprintf("%s-chunk-%d", documentID, index)
+}
+
+// ChunkPartVectorID returns the ID of the vector holding one part of a
+// chunk that was split to fit the embedding model's token limit
+func ChunkPartVectorID(documentID string, index, part int) string {
+	return fmt.Sprintf("%s-chunk-%d-part-%d", documentID, index, part)
+}
+
+// SummaryVectorID returns the ID of a document's summary vector in the
+// summary namespace
+func SummaryVectorID(documentID string) string {
+	return documentID + "-summary"
+}
+
+// ImageVectorID returns the ID of an image document's image embedding in
+// the image namespace
+func ImageVectorID(documentID string) string {
+	return documentID + "-image"
+}
+
 // FileMetadata contains metadata about a scanned file
 type FileMetadata struct {
 	Path         string            `json:"path"`
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,28 +10,205 @@ import (
 
 // Query represents a user query in the RAG system
 type Query struct {
-	ID        uuid.UUID `json:"id"`
-	Text      string    `json:"text"`
-	TopK      int       `json:"top_k"`
-	Namespace string    `json:"namespace,omitempty"`
-	Filter    Filter    `json:"filter,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                uuid.UUID       `json:"id"`
+	Text              string          `json:"text"`
+	TopK              int             `json:"top_k"`
+	Namespace         string          `json:"namespace,omitempty"`
+	Filter            Filter          `json:"filter,omitempty"`
+	AsOf              *time.Time      `json:"as_of,omitempty"`               // Answer from the index state at this time
+	Mode              string          `json:"mode,omitempty"`                // Retrieval mode; empty uses the configured default
+	ContextWindow     *int            `json:"context_window,omitempty"`      // Neighbouring chunks added on each side of a match; nil uses the configured default
+	Alpha             *float64        `json:"alpha,omitempty"`               // Weight of meaning against terms in hybrid search, 0 to 1; nil uses the configured default
+	Profile           string          `json:"profile,omitempty"`             // Retrieval profile the unset settings were taken from
+	MinScore          *float64        `json:"min_score,omitempty"`           // Matches scoring below this are dropped; nil uses the configured default
+	MaxDistance       *float64        `json:"max_distance,omitempty"`        // Matches farther from the query than this are dropped; nil uses the configured default
+	FreshnessHalfLife *int            `json:"freshness_half_life,omitempty"` // Days at which the decaying part of a match score halves; 0 ranks by relevance alone, nil uses the configured default
+	ResponseFormat    string          `json:"response_format,omitempty"`     // text, or json_schema for an answer conforming to Schema
+	Schema            json.RawMessage `json:"schema,omitempty"`              // JSON schema of a json_schema answer
+	Caller            *Identity       `json:"-"`                             // Identity used for access control filtering
+	CreatedAt         time.Time       `json:"created_at"`
 }
 
+// Retrieval modes
+const (
+	RetrievalChunks   = "chunks"    // search all chunks directly
+	RetrievalTwoStage = "two_stage" // find documents by summary, then search their chunks
+	RetrievalAgent    = "agent"     // break the question into sub-questions and retrieve for each
+)
+
+// Answer formats
+const (
+	ResponseText       = "text"        // a prose answer citing its sources
+	ResponseJSONSchema = "json_schema" // a JSON answer conforming to the query's schema
+)
+
+// MaxContextWindow caps the neighbouring chunks added on each side of a match
+const MaxContextWindow = 10
+
 // Filter represents query filters
 type Filter struct {
-	FileType string            `json:"file_type,omitempty"`
-	DateFrom *time.Time        `json:"date_from,omitempty"`
-	DateTo   *time.Time        `json:"date_to,omitempty"`
-	Metadata map[string]string `json:"metadata,omitempty"`
+	FileType   string            `json:"file_type,omitempty"`
+	DateFrom   *time.Time        `json:"date_from,omitempty"`
+	DateTo     *time.Time        `json:"date_to,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Collection string            `json:"collection,omitempty"` // only documents in this collection
+	HasMath    bool              `json:"has_math,omitempty"`   // only chunks holding a formula
+	Tag        string            `json:"tag,omitempty"`        // only notes with this frontmatter tag
+	LogFrom    *time.Time        `json:"log_from,omitempty"`   // only log chunks with entries at or after this time
+	LogTo      *time.Time        `json:"log_to,omitempty"`     // only log chunks with entries at or before this time
+	LogLevel   string            `json:"log_level,omitempty"`  // only log chunks with entries at this severity or above
 }
 
 // QueryResult represents the result of a RAG query
 type QueryResult struct {
-	QueryID   uuid.UUID      `json:"query_id"`
-	Answer    string         `json:"answer"`
-	Sources   []SearchResult `json:"sources"`
-	Timestamp time.Time      `json:"timestamp"`
+	QueryID      uuid.UUID       `json:"query_id"`
+	Answer       string          `json:"answer"`
+	Sources      []SearchResult  `json:"sources"`
+	AsOf         *time.Time      `json:"as_of,omitempty"`
+	Trace        *QueryTrace     `json:"trace,omitempty"`
+	Faithfulness *float64        `json:"faithfulness,omitempty"` // Share of cited sentences their sources support, when citations are verified
+	Data         json.RawMessage `json:"data,omitempty"`         // The answer of a json_schema query, as JSON
+	Timestamp    time.Time       `json:"timestamp"`
+}
+
+// QueryTrace records how the sources of an answer were given to the model
+type QueryTrace struct {
+	// InjectionScore is the highest prompt-injection score of the sources,
+	// from 0 for none to 1 for text certainly written to instruct a model
+	InjectionScore float64 `json:"injection_score"`
+	// Injections lists the sources scoring at or above the configured
+	// threshold
+	Injections []InjectionFinding `json:"injections,omitempty"`
+	// Moderation lists the query or answer flagged by the safety
+	// classification when its action is warn
+	Moderation []ModerationVerdict `json:"moderation,omitempty"`
+	// Citations lists the verification of each answer sentence citing
+	// sources, when citations are verified
+	Citations []CitationCheck `json:"citations,omitempty"`
+	// ToolCalls lists the tools the model called while answering
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// Agent records the research of an agent mode answer
+	Agent *AgentTrace `json:"agent,omitempty"`
+}
+
+// AgentTrace records how an agent mode answer was researched
+type AgentTrace struct {
+	Plan   string      `json:"plan,omitempty"` // the model's reasoning for the sub-questions
+	Steps  []AgentStep `json:"steps"`
+	Tokens int64       `json:"tokens"` // model tokens used, prompt and completion
+	// Stopped says why sub-questions were left out, when any were
+	Stopped string `json:"stopped,omitempty"`
+}
+
+// AgentStep is a sub-question of an agent mode answer
+type AgentStep struct {
+	Question string `json:"question"`
+	Sources  []int  `json:"sources"`           // numbers of the sources retrieved for it, from 1
+	Skipped  bool   `json:"skipped,omitempty"` // not retrieved for, past the budget
+}
+
+// ToolCall is a tool the model called while answering
+type ToolCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`         // JSON object
+	Sources   int    `json:"sources,omitempty"` // sources it added
+	Error     string `json:"error,omitempty"`
+}
+
+// CitationCheck is the verification of an answer sentence against the
+// sources it cites
+type CitationCheck struct {
+	Sentence  string `json:"sentence"`
+	Sources   []int  `json:"sources"` // cited source numbers, from 1
+	Supported bool   `json:"supported"`
+	// Overlap is the share of the sentence's words found in the best cited
+	// source
+	Overlap  float64 `json:"overlap"`
+	Stripped bool    `json:"stripped,omitempty"` // removed from the answer
+}
+
+// Faithfulness returns the share of checked sentences their sources
+// support, or nil when none was checked
+func Faithfulness(checks []CitationCheck) *float64 {
+	if len(checks) == 0 {
+		return nil
+	}
+	supported := 0
+	for _, c := range checks {
+		if c.Supported {
+			supported++
+		}
+	}
+	score := float64(supported) / float64(len(checks))
+	return &score
+}
+
+// Moderation subjects
+const (
+	ModerationQuery  = "query"
+	ModerationAnswer = "answer"
+)
+
+// ModerationVerdict is the safety classification of a flagged query or
+// answer
+type ModerationVerdict struct {
+	Subject    string           `json:"subject"`    // query or answer
+	Action     string           `json:"action"`     // block, warn or log
+	Categories []SafetyCategory `json:"categories"` // categories at or above the threshold
+}
+
+// SafetyCategory is a category of harmful content found in a text, with its
+// severity from 0 (safe) to 7
+type SafetyCategory struct {
+	Category string `json:"category"`
+	Severity int    `json:"severity"`
+}
+
+// InjectionFinding is a source whose text reads like instructions to the
+// model answering the question
+type InjectionFinding struct {
+	Source    int      `json:"source"` // position in the sources, from 1 as cited in the prompt
+	FilePath  string   `json:"file_path"`
+	Score     float64  `json:"score"`
+	Patterns  []string `json:"patterns"`            // names of the phrases found
+	Sanitized bool     `json:"sanitized,omitempty"` // the phrases were removed before the source was given to the model
+}
+
+// ComparisonTarget is one side of a comparison: a document by ID, or the
+// document at a file path
+type ComparisonTarget struct {
+	DocumentID string `json:"document_id,omitempty"`
+	FilePath   string `json:"file_path,omitempty"`
+}
+
+// Comparison is the structured answer to a question comparing two documents
+type Comparison struct {
+	QueryID      uuid.UUID         `json:"query_id"`
+	Summary      string            `json:"summary"`
+	Similarities []string          `json:"similarities"`
+	Differences  []ComparisonPoint `json:"differences"`
+	LeftSources  []SearchResult    `json:"left_sources"`
+	RightSources []SearchResult    `json:"right_sources"` // numbered after the left sources
+	AsOf         *time.Time        `json:"as_of,omitempty"`
+	Trace        *QueryTrace       `json:"trace,omitempty"`
+	Timestamp    time.Time         `json:"timestamp"`
+}
+
+// ComparisonPoint is an aspect in which the two documents differ
+type ComparisonPoint struct {
+	Aspect  string `json:"aspect"`
+	Left    string `json:"left"`
+	Right   string `json:"right"`
+	Sources []int  `json:"sources"` // numbers of the sources it rests on, from 1
+}
+
+// SimilarDocument is a document found like another by its chunk vectors
+type SimilarDocument struct {
+	DocumentID string  `json:"document_id"`
+	FileName   string  `json:"file_name"`
+	FilePath   string  `json:"file_path"`
+	Score      float32 `json:"score"`
+	Matches    int     `json:"matches"` // chunks of it that matched
 }
 
 // SearchResult represents a single search result from vector store
@@ -53,3 +232,22 @@ func NewQuery(text string, topK int) *Query {
 		CreatedAt: time.Now(),
 	}
 }
+
+// asOfLayouts lists the accepted formats for as_of timestamps
+var asOfLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// ParseAsOf parses an as_of value given as a date (2024-06-01) or RFC 3339 timestamp.
+// A bare date refers to the end of that day in UTC.
+func ParseAsOf(value string) (time.Time, error) {
+	for _, layout := range asOfLayouts {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		if layout == "2006-01-02" {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid as_of %q: expected YYYY-MM-DD or RFC 3339", value)
+}
@@ -0,0 +1,194 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// request is a single caller's embedding request waiting in the queue
+type request struct {
+	ctx    context.Context
+	texts  []string
+	result chan response
+}
+
+type response struct {
+	embeddings [][]float32
+	err        error
+}
+
+// Batcher coalesces concurrent embedding requests into provider batch calls.
+// Requests are collected until maxBatch texts are queued or maxWait elapses
+// after the first request, whichever happens first.
+type Batcher struct {
+	provider Provider
+	maxBatch int
+	maxWait  time.Duration
+	queue    chan *request
+	done     chan struct{}
+	logger   *zap.Logger
+
+	// mu is held for reading while a request is queued and for writing by
+	// Stop, so no request is queued after the queue is drained
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewBatcher creates and starts a batcher
+func NewBatcher(provider Provider, maxBatch int, maxWait time.Duration, logger *zap.Logger) *Batcher {
+	b := &Batcher{
+		provider: provider,
+		maxBatch: maxBatch,
+		maxWait:  maxWait,
+		queue:    make(chan *request, maxBatch*4),
+		done:     make(chan struct{}),
+		logger:   logger,
+	}
+	go b.run()
+	return b
+}
+
+// Embed queues texts and waits for their embeddings
+func (b *Batcher) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("texts cannot be empty")
+	}
+
+	req := &request{ctx: ctx, texts: texts, result: make(chan response, 1)}
+	if err := b.enqueue(ctx, req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-req.result:
+		return resp.embeddings, resp.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// enqueue adds a request to the queue unless the batcher is stopped
+func (b *Batcher) enqueue(ctx context.Context, req *request) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return fmt.Errorf("embedding batcher is stopped")
+	}
+	select {
+	case b.queue <- req:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop stops the batcher; requests already queued are still processed
+func (b *Batcher) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.done)
+}
+
+func (b *Batcher) run() {
+	for {
+		var first *request
+		select {
+		case first = <-b.queue:
+		case <-b.done:
+			b.drain()
+			return
+		}
+
+		batch := []*request{first}
+		size := len(first.texts)
+		timer := time.NewTimer(b.maxWait)
+
+	collect:
+		for size < b.maxBatch {
+			select {
+			case req := <-b.queue:
+				batch = append(batch, req)
+				size += len(req.texts)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		b.flush(batch)
+	}
+}
+
+// drain processes any requests left in the queue after Stop
+func (b *Batcher) drain() {
+	for {
+		select {
+		case req := <-b.queue:
+			b.flush([]*request{req})
+		default:
+			return
+		}
+	}
+}
+
+// flush sends a batch to the provider and distributes the results
+func (b *Batcher) flush(batch []*request) {
+	// Skip requests whose callers have already given up
+	live := batch[:0]
+	texts := make([]string, 0, b.maxBatch)
+	for _, req := range batch {
+		if req.ctx.Err() != nil {
+			continue
+		}
+		live = append(live, req)
+		texts = append(texts, req.texts...)
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	// The batch call must not be cancelled by any single caller
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	b.logger.Debug("Flushing embedding batch",
+		zap.Int("requests", len(live)),
+		zap.Int("texts", len(texts)))
+
+	embeddings, err := b.generate(ctx, texts)
+
+	offset := 0
+	for _, req := range live {
+		if err != nil {
+			req.result <- response{err: err}
+			continue
+		}
+		req.result <- response{embeddings: embeddings[offset : offset+len(req.texts)]}
+		offset += len(req.texts)
+	}
+}
+
+// generate calls the provider in chunks of at most maxBatch texts
+func (b *Batcher) generate(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += b.maxBatch {
+		end := start + b.maxBatch
+		if end > len(texts) {
+			end = len(texts)
+		}
+		result, err := b.provider.GenerateEmbeddings(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, result...)
+	}
+	return embeddings, nil
+}
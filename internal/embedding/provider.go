@@ -0,0 +1,99 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Provider generates embeddings for a batch of texts
+type Provider interface {
+	Name() string
+	GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// FailoverProvider tries providers in order until one succeeds
+type FailoverProvider struct {
+	providers []Provider
+	dimension int // length every provider's vectors must have
+	logger    *zap.Logger
+}
+
+// NewFailoverProvider creates a provider that fails over between providers
+// in order. Vectors not of the given dimension count as a failure, so a
+// fallback with another model cannot mix its vectors into the index.
+func NewFailoverProvider(logger *zap.Logger, dimension int, providers ...Provider) *FailoverProvider {
+	return &FailoverProvider{providers: providers, dimension: dimension, logger: logger}
+}
+
+// Name returns the name of the primary provider
+func (p *FailoverProvider) Name() string {
+	if len(p.providers) == 0 {
+		return "none"
+	}
+	return p.providers[0].Name()
+}
+
+// GenerateEmbeddings calls each provider in turn, returning the first success
+func (p *FailoverProvider) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	var errs []error
+	for i, provider := range p.providers {
+		embeddings, err := provider.GenerateEmbeddings(ctx, texts)
+		if err == nil {
+			err = p.checkDimension(embeddings)
+		}
+		if err == nil {
+			if i > 0 {
+				p.logger.Warn("Embedding served by fallback provider",
+					zap.String("provider", provider.Name()),
+					zap.Int("count", len(texts)))
+			}
+			return embeddings, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+		p.logger.Warn("Embedding provider failed",
+			zap.String("provider", provider.Name()),
+			zap.Error(err))
+	}
+
+	return nil, fmt.Errorf("all embedding providers failed: %w", errors.Join(errs...))
+}
+
+// Validate embeds a probe text with every provider and fails when one
+// returns vectors of another dimension. Providers that cannot be reached
+// are only logged, as they may be down for a while; their vectors are
+// still checked on every call.
+func (p *FailoverProvider) Validate(ctx context.Context) error {
+	for _, provider := range p.providers {
+		embeddings, err := provider.GenerateEmbeddings(ctx, []string{"dimension probe"})
+		if err != nil {
+			p.logger.Warn("Could not check the dimension of embedding provider",
+				zap.String("provider", provider.Name()),
+				zap.Error(err))
+			continue
+		}
+		if err := p.checkDimension(embeddings); err != nil {
+			return fmt.Errorf("%s: %w", provider.Name(), err)
+		}
+	}
+	return nil
+}
+
+// checkDimension fails when a vector is not of the configured dimension
+func (p *FailoverProvider) checkDimension(embeddings [][]float32) error {
+	if p.dimension <= 0 {
+		return nil
+	}
+	for _, e := range embeddings {
+		if len(e) != p.dimension {
+			return fmt.Errorf("embedding dimension %d does not match PINECONE_DIMENSION %d", len(e), p.dimension)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/azure"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// azureProvider adapts the Azure OpenAI client to the Provider interface
+type azureProvider struct {
+	name   string
+	client *azure.OpenAIClient
+}
+
+func (p *azureProvider) Name() string {
+	return p.name
+}
+
+func (p *azureProvider) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	return p.client.GenerateEmbeddings(ctx, texts)
+}
+
+// Service generates embeddings through a micro-batching queue with provider failover
+type Service struct {
+	batcher   *Batcher
+	provider  Provider
+	dimension int
+	model     string
+	logger    *zap.Logger
+}
+
+// NewService creates an embedding service with the configured providers
+func NewService(cfg *config.Config, logger *zap.Logger) (*Service, error) {
+	primary, err := azure.NewOpenAIClient(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure client: %w", err)
+	}
+	providers := []Provider{&azureProvider{name: "azure:" + primary.EndpointName(), client: primary}}
+
+	if cfg.Embedding.FallbackEndpoint != "" {
+		fallbackCfg := *cfg
+		fallbackCfg.Azure.OpenAIEndpoint = cfg.Embedding.FallbackEndpoint
+		if cfg.Embedding.FallbackAPIKey != "" {
+			fallbackCfg.Azure.OpenAIAPIKey = cfg.Embedding.FallbackAPIKey
+		}
+		if cfg.Embedding.FallbackDeployment != "" {
+			fallbackCfg.Azure.OpenAIEmbeddingsDeployment = cfg.Embedding.FallbackDeployment
+		}
+
+		fallback, err := azure.NewOpenAIClient(&fallbackCfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fallback Azure client: %w", err)
+		}
+		providers = append(providers, &azureProvider{name: "azure:" + fallback.EndpointName(), client: fallback})
+		logger.Info("Embedding failover enabled", zap.String("fallback", fallback.EndpointName()))
+	}
+
+	provider := NewFailoverProvider(logger, cfg.Pinecone.Dimension, providers...)
+	if len(providers) > 1 {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := provider.Validate(ctx); err != nil {
+			return nil, fmt.Errorf("embedding providers disagree on the dimension: %w", err)
+		}
+	}
+
+	return &Service{
+		batcher:   NewBatcher(provider, cfg.Embedding.MaxBatchSize, cfg.Embedding.MaxBatchWait, logger),
+		provider:  provider,
+		dimension: cfg.Pinecone.Dimension,
+		model:     cfg.Azure.OpenAIEmbeddingsDeployment,
+		logger:    logger,
+	}, nil
+}
+
+// GenerateEmbedding creates an embedding for a single text
+func (s *Service) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	embeddings, err := s.batcher.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// GenerateEmbeddings creates embeddings for multiple texts
+func (s *Service) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("text %d cannot be empty", i)
+		}
+	}
+	return s.batcher.Embed(ctx, texts)
+}
+
+// GetDimension returns the embedding dimension
+func (s *Service) GetDimension() int {
+	return s.dimension
+}
+
+// Model returns the primary embedding deployment name
+func (s *Service) Model() string {
+	return s.model
+}
+
+// Close stops the batching queue
+func (s *Service) Close() {
+	s.batcher.Stop()
+}
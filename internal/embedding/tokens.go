@@ -0,0 +1,142 @@
+package embedding
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Overflow strategies for embedding inputs above the token limit
+const (
+	OverflowSplit        = "split"         // embed the text as several parts
+	OverflowTruncateHead = "truncate_head" // drop the start of the text, keep the end
+	OverflowTruncateTail = "truncate_tail" // drop the end of the text, keep the start
+	OverflowSkip         = "skip"          // do not embed the text
+)
+
+// Encoding is the tokenizer of the Azure OpenAI embedding models
+// (text-embedding-ada-002 and text-embedding-3), also used to budget chat
+// prompts. Its vocabulary is compiled into the binary, so counting needs no
+// download.
+const Encoding = "cl100k_base"
+
+var (
+	tokenizerOnce sync.Once
+	tokenizer     *tiktoken.Tiktoken
+)
+
+// piece is a run of text counted as a whole number of tokens
+type piece struct {
+	end    int // byte offset just past the piece
+	tokens int
+}
+
+// CountTokens returns the number of model tokens in text
+func CountTokens(text string) int {
+	if t := loadTokenizer(); t != nil {
+		return len(t.EncodeOrdinary(text))
+	}
+	return len(text)
+}
+
+// FitTokens brings text within maxTokens using the overflow strategy and
+// returns the inputs to embed: the text itself when it fits, one shortened
+// text when truncating, several parts when splitting and none when skipping.
+func FitTokens(text string, maxTokens int, strategy string) []string {
+	ps := pieces(text)
+	total := 0
+	for _, p := range ps {
+		total += p.tokens
+	}
+	if total <= maxTokens {
+		return []string{text}
+	}
+
+	switch strategy {
+	case OverflowSkip:
+		return nil
+	case OverflowTruncateTail:
+		end, used := 0, 0
+		for _, p := range ps {
+			if used+p.tokens > maxTokens {
+				break
+			}
+			used += p.tokens
+			end = p.end
+		}
+		return []string{text[:end]}
+	case OverflowTruncateHead:
+		start, used := len(text), 0
+		for i := len(ps) - 1; i >= 0; i-- {
+			if used+ps[i].tokens > maxTokens {
+				break
+			}
+			used += ps[i].tokens
+			start = 0
+			if i > 0 {
+				start = ps[i-1].end
+			}
+		}
+		return []string{text[start:]}
+	default:
+		var parts []string
+		start, used := 0, 0
+		for i, p := range ps {
+			if used+p.tokens > maxTokens && used > 0 {
+				parts = append(parts, text[start:ps[i-1].end])
+				start, used = ps[i-1].end, 0
+			}
+			used += p.tokens
+		}
+		return append(parts, text[start:])
+	}
+}
+
+// loadTokenizer returns the tokenizer, or nil if its vocabulary cannot be
+// read, which would be a build error as it is compiled in
+func loadTokenizer() *tiktoken.Tiktoken {
+	tokenizerOnce.Do(func() {
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+		if t, err := tiktoken.GetEncoding(Encoding); err == nil {
+			tokenizer = t
+		}
+	})
+	return tokenizer
+}
+
+// pieces splits text into runs of tokens that end on character boundaries.
+// A token may hold part of a multi-byte character, so tokens are merged
+// until the text can be cut after them. Without the tokenizer, or for text
+// that is not valid UTF-8 and so does not decode back to itself, every byte
+// counts as a token, which no encoding exceeds.
+func pieces(text string) []piece {
+	t := loadTokenizer()
+	if t == nil || !utf8.ValidString(text) {
+		return bytePieces(text)
+	}
+
+	var ps []piece
+	end, tokens := 0, 0
+	for _, token := range t.EncodeOrdinary(text) {
+		end += len(t.Decode([]int{token}))
+		tokens++
+		if end >= len(text) || utf8.RuneStart(text[end]) {
+			ps = append(ps, piece{end: min(end, len(text)), tokens: tokens})
+			tokens = 0
+		}
+	}
+	return ps
+}
+
+// bytePieces splits text into characters counted as one token per byte
+func bytePieces(text string) []piece {
+	ps := make([]piece, 0, len(text))
+	for i := 0; i < len(text); {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		ps = append(ps, piece{end: i, tokens: size})
+	}
+	return ps
+}
@@ -0,0 +1,78 @@
+package embedding
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCountTokens(t *testing.T) {
+	// Counts of the cl100k_base reference tokenizer
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello world", 2},
+		{"tiktoken is great!", 6},
+	}
+	for _, tt := range tests {
+		if got := CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+// fitSamples are texts whose tokens cut across characters or that a
+// character-based estimate miscounts
+var fitSamples = map[string]string{
+	"prose":  strings.Repeat("The service splits each document into chunks and embeds them. ", 20),
+	"base64": strings.Repeat("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChwGA60e6kgAAAABJRU5ErkJggg==", 10),
+	"cjk":    strings.Repeat("检索服务把每个文档切分成片段并生成向量。検索サービスは文書を分割します。", 10),
+	"emoji":  strings.Repeat("Deploy ✅ tests 🚀 family 👨‍👩‍👧‍👦 flag 🇱🇰 ", 10),
+	"hindi":  strings.Repeat("सेवा प्रत्येक दस्तावेज़ को टुकड़ों में विभाजित करती है। ", 10),
+}
+
+func TestFitTokens(t *testing.T) {
+	const limit = 37
+	for name, text := range fitSamples {
+		t.Run(name, func(t *testing.T) {
+			if CountTokens(text) <= limit {
+				t.Fatalf("sample has %d tokens, want more than %d", CountTokens(text), limit)
+			}
+
+			parts := FitTokens(text, limit, OverflowSplit)
+			if len(parts) < 2 || strings.Join(parts, "") != text {
+				t.Errorf("split into %d parts that do not rejoin to the text", len(parts))
+			}
+			head := FitTokens(text, limit, OverflowTruncateHead)
+			tail := FitTokens(text, limit, OverflowTruncateTail)
+			if len(head) != 1 || !strings.HasSuffix(text, head[0]) {
+				t.Errorf("truncate_head = %q, want an end of the text", head)
+			}
+			if len(tail) != 1 || !strings.HasPrefix(text, tail[0]) {
+				t.Errorf("truncate_tail = %q, want a start of the text", tail)
+			}
+			for _, input := range append(append(parts, head...), tail...) {
+				if tokens := CountTokens(input); tokens > limit || tokens == 0 {
+					t.Errorf("input of %d tokens, want 1 to %d: %q", tokens, limit, input)
+				}
+				if !utf8.ValidString(input) {
+					t.Errorf("input cut inside a character: %q", input)
+				}
+			}
+			if got := FitTokens(text, limit, OverflowSkip); got != nil {
+				t.Errorf("skip = %q, want nothing", got)
+			}
+		})
+	}
+}
+
+func TestFitTokensInvalidUTF8(t *testing.T) {
+	text := strings.Repeat("caf\xe9 ", 20)
+	for _, input := range FitTokens(text, 10, OverflowSplit) {
+		if tokens := CountTokens(input); tokens > 10 {
+			t.Errorf("input of %d tokens, want at most 10: %q", tokens, input)
+		}
+	}
+}
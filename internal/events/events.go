@@ -0,0 +1,262 @@
+// Package events publishes pipeline events to a Redis stream, so any number
+// of consumers (the UI, webhooks, metrics exporters) can follow indexing
+// without coupling to the orchestrator. Each consumer reads the stream on
+// its own, from an ID it remembers or through a consumer group of its own.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Event types
+const (
+	// TypeDocumentState is published when a document moves to a new
+	// processing state
+	TypeDocumentState = "document.state-changed"
+	// TypeRunProgress is published when a directory run starts, after each
+	// of its files and when it finishes
+	TypeRunProgress = "run.progress"
+)
+
+// Event is an entry of the event stream
+type Event struct {
+	// ID is the stream entry ID, which orders events and is the position
+	// to read on from
+	ID        string          `json:"id"`
+	EventID   string          `json:"event_id"` // unique across publishers
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// DocumentStateChanged is the data of a document.state-changed event
+type DocumentStateChanged struct {
+	DocumentID    string `json:"document_id"`
+	FilePath      string `json:"file_path"`
+	State         string `json:"state"`
+	PreviousState string `json:"previous_state,omitempty"`
+	RunID         string `json:"run_id,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// RunProgress is the data of a run.progress event
+type RunProgress struct {
+	RunID     string `json:"run_id"`
+	Directory string `json:"directory"`
+	Status    string `json:"status"` // running, completed or cancelled
+	Total     int    `json:"total_files"`
+	Done      int    `json:"done"` // files reached so far
+	Processed int    `json:"processed"`
+	Skipped   int    `json:"skipped"`
+	Failed    int    `json:"failed"`
+	File      string `json:"file,omitempty"` // file just finished
+}
+
+// Bus publishes events to and reads them from a Redis stream. A nil bus
+// publishes nothing.
+type Bus struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+	logger *zap.Logger
+}
+
+// New connects the event bus. It returns nil when events are disabled.
+func New(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*Bus, error) {
+	if !cfg.Events.Enabled {
+		return nil, nil
+	}
+	client, err := redisclient.Connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Event bus enabled", zap.String("stream", cfg.Events.Stream), zap.Int64("max_len", cfg.Events.MaxLen))
+	return NewBus(client, cfg.Events.Stream, cfg.Events.MaxLen, logger), nil
+}
+
+// NewBus creates a bus on a stream trimmed to about maxLen entries
+func NewBus(client *redis.Client, stream string, maxLen int64, logger *zap.Logger) *Bus {
+	return &Bus{client: client, stream: stream, maxLen: maxLen, logger: logger}
+}
+
+// Enabled reports whether events are published
+func (b *Bus) Enabled() bool {
+	return b != nil
+}
+
+// Publish adds an event to the stream. Failures are logged and never fail
+// the operation publishing.
+func (b *Bus) Publish(ctx context.Context, eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		b.logger.Warn("Failed to marshal event", zap.String("type", eventType), zap.Error(err))
+		return
+	}
+	err = b.client.XAdd(context.WithoutCancel(ctx), &redis.XAddArgs{
+		Stream: b.stream,
+		MaxLen: b.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"event_id":  uuid.New().String(),
+			"type":      eventType,
+			"timestamp": time.Now().UTC().Format(time.RFC3339Nano),
+			"data":      payload,
+		},
+	}).Err()
+	if err != nil {
+		b.logger.Warn("Failed to publish event", zap.String("type", eventType), zap.Error(err))
+	}
+}
+
+// Read returns the events among the next count stream entries after the
+// entry ID after, oldest first; an empty after reads from the start of the
+// stream. Events of other types than those given are left out. It returns
+// the ID of the last entry read, or after when none followed, to read on
+// from.
+func (b *Bus) Read(ctx context.Context, after string, count int64, types ...string) ([]Event, string, error) {
+	if b == nil {
+		return nil, after, ErrDisabled
+	}
+	start := "-"
+	if after != "" {
+		start = "(" + after
+	}
+	messages, err := b.client.XRangeN(ctx, b.stream, start, "+", count).Result()
+	if err != nil {
+		return nil, after, fmt.Errorf("failed to read event stream: %w", err)
+	}
+	next := after
+	if len(messages) > 0 {
+		next = messages[len(messages)-1].ID
+	}
+	return decode(messages, types), next, nil
+}
+
+// Consume delivers the events of the stream to handle through the consumer
+// group, created at the end of the stream when missing, until ctx is done.
+// Events are acknowledged once handled; an event handle fails for stays
+// pending and is delivered again when the consumer restarts.
+func (b *Bus) Consume(ctx context.Context, group, consumer string, handle func(context.Context, Event) error) error {
+	if b == nil {
+		return ErrDisabled
+	}
+	err := b.client.XGroupCreateMkStream(ctx, b.stream, group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	// Pending events of an earlier run of this consumer come first, read
+	// on from the last one delivered so an event failing again is left for
+	// the next restart
+	pending := "0"
+	for ctx.Err() == nil {
+		position := ">"
+		if pending != "" {
+			position = pending
+		}
+		streams, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    group,
+			Consumer: consumer,
+			Streams:  []string{b.stream, position},
+			Count:    100,
+			Block:    5 * time.Second,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return fmt.Errorf("failed to read event stream: %w", err)
+		}
+		for _, stream := range streams {
+			if pending != "" {
+				if len(stream.Messages) == 0 {
+					pending = ""
+				} else {
+					pending = stream.Messages[len(stream.Messages)-1].ID
+				}
+			}
+			for _, msg := range stream.Messages {
+				event, ok := decodeMessage(msg)
+				if ok {
+					if err := handle(ctx, event); err != nil {
+						b.logger.Warn("Failed to handle event", zap.String("id", msg.ID), zap.String("type", event.Type), zap.Error(err))
+						continue
+					}
+				}
+				if err := b.client.XAck(ctx, b.stream, group, msg.ID).Err(); err != nil {
+					return fmt.Errorf("failed to acknowledge event: %w", err)
+				}
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// Close closes the Redis client
+func (b *Bus) Close() error {
+	if b == nil {
+		return nil
+	}
+	return b.client.Close()
+}
+
+// ErrDisabled is returned when reading events while the bus is disabled
+var ErrDisabled = errors.New("event bus is disabled")
+
+// decode converts stream messages to events of the given types, or of any
+// type when none are given
+func decode(messages []redis.XMessage, types []string) []Event {
+	events := make([]Event, 0, len(messages))
+	for _, msg := range messages {
+		event, ok := decodeMessage(msg)
+		if !ok {
+			continue
+		}
+		if len(types) > 0 && !contains(types, event.Type) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func decodeMessage(msg redis.XMessage) (Event, bool) {
+	event := Event{ID: msg.ID}
+	event.EventID, _ = msg.Values["event_id"].(string)
+	event.Type, _ = msg.Values["type"].(string)
+	if ts, ok := msg.Values["timestamp"].(string); ok {
+		event.Timestamp, _ = time.Parse(time.RFC3339Nano, ts) //nolint:errcheck
+	}
+	data, ok := msg.Values["data"].(string)
+	if event.Type == "" || !ok || !json.Valid([]byte(data)) {
+		return Event{}, false
+	}
+	event.Data = json.RawMessage(data)
+	return event, true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
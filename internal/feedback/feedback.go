@@ -0,0 +1,201 @@
+// Package feedback keeps the ratings users give answers. A rating links a
+// query ID to thumbs up or down with an optional comment; a user rating the
+// same answer again replaces their rating. Poorly rated queries are the
+// cases an evaluation run should check first.
+package feedback
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// Ratings
+const (
+	RatingUp   = "up"
+	RatingDown = "down"
+)
+
+// MaxCommentLength caps the characters of a comment
+const MaxCommentLength = 2000
+
+// Feedback is a user's rating of an answer
+type Feedback struct {
+	QueryID   string    `json:"query_id"`
+	Rating    string    `json:"rating"` // up or down
+	Comment   string    `json:"comment,omitempty"`
+	Query     string    `json:"query,omitempty"` // question asked, as sent with the rating
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Filter selects feedback
+type Filter struct {
+	Rating string
+	Since  *time.Time
+	Limit  int
+}
+
+// Matches reports whether feedback satisfies the filter
+func (f *Filter) Matches(fb *Feedback) bool {
+	if f.Rating != "" && fb.Rating != f.Rating {
+		return false
+	}
+	if f.Since != nil && fb.CreatedAt.Before(*f.Since) {
+		return false
+	}
+	return true
+}
+
+// Store persists feedback
+type Store interface {
+	// Put stores feedback, replacing the actor's earlier rating of the query
+	Put(ctx context.Context, fb *Feedback) error
+	// Get returns the actor's rating of the query, or nil without one
+	Get(ctx context.Context, queryID, actor string) (*Feedback, error)
+	// List returns matching feedback, newest first
+	List(ctx context.Context, filter Filter) ([]*Feedback, error)
+	Close() error
+}
+
+// Compile-time checks that the stores implement the interface
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+// NewStore creates the feedback store. Feedback is kept beside the
+// document registry, in the same backend.
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	switch cfg.Registry.Backend {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Feedback enabled", zap.String("backend", "redis"), zap.String("key_prefix", cfg.Registry.KeyPrefix))
+		return NewRedisStore(client, cfg.Registry.KeyPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown registry backend: %s", cfg.Registry.Backend)
+	}
+}
+
+// Stats aggregates feedback
+type Stats struct {
+	Total     int `json:"total"`
+	Up        int `json:"up"`
+	Down      int `json:"down"`
+	Commented int `json:"commented"`
+	// Satisfaction is the share of ratings that are up, or nil without
+	// ratings
+	Satisfaction *float64 `json:"satisfaction,omitempty"`
+	Queries      int      `json:"queries"`      // distinct queries rated
+	PoorlyRated  int      `json:"poorly_rated"` // queries rated down more than up
+}
+
+// RatedQuery is the feedback on one query
+type RatedQuery struct {
+	QueryID  string    `json:"query_id"`
+	Query    string    `json:"query,omitempty"`
+	Up       int       `json:"up"`
+	Down     int       `json:"down"`
+	Comments []string  `json:"comments,omitempty"`
+	LastAt   time.Time `json:"last_at"`
+}
+
+// Summarize aggregates feedback into its stats
+func Summarize(feedback []*Feedback) Stats {
+	var stats Stats
+	for _, fb := range feedback {
+		stats.Total++
+		if fb.Rating == RatingUp {
+			stats.Up++
+		} else {
+			stats.Down++
+		}
+		if fb.Comment != "" {
+			stats.Commented++
+		}
+	}
+	if stats.Total > 0 {
+		satisfaction := float64(stats.Up) / float64(stats.Total)
+		stats.Satisfaction = &satisfaction
+	}
+	queries := ByQuery(feedback)
+	stats.Queries = len(queries)
+	for _, q := range queries {
+		if q.Down > q.Up {
+			stats.PoorlyRated++
+		}
+	}
+	return stats
+}
+
+// ByQuery groups feedback by query, most recently rated first. The
+// question is taken from the latest rating sending it.
+func ByQuery(feedback []*Feedback) []*RatedQuery {
+	byID := make(map[string]*RatedQuery)
+	var queries []*RatedQuery
+	for _, fb := range feedback {
+		q, ok := byID[fb.QueryID]
+		if !ok {
+			q = &RatedQuery{QueryID: fb.QueryID}
+			byID[fb.QueryID] = q
+			queries = append(queries, q)
+		}
+		if fb.Rating == RatingUp {
+			q.Up++
+		} else {
+			q.Down++
+		}
+		if fb.Comment != "" {
+			q.Comments = append(q.Comments, fb.Comment)
+		}
+		if fb.CreatedAt.After(q.LastAt) {
+			q.LastAt = fb.CreatedAt
+			if fb.Query != "" {
+				q.Query = fb.Query
+			}
+		} else if q.Query == "" {
+			q.Query = fb.Query
+		}
+	}
+	sort.SliceStable(queries, func(i, j int) bool { return queries[i].LastAt.After(queries[j].LastAt) })
+	return queries
+}
+
+// PoorlyRated returns the queries rated down more than up, the most down
+// ratings first
+func PoorlyRated(feedback []*Feedback) []*RatedQuery {
+	poor := []*RatedQuery{}
+	for _, q := range ByQuery(feedback) {
+		if q.Down > q.Up {
+			poor = append(poor, q)
+		}
+	}
+	sort.SliceStable(poor, func(i, j int) bool {
+		return poor[i].Down-poor[i].Up > poor[j].Down-poor[j].Up
+	})
+	return poor
+}
+
+// key identifies an actor's rating of a query
+func key(fb *Feedback) string {
+	return fb.QueryID + ":" + fb.Actor
+}
+
+// newestFirst orders feedback and applies the filter's limit
+func newestFirst(feedback []*Feedback, filter Filter) []*Feedback {
+	sort.Slice(feedback, func(i, j int) bool { return feedback[i].CreatedAt.After(feedback[j].CreatedAt) })
+	if filter.Limit > 0 && len(feedback) > filter.Limit {
+		feedback = feedback[:filter.Limit]
+	}
+	return feedback
+}
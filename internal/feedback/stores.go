@@ -0,0 +1,132 @@
+package feedback
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryStore keeps feedback in process memory
+type MemoryStore struct {
+	mu       sync.RWMutex
+	feedback map[string]*Feedback
+}
+
+// NewMemoryStore creates an empty in-memory feedback store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{feedback: make(map[string]*Feedback)}
+}
+
+// Put stores feedback
+func (s *MemoryStore) Put(_ context.Context, fb *Feedback) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *fb
+	s.feedback[key(fb)] = &stored
+	return nil
+}
+
+// Get returns the actor's rating of the query
+func (s *MemoryStore) Get(_ context.Context, queryID, actor string) (*Feedback, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fb, ok := s.feedback[key(&Feedback{QueryID: queryID, Actor: actor})]
+	if !ok {
+		return nil, nil
+	}
+	copied := *fb
+	return &copied, nil
+}
+
+// List returns matching feedback
+func (s *MemoryStore) List(_ context.Context, filter Filter) ([]*Feedback, error) {
+	s.mu.RLock()
+	result := make([]*Feedback, 0, len(s.feedback))
+	for _, fb := range s.feedback {
+		if filter.Matches(fb) {
+			copied := *fb
+			result = append(result, &copied)
+		}
+	}
+	s.mu.RUnlock()
+
+	return newestFirst(result, filter), nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// RedisStore keeps feedback in a Redis hash by query ID and actor
+type RedisStore struct {
+	client   *redis.Client
+	feedback string
+}
+
+// NewRedisStore creates a Redis backed feedback store
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	return &RedisStore{
+		client:   client,
+		feedback: keyPrefix + ":feedback",
+	}
+}
+
+// Put stores feedback
+func (s *RedisStore) Put(ctx context.Context, fb *Feedback) error {
+	data, err := json.Marshal(fb)
+	if err != nil {
+		return fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+	if err := s.client.HSet(ctx, s.feedback, key(fb), data).Err(); err != nil {
+		return fmt.Errorf("failed to write feedback: %w", err)
+	}
+	return nil
+}
+
+// Get returns the actor's rating of the query
+func (s *RedisStore) Get(ctx context.Context, queryID, actor string) (*Feedback, error) {
+	data, err := s.client.HGet(ctx, s.feedback, key(&Feedback{QueryID: queryID, Actor: actor})).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feedback: %w", err)
+	}
+	var fb Feedback
+	if err := json.Unmarshal([]byte(data), &fb); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal feedback: %w", err)
+	}
+	return &fb, nil
+}
+
+// List returns matching feedback
+func (s *RedisStore) List(ctx context.Context, filter Filter) ([]*Feedback, error) {
+	values, err := s.client.HVals(ctx, s.feedback).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feedback: %w", err)
+	}
+
+	result := make([]*Feedback, 0, len(values))
+	for _, data := range values {
+		var fb Feedback
+		if err := json.Unmarshal([]byte(data), &fb); err != nil {
+			continue
+		}
+		if filter.Matches(&fb) {
+			result = append(result, &fb)
+		}
+	}
+	return newestFirst(result, filter), nil
+}
+
+// Close closes the Redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,183 @@
+// Package gateway implements the public API gateway that fronts the
+// internal services.
+package gateway
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/httpsec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/longpoll"
+	"go.uber.org/zap"
+)
+
+// webUI is the single-page search and document browsing UI
+//
+//go:embed ui/index.html
+var webUI []byte
+
+// Gateway routes public API requests to internal services
+type Gateway struct {
+	proxies map[string]*httputil.ReverseProxy
+	limiter *RateLimiter
+	config  *config.Config
+	logger  *zap.Logger
+	spec    []byte
+}
+
+// New creates a gateway for the configured upstream services
+func New(cfg *config.Config, logger *zap.Logger) (*Gateway, error) {
+	g := &Gateway{
+		proxies: make(map[string]*httputil.ReverseProxy),
+		config:  cfg,
+		logger:  logger,
+	}
+
+	for name, rawURL := range upstreamURLs(cfg.Services) {
+		target, err := url.Parse(rawURL)
+		if err != nil || target.Host == "" {
+			return nil, fmt.Errorf("invalid URL for upstream %s: %q", name, rawURL)
+		}
+		g.proxies[name] = g.newProxy(name, target)
+	}
+
+	if cfg.Gateway.RateLimit > 0 {
+		g.limiter = NewRateLimiter(cfg.Gateway.RateLimit, cfg.Gateway.RateBurst)
+	}
+
+	spec, err := buildSpec(Routes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI spec: %w", err)
+	}
+	g.spec = spec
+
+	return g, nil
+}
+
+// SetTransport makes requests to the upstream services through transport,
+// such as one presenting a client certificate
+func (g *Gateway) SetTransport(transport http.RoundTripper) {
+	for _, proxy := range g.proxies {
+		proxy.Transport = transport
+	}
+}
+
+// Register adds the public routes and documentation endpoints to the router
+func (g *Gateway) Register(router *gin.Engine) {
+	router.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", g.spec)
+	})
+	router.GET("/docs", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUI))
+	})
+	// The UI page itself is public; its API calls carry the key entered in the page
+	router.GET("/ui", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", webUI)
+	})
+	router.GET("/", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, "/ui")
+	})
+
+	api := router.Group("/", requestID(), Authenticate(g.config.Gateway))
+	if g.limiter != nil {
+		api.Use(g.limiter.Middleware())
+	}
+	for _, route := range Routes {
+		if route.Admin() {
+			api.Handle(route.Method, route.Path, RequireScope(config.ScopeAdmin), g.forward(route))
+			continue
+		}
+		api.Handle(route.Method, route.Path, g.forward(route))
+	}
+}
+
+// StartCleanup periodically drops idle rate limit buckets until stop is closed
+func (g *Gateway) StartCleanup(stop <-chan struct{}) {
+	if g.limiter == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				g.limiter.Cleanup()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// forward rewrites the request path to the upstream endpoint and proxies it
+func (g *Gateway) forward(route Route) gin.HandlerFunc {
+	proxy := g.proxies[route.Upstream]
+	return func(c *gin.Context) {
+		path := route.Target
+		for _, param := range c.Params {
+			path = strings.Replace(path, ":"+param.Key, url.PathEscape(param.Value), 1)
+		}
+		c.Request.URL.Path = path
+		c.Request.URL.RawPath = ""
+
+		// The gateway authenticates callers itself; upstream services never see
+		// the key, and trust the identity it sets
+		c.Request.Header.Del("X-API-Key")
+		c.Request.Header.Del("Authorization")
+		setIdentity(c)
+		c.Request.Header.Del(httpsec.HeaderAdminToken)
+		if route.Admin() && g.config.Services.AdminToken != "" {
+			c.Request.Header.Set(httpsec.HeaderAdminToken, g.config.Services.AdminToken)
+		}
+
+		// Status requests may wait upstream for longer than the write timeout
+		if wait, err := longpoll.ParseWait(c); err == nil && wait > 0 {
+			longpoll.Extend(c, wait)
+		}
+
+		proxy.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+func (g *Gateway) newProxy(name string, target *url.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	// Flush immediately so server-sent events reach the client as they arrive
+	proxy.FlushInterval = -1
+	// The gateway's own CORS and security headers apply to what it serves
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		httpsec.DropHeaders(resp.Header)
+		return nil
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		g.logger.Error("Upstream request failed",
+			zap.String("upstream", name),
+			zap.String("path", r.URL.Path),
+			zap.Error(err))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprintf(w, `{"error":"upstream %s unavailable"}`, name)
+	}
+	return proxy
+}
+
+// requestID assigns an X-Request-ID to requests that do not carry one
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if id == "" {
+			id = uuid.New().String()
+			c.Request.Header.Set("X-Request-ID", id)
+		}
+		c.Header("X-Request-ID", id)
+		c.Next()
+	}
+}
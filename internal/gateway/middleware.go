@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+)
+
+// Identity headers the services behind the gateway trust
+const (
+	headerUserID     = "X-User-ID"
+	headerUserGroups = "X-User-Groups"
+	headerTenant     = "X-Tenant-ID"
+)
+
+// contextKeyClient holds the client key used for rate limiting
+const contextKeyClient = "gateway.client"
+
+// contextKeyCaller holds the gateway client a request authenticated as
+const contextKeyCaller = "gateway.caller"
+
+// Authenticate requires the key of one of the configured clients, or one of
+// the plain API keys, in the X-API-Key header or as a bearer token. With
+// authentication disabled, every caller is an admin without an identity.
+func Authenticate(cfg config.GatewayConfig) gin.HandlerFunc {
+	clients := make([]config.GatewayClient, 0, len(cfg.APIKeys)+len(cfg.Clients))
+	for _, key := range cfg.APIKeys {
+		clients = append(clients, config.GatewayClient{Key: key})
+	}
+	clients = append(clients, cfg.Clients...)
+
+	return func(c *gin.Context) {
+		if len(clients) == 0 {
+			c.Set(contextKeyClient, "ip:"+c.ClientIP())
+			c.Set(contextKeyCaller, config.GatewayClient{Scopes: []string{config.ScopeAdmin}})
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			key = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+
+		for _, client := range clients {
+			if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(client.Key)) == 1 {
+				c.Set(contextKeyClient, "key:"+keyID(client.Key))
+				c.Set(contextKeyCaller, client)
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid API key"})
+	}
+}
+
+// caller returns the gateway client a request authenticated as
+func caller(c *gin.Context) config.GatewayClient {
+	client, _ := c.Get(contextKeyCaller)
+	gc, _ := client.(config.GatewayClient)
+	return gc
+}
+
+// RequireScope refuses requests from clients without scope with 403
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !caller(c).HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API key lacks the %s scope", scope)})
+			return
+		}
+		c.Next()
+	}
+}
+
+// setIdentity replaces the identity headers of a request with those of the
+// client it authenticated as. Clients with the delegate scope keep the
+// identity they name and fall back to their own.
+func setIdentity(c *gin.Context) {
+	client := caller(c)
+	header := c.Request.Header
+	if client.HasScope(config.ScopeDelegate) && (header.Get(headerUserID) != "" || header.Get(headerTenant) != "") {
+		return
+	}
+
+	header.Del(headerUserID)
+	header.Del(headerUserGroups)
+	header.Del(headerTenant)
+	if client.UserID != "" {
+		header.Set(headerUserID, client.UserID)
+		if len(client.Groups) > 0 {
+			header.Set(headerUserGroups, strings.Join(client.Groups, ","))
+		}
+	}
+	if client.Tenant != "" {
+		header.Set(headerTenant, client.Tenant)
+	}
+}
+
+// keyID identifies an API key without keeping the key itself in memory maps or logs
+func keyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+// RateLimiter applies a token bucket per client
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter allowing rate requests per second with the given burst
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token for the client and returns how long to wait when none is left
+func (l *RateLimiter) Allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// Cleanup drops buckets that have been idle long enough to be full again
+func (l *RateLimiter) Cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	idle := time.Duration(l.burst / l.rate * float64(time.Second))
+	for client, b := range l.buckets {
+		if time.Since(b.last) > idle {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// Middleware rejects requests over the limit with 429
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := c.GetString(contextKeyClient)
+		if client == "" {
+			client = "ip:" + c.ClientIP()
+		}
+
+		if ok, wait := l.Allow(client); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,672 @@
+package gateway
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/apispec"
+	"github.com/nadeeshame/rag-knowledge-service/internal/collections"
+	"github.com/nadeeshame/rag-knowledge-service/internal/dlq"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+)
+
+// schemas are the component schemas referenced by the route table
+var schemas = map[string]interface{}{
+	"Object": map[string]interface{}{"type": "object", "additionalProperties": true},
+	"Error": object(map[string]interface{}{
+		"error": str(),
+	}, "error"),
+	"IngestDocumentRequest": object(map[string]interface{}{
+		"file_path":       str(),
+		"force_reprocess": boolean(),
+		"priority":        ref("IngestPriority"),
+	}, "file_path"),
+	"IngestDirectoryRequest": object(map[string]interface{}{
+		"directory":       str(),
+		"recursive":       boolean(),
+		"force_reprocess": boolean(),
+		"priority":        ref("IngestPriority"),
+	}, "directory"),
+	"IngestBatchRequest": object(map[string]interface{}{
+		"items":           array(str()),
+		"force_reprocess": boolean(),
+	}, "items"),
+	"IngestBatchItem": object(map[string]interface{}{
+		"item":      str(),
+		"file_path": str(),
+		"status":    map[string]interface{}{"type": "string", "enum": []string{"accepted", "skipped", "invalid"}},
+		"reason":    str(),
+	}),
+	"IngestBatchResponse": object(map[string]interface{}{
+		"status":   str(),
+		"run_id":   str(),
+		"accepted": integer(),
+		"skipped":  integer(),
+		"invalid":  integer(),
+		"items":    array(ref("IngestBatchItem")),
+	}),
+	"IngestPriority": map[string]interface{}{
+		"type":        "string",
+		"enum":        []string{"high", "normal", "low"},
+		"description": "high for interactive uploads, low for bulk backfills; defaults to normal",
+	},
+	"IngestResponse": object(map[string]interface{}{
+		"status":      str(),
+		"document_id": str(),
+		"message":     str(),
+		"priority":    ref("IngestPriority"),
+		"queued":      integer(),
+		"job_id":      str(),
+	}),
+	"RunSummary": object(map[string]interface{}{
+		"id":          str(),
+		"directory":   str(),
+		"batch":       boolean(),
+		"status":      str(),
+		"remaining":   integer(),
+		"started_at":  dateTime(),
+		"finished_at": dateTime(),
+		"total_files": integer(),
+		"processed":   integer(),
+		"skipped":     integer(),
+		"failed":      integer(),
+		"added":       integer(),
+		"updated":     integer(),
+		"duration_ms": integer(),
+		"stage_ms":    ref("StageDurations"),
+		"tokens":      ref("TokenUsage"),
+	}),
+	"StageDurations": map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": integer(),
+		"description":          "milliseconds spent in each stage: scan, extract, vision, summary, chunk, embed, index",
+	},
+	"TokenUsage": apispec.SchemaFor(runs.TokenUsage{}),
+	"RunFile":    apispec.SchemaFor(runs.FileReport{}),
+	"RunReport": object(map[string]interface{}{
+		"id":          str(),
+		"directory":   str(),
+		"batch":       boolean(),
+		"status":      str(),
+		"remaining":   integer(),
+		"started_at":  dateTime(),
+		"finished_at": dateTime(),
+		"total_files": integer(),
+		"processed":   integer(),
+		"skipped":     integer(),
+		"failed":      integer(),
+		"added":       integer(),
+		"updated":     integer(),
+		"duration_ms": integer(),
+		"stage_ms":    ref("StageDurations"),
+		"tokens":      ref("TokenUsage"),
+		"files":       array(ref("RunFile")),
+	}),
+	"RunList": object(map[string]interface{}{
+		"runs":  array(ref("RunSummary")),
+		"count": integer(),
+	}),
+	"RunChange": apispec.SchemaFor(runs.Change{}),
+	"RunChanges": object(map[string]interface{}{
+		"run_id":      str(),
+		"directory":   str(),
+		"status":      str(),
+		"started_at":  dateTime(),
+		"finished_at": dateTime(),
+		"changes":     array(ref("RunChange")),
+		"count":       integer(),
+		"digest":      str(),
+	}),
+	"RunAccepted": object(map[string]interface{}{
+		"status":    str(),
+		"run_id":    str(),
+		"remaining": integer(),
+	}),
+	"IndexingStatus": object(map[string]interface{}{
+		"paused":      boolean(),
+		"active_runs": array(str()),
+		"queue":       ref("IngestQueue"),
+		"degraded_stages": map[string]interface{}{
+			"type":        "array",
+			"items":       str(),
+			"description": "optional stages (vision, summary) skipped while their dependency keeps failing",
+		},
+	}),
+	"IngestQueue": object(map[string]interface{}{
+		"depth":       map[string]interface{}{"type": "object", "additionalProperties": integer(), "description": "files waiting at each priority"},
+		"oldest_wait": map[string]interface{}{"type": "object", "additionalProperties": str(), "description": "age of the oldest waiting file at each priority"},
+		"capacity":    integer(),
+		"workers":     integer(),
+		"in_flight":   integer(),
+		"completed":   integer(),
+		"skipped":     integer(),
+		"failed":      integer(),
+		"shared":      map[string]interface{}{"type": "boolean", "description": "set when the orchestrator replicas share the queue"},
+		"claimed":     map[string]interface{}{"type": "integer", "description": "files being processed by any replica of a shared queue"},
+	}),
+	"QueryFilter": object(map[string]interface{}{
+		"file_type":  str(),
+		"date_from":  dateTime(),
+		"date_to":    dateTime(),
+		"metadata":   map[string]interface{}{"type": "object", "additionalProperties": str()},
+		"collection": map[string]interface{}{"type": "string", "description": "only documents in this collection"},
+	}),
+	"QueryRequest": object(map[string]interface{}{
+		"text":      str(),
+		"top_k":     integer(),
+		"namespace": str(),
+		"filter":    ref("QueryFilter"),
+		"as_of":     map[string]interface{}{"type": "string", "description": "YYYY-MM-DD or RFC 3339 timestamp"},
+	}, "text"),
+	"SearchResult": object(map[string]interface{}{
+		"document_id": str(),
+		"chunk_id":    str(),
+		"score":       map[string]interface{}{"type": "number"},
+		"content":     str(),
+		"file_name":   str(),
+		"file_path":   str(),
+		"file_type":   str(),
+		"metadata":    map[string]interface{}{"type": "object", "additionalProperties": str()},
+	}),
+	"QueryResult": object(map[string]interface{}{
+		"query_id":  str(),
+		"answer":    str(),
+		"sources":   array(ref("SearchResult")),
+		"as_of":     dateTime(),
+		"trace":     ref("QueryTrace"),
+		"timestamp": dateTime(),
+	}),
+	"QueryTrace": object(map[string]interface{}{
+		"injection_score": map[string]interface{}{"type": "number"},
+		"injections":      array(ref("InjectionFinding")),
+		"moderation":      array(ref("ModerationVerdict")),
+	}),
+	"ModerationVerdict": object(map[string]interface{}{
+		"subject":    str(),
+		"action":     str(),
+		"categories": array(ref("SafetyCategory")),
+	}),
+	"SafetyCategory": object(map[string]interface{}{
+		"category": str(),
+		"severity": integer(),
+	}),
+	"InjectionFinding": object(map[string]interface{}{
+		"source":    integer(),
+		"file_path": str(),
+		"score":     map[string]interface{}{"type": "number"},
+		"patterns":  array(str()),
+		"sanitized": boolean(),
+	}),
+	"SearchResponse": object(map[string]interface{}{
+		"query_id":   str(),
+		"results":    array(ref("SearchResult")),
+		"total":      integer(),
+		"moderation": array(ref("ModerationVerdict")),
+	}),
+	"ComparisonTarget": object(map[string]interface{}{
+		"document_id": str(),
+		"file_path":   str(),
+	}),
+	"CompareRequest": object(map[string]interface{}{
+		"text":   str(),
+		"left":   ref("ComparisonTarget"),
+		"right":  ref("ComparisonTarget"),
+		"top_k":  map[string]interface{}{"type": "integer", "description": "passages retrieved from each document"},
+		"filter": ref("QueryFilter"),
+		"as_of":  map[string]interface{}{"type": "string", "description": "YYYY-MM-DD or RFC 3339 timestamp"},
+	}, "text", "left", "right"),
+	"Comparison": object(map[string]interface{}{
+		"query_id":     str(),
+		"summary":      str(),
+		"similarities": array(str()),
+		"differences": array(object(map[string]interface{}{
+			"aspect":  str(),
+			"left":    str(),
+			"right":   str(),
+			"sources": array(integer()),
+		})),
+		"left_sources":  array(ref("SearchResult")),
+		"right_sources": array(ref("SearchResult")),
+		"as_of":         dateTime(),
+		"trace":         ref("QueryTrace"),
+		"timestamp":     dateTime(),
+	}),
+	"FeedbackRequest": object(map[string]interface{}{
+		"query_id": str(),
+		"rating": map[string]interface{}{
+			"type": "string",
+			"enum": []string{"up", "down"},
+		},
+		"comment": str(),
+		"query":   map[string]interface{}{"type": "string", "description": "question asked, kept so poorly rated queries can be evaluated again"},
+	}, "query_id", "rating"),
+	"Feedback": object(map[string]interface{}{
+		"query_id":   str(),
+		"rating":     str(),
+		"comment":    str(),
+		"query":      str(),
+		"actor":      str(),
+		"created_at": dateTime(),
+	}),
+	"FeedbackList": object(map[string]interface{}{
+		"feedback": array(ref("Feedback")),
+		"count":    integer(),
+	}),
+	"FeedbackStats": object(map[string]interface{}{
+		"total":        integer(),
+		"up":           integer(),
+		"down":         integer(),
+		"commented":    integer(),
+		"satisfaction": map[string]interface{}{"type": "number"},
+		"queries":      integer(),
+		"poorly_rated": integer(),
+	}),
+	"RatedQueries": object(map[string]interface{}{
+		"queries": array(object(map[string]interface{}{
+			"query_id": str(),
+			"query":    str(),
+			"up":       integer(),
+			"down":     integer(),
+			"comments": array(str()),
+			"last_at":  dateTime(),
+		})),
+		"count": integer(),
+	}),
+	"QueryAnalytics": object(map[string]interface{}{
+		"since":            dateTime(),
+		"until":            dateTime(),
+		"queries":          integer(),
+		"distinct":         integer(),
+		"failed":           integer(),
+		"zero_results":     integer(),
+		"zero_result_rate": map[string]interface{}{"type": "number"},
+		"latency": object(map[string]interface{}{
+			"p50_ms": integer(),
+			"p90_ms": integer(),
+			"p95_ms": integer(),
+			"p99_ms": integer(),
+			"max_ms": integer(),
+		}),
+		"up":                  integer(),
+		"down":                integer(),
+		"top":                 array(ref("QueryStats")),
+		"zero_result_queries": array(ref("QueryStats")),
+	}),
+	"QueryStats": object(map[string]interface{}{
+		"hash":         str(),
+		"text":         map[string]interface{}{"type": "string", "description": "normalized query text, with ANALYTICS_KEEP_TEXT"},
+		"count":        integer(),
+		"zero_results": integer(),
+		"up":           integer(),
+		"down":         integer(),
+		"last_at":      dateTime(),
+	}),
+	"TopicOverview": object(map[string]interface{}{
+		"generated_at": dateTime(),
+		"documents":    integer(),
+		"skipped":      integer(),
+		"topics": array(object(map[string]interface{}{
+			"id":          integer(),
+			"label":       str(),
+			"description": str(),
+			"size":        integer(),
+			"documents": array(object(map[string]interface{}{
+				"document_id": str(),
+				"file_path":   str(),
+				"title":       str(),
+				"similarity":  map[string]interface{}{"type": "number"},
+			})),
+		})),
+	}),
+	"PopularDocuments": object(map[string]interface{}{
+		"documents": array(object(map[string]interface{}{
+			"document_id":  str(),
+			"file_path":    str(),
+			"answers":      integer(),
+			"up":           integer(),
+			"down":         integer(),
+			"last_used_at": dateTime(),
+			"popularity":   map[string]interface{}{"type": "number"},
+		})),
+		"count": integer(),
+	}),
+	"SimilarDocuments": object(map[string]interface{}{
+		"document_id": str(),
+		"method":      str(),
+		"documents": array(object(map[string]interface{}{
+			"document_id": str(),
+			"file_name":   str(),
+			"file_path":   str(),
+			"score":       map[string]interface{}{"type": "number"},
+			"matches":     integer(),
+		})),
+		"count": integer(),
+	}),
+	"DuplicateReport": object(map[string]interface{}{
+		"generated_at": dateTime(),
+		"documents":    integer(),
+		"threshold":    map[string]interface{}{"type": "number"},
+		"exact":        array(ref("DuplicateGroup")),
+		"near":         array(ref("DuplicateGroup")),
+		"unembedded":   integer(),
+		"redundant":    integer(),
+	}),
+	"DuplicateGroup": object(map[string]interface{}{
+		"file_hash":  str(),
+		"similarity": map[string]interface{}{"type": "number"},
+		"documents": array(object(map[string]interface{}{
+			"document_id": str(),
+			"file_path":   str(),
+			"file_hash":   str(),
+			"indexed_at":  dateTime(),
+			"similarity":  map[string]interface{}{"type": "number"},
+		})),
+	}),
+	"EventPage": object(map[string]interface{}{
+		"events": array(object(map[string]interface{}{
+			"id":        str(),
+			"event_id":  str(),
+			"type":      map[string]interface{}{"type": "string", "enum": []string{"document.state-changed", "run.progress"}},
+			"timestamp": dateTime(),
+			"data":      ref("Object"),
+		})),
+		"next": str(),
+	}),
+	"RerunRequest": object(map[string]interface{}{
+		"document_ids": array(str()),
+	}, "document_ids"),
+	"RerunResponse": object(map[string]interface{}{
+		"status":   str(),
+		"accepted": array(str()),
+		"rejected": map[string]interface{}{"type": "object", "additionalProperties": str()},
+	}),
+	"DeleteResponse": object(map[string]interface{}{
+		"deleted": boolean(),
+		"count":   integer(),
+	}),
+	"ExistsResponse": object(map[string]interface{}{
+		"exists": boolean(),
+	}),
+	"AuditEvent": object(map[string]interface{}{
+		"id":           str(),
+		"timestamp":    dateTime(),
+		"actor":        str(),
+		"action":       str(),
+		"resource":     str(),
+		"document_ids": array(str()),
+		"outcome":      str(),
+		"details":      map[string]interface{}{"type": "object", "additionalProperties": str()},
+	}),
+	"Document": apispec.SchemaFor(registry.Record{}),
+	"DocumentList": object(map[string]interface{}{
+		"documents": array(ref("Document")),
+		"count":     integer(),
+	}),
+	"StaleDocuments": object(map[string]interface{}{
+		"months": integer(),
+		"before": dateTime(),
+		"documents": array(object(map[string]interface{}{
+			"document_id": str(),
+			"file_path":   str(),
+			"modified_at": dateTime(),
+			"indexed_at":  dateTime(),
+			"age_days":    integer(),
+		})),
+		"count": integer(),
+	}),
+	"DocumentLink": object(map[string]interface{}{
+		"target":      str(),
+		"document_id": str(),
+		"file_path":   str(),
+		"title":       str(),
+	}),
+	"DocumentLinks": object(map[string]interface{}{
+		"document_id": str(),
+		"links":       array(ref("DocumentLink")),
+		"backlinks":   array(ref("DocumentLink")),
+		"unresolved":  array(str()),
+	}),
+	"Collection": apispec.SchemaFor(collections.Collection{}),
+	"CollectionSummary": object(map[string]interface{}{
+		"name":           str(),
+		"description":    str(),
+		"document_count": integer(),
+		"created_at":     dateTime(),
+		"updated_at":     dateTime(),
+	}),
+	"CollectionList": object(map[string]interface{}{
+		"collections": array(ref("CollectionSummary")),
+		"count":       integer(),
+	}),
+	"CreateCollectionRequest": object(map[string]interface{}{
+		"name":        str(),
+		"description": str(),
+	}, "name"),
+	"CollectionDocumentsRequest": object(map[string]interface{}{
+		"document_ids": array(str()),
+	}, "document_ids"),
+	"CollectionUpdateResponse": object(map[string]interface{}{
+		"collection": ref("Collection"),
+		"added":      integer(),
+		"rejected":   map[string]interface{}{"type": "object", "additionalProperties": str()},
+	}),
+	"DeadLetter": apispec.SchemaFor(dlq.Entry{}),
+	"DeadLetterList": object(map[string]interface{}{
+		"entries": array(ref("DeadLetter")),
+		"count":   integer(),
+	}),
+	"DeadLetterRetryRequest": object(map[string]interface{}{
+		"ids":      array(str()),
+		"all":      boolean(),
+		"priority": ref("IngestPriority"),
+	}),
+	"DeadLetterRetryResponse": object(map[string]interface{}{
+		"status":   str(),
+		"queued":   array(str()),
+		"rejected": map[string]interface{}{"type": "object", "additionalProperties": str()},
+	}),
+	"DigestList": object(map[string]interface{}{
+		"reports": array(ref("Object")),
+		"count":   integer(),
+	}),
+	"DigestRun": object(map[string]interface{}{
+		"digest":    ref("Object"),
+		"body":      str(),
+		"delivered": boolean(),
+	}),
+	"CreateIndexRequest": apispec.SchemaFor(pinecone.CreateIndexRequest{}),
+	"PineconeIndex":      apispec.SchemaFor(pinecone.Index{}),
+	"AuditResponse": object(map[string]interface{}{
+		"events": array(ref("AuditEvent")),
+		"count":  integer(),
+	}),
+}
+
+// buildSpec generates the OpenAPI 3 document for the given routes
+func buildSpec(routes []Route) ([]byte, error) {
+	paths := make(map[string]map[string]interface{})
+	for _, route := range routes {
+		path, params := openAPIPath(route.Path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(route.Method)] = operation(route, params)
+	}
+
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, route := range routes {
+		if !seen[route.Tag] {
+			seen[route.Tag] = true
+			tags = append(tags, route.Tag)
+		}
+	}
+	sort.Strings(tags)
+	tagList := make([]map[string]string, 0, len(tags))
+	for _, tag := range tags {
+		tagList = append(tagList, map[string]string{"name": tag})
+	}
+
+	spec := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "RepoGraph Platform API",
+			"version":     "1.0.0",
+			"description": "Public API for document ingestion, querying and administration.",
+		},
+		"tags":  tagList,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"ApiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+		"security": []map[string][]string{{"ApiKey": {}}},
+	}
+
+	return json.MarshalIndent(spec, "", "  ")
+}
+
+func operation(route Route, params []string) map[string]interface{} {
+	op := map[string]interface{}{
+		"tags":        []string{route.Tag},
+		"summary":     route.Summary,
+		"operationId": operationID(route),
+	}
+
+	parameters := make([]map[string]interface{}, 0, len(params)+1)
+	for _, p := range params {
+		parameters = append(parameters, map[string]interface{}{
+			"name": p, "in": "path", "required": true, "schema": str(),
+		})
+	}
+	if idempotent(route) {
+		parameters = append(parameters, map[string]interface{}{
+			"name": "Idempotency-Key", "in": "header", "required": false, "schema": str(),
+			"description": "retries with the same key replay the first response instead of repeating the change",
+		})
+	}
+	op["parameters"] = parameters
+
+	if route.Request != "" {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": ref(route.Request)}},
+		}
+	}
+
+	success := map[string]interface{}{"description": "Success"}
+	if route.Stream {
+		success["content"] = map[string]interface{}{"text/event-stream": map[string]interface{}{"schema": str()}}
+	} else if route.Text {
+		success["content"] = map[string]interface{}{"text/plain": map[string]interface{}{"schema": str()}}
+	} else if route.Image {
+		success["content"] = map[string]interface{}{"image/png": map[string]interface{}{"schema": binary()}}
+	} else if route.Response != "" {
+		success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": ref(route.Response)}}
+	}
+
+	errors := map[string]string{
+		"400": "Invalid request",
+		"401": "Missing or invalid API key",
+		"429": "Rate limit exceeded",
+		"502": "Upstream service unavailable",
+	}
+	if route.Admin() {
+		errors["403"] = "API key lacks the admin scope"
+		op["description"] = "Needs an API key with the admin scope."
+	}
+	responses := map[string]interface{}{"200": success}
+	for code, description := range errors {
+		responses[code] = map[string]interface{}{
+			"description": description,
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": ref("Error")}},
+		}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// openAPIPath converts gin path parameters (:id) to OpenAPI syntax ({id})
+func openAPIPath(path string) (string, []string) {
+	var params []string
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			name := segment[1:]
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// idempotent reports whether a route accepts an Idempotency-Key header: the
+// mutations of the orchestrator and vector store
+func idempotent(route Route) bool {
+	if route.Method == "GET" {
+		return false
+	}
+	return route.Upstream == upstreamOrchestrator || route.Upstream == upstreamVectorStore
+}
+
+// operationID derives a stable identifier such as postV1QuerySearch
+func operationID(route Route) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.Method))
+	for _, segment := range strings.FieldsFunc(route.Path, func(r rune) bool { return r == '/' || r == ':' }) {
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return b.String()
+}
+
+func object(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func array(items map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": "array", "items": items}
+}
+
+func str() map[string]interface{}     { return map[string]interface{}{"type": "string"} }
+func integer() map[string]interface{} { return map[string]interface{}{"type": "integer"} }
+func boolean() map[string]interface{} { return map[string]interface{}{"type": "boolean"} }
+func dateTime() map[string]interface{} {
+	return map[string]interface{}{"type": "string", "format": "date-time"}
+}
+func binary() map[string]interface{} {
+	return map[string]interface{}{"type": "string", "format": "binary"}
+}
+
+// swaggerUI renders the spec served at /openapi.json
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>RepoGraph Platform API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
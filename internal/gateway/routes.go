@@ -0,0 +1,161 @@
+package gateway
+
+import "github.com/nadeeshame/rag-knowledge-service/internal/config"
+
+// Upstream services the gateway routes to
+const (
+	upstreamOrchestrator = "orchestrator"
+	upstreamQuery        = "query"
+	upstreamVectorStore  = "vector-store"
+)
+
+// Route maps a public endpoint to an internal service endpoint. The route
+// table is the single source for both the router and the OpenAPI schema.
+type Route struct {
+	Method   string
+	Path     string // public path, gin syntax
+	Upstream string
+	Target   string // upstream path, gin syntax with the same parameters
+	Tag      string
+	Summary  string
+	Request  string // request schema name, empty for no body
+	Response string // response schema name
+	Stream   bool   // response is server-sent events
+	Text     bool   // response is plain text
+	Image    bool   // response is a PNG image
+}
+
+// tagAdmin tags the administration routes, which need the admin scope
+const tagAdmin = "admin"
+
+// Admin reports whether the route needs a client with the admin scope
+func (r Route) Admin() bool {
+	return r.Tag == tagAdmin
+}
+
+// Routes is the public API exposed by the gateway
+var Routes = []Route{
+	{Method: "POST", Path: "/v1/ingest/document", Upstream: upstreamOrchestrator, Target: "/api/v1/process/document",
+		Tag: "ingest", Summary: "Queue a single document for processing", Request: "IngestDocumentRequest", Response: "IngestResponse"},
+	{Method: "POST", Path: "/v1/ingest/directory", Upstream: upstreamOrchestrator, Target: "/api/v1/process/directory",
+		Tag: "ingest", Summary: "Queue all documents in a directory for processing", Request: "IngestDirectoryRequest", Response: "IngestResponse"},
+	{Method: "POST", Path: "/v1/ingest/batch", Upstream: upstreamOrchestrator, Target: "/api/v1/process/batch",
+		Tag: "ingest", Summary: "Process a list of files as one run, reporting the status of each", Request: "IngestBatchRequest", Response: "IngestBatchResponse"},
+	{Method: "GET", Path: "/v1/ingest/status/:id", Upstream: upstreamOrchestrator, Target: "/api/v1/status/:id",
+		Tag: "ingest", Summary: "Get the processing status of a document", Response: "Object"},
+	{Method: "GET", Path: "/v1/ingest/runs", Upstream: upstreamOrchestrator, Target: "/api/v1/runs",
+		Tag: "ingest", Summary: "List recent indexing runs, newest first", Response: "RunList"},
+	{Method: "GET", Path: "/v1/ingest/runs/:id", Upstream: upstreamOrchestrator, Target: "/api/v1/runs/:id",
+		Tag: "ingest", Summary: "Get the report of an indexing run: the outcome, stage durations and token usage of each file", Response: "RunReport"},
+	{Method: "GET", Path: "/v1/ingest/runs/:id/changes", Upstream: upstreamOrchestrator, Target: "/api/v1/runs/:id/changes",
+		Tag: "ingest", Summary: "Get the documents an indexing run added or updated, with a digest of the new content", Response: "RunChanges"},
+	{Method: "DELETE", Path: "/v1/ingest/runs/:id", Upstream: upstreamOrchestrator, Target: "/api/v1/runs/:id",
+		Tag: "admin", Summary: "Cancel a run in progress after its current file, keeping the files left for a resume", Response: "RunAccepted"},
+	{Method: "POST", Path: "/v1/ingest/runs/:id/resume", Upstream: upstreamOrchestrator, Target: "/api/v1/runs/:id/resume",
+		Tag: "admin", Summary: "Resume a cancelled run with the files it did not reach", Response: "RunAccepted"},
+
+	{Method: "POST", Path: "/v1/query", Upstream: upstreamQuery, Target: "/api/v1/ask",
+		Tag: "query", Summary: "Answer a question using retrieved context", Request: "QueryRequest", Response: "QueryResult"},
+	{Method: "POST", Path: "/v1/query/search", Upstream: upstreamQuery, Target: "/api/v1/search",
+		Tag: "query", Summary: "Search for relevant document chunks", Request: "QueryRequest", Response: "SearchResponse"},
+	{Method: "POST", Path: "/v1/query/compare", Upstream: upstreamQuery, Target: "/api/v1/compare",
+		Tag: "query", Summary: "Compare two documents on a question", Request: "CompareRequest", Response: "Comparison"},
+	{Method: "POST", Path: "/v1/query/stream", Upstream: upstreamQuery, Target: "/api/v1/stream",
+		Tag: "query", Summary: "Stream an answer as server-sent events", Request: "QueryRequest", Stream: true},
+	{Method: "POST", Path: "/v1/feedback", Upstream: upstreamQuery, Target: "/api/v1/feedback",
+		Tag: "query", Summary: "Rate an answer up or down by its query_id, with an optional comment", Request: "FeedbackRequest", Response: "Feedback"},
+
+	{Method: "GET", Path: "/v1/documents", Upstream: upstreamOrchestrator, Target: "/api/v1/documents",
+		Tag: "documents", Summary: "List indexed documents with their processing state", Response: "DocumentList"},
+	{Method: "GET", Path: "/v1/documents/stale", Upstream: upstreamOrchestrator, Target: "/api/v1/documents/stale",
+		Tag: "documents", Summary: "List indexed documents not modified for months (months=12), oldest first", Response: "StaleDocuments"},
+	{Method: "GET", Path: "/v1/documents/popular", Upstream: upstreamQuery, Target: "/api/v1/documents/popular",
+		Tag: "documents", Summary: "List the documents used in the most answers, with the ratings of those answers", Response: "PopularDocuments"},
+	{Method: "GET", Path: "/v1/documents/:id", Upstream: upstreamOrchestrator, Target: "/api/v1/documents/:id",
+		Tag: "documents", Summary: "Get a document with its summary and error", Response: "Document"},
+	{Method: "GET", Path: "/v1/documents/:id/content", Upstream: upstreamOrchestrator, Target: "/api/v1/documents/:id/content",
+		Tag: "documents", Summary: "Get the extracted text of a document", Text: true},
+	{Method: "GET", Path: "/v1/documents/:id/thumbnail", Upstream: upstreamOrchestrator, Target: "/api/v1/documents/:id/thumbnail",
+		Tag: "documents", Summary: "Get the PNG thumbnail of an image document", Image: true},
+	{Method: "GET", Path: "/v1/documents/:id/links", Upstream: upstreamOrchestrator, Target: "/api/v1/documents/:id/links",
+		Tag: "documents", Summary: "Get the wiki-links of a note and the notes linking to it", Response: "DocumentLinks"},
+	{Method: "GET", Path: "/v1/topics", Upstream: upstreamOrchestrator, Target: "/api/v1/topics",
+		Tag: "documents", Summary: "Get the topic overview: clusters of similar documents, named by the model", Response: "TopicOverview"},
+	{Method: "POST", Path: "/v1/topics/run", Upstream: upstreamOrchestrator, Target: "/api/v1/topics/run",
+		Tag: "admin", Summary: "Generate the topic overview now", Response: "TopicOverview"},
+	{Method: "GET", Path: "/v1/documents/:id/similar", Upstream: upstreamQuery, Target: "/api/v1/documents/:id/similar",
+		Tag: "documents", Summary: "Find the documents most like a document by their chunk vectors", Response: "SimilarDocuments"},
+	{Method: "POST", Path: "/v1/documents/:id/reindex", Upstream: upstreamOrchestrator, Target: "/api/v1/documents/:id/reindex",
+		Tag: "admin", Summary: "Process a document's file again", Response: "IngestResponse"},
+	{Method: "POST", Path: "/v1/documents/rechunk", Upstream: upstreamOrchestrator, Target: "/api/v1/documents/rechunk",
+		Tag: "admin", Summary: "Chunk and embed documents again from their stored content", Request: "RerunRequest", Response: "RerunResponse"},
+	{Method: "POST", Path: "/v1/documents/resummarize", Upstream: upstreamOrchestrator, Target: "/api/v1/documents/resummarize",
+		Tag: "admin", Summary: "Summarize documents again from their stored content", Request: "RerunRequest", Response: "RerunResponse"},
+	{Method: "DELETE", Path: "/v1/documents/:id", Upstream: upstreamVectorStore, Target: "/api/v1/document/:id",
+		Tag: "admin", Summary: "Delete all vectors of a document", Response: "DeleteResponse"},
+	{Method: "GET", Path: "/v1/documents/exists/:hash", Upstream: upstreamVectorStore, Target: "/api/v1/exists/:hash",
+		Tag: "documents", Summary: "Check whether a file hash is indexed", Response: "ExistsResponse"},
+
+	{Method: "GET", Path: "/v1/collections", Upstream: upstreamOrchestrator, Target: "/api/v1/collections",
+		Tag: "collections", Summary: "List collections", Response: "CollectionList"},
+	{Method: "POST", Path: "/v1/collections", Upstream: upstreamOrchestrator, Target: "/api/v1/collections",
+		Tag: "collections", Summary: "Create a collection", Request: "CreateCollectionRequest", Response: "Collection"},
+	{Method: "GET", Path: "/v1/collections/:name", Upstream: upstreamOrchestrator, Target: "/api/v1/collections/:name",
+		Tag: "collections", Summary: "Get a collection with the file paths of its documents", Response: "Collection"},
+	{Method: "DELETE", Path: "/v1/collections/:name", Upstream: upstreamOrchestrator, Target: "/api/v1/collections/:name",
+		Tag: "admin", Summary: "Delete a collection", Response: "Object"},
+	{Method: "POST", Path: "/v1/collections/:name/documents", Upstream: upstreamOrchestrator, Target: "/api/v1/collections/:name/documents",
+		Tag: "collections", Summary: "Add documents to a collection", Request: "CollectionDocumentsRequest", Response: "CollectionUpdateResponse"},
+	{Method: "DELETE", Path: "/v1/collections/:name/documents/:id", Upstream: upstreamOrchestrator, Target: "/api/v1/collections/:name/documents/:id",
+		Tag: "collections", Summary: "Remove a document from a collection", Response: "Collection"},
+
+	{Method: "GET", Path: "/v1/admin/audit", Upstream: upstreamOrchestrator, Target: "/api/v1/audit",
+		Tag: "admin", Summary: "List audit log events", Response: "AuditResponse"},
+	{Method: "GET", Path: "/v1/admin/indexing", Upstream: upstreamOrchestrator, Target: "/api/v1/indexing",
+		Tag: "admin", Summary: "Report whether indexing is paused, which runs are in progress and the ingest queue depth per priority", Response: "IndexingStatus"},
+	{Method: "POST", Path: "/v1/admin/indexing/pause", Upstream: upstreamOrchestrator, Target: "/api/v1/indexing/pause",
+		Tag: "admin", Summary: "Pause directory runs before their next file", Response: "IndexingStatus"},
+	{Method: "POST", Path: "/v1/admin/indexing/resume", Upstream: upstreamOrchestrator, Target: "/api/v1/indexing/resume",
+		Tag: "admin", Summary: "Resume paused directory runs", Response: "IndexingStatus"},
+	{Method: "GET", Path: "/v1/admin/dlq", Upstream: upstreamOrchestrator, Target: "/api/v1/dlq",
+		Tag: "admin", Summary: "List files that failed every retry, most recent failure first", Response: "DeadLetterList"},
+	{Method: "POST", Path: "/v1/admin/dlq/retry", Upstream: upstreamOrchestrator, Target: "/api/v1/dlq/retry",
+		Tag: "admin", Summary: "Queue dead-lettered files for processing again", Request: "DeadLetterRetryRequest", Response: "DeadLetterRetryResponse"},
+	{Method: "GET", Path: "/v1/admin/dlq/export", Upstream: upstreamOrchestrator, Target: "/api/v1/dlq/export",
+		Tag: "admin", Summary: "Download the dead-letter list as JSON lines (format=jsonl) or CSV (format=csv)", Text: true},
+	{Method: "DELETE", Path: "/v1/admin/dlq/:id", Upstream: upstreamOrchestrator, Target: "/api/v1/dlq/:id",
+		Tag: "admin", Summary: "Remove a dead-letter entry without retrying its file", Response: "Object"},
+	{Method: "GET", Path: "/v1/admin/digests", Upstream: upstreamOrchestrator, Target: "/api/v1/digests",
+		Tag: "admin", Summary: "List digest reports with their schedules", Response: "DigestList"},
+	{Method: "POST", Path: "/v1/admin/digests/:name/run", Upstream: upstreamOrchestrator, Target: "/api/v1/digests/:name/run",
+		Tag: "admin", Summary: "Generate a digest report now and deliver it", Response: "DigestRun"},
+	{Method: "GET", Path: "/v1/admin/duplicates", Upstream: upstreamOrchestrator, Target: "/api/v1/duplicates",
+		Tag: "admin", Summary: "Report documents at different paths with identical or nearly identical content (threshold=0.95)", Response: "DuplicateReport"},
+	{Method: "GET", Path: "/v1/admin/events", Upstream: upstreamOrchestrator, Target: "/api/v1/events",
+		Tag: "admin", Summary: "Read pipeline events (document.state-changed, run.progress) after a stream ID (after=, limit=100, type=)", Response: "EventPage"},
+	{Method: "GET", Path: "/v1/admin/feedback", Upstream: upstreamQuery, Target: "/api/v1/feedback",
+		Tag: "admin", Summary: "List answer ratings, newest first", Response: "FeedbackList"},
+	{Method: "GET", Path: "/v1/admin/feedback/stats", Upstream: upstreamQuery, Target: "/api/v1/feedback/stats",
+		Tag: "admin", Summary: "Aggregate answer ratings: counts, satisfaction and poorly rated queries", Response: "FeedbackStats"},
+	{Method: "GET", Path: "/v1/admin/feedback/poor", Upstream: upstreamQuery, Target: "/api/v1/feedback/poor",
+		Tag: "admin", Summary: "List queries rated down more than up, with their comments, for evaluation runs", Response: "RatedQueries"},
+	{Method: "GET", Path: "/v1/admin/analytics/queries", Upstream: upstreamQuery, Target: "/api/v1/analytics/queries",
+		Tag: "admin", Summary: "Aggregate the queries run: the most frequent, those finding nothing, latency percentiles and ratings", Response: "QueryAnalytics"},
+	{Method: "GET", Path: "/v1/admin/stats", Upstream: upstreamVectorStore, Target: "/api/v1/stats",
+		Tag: "admin", Summary: "Get vector index statistics", Response: "Object"},
+	{Method: "POST", Path: "/v1/admin/indexes", Upstream: upstreamVectorStore, Target: "/api/v1/indexes",
+		Tag: "admin", Summary: "Create a serverless Pinecone index, by default the configured one", Request: "CreateIndexRequest", Response: "PineconeIndex"},
+	{Method: "GET", Path: "/v1/admin/indexes/:name", Upstream: upstreamVectorStore, Target: "/api/v1/indexes/:name",
+		Tag: "admin", Summary: "Describe a Pinecone index and whether it is ready", Response: "PineconeIndex"},
+	{Method: "DELETE", Path: "/v1/admin/indexes/:name", Upstream: upstreamVectorStore, Target: "/api/v1/indexes/:name",
+		Tag: "admin", Summary: "Delete a Pinecone index and all its vectors; confirm= must repeat the name, and only the configured index or those in pinecone.deletable_indexes are deleted", Response: "Object"},
+}
+
+// upstreamURLs returns the base URL of each upstream service
+func upstreamURLs(services config.ServicesConfig) map[string]string {
+	return map[string]string{
+		upstreamOrchestrator: services.OrchestratorServiceURL,
+		upstreamQuery:        services.QueryServiceURL,
+		upstreamVectorStore:  services.VectorStoreServiceURL,
+	}
+}
@@ -0,0 +1,212 @@
+// Package httpsec provides the middleware that lets browser frontends call
+// the public APIs across origins (CORS), sets the standard security headers
+// on their responses, bounds the size of request bodies, checks the admin
+// token of admin requests and reads the caller's identity.
+package httpsec
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+)
+
+// CORS answers preflight requests from the allowed origins and lets their
+// scripts read the responses of other requests. Requests from other
+// origins are served without CORS headers, so browsers keep their scripts
+// from reading the response; their preflight requests are refused. No
+// allowed origins disables CORS.
+func CORS(c config.CORSConfig) gin.HandlerFunc {
+	if len(c.AllowedOrigins) == 0 {
+		return func(ctx *gin.Context) { ctx.Next() }
+	}
+
+	anyOrigin := slices.Contains(c.AllowedOrigins, "*")
+	origins := make([]string, len(c.AllowedOrigins))
+	for i, origin := range c.AllowedOrigins {
+		origins[i] = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	}
+	methods := strings.ToUpper(strings.Join(c.AllowedMethods, ", "))
+	headers := strings.Join(c.AllowedHeaders, ", ")
+	exposed := strings.Join(c.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(c.MaxAge.Seconds()))
+
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader("Origin")
+		if origin == "" {
+			ctx.Next()
+			return
+		}
+		h := ctx.Writer.Header()
+		h.Add("Vary", "Origin")
+		preflight := ctx.Request.Method == http.MethodOptions && ctx.GetHeader("Access-Control-Request-Method") != ""
+
+		if !anyOrigin && !slices.Contains(origins, strings.ToLower(origin)) {
+			if preflight {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "origin not allowed"})
+				return
+			}
+			ctx.Next()
+			return
+		}
+
+		if anyOrigin && !c.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if c.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method, Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", methods)
+			if headers != "" {
+				h.Set("Access-Control-Allow-Headers", headers)
+			}
+			h.Set("Access-Control-Max-Age", maxAge)
+			ctx.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		if exposed != "" {
+			h.Set("Access-Control-Expose-Headers", exposed)
+		}
+		ctx.Next()
+	}
+}
+
+// SecurityHeaders sets the standard security headers on every response:
+// no content type sniffing, no framing, no referrer, the configured content
+// security policy and, on HTTPS responses, Strict-Transport-Security
+func SecurityHeaders(c config.SecurityHeadersConfig) gin.HandlerFunc {
+	if !c.Enabled {
+		return func(ctx *gin.Context) { ctx.Next() }
+	}
+
+	var hsts string
+	if c.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d; includeSubDomains", int64(c.HSTSMaxAge.Seconds()))
+	}
+
+	return func(ctx *gin.Context) {
+		h := ctx.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", c.FrameOptions)
+		h.Set("Referrer-Policy", "no-referrer")
+		if c.ContentSecurityPolicy != "" {
+			h.Set("Content-Security-Policy", c.ContentSecurityPolicy)
+		}
+		if hsts != "" && ctx.Request.TLS != nil {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		ctx.Next()
+	}
+}
+
+// headers lists the headers this package sets
+var headers = []string{
+	"X-Content-Type-Options",
+	"X-Frame-Options",
+	"Referrer-Policy",
+	"Content-Security-Policy",
+	"Strict-Transport-Security",
+}
+
+// DropHeaders removes the CORS and security headers from a proxied
+// response, leaving those of the proxy, which browsers would otherwise see
+// twice
+func DropHeaders(h http.Header) {
+	for name := range h {
+		if strings.HasPrefix(name, "Access-Control-") {
+			h.Del(name)
+		}
+	}
+	for _, name := range headers {
+		h.Del(name)
+	}
+}
+
+// LimitBody answers 413 to requests with a body larger than max bytes: at
+// once when their Content-Length says so, otherwise once the body read
+// exceeds it. The body is read before the handler runs, so handlers never
+// see a truncated one. Zero is unlimited.
+func LimitBody(max int64) gin.HandlerFunc {
+	if max <= 0 {
+		return func(ctx *gin.Context) { ctx.Next() }
+	}
+
+	tooLarge := func(ctx *gin.Context, size int64) {
+		body := gin.H{"error": fmt.Sprintf("request body exceeds the %d byte limit", max), "limit": max}
+		if size > 0 {
+			body["size"] = size
+		}
+		ctx.Header("Connection", "close")
+		ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, body)
+	}
+
+	return func(ctx *gin.Context) {
+		if ctx.Request.Body == nil || ctx.Request.Body == http.NoBody {
+			ctx.Next()
+			return
+		}
+		if ctx.Request.ContentLength > max {
+			tooLarge(ctx, ctx.Request.ContentLength)
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(ctx.Request.Body, max+1))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		if int64(len(data)) > max {
+			tooLarge(ctx, 0)
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(data))
+		ctx.Next()
+	}
+}
+
+// HeaderAdminToken carries the admin token from the gateway to the services
+const HeaderAdminToken = "X-Admin-Token"
+
+// IsAdmin reports whether a request carries the admin token. Without a
+// configured token no request is an admin one.
+func IsAdmin(c *gin.Context, token string) bool {
+	sent := c.GetHeader(HeaderAdminToken)
+	return token != "" && sent != "" && subtle.ConstantTimeCompare([]byte(sent), []byte(token)) == 1
+}
+
+// RequireAdmin answers 403 and reports false when a request does not carry
+// the admin token, so admin endpoints cannot be called past the gateway
+func RequireAdmin(c *gin.Context, token string) bool {
+	if IsAdmin(c, token) {
+		return true
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this endpoint needs the admin token; set ADMIN_TOKEN on the gateway and the services"})
+	return false
+}
+
+// Identity reads the caller identity from the X-User-ID, X-User-Groups and
+// X-Tenant-ID headers, which are expected to be set by an authenticating
+// proxy in front of the service. Requests without them are anonymous.
+func Identity(c *gin.Context) *models.Identity {
+	identity := &models.Identity{
+		UserID: strings.TrimSpace(c.GetHeader("X-User-ID")),
+		Tenant: strings.TrimSpace(c.GetHeader("X-Tenant-ID")),
+	}
+	for _, group := range strings.Split(c.GetHeader("X-User-Groups"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			identity.Groups = append(identity.Groups, group)
+		}
+	}
+	return identity
+}
@@ -0,0 +1,227 @@
+// Package idempotency lets clients retry mutations safely. A request sent
+// with an Idempotency-Key header has its response stored under the key;
+// a retry with the same key gets the stored response instead of running
+// the mutation again. Keys are scoped to the tenant, user, method and path
+// of the request, and a key reused with a different body is refused.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+const (
+	// Header carries the client's idempotency key
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from an earlier request
+	ReplayedHeader = "Idempotent-Replayed"
+	// MaxKeyLength caps the characters of a key
+	MaxKeyLength = 255
+	// pendingTTL is how long a key stays reserved for a request in flight,
+	// so that a service stopping mid-request does not hold it for the
+	// whole TTL
+	pendingTTL = 5 * time.Minute
+)
+
+// Record is the stored outcome of a request
+type Record struct {
+	// Fingerprint is the hash of the request body, to tell a retry from a
+	// different request reusing the key
+	Fingerprint string    `json:"fingerprint"`
+	Pending     bool      `json:"pending,omitempty"` // the request is in flight
+	Status      int       `json:"status,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store persists records by scoped key
+type Store interface {
+	// Begin reserves a key with a pending record. It returns nil when the
+	// key was free, and the key's record otherwise.
+	Begin(ctx context.Context, key string, pending *Record) (*Record, error)
+	// Complete stores a request's outcome under its key for ttl
+	Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error
+	// Abandon frees a key whose request failed, so a retry runs again
+	Abandon(ctx context.Context, key string) error
+	Close() error
+}
+
+// Compile-time checks that the stores implement the interface
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+// NewStore creates the idempotency store. Records are kept beside the
+// document registry, in the same backend. It returns nil when idempotency
+// keys are disabled.
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	if !cfg.Idempotency.Enabled {
+		return nil, nil
+	}
+	switch cfg.Registry.Backend {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Idempotency keys enabled", zap.String("backend", "redis"), zap.Duration("ttl", cfg.Idempotency.TTL))
+		return NewRedisStore(client, cfg.Registry.KeyPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown registry backend: %s", cfg.Registry.Backend)
+	}
+}
+
+// Middleware replays the stored response of mutations retried with the
+// same Idempotency-Key. Requests without the header, and reads, pass
+// through. A key in use by a request in flight is answered 409, and a key
+// reused with a different body 422. Only successes and rejections of the
+// request itself are stored; after any other response, such as a 5xx, a
+// 429 or a 409 for a locked directory, the key is freed and a retry runs
+// again. A nil store passes everything through.
+func Middleware(store Store, ttl time.Duration, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if store == nil || key == "" || !mutation(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > MaxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be at most %d characters", Header, MaxKeyLength)})
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			data, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+				return
+			}
+			body = data
+			c.Request.Body = io.NopCloser(bytes.NewReader(data))
+		}
+
+		ctx := c.Request.Context()
+		scoped := scope(c, key)
+		fingerprint := hash(body)
+		existing, err := store.Begin(ctx, scoped, &Record{Fingerprint: fingerprint, Pending: true, CreatedAt: time.Now()})
+		if err != nil {
+			// Serving the request unprotected beats refusing it
+			logger.Warn("Failed to check idempotency key", zap.Error(err))
+			c.Next()
+			return
+		}
+		switch {
+		case existing == nil:
+		case existing.Fingerprint != fingerprint:
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": Header + " was already used with a different request"})
+			return
+		case existing.Pending:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this " + Header + " is in progress"})
+			return
+		default:
+			c.Header(ReplayedHeader, "true")
+			c.Data(existing.Status, existing.ContentType, existing.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// The outcome is stored even when the client went away, as it is
+		// the retry that needs it
+		ctx = context.WithoutCancel(ctx)
+		if !storable(recorder.Status()) {
+			if err := store.Abandon(ctx, scoped); err != nil {
+				logger.Warn("Failed to free idempotency key", zap.Error(err))
+			}
+			return
+		}
+		record := &Record{
+			Fingerprint: fingerprint,
+			Status:      recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+			CreatedAt:   time.Now(),
+		}
+		if err := store.Complete(ctx, scoped, record, ttl); err != nil {
+			logger.Warn("Failed to store idempotent response", zap.Error(err))
+		}
+	}
+}
+
+// storable reports whether a response of the status is the outcome every
+// retry would get: a success, or a rejection of the request as sent.
+// Responses depending on the moment, such as rate limits, conflicts with
+// other requests, missing or unauthorized resources and server failures,
+// are not.
+func storable(status int) bool {
+	if status >= http.StatusOK && status < http.StatusMultipleChoices {
+		return true
+	}
+	switch status {
+	case http.StatusBadRequest,
+		http.StatusMethodNotAllowed,
+		http.StatusRequestEntityTooLarge,
+		http.StatusRequestURITooLong,
+		http.StatusUnsupportedMediaType,
+		http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// responseRecorder keeps a copy of the response body written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// mutation reports whether requests of a method change state
+func mutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// scope hashes a key with the tenant, user, method and path of the
+// request, so that clients cannot see each other's responses and a key
+// sent to two endpoints stands for two requests
+func scope(c *gin.Context, key string) string {
+	return hash([]byte(c.GetHeader("X-Tenant-ID") + "\n" + c.GetHeader("X-User-ID") + "\n" +
+		c.Request.Method + "\n" + c.Request.URL.Path + "\n" + key))
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,89 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// statusRouter answers each request to /ingest with the next of statuses,
+// counting the requests that reach the handler
+func statusRouter(statuses []int, calls *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(NewMemoryStore(), time.Hour, zap.NewNop()))
+	router.POST("/ingest", func(c *gin.Context) {
+		status := statuses[min(*calls, len(statuses)-1)]
+		*calls++
+		c.JSON(status, gin.H{"call": *calls})
+	})
+	return router
+}
+
+func send(router *gin.Engine, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(`{"file_path":"/data/a.md"}`))
+	req.Header.Set(Header, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddlewareStoresDeterministicResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		stored bool
+	}{
+		{"accepted", http.StatusAccepted, true},
+		{"ok", http.StatusOK, true},
+		{"bad request", http.StatusBadRequest, true},
+		{"body too large", http.StatusRequestEntityTooLarge, true},
+		{"unprocessable", http.StatusUnprocessableEntity, true},
+		{"rate limited", http.StatusTooManyRequests, false},
+		{"directory locked", http.StatusConflict, false},
+		{"not found", http.StatusNotFound, false},
+		{"forbidden", http.StatusForbidden, false},
+		{"unavailable", http.StatusServiceUnavailable, false},
+		{"server error", http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			router := statusRouter([]int{tt.status, http.StatusAccepted}, &calls)
+
+			if w := send(router, "key"); w.Code != tt.status {
+				t.Fatalf("first status = %d, want %d", w.Code, tt.status)
+			}
+			w := send(router, "key")
+			replayed := w.Header().Get(ReplayedHeader) == "true"
+			if tt.stored {
+				if !replayed || w.Code != tt.status || calls != 1 {
+					t.Errorf("retry = %d (replayed %v, %d calls), want the stored %d", w.Code, replayed, calls, tt.status)
+				}
+				return
+			}
+			if replayed || w.Code != http.StatusAccepted || calls != 2 {
+				t.Errorf("retry = %d (replayed %v, %d calls), want the request run again", w.Code, replayed, calls)
+			}
+		})
+	}
+}
+
+func TestMiddlewareRefusesKeyReuse(t *testing.T) {
+	calls := 0
+	router := statusRouter([]int{http.StatusAccepted}, &calls)
+	send(router, "key")
+
+	req := httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(`{"file_path":"/data/b.md"}`))
+	req.Header.Set(Header, "key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity || calls != 1 {
+		t.Errorf("reused key = %d with %d calls, want 422 without running", w.Code, calls)
+	}
+}
@@ -0,0 +1,142 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryStore keeps records in process memory
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*memoryRecord
+}
+
+type memoryRecord struct {
+	record  *Record
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty in-memory idempotency store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*memoryRecord)}
+}
+
+// Begin reserves a key unless it holds an unexpired record
+func (s *MemoryStore) Begin(_ context.Context, key string, pending *Record) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+	if r, ok := s.records[key]; ok {
+		copied := *r.record
+		return &copied, nil
+	}
+	stored := *pending
+	s.records[key] = &memoryRecord{record: &stored, expires: now.Add(pendingTTL)}
+	return nil, nil
+}
+
+// Complete stores a request's outcome
+func (s *MemoryStore) Complete(_ context.Context, key string, record *Record, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *record
+	s.records[key] = &memoryRecord{record: &stored, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// Abandon frees a key
+func (s *MemoryStore) Abandon(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.records, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// expire drops expired records; callers hold the lock
+func (s *MemoryStore) expire(now time.Time) {
+	for key, r := range s.records {
+		if now.After(r.expires) {
+			delete(s.records, key)
+		}
+	}
+}
+
+// RedisStore keeps each record in a Redis key expiring with it
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a Redis backed idempotency store
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: keyPrefix + ":idempotency:"}
+}
+
+// Begin reserves a key unless it holds a record
+func (s *RedisStore) Begin(ctx context.Context, key string, pending *Record) (*Record, error) {
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+	ok, err := s.client.SetNX(ctx, s.prefix+key, data, pendingTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if ok {
+		return nil, nil
+	}
+
+	stored, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// Expired between the two calls; the caller may treat it as taken
+		// by a request in flight, and the client retries
+		return &Record{Fingerprint: pending.Fingerprint, Pending: true}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency record: %w", err)
+	}
+	var record Record
+	if err := json.Unmarshal(stored, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+	}
+	return &record, nil
+}
+
+// Complete stores a request's outcome
+func (s *RedisStore) Complete(ctx context.Context, key string, record *Record, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+	if err := s.client.Set(ctx, s.prefix+key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store idempotency record: %w", err)
+	}
+	return nil
+}
+
+// Abandon frees a key
+func (s *RedisStore) Abandon(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.prefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to free idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,158 @@
+// Package imagemeta reads the dimensions and format of images from their
+// headers and renders small PNG thumbnails for previews. PNG, JPEG and GIF
+// images are decoded; SVG dimensions are taken from the root element.
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // register the GIF decoder
+	_ "image/jpeg" // register the JPEG decoder
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/safeparse"
+)
+
+// MaxPixels is the largest image, in pixels, decoded for a thumbnail
+const MaxPixels = 50_000_000
+
+// ErrTooLarge is returned when an image has more than MaxPixels pixels
+var ErrTooLarge = errors.New("image too large for a thumbnail")
+
+// Info describes an image
+type Info struct {
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Format    string `json:"format"`              // png, jpeg, gif or svg
+	Thumbnail bool   `json:"thumbnail,omitempty"` // a thumbnail is in the content store
+}
+
+// Read returns the dimensions and format of an image, read from its header
+// without decoding the pixels. ext selects SVG parsing for ".svg" files.
+func Read(r io.Reader, ext string) (Info, error) {
+	if strings.EqualFold(ext, ".svg") {
+		return readSVG(r)
+	}
+	config, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to read image header: %w", err)
+	}
+	return Info{Width: config.Width, Height: config.Height, Format: format}, nil
+}
+
+// Thumbnail decodes an image and returns it scaled down to fit within
+// size by size pixels as PNG. Smaller images keep their size.
+func Thumbnail(r io.ReadSeeker, size int) ([]byte, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image header: %w", err)
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, config.Width, config.Height)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind image: %w", err)
+	}
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scale(src, size)); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// scale shrinks an image to fit within size by size pixels, keeping its
+// aspect ratio. Each pixel is the average of the source pixels it covers.
+func scale(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := bounds.Min.Y+y*h/dh, bounds.Min.Y+max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := bounds.Min.X+x*w/dw, bounds.Min.X+max((x+1)*w/dw, x*w/dw+1)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					r, g, b, a = r+uint64(c.R), g+uint64(c.G), b+uint64(c.B), a+uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// readSVG takes the dimensions of an SVG image from the width and height of
+// its root element, or from its viewBox when they are missing or relative.
+// The document is read within the safeparse limits.
+func readSVG(r io.Reader) (Info, error) {
+	decoder := safeparse.NewXMLDecoder(r, safeparse.DefaultXMLLimits)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return Info{}, fmt.Errorf("failed to read SVG: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "svg" {
+			return Info{}, errors.New("failed to read SVG: root element is not svg")
+		}
+
+		info := Info{Format: "svg"}
+		var viewBox []string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "width":
+				info.Width = svgLength(attr.Value)
+			case "height":
+				info.Height = svgLength(attr.Value)
+			case "viewBox":
+				viewBox = strings.Fields(strings.ReplaceAll(attr.Value, ",", " "))
+			}
+		}
+		if (info.Width == 0 || info.Height == 0) && len(viewBox) == 4 {
+			info.Width, info.Height = svgLength(viewBox[2]), svgLength(viewBox[3])
+		}
+		return info, nil
+	}
+}
+
+// svgLength returns an SVG length in pixels, or 0 for relative lengths
+func svgLength(value string) int {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return int(n + 0.5)
+}
@@ -0,0 +1,160 @@
+// Package imagesearch embeds images with a multimodal model and stores the
+// vectors beside the text vectors, so queries find images by what they
+// show rather than only by their OCR text or description. Query text is
+// embedded by the same model and searched against the image vectors.
+package imagesearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// embeddableExts are the image formats the providers accept
+var embeddableExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".webp": true,
+}
+
+// Index embeds images and searches them with query text. Image vectors are
+// kept in their own namespace of the text index, or of a separate index
+// when the provider's dimension differs from the text index's.
+type Index struct {
+	provider  Provider
+	pinecone  *pinecone.PineconeClient
+	namespace string
+	maxBytes  int64
+	logger    *zap.Logger
+}
+
+// New creates the image index selected by configuration. It returns nil
+// without an error when image search is disabled.
+func New(cfg *config.Config, logger *zap.Logger) (*Index, error) {
+	c := cfg.ImageSearch
+	var provider Provider
+	switch c.Provider {
+	case "none", "":
+		return nil, nil
+	case "azure_vision":
+		provider = newAzureVisionProvider(c.Endpoint, c.APIKey, c.Model)
+	case "clip":
+		provider = newCLIPProvider(c.Endpoint, c.APIKey, c.Model)
+	default:
+		return nil, fmt.Errorf("unknown image search provider: %s", c.Provider)
+	}
+
+	// The image index shares the Pinecone project, and summary vectors
+	// belong to the text index only
+	indexCfg := *cfg
+	indexCfg.App.SummaryVectors = false
+	indexCfg.Pinecone.Dimension = c.Dimension
+	if c.IndexName != "" || c.IndexHost != "" {
+		indexCfg.Pinecone.Host = c.IndexHost
+		if c.IndexName != "" {
+			indexCfg.Pinecone.IndexName = c.IndexName
+		}
+	}
+	client, err := pinecone.NewPineconeClient(&indexCfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Pinecone client for image vectors: %w", err)
+	}
+
+	logger.Info("Image search enabled",
+		zap.String("provider", provider.Name()),
+		zap.String("index", indexCfg.Pinecone.IndexName),
+		zap.String("namespace", c.Namespace))
+	return &Index{
+		provider:  provider,
+		pinecone:  client,
+		namespace: c.Namespace,
+		maxBytes:  cfg.Vision.MaxImageBytes,
+		logger:    logger,
+	}, nil
+}
+
+// Provider returns the name of the embedding provider
+func (i *Index) Provider() string {
+	return i.provider.Name()
+}
+
+// Embeddable reports whether images with the extension can be embedded
+func Embeddable(ext string) bool {
+	return embeddableExts[strings.ToLower(ext)]
+}
+
+// IndexFile embeds an image file and upserts its vector for the document,
+// with the given metadata
+func (i *Index) IndexFile(ctx context.Context, documentID, filePath string, metadata map[string]interface{}) error {
+	data, err := i.readImage(filePath)
+	if err != nil {
+		return err
+	}
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(filePath)))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	values, err := i.provider.EmbedImage(ctx, data, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to embed image: %w", err)
+	}
+	vector := &pinecone.Vector{ID: models.ImageVectorID(documentID), Values: values, Metadata: metadata}
+	if err := i.pinecone.UpsertVectorsInNamespace(ctx, i.namespace, []*pinecone.Vector{vector}); err != nil {
+		return fmt.Errorf("failed to store image vector: %w", err)
+	}
+	return nil
+}
+
+// Supersede marks the image vectors of earlier versions of a file as
+// superseded, as the text vectors are
+func (i *Index) Supersede(ctx context.Context, filePath, currentDocumentID string, at time.Time) error {
+	_, err := i.pinecone.SupersedeVersionsInNamespace(ctx, i.namespace, filePath, currentDocumentID, at)
+	return err
+}
+
+// Search returns the image vectors most similar to the text that match
+// the metadata filter
+func (i *Index) Search(ctx context.Context, text string, topK int, filter map[string]interface{}) ([]*pinecone.Match, error) {
+	values, err := i.provider.EmbedText(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query for image search: %w", err)
+	}
+	return i.pinecone.QueryVectorsInNamespace(ctx, i.namespace, values, topK, filter)
+}
+
+// Delete removes the image vector of a document
+func (i *Index) Delete(ctx context.Context, documentID string) error {
+	return i.pinecone.DeleteVectorsInNamespace(ctx, i.namespace, []string{models.ImageVectorID(documentID)})
+}
+
+// readImage reads an image file, refusing files above the vision size limit
+func (i *Index) readImage(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	defer file.Close() //nolint:errcheck
+
+	reader := io.Reader(file)
+	if i.maxBytes > 0 {
+		reader = io.LimitReader(file, i.maxBytes+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if i.maxBytes > 0 && int64(len(data)) > i.maxBytes {
+		return nil, errors.New("image exceeds VISION_MAX_IMAGE_BYTES")
+	}
+	return data, nil
+}
@@ -0,0 +1,173 @@
+package imagesearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// azureVisionAPIVersion is the Azure AI Vision API version of the
+// multimodal embedding endpoints
+const azureVisionAPIVersion = "2024-02-01"
+
+// defaultAzureVisionModel is the multimodal embedding model version used
+// when none is configured
+const defaultAzureVisionModel = "2023-04-15"
+
+// Provider embeds images and text into one vector space, so text finds
+// the images it describes
+type Provider interface {
+	Name() string
+	EmbedImage(ctx context.Context, data []byte, contentType string) ([]float32, error)
+	EmbedText(ctx context.Context, text string) ([]float32, error)
+}
+
+// azureVisionProvider uses the Azure AI Vision multimodal embeddings API
+// (retrieval:vectorizeImage and retrieval:vectorizeText)
+type azureVisionProvider struct {
+	endpoint   string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+func newAzureVisionProvider(endpoint, apiKey, model string) *azureVisionProvider {
+	if model == "" {
+		model = defaultAzureVisionModel
+	}
+	return &azureVisionProvider{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (p *azureVisionProvider) Name() string {
+	return "azure_vision:" + p.model
+}
+
+// EmbedImage sends the image bytes to vectorizeImage
+func (p *azureVisionProvider) EmbedImage(ctx context.Context, data []byte, _ string) ([]float32, error) {
+	return p.vectorize(ctx, "vectorizeImage", "application/octet-stream", bytes.NewReader(data))
+}
+
+// EmbedText sends the text to vectorizeText
+func (p *azureVisionProvider) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return p.vectorize(ctx, "vectorizeText", "application/json", bytes.NewReader(body))
+}
+
+func (p *azureVisionProvider) vectorize(ctx context.Context, operation, contentType string, body io.Reader) ([]float32, error) {
+	query := url.Values{"api-version": {azureVisionAPIVersion}, "model-version": {p.model}}
+	endpoint := fmt.Sprintf("%s/computervision/retrieval:%s?%s", p.endpoint, operation, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Ocp-Apim-Subscription-Key", p.apiKey)
+
+	var resp struct {
+		Vector []float32 `json:"vector"`
+	}
+	if err := send(p.httpClient, req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Vector) == 0 {
+		return nil, fmt.Errorf("no vector in response")
+	}
+	return resp.Vector, nil
+}
+
+// clipProvider uses a CLIP-compatible server with an OpenAI-style
+// /embeddings endpoint that accepts images as base64 data URLs, as served
+// by common CLIP inference servers
+type clipProvider struct {
+	endpoint   string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+func newCLIPProvider(endpoint, apiKey, model string) *clipProvider {
+	return &clipProvider{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		apiKey:     apiKey,
+		model:      model,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (p *clipProvider) Name() string {
+	if p.model == "" {
+		return "clip"
+	}
+	return "clip:" + p.model
+}
+
+// EmbedImage sends the image as a data URL
+func (p *clipProvider) EmbedImage(ctx context.Context, data []byte, contentType string) ([]float32, error) {
+	return p.embed(ctx, "data:"+contentType+";base64,"+base64.StdEncoding.EncodeToString(data))
+}
+
+// EmbedText sends the text as is
+func (p *clipProvider) EmbedText(ctx context.Context, text string) ([]float32, error) {
+	return p.embed(ctx, text)
+}
+
+func (p *clipProvider) embed(ctx context.Context, input string) ([]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": p.model, "input": []string{input}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	var resp struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := send(p.httpClient, req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("no embedding in response")
+	}
+	return resp.Data[0].Embedding, nil
+}
+
+// send performs a request and decodes a successful JSON response into out
+func send(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096)) //nolint:errcheck
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+// Package jsonschema validates JSON values against the subset of JSON
+// Schema that structured model outputs use: type, properties, required,
+// additionalProperties, items, enum, minItems and maxItems. Other keywords
+// are accepted and ignored, so a schema written for a full validator still
+// loads.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// types are the JSON types a schema can name
+var types = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// Schema is a parsed JSON schema
+type Schema struct {
+	raw map[string]interface{}
+}
+
+// Parse parses a JSON schema and checks the keywords it validates with
+func Parse(data []byte) (*Schema, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("schema is not a JSON object: %w", err)
+	}
+	if err := check(raw, "$"); err != nil {
+		return nil, err
+	}
+	return &Schema{raw: raw}, nil
+}
+
+// Validate checks a JSON document against the schema. The error lists
+// every violation, each with the path of the value, so a model can be asked
+// to repair them all at once.
+func (s *Schema) Validate(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("not valid JSON: %w", err)
+	}
+	var violations []string
+	validate(s.raw, value, "$", &violations)
+	if len(violations) > 0 {
+		return fmt.Errorf("%s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// check verifies the keywords of a schema and its subschemas
+func check(schema map[string]interface{}, path string) error {
+	if t, ok := schema["type"]; ok {
+		names, ok := typeNames(t)
+		if !ok {
+			return fmt.Errorf("%s: type must be a type name or a list of them", path)
+		}
+		for _, name := range names {
+			if !slices.Contains(types, name) {
+				return fmt.Errorf("%s: unknown type %q", path, name)
+			}
+		}
+	}
+	if p, ok := schema["properties"]; ok {
+		properties, ok := p.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: properties must be an object", path)
+		}
+		for name, sub := range properties {
+			subschema, ok := sub.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s.%s: schema must be an object", path, name)
+			}
+			if err := check(subschema, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if r, ok := schema["required"]; ok {
+		if _, ok := stringList(r); !ok {
+			return fmt.Errorf("%s: required must be a list of property names", path)
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		subschema, ok := items.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: items must be a schema object", path)
+		}
+		if err := check(subschema, path+"[]"); err != nil {
+			return err
+		}
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+		if err := check(additional, path+".*"); err != nil {
+			return err
+		}
+	}
+	if e, ok := schema["enum"]; ok {
+		if _, ok := e.([]interface{}); !ok {
+			return fmt.Errorf("%s: enum must be a list", path)
+		}
+	}
+	return nil
+}
+
+// validate appends the violations of a value to the list
+func validate(schema map[string]interface{}, value interface{}, path string, violations *[]string) {
+	if t, ok := schema["type"]; ok {
+		names, _ := typeNames(t)
+		if !slices.ContainsFunc(names, func(name string) bool { return hasType(value, name) }) {
+			*violations = append(*violations, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(names, " or "), typeOf(value)))
+			return
+		}
+	}
+	if e, ok := schema["enum"].([]interface{}); ok {
+		if !slices.ContainsFunc(e, func(v interface{}) bool { return reflect.DeepEqual(v, value) }) {
+			*violations = append(*violations, fmt.Sprintf("%s: value is not one of the allowed values", path))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := stringList(schema["required"])
+		for _, name := range required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := properties[name].(map[string]interface{}); ok {
+				validate(sub, v[name], path+"."+name, violations)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					*violations = append(*violations, fmt.Sprintf("%s: unexpected property %q", path, name))
+				}
+			case map[string]interface{}:
+				validate(additional, v[name], path+"."+name, violations)
+			}
+		}
+	case []interface{}:
+		if minItems, ok := schema["minItems"].(float64); ok && float64(len(v)) < minItems {
+			*violations = append(*violations, fmt.Sprintf("%s: expected at least %d items, got %d", path, int(minItems), len(v)))
+		}
+		if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(v)) > maxItems {
+			*violations = append(*violations, fmt.Sprintf("%s: expected at most %d items, got %d", path, int(maxItems), len(v)))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validate(items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	}
+}
+
+// hasType reports whether a decoded JSON value is of a schema type
+func hasType(value interface{}, name string) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	default:
+		return typeOf(value) == name
+	}
+}
+
+// typeOf returns the schema type name of a decoded JSON value
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+// typeNames returns the type names of a type keyword, a name or a list
+func typeNames(t interface{}) ([]string, bool) {
+	if name, ok := t.(string); ok {
+		return []string{name}, true
+	}
+	return stringList(t)
+}
+
+// stringList converts a decoded JSON list of strings
+func stringList(v interface{}) ([]string, bool) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, s)
+	}
+	return out, true
+}
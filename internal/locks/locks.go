@@ -0,0 +1,318 @@
+// Package locks coordinates orchestrator replicas through Redis. A lock is
+// a key set to its owner's token with a time to live, refreshed while held,
+// so a replica that dies releases its locks once the TTL passes. Leader
+// election is a lock campaigned for until won.
+package locks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrHeld is returned when acquiring a lock another owner holds
+var ErrHeld = errors.New("lock is held by another replica")
+
+// refreshScript extends a lock's TTL if the token still owns it
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseScript deletes a lock if the token still owns it
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Locker acquires locks for one owner. A nil locker grants every lock, as
+// a single replica needs no coordination.
+type Locker struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+	owner  string
+	logger *zap.Logger
+}
+
+// New connects the locker. It returns nil when locking is disabled.
+func New(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*Locker, error) {
+	if !cfg.Locks.Enabled {
+		return nil, nil
+	}
+	client, err := redisclient.Connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	owner := cfg.Locks.Owner
+	if owner == "" {
+		owner = defaultOwner()
+	}
+	logger.Info("Distributed locks enabled", zap.String("owner", owner), zap.Duration("ttl", cfg.Locks.TTL))
+	return NewLocker(client, cfg.Locks.KeyPrefix, cfg.Locks.TTL, owner, logger), nil
+}
+
+// NewLocker creates a locker keeping locks under the key prefix
+func NewLocker(client *redis.Client, keyPrefix string, ttl time.Duration, owner string, logger *zap.Logger) *Locker {
+	return &Locker{client: client, prefix: keyPrefix, ttl: ttl, owner: owner, logger: logger}
+}
+
+// Owner returns the name the locker holds locks under
+func (l *Locker) Owner() string {
+	if l == nil {
+		return ""
+	}
+	return l.owner
+}
+
+// Acquire takes the named lock, returning ErrHeld when another owner holds
+// it. The lock is refreshed until released; Lost reports when a refresh
+// finds it expired or taken over.
+func (l *Locker) Acquire(ctx context.Context, name string) (*Lock, error) {
+	if l == nil {
+		return &Lock{}, nil
+	}
+	lock := &Lock{
+		locker: l,
+		name:   name,
+		key:    l.prefix + ":" + name,
+		token:  l.owner + "/" + uuid.New().String(),
+		lost:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	ok, err := l.client.SetNX(ctx, lock.key, lock.token, l.ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if !ok {
+		return nil, ErrHeld
+	}
+	go lock.refresh()
+	return lock, nil
+}
+
+// AcquireWait takes the named lock, retrying every interval while another
+// owner holds it until ctx is done
+func (l *Locker) AcquireWait(ctx context.Context, name string, interval time.Duration) (*Lock, error) {
+	for {
+		lock, err := l.Acquire(ctx, name)
+		if !errors.Is(err, ErrHeld) {
+			return lock, err
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("failed to acquire lock %s: %w", name, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// Holder returns the owner holding the named lock, or an empty string when
+// it is free
+func (l *Locker) Holder(ctx context.Context, name string) (string, error) {
+	if l == nil {
+		return "", nil
+	}
+	token, err := l.client.Get(ctx, l.prefix+":"+name).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read lock %s: %w", name, err)
+	}
+	owner, _, _ := strings.Cut(token, "/")
+	return owner, nil
+}
+
+// Lead campaigns for the named leadership and calls run while this owner
+// leads. The context given to run is cancelled when leadership is lost,
+// after which the campaign starts again. Lead returns when ctx is done or
+// run returns while still leading.
+func (l *Locker) Lead(ctx context.Context, name string, run func(ctx context.Context)) {
+	if l == nil {
+		run(ctx)
+		return
+	}
+	for ctx.Err() == nil {
+		lock, err := l.Acquire(ctx, name)
+		if err != nil {
+			if !errors.Is(err, ErrHeld) {
+				l.logger.Warn("Failed to campaign for leadership", zap.String("name", name), zap.Error(err))
+			}
+			timer := time.NewTimer(l.ttl / 2)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+			continue
+		}
+
+		l.logger.Info("Became leader", zap.String("name", name), zap.String("owner", l.owner))
+		leadCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-lock.Lost():
+				cancel()
+			case <-leadCtx.Done():
+			}
+		}()
+		run(leadCtx)
+		cancel()
+		lock.Release(context.WithoutCancel(ctx))
+
+		select {
+		case <-lock.Lost():
+			l.logger.Warn("Lost leadership", zap.String("name", name), zap.String("owner", l.owner))
+		default:
+			return
+		}
+	}
+}
+
+// Lock is a held lock
+type Lock struct {
+	locker   *Locker
+	name     string
+	key      string
+	token    string
+	lost     chan struct{} // closed when the lock is lost
+	done     chan struct{} // closed when the lock is released
+	lostOnce sync.Once
+	doneOnce sync.Once
+}
+
+// Lost returns a channel closed when the lock expired or was taken over
+// while held. The channel of a lock granted by a nil locker is never
+// closed.
+func (lk *Lock) Lost() <-chan struct{} {
+	return lk.lost
+}
+
+// Release stops refreshing the lock and frees it if still held
+func (lk *Lock) Release(ctx context.Context) {
+	if lk.locker == nil {
+		return
+	}
+	lk.doneOnce.Do(func() { close(lk.done) })
+	err := releaseScript.Run(ctx, lk.locker.client, []string{lk.key}, lk.token).Err()
+	if err != nil {
+		lk.locker.logger.Warn("Failed to release lock", zap.String("name", lk.name), zap.Error(err))
+	}
+}
+
+// refresh extends the lock's TTL a few times per TTL until released. The
+// lock is lost when a refresh finds it gone, or when refreshes fail until
+// the TTL has passed.
+func (lk *Lock) refresh() {
+	ttl := lk.locker.ttl
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	refreshed := time.Now()
+	for {
+		select {
+		case <-lk.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+		held, err := refreshScript.Run(ctx, lk.locker.client, []string{lk.key}, lk.token, ttl.Milliseconds()).Int()
+		cancel()
+		switch {
+		case err == nil && held == 1:
+			refreshed = time.Now()
+			continue
+		case err != nil && time.Since(refreshed) < ttl:
+			lk.locker.logger.Warn("Failed to refresh lock", zap.String("name", lk.name), zap.Error(err))
+			continue
+		}
+		lk.locker.logger.Warn("Lock lost", zap.String("name", lk.name), zap.String("owner", lk.locker.owner))
+		lk.lostOnce.Do(func() { close(lk.lost) })
+		return
+	}
+}
+
+// Close closes the Redis client
+func (l *Locker) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.client.Close()
+}
+
+// defaultOwner names the replica by its host name, which is the pod name
+// under Kubernetes, and process ID
+func defaultOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "orchestrator"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Keyed holds a mutex per name, for locking within one replica what a
+// Locker locks across replicas. The zero value is ready to use, and a
+// name's mutex is dropped once no one holds or waits for it.
+type Keyed struct {
+	mu    sync.Mutex
+	names map[string]*keyedMutex
+}
+
+// keyedMutex is the mutex of a name, counting its holder and waiters
+type keyedMutex struct {
+	held chan struct{} // holds a value while locked
+	refs int
+}
+
+// Lock locks the named mutex, waiting until it is free or ctx is done. The
+// returned function unlocks it.
+func (k *Keyed) Lock(ctx context.Context, name string) (func(), error) {
+	k.mu.Lock()
+	if k.names == nil {
+		k.names = make(map[string]*keyedMutex)
+	}
+	m, ok := k.names[name]
+	if !ok {
+		m = &keyedMutex{held: make(chan struct{}, 1)}
+		k.names[name] = m
+	}
+	m.refs++
+	k.mu.Unlock()
+
+	select {
+	case m.held <- struct{}{}:
+		return func() {
+			<-m.held
+			k.release(name, m)
+		}, nil
+	case <-ctx.Done():
+		k.release(name, m)
+		return nil, ctx.Err()
+	}
+}
+
+// release drops a reference to a name's mutex
+func (k *Keyed) release(name string, m *keyedMutex) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	m.refs--
+	if m.refs == 0 {
+		delete(k.names, name)
+	}
+}
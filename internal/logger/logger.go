@@ -11,6 +11,12 @@ var Log *zap.Logger
 
 // Initialize initializes the global logger
 func Initialize(level string) error {
+	return InitializeWithOutput(level, "stdout")
+}
+
+// InitializeWithOutput initializes the global logger writing to the given
+// paths, e.g. "stderr" for command-line tools whose stdout carries results
+func InitializeWithOutput(level string, outputPaths ...string) error {
 	var zapLevel zapcore.Level
 	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
 		zapLevel = zapcore.InfoLevel
@@ -23,7 +29,7 @@ func Initialize(level string) error {
 	config.EncoderConfig.CallerKey = "caller"
 	config.EncoderConfig.FunctionKey = "function"
 	config.EncoderConfig.StacktraceKey = "stacktrace"
-	config.OutputPaths = []string{"stdout"}
+	config.OutputPaths = outputPaths
 	config.ErrorOutputPaths = []string{"stderr"}
 
 	logger, err := config.Build(
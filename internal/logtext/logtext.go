@@ -0,0 +1,185 @@
+// Package logtext reads the timestamps and severity levels of log lines in
+// the formats services commonly write: ISO 8601 and RFC 3339 timestamps,
+// syslog, Apache access logs, Go's log package, glog, and JSON lines with
+// time and level fields.
+package logtext
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity levels lines are normalized to, from the most severe
+const (
+	LevelFatal = "fatal"
+	LevelError = "error"
+	LevelWarn  = "warn"
+	LevelInfo  = "info"
+	LevelDebug = "debug"
+)
+
+// Levels lists the severity levels from the most severe
+var Levels = []string{LevelFatal, LevelError, LevelWarn, LevelInfo, LevelDebug}
+
+// levelNames maps the level names loggers write to severity levels
+var levelNames = map[string]string{
+	"fatal": LevelFatal, "panic": LevelFatal, "critical": LevelFatal, "crit": LevelFatal,
+	"emerg": LevelFatal, "emergency": LevelFatal, "alert": LevelFatal,
+	"error": LevelError, "err": LevelError, "severe": LevelError,
+	"warning": LevelWarn, "warn": LevelWarn,
+	"info": LevelInfo, "information": LevelInfo, "notice": LevelInfo,
+	"debug": LevelDebug, "trace": LevelDebug, "verbose": LevelDebug,
+}
+
+// glogLevels maps the letters starting glog lines to severity levels
+var glogLevels = map[string]string{"I": LevelInfo, "W": LevelWarn, "E": LevelError, "F": LevelFatal}
+
+// Level returns the severity level a level name stands for, such as error
+// for ERR or SEVERE, or an empty string for a name it does not know
+func Level(name string) string {
+	return levelNames[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// AtLeast returns the levels at least as severe as level, most severe
+// first
+func AtLeast(level string) []string {
+	for i, l := range Levels {
+		if l == level {
+			return Levels[:i+1]
+		}
+	}
+	return nil
+}
+
+// headBytes is how much of a line is searched for its timestamp and level
+const headBytes = 160
+
+var (
+	isoTime    = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+	slashTime  = regexp.MustCompile(`\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}`)
+	apacheTime = regexp.MustCompile(`\[(\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4})\]`)
+	syslogTime = regexp.MustCompile(`^(?:<\d+>)?([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2})`)
+	glogLine   = regexp.MustCompile(`^([IWEF])(\d{4} \d{2}:\d{2}:\d{2})`)
+	epochTime  = regexp.MustCompile(`"(?:ts|time|timestamp)"\s*:\s*(\d{10})(?:\d{3})?(?:\.\d+)?[,}\s]`)
+
+	// fieldLevel matches levels written as fields: level=error, "level":"warn"
+	fieldLevel = regexp.MustCompile(`(?i)\b(?:level|lvl|severity|loglevel)["']?\s*[=:]\s*["']?([a-z]+)`)
+	// wordLevel matches levels written as upper-case words: ERROR, [WARN]
+	wordLevel = regexp.MustCompile(`\b(FATAL|PANIC|CRITICAL|CRIT|EMERG|ALERT|SEVERE|ERROR|ERR|WARNING|WARN|NOTICE|INFO|DEBUG|TRACE)\b`)
+)
+
+// Entry is what a log line says about itself
+type Entry struct {
+	Time  time.Time // zero when the line has no timestamp
+	Level string    // one of Levels, empty when the line names none
+}
+
+// Parse reads the timestamp and level of a log line. Timestamps without a
+// year, as syslog writes them, take the year of ref, or the year before
+// when that would put them after ref; ref is usually the time the log was
+// last written. Timestamps without a zone are read as UTC.
+func Parse(line string, ref time.Time) Entry {
+	head := line
+	if len(head) > headBytes {
+		head = head[:headBytes]
+	}
+	entry := Entry{Time: parseTime(head, ref)}
+
+	if m := glogLine.FindStringSubmatch(head); m != nil {
+		entry.Level = glogLevels[m[1]]
+	} else if m := fieldLevel.FindStringSubmatch(head); m != nil {
+		entry.Level = levelNames[strings.ToLower(m[1])]
+	}
+	if entry.Level == "" {
+		if m := wordLevel.FindString(head); m != "" {
+			entry.Level = levelNames[strings.ToLower(m)]
+		}
+	}
+	return entry
+}
+
+func parseTime(head string, ref time.Time) time.Time {
+	if m := isoTime.FindString(head); m != "" {
+		value := strings.Replace(strings.Replace(m, " ", "T", 1), ",", ".", 1)
+		if n := len(value); n > 5 && (value[n-5] == '+' || value[n-5] == '-') && !strings.Contains(value[n-5:], ":") {
+			value = value[:n-2] + ":" + value[n-2:] // +0200 to +02:00
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC()
+			}
+		}
+	}
+	if m := apacheTime.FindStringSubmatch(head); m != nil {
+		if t, err := time.Parse("02/Jan/2006:15:04:05 -0700", m[1]); err == nil {
+			return t.UTC()
+		}
+	}
+	if m := slashTime.FindString(head); m != "" {
+		if t, err := time.Parse("2006/01/02 15:04:05", m); err == nil {
+			return t
+		}
+	}
+	if m := epochTime.FindStringSubmatch(head); m != nil {
+		seconds, _ := strconv.ParseInt(m[1], 10, 64)
+		return time.Unix(seconds, 0).UTC()
+	}
+	if m := syslogTime.FindStringSubmatch(head); m != nil {
+		if t, err := time.Parse("Jan _2 15:04:05", m[1]); err == nil {
+			return withYear(t, ref)
+		}
+	}
+	if m := glogLine.FindStringSubmatch(head); m != nil {
+		if t, err := time.Parse("0102 15:04:05", m[2]); err == nil {
+			return withYear(t, ref)
+		}
+	}
+	return time.Time{}
+}
+
+// withYear dates a timestamp written without a year in the year of ref,
+// or the year before when it would otherwise be more than a day after ref
+func withYear(t, ref time.Time) time.Time {
+	if ref.IsZero() {
+		ref = time.Now()
+	}
+	dated := time.Date(ref.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	if dated.After(ref.Add(24 * time.Hour)) {
+		dated = dated.AddDate(-1, 0, 0)
+	}
+	return dated
+}
+
+// Summary is the time span and severity distribution of log lines
+type Summary struct {
+	Start, End time.Time      // zero when no line has a timestamp
+	Levels     map[string]int // lines per level
+	Lines      int
+}
+
+// Summarize reads the timestamps and levels of the lines of text
+func Summarize(text string, ref time.Time) Summary {
+	summary := Summary{Levels: make(map[string]int)}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		summary.Lines++
+		entry := Parse(line, ref)
+		if entry.Level != "" {
+			summary.Levels[entry.Level]++
+		}
+		if entry.Time.IsZero() {
+			continue
+		}
+		if summary.Start.IsZero() || entry.Time.Before(summary.Start) {
+			summary.Start = entry.Time
+		}
+		if entry.Time.After(summary.End) {
+			summary.End = entry.Time
+		}
+	}
+	return summary
+}
@@ -0,0 +1,78 @@
+// Package longpoll lets status endpoints hold a request until what it
+// reports changes, so that clients waiting for a document or run need not
+// poll in a tight loop. Waiting requests read the state again every
+// interval, so a change made by another replica is seen as well.
+package longpoll
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Param is the query parameter of the longest time to wait
+	Param = "wait"
+	// MaxWait caps the time a request waits
+	MaxWait = 60 * time.Second
+	// interval is how often a waiting request checks for a change
+	interval = 500 * time.Millisecond
+	// writeMargin is the time left to write the response after waiting
+	writeMargin = 10 * time.Second
+)
+
+// ParseWait returns the wait a request asks for, as a duration such as 30s
+// or a number of seconds; zero when it asks for none
+func ParseWait(c *gin.Context) (time.Duration, error) {
+	value := c.Query(Param)
+	if value == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("%s must be a duration such as 30s", Param)
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+	if wait < 0 || wait > MaxWait {
+		return 0, fmt.Errorf("%s must be between 0s and %s", Param, MaxWait)
+	}
+	return wait, nil
+}
+
+// Extend moves the write deadline of a request past the wait, so that a
+// server write timeout shorter than the wait does not cut the response
+func Extend(c *gin.Context, wait time.Duration) {
+	//nolint:errcheck // writers without deadlines keep the server's timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(wait + writeMargin))
+}
+
+// Until calls changed every interval until it reports a change, the wait
+// passes or ctx is done. It returns whether a change was seen, and the
+// first error of changed.
+func Until(ctx context.Context, wait time.Duration, changed func(ctx context.Context) (bool, error)) (bool, error) {
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case <-deadline.C:
+			return false, nil
+		case <-ticker.C:
+		}
+		ok, err := changed(ctx)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+}
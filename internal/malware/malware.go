@@ -0,0 +1,155 @@
+// Package malware scans files for malware before they are processed, with
+// ClamAV's clamd or a scan API. A flagged file is rejected, or moved to a
+// quarantine directory so that no later scan picks it up again.
+package malware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Scanner scans a file for malware
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, filePath string) (Verdict, error)
+}
+
+// Verdict is the result of scanning a file
+type Verdict struct {
+	Infected  bool
+	Signature string // name of the malware found
+}
+
+// InfectedError rejects a file the scanner flagged
+type InfectedError struct {
+	Signature  string
+	Quarantine string // where the file was moved; empty when it was left in place
+}
+
+func (e *InfectedError) Error() string {
+	if e.Quarantine != "" {
+		return fmt.Sprintf("malware detected: %s (quarantined)", e.Signature)
+	}
+	return fmt.Sprintf("malware detected: %s", e.Signature)
+}
+
+// Guard scans files before they are processed and acts on flagged ones
+type Guard struct {
+	scanner       Scanner
+	timeout       time.Duration
+	quarantineDir string
+	failOpen      bool
+	logger        *zap.Logger
+}
+
+// New creates the guard selected by configuration. It returns nil without
+// an error when scanning is disabled.
+func New(cfg *config.Config, logger *zap.Logger) (*Guard, error) {
+	c := cfg.Malware
+	var scanner Scanner
+	switch c.Scanner {
+	case "none", "":
+		return nil, nil
+	case "clamav":
+		scanner = newClamAVScanner(c.ClamAVAddress)
+	case "api":
+		scanner = newAPIScanner(c.APIURL, c.APIKey)
+	default:
+		return nil, fmt.Errorf("unknown malware scanner: %s", c.Scanner)
+	}
+
+	g := &Guard{scanner: scanner, timeout: c.Timeout, failOpen: c.FailOpen, logger: logger}
+	if c.Action == "quarantine" {
+		g.quarantineDir = utils.LocalPath(c.QuarantineDir)
+		if err := os.MkdirAll(g.quarantineDir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create quarantine directory: %w", err)
+		}
+	}
+
+	logger.Info("Malware scanning enabled",
+		zap.String("scanner", scanner.Name()),
+		zap.String("quarantine_dir", g.quarantineDir),
+		zap.Bool("fail_open", g.failOpen))
+	return g, nil
+}
+
+// Check scans a file. A flagged file is moved to quarantine when that is
+// the configured action, and reported as an *InfectedError. A file that
+// could not be scanned is an error, unless the guard fails open.
+func (g *Guard) Check(ctx context.Context, filePath string) error {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+	verdict, err := g.scanner.Scan(ctx, utils.LocalPath(filePath))
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("scan timed out after %s", g.timeout)
+		}
+		if g.failOpen {
+			g.logger.Warn("Malware scan failed, processing file unscanned",
+				zap.String("file", filePath),
+				zap.Error(err))
+			return nil
+		}
+		return fmt.Errorf("malware scan failed: %w", err)
+	}
+	if !verdict.Infected {
+		return nil
+	}
+
+	infected := &InfectedError{Signature: verdict.Signature}
+	if g.quarantineDir != "" {
+		if infected.Quarantine, err = g.quarantine(filePath); err != nil {
+			g.logger.Error("Failed to quarantine file",
+				zap.String("file", filePath),
+				zap.Error(err))
+		}
+	}
+	g.logger.Warn("Malware detected, file rejected",
+		zap.String("file", filePath),
+		zap.String("signature", verdict.Signature),
+		zap.String("quarantine", infected.Quarantine))
+	return infected
+}
+
+// quarantine moves a file into the quarantine directory, under its name
+// prefixed with the time, and returns its new path
+func (g *Guard) quarantine(filePath string) (string, error) {
+	src := utils.LocalPath(filePath)
+	dst := filepath.Join(g.quarantineDir, fmt.Sprintf("%d-%s", time.Now().UnixNano(), filepath.Base(src)))
+	if err := os.Rename(src, dst); err == nil {
+		return dst, nil
+	}
+
+	// Across file systems the file is copied, then removed
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	if err := os.Remove(src); err != nil {
+		return "", fmt.Errorf("copied to %s but could not remove the original: %w", dst, err)
+	}
+	return dst, nil
+}
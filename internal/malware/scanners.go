@@ -0,0 +1,196 @@
+package malware
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// clamdChunk is the size of the chunks a file is streamed to clamd in
+const clamdChunk = 64 * 1024
+
+// clamAVScanner streams files to ClamAV's clamd with the INSTREAM command,
+// so clamd needs no access to the files themselves
+type clamAVScanner struct {
+	network string
+	address string
+}
+
+// newClamAVScanner creates a scanner for clamd at a unix socket path or a
+// host:port
+func newClamAVScanner(address string) *clamAVScanner {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		return &clamAVScanner{network: "unix", address: path}
+	}
+	if strings.HasPrefix(address, "/") {
+		return &clamAVScanner{network: "unix", address: address}
+	}
+	return &clamAVScanner{network: "tcp", address: address}
+}
+
+func (s *clamAVScanner) Name() string {
+	return "clamav"
+}
+
+// Scan sends the file in length-prefixed chunks, ended by an empty chunk,
+// and reads clamd's verdict
+func (s *clamAVScanner) Scan(ctx context.Context, filePath string) (Verdict, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	// Closing the connection unblocks reads and writes when ctx ends
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := s.send(conn, file); err != nil {
+		// clamd replies before closing a stream it refuses, such as one
+		// above its StreamMaxLength
+		if reply, readErr := readReply(conn); readErr == nil && reply != "" {
+			return parseClamdReply(reply)
+		}
+		return Verdict{}, fmt.Errorf("failed to send file to clamd: %w", err)
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamdReply(reply)
+}
+
+func (s *clamAVScanner) send(conn net.Conn, file io.Reader) error {
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+clamdChunk)
+	for {
+		n, err := file.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// readReply reads a null-terminated clamd reply
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(io.LimitReader(conn, 4096)).ReadString(0)
+	if err != nil && !(err == io.EOF && reply != "") {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// parseClamdReply reads clamd's reply to a stream: "stream: OK",
+// "stream: Eicar-Test-Signature FOUND", or an error ending in "ERROR"
+func parseClamdReply(reply string) (Verdict, error) {
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	case result == "OK":
+		return Verdict{}, nil
+	}
+	return Verdict{}, fmt.Errorf("clamd: %s", reply)
+}
+
+// apiScanner uploads files to a scan API as the "file" field of a
+// multipart form. The API answers with JSON naming whether the file is
+// infected and, if so, the malware found:
+// {"infected": true, "signature": "Eicar-Test-Signature"}.
+type apiScanner struct {
+	url        string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newAPIScanner(url, apiKey string) *apiScanner {
+	return &apiScanner{url: url, apiKey: apiKey, httpClient: &http.Client{}}
+}
+
+func (s *apiScanner) Name() string {
+	return "api"
+}
+
+// Scan streams the file to the API and decodes its verdict
+func (s *apiScanner) Scan(ctx context.Context, filePath string) (Verdict, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		part, err := form.CreateFormFile("file", filepath.Base(filePath))
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, body)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096)) //nolint:errcheck
+		return Verdict{}, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(message))
+	}
+
+	var verdict struct {
+		Infected  *bool  `json:"infected"`
+		Signature string `json:"signature"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&verdict); err != nil {
+		return Verdict{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if verdict.Infected == nil {
+		return Verdict{}, fmt.Errorf("no verdict in response")
+	}
+	if *verdict.Infected && verdict.Signature == "" {
+		verdict.Signature = "unnamed malware"
+	}
+	return Verdict{Infected: *verdict.Infected, Signature: verdict.Signature}, nil
+}
@@ -0,0 +1,153 @@
+// Package mathtext finds mathematical formulas in text: LaTeX math in its
+// display and inline delimiters, MathML, and lines of Unicode math as
+// extracted from PDFs. Markdown code spans and fenced blocks are not
+// searched, so code using dollar signs is not taken for math.
+package mathtext
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formula kinds
+const (
+	KindLaTeX   = "latex"
+	KindMathML  = "mathml"
+	KindUnicode = "unicode"
+)
+
+// maxFormulaLength bounds a formula, so an unmatched delimiter does not
+// take the rest of the text
+const maxFormulaLength = 4000
+
+// Formula is a formula found in text
+type Formula struct {
+	Kind    string
+	Source  string // the formula with its delimiters
+	Display bool   // set apart from the text rather than inline
+}
+
+var (
+	fencedCode = regexp.MustCompile("(?s)```.*?```|~~~.*?~~~")
+	inlineCode = regexp.MustCompile("`[^`\n]+`")
+
+	displayMath = regexp.MustCompile(`(?s)\$\$(.+?)\$\$|\\\[(.+?)\\\]`)
+	environment = regexp.MustCompile(`\\begin\{(equation|align|gather|multline|eqnarray|math|displaymath|flalign|alignat)(\*?)\}`)
+	inlineParen = regexp.MustCompile(`(?s)\\\((.+?)\\\)`)
+	inlineMath  = regexp.MustCompile(`\$([^\s$](?:[^$\n]*[^\s$\\])?)\$`)
+	mathML      = regexp.MustCompile(`(?is)<(?:\w+:)?math\b.*?</(?:\w+:)?math>`)
+
+	// inlineSignal is what an inline $...$ span must contain to be math
+	// rather than two amounts of money
+	inlineSignal = regexp.MustCompile(`^[A-Za-z]$|[\\^_={}<>]`)
+)
+
+// mathRunes are symbols that mark a line of Unicode math
+const mathRunes = "∑∏∫∮√∞∂∇≤≥≠≈≡±×÷∈∉⊂⊆⊃∪∩∀∃∝∧∨¬⇒⇔→↦∘⊗⊕′″αβγδεζηθλμνξπρστφχψωΓΔΘΛΞΠΣΦΨΩ"
+
+// minMathRunes is how many math symbols a line needs to be a formula
+const minMathRunes = 2
+
+// match is a formula at its position in the text
+type match struct {
+	start, end int
+	formula    Formula
+}
+
+// Find returns the formulas of a text in order, each source once
+func Find(text string) []Formula {
+	work := []byte(text)
+	blank := func(start, end int) {
+		for i := start; i < end; i++ {
+			if work[i] != '\n' {
+				work[i] = ' '
+			}
+		}
+	}
+	for _, loc := range fencedCode.FindAllIndex(work, -1) {
+		blank(loc[0], loc[1])
+	}
+	for _, loc := range inlineCode.FindAllIndex(work, -1) {
+		blank(loc[0], loc[1])
+	}
+
+	var matches []match
+	take := func(start, end int, kind string, display bool) {
+		if end-start > maxFormulaLength {
+			return
+		}
+		matches = append(matches, match{start, end, Formula{Kind: kind, Source: text[start:end], Display: display}})
+		blank(start, end)
+	}
+
+	for _, loc := range mathML.FindAllIndex(work, -1) {
+		take(loc[0], loc[1], KindMathML, strings.Contains(text[loc[0]:loc[1]], `display="block"`))
+	}
+	for _, loc := range environment.FindAllSubmatchIndex(work, -1) {
+		if work[loc[0]] == ' ' {
+			continue // inside a formula taken already
+		}
+		end := "\\end{" + text[loc[2]:loc[3]] + text[loc[4]:loc[5]] + "}"
+		if i := strings.Index(string(work[loc[1]:]), end); i >= 0 {
+			take(loc[0], loc[1]+i+len(end), KindLaTeX, true)
+		}
+	}
+	for _, loc := range displayMath.FindAllIndex(work, -1) {
+		take(loc[0], loc[1], KindLaTeX, true)
+	}
+	for _, loc := range inlineParen.FindAllIndex(work, -1) {
+		take(loc[0], loc[1], KindLaTeX, false)
+	}
+	for _, loc := range inlineMath.FindAllSubmatchIndex(work, -1) {
+		// An amount followed by a number is money: "$5 to $10"
+		if loc[1] < len(work) && work[loc[1]] >= '0' && work[loc[1]] <= '9' {
+			continue
+		}
+		if inlineSignal.Match(work[loc[2]:loc[3]]) {
+			take(loc[0], loc[1], KindLaTeX, false)
+		}
+	}
+
+	offset := 0
+	for _, line := range strings.SplitAfter(string(work), "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && isMathLine(trimmed) {
+			start := offset + strings.Index(line, trimmed)
+			take(start, start+len(trimmed), KindUnicode, true)
+		}
+		offset += len(line)
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	seen := make(map[string]bool, len(matches))
+	formulas := make([]Formula, 0, len(matches))
+	for _, m := range matches {
+		if !seen[m.formula.Source] {
+			seen[m.formula.Source] = true
+			formulas = append(formulas, m.formula)
+		}
+	}
+	return formulas
+}
+
+// Contains reports whether a text holds any formula
+func Contains(text string) bool {
+	return len(Find(text)) > 0
+}
+
+// isMathLine reports whether a line reads as a formula in Unicode math
+// symbols, as PDF extraction yields: short, with a relation and enough
+// math symbols
+func isMathLine(line string) bool {
+	if utf8.RuneCountInString(line) > 200 || !strings.ContainsAny(line, "=<>≤≥≠≈≡∝∈⊂⊆→⇒⇔") {
+		return false
+	}
+	count := 0
+	for _, r := range line {
+		if strings.ContainsRune(mathRunes, r) {
+			count++
+		}
+	}
+	return count >= minMathRunes
+}
@@ -0,0 +1,258 @@
+// Package mcp serves the knowledge base over the Model Context Protocol, so
+// IDE assistants and chat clients can search it, ask it questions and read
+// its documents as tools. Messages are JSON-RPC 2.0, read from standard
+// input by an assistant that starts the server or posted over HTTP. Tools
+// call the gateway through the Go SDK; each caller may be limited to some
+// tools and to the documents of a user, and results are cut to a size
+// a model's context can hold.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/sdk"
+	"go.uber.org/zap"
+)
+
+// ProtocolVersion is the latest protocol version the server speaks
+const ProtocolVersion = "2025-06-18"
+
+// protocolVersions are the versions the server speaks, newest first
+var protocolVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// message is a JSON-RPC request or notification; notifications have no ID
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is the JSON-RPC response to a request
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Caller is who calls the tools: the tools it may call and the user its
+// calls are made for
+type Caller struct {
+	Name     string
+	Tools    []string      // empty allows every offered tool
+	Identity *sdk.Identity // nil calls as the gateway key alone
+}
+
+// allows reports whether the caller may call a tool
+func (c *Caller) allows(name string) bool {
+	return len(c.Tools) == 0 || slices.Contains(c.Tools, name)
+}
+
+// Server answers MCP messages with the offered tools
+type Server struct {
+	client *sdk.Client
+	cfg    config.MCPConfig
+	tools  []*tool // offered, in the order they are listed
+	logger *zap.Logger
+}
+
+// New creates a server offering the configured tools, which call the
+// gateway with client
+func New(cfg config.MCPConfig, client *sdk.Client, logger *zap.Logger) *Server {
+	s := &Server{client: client, cfg: cfg, logger: logger}
+	for _, t := range tools {
+		if len(cfg.Tools) == 0 || slices.Contains(cfg.Tools, t.Name) {
+			s.tools = append(s.tools, t)
+		}
+	}
+	return s
+}
+
+// Tools returns the names of the offered tools
+func (s *Server) Tools() []string {
+	names := make([]string, len(s.tools))
+	for i, t := range s.tools {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Handle answers a JSON-RPC message from caller. It returns nil for
+// notifications and for responses sent by the client, which need no answer.
+func (s *Server) Handle(ctx context.Context, caller *Caller, data []byte) []byte {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return encode(response{ID: json.RawMessage("null"), Error: &rpcError{Code: codeParseError, Message: "invalid JSON: " + err.Error()}})
+	}
+	if msg.Method == "" && msg.ID != nil {
+		return nil
+	}
+	if msg.JSONRPC != "2.0" || msg.Method == "" {
+		id := msg.ID
+		if id == nil {
+			id = json.RawMessage("null")
+		}
+		return encode(response{ID: id, Error: &rpcError{Code: codeInvalidRequest, Message: "not a JSON-RPC 2.0 request"}})
+	}
+	if msg.ID == nil {
+		// Notifications such as notifications/initialized need no action
+		return nil
+	}
+
+	result, rpcErr := s.dispatch(ctx, caller, &msg)
+	return encode(response{ID: msg.ID, Result: result, Error: rpcErr})
+}
+
+// dispatch runs the method of a request
+func (s *Server) dispatch(ctx context.Context, caller *Caller, msg *message) (interface{}, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		return s.initialize(msg.Params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools(caller), nil
+	case "tools/call":
+		return s.callTool(ctx, caller, msg.Params)
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// initialize agrees on the protocol version, the client's when the server
+// speaks it and the latest otherwise, and describes the server
+func (s *Server) initialize(params json.RawMessage) (interface{}, *rpcError) {
+	var req struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid initialize params: " + err.Error()}
+		}
+	}
+	version := ProtocolVersion
+	if slices.Contains(protocolVersions, req.ProtocolVersion) {
+		version = req.ProtocolVersion
+	}
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{"listChanged": false},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    "repograph",
+			"title":   "RepoGraph knowledge base",
+			"version": "1.0.0",
+		},
+		"instructions": "Search the indexed documents, ask questions answered from them with citations, " +
+			"and read a document by the document_id a search or answer gives. Results are cut at " +
+			fmt.Sprintf("%d characters; narrow the request when a result is cut.", s.cfg.MaxResultChars),
+	}, nil
+}
+
+// listTools lists the offered tools the caller may call
+func (s *Server) listTools(caller *Caller) interface{} {
+	listed := make([]*tool, 0, len(s.tools))
+	for _, t := range s.tools {
+		if caller.allows(t.Name) {
+			listed = append(listed, t)
+		}
+	}
+	return map[string]interface{}{"tools": listed}
+}
+
+// toolResult is the result of a tool call. Failures of the tool, such as a
+// document not found, are results with isError set, so the model sees them.
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// callTool calls a tool the caller may call, within the configured timeout
+func (s *Server) callTool(ctx context.Context, caller *Caller, params json.RawMessage) (interface{}, *rpcError) {
+	var req struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &req); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid tools/call params: " + err.Error()}
+	}
+	var found *tool
+	for _, t := range s.tools {
+		if t.Name == req.Name && caller.allows(t.Name) {
+			found = t
+		}
+	}
+	if found == nil {
+		// Tools the caller may not call are not listed to it either
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + req.Name}
+	}
+	if len(req.Arguments) == 0 {
+		req.Arguments = json.RawMessage("{}")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	if caller.Identity != nil {
+		ctx = sdk.WithIdentity(ctx, *caller.Identity)
+	}
+
+	start := time.Now()
+	text, err := found.call(ctx, s, req.Arguments)
+	fields := []zap.Field{
+		zap.String("tool", found.Name),
+		zap.String("caller", caller.Name),
+		zap.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		s.logger.Warn("MCP tool call failed", append(fields, zap.Error(err))...)
+		return toolResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	s.logger.Info("MCP tool called", fields...)
+	return toolResult{Content: []textContent{{Type: "text", Text: s.cut(text)}}}, nil
+}
+
+// cut shortens a result to the configured number of characters, saying
+// that it was cut
+func (s *Server) cut(text string) string {
+	runes := []rune(text)
+	if len(runes) <= s.cfg.MaxResultChars {
+		return text
+	}
+	return string(runes[:s.cfg.MaxResultChars]) +
+		fmt.Sprintf("\n\n[Result cut at %d of %d characters]", s.cfg.MaxResultChars, len(runes))
+}
+
+func encode(resp response) []byte {
+	resp.JSONRPC = "2.0"
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(response{JSONRPC: "2.0", ID: resp.ID, Error: &rpcError{Code: codeInternalError, Message: err.Error()}}) //nolint:errcheck
+	}
+	return data
+}
@@ -0,0 +1,231 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nadeeshame/rag-knowledge-service/pkg/sdk"
+)
+
+// defaultTopK is the chunks retrieved when a call does not say
+const defaultTopK = 5
+
+// tool is a tool the server can offer
+type tool struct {
+	Name        string                 `json:"name"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	// call runs the tool with its arguments and returns its text
+	call func(ctx context.Context, s *Server, args json.RawMessage) (string, error)
+}
+
+// tools are the tools the server can offer, of config.MCPTools
+var tools = []*tool{
+	{
+		Name:  "search",
+		Title: "Search the knowledge base",
+		Description: "Find the passages of the indexed documents most relevant to a query, " +
+			"with their file, score and document_id. Use it to find where something is described.",
+		InputSchema: schema(map[string]interface{}{
+			"query":      property("string", "what to look for, in natural language"),
+			"top_k":      property("integer", "number of passages to return"),
+			"collection": property("string", "only search documents in this collection"),
+		}, "query"),
+		call: search,
+	},
+	{
+		Name:  "ask",
+		Title: "Ask the knowledge base",
+		Description: "Answer a question from the indexed documents. The answer cites its sources, " +
+			"which are listed with their file and document_id.",
+		InputSchema: schema(map[string]interface{}{
+			"question":   property("string", "the question to answer"),
+			"top_k":      property("integer", "number of passages the answer is given"),
+			"collection": property("string", "only answer from documents in this collection"),
+		}, "question"),
+		call: ask,
+	},
+	{
+		Name:  "get_document",
+		Title: "Read a document",
+		Description: "Get an indexed document by the document_id a search or answer gives: " +
+			"its file, state and summary, and its extracted text.",
+		InputSchema: schema(map[string]interface{}{
+			"document_id":     property("string", "document_id of the document"),
+			"include_content": property("boolean", "include the extracted text; defaults to true"),
+		}, "document_id"),
+		call: getDocument,
+	},
+}
+
+// retrieval are the arguments of the search and ask tools
+type retrieval struct {
+	Query      string `json:"query"`
+	Question   string `json:"question"`
+	TopK       int    `json:"top_k"`
+	Collection string `json:"collection"`
+}
+
+// request builds the query of a retrieval, with top_k capped
+func (r *retrieval) request(s *Server, text string) sdk.QueryRequest {
+	topK := r.TopK
+	if topK <= 0 {
+		topK = min(defaultTopK, s.cfg.MaxTopK)
+	}
+	req := sdk.QueryRequest{Text: text, TopK: min(topK, s.cfg.MaxTopK)}
+	if r.Collection != "" {
+		req.Filter = &sdk.QueryFilter{Collection: r.Collection}
+	}
+	return req
+}
+
+func search(ctx context.Context, s *Server, args json.RawMessage) (string, error) {
+	var r retrieval
+	if err := decode(args, &r); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(r.Query) == "" {
+		return "", errors.New("query is required")
+	}
+	resp, err := s.client.Search(ctx, r.request(s, r.Query))
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Results) == 0 {
+		return "No passages found.", nil
+	}
+
+	var b strings.Builder
+	for i, result := range resp.Results {
+		fmt.Fprintf(&b, "[%d] %s (score %.3f, document_id %s)\n%s\n\n",
+			i+1, result.FilePath, result.Score, result.DocumentID, strings.TrimSpace(result.Content))
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func ask(ctx context.Context, s *Server, args json.RawMessage) (string, error) {
+	var r retrieval
+	if err := decode(args, &r); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(r.Question) == "" {
+		return "", errors.New("question is required")
+	}
+	result, err := s.client.Query(ctx, r.request(s, r.Question))
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(result.Answer))
+	if len(result.Sources) > 0 {
+		b.WriteString("\n\nSources:\n")
+		for i, source := range result.Sources {
+			fmt.Fprintf(&b, "[%d] %s (document_id %s)\n", i+1, source.FilePath, source.DocumentID)
+		}
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func getDocument(ctx context.Context, s *Server, args json.RawMessage) (string, error) {
+	var r struct {
+		DocumentID     string `json:"document_id"`
+		IncludeContent *bool  `json:"include_content"`
+	}
+	if err := decode(args, &r); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(r.DocumentID) == "" {
+		return "", errors.New("document_id is required")
+	}
+	doc, err := s.client.Document(ctx, r.DocumentID)
+	if sdk.IsNotFound(err) {
+		return "", fmt.Errorf("document %s not found", r.DocumentID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if doc.Title != "" {
+		fmt.Fprintf(&b, "Title: %s\n", doc.Title)
+	}
+	fmt.Fprintf(&b, "File: %s\nType: %s\nState: %s\n", doc.FilePath, doc.FileType, doc.State)
+	if len(doc.Tags) > 0 {
+		fmt.Fprintf(&b, "Tags: %s\n", strings.Join(doc.Tags, ", "))
+	}
+	if doc.IndexedAt != nil {
+		fmt.Fprintf(&b, "Indexed: %s\n", doc.IndexedAt.Format("2006-01-02 15:04:05"))
+	}
+	if doc.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", doc.Error)
+	}
+	if doc.Summary != "" {
+		fmt.Fprintf(&b, "\nSummary:\n%s\n", strings.TrimSpace(doc.Summary))
+	}
+
+	if r.IncludeContent != nil && !*r.IncludeContent {
+		return strings.TrimSpace(b.String()), nil
+	}
+	if !doc.ContentStored {
+		b.WriteString("\nThe extracted text of this document is not stored.")
+		return b.String(), nil
+	}
+	// Read no more of the text than the result can hold
+	budget := s.cfg.MaxResultChars - utf8.RuneCountInString(b.String())
+	text, cut, err := s.readContent(ctx, doc.ID, budget)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(&b, "\nContent:\n%s", text)
+	if cut {
+		fmt.Fprintf(&b, "\n\n[Content cut at %d characters; the document is longer]", utf8.RuneCountInString(text))
+	}
+	return b.String(), nil
+}
+
+// cutNoteLength leaves room for the note saying content was cut
+const cutNoteLength = 80
+
+// readContent reads up to budget characters of a document's text, and
+// reports whether there was more
+func (s *Server) readContent(ctx context.Context, id string, budget int) (string, bool, error) {
+	body, err := s.client.DocumentContent(ctx, id)
+	if err != nil {
+		return "", false, err
+	}
+	defer body.Close()
+
+	budget = max(budget-cutNoteLength, 0)
+	data, err := io.ReadAll(io.LimitReader(body, int64(budget*utf8.UTFMax)+1))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read document content: %w", err)
+	}
+	runes := []rune(string(data))
+	if len(data) <= budget*utf8.UTFMax && len(runes) <= budget {
+		return string(data), false, nil
+	}
+	return string(runes[:min(budget, len(runes))]), true, nil
+}
+
+// decode reads the arguments of a tool call
+func decode(args json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+func schema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+}
+
+func property(kind, description string) map[string]interface{} {
+	return map[string]interface{}{"type": kind, "description": description}
+}
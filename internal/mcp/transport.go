@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/sdk"
+	"go.uber.org/zap"
+)
+
+// maxMessageBytes caps the size of a message read from a client
+const maxMessageBytes = 4 << 20
+
+// ServeStdio answers the messages read from in, one JSON object per line,
+// writing each response on a line of out, until in ends or ctx is done
+func (s *Server) ServeStdio(ctx context.Context, caller *Caller, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageBytes)
+	w := bufio.NewWriter(out)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		resp := s.Handle(ctx, caller, line)
+		if resp == nil {
+			continue
+		}
+		if _, err := w.Write(append(resp, '\n')); err != nil {
+			return fmt.Errorf("failed to write response: %w", err)
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to write response: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read message: %w", err)
+	}
+	return nil
+}
+
+// Register serves the HTTP transport at path: messages are posted one per
+// request and answered in the response body. Callers authenticate with a
+// key in X-API-Key or as a bearer token, which selects their tools; browser
+// requests are refused unless their origin is allowed by CORS.
+func (s *Server) Register(router gin.IRouter, path string, cfg *config.Config) {
+	router.POST(path, s.originAllowed(cfg.CORS), s.authenticate(), func(c *gin.Context) {
+		data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxMessageBytes+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read message"})
+			return
+		}
+		if len(data) > maxMessageBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("messages are limited to %d bytes", maxMessageBytes)})
+			return
+		}
+		caller := c.MustGet(contextKeyCaller).(*Caller)
+		resp := s.Handle(c.Request.Context(), caller, data)
+		if resp == nil {
+			c.Status(http.StatusAccepted)
+			return
+		}
+		c.Data(http.StatusOK, "application/json", resp)
+	})
+	// Server-initiated messages are not sent, so there is no event stream
+	// to open, and without sessions none to end
+	router.GET(path, func(c *gin.Context) {
+		c.Header("Allow", http.MethodPost)
+		c.Status(http.StatusMethodNotAllowed)
+	})
+}
+
+// contextKeyCaller holds the authenticated caller of an HTTP request
+const contextKeyCaller = "mcp.caller"
+
+// authenticate resolves the caller of a request from its key. Without
+// configured keys every request may call every offered tool.
+func (s *Server) authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(s.cfg.APIKeys) == 0 && len(s.cfg.Clients) == 0 {
+			c.Set(contextKeyCaller, &Caller{Name: "ip:" + c.ClientIP()})
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader("X-API-Key")
+		if key == "" {
+			key = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if caller := s.Caller(key); key != "" && caller != nil {
+			c.Set(contextKeyCaller, caller)
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid API key"})
+	}
+}
+
+// Caller returns the caller with a key: a configured client, limited to
+// its tools and calling for its user, or a caller of every offered tool
+// for a key of api_keys. It returns nil for unknown keys.
+func (s *Server) Caller(key string) *Caller {
+	for _, client := range s.cfg.Clients {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(client.Key)) == 1 {
+			return clientCaller(client)
+		}
+	}
+	for i, valid := range s.cfg.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(valid)) == 1 {
+			return &Caller{Name: fmt.Sprintf("api_key:%d", i+1)}
+		}
+	}
+	return nil
+}
+
+// ClientCaller returns the caller of a configured client by name, or nil
+func (s *Server) ClientCaller(name string) *Caller {
+	for _, client := range s.cfg.Clients {
+		if client.Name == name {
+			return clientCaller(client)
+		}
+	}
+	return nil
+}
+
+func clientCaller(client config.MCPClient) *Caller {
+	caller := &Caller{Name: client.Name, Tools: client.Tools}
+	if client.UserID != "" || len(client.Groups) > 0 || client.Tenant != "" {
+		caller.Identity = &sdk.Identity{UserID: client.UserID, Groups: client.Groups, Tenant: client.Tenant}
+	}
+	return caller
+}
+
+// originAllowed refuses browser requests from origins CORS does not allow,
+// so a web page cannot reach a server on the user's machine through DNS
+// rebinding
+func (s *Server) originAllowed(cors config.CORSConfig) gin.HandlerFunc {
+	origins := make([]string, len(cors.AllowedOrigins))
+	for i, origin := range cors.AllowedOrigins {
+		origins[i] = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	}
+	return func(c *gin.Context) {
+		origin := strings.ToLower(c.GetHeader("Origin"))
+		if origin == "" || slices.Contains(origins, "*") || slices.Contains(origins, origin) {
+			c.Next()
+			return
+		}
+		s.logger.Warn("Refused MCP request from origin", zap.String("origin", origin))
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "origin not allowed"})
+	}
+}
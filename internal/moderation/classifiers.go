@@ -0,0 +1,180 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+)
+
+const (
+	// contentSafetyAPIVersion is the Azure AI Content Safety API version
+	contentSafetyAPIVersion = "2024-09-01"
+	// contentSafetyMaxText is the most characters Content Safety analyzes
+	// in one request; longer text is analyzed in parts
+	contentSafetyMaxText = 10000
+)
+
+// azureClassifier analyzes text with Azure AI Content Safety, which rates
+// hate, self-harm, sexual and violent content from 0 to 7
+type azureClassifier struct {
+	endpoint   string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newAzureClassifier(endpoint, apiKey string) *azureClassifier {
+	return &azureClassifier{endpoint: strings.TrimSuffix(endpoint, "/"), apiKey: apiKey, httpClient: &http.Client{}}
+}
+
+func (c *azureClassifier) Name() string {
+	return "azure"
+}
+
+// Classify analyzes the text in parts Content Safety accepts and keeps the
+// highest severity of each category
+func (c *azureClassifier) Classify(ctx context.Context, text string) ([]models.SafetyCategory, error) {
+	severities := make(map[string]int)
+	var order []string
+	for _, part := range splitText(text, contentSafetyMaxText) {
+		categories, err := c.analyze(ctx, part)
+		if err != nil {
+			return nil, err
+		}
+		for _, category := range categories {
+			severity, seen := severities[category.Category]
+			if !seen {
+				order = append(order, category.Category)
+			}
+			severities[category.Category] = max(severity, category.Severity)
+		}
+	}
+
+	categories := make([]models.SafetyCategory, len(order))
+	for i, name := range order {
+		categories[i] = models.SafetyCategory{Category: name, Severity: severities[name]}
+	}
+	return categories, nil
+}
+
+// analyze sends one part of a text to the text:analyze operation
+func (c *azureClassifier) analyze(ctx context.Context, text string) ([]models.SafetyCategory, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"text":       text,
+		"outputType": "EightSeverityLevels",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	url := c.endpoint + "/contentsafety/text:analyze?api-version=" + contentSafetyAPIVersion
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Ocp-Apim-Subscription-Key", c.apiKey)
+
+	var result struct {
+		CategoriesAnalysis []struct {
+			Category string `json:"category"`
+			Severity int    `json:"severity"`
+		} `json:"categoriesAnalysis"`
+	}
+	if err := doJSON(c.httpClient, req, &result); err != nil {
+		return nil, err
+	}
+	categories := make([]models.SafetyCategory, len(result.CategoriesAnalysis))
+	for i, a := range result.CategoriesAnalysis {
+		categories[i] = models.SafetyCategory{Category: strings.ToLower(a.Category), Severity: a.Severity}
+	}
+	return categories, nil
+}
+
+// apiClassifier posts text to a classifier API as JSON, {"text": "..."}.
+// The API answers with the severity, from 0 to 7, of each category it
+// found: {"categories": [{"category": "hate", "severity": 4}]}.
+type apiClassifier struct {
+	url        string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newAPIClassifier(url, apiKey string) *apiClassifier {
+	return &apiClassifier{url: url, apiKey: apiKey, httpClient: &http.Client{}}
+}
+
+func (c *apiClassifier) Name() string {
+	return "api"
+}
+
+// Classify sends the text to the API and decodes its categories
+func (c *apiClassifier) Classify(ctx context.Context, text string) ([]models.SafetyCategory, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	var result struct {
+		Categories *[]models.SafetyCategory `json:"categories"`
+	}
+	if err := doJSON(c.httpClient, req, &result); err != nil {
+		return nil, err
+	}
+	if result.Categories == nil {
+		return nil, fmt.Errorf("no categories in response")
+	}
+	categories := *result.Categories
+	for i := range categories {
+		categories[i].Category = strings.ToLower(categories[i].Category)
+	}
+	return categories, nil
+}
+
+// doJSON sends a request and decodes its JSON response into v
+func doJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096)) //nolint:errcheck
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(message))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// splitText splits a text into parts of at most n characters, at spaces
+// where possible
+func splitText(text string, n int) []string {
+	runes := []rune(text)
+	var parts []string
+	for len(runes) > n {
+		cut := n
+		for i := n; i > n/2; i-- {
+			if runes[i] == ' ' || runes[i] == '\n' {
+				cut = i
+				break
+			}
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	return append(parts, string(runes))
+}
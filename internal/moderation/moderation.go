@@ -0,0 +1,227 @@
+// Package moderation classifies queries and generated answers for harmful
+// content, with Azure AI Content Safety or a classifier API. Text with a
+// category at or above the policy's threshold severity is blocked,
+// answered with a warning, or only logged; tenants can have policies of
+// their own.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// Actions taken on flagged text
+const (
+	ActionBlock = "block" // the query is refused, or the answer withheld
+	ActionWarn  = "warn"  // the response carries the verdict
+	ActionLog   = "log"   // the verdict is only logged
+)
+
+// Classifier rates the severity of categories of harmful content in a text
+type Classifier interface {
+	Name() string
+	Classify(ctx context.Context, text string) ([]models.SafetyCategory, error)
+}
+
+// BlockedError is returned for a query or answer blocked by moderation
+type BlockedError struct {
+	Verdict models.ModerationVerdict
+}
+
+func (e *BlockedError) Error() string {
+	categories := make([]string, len(e.Verdict.Categories))
+	for i, c := range e.Verdict.Categories {
+		categories[i] = fmt.Sprintf("%s (severity %d)", c.Category, c.Severity)
+	}
+	return fmt.Sprintf("%s blocked by content moderation: %s", e.Verdict.Subject, strings.Join(categories, ", "))
+}
+
+// Policy says what is classified and how flagged text is handled
+type Policy struct {
+	Action     string
+	Threshold  int
+	Queries    bool
+	Answers    bool
+	Categories []string // lower-case; empty checks all
+}
+
+// Moderator classifies queries and answers under the policy of their tenant
+type Moderator struct {
+	classifier Classifier
+	timeout    time.Duration
+	failOpen   bool
+	policy     Policy
+	tenants    map[string]Policy
+	logger     *zap.Logger
+}
+
+// New creates the moderator selected by configuration. It returns nil
+// without an error when moderation is disabled.
+func New(cfg *config.Config, logger *zap.Logger) (*Moderator, error) {
+	c := cfg.Moderation
+	var classifier Classifier
+	switch c.Classifier {
+	case "none", "":
+		return nil, nil
+	case "azure":
+		classifier = newAzureClassifier(c.Endpoint, c.APIKey)
+	case "api":
+		classifier = newAPIClassifier(c.Endpoint, c.APIKey)
+	default:
+		return nil, fmt.Errorf("unknown moderation classifier: %s", c.Classifier)
+	}
+
+	m := &Moderator{
+		classifier: classifier,
+		timeout:    c.Timeout,
+		failOpen:   c.FailOpen,
+		policy: Policy{
+			Action:     c.Action,
+			Threshold:  c.Threshold,
+			Queries:    c.Queries,
+			Answers:    c.Answers,
+			Categories: categoryNames(c.Categories),
+		},
+		tenants: make(map[string]Policy, len(c.Tenants)),
+		logger:  logger,
+	}
+	for tenant, o := range c.Tenants {
+		m.tenants[tenant] = m.policy.override(o)
+	}
+
+	logger.Info("Content moderation enabled",
+		zap.String("classifier", classifier.Name()),
+		zap.String("action", m.policy.Action),
+		zap.Int("threshold", m.policy.Threshold),
+		zap.Int("tenant_policies", len(m.tenants)))
+	return m, nil
+}
+
+// override returns the policy with a tenant's changes
+func (p Policy) override(o config.ModerationOverride) Policy {
+	if o.Action != "" {
+		p.Action = o.Action
+	}
+	if o.Threshold != 0 {
+		p.Threshold = o.Threshold
+	}
+	if o.Queries != nil {
+		p.Queries = *o.Queries
+	}
+	if o.Answers != nil {
+		p.Answers = *o.Answers
+	}
+	if o.Categories != nil {
+		p.Categories = categoryNames(o.Categories)
+	}
+	return p
+}
+
+// categoryNames normalizes category names to lower case, dropping empty
+// ones left by a trailing comma
+func categoryNames(names []string) []string {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized
+}
+
+// Policy returns the policy of a tenant, the service-wide one for tenants
+// without their own
+func (m *Moderator) Policy(tenant string) Policy {
+	if p, ok := m.tenants[tenant]; ok {
+		return p
+	}
+	return m.policy
+}
+
+// Blocks reports whether the policy of the identity's tenant classifies a
+// subject and blocks it when flagged
+func (m *Moderator) Blocks(subject string, identity *models.Identity) bool {
+	policy := m.Policy(tenantOf(identity))
+	return policy.covers(subject) && policy.Action == ActionBlock
+}
+
+// covers reports whether the policy classifies a subject
+func (p Policy) covers(subject string) bool {
+	return subject == models.ModerationQuery && p.Queries || subject == models.ModerationAnswer && p.Answers
+}
+
+// tenantOf returns the tenant of an identity, empty for none
+func tenantOf(identity *models.Identity) string {
+	if identity == nil {
+		return ""
+	}
+	return identity.Tenant
+}
+
+// Check classifies a query or answer when the tenant's policy covers it.
+// It returns the verdict on flagged text, nil when the text was not
+// flagged or not classified. Blocked text is also reported as a
+// *BlockedError. Text that could not be classified is an error, unless the
+// moderator fails open.
+func (m *Moderator) Check(ctx context.Context, subject string, identity *models.Identity, text string) (*models.ModerationVerdict, error) {
+	tenant := tenantOf(identity)
+	policy := m.Policy(tenant)
+	if !policy.covers(subject) {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	categories, err := m.classifier.Classify(ctx, text)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("classification timed out after %s", m.timeout)
+		}
+		if m.failOpen {
+			m.logger.Warn("Content moderation failed, continuing unclassified",
+				zap.String("subject", subject),
+				zap.String("tenant", tenant),
+				zap.Error(err))
+			return nil, nil
+		}
+		return nil, fmt.Errorf("content moderation failed: %w", err)
+	}
+
+	verdict := &models.ModerationVerdict{Subject: subject, Action: policy.Action}
+	for _, c := range categories {
+		if c.Severity >= policy.Threshold && (len(policy.Categories) == 0 || slices.Contains(policy.Categories, c.Category)) {
+			verdict.Categories = append(verdict.Categories, c)
+		}
+	}
+	if len(verdict.Categories) == 0 {
+		return nil, nil
+	}
+	sort.Slice(verdict.Categories, func(i, j int) bool {
+		return verdict.Categories[i].Severity > verdict.Categories[j].Severity
+	})
+
+	fields := []zap.Field{
+		zap.String("subject", subject),
+		zap.String("tenant", tenant),
+		zap.String("action", policy.Action),
+		zap.Any("categories", verdict.Categories),
+	}
+	if policy.Action == ActionLog {
+		m.logger.Info("Content moderation flagged text", fields...)
+		return verdict, nil
+	}
+	m.logger.Warn("Content moderation flagged text", fields...)
+	if policy.Action == ActionBlock {
+		return verdict, &BlockedError{Verdict: *verdict}
+	}
+	return verdict, nil
+}
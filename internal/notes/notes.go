@@ -0,0 +1,161 @@
+// Package notes reads the structure of Markdown notes as kept in personal
+// knowledge bases such as Obsidian vaults: YAML frontmatter with a title,
+// tags and aliases, and [[wiki-links]] to other notes, which a Resolver
+// turns into links between documents.
+package notes
+
+import (
+	"regexp"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Note is what a Markdown note says about itself and links to
+type Note struct {
+	Title   string
+	Tags    []string
+	Aliases []string
+	// Links are the targets of the note's wiki-links as written, without
+	// heading or display text, each once
+	Links []string
+}
+
+var (
+	fencedCode = regexp.MustCompile("(?s)```.*?```|~~~.*?~~~")
+	inlineCode = regexp.MustCompile("`[^`\n]+`")
+
+	// wikiLink matches [[target]], [[target#heading]], [[target|text]] and
+	// embeds written ![[target]]
+	wikiLink = regexp.MustCompile(`!?\[\[([^\[\]|#\n]*)(?:#[^\[\]|\n]*)?(?:\|[^\[\]\n]*)?\]\]`)
+)
+
+// IsMarkdown reports whether a file type (an extension with the dot) is
+// Markdown
+func IsMarkdown(fileType string) bool {
+	switch strings.ToLower(fileType) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// Parse reads the frontmatter and wiki-links of a note. Frontmatter that is
+// not valid YAML is ignored.
+func Parse(text string) Note {
+	var note Note
+	frontmatter, body := splitFrontmatter(text)
+	if frontmatter != "" {
+		var fields map[string]interface{}
+		if err := yaml.Unmarshal([]byte(frontmatter), &fields); err == nil {
+			note.Title = stringField(fields["title"])
+			note.Tags = tags(fields)
+			note.Aliases = listField(fields["aliases"], fields["alias"])
+		}
+	}
+	note.Links = Links(body)
+	return note
+}
+
+// splitFrontmatter separates the YAML frontmatter between the --- lines at
+// the start of a note from the rest of it
+func splitFrontmatter(text string) (string, string) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	rest, ok := strings.CutPrefix(text, "---\n")
+	if !ok {
+		if rest, ok = strings.CutPrefix(text, "---\r\n"); !ok {
+			return "", text
+		}
+	}
+	offset := 0
+	for _, line := range strings.SplitAfter(rest, "\n") {
+		if end := strings.TrimRight(line, "\r\n"); end == "---" || end == "..." {
+			return rest[:offset], rest[offset+len(line):]
+		}
+		offset += len(line)
+	}
+	return "", text
+}
+
+// Links returns the targets of the wiki-links in text, each once. Links in
+// code are not links.
+func Links(text string) []string {
+	masked := fencedCode.ReplaceAllStringFunc(text, blank)
+	masked = inlineCode.ReplaceAllStringFunc(masked, blank)
+
+	var links []string
+	seen := make(map[string]bool)
+	for _, m := range wikiLink.FindAllStringSubmatch(masked, -1) {
+		target := strings.TrimSpace(m[1])
+		if target == "" || seen[strings.ToLower(target)] {
+			continue // a link to a heading of the note itself
+		}
+		seen[strings.ToLower(target)] = true
+		links = append(links, target)
+	}
+	return links
+}
+
+// blank replaces text with spaces, so what follows keeps its position
+func blank(s string) string {
+	return strings.Repeat(" ", len(s))
+}
+
+// tags reads the tags of a note, written as a list or as a string
+// separated by commas or spaces, with or without a leading #
+func tags(fields map[string]interface{}) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, value := range listField(fields["tags"], fields["tag"]) {
+		for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			tag = strings.TrimPrefix(tag, "#")
+			if tag != "" && !seen[strings.ToLower(tag)] {
+				seen[strings.ToLower(tag)] = true
+				out = append(out, tag)
+			}
+		}
+	}
+	return out
+}
+
+// listField reads frontmatter values that may be a list or a single
+// value, skipping empty ones
+func listField(values ...interface{}) []string {
+	var out []string
+	for _, value := range values {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, item := range v {
+				if s := stringField(item); s != "" {
+					out = append(out, s)
+				}
+			}
+		default:
+			if s := stringField(v); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// stringField reads a scalar frontmatter value as a string
+func stringField(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case nil, map[string]interface{}, []interface{}:
+		return ""
+	default:
+		return strings.TrimSpace(yamlScalar(v))
+	}
+}
+
+// yamlScalar formats a number, boolean or date the way it was written
+func yamlScalar(v interface{}) string {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,97 @@
+package notes
+
+import (
+	"path"
+	"strings"
+)
+
+// Resolver finds the documents wiki-links point to. A link names a note by
+// its file name with or without the .md extension, by a path ending in
+// that name, or by one of the note's aliases, all without regard to case.
+// A name shared by several documents resolves to the one in the linking
+// note's directory, or else to the one with the shortest path.
+type Resolver struct {
+	byName  map[string][]entry
+	byAlias map[string][]entry
+}
+
+type entry struct {
+	id   string
+	path string // slash-separated, lower case
+}
+
+// NewResolver creates an empty resolver
+func NewResolver() *Resolver {
+	return &Resolver{
+		byName:  make(map[string][]entry),
+		byAlias: make(map[string][]entry),
+	}
+}
+
+// Add makes a document a link target under its file name and aliases
+func (r *Resolver) Add(id, filePath string, aliases []string) {
+	e := entry{id: id, path: strings.ToLower(slashPath(filePath))}
+	name := path.Base(e.path)
+	r.byName[name] = append(r.byName[name], e)
+	for _, alias := range aliases {
+		key := strings.ToLower(strings.TrimSpace(alias))
+		if key != "" {
+			r.byAlias[key] = append(r.byAlias[key], e)
+		}
+	}
+}
+
+// Resolve returns the ID of the document a link target written in the
+// note at from points to
+func (r *Resolver) Resolve(from, target string) (string, bool) {
+	target = strings.ToLower(strings.Trim(slashPath(strings.TrimSpace(target)), "/"))
+	if target == "" {
+		return "", false
+	}
+	dir := path.Dir(strings.ToLower(slashPath(from)))
+
+	for _, name := range []string{target, target + ".md"} {
+		var candidates []entry
+		for _, e := range r.byName[path.Base(name)] {
+			if !strings.Contains(name, "/") || strings.HasSuffix(e.path, "/"+name) {
+				candidates = append(candidates, e)
+			}
+		}
+		if e, ok := nearest(candidates, dir); ok {
+			return e.id, true
+		}
+	}
+	if e, ok := nearest(r.byAlias[target], dir); ok {
+		return e.id, true
+	}
+	return "", false
+}
+
+// nearest picks the candidate in dir, or else the one with the shortest path
+func nearest(candidates []entry, dir string) (entry, bool) {
+	if len(candidates) == 0 {
+		return entry{}, false
+	}
+	best := candidates[0]
+	for _, e := range candidates[1:] {
+		inDir, bestInDir := path.Dir(e.path) == dir, path.Dir(best.path) == dir
+		switch {
+		case inDir != bestInDir:
+			if inDir {
+				best = e
+			}
+		case len(e.path) != len(best.path):
+			if len(e.path) < len(best.path) {
+				best = e
+			}
+		case e.path < best.path:
+			best = e
+		}
+	}
+	return best, true
+}
+
+// slashPath writes a path with forward slashes
+func slashPath(p string) string {
+	return strings.ReplaceAll(p, "\\", "/")
+}
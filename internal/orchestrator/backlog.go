@@ -0,0 +1,162 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// Backlog holds the jobs waiting in the ingest queue
+type Backlog interface {
+	// Push adds jobs, all or none: it fails with ErrQueueFull when they do
+	// not all fit. It returns the jobs added; a shared backlog leaves out
+	// files already waiting or being processed.
+	Push(ctx context.Context, jobs []*Job) ([]*Job, error)
+	// Claim takes the highest priority waiting job, or returns nil when
+	// none waits. A shared backlog hides the job from other replicas until
+	// it is acknowledged, or offers it again once its claim expires.
+	Claim(ctx context.Context) (*Job, error)
+	// Extend keeps a claimed job from being offered again while it is
+	// processed; it reports false once the claim has expired
+	Extend(ctx context.Context, job *Job) (bool, error)
+	// Ack removes a claimed job once processed
+	Ack(ctx context.Context, job *Job) error
+	// Stats returns the waiting jobs per priority and the oldest of each
+	Stats(ctx context.Context) (BacklogStats, error)
+	// Poll is how often idle workers look for jobs pushed by other
+	// replicas, or zero when every job is pushed through this queue
+	Poll() time.Duration
+	Close() error
+}
+
+// BacklogStats describes the jobs of a backlog
+type BacklogStats struct {
+	Depth    map[Priority]int
+	Oldest   map[Priority]time.Time
+	Capacity int
+	Claimed  int // jobs being processed by any replica, for shared backlogs
+}
+
+// Compile-time checks that the backlogs implement the interface
+var (
+	_ Backlog = (*MemoryBacklog)(nil)
+	_ Backlog = (*RedisBacklog)(nil)
+)
+
+// NewBacklog creates the ingest queue's backlog: in process memory, or
+// shared by the replicas through Redis when sharding is enabled
+func NewBacklog(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Backlog, error) {
+	if !cfg.Sharding.Enabled {
+		return NewMemoryBacklog(cfg.App.IngestQueueSize), nil
+	}
+	client, err := redisclient.Connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Ingest queue shared across replicas",
+		zap.String("key_prefix", cfg.Sharding.KeyPrefix),
+		zap.Duration("visibility_timeout", cfg.Sharding.VisibilityTimeout))
+	return NewRedisBacklog(client, cfg.Sharding, cfg.App.IngestQueueSize), nil
+}
+
+// MemoryBacklog keeps waiting jobs in process memory, taking higher
+// priority jobs first and jobs of the same priority in order
+type MemoryBacklog struct {
+	capacity int
+
+	mu   sync.Mutex
+	jobs map[Priority][]*Job
+	size int
+}
+
+// NewMemoryBacklog creates a backlog holding up to capacity jobs
+func NewMemoryBacklog(capacity int) *MemoryBacklog {
+	return &MemoryBacklog{capacity: capacity, jobs: make(map[Priority][]*Job, len(priorities))}
+}
+
+// Push adds jobs, all or none
+func (b *MemoryBacklog) Push(_ context.Context, jobs []*Job) ([]*Job, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.size+len(jobs) > b.capacity {
+		return nil, ErrQueueFull
+	}
+	for _, job := range jobs {
+		b.jobs[job.Priority] = append(b.jobs[job.Priority], job)
+	}
+	b.size += len(jobs)
+	return jobs, nil
+}
+
+// Claim removes the highest priority waiting job
+func (b *MemoryBacklog) Claim(_ context.Context) (*Job, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, p := range priorities {
+		if waiting := b.jobs[p]; len(waiting) > 0 {
+			job := waiting[0]
+			waiting[0] = nil
+			b.jobs[p] = waiting[1:]
+			b.size--
+			job.Deliveries++
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// Extend is a no-op; claims in memory do not expire
+func (b *MemoryBacklog) Extend(_ context.Context, _ *Job) (bool, error) {
+	return true, nil
+}
+
+// Ack is a no-op; claimed jobs are already removed
+func (b *MemoryBacklog) Ack(_ context.Context, _ *Job) error {
+	return nil
+}
+
+// Stats returns the waiting jobs per priority
+func (b *MemoryBacklog) Stats(_ context.Context) (BacklogStats, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := BacklogStats{
+		Depth:    make(map[Priority]int, len(priorities)),
+		Oldest:   make(map[Priority]time.Time, len(priorities)),
+		Capacity: b.capacity,
+	}
+	for _, p := range priorities {
+		stats.Depth[p] = len(b.jobs[p])
+		if len(b.jobs[p]) > 0 {
+			stats.Oldest[p] = b.jobs[p][0].EnqueuedAt
+		}
+	}
+	return stats, nil
+}
+
+// Poll returns zero; every job is pushed through the queue
+func (b *MemoryBacklog) Poll() time.Duration {
+	return 0
+}
+
+// Close is a no-op for the memory backlog
+func (b *MemoryBacklog) Close() error {
+	return nil
+}
+
+// priorityIndex returns the position of a priority in priorities
+func priorityIndex(p Priority) (int, error) {
+	for i, q := range priorities {
+		if q == p {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid priority %q", p)
+}
@@ -0,0 +1,270 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// pushScript adds jobs not already queued by file path, all or none.
+// KEYS: jobs, paths, then the pending list of each priority; ARGV: the
+// capacity, then the ID, path, priority position and JSON of each job.
+var pushScript = redis.NewScript(`
+local waiting = 0
+for i = 3, #KEYS do
+	waiting = waiting + redis.call("LLEN", KEYS[i])
+end
+local added = {}
+local seen = {}
+for i = 2, #ARGV, 4 do
+	local path = ARGV[i + 1]
+	if not seen[path] and redis.call("HEXISTS", KEYS[2], path) == 0 then
+		seen[path] = true
+		table.insert(added, i)
+	end
+end
+if waiting + #added > tonumber(ARGV[1]) then
+	return -1
+end
+local ids = {}
+for _, i in ipairs(added) do
+	redis.call("HSET", KEYS[1], ARGV[i], ARGV[i + 3])
+	redis.call("HSET", KEYS[2], ARGV[i + 1], ARGV[i])
+	redis.call("RPUSH", KEYS[3 + tonumber(ARGV[i + 2])], ARGV[i])
+	table.insert(ids, ARGV[i])
+end
+return ids`)
+
+// reclaimScript moves the jobs whose claims expired back to the front of
+// their pending lists. KEYS: jobs, claimed, then the pending list of each
+// priority; ARGV: the time in milliseconds, then the priority names.
+var reclaimScript = redis.NewScript(`
+local lists = {}
+for i = 2, #ARGV do
+	lists[ARGV[i]] = KEYS[i + 1]
+end
+local expired = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", ARGV[1])
+for _, id in ipairs(expired) do
+	redis.call("ZREM", KEYS[2], id)
+	local data = redis.call("HGET", KEYS[1], id)
+	if data then
+		local list = lists[cjson.decode(data).priority] or KEYS[#KEYS]
+		redis.call("LPUSH", list, id)
+	end
+end
+return #expired`)
+
+// claimScript takes the first job of the highest priority pending list and
+// hides it until the deadline. KEYS: jobs, claimed, deliveries, then the
+// pending list of each priority; ARGV: the deadline in milliseconds.
+var claimScript = redis.NewScript(`
+for i = 4, #KEYS do
+	while true do
+		local id = redis.call("LPOP", KEYS[i])
+		if not id then
+			break
+		end
+		local data = redis.call("HGET", KEYS[1], id)
+		if data then
+			redis.call("ZADD", KEYS[2], ARGV[1], id)
+			return {data, redis.call("HINCRBY", KEYS[3], id, 1)}
+		end
+	end
+end
+return false`)
+
+// extendScript moves a claim's deadline if it has not expired. KEYS:
+// claimed; ARGV: the job ID and the deadline in milliseconds.
+var extendScript = redis.NewScript(`
+if redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+	redis.call("ZADD", KEYS[1], ARGV[2], ARGV[1])
+	return 1
+end
+return 0`)
+
+// ackScript removes a job. KEYS: jobs, paths, claimed, deliveries; ARGV:
+// the job ID and path.
+var ackScript = redis.NewScript(`
+redis.call("ZREM", KEYS[3], ARGV[1])
+redis.call("HDEL", KEYS[1], ARGV[1])
+redis.call("HDEL", KEYS[4], ARGV[1])
+if redis.call("HGET", KEYS[2], ARGV[2]) == ARGV[1] then
+	redis.call("HDEL", KEYS[2], ARGV[2])
+end
+return 1`)
+
+// RedisBacklog shares waiting jobs between replicas. Job bodies are kept
+// in a hash and their IDs in a list per priority; a claimed job's ID moves
+// to a sorted set scored by when its claim expires, and jobs whose claims
+// expire, as when their replica stopped, are offered again first.
+type RedisBacklog struct {
+	client     *redis.Client
+	capacity   int
+	visibility time.Duration
+	poll       time.Duration
+
+	jobs       string // hash of job ID to job
+	paths      string // hash of file path to the ID of its job
+	claimed    string // sorted set of claimed job IDs by claim expiry
+	deliveries string // hash of job ID to times claimed
+	pending    []string
+}
+
+// NewRedisBacklog creates a backlog holding up to capacity waiting jobs
+func NewRedisBacklog(client *redis.Client, cfg config.ShardingConfig, capacity int) *RedisBacklog {
+	b := &RedisBacklog{
+		client:     client,
+		capacity:   capacity,
+		visibility: cfg.VisibilityTimeout,
+		poll:       cfg.PollInterval,
+		jobs:       cfg.KeyPrefix + ":jobs",
+		paths:      cfg.KeyPrefix + ":paths",
+		claimed:    cfg.KeyPrefix + ":claimed",
+		deliveries: cfg.KeyPrefix + ":deliveries",
+	}
+	for _, p := range priorities {
+		b.pending = append(b.pending, cfg.KeyPrefix+":pending:"+string(p))
+	}
+	return b
+}
+
+// Push adds the jobs whose files are not already waiting or being
+// processed, all or none
+func (b *RedisBacklog) Push(ctx context.Context, jobs []*Job) ([]*Job, error) {
+	args := make([]interface{}, 0, 1+4*len(jobs))
+	args = append(args, b.capacity)
+	byID := make(map[string]*Job, len(jobs))
+	for _, job := range jobs {
+		index, err := priorityIndex(job.Priority)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(job)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal job: %w", err)
+		}
+		args = append(args, job.ID, job.FilePath, index, data)
+		byID[job.ID] = job
+	}
+
+	keys := append([]string{b.jobs, b.paths}, b.pending...)
+	result, err := pushScript.Run(ctx, b.client, keys, args...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue jobs: %w", err)
+	}
+	ids, ok := result.([]interface{})
+	if !ok {
+		return nil, ErrQueueFull
+	}
+	added := make([]*Job, 0, len(ids))
+	for _, id := range ids {
+		if job, ok := byID[fmt.Sprint(id)]; ok {
+			added = append(added, job)
+		}
+	}
+	return added, nil
+}
+
+// Claim takes the highest priority waiting job for the visibility timeout,
+// first offering again the jobs whose claims expired
+func (b *RedisBacklog) Claim(ctx context.Context) (*Job, error) {
+	now := time.Now()
+	args := []interface{}{now.UnixMilli()}
+	for _, p := range priorities {
+		args = append(args, string(p))
+	}
+	if err := reclaimScript.Run(ctx, b.client, append([]string{b.jobs, b.claimed}, b.pending...), args...).Err(); err != nil {
+		return nil, fmt.Errorf("failed to reclaim expired jobs: %w", err)
+	}
+
+	keys := append([]string{b.jobs, b.claimed, b.deliveries}, b.pending...)
+	result, err := claimScript.Run(ctx, b.client, keys, now.Add(b.visibility).UnixMilli()).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	if len(result) != 2 {
+		return nil, fmt.Errorf("failed to claim job: unexpected reply %v", result)
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(fmt.Sprint(result[0])), &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	if n, ok := result[1].(int64); ok {
+		job.Deliveries = int(n)
+	}
+	return &job, nil
+}
+
+// Extend moves the job's claim to expire a visibility timeout from now
+func (b *RedisBacklog) Extend(ctx context.Context, job *Job) (bool, error) {
+	deadline := time.Now().Add(b.visibility).UnixMilli()
+	held, err := extendScript.Run(ctx, b.client, []string{b.claimed}, job.ID, deadline).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to extend job claim: %w", err)
+	}
+	return held == 1, nil
+}
+
+// Ack removes a processed job
+func (b *RedisBacklog) Ack(ctx context.Context, job *Job) error {
+	keys := []string{b.jobs, b.paths, b.claimed, b.deliveries}
+	if err := ackScript.Run(ctx, b.client, keys, job.ID, job.FilePath).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge job: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the waiting jobs per priority across the replicas
+func (b *RedisBacklog) Stats(ctx context.Context) (BacklogStats, error) {
+	stats := BacklogStats{
+		Depth:    make(map[Priority]int, len(priorities)),
+		Oldest:   make(map[Priority]time.Time, len(priorities)),
+		Capacity: b.capacity,
+	}
+	pipe := b.client.Pipeline()
+	lengths := make([]*redis.IntCmd, len(priorities))
+	firsts := make([]*redis.StringCmd, len(priorities))
+	for i := range priorities {
+		lengths[i] = pipe.LLen(ctx, b.pending[i])
+		firsts[i] = pipe.LIndex(ctx, b.pending[i], 0)
+	}
+	claimed := pipe.ZCard(ctx, b.claimed)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return stats, fmt.Errorf("failed to read ingest queue: %w", err)
+	}
+	stats.Claimed = int(claimed.Val())
+
+	for i, p := range priorities {
+		stats.Depth[p] = int(lengths[i].Val())
+		if firsts[i].Val() == "" {
+			continue
+		}
+		data, err := b.client.HGet(ctx, b.jobs, firsts[i].Val()).Result()
+		if err != nil {
+			continue
+		}
+		var job Job
+		if json.Unmarshal([]byte(data), &job) == nil {
+			stats.Oldest[p] = job.EnqueuedAt
+		}
+	}
+	return stats, nil
+}
+
+// Poll returns how often idle workers look for jobs
+func (b *RedisBacklog) Poll() time.Duration {
+	return b.poll
+}
+
+// Close closes the Redis client
+func (b *RedisBacklog) Close() error {
+	return b.client.Close()
+}
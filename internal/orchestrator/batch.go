@@ -0,0 +1,204 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// MaxBatchItems caps the items of a batch
+const MaxBatchItems = 1000
+
+// Batch item statuses
+const (
+	BatchAccepted = "accepted" // processed by the batch's run
+	BatchSkipped  = "skipped"  // repeated in the batch, or indexed and unchanged
+	BatchInvalid  = "invalid"  // not a readable file
+)
+
+// BatchItem is the status of one item of a batch
+type BatchItem struct {
+	Item     string `json:"item"`                // path or file:// URL as sent
+	FilePath string `json:"file_path,omitempty"` // the file it names
+	Status   string `json:"status"`
+	Reason   string `json:"reason,omitempty"` // why the item was skipped or is invalid
+}
+
+// Batch is a list of files processed as one run
+type Batch struct {
+	RunID string      `json:"run_id,omitempty"` // empty when no item was accepted
+	Items []BatchItem `json:"items"`
+
+	run   *runs.Run
+	files []string
+}
+
+// Files returns the paths of the accepted items
+func (b *Batch) Files() []string {
+	return b.files
+}
+
+// Count returns the number of items with a status
+func (b *Batch) Count(status string) int {
+	n := 0
+	for _, item := range b.Items {
+		if item.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// PrepareBatch checks each item of a batch, accepting the readable files.
+// An item naming a file of an earlier item, or a file indexed and not
+// modified since, unless force is set, is skipped. Items that are not
+// local paths or file:// URLs of regular files under the data roots are
+// invalid. When any item
+// is accepted, admit is given the accepted files and, unless it fails, the
+// batch's run is recorded as running, so its progress can be followed
+// before ProcessBatch starts it. admit may be nil.
+func (dp *DocumentProcessor) PrepareBatch(ctx context.Context, items []string, force bool, admit func(files []string) error) (*Batch, error) {
+	batch := &Batch{Items: make([]BatchItem, 0, len(items))}
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		result := BatchItem{Item: item}
+		path, err := batchPath(item)
+		if err == nil {
+			err = ConfinePath(DataRoots(dp.config), path)
+		}
+		if err == nil {
+			result.FilePath = path
+			err = checkBatchFile(path)
+		}
+		switch {
+		case err != nil:
+			result.Status, result.Reason = BatchInvalid, err.Error()
+		case seen[path]:
+			result.Status, result.Reason = BatchSkipped, "repeats an earlier item"
+		case !force && dp.unchanged(ctx, path):
+			result.Status, result.Reason = BatchSkipped, "already indexed and not modified since"
+		default:
+			result.Status = BatchAccepted
+			batch.files = append(batch.files, path)
+		}
+		if result.FilePath != "" {
+			seen[path] = true
+		}
+		batch.Items = append(batch.Items, result)
+	}
+	if len(batch.files) == 0 {
+		return batch, nil
+	}
+	if admit != nil {
+		if err := admit(batch.files); err != nil {
+			return nil, err
+		}
+	}
+
+	batch.run = &runs.Run{
+		ID:        uuid.New().String(),
+		Batch:     true,
+		Status:    runs.StatusRunning,
+		Force:     force,
+		StartedAt: time.Now(),
+		Total:     len(batch.files),
+		Changes:   []runs.Change{},
+	}
+	batch.RunID = batch.run.ID
+	dp.saveRun(ctx, batch.run)
+	dp.logger.Info("Prepared batch",
+		zap.String("run_id", batch.RunID),
+		zap.Int("accepted", len(batch.files)),
+		zap.Int("skipped", batch.Count(BatchSkipped)),
+		zap.Int("invalid", batch.Count(BatchInvalid)))
+	return batch, nil
+}
+
+// ProcessBatch processes the accepted files of a batch as its run, which
+// like a directory run waits while indexing is paused and can be
+// cancelled and resumed
+func (dp *DocumentProcessor) ProcessBatch(ctx context.Context, batch *Batch) (*DirectoryResult, error) {
+	if batch.run == nil {
+		return nil, errors.New("batch has no accepted items")
+	}
+	lock, err := dp.lockRun(ctx, batch.run)
+	if err != nil {
+		// The run was recorded as running; leave it to be resumed
+		batch.run.Status, batch.run.Remaining, batch.run.FinishedAt = runs.StatusCancelled, batch.files, time.Now()
+		dp.saveRun(context.WithoutCancel(ctx), batch.run)
+		return nil, err
+	}
+	defer lock.Release(context.WithoutCancel(ctx))
+
+	result := &DirectoryResult{RunID: batch.run.ID, Total: len(batch.files)}
+	return dp.processRun(ctx, batch.run, batch.files, result, lock)
+}
+
+// unchanged reports whether a file is indexed and was not modified since
+func (dp *DocumentProcessor) unchanged(ctx context.Context, path string) bool {
+	if dp.registry == nil {
+		return false
+	}
+	record, err := dp.registry.GetByPath(ctx, path)
+	if err != nil {
+		if !errors.Is(err, registry.ErrNotFound) {
+			dp.logger.Warn("Failed to look up batch file", zap.String("file", path), zap.Error(err))
+		}
+		return false
+	}
+	if record.State != models.StateIndexed || record.ModifiedAt == nil {
+		return false
+	}
+	info, err := os.Stat(utils.LocalPath(path))
+	return err == nil && info.ModTime().Equal(*record.ModifiedAt)
+}
+
+// batchPath returns the normalized path an item names: the item itself, or
+// the path of a file:// URL
+func batchPath(item string) (string, error) {
+	item = strings.TrimSpace(item)
+	if item == "" {
+		return "", errors.New("empty path")
+	}
+	if scheme, _, ok := strings.Cut(item, "://"); ok {
+		if !strings.EqualFold(scheme, "file") {
+			return "", fmt.Errorf("unsupported URL scheme %q: only local paths and file:// URLs are processed", scheme)
+		}
+		u, err := url.Parse(item)
+		if err != nil {
+			return "", fmt.Errorf("invalid URL: %w", err)
+		}
+		if u.Host != "" && u.Host != "localhost" {
+			return "", fmt.Errorf("file URL names remote host %q", u.Host)
+		}
+		item = u.Path
+	}
+	return utils.NormalizePath(item), nil
+}
+
+// checkBatchFile checks that a path names a regular file
+func checkBatchFile(path string) error {
+	info, err := os.Stat(utils.LocalPath(path))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return errors.New("file not found")
+	case err != nil:
+		return fmt.Errorf("file not readable: %w", err)
+	case info.IsDir():
+		return errors.New("is a directory; process directories with /process/directory")
+	case !info.Mode().IsRegular():
+		return errors.New("not a regular file")
+	}
+	return nil
+}
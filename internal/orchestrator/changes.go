@@ -0,0 +1,126 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// changeDigestDocuments is the most changed documents described to the
+	// model when generating a run's change digest
+	changeDigestDocuments = 50
+	// changeDigestSummaryChars is how much of each document summary is
+	// included in the digest prompt
+	changeDigestSummaryChars = 800
+)
+
+const changeDigestSystemPrompt = `You write short release-note style digests of what changed in a document collection.
+You are given the documents that were added or updated by an indexing run, each with a summary of its content.
+Describe what is new in a few short paragraphs or bullet points, grouping related documents and naming the notable ones by file name.
+Only use the information given; do not speculate about content that is not summarized.`
+
+// SetRunStore makes the processor record each directory run with the
+// documents it added or updated
+func (dp *DocumentProcessor) SetRunStore(store runs.Store) {
+	dp.runStore = store
+}
+
+// previousVersion returns the registry record of a file before it is
+// processed, or nil when the file has none
+func (dp *DocumentProcessor) previousVersion(ctx context.Context, filePath string) *registry.Record {
+	if dp.registry == nil {
+		return nil
+	}
+	record, err := dp.registry.GetByPath(ctx, utils.NormalizePath(filePath))
+	if err != nil {
+		if !errors.Is(err, registry.ErrNotFound) {
+			dp.logger.Warn("Failed to read document registry",
+				zap.String("file", filePath),
+				zap.Error(err))
+		}
+		return nil
+	}
+	return record
+}
+
+// recordChange adds a processed file to the run's changes when processing
+// indexed a new version of it. A file is added when no earlier version was
+// indexed and updated otherwise.
+func (dp *DocumentProcessor) recordChange(ctx context.Context, run *runs.Run, filePath string, previous *registry.Record) {
+	current := dp.previousVersion(ctx, filePath)
+	if current == nil || current.State != models.StateIndexed {
+		return
+	}
+	if previous != nil && previous.ID == current.ID {
+		return
+	}
+
+	change := runs.Change{
+		DocumentID: current.ID,
+		FilePath:   current.FilePath,
+		Kind:       runs.ChangeAdded,
+	}
+	if current.Summary != summaryFailed {
+		change.Summary = current.Summary
+	}
+	if previous != nil && previous.IndexedAt != nil {
+		change.Kind = runs.ChangeUpdated
+		change.PreviousID = previous.ID
+	}
+	run.Changes = append(run.Changes, change)
+}
+
+// summarizeChanges generates the change digest of a run from the summaries
+// of the documents it added or updated. A failed generation is logged and
+// leaves the digest empty.
+func (dp *DocumentProcessor) summarizeChanges(ctx context.Context, run *runs.Run) {
+	if len(run.Changes) == 0 {
+		return
+	}
+
+	var prompt strings.Builder
+	added, updated := run.Counts()
+	fmt.Fprintf(&prompt, "Indexing %s added %d and updated %d document(s).\n\n", run.Directory, added, updated)
+	for i, change := range run.Changes {
+		if i == changeDigestDocuments {
+			fmt.Fprintf(&prompt, "(%d more document(s) not listed)\n", len(run.Changes)-i)
+			break
+		}
+		summary := change.Summary
+		if summary == "" {
+			summary = "(no summary available)"
+		} else if len(summary) > changeDigestSummaryChars {
+			summary = strings.ToValidUTF8(summary[:changeDigestSummaryChars], "") + "..."
+		}
+		fmt.Fprintf(&prompt, "- %s [%s]: %s\n", change.FilePath, change.Kind, summary)
+	}
+
+	digest, err := dp.azureClient.ChatCompletion(ctx, changeDigestSystemPrompt, prompt.String())
+	if err != nil {
+		dp.logger.Warn("Failed to generate change digest",
+			zap.String("run_id", run.ID),
+			zap.Error(err))
+		return
+	}
+	run.Digest = strings.TrimSpace(digest)
+}
+
+// saveRun records a run in the run store
+func (dp *DocumentProcessor) saveRun(ctx context.Context, run *runs.Run) {
+	if dp.runStore == nil {
+		return
+	}
+	if err := dp.runStore.Save(ctx, run); err != nil {
+		dp.logger.Warn("Failed to record run",
+			zap.String("run_id", run.ID),
+			zap.Error(err))
+	}
+}
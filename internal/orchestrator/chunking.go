@@ -0,0 +1,110 @@
+package orchestrator
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+)
+
+// chunkCount returns the number of chunks forEachChunk produces for
+// content of the given size
+func chunkCount(size int64, chunkSize, overlap int) int {
+	if size <= int64(chunkSize) {
+		return 1
+	}
+	step := int64(chunkSize - overlap)
+	return int((size + step - 1) / step)
+}
+
+// forEachChunk reads content of the given size from r and calls fn with
+// each overlapping chunk in order. Only one chunk is held in memory at a
+// time. A chunk starts every chunkSize-overlap bytes; content that fits
+// in one chunk yields exactly one.
+func forEachChunk(r io.Reader, size int64, chunkSize, overlap int, fn func(index int, chunk string) error) error {
+	buf := make([]byte, chunkSize)
+	step := chunkSize - overlap
+	filled := 0
+	eof := false
+
+	for i := 0; ; i++ {
+		if !eof {
+			n, err := io.ReadFull(r, buf[filled:])
+			filled += n
+			switch err {
+			case nil:
+			case io.EOF, io.ErrUnexpectedEOF:
+				eof = true
+			default:
+				return err
+			}
+		}
+		if filled == 0 && i > 0 {
+			return nil
+		}
+
+		if err := fn(i, string(buf[:filled])); err != nil {
+			return err
+		}
+		if size <= int64(chunkSize) || (eof && filled <= step) {
+			return nil
+		}
+
+		// Keep the overlap as the start of the next chunk
+		filled = copy(buf, buf[step:filled])
+	}
+}
+
+// forEachSectionChunk calls fn with the chunks of text made of sections
+// separated by processors.SectionBreak. Each section is chunked on its own
+// as forEachChunk does, so no chunk spans two sections; empty sections
+// yield no chunks. One section is held in memory at a time.
+func forEachSectionChunk(r io.Reader, chunkSize, overlap int, fn func(index int, chunk string) error) error {
+	br := bufio.NewReader(r)
+	index := 0
+	for {
+		section, err := br.ReadString(processors.SectionBreak)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		last := err == io.EOF
+		section = strings.TrimSpace(strings.TrimSuffix(section, string(processors.SectionBreak)))
+		if section != "" {
+			chunkErr := forEachChunk(strings.NewReader(section), int64(len(section)), chunkSize, overlap, func(_ int, chunk string) error {
+				index++
+				return fn(index-1, chunk)
+			})
+			if chunkErr != nil {
+				return chunkErr
+			}
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// chunkContent returns the number of chunks of extracted content and a
+// function calling fn with each of them in order. Content with section
+// breaks is chunked section by section, and read once more to count its
+// chunks.
+func chunkContent(content *processors.Content, chunkSize, overlap int) (int, func(fn func(index int, chunk string) error) error, error) {
+	if !content.Sectioned() {
+		return chunkCount(content.Size(), chunkSize, overlap), func(fn func(int, string) error) error {
+			return forEachChunk(content.Reader(), content.Size(), chunkSize, overlap, fn)
+		}, nil
+	}
+
+	total := 0
+	err := forEachSectionChunk(content.Reader(), chunkSize, overlap, func(int, string) error {
+		total++
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, func(fn func(int, string) error) error {
+		return forEachSectionChunk(content.Reader(), chunkSize, overlap, fn)
+	}, nil
+}
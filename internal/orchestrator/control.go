@@ -0,0 +1,201 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrRunNotActive is returned when cancelling a run that is not in progress
+	ErrRunNotActive = errors.New("run is not in progress")
+	// ErrRunNotResumable is returned when resuming a run that was not cancelled
+	ErrRunNotResumable = errors.New("only cancelled runs can be resumed")
+)
+
+// runControl pauses directory runs between files and stops them on
+// request. Its zero value has no runs and is not paused.
+type runControl struct {
+	mu      sync.Mutex
+	paused  bool
+	resumed chan struct{} // closed when paused indexing resumes
+	active  map[string]chan struct{}
+}
+
+// Pause makes directory runs and ingest queue workers wait before their
+// next file until Resume is called. The file being processed when pausing
+// is finished first.
+func (dp *DocumentProcessor) Pause() {
+	c := &dp.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		c.paused = true
+		c.resumed = make(chan struct{})
+		dp.logger.Info("Indexing paused")
+	}
+}
+
+// Resume lets paused directory runs continue
+func (dp *DocumentProcessor) Resume() {
+	c := &dp.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		c.paused = false
+		close(c.resumed)
+		dp.logger.Info("Indexing resumed")
+	}
+}
+
+// Paused reports whether indexing is paused
+func (dp *DocumentProcessor) Paused() bool {
+	dp.control.mu.Lock()
+	defer dp.control.mu.Unlock()
+	return dp.control.paused
+}
+
+// ActiveRuns returns the IDs of the directory runs in progress
+func (dp *DocumentProcessor) ActiveRuns() []string {
+	dp.control.mu.Lock()
+	ids := make([]string, 0, len(dp.control.active))
+	for id := range dp.control.active {
+		ids = append(ids, id)
+	}
+	dp.control.mu.Unlock()
+
+	sort.Strings(ids)
+	return ids
+}
+
+// CancelRun stops a directory run in progress once its current file is
+// done. The run is recorded as cancelled with the files it did not reach,
+// and can be resumed with ResumeRun.
+func (dp *DocumentProcessor) CancelRun(id string) error {
+	c := &dp.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stop, ok := c.active[id]
+	if !ok {
+		return ErrRunNotActive
+	}
+	select {
+	case <-stop:
+	default:
+		close(stop)
+		dp.logger.Info("Cancelling run", zap.String("run_id", id))
+	}
+	return nil
+}
+
+// ResumeRun continues a cancelled run with the files it did not reach. The
+// run keeps its ID, and its counts and changes include those from before
+// it was cancelled. It returns ErrDirectoryLocked when another replica is
+// running the run's directory, or the run itself for a batch.
+func (dp *DocumentProcessor) ResumeRun(ctx context.Context, id string) (*DirectoryResult, error) {
+	if dp.runStore == nil {
+		return nil, fmt.Errorf("no run store: %w", runs.ErrNotFound)
+	}
+	run, err := dp.runStore.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != runs.StatusCancelled {
+		return nil, ErrRunNotResumable
+	}
+
+	lock, err := dp.lockRun(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release(context.WithoutCancel(ctx))
+
+	// Another replica may have resumed the run before the lock was taken
+	run, err = dp.runStore.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != runs.StatusCancelled {
+		return nil, ErrRunNotResumable
+	}
+
+	dp.logger.Info("Resuming run",
+		zap.String("run_id", run.ID),
+		zap.String("directory", run.Directory),
+		zap.Int("remaining", len(run.Remaining)))
+
+	files := run.Remaining
+	run.Status, run.Remaining, run.FinishedAt = runs.StatusRunning, nil, time.Time{}
+	result := &DirectoryResult{
+		RunID:     run.ID,
+		Directory: run.Directory,
+		Total:     run.Total,
+		Processed: run.Processed,
+		Skipped:   run.Skipped,
+		Failed:    run.Failed,
+	}
+	return dp.processRun(ctx, run, files, result, lock)
+}
+
+// startRun registers a run as in progress and returns the channel closed
+// when it is cancelled
+func (dp *DocumentProcessor) startRun(id string) (<-chan struct{}, error) {
+	c := &dp.control
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.active[id]; ok {
+		return nil, fmt.Errorf("run %s is already in progress", id)
+	}
+	if c.active == nil {
+		c.active = make(map[string]chan struct{})
+	}
+	stop := make(chan struct{})
+	c.active[id] = stop
+	return stop, nil
+}
+
+// finishRun unregisters a run
+func (dp *DocumentProcessor) finishRun(id string) {
+	dp.control.mu.Lock()
+	delete(dp.control.active, id)
+	dp.control.mu.Unlock()
+}
+
+// proceed waits while indexing is paused and reports whether the run may
+// process its next file, false once it is cancelled or ctx is done
+func (dp *DocumentProcessor) proceed(ctx context.Context, stop <-chan struct{}) bool {
+	for {
+		dp.control.mu.Lock()
+		paused, resumed := dp.control.paused, dp.control.resumed
+		dp.control.mu.Unlock()
+
+		select {
+		case <-stop:
+			return false
+		case <-ctx.Done():
+			return false
+		default:
+		}
+		if !paused {
+			return true
+		}
+
+		select {
+		case <-resumed:
+		case <-stop:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
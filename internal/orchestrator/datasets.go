@@ -0,0 +1,39 @@
+package orchestrator
+
+import (
+	"github.com/nadeeshame/rag-knowledge-service/internal/datafile"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+// maxDatasetNames bounds the table and column names stored in the metadata
+// of each vector, which wide datasets would otherwise overflow
+const maxDatasetNames = 100
+
+// describeDataset records the tables and columns of a SQLite database or
+// Parquet file. A failed read is logged and leaves the record without them.
+func (dp *DocumentProcessor) describeDataset(record *registry.Record) {
+	info, err := datafile.Read(record.FilePath, 0)
+	if err != nil {
+		dp.logger.Warn("Failed to read data file schema", zap.String("file", record.FilePath), zap.Error(err))
+		return
+	}
+	record.Dataset = info
+}
+
+// setDatasetMetadata stores the format and the table and column names of
+// a data file in vector metadata, so searches can be filtered to datasets
+// with a given table or column
+func setDatasetMetadata(metadata map[string]interface{}, dataset *datafile.Info) {
+	if dataset == nil {
+		return
+	}
+	tables := make([]string, 0, len(dataset.Tables))
+	for _, table := range dataset.Tables {
+		tables = append(tables, table.Name)
+	}
+	columns := dataset.ColumnNames()
+	metadata["data_format"] = dataset.Format
+	metadata["data_tables"] = tables[:min(len(tables), maxDatasetNames)]
+	metadata["data_columns"] = columns[:min(len(columns), maxDatasetNames)]
+}
@@ -0,0 +1,120 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/dlq"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/malware"
+	"go.uber.org/zap"
+)
+
+// SourceQueue is the dead-letter source of files from the ingest queue;
+// files from directory runs name their run
+const SourceQueue = "queue"
+
+// StageError is a processing failure of a tracked document, with the last
+// state the document reached before failing
+type StageError struct {
+	DocumentID string
+	Stage      models.ProcessingState
+	Err        error
+}
+
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// SetDeadLetters makes the processor retry failed files and record those
+// that still fail in the dead-letter list
+func (dp *DocumentProcessor) SetDeadLetters(store dlq.Store) {
+	dp.deadLetters = store
+}
+
+// skippable reports whether a file was left out rather than failed: it is
+// already indexed, above the extraction size limit, stopped by an
+// extraction time or memory limit, flagged as malware or skipped by a
+// pipeline hook
+func skippable(err error) bool {
+	var tooLarge *processors.FileTooLargeError
+	var limited *processors.LimitExceededError
+	var infected *malware.InfectedError
+	return errors.As(err, &tooLarge) || errors.As(err, &limited) || errors.As(err, &infected) ||
+		errors.Is(err, ErrSkipDocument) || strings.Contains(err.Error(), "already indexed")
+}
+
+// processWithRetry processes a file, retrying failures with a doubling
+// backoff when a dead-letter list is set. A file that fails every attempt
+// is added to the list; source names what submitted it. Skipped files are
+// not retried.
+func (dp *DocumentProcessor) processWithRetry(ctx context.Context, filePath string, force bool, source string) error {
+	err := dp.processFile(ctx, filePath, force)
+	if err == nil || dp.deadLetters == nil || skippable(err) {
+		return err
+	}
+
+	attempts := 1
+	backoff := dp.config.DLQ.RetryBackoff
+	for ; attempts <= dp.config.DLQ.MaxRetries; attempts++ {
+		dp.logger.Warn("Retrying failed file",
+			zap.String("file", filePath),
+			zap.Int("attempt", attempts+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+
+		if err = dp.processFile(ctx, filePath, force); err == nil || skippable(err) {
+			return err
+		}
+	}
+
+	dp.deadLetter(ctx, filePath, force, source, attempts, err)
+	return err
+}
+
+// deadLetter adds a file that failed every attempt to the dead-letter list
+func (dp *DocumentProcessor) deadLetter(ctx context.Context, filePath string, force bool, source string, attempts int, err error) {
+	entry := &dlq.Entry{
+		ID:       dlq.EntryID(filePath),
+		FilePath: filePath,
+		Errors:   dlq.Chain(err),
+		Attempts: attempts,
+		Force:    force,
+		Source:   source,
+		FailedAt: time.Now(),
+	}
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		entry.DocumentID, entry.Stage = stageErr.DocumentID, string(stageErr.Stage)
+		entry.Errors = dlq.Chain(stageErr.Err)
+	}
+
+	if addErr := dp.deadLetters.Add(context.WithoutCancel(ctx), entry); addErr != nil {
+		dp.logger.Error("Failed to add file to dead-letter list",
+			zap.String("file", filePath),
+			zap.Error(addErr))
+		return
+	}
+	dp.logger.Error("File failed every attempt, added to dead-letter list",
+		zap.String("file", filePath),
+		zap.String("entry_id", entry.ID),
+		zap.String("stage", entry.Stage),
+		zap.Int("attempts", attempts),
+		zap.Error(err))
+}
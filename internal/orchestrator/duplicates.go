@@ -0,0 +1,264 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultDuplicateThreshold is the summary similarity at or above which
+	// two documents are reported as near duplicates
+	DefaultDuplicateThreshold = 0.95
+	// maxNearDuplicateDocuments caps the documents compared pairwise by
+	// their summary vectors
+	maxNearDuplicateDocuments = 5000
+	// summaryFetchBatch is the number of summary vectors fetched per request
+	summaryFetchBatch = 100
+)
+
+// DuplicateReport lists indexed documents at different paths that hold the
+// same or nearly the same content
+type DuplicateReport struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Documents   int              `json:"documents"` // indexed documents checked
+	Threshold   float64          `json:"threshold"`
+	Exact       []DuplicateGroup `json:"exact"` // identical file hashes
+	Near        []DuplicateGroup `json:"near"`  // summary embeddings at least the threshold alike
+	// Unembedded counts documents not compared by summary, having no
+	// summary vector or being past the comparison limit
+	Unembedded int `json:"unembedded"`
+	// Redundant counts the documents that could be removed, all but one of
+	// each group
+	Redundant int `json:"redundant"`
+}
+
+// DuplicateGroup is a set of documents holding the same content, oldest
+// first, as the copy most likely to be the original
+type DuplicateGroup struct {
+	FileHash string `json:"file_hash,omitempty"` // of an exact group
+	// Similarity is the lowest summary similarity of a document of a near
+	// group to the first
+	Similarity float32             `json:"similarity,omitempty"`
+	Documents  []DuplicateDocument `json:"documents"`
+}
+
+// DuplicateDocument is a document of a duplicate group
+type DuplicateDocument struct {
+	DocumentID string     `json:"document_id"`
+	FilePath   string     `json:"file_path"`
+	FileHash   string     `json:"file_hash,omitempty"`
+	IndexedAt  *time.Time `json:"indexed_at,omitempty"`
+	Similarity float32    `json:"similarity,omitempty"` // summary similarity to the first document of a near group
+}
+
+// Duplicates reports the indexed documents whose files have identical
+// hashes, and those whose summary embeddings are at least threshold alike
+// by cosine similarity. Documents sharing a hash are compared by summary
+// once, so a near group does not repeat an exact one.
+func (dp *DocumentProcessor) Duplicates(ctx context.Context, threshold float64) (*DuplicateReport, error) {
+	if dp.registry == nil {
+		return nil, errors.New("document registry is not configured")
+	}
+	records, err := dp.registry.List(ctx, registry.Filter{State: models.StateIndexed})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return olderRecord(records[i], records[j]) })
+
+	report := &DuplicateReport{
+		GeneratedAt: time.Now(),
+		Documents:   len(records),
+		Threshold:   threshold,
+		Exact:       []DuplicateGroup{},
+		Near:        []DuplicateGroup{},
+	}
+
+	// Records are oldest first, so the first of each hash is the original
+	byHash := make(map[string][]*registry.Record)
+	var distinct []*registry.Record
+	for _, r := range records {
+		if r.FileHash == "" {
+			distinct = append(distinct, r)
+			continue
+		}
+		if len(byHash[r.FileHash]) == 0 {
+			distinct = append(distinct, r)
+		}
+		byHash[r.FileHash] = append(byHash[r.FileHash], r)
+	}
+	for hash, group := range byHash {
+		if len(group) < 2 {
+			continue
+		}
+		docs := make([]DuplicateDocument, 0, len(group))
+		for _, r := range group {
+			docs = append(docs, duplicateDocument(r))
+		}
+		report.Exact = append(report.Exact, DuplicateGroup{FileHash: hash, Documents: docs})
+	}
+
+	near, unembedded, err := dp.nearDuplicates(ctx, distinct, threshold)
+	if err != nil {
+		return nil, err
+	}
+	report.Near = near
+	report.Unembedded = unembedded
+
+	for _, groups := range [][]DuplicateGroup{report.Exact, report.Near} {
+		sort.SliceStable(groups, func(i, j int) bool {
+			if len(groups[i].Documents) != len(groups[j].Documents) {
+				return len(groups[i].Documents) > len(groups[j].Documents)
+			}
+			return groups[i].Documents[0].FilePath < groups[j].Documents[0].FilePath
+		})
+		for _, g := range groups {
+			report.Redundant += len(g.Documents) - 1
+		}
+	}
+
+	dp.logger.Info("Generated duplicate report",
+		zap.Int("documents", report.Documents),
+		zap.Int("exact_groups", len(report.Exact)),
+		zap.Int("near_groups", len(report.Near)),
+		zap.Int("redundant", report.Redundant))
+	return report, nil
+}
+
+// nearDuplicates groups the documents whose summary vectors are at least
+// threshold alike, joining pairs into groups transitively. It returns the
+// groups and the number of documents not compared.
+func (dp *DocumentProcessor) nearDuplicates(ctx context.Context, records []*registry.Record, threshold float64) ([]DuplicateGroup, int, error) {
+	groups := []DuplicateGroup{}
+	ns := dp.pineconeClient.SummaryNamespace()
+	if ns == "" {
+		return groups, len(records), nil
+	}
+	unembedded := 0
+	if len(records) > maxNearDuplicateDocuments {
+		unembedded = len(records) - maxNearDuplicateDocuments
+		records = records[:maxNearDuplicateDocuments]
+	}
+
+	var embedded []*registry.Record
+	var vectors [][]float32
+	for start := 0; start < len(records); start += summaryFetchBatch {
+		batch := records[start:min(start+summaryFetchBatch, len(records))]
+		ids := make([]string, 0, len(batch))
+		for _, r := range batch {
+			ids = append(ids, models.SummaryVectorID(r.ID))
+		}
+		fetched, err := dp.pineconeClient.FetchVectorsInNamespace(ctx, ns, ids)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to fetch summary vectors: %w", err)
+		}
+		for _, r := range batch {
+			v, ok := fetched[models.SummaryVectorID(r.ID)]
+			if !ok || v == nil || len(v.Values) == 0 {
+				unembedded++
+				continue
+			}
+			embedded = append(embedded, r)
+			vectors = append(vectors, unitVector(v.Values))
+		}
+	}
+
+	// Union-find over the pairs at or above the threshold; the root of a
+	// group is its oldest document, as records are oldest first
+	parent := make([]int, len(embedded))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range vectors {
+		for j := i + 1; j < len(vectors); j++ {
+			if len(vectors[i]) != len(vectors[j]) || float64(cosine(vectors[i], vectors[j])) < threshold {
+				continue
+			}
+			a, b := find(i), find(j)
+			if a != b {
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+
+	members := make(map[int][]int)
+	for i := range embedded {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+	for root, group := range members {
+		if len(group) < 2 {
+			continue
+		}
+		g := DuplicateGroup{Similarity: 1, Documents: make([]DuplicateDocument, 0, len(group))}
+		for _, i := range group {
+			doc := duplicateDocument(embedded[i])
+			doc.Similarity = 1
+			if i != root {
+				doc.Similarity = cosine(vectors[root], vectors[i])
+			}
+			g.Similarity = min(g.Similarity, doc.Similarity)
+			g.Documents = append(g.Documents, doc)
+		}
+		groups = append(groups, g)
+	}
+	return groups, unembedded, nil
+}
+
+// duplicateDocument describes a document of a duplicate group
+func duplicateDocument(r *registry.Record) DuplicateDocument {
+	return DuplicateDocument{
+		DocumentID: r.ID,
+		FilePath:   r.FilePath,
+		FileHash:   r.FileHash,
+		IndexedAt:  r.IndexedAt,
+	}
+}
+
+// olderRecord orders records by when they were first added, then by path
+func olderRecord(a, b *registry.Record) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.FilePath < b.FilePath
+}
+
+// unitVector returns a copy of v scaled to unit length
+func unitVector(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	unit := make([]float32, len(v))
+	if norm == 0 {
+		return unit
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i, x := range v {
+		unit[i] = x * scale
+	}
+	return unit
+}
+
+// cosine returns the cosine similarity of two unit vectors
+func cosine(a, b []float32) float32 {
+	var sum float32
+	for i := range min(len(a), len(b)) {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
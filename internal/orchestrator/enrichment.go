@@ -0,0 +1,254 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/google"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+// Optional stages that are skipped, and the document flagged, while their
+// dependency is failing
+const (
+	StageVision  = "vision"
+	StageSummary = "summary"
+)
+
+// ErrRepairRunning is returned when a repair is started while one is running
+var ErrRepairRunning = errors.New("enrichment repair is already running")
+
+// breaker skips a stage after consecutive failures. Once the cooldown has
+// passed one call is let through as a trial: success closes the breaker,
+// failure keeps it open for another cooldown.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: max(1, threshold), cooldown: cooldown}
+}
+
+// allow reports whether the stage may run, claiming the trial call when
+// the cooldown has passed
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if now := time.Now(); !now.Before(b.openUntil) {
+		b.openUntil = now.Add(b.cooldown)
+		return true
+	}
+	return false
+}
+
+// ready reports whether the stage may run, without claiming the trial call
+func (b *breaker) ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures < b.threshold || !time.Now().Before(b.openUntil)
+}
+
+// open reports whether the stage is being skipped
+func (b *breaker) open() bool {
+	return !b.ready()
+}
+
+// record counts the outcome of a call
+func (b *breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures == b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// DegradedStages returns the optional stages currently skipped because
+// their dependency keeps failing
+func (dp *DocumentProcessor) DegradedStages() []string {
+	stages := []string{}
+	if dp.visionBreaker.open() {
+		stages = append(stages, StageVision)
+	}
+	if dp.summaryBreaker.open() {
+		stages = append(stages, StageSummary)
+	}
+	return stages
+}
+
+// analyzeImage describes an image with the vision client. While vision is
+// failing the stage is skipped and the record flagged for repair.
+func (dp *DocumentProcessor) analyzeImage(ctx context.Context, record *registry.Record) string {
+	if !dp.visionBreaker.allow() {
+		skipStage(record, StageVision)
+		return ""
+	}
+
+	visualContent, err := dp.visionClient.AnalyzeImage(ctx, record.FilePath)
+	if errors.Is(err, google.ErrImageTooLarge) {
+		// A property of the file rather than of the dependency
+		dp.logger.Warn("Failed to analyze image", zap.Error(err))
+		return ""
+	}
+	dp.visionBreaker.record(err)
+	if err != nil {
+		dp.logger.Warn("Failed to analyze image, flagging for enrichment",
+			zap.String("file", record.FilePath),
+			zap.Error(err))
+		skipStage(record, StageVision)
+		return ""
+	}
+	dp.track(ctx, record, models.StateAnalyzed)
+	return visualContent
+}
+
+// skipStage flags a record as missing an optional stage
+func skipStage(record *registry.Record, stage string) {
+	if !slices.Contains(record.NeedsEnrichment, stage) {
+		record.NeedsEnrichment = append(record.NeedsEnrichment, stage)
+	}
+}
+
+// completeStage clears a record's flag for an optional stage
+func completeStage(record *registry.Record, stage string) {
+	record.NeedsEnrichment = slices.DeleteFunc(record.NeedsEnrichment, func(s string) bool { return s == stage })
+	if len(record.NeedsEnrichment) == 0 {
+		record.NeedsEnrichment = nil
+	}
+}
+
+// RepairResult summarizes an enrichment repair
+type RepairResult struct {
+	Repaired int `json:"repaired"`
+	Failed   int `json:"failed"`
+	Waiting  int `json:"waiting"` // documents whose stages are still failing
+}
+
+// RepairEnrichment runs the skipped stages again for indexed documents
+// flagged as needing enrichment, once the stages' dependencies are no
+// longer failing. A document missing only its summary is resummarized from
+// its stored content; otherwise its file is processed again. The repair
+// waits while indexing is paused.
+func (dp *DocumentProcessor) RepairEnrichment(ctx context.Context) (*RepairResult, error) {
+	if dp.registry == nil {
+		return nil, errors.New("document registry is not configured")
+	}
+	if !dp.repairMu.TryLock() {
+		return nil, ErrRepairRunning
+	}
+	defer dp.repairMu.Unlock()
+
+	records, err := dp.registry.List(ctx, registry.Filter{State: models.StateIndexed, NeedsEnrichment: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents needing enrichment: %w", err)
+	}
+
+	result := &RepairResult{}
+	for _, record := range records {
+		if !dp.proceed(ctx, nil) {
+			break
+		}
+		if !dp.stagesReady(record.NeedsEnrichment) {
+			result.Waiting++
+			continue
+		}
+		if err := dp.repair(ctx, record); err != nil {
+			result.Failed++
+			dp.logger.Warn("Failed to repair document enrichment",
+				zap.String("document_id", record.ID),
+				zap.Strings("stages", record.NeedsEnrichment),
+				zap.Error(err))
+			continue
+		}
+		result.Repaired++
+	}
+	return result, nil
+}
+
+// RunRepair repairs enrichment every interval until ctx is done
+func (dp *DocumentProcessor) RunRepair(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := dp.RepairEnrichment(ctx)
+		if err != nil {
+			if !errors.Is(err, ErrRepairRunning) {
+				dp.logger.Error("Failed to repair enrichment", zap.Error(err))
+			}
+			continue
+		}
+		if result.Repaired > 0 || result.Failed > 0 {
+			dp.logger.Info("Repaired document enrichment",
+				zap.Int("repaired", result.Repaired),
+				zap.Int("failed", result.Failed),
+				zap.Int("waiting", result.Waiting))
+		}
+	}
+}
+
+// stagesReady reports whether the dependencies of all the stages can be
+// tried again
+func (dp *DocumentProcessor) stagesReady(stages []string) bool {
+	for _, stage := range stages {
+		switch stage {
+		case StageVision:
+			if dp.visionClient == nil || !dp.visionBreaker.ready() {
+				return false
+			}
+		case StageSummary:
+			if !dp.summaryBreaker.ready() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// repair runs a document's skipped stages again
+func (dp *DocumentProcessor) repair(ctx context.Context, record *registry.Record) error {
+	if slices.Equal(record.NeedsEnrichment, []string{StageSummary}) {
+		_, err := dp.Resummarize(ctx, record.ID)
+		if !errors.Is(err, ErrContentNotStored) {
+			return err
+		}
+	}
+
+	// Image analysis is part of the content, so the file is processed again
+	if err := dp.processFile(ctx, record.FilePath, true); err != nil {
+		return err
+	}
+	current, err := dp.registry.GetByPath(ctx, record.FilePath)
+	if err != nil {
+		return err
+	}
+	if len(current.NeedsEnrichment) > 0 {
+		return fmt.Errorf("stages still skipped: %v", current.NeedsEnrichment)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/events"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+)
+
+// SetEvents makes the processor publish document state changes and run
+// progress to the event bus
+func (dp *DocumentProcessor) SetEvents(bus *events.Bus) {
+	dp.events = bus
+}
+
+// publishState publishes a document's move from one state to another
+func (dp *DocumentProcessor) publishState(ctx context.Context, record *registry.Record, previous models.ProcessingState) {
+	if !dp.events.Enabled() {
+		return
+	}
+	change := events.DocumentStateChanged{
+		DocumentID:    record.ID,
+		FilePath:      record.FilePath,
+		State:         string(record.State),
+		PreviousState: string(previous),
+		Error:         record.Error,
+	}
+	if t := traceFrom(ctx); t != nil {
+		change.RunID = t.runID
+	}
+	dp.events.Publish(ctx, events.TypeDocumentState, change)
+}
+
+// publishProgress publishes the progress of a run; file is the file just
+// finished, if any
+func (dp *DocumentProcessor) publishProgress(ctx context.Context, run *runs.Run, result *DirectoryResult, file string) {
+	if !dp.events.Enabled() {
+		return
+	}
+	dp.events.Publish(ctx, events.TypeRunProgress, events.RunProgress{
+		RunID:     run.ID,
+		Directory: result.Directory,
+		Status:    run.Status,
+		Total:     result.Total,
+		Done:      result.Processed + result.Skipped + result.Failed,
+		Processed: result.Processed,
+		Skipped:   result.Skipped,
+		Failed:    result.Failed,
+		File:      file,
+	})
+}
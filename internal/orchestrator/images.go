@@ -0,0 +1,123 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/contentstore"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/imagemeta"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/scanner"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// describeImage records an image's dimensions and format and, when the
+// content store keeps thumbnails, stores its thumbnail unless one is
+// already stored for the file hash. Failures are logged and leave the
+// record without the information.
+func (dp *DocumentProcessor) describeImage(ctx context.Context, record *registry.Record) {
+	file, err := os.Open(record.FilePath)
+	if err != nil {
+		dp.logger.Warn("Failed to open image", zap.String("file", record.FilePath), zap.Error(err))
+		return
+	}
+	defer file.Close() //nolint:errcheck
+
+	info, err := imagemeta.Read(file, utils.Ext(record.FilePath))
+	if err != nil {
+		dp.logger.Debug("Failed to read image dimensions", zap.String("file", record.FilePath), zap.Error(err))
+		return
+	}
+	record.Image = &info
+
+	size := dp.config.ContentStore.ThumbnailSize
+	if dp.contentStore == nil || size == 0 || info.Format == "svg" {
+		return
+	}
+	_, err = dp.contentStore.GetThumbnail(ctx, record.FileHash)
+	if err == nil {
+		info.Thumbnail = true
+		return
+	}
+	if !errors.Is(err, contentstore.ErrNotFound) {
+		dp.logger.Warn("Failed to check for a stored thumbnail", zap.String("file", record.FilePath), zap.Error(err))
+		return
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		dp.logger.Warn("Failed to rewind image", zap.String("file", record.FilePath), zap.Error(err))
+		return
+	}
+	thumbnail, err := imagemeta.Thumbnail(file, size)
+	if err != nil {
+		dp.logger.Warn("Failed to create thumbnail", zap.String("file", record.FilePath), zap.Error(err))
+		return
+	}
+	if err := dp.contentStore.PutThumbnail(ctx, record.FileHash, thumbnail); err != nil {
+		dp.logger.Warn("Failed to store thumbnail", zap.String("file", record.FilePath), zap.Error(err))
+		return
+	}
+	info.Thumbnail = true
+}
+
+// indexImage embeds an image document with the multimodal model and
+// supersedes the image vectors of earlier versions of its file. The vector
+// carries the same filterable metadata as the document's chunks, and the
+// summary as its content. Failures are logged; the document stays
+// searchable by its text.
+func (dp *DocumentProcessor) indexImage(ctx context.Context, record *registry.Record, detected scanner.Detection, acl models.ACL) {
+	content := record.FileName
+	if record.Summary != "" && record.Summary != summaryFailed {
+		content = record.Summary
+	}
+	metadata := map[string]interface{}{
+		"document_id":    record.ID,
+		"file_name":      record.FileName,
+		"file_path":      record.FilePath,
+		"file_type":      record.FileType,
+		"file_hash":      record.FileHash,
+		"hash_algorithm": utils.HashAlgorithm(record.FileHash),
+		"content":        content,
+		"content_type":   detected.MimeType,
+		"indexed_at":     time.Now().Unix(),
+	}
+	setACLMetadata(metadata, acl)
+	setModifiedMetadata(metadata, record)
+	setImageMetadata(metadata, record.Image)
+	setCustomMetadata(metadata, record.Metadata)
+	if err := pinecone.FitMetadata(metadata, dp.config.Pinecone.MetadataLimit); err != nil {
+		dp.logger.Warn("Failed to fit image vector metadata", zap.String("document_id", record.ID), zap.Error(err))
+		return
+	}
+
+	if err := dp.imageIndex.IndexFile(ctx, record.ID, record.FilePath, metadata); err != nil {
+		dp.logger.Warn("Failed to index image embedding",
+			zap.String("document_id", record.ID),
+			zap.String("file", record.FilePath),
+			zap.Error(err))
+		return
+	}
+	if err := dp.imageIndex.Supersede(ctx, record.FilePath, record.ID, time.Now()); err != nil {
+		dp.logger.Warn("Failed to supersede previous image vectors",
+			zap.String("file", record.FilePath),
+			zap.Error(err))
+	}
+}
+
+// setImageMetadata stores an image's dimensions, format and whether it has
+// a thumbnail in vector metadata, so search results can show previews
+func setImageMetadata(metadata map[string]interface{}, image *imagemeta.Info) {
+	if image == nil {
+		return
+	}
+	metadata["image_width"] = image.Width
+	metadata["image_height"] = image.Height
+	metadata["image_format"] = image.Format
+	metadata["thumbnail"] = image.Thumbnail
+}
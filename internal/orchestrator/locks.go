@@ -0,0 +1,115 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/locks"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ErrDirectoryLocked is returned when starting a run of a directory another
+// replica is indexing
+var ErrDirectoryLocked = errors.New("directory is being indexed by another replica")
+
+// SetLocker makes the processor lock each directory it runs, so that
+// replicas sharing the locker do not index the same directory at once
+func (dp *DocumentProcessor) SetLocker(locker *locks.Locker) {
+	dp.locker = locker
+}
+
+// lockDirectory takes the lock of a directory for a run
+func (dp *DocumentProcessor) lockDirectory(ctx context.Context, directory string) (*locks.Lock, error) {
+	return dp.lock(ctx, directoryLock(directory), zap.String("directory", directory))
+}
+
+// lockRun takes the lock of a run's directory, or of the run itself for a
+// batch, whose files another replica may be processing in another run
+func (dp *DocumentProcessor) lockRun(ctx context.Context, run *runs.Run) (*locks.Lock, error) {
+	if run.Batch {
+		return dp.lock(ctx, batchLock(run.ID), zap.String("run_id", run.ID))
+	}
+	return dp.lockDirectory(ctx, run.Directory)
+}
+
+// lock takes a named lock, failing with ErrDirectoryLocked and the holder
+// when another replica has it
+func (dp *DocumentProcessor) lock(ctx context.Context, name string, subject zap.Field) (*locks.Lock, error) {
+	lock, err := dp.locker.Acquire(ctx, name)
+	if errors.Is(err, locks.ErrHeld) {
+		holder, _ := dp.locker.Holder(ctx, name) //nolint:errcheck
+		dp.logger.Info("Run is locked by another replica", subject, zap.String("holder", holder))
+		if holder == "" {
+			return nil, ErrDirectoryLocked
+		}
+		return nil, fmt.Errorf("%w: %s", ErrDirectoryLocked, holder)
+	}
+	return lock, err
+}
+
+// cancelOnLoss cancels a run when the lock of its directory is lost, as
+// another replica may then start the directory. The returned function
+// stops watching.
+func (dp *DocumentProcessor) cancelOnLoss(lock *locks.Lock, runID string) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-lock.Lost():
+			dp.logger.Warn("Cancelling run whose directory lock was lost", zap.String("run_id", runID))
+			dp.CancelRun(runID) //nolint:errcheck
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// RunHolder returns the replica running a run's directory, or the run
+// itself for a batch, or an empty string when none is
+func (dp *DocumentProcessor) RunHolder(ctx context.Context, run *runs.Run) (string, error) {
+	if run.Batch {
+		return dp.locker.Holder(ctx, batchLock(run.ID))
+	}
+	return dp.locker.Holder(ctx, directoryLock(run.Directory))
+}
+
+// vectorLockRetry is how often a vector lock held by another replica is
+// tried again
+const vectorLockRetry = 50 * time.Millisecond
+
+// lockVector locks a vector for a read-modify-write of its metadata, among
+// this replica's workers and, with a locker, among replicas. The returned
+// function unlocks it.
+func (dp *DocumentProcessor) lockVector(ctx context.Context, vectorID string) (func(), error) {
+	unlock, err := dp.vectorLocks.Lock(ctx, vectorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock vector %s: %w", vectorID, err)
+	}
+	lock, err := dp.locker.AcquireWait(ctx, vectorLock(vectorID), vectorLockRetry)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return func() {
+		lock.Release(context.WithoutCancel(ctx))
+		unlock()
+	}, nil
+}
+
+// directoryLock names the lock of a directory
+func directoryLock(directory string) string {
+	return "directory:" + utils.NormalizePath(directory)
+}
+
+// batchLock names the lock of a batch run
+func batchLock(runID string) string {
+	return "batch:" + runID
+}
+
+// vectorLock names the lock of a vector
+func vectorLock(vectorID string) string {
+	return "vector:" + vectorID
+}
@@ -0,0 +1,46 @@
+package orchestrator
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/logtext"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+)
+
+// logReference reports whether a file is a log and returns the time it was
+// last written, which dates entries written without a year
+func logReference(filePath string) (time.Time, bool) {
+	if !strings.EqualFold(utils.Ext(filePath), ".log") {
+		return time.Time{}, false
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return time.Now(), true
+	}
+	return info.ModTime(), true
+}
+
+// setLogMetadata stores the time span, severity levels and error count of
+// the entries of a log chunk, so searches can be filtered to a time range
+// or to chunks with errors
+func setLogMetadata(metadata map[string]interface{}, text string, ref time.Time) {
+	summary := logtext.Summarize(text, ref)
+	if !summary.Start.IsZero() {
+		metadata["log_start"] = summary.Start.Unix()
+		metadata["log_end"] = summary.End.Unix()
+	}
+	var levels []string
+	for _, level := range logtext.Levels {
+		if summary.Levels[level] > 0 {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) > 0 {
+		metadata["log_levels"] = levels
+	}
+	if count := summary.Levels[logtext.LevelFatal] + summary.Levels[logtext.LevelError]; count > 0 {
+		metadata["log_errors"] = count
+	}
+}
@@ -0,0 +1,23 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/malware"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+)
+
+// scanForMalware scans a file when malware scanning is enabled. A flagged
+// file is recorded with the malware found and where it was quarantined.
+func (dp *DocumentProcessor) scanForMalware(ctx context.Context, record *registry.Record) error {
+	if dp.malwareGuard == nil {
+		return nil
+	}
+	err := dp.malwareGuard.Check(ctx, record.FilePath)
+	var infected *malware.InfectedError
+	if errors.As(err, &infected) {
+		record.Malware, record.Quarantine = infected.Signature, infected.Quarantine
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/mathtext"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+const (
+	// mathInputBytes is how much of a document's content is searched for
+	// formulas to describe
+	mathInputBytes = 1 << 20
+	// maxDescribedFormula is the longest formula sent for description;
+	// longer formulas are left as they are
+	maxDescribedFormula = 600
+)
+
+const formulaSystemPrompt = `You explain mathematical formulas in plain language for readers searching a document collection.
+You are given a numbered list of formulas in LaTeX, MathML or Unicode notation.
+For each formula, write one sentence saying what it states, naming the operations and relations and, where the notation makes it clear, what the symbols stand for.
+Answer with the same numbered list, one line per formula in the form "N. description", and nothing else.`
+
+// describeFormulas appends plain-language descriptions of the formulas of
+// the content, written by the chat deployment, so questions asked in words
+// find the chunks holding the formulas. The formulas themselves are kept
+// as they are. A failed description is logged and adds nothing.
+func (dp *DocumentProcessor) describeFormulas(ctx context.Context, record *registry.Record, content *processors.Content) {
+	text, err := content.Prefix(mathInputBytes)
+	if err != nil {
+		dp.logger.Warn("Failed to read content for formula descriptions",
+			zap.String("file", record.FilePath),
+			zap.Error(err))
+		return
+	}
+
+	var formulas []string
+	for _, f := range mathtext.Find(strings.ToValidUTF8(text, "")) {
+		source := strings.Join(strings.Fields(f.Source), " ")
+		if len(source) > maxDescribedFormula {
+			continue
+		}
+		formulas = append(formulas, source)
+		if len(formulas) == dp.config.Math.MaxFormulas {
+			break
+		}
+	}
+	if len(formulas) == 0 {
+		return
+	}
+
+	var prompt strings.Builder
+	for i, f := range formulas {
+		fmt.Fprintf(&prompt, "%d. %s\n", i+1, f)
+	}
+	answer, err := dp.azureClient.ChatCompletion(ctx, formulaSystemPrompt, prompt.String())
+	if err != nil {
+		dp.logger.Warn("Failed to describe formulas",
+			zap.String("file", record.FilePath),
+			zap.Error(err))
+		return
+	}
+
+	descriptions := parseNumberedList(answer, len(formulas))
+	var b strings.Builder
+	for i, f := range formulas {
+		if descriptions[i] != "" {
+			fmt.Fprintf(&b, "%s: %s\n", f, descriptions[i])
+		}
+	}
+	if b.Len() == 0 {
+		return
+	}
+	if _, err := io.WriteString(content, "\n\nFormula Descriptions:\n"+b.String()); err != nil {
+		dp.logger.Warn("Failed to append formula descriptions",
+			zap.String("file", record.FilePath),
+			zap.Error(err))
+		return
+	}
+	dp.logger.Debug("Described formulas",
+		zap.String("file", record.FilePath),
+		zap.Int("formulas", len(formulas)))
+}
+
+// parseNumberedList reads the "N. text" lines of a model answer into n
+// entries; entries the answer leaves out are empty
+func parseNumberedList(answer string, n int) []string {
+	entries := make([]string, n)
+	for _, line := range strings.Split(answer, "\n") {
+		number, text, ok := strings.Cut(strings.TrimSpace(line), ".")
+		if !ok {
+			continue
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(number))
+		if err != nil || i < 1 || i > n {
+			continue
+		}
+		entries[i-1] = strings.TrimSpace(text)
+	}
+	return entries
+}
+
+// setMathMetadata tags a chunk holding a formula, so searches can be
+// filtered to chunks with math
+func setMathMetadata(metadata map[string]interface{}, text string) {
+	if mathtext.Contains(text) {
+		metadata["has_math"] = true
+	}
+}
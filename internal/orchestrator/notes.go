@@ -0,0 +1,43 @@
+package orchestrator
+
+import (
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/notes"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+// noteInputBytes is how much of a Markdown note is read for its
+// frontmatter and wiki-links
+const noteInputBytes = 4 << 20
+
+// readNote records the title, tags and aliases of a Markdown note's
+// frontmatter and the targets of its wiki-links. A failed read is logged
+// and leaves the record without them.
+func (dp *DocumentProcessor) readNote(record *registry.Record, content *processors.Content) {
+	text, err := content.Prefix(noteInputBytes)
+	if err != nil {
+		dp.logger.Warn("Failed to read note", zap.String("file", record.FilePath), zap.Error(err))
+		return
+	}
+	note := notes.Parse(text)
+	record.Title, record.Tags, record.Aliases, record.Links = note.Title, note.Tags, note.Aliases, note.Links
+}
+
+// setNoteMetadata stores a note's title and tags in vector metadata, so
+// searches can be filtered by tag. Tags are stored in lower case, as tags
+// differing only in case are the same tag.
+func setNoteMetadata(metadata map[string]interface{}, record *registry.Record) {
+	if record.Title != "" {
+		metadata["title"] = record.Title
+	}
+	if len(record.Tags) > 0 {
+		tags := make([]string, len(record.Tags))
+		for i, tag := range record.Tags {
+			tags[i] = strings.ToLower(tag)
+		}
+		metadata["tags"] = tags
+	}
+}
@@ -0,0 +1,224 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/dedup"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/scanner"
+)
+
+// PipelineStage is a stage of the pipeline a file is processed by
+type PipelineStage string
+
+// Pipeline stages, in processing order
+const (
+	// PipelineScan hashes and detects the file and creates its record
+	PipelineScan PipelineStage = "scan"
+	// PipelineExtract extracts the file's content, or reuses stored content
+	PipelineExtract PipelineStage = "extract"
+	// PipelineEnrich reads note, image and dataset metadata, analyzes
+	// images and formulas and summarizes the content
+	PipelineEnrich PipelineStage = "enrich"
+	// PipelineChunk cuts the content into chunks and drops those already
+	// stored
+	PipelineChunk PipelineStage = "chunk"
+	// PipelineEmbed embeds the chunks as vectors, and stores those of
+	// streamed content
+	PipelineEmbed PipelineStage = "embed"
+	// PipelineStore upserts the vectors and supersedes earlier versions
+	PipelineStore PipelineStage = "store"
+)
+
+// PipelineStages lists the stages in processing order
+var PipelineStages = []PipelineStage{PipelineScan, PipelineExtract, PipelineEnrich, PipelineChunk, PipelineEmbed, PipelineStore}
+
+// ErrSkipDocument is returned, wrapped with the reason, by a hook that
+// leaves a file out of the index. The file is counted as skipped rather
+// than failed and is not retried.
+var ErrSkipDocument = errors.New("skipped by pipeline hook")
+
+// SkipDocument returns an error skipping the document for a reason
+func SkipDocument(reason string) error {
+	return fmt.Errorf("%w: %s", ErrSkipDocument, reason)
+}
+
+// Document is a file on its way through the pipeline. Each stage fills in
+// more of it: scan the hashes, detection and Record, extract the Content,
+// enrich the summary, chunk the Chunks and embed the Vectors. Hooks may
+// change what a stage left for the next, such as the Chunks, and add
+// vector metadata with SetMetadata. Content above STREAM_CHUNKING_BYTES is
+// streamed: its Chunks and Vectors stay empty, as the embed stage reads,
+// embeds and stores the chunks in batches.
+type Document struct {
+	FilePath    string
+	Force       bool                      // processed even when already indexed
+	Options     processors.ExtractOptions // requested; narrowed by extract to those the processor honours
+	FileHash    string
+	OtherHashes []string // hashes matching documents indexed with other algorithms
+	Detection   scanner.Detection
+	Record      *registry.Record
+	Content     *processors.Content
+	Summarized  bool // a summary was generated for the document
+	Chunks      []Chunk
+	Vectors     []*pinecone.Vector
+
+	metadata   map[string]interface{} // set before the record exists
+	reused     bool                   // content came from the content store
+	acl        models.ACL
+	chunkTotal int
+	overlap    int
+	newEntries map[string]*dedup.Entry
+	eachChunk  func(fn func(index int, chunk string) error) error // reads the chunks of streamed content
+}
+
+// Chunk is a chunk of a document's content waiting to be embedded
+type Chunk struct {
+	Index       int
+	Text        string
+	ContentHash string // scoped content hash used for deduplication
+
+	signature []uint64
+}
+
+// SetMetadata adds a field to the metadata of the document's vectors. It
+// is kept in the registry, so the document's vectors keep it when they
+// are rechunked, but never replaces metadata the pipeline sets itself.
+func (d *Document) SetMetadata(key string, value interface{}) {
+	metadata := &d.metadata
+	if d.Record != nil {
+		metadata = &d.Record.Metadata
+	}
+	if *metadata == nil {
+		*metadata = make(map[string]interface{})
+	}
+	(*metadata)[key] = value
+}
+
+// StageFunc runs a pipeline stage on a document
+type StageFunc func(ctx context.Context, doc *Document) error
+
+// Middleware wraps a pipeline stage. It may act on the document before and
+// after calling next, replace the stage by not calling it, or stop the
+// processing of the file by returning an error, such as one made by
+// SkipDocument.
+type Middleware func(stage PipelineStage, next StageFunc) StageFunc
+
+// Before returns middleware running fn before a stage
+func Before(stage PipelineStage, fn StageFunc) Middleware {
+	return func(s PipelineStage, next StageFunc) StageFunc {
+		if s != stage {
+			return next
+		}
+		return func(ctx context.Context, doc *Document) error {
+			if err := fn(ctx, doc); err != nil {
+				return err
+			}
+			return next(ctx, doc)
+		}
+	}
+}
+
+// After returns middleware running fn after a stage succeeds; a custom
+// stage is added to the pipeline by running it after the stage it follows
+func After(stage PipelineStage, fn StageFunc) Middleware {
+	return func(s PipelineStage, next StageFunc) StageFunc {
+		if s != stage {
+			return next
+		}
+		return func(ctx context.Context, doc *Document) error {
+			if err := next(ctx, doc); err != nil {
+				return err
+			}
+			return fn(ctx, doc)
+		}
+	}
+}
+
+// pipeline runs stages through the registered middleware
+type pipeline struct {
+	mu         sync.RWMutex
+	middleware []Middleware
+}
+
+// use registers middleware; middleware registered first runs outermost
+func (p *pipeline) use(middleware ...Middleware) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.middleware = append(p.middleware, middleware...)
+}
+
+// run runs a stage on a document through the middleware
+func (p *pipeline) run(ctx context.Context, stage PipelineStage, doc *Document, fn StageFunc) error {
+	p.mu.RLock()
+	middleware := p.middleware
+	p.mu.RUnlock()
+	for i := len(middleware) - 1; i >= 0; i-- {
+		fn = middleware[i](stage, fn)
+	}
+	return fn(ctx, doc)
+}
+
+// Use registers pipeline middleware, run around every stage of every file
+// processed from then on, in the order registered
+func (dp *DocumentProcessor) Use(middleware ...Middleware) {
+	dp.pipeline.use(middleware...)
+}
+
+// setCustomMetadata adds the metadata hooks set on a document to a vector's
+// metadata, leaving the fields the pipeline set alone
+func setCustomMetadata(metadata, custom map[string]interface{}) {
+	for key, value := range custom {
+		if _, set := metadata[key]; !set {
+			metadata[key] = value
+		}
+	}
+}
+
+// errDocumentDone ends the processing of a file whose outcome a stage has
+// already recorded, such as a file without content. It is not a failure.
+var errDocumentDone = errors.New("document processing done")
+
+// pipelineStep is a stage and the function running it
+type pipelineStep struct {
+	stage PipelineStage
+	run   StageFunc
+}
+
+// fileSteps are the stages processing a file
+func (dp *DocumentProcessor) fileSteps() []pipelineStep {
+	return append([]pipelineStep{
+		{PipelineScan, dp.scanStage},
+		{PipelineExtract, dp.extractStage},
+		{PipelineEnrich, dp.enrichStage},
+	}, dp.indexSteps()...)
+}
+
+// indexSteps are the stages indexing a document's content
+func (dp *DocumentProcessor) indexSteps() []pipelineStep {
+	return []pipelineStep{
+		{PipelineChunk, dp.chunkStage},
+		{PipelineEmbed, dp.embedStage},
+		{PipelineStore, dp.storeStage},
+	}
+}
+
+// runStages runs stages on a document in order, until one fails or ends
+// the processing with errDocumentDone, which is not returned
+func (dp *DocumentProcessor) runStages(ctx context.Context, doc *Document, steps []pipelineStep) error {
+	for _, step := range steps {
+		if err := dp.pipeline.run(ctx, step.stage, doc, step.run); err != nil {
+			if errors.Is(err, errDocumentDone) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// filePolicy is how a file is processed, resolved from the processing
+// policies of its category and extension
+type filePolicy struct {
+	summarize    bool
+	vision       bool
+	ocr          bool
+	chunkSize    int
+	chunkOverlap int
+}
+
+// policyFor resolves the processing policy of a record's file. Files are
+// summarized and analyzed but not OCRed, and chunked with the App chunk
+// settings, unless a policy says otherwise.
+func (dp *DocumentProcessor) policyFor(record *registry.Record) filePolicy {
+	policy := dp.config.Policy(record.Category, utils.Ext(record.FilePath))
+	resolved := filePolicy{
+		summarize:    true,
+		vision:       true,
+		chunkSize:    dp.config.App.ChunkSize,
+		chunkOverlap: dp.config.App.ChunkOverlap,
+	}
+	if policy.Summarize != nil {
+		resolved.summarize = *policy.Summarize
+	}
+	if policy.Vision != nil {
+		resolved.vision = *policy.Vision
+	}
+	if policy.OCR != nil {
+		resolved.ocr = *policy.OCR
+	}
+	if policy.ChunkSize > 0 {
+		resolved.chunkSize = policy.ChunkSize
+	}
+	if policy.ChunkOverlap != nil {
+		resolved.chunkOverlap = *policy.ChunkOverlap
+	}
+	// A category's overlap may not fit an extension's smaller chunks
+	resolved.chunkOverlap = min(resolved.chunkOverlap, resolved.chunkSize-1)
+	return resolved
+}
+
+// detectText returns the text detected in an image for policies that OCR
+// images. A failure is logged and leaves the content without the text.
+func (dp *DocumentProcessor) detectText(ctx context.Context, record *registry.Record) string {
+	text, err := dp.visionClient.DetectText(ctx, record.FilePath)
+	if err != nil {
+		dp.logger.Warn("Failed to detect text in image",
+			zap.String("file", record.FilePath),
+			zap.Error(err))
+		return ""
+	}
+	return text
+}
@@ -0,0 +1,312 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"go.uber.org/zap"
+)
+
+// Priority orders ingestion jobs; higher priorities are processed first
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"   // interactive uploads
+	PriorityNormal Priority = "normal" // default
+	PriorityLow    Priority = "low"    // bulk backfills
+)
+
+// priorities lists the priorities from highest to lowest
+var priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// ParsePriority returns the priority with the given name; empty is normal
+func ParsePriority(name string) (Priority, error) {
+	if name == "" {
+		return PriorityNormal, nil
+	}
+	for _, p := range priorities {
+		if string(p) == name {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid priority %q: use high, normal or low", name)
+}
+
+// ErrQueueFull is returned when a submission does not fit in the queue
+var ErrQueueFull = errors.New("ingest queue is full")
+
+// Job is a file waiting to be processed
+type Job struct {
+	ID         string    `json:"id"`
+	FilePath   string    `json:"file_path"`
+	Force      bool      `json:"force"`
+	Priority   Priority  `json:"priority"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	// Options are the extraction options the file is extracted with
+	Options processors.ExtractOptions `json:"options"`
+	// Deliveries counts the times the job was claimed, more than once when
+	// a replica stopped while processing it
+	Deliveries int `json:"deliveries,omitempty"`
+}
+
+// QueueStats describes the ingest queue. Depth counts the jobs waiting at
+// each priority; Wait is the age of the oldest waiting job at each. The
+// counters are those of this replica's workers.
+type QueueStats struct {
+	Depth     map[Priority]int    `json:"depth"`
+	Wait      map[Priority]string `json:"oldest_wait,omitempty"`
+	Capacity  int                 `json:"capacity"`
+	Workers   int                 `json:"workers"`
+	InFlight  int64               `json:"in_flight"`
+	Completed int64               `json:"completed"`
+	Skipped   int64               `json:"skipped"`
+	Failed    int64               `json:"failed"`
+	// Shared is set when the replicas share the queue, and Claimed counts
+	// the files being processed by any of them
+	Shared  bool `json:"shared,omitempty"`
+	Claimed int  `json:"claimed,omitempty"`
+}
+
+// IngestQueue processes submitted files with a pool of workers, taking
+// higher priority jobs first and jobs of the same priority in order. The
+// workers wait while indexing is paused. With a shared backlog the workers
+// of every replica claim jobs from it, whichever replica submitted them.
+type IngestQueue struct {
+	processor *DocumentProcessor
+	backlog   Backlog
+	workers   int
+	logger    *zap.Logger
+
+	signal chan struct{} // has a value while jobs may be waiting
+
+	inFlight  atomic.Int64
+	completed atomic.Int64
+	skipped   atomic.Int64
+	failed    atomic.Int64
+}
+
+// NewIngestQueue creates a queue holding its jobs in the backlog, processed
+// by the given number of workers once started
+func NewIngestQueue(processor *DocumentProcessor, backlog Backlog, workers int, logger *zap.Logger) *IngestQueue {
+	return &IngestQueue{
+		processor: processor,
+		backlog:   backlog,
+		workers:   workers,
+		logger:    logger,
+		signal:    make(chan struct{}, 1),
+	}
+}
+
+// Start runs the workers until ctx is done
+func (q *IngestQueue) Start(ctx context.Context) {
+	for i := 0; i < q.workers; i++ {
+		go q.work(ctx)
+	}
+	q.logger.Info("Ingest queue started",
+		zap.Int("workers", q.workers),
+		zap.Bool("shared", q.shared()))
+}
+
+// Submit queues files at a priority, to be extracted with the given
+// options, all or none: it fails with ErrQueueFull when they do not all
+// fit. A shared queue leaves out files already waiting or being processed.
+func (q *IngestQueue) Submit(ctx context.Context, paths []string, priority Priority, force bool, options processors.ExtractOptions) ([]*Job, error) {
+	now := time.Now()
+	jobs := make([]*Job, len(paths))
+	for i, path := range paths {
+		jobs[i] = &Job{ID: uuid.New().String(), FilePath: path, Force: force, Priority: priority, EnqueuedAt: now, Options: options}
+	}
+	jobs, err := q.backlog.Push(ctx, jobs)
+	if err != nil {
+		return nil, err
+	}
+	q.notify()
+	return jobs, nil
+}
+
+// SubmitDirectory queues the files found in a directory at a priority,
+// like Submit
+func (q *IngestQueue) SubmitDirectory(ctx context.Context, directory string, priority Priority, force bool, options processors.ExtractOptions) ([]*Job, error) {
+	paths, err := q.ScanDirectory(directory)
+	if err != nil {
+		return nil, err
+	}
+	return q.Submit(ctx, paths, priority, force, options)
+}
+
+// ScanDirectory returns the paths of the files SubmitDirectory would queue
+func (q *IngestQueue) ScanDirectory(directory string) ([]string, error) {
+	scan, err := q.processor.scanDirectory(directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan directory: %w", err)
+	}
+	return scan.Paths(), nil
+}
+
+// Stats returns the queue depth per priority and the worker counters
+func (q *IngestQueue) Stats(ctx context.Context) QueueStats {
+	stats := QueueStats{
+		Depth:   make(map[Priority]int, len(priorities)),
+		Wait:    make(map[Priority]string, len(priorities)),
+		Workers: q.workers,
+		Shared:  q.shared(),
+	}
+	backlog, err := q.backlog.Stats(ctx)
+	if err != nil {
+		q.logger.Warn("Failed to read ingest queue", zap.Error(err))
+	}
+	for _, p := range priorities {
+		stats.Depth[p] = backlog.Depth[p]
+		if oldest, ok := backlog.Oldest[p]; ok {
+			stats.Wait[p] = time.Since(oldest).Round(time.Second).String()
+		}
+	}
+	stats.Capacity, stats.Claimed = backlog.Capacity, backlog.Claimed
+
+	stats.InFlight = q.inFlight.Load()
+	stats.Completed = q.completed.Load()
+	stats.Skipped = q.skipped.Load()
+	stats.Failed = q.failed.Load()
+	return stats
+}
+
+// work processes jobs until ctx is done
+func (q *IngestQueue) work(ctx context.Context) {
+	for {
+		job := q.next(ctx)
+		if job == nil {
+			return
+		}
+		q.process(ctx, job)
+	}
+}
+
+// next waits for indexing to be allowed and returns the highest priority
+// waiting job, or nil once ctx is done. Idle workers of a shared queue
+// look for jobs submitted on other replicas every poll interval.
+func (q *IngestQueue) next(ctx context.Context) *Job {
+	for {
+		if !q.processor.proceed(ctx, nil) {
+			return nil
+		}
+		job, err := q.backlog.Claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			q.logger.Warn("Failed to claim queued file", zap.Error(err))
+		}
+		if job != nil {
+			// Another worker may find the next job
+			q.notify()
+			return job
+		}
+
+		var poll <-chan time.Time
+		timer := time.NewTimer(q.backlog.Poll())
+		if q.shared() {
+			poll = timer.C
+		}
+		select {
+		case <-q.signal:
+		case <-poll:
+		case <-ctx.Done():
+		}
+		timer.Stop()
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// notify wakes a waiting worker
+func (q *IngestQueue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// shared reports whether the replicas share the queue
+func (q *IngestQueue) shared() bool {
+	return q.backlog.Poll() > 0
+}
+
+// process processes a job, counting files that are already indexed or too
+// large as skipped. Failed files are retried and dead-lettered when the
+// processor has a dead-letter list. A job of a shared queue keeps its
+// claim while processed and is acknowledged once done; a job claimed too
+// many times is dead-lettered without processing.
+func (q *IngestQueue) process(ctx context.Context, job *Job) {
+	q.inFlight.Add(1)
+	defer q.inFlight.Add(-1)
+
+	fields := []zap.Field{
+		zap.String("job_id", job.ID),
+		zap.String("file", job.FilePath),
+		zap.String("priority", string(job.Priority)),
+	}
+	defer func() {
+		if err := q.backlog.Ack(context.WithoutCancel(ctx), job); err != nil {
+			q.logger.Warn("Failed to acknowledge queued file", append(fields, zap.Error(err))...)
+		}
+	}()
+
+	if q.shared() {
+		if limit := q.processor.config.Sharding.MaxDeliveries; job.Deliveries > limit {
+			q.failed.Add(1)
+			err := fmt.Errorf("claimed %d times without finishing, its replicas stopped while processing it", job.Deliveries)
+			q.logger.Error("Dropped queued file", append(fields, zap.Error(err))...)
+			if q.processor.deadLetters != nil {
+				q.processor.deadLetter(ctx, job.FilePath, job.Force, SourceQueue, job.Deliveries, err)
+			}
+			return
+		}
+		stop := q.keepClaim(ctx, job)
+		defer stop()
+	}
+
+	err := q.processor.processWithRetry(processors.WithOptions(ctx, job.Options), job.FilePath, job.Force, SourceQueue)
+	switch {
+	case err == nil:
+		q.completed.Add(1)
+		q.logger.Info("Processed queued file", append(fields, zap.Duration("waited", time.Since(job.EnqueuedAt)))...)
+	case skippable(err):
+		q.skipped.Add(1)
+		q.logger.Info("Skipped queued file", append(fields, zap.Error(err))...)
+	default:
+		q.failed.Add(1)
+		q.logger.Error("Failed to process queued file", append(fields, zap.Error(err))...)
+	}
+}
+
+// keepClaim extends a job's claim a few times per visibility timeout until
+// the returned function is called
+func (q *IngestQueue) keepClaim(ctx context.Context, job *Job) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(max(q.processor.config.Sharding.VisibilityTimeout/3, time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			held, err := q.backlog.Extend(context.WithoutCancel(ctx), job)
+			if err != nil {
+				q.logger.Warn("Failed to extend claim of queued file", zap.String("job_id", job.ID), zap.Error(err))
+				continue
+			}
+			if !held {
+				q.logger.Warn("Claim of queued file expired, another replica may process it",
+					zap.String("job_id", job.ID),
+					zap.String("file", job.FilePath))
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
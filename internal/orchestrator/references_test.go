@@ -0,0 +1,108 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+// metadataServer serves fetch and update for a single vector, pausing
+// between reading and answering a fetch so unserialized read-modify-write
+// updates overwrite each other
+type metadataServer struct {
+	mu       sync.Mutex
+	id       string
+	metadata map[string]interface{}
+}
+
+func (s *metadataServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/vectors/fetch":
+		s.mu.Lock()
+		data, _ := json.Marshal(pinecone.FetchResponse{Vectors: map[string]*pinecone.Vector{ //nolint:errcheck
+			s.id: {ID: s.id, Values: []float32{1, 0}, Metadata: s.metadata},
+		}})
+		s.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		w.Write(data) //nolint:errcheck
+	case "/vectors/update":
+		var req pinecone.UpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := json.Marshal(req.SetMetadata) //nolint:errcheck
+		var fields map[string]interface{}
+		json.Unmarshal(data, &fields) //nolint:errcheck
+		s.mu.Lock()
+		for key, value := range fields {
+			s.metadata[key] = value
+		}
+		s.mu.Unlock()
+		w.Write([]byte("{}")) //nolint:errcheck
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestAddChunkReferenceConcurrent(t *testing.T) {
+	server := &metadataServer{
+		id:       "doc-a-chunk-0",
+		metadata: map[string]interface{}{"document_id": "doc-a", "file_path": "/data/a.md"},
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	cfg := &config.Config{Pinecone: config.PineconeConfig{
+		APIKey:          "key",
+		IndexName:       "index",
+		Host:            httpServer.URL,
+		Dimension:       2,
+		UpsertBatchSize: 100,
+	}}
+	client, err := pinecone.NewPineconeClient(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dp := &DocumentProcessor{pineconeClient: client, config: cfg, logger: zap.NewNop()}
+
+	const files = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, files)
+	for i := 0; i < files; i++ {
+		doc := &Document{
+			FilePath: fmt.Sprintf("/data/copy-%d.md", i),
+			FileHash: fmt.Sprintf("hash-%d", i),
+			Record:   &registry.Record{ID: fmt.Sprintf("doc-%d", i)},
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- dp.addChunkReference(context.Background(), "", server.id, doc)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, key := range []string{"referenced_by", "referenced_by_hashes", "referenced_by_documents"} {
+		refs, _ := server.metadata[key].([]interface{})
+		if len(refs) != files {
+			t.Errorf("%s = %v, want all %d referencing files", key, refs, files)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/wal"
+	"go.uber.org/zap"
+)
+
+// upsert stores vectors in a namespace, the chunk namespace when empty.
+// With a write-ahead log the vectors are logged first and acknowledged once
+// stored; a failed upsert leaves its entry to be replayed later.
+func (dp *DocumentProcessor) upsert(ctx context.Context, namespace string, record *registry.Record, vectors []*pinecone.Vector) error {
+	return dp.upsertEntry(ctx, &wal.Entry{
+		DocumentID: record.ID,
+		FilePath:   record.FilePath,
+		Category:   record.Category,
+		Namespace:  namespace,
+		Vectors:    vectors,
+	})
+}
+
+// upsertEntry stores the vectors of an entry, logging it first when there
+// is a write-ahead log
+func (dp *DocumentProcessor) upsertEntry(ctx context.Context, entry *wal.Entry) error {
+	if dp.wal == nil {
+		return dp.upsertVectors(ctx, entry)
+	}
+
+	if err := dp.wal.Append(ctx, entry); err != nil {
+		return fmt.Errorf("failed to write vectors to the write-ahead log: %w", err)
+	}
+	if err := dp.upsertVectors(ctx, entry); err != nil {
+		return err
+	}
+	if err := dp.wal.Ack(ctx, entry.ID); err != nil {
+		dp.logger.Warn("Failed to acknowledge write-ahead log entry",
+			zap.String("document_id", entry.DocumentID),
+			zap.Error(err))
+	}
+	return nil
+}
+
+// upsertVectors stores the vectors of an entry in its namespace, or in the
+// chunk namespace of its category's route when it has none
+func (dp *DocumentProcessor) upsertVectors(ctx context.Context, entry *wal.Entry) error {
+	if entry.Namespace == "" {
+		return dp.chunkClient(entry.Category).UpsertVectors(ctx, entry.Vectors)
+	}
+	return dp.pineconeClient.UpsertVectorsInNamespace(ctx, entry.Namespace, entry.Vectors)
+}
+
+// ReplayResult summarizes a write-ahead log replay
+type ReplayResult struct {
+	Entries int `json:"entries"`
+	Vectors int `json:"vectors"`
+	Failed  int `json:"failed"`
+}
+
+// replayPageSize is how many write-ahead log entries are read at a time
+const replayPageSize = 100
+
+// ReplayWAL upserts the vectors of write-ahead log entries that were never
+// acknowledged, oldest first, and acknowledges them. Entries of writers
+// still running, on this or another replica, are left to them, and nothing
+// is replayed while another process replays the log. Documents whose chunk
+// vectors are replayed supersede their previous versions and are marked
+// indexed in the registry, as their processing would have done, unless the
+// entry is one batch of a streamed document. Entries that fail again stay
+// in the log for the next replay.
+func (dp *DocumentProcessor) ReplayWAL(ctx context.Context) (*ReplayResult, error) {
+	result := &ReplayResult{}
+	if dp.wal == nil {
+		return result, nil
+	}
+
+	unlock, ok, err := dp.wal.LockReplay(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock write-ahead log replay: %w", err)
+	}
+	if !ok {
+		dp.logger.Info("Write-ahead log is being replayed by another process")
+		return result, nil
+	}
+	defer unlock()
+
+	cursor := ""
+	for {
+		entries, next, err := dp.wal.Pending(ctx, cursor, replayPageSize)
+		if err != nil {
+			return result, err
+		}
+		if len(entries) > 0 && result.Entries+result.Failed == 0 {
+			dp.logger.Info("Replaying write-ahead log")
+		}
+		for _, entry := range entries {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			dp.replayEntry(ctx, entry, result)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if result.Entries+result.Failed == 0 {
+		return result, nil
+	}
+
+	dp.logger.Info("Write-ahead log replay complete",
+		zap.Int("entries", result.Entries),
+		zap.Int("vectors", result.Vectors),
+		zap.Int("failed", result.Failed))
+	return result, nil
+}
+
+// RunReplay replays the write-ahead log every interval until ctx is done,
+// storing the entries of writers that stopped since the last replay
+func (dp *DocumentProcessor) RunReplay(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := dp.ReplayWAL(ctx)
+		if err != nil {
+			dp.logger.Error("Failed to replay write-ahead log", zap.Error(err))
+			continue
+		}
+		if result.Failed > 0 {
+			dp.logger.Warn("Some write-ahead log entries could not be replayed",
+				zap.Int("failed", result.Failed))
+		}
+	}
+}
+
+// replayEntry upserts and acknowledges one write-ahead log entry, counting
+// it in the result
+func (dp *DocumentProcessor) replayEntry(ctx context.Context, entry *wal.Entry, result *ReplayResult) {
+	if err := dp.upsertVectors(ctx, entry); err != nil {
+		result.Failed++
+		dp.logger.Warn("Failed to replay write-ahead log entry",
+			zap.String("document_id", entry.DocumentID),
+			zap.String("file", entry.FilePath),
+			zap.Error(err))
+		return
+	}
+	if err := dp.wal.Ack(ctx, entry.ID); err != nil {
+		dp.logger.Warn("Failed to acknowledge write-ahead log entry",
+			zap.String("document_id", entry.DocumentID),
+			zap.Error(err))
+	}
+	result.Entries++
+	result.Vectors += len(entry.Vectors)
+
+	if entry.Namespace == "" && !entry.Partial {
+		dp.completeReplayed(ctx, entry)
+	}
+}
+
+// completeReplayed finishes the indexing of a document whose chunk vectors
+// were stored by a replay. Nothing is changed when the registry shows the
+// file was indexed again since, so a newer version is never superseded.
+func (dp *DocumentProcessor) completeReplayed(ctx context.Context, entry *wal.Entry) {
+	var record *registry.Record
+	if dp.registry != nil {
+		current, err := dp.registry.GetByPath(ctx, entry.FilePath)
+		if err == nil && current.ID != entry.DocumentID {
+			return
+		}
+		record = current
+	}
+
+	if _, err := dp.supersedeVersions(ctx, entry.Category, entry.FilePath, entry.DocumentID); err != nil {
+		dp.logger.Warn("Failed to supersede previous versions",
+			zap.String("file", entry.FilePath),
+			zap.Error(err))
+	}
+
+	if record == nil || record.State == models.StateIndexed {
+		return
+	}
+	indexedAt := time.Now()
+	record.IndexedAt = &indexedAt
+	record.Error = ""
+	record.ChunkCount = len(entry.Vectors)
+	dp.track(ctx, record, models.StateIndexed)
+}
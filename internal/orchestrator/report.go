@@ -0,0 +1,85 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/azure"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+)
+
+// stageOf maps the state a document reaches to the stage that led to it
+var stageOf = map[models.ProcessingState]string{
+	models.StateScanned:    "scan",
+	models.StateExtracted:  "extract",
+	models.StateAnalyzed:   StageVision,
+	models.StateSummarized: StageSummary,
+	models.StateChunked:    "chunk",
+	models.StateEmbedded:   "embed",
+	models.StateIndexed:    "index",
+}
+
+// fileTrace builds the report of a file of a run as it is processed: the
+// time between state changes is the time spent in the stage reaching the
+// new state, and Azure OpenAI token usage is metered through the context
+type fileTrace struct {
+	runID   string
+	report  runs.FileReport
+	meter   azure.TokenMeter
+	started time.Time
+	mark    time.Time
+}
+
+type traceKey struct{}
+
+func newFileTrace(filePath, runID string) *fileTrace {
+	return &fileTrace{runID: runID, report: runs.FileReport{FilePath: filePath}, started: time.Now()}
+}
+
+// withTrace returns a context that reports processing to the trace
+func withTrace(ctx context.Context, t *fileTrace) context.Context {
+	return azure.WithTokenMeter(context.WithValue(ctx, traceKey{}, t), &t.meter)
+}
+
+// traceFrom returns the context's trace, or nil outside of a run
+func traceFrom(ctx context.Context) *fileTrace {
+	t, _ := ctx.Value(traceKey{}).(*fileTrace)
+	return t
+}
+
+// attempt starts timing another attempt at the file
+func (t *fileTrace) attempt() {
+	if t == nil {
+		return
+	}
+	t.report.Attempts++
+	t.report.StageMs = make(map[string]int64, len(runs.Stages))
+	t.mark = time.Now()
+}
+
+// reached records the time the document took to reach a state
+func (t *fileTrace) reached(record *registry.Record, state models.ProcessingState) {
+	if t == nil || t.report.StageMs == nil {
+		return
+	}
+	now := time.Now()
+	if stage, ok := stageOf[state]; ok {
+		t.report.StageMs[stage] += now.Sub(t.mark).Milliseconds()
+	}
+	t.report.DocumentID = record.ID
+	t.mark = now
+}
+
+// finish completes the report with the file's outcome
+func (t *fileTrace) finish(outcome string, err error) runs.FileReport {
+	t.report.Outcome = outcome
+	if err != nil {
+		t.report.Error = err.Error()
+	}
+	t.report.DurationMs = time.Since(t.started).Milliseconds()
+	usage := t.meter.Usage()
+	t.report.Tokens = runs.TokenUsage{Prompt: usage.PromptTokens, Completion: usage.CompletionTokens}
+	return t.report
+}
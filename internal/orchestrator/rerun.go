@@ -0,0 +1,132 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/scanner"
+	"go.uber.org/zap"
+)
+
+// ErrContentNotStored is returned when a document's extracted text is not
+// in the content store, so a stage cannot run without extracting again
+var ErrContentNotStored = errors.New("document content is not stored")
+
+// Rechunk chunks and embeds a document's stored content again with the
+// current chunking and embedding settings, keeping its summary. The result
+// is a new version of the document that supersedes the previous one, as a
+// reindex does, but without reading the file or extracting it again.
+func (dp *DocumentProcessor) Rechunk(ctx context.Context, documentID string) (record *registry.Record, err error) {
+	previous, content, err := dp.storedDocument(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close() //nolint:errcheck
+
+	now := time.Now()
+	record = &registry.Record{
+		ID:              uuid.New().String(),
+		FilePath:        previous.FilePath,
+		FileName:        previous.FileName,
+		FileType:        previous.FileType,
+		Category:        previous.Category,
+		ContentType:     previous.ContentType,
+		TypeMismatch:    previous.TypeMismatch,
+		FileHash:        previous.FileHash,
+		Image:           previous.Image,
+		Dataset:         previous.Dataset,
+		Title:           previous.Title,
+		Tags:            previous.Tags,
+		Aliases:         previous.Aliases,
+		Links:           previous.Links,
+		Summary:         previous.Summary,
+		Metadata:        previous.Metadata,
+		ContentStored:   true,
+		NeedsEnrichment: previous.NeedsEnrichment,
+		ModifiedAt:      previous.ModifiedAt,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	dp.track(ctx, record, models.StateExtracted)
+	defer func() {
+		if err != nil {
+			record.Error = err.Error()
+			dp.track(ctx, record, models.StateFailed)
+		}
+	}()
+
+	doc := &Document{
+		FilePath:   record.FilePath,
+		FileHash:   record.FileHash,
+		Detection:  scanner.Detection{MimeType: previous.ContentType, Mismatch: previous.TypeMismatch},
+		Record:     record,
+		Content:    content,
+		Summarized: previous.Summary != "" && previous.Summary != summaryFailed,
+	}
+	if err := dp.runStages(ctx, doc, dp.indexSteps()); err != nil {
+		return record, err
+	}
+
+	dp.logger.Info("Rechunked document",
+		zap.String("previous_id", previous.ID),
+		zap.String("document_id", record.ID),
+		zap.Int("chunks", record.ChunkCount))
+	return record, nil
+}
+
+// Resummarize generates a document's summary again from its stored content
+// and replaces the summary in the registry and, when enabled, the summary
+// vector. Chunk vectors are left unchanged.
+func (dp *DocumentProcessor) Resummarize(ctx context.Context, documentID string) (*registry.Record, error) {
+	record, content, err := dp.storedDocument(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close() //nolint:errcheck
+
+	summarized, err := dp.summarize(ctx, record, content)
+	if err != nil {
+		return nil, err
+	}
+	if !summarized {
+		return nil, errors.New("failed to generate summary")
+	}
+
+	if ns := dp.pineconeClient.SummaryNamespace(); ns != "" && record.State == models.StateIndexed {
+		detected := scanner.Detection{MimeType: record.ContentType}
+		if err := dp.indexSummary(ctx, ns, record, detected, dp.resolveACL(record.FilePath)); err != nil {
+			return nil, fmt.Errorf("failed to index document summary: %w", err)
+		}
+	}
+	dp.track(ctx, record, record.State)
+
+	dp.logger.Info("Resummarized document", zap.String("document_id", record.ID))
+	return record, nil
+}
+
+// storedDocument returns a document's registry record and its content from
+// the content store
+func (dp *DocumentProcessor) storedDocument(ctx context.Context, documentID string) (*registry.Record, *processors.Content, error) {
+	if dp.registry == nil {
+		return nil, nil, errors.New("document registry is not configured")
+	}
+	record, err := dp.registry.Get(ctx, documentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := dp.storedContent(ctx, record.FileHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read stored content: %w", err)
+	}
+	if content == nil {
+		return nil, nil, ErrContentNotStored
+	}
+	return record, content, nil
+}
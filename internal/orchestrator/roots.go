@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+)
+
+// ErrOutsideRoots is returned for a path that does not exist or lies
+// outside the data roots. The two are not told apart, so callers cannot
+// probe which files the server has.
+var ErrOutsideRoots = errors.New("not a path under the data directories")
+
+// DataRoots returns the directories the processing endpoints may read
+// files from
+func DataRoots(cfg *config.Config) []string {
+	if len(cfg.App.IngestRoots) > 0 {
+		return cfg.App.IngestRoots
+	}
+	return []string{cfg.App.DataDirectory}
+}
+
+// ConfinePath checks that a path, with its symlinks resolved, is one of
+// the roots or lies under one, and returns ErrOutsideRoots otherwise
+func ConfinePath(roots []string, path string) error {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return ErrOutsideRoots
+	}
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		resolvedRoot, err := resolvePath(root)
+		if err != nil {
+			continue
+		}
+		if within(resolved, resolvedRoot) {
+			return nil
+		}
+	}
+	return ErrOutsideRoots
+}
+
+// resolvePath returns the absolute path a path names with every symlink
+// resolved; it fails when the path does not exist
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(utils.LocalPath(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// within reports whether a resolved path is root or lies under it
+func within(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,134 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// dataTree lays out a data root holding a file, a symlink to it and a
+// symlink to a secret file in a sibling directory whose name shares the
+// root's prefix
+func dataTree(t *testing.T) (root, outside string) {
+	t.Helper()
+	base := t.TempDir()
+	root = filepath.Join(base, "data")
+	outside = filepath.Join(base, "data-evil")
+	for _, dir := range []string{filepath.Join(root, "docs"), outside} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(root, "docs", "report.md"): "report",
+		filepath.Join(outside, "secret.md"):      "secret",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		filepath.Join(root, "docs", "alias.md"):  filepath.Join(root, "docs", "report.md"),
+		filepath.Join(root, "docs", "escape.md"): filepath.Join(outside, "secret.md"),
+		filepath.Join(root, "escape"):            outside,
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+	return root, outside
+}
+
+func TestConfinePath(t *testing.T) {
+	root, outside := dataTree(t)
+	roots := []string{root}
+
+	tests := []struct {
+		name string
+		path string
+		ok   bool
+	}{
+		{"file under root", filepath.Join(root, "docs", "report.md"), true},
+		{"root itself", root, true},
+		{"path with dot segments", root + "/docs/../docs/report.md", true},
+		{"symlink to a file under root", filepath.Join(root, "docs", "alias.md"), true},
+		{"traversal out of root", root + "/docs/../../data-evil/secret.md", false},
+		{"sibling directory sharing the prefix", filepath.Join(outside, "secret.md"), false},
+		{"symlinked file escaping root", filepath.Join(root, "docs", "escape.md"), false},
+		{"file under a symlinked directory escaping root", filepath.Join(root, "escape", "secret.md"), false},
+		{"symlinked directory escaping root", filepath.Join(root, "escape"), false},
+		{"missing file under root", filepath.Join(root, "docs", "missing.md"), false},
+		{"absolute system path", "/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ConfinePath(roots, tt.path)
+			if tt.ok && err != nil {
+				t.Errorf("ConfinePath(%q) = %v, want nil", tt.path, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrOutsideRoots) {
+				t.Errorf("ConfinePath(%q) = %v, want ErrOutsideRoots", tt.path, err)
+			}
+		})
+	}
+}
+
+func TestConfinePathRoots(t *testing.T) {
+	root, outside := dataTree(t)
+	secret := filepath.Join(outside, "secret.md")
+
+	if err := ConfinePath([]string{"", filepath.Join(root, "missing")}, secret); err == nil {
+		t.Error("empty and missing roots confine nothing, got nil")
+	}
+	if err := ConfinePath([]string{root, outside}, secret); err != nil {
+		t.Errorf("file under the second root = %v, want nil", err)
+	}
+}
+
+func TestDataRoots(t *testing.T) {
+	cfg := &config.Config{App: config.AppConfig{DataDirectory: "/data"}}
+	if got := DataRoots(cfg); len(got) != 1 || got[0] != "/data" {
+		t.Errorf("DataRoots = %v, want the data directory", got)
+	}
+	cfg.App.IngestRoots = []string{"/srv/a", "/srv/b"}
+	if got := DataRoots(cfg); len(got) != 2 || got[0] != "/srv/a" {
+		t.Errorf("DataRoots = %v, want the ingest roots", got)
+	}
+}
+
+func TestPrepareBatchRejectsPathsOutsideRoots(t *testing.T) {
+	root, outside := dataTree(t)
+	dp := &DocumentProcessor{
+		config: &config.Config{App: config.AppConfig{DataDirectory: root}},
+		logger: zap.NewNop(),
+	}
+
+	items := []string{
+		root + "/docs/../../data-evil/secret.md",
+		"file://" + filepath.Join(outside, "secret.md"),
+		filepath.Join(root, "docs", "escape.md"),
+		filepath.Join(root, "docs", "missing.md"),
+		filepath.Join(outside, "missing.md"),
+	}
+	batch, err := dp.PrepareBatch(context.Background(), items, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.RunID != "" {
+		t.Errorf("run %q started with no accepted item", batch.RunID)
+	}
+	for _, item := range batch.Items {
+		// A missing file and an existing file outside the roots must not be
+		// told apart
+		if item.Status != BatchInvalid || item.Reason != ErrOutsideRoots.Error() || item.FilePath != "" {
+			t.Errorf("item %q = %+v, want invalid with the generic reason", item.Item, item)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/azure"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/sparse"
+)
+
+// chunkClient returns the Pinecone client storing the chunk vectors of a
+// file category
+func (dp *DocumentProcessor) chunkClient(category string) *pinecone.PineconeClient {
+	if route := dp.router.Route(category); route != nil {
+		return route.Pinecone
+	}
+	return dp.pineconeClient
+}
+
+// chunkEmbedder returns the client embedding the chunks of a file category
+func (dp *DocumentProcessor) chunkEmbedder(category string) *azure.OpenAIClient {
+	if route := dp.router.Route(category); route != nil && route.Embedder != nil {
+		return route.Embedder
+	}
+	return dp.azureClient
+}
+
+// chunkSparse returns the sparse encoder of the chunks of a file category,
+// or nil when they get dense vectors only: without an encoder, or when a
+// route stores them in an index of its own, which need not take sparse
+// values
+func (dp *DocumentProcessor) chunkSparse(category string) sparse.Encoder {
+	if route := dp.router.Route(category); route != nil && !route.SameIndex {
+		return nil
+	}
+	return dp.sparseEncoder
+}
+
+// dedupScope returns the scope a document's chunks are deduplicated in:
+// its access control, and its category when that is routed, as a chunk
+// can only reference a vector stored by the same route
+func (dp *DocumentProcessor) dedupScope(doc *Document) string {
+	scope := doc.acl.Key()
+	if dp.router.Route(doc.Record.Category) != nil {
+		scope += "|category:" + doc.Record.Category
+	}
+	return scope
+}
+
+// documentExists reports whether a file with one of the hashes is indexed.
+// The check runs before the file's category is detected, so every route
+// is asked after the Pinecone index.
+func (dp *DocumentProcessor) documentExists(ctx context.Context, fileHash string, otherHashes ...string) (bool, error) {
+	exists, err := dp.pineconeClient.CheckDocumentExists(ctx, fileHash, otherHashes...)
+	if err != nil || exists {
+		return exists, err
+	}
+	for _, route := range dp.router.Routes() {
+		exists, err := route.Pinecone.CheckDocumentExists(ctx, fileHash, otherHashes...)
+		if err != nil {
+			return false, fmt.Errorf("failed to check %s vectors: %w", route.Category, err)
+		}
+		if exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// supersedeVersions marks the vectors of earlier versions of a file as
+// superseded: its chunk vectors wherever its category stores them, and its
+// summary vectors, which stay in the Pinecone index
+func (dp *DocumentProcessor) supersedeVersions(ctx context.Context, category, filePath, docID string) (int, error) {
+	client := dp.chunkClient(category)
+	superseded, err := client.SupersedeVersions(ctx, filePath, docID, time.Now())
+	if err != nil || client == dp.pineconeClient {
+		return superseded, err
+	}
+	if ns := dp.pineconeClient.SummaryNamespace(); ns != "" {
+		if _, err := dp.pineconeClient.SupersedeVersionsInNamespace(ctx, ns, filePath, docID, time.Now()); err != nil {
+			return superseded, fmt.Errorf("failed to supersede summary vectors: %w", err)
+		}
+	}
+	return superseded, nil
+}
+
+// setRouteMetadata records the category of a routed chunk in its metadata,
+// so searches know which route to fetch its neighbours from
+func (dp *DocumentProcessor) setRouteMetadata(metadata map[string]interface{}, category string) {
+	if dp.router.Route(category) != nil {
+		metadata["category"] = category
+	}
+}
@@ -0,0 +1,197 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+// defaultWebhookTimeout bounds a webhook stage without a timeout of its own
+const defaultWebhookTimeout = 30 * time.Second
+
+// webhookRequest is the JSON body posted to a webhook stage
+type webhookRequest struct {
+	Stage    PipelineStage    `json:"stage"`
+	Document *registry.Record `json:"document"`
+	// Content is the start of the document's content, sent after the
+	// extract and enrich stages
+	Content string `json:"content,omitempty"`
+	// Chunks are the chunks waiting to be embedded, sent after the chunk
+	// stage unless the content is streamed
+	Chunks []webhookChunk `json:"chunks,omitempty"`
+}
+
+// webhookChunk is a chunk sent to or returned by a webhook stage
+type webhookChunk struct {
+	Index int    `json:"index"`
+	Text  string `json:"text"`
+}
+
+// webhookResponse is the JSON a webhook stage answers with. Every field is
+// optional; an empty object lets the document through unchanged.
+type webhookResponse struct {
+	Skip     string                 `json:"skip"`     // reason to leave the document out of the index
+	Metadata map[string]interface{} `json:"metadata"` // added to the document's vectors
+	// Chunks replace the document's chunks after the chunk stage: a chunk
+	// left out is dropped. Null keeps the chunks as they are.
+	Chunks *[]webhookChunk `json:"chunks"`
+}
+
+// webhookStage posts documents to an HTTP endpoint after a pipeline stage
+type webhookStage struct {
+	config.WebhookStage
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+// webhookMiddleware returns middleware running the configured webhook
+// stages, each after the stage it follows
+func webhookMiddleware(cfg config.PipelineConfig, logger *zap.Logger) []Middleware {
+	middleware := make([]Middleware, 0, len(cfg.Webhooks))
+	for _, webhook := range cfg.Webhooks {
+		if webhook.Timeout == 0 {
+			webhook.Timeout = defaultWebhookTimeout
+		}
+		stage := &webhookStage{
+			WebhookStage: webhook,
+			httpClient:   &http.Client{Timeout: webhook.Timeout},
+			logger:       logger,
+		}
+		middleware = append(middleware, After(PipelineStage(webhook.After), stage.run))
+	}
+	return middleware
+}
+
+// run calls the webhook and applies its answer. A webhook failing open is
+// logged and leaves the document unchanged.
+func (w *webhookStage) run(ctx context.Context, doc *Document) error {
+	err := w.call(ctx, doc)
+	if errors.Is(err, ErrSkipDocument) {
+		return err
+	}
+	if err != nil && w.FailOpen && ctx.Err() == nil {
+		w.logger.Warn("Pipeline webhook failed, continuing",
+			zap.String("webhook", w.Name),
+			zap.String("file", doc.FilePath),
+			zap.Error(err))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("pipeline webhook %s: %w", w.Name, err)
+	}
+	return nil
+}
+
+func (w *webhookStage) call(ctx context.Context, doc *Document) error {
+	stage := PipelineStage(w.After)
+	body := webhookRequest{Stage: stage, Document: doc.Record}
+	if (stage == PipelineExtract || stage == PipelineEnrich) && doc.Content != nil {
+		prefix, err := doc.Content.Prefix(summaryInputBytes)
+		if err != nil {
+			return fmt.Errorf("failed to read content: %w", err)
+		}
+		body.Content = prefix
+	}
+	if stage == PipelineChunk {
+		body.Chunks = make([]webhookChunk, len(doc.Chunks))
+		for i, chunk := range doc.Chunks {
+			body.Chunks[i] = webhookChunk{Index: chunk.Index, Text: chunk.Text}
+		}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096)) //nolint:errcheck
+		return fmt.Errorf("webhook error (status %d): %s", resp.StatusCode, string(message))
+	}
+
+	var answer webhookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return w.apply(doc, stage, answer)
+}
+
+// apply checks a webhook's answer and applies it to the document. Nothing
+// is applied when any of it is invalid.
+func (w *webhookStage) apply(doc *Document, stage PipelineStage, answer webhookResponse) error {
+	for key, value := range answer.Metadata {
+		if !validMetadataValue(value) {
+			return fmt.Errorf("metadata %q must be a string, number, boolean or list of strings", key)
+		}
+	}
+
+	var chunks []Chunk
+	if answer.Chunks != nil {
+		if stage != PipelineChunk {
+			return fmt.Errorf("chunks can only be returned after the chunk stage")
+		}
+		if doc.eachChunk != nil {
+			return fmt.Errorf("chunks of streamed content cannot be returned")
+		}
+		byIndex := make(map[int]Chunk, len(doc.Chunks))
+		for _, chunk := range doc.Chunks {
+			byIndex[chunk.Index] = chunk
+		}
+		chunks = make([]Chunk, 0, len(*answer.Chunks))
+		for _, returned := range *answer.Chunks {
+			chunk, ok := byIndex[returned.Index]
+			if !ok {
+				return fmt.Errorf("unknown chunk %d returned", returned.Index)
+			}
+			chunk.Text = returned.Text
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	if answer.Skip != "" {
+		return SkipDocument(answer.Skip)
+	}
+	for key, value := range answer.Metadata {
+		doc.SetMetadata(key, value)
+	}
+	if answer.Chunks != nil {
+		doc.Chunks = chunks
+	}
+	return nil
+}
+
+// validMetadataValue reports whether a decoded JSON value can be stored as
+// vector metadata
+func validMetadataValue(value interface{}) bool {
+	switch v := value.(type) {
+	case string, float64, bool:
+		return true
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(string); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -2,29 +2,81 @@ package orchestrator
 
 import (
 	"context"
-	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/azure"
 	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/google"
 	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/chunkstore"
 	"github.com/nadeeshame/rag-knowledge-service/internal/config"
 	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/contentstore"
+	"github.com/nadeeshame/rag-knowledge-service/internal/datafile"
+	"github.com/nadeeshame/rag-knowledge-service/internal/dedup"
+	"github.com/nadeeshame/rag-knowledge-service/internal/dlq"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/embedding"
+	"github.com/nadeeshame/rag-knowledge-service/internal/events"
+	"github.com/nadeeshame/rag-knowledge-service/internal/imagesearch"
+	"github.com/nadeeshame/rag-knowledge-service/internal/locks"
+	"github.com/nadeeshame/rag-knowledge-service/internal/malware"
+	"github.com/nadeeshame/rag-knowledge-service/internal/notes"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/routing"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+	"github.com/nadeeshame/rag-knowledge-service/internal/scanner"
+	"github.com/nadeeshame/rag-knowledge-service/internal/sparse"
+	"github.com/nadeeshame/rag-knowledge-service/internal/wal"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
 	"go.uber.org/zap"
 )
 
+// summaryInputBytes is how much of a document's content is sent for
+// summarization; the summary client truncates it further
+const summaryInputBytes = 16 * 1024
+
+// summaryFailed is the summary recorded when none could be generated
+const summaryFailed = "Summary generation failed"
+
+// runCheckpointInterval is how often a run in progress is recorded with
+// the files it finished
+const runCheckpointInterval = time.Second
+
 // DocumentProcessor handles the complete document processing workflow
 type DocumentProcessor struct {
 	azureClient    *azure.OpenAIClient
 	visionClient   *google.VisionClient
 	pineconeClient *pinecone.PineconeClient
-	processors     []processors.ProcessorInterface
+	processors     *processors.Registry
+	limits         processors.Limits
+	dedupIndex     *dedup.Index
+	malwareGuard   *malware.Guard
+	pipeline       pipeline
+	registry       registry.Store
+	chunkStore     chunkstore.Store
+	wal            wal.Store
+	contentStore   contentstore.Store
+	imageIndex     *imagesearch.Index
+	router         *routing.Router
+	sparseEncoder  sparse.Encoder
+	runStore       runs.Store
+	events         *events.Bus
+	locker         *locks.Locker
+	vectorLocks    locks.Keyed
+	deadLetters    dlq.Store
+	control        runControl
+	visionBreaker  *breaker
+	summaryBreaker *breaker
+	repairMu       sync.Mutex
 	config         *config.Config
 	logger         *zap.Logger
 }
@@ -42,7 +94,7 @@ func NewDocumentProcessor(
 
 	// Initialize Google Vision client (optional)
 	var visionClient *google.VisionClient
-	if cfg.Google.VisionAPIKey != "" || cfg.Google.ApplicationCredentials != "" {
+	if cfg.Google.VisionAPIKey != "" || cfg.Google.ApplicationCredentials != "" || cfg.Azure.OpenAIVisionDeployment != "" {
 		visionClient, err = google.NewVisionClient(cfg, logger)
 		if err != nil {
 			logger.Warn("Failed to create Vision client", zap.Error(err))
@@ -56,107 +108,364 @@ func NewDocumentProcessor(
 	}
 
 	// Initialize content processors
-	contentProcessors := []processors.ProcessorInterface{
-		processors.NewTextProcessor(logger),
-		processors.NewImageProcessor(logger),
-		processors.NewDocumentProcessor(logger),
-		processors.NewSpreadsheetProcessor(logger),
-		processors.NewCodeProcessor(logger),
+	contentProcessors, err := processors.NewDefaultRegistry(context.Background(), logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if visionClient != nil {
+		contentProcessors.SetTextDetector(visionClient)
+	}
+
+	// Initialize chunk dedup index (optional)
+	var dedupIndex *dedup.Index
+	if cfg.Dedup.Enabled {
+		if cfg.Dedup.NearDuplicate {
+			dedupIndex = dedup.NewIndex(cfg.Dedup.MinHashPermutations, cfg.Dedup.MinHashBands, cfg.Dedup.SimilarityThreshold)
+		} else {
+			dedupIndex = dedup.NewIndex(0, 0, 0)
+		}
+	}
+
+	// Initialize malware scanning (optional)
+	malwareGuard, err := malware.New(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create malware scanner: %w", err)
 	}
 
-	return &DocumentProcessor{
+	dp := &DocumentProcessor{
 		azureClient:    azureClient,
 		visionClient:   visionClient,
 		pineconeClient: pineconeClient,
 		processors:     contentProcessors,
+		limits:         processors.LimitsFromConfig(cfg.Extraction),
+		dedupIndex:     dedupIndex,
+		malwareGuard:   malwareGuard,
+		visionBreaker:  newBreaker(cfg.Enrichment.FailureThreshold, cfg.Enrichment.Cooldown),
+		summaryBreaker: newBreaker(cfg.Enrichment.FailureThreshold, cfg.Enrichment.Cooldown),
 		config:         cfg,
 		logger:         logger,
-	}, nil
+	}
+	dp.Use(webhookMiddleware(cfg.Pipeline, logger)...)
+	return dp, nil
+}
+
+// SetRegistry makes the processor record document state in the registry
+func (dp *DocumentProcessor) SetRegistry(store registry.Store) {
+	dp.registry = store
+}
+
+// SetChunkStore makes the processor keep the full text of chunks whose
+// content is truncated to fit the vector metadata limit
+func (dp *DocumentProcessor) SetChunkStore(store chunkstore.Store) {
+	dp.chunkStore = store
+}
+
+// SetWAL makes the processor log vectors to a write-ahead log before
+// upserting them, so they survive a crash before the upsert completes
+func (dp *DocumentProcessor) SetWAL(store wal.Store) {
+	dp.wal = store
+}
+
+// SetContentStore makes the processor keep the extracted text of files and
+// reuse it instead of extracting unchanged files again
+func (dp *DocumentProcessor) SetContentStore(store contentstore.Store) {
+	dp.contentStore = store
+}
+
+// SetImageIndex makes the processor embed images with a multimodal model,
+// so they can be found by visual similarity
+func (dp *DocumentProcessor) SetImageIndex(index *imagesearch.Index) {
+	dp.imageIndex = index
+}
+
+// SetRouter makes the processor store the chunk vectors of routed file
+// categories in their own indexes or namespaces, embedded by their own
+// deployments
+func (dp *DocumentProcessor) SetRouter(router *routing.Router) {
+	dp.router = router
+}
+
+// SetSparseEncoder makes the processor store a sparse vector of terms with
+// each chunk's embedding, for hybrid queries
+func (dp *DocumentProcessor) SetSparseEncoder(encoder sparse.Encoder) {
+	dp.sparseEncoder = encoder
+}
+
+// PineconeReady checks that the Pinecone index, and the index of every
+// category route, answers
+func (dp *DocumentProcessor) PineconeReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := dp.pineconeClient.GetStats(ctx); err != nil {
+		return fmt.Errorf("pinecone not reachable: %w", err)
+	}
+	for _, route := range dp.router.Routes() {
+		if _, err := route.Pinecone.GetStats(ctx); err != nil {
+			return fmt.Errorf("pinecone index of %s vectors not reachable: %w", route.Category, err)
+		}
+	}
+	return nil
+}
+
+// ProcessFile processes a single file. With force set, files that are
+// already indexed are processed again.
+func (dp *DocumentProcessor) ProcessFile(ctx context.Context, filePath string, force bool) error {
+	return dp.processFile(ctx, filePath, force)
+}
+
+// DirectoryResult summarizes a directory processing run
+type DirectoryResult struct {
+	RunID     string        `json:"run_id"`
+	Directory string        `json:"directory"`
+	Total     int           `json:"total_files"`
+	Processed int           `json:"processed"`
+	Skipped   int           `json:"skipped"`
+	Failed    int           `json:"failed"`
+	Failures  []FileFailure `json:"failures,omitempty"`
+	// Ignored lists links, cycles and duplicate hard links left out of the
+	// scan, and files skipped for exceeding the extraction size, time or
+	// memory limits
+	Ignored []scanner.Skipped `json:"ignored,omitempty"`
+	// Changes lists the documents the run added or updated, and
+	// ChangeDigest summarizes their new content
+	Changes      []runs.Change `json:"changes,omitempty"`
+	ChangeDigest string        `json:"change_digest,omitempty"`
+	// Cancelled is set when the run stopped before processing every file
+	Cancelled bool `json:"cancelled,omitempty"`
+}
+
+// FileFailure records why a file could not be processed
+type FileFailure struct {
+	FilePath string `json:"file_path"`
+	Error    string `json:"error"`
 }
 
-// ProcessDirectory processes all files in a directory
-func (dp *DocumentProcessor) ProcessDirectory(ctx context.Context, directory string) error {
+// ProcessDirectory processes all files in a directory. With force set,
+// files that are already indexed are processed again. Failures of
+// individual files are reported in the result rather than as an error.
+// The documents added or updated are summarized in a change digest, and
+// the run is recorded in the run store when one is set. The run waits
+// between files while indexing is paused, and stops early when cancelled.
+// It returns ErrDirectoryLocked when another replica is running the
+// directory.
+func (dp *DocumentProcessor) ProcessDirectory(ctx context.Context, directory string, force bool) (*DirectoryResult, error) {
+	lock, err := dp.lockDirectory(ctx, directory)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release(context.WithoutCancel(ctx))
+
 	dp.logger.Info("Starting directory processing", zap.String("directory", directory))
+	run := &runs.Run{
+		ID:        uuid.New().String(),
+		Directory: directory,
+		Status:    runs.StatusRunning,
+		Force:     force,
+		StartedAt: time.Now(),
+		Changes:   []runs.Change{},
+	}
 
 	// Scan directory
-	files, err := dp.scanDirectory(directory)
+	scan, err := dp.scanDirectory(directory)
 	if err != nil {
-		return fmt.Errorf("failed to scan directory: %w", err)
+		return nil, fmt.Errorf("failed to scan directory: %w", err)
 	}
+	files := scan.Paths()
 
-	dp.logger.Info("Found files", zap.Int("count", len(files)))
+	dp.logger.Info("Found files",
+		zap.String("run_id", run.ID),
+		zap.Int("count", len(files)),
+		zap.Int("ignored", len(scan.Skipped)))
 
-	// Process each file
-	successCount := 0
-	skipCount := 0
-	errorCount := 0
+	run.Total = len(files)
+	result := &DirectoryResult{RunID: run.ID, Directory: directory, Total: len(files), Ignored: scan.Skipped}
+	return dp.processRun(ctx, run, files, result, lock)
+}
+
+// processRun processes the files of a run, adding to the counts already
+// in the result, and records the run when it finishes or is cancelled. The
+// run is cancelled if the lock of its directory is lost.
+func (dp *DocumentProcessor) processRun(ctx context.Context, run *runs.Run, files []string, result *DirectoryResult, lock *locks.Lock) (*DirectoryResult, error) {
+	stop, err := dp.startRun(run.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer dp.finishRun(run.ID)
+	defer dp.cancelOnLoss(lock, run.ID)()
+	dp.saveRun(ctx, run)
+	dp.publishProgress(ctx, run, result, "")
+
+	// Each finished file is published, and the run is recorded with its
+	// progress now and then, so that its report is current while it runs
+	saved := time.Now()
+	progress := func(file string) {
+		dp.publishProgress(ctx, run, result, file)
+		if time.Since(saved) >= runCheckpointInterval {
+			run.Processed, run.Skipped, run.Failed = result.Processed, result.Skipped, result.Failed
+			dp.saveRun(ctx, run)
+			saved = time.Now()
+		}
+	}
 
+	// Process each file
 	for i, file := range files {
+		if !dp.proceed(ctx, stop) {
+			run.Status, run.Remaining = runs.StatusCancelled, files[i:]
+			result.Cancelled = true
+			dp.logger.Warn("Run cancelled",
+				zap.String("run_id", run.ID),
+				zap.Int("remaining", len(run.Remaining)))
+			break
+		}
+
 		dp.logger.Info("Processing file",
 			zap.Int("index", i+1),
 			zap.Int("total", len(files)),
 			zap.String("file", file))
 
-		err := dp.processFile(ctx, file)
+		previous := dp.previousVersion(ctx, file)
+		trace := newFileTrace(file, run.ID)
+		err := dp.processWithRetry(withTrace(ctx, trace), file, run.Force, run.ID)
 		if err != nil {
-			if strings.Contains(err.Error(), "already indexed") {
-				skipCount++
+			var tooLarge *processors.FileTooLargeError
+			var infected *malware.InfectedError
+			var limited *processors.LimitExceededError
+			if errors.As(err, &tooLarge) {
+				result.Skipped++
+				result.Ignored = append(result.Ignored, scanner.Skipped{Path: file, Reason: scanner.SkipTooLarge, Error: err.Error()})
+				run.Files = append(run.Files, trace.finish(runs.OutcomeSkipped, err))
+				dp.logger.Warn("Skipped file above size limit",
+					zap.String("file", file),
+					zap.Int64("size", tooLarge.Size),
+					zap.Int64("limit", tooLarge.Limit))
+			} else if errors.As(err, &limited) {
+				result.Skipped++
+				result.Ignored = append(result.Ignored, scanner.Skipped{Path: file, Reason: scanner.SkipLimit, Error: err.Error()})
+				run.Files = append(run.Files, trace.finish(runs.OutcomeSkipped, err))
+				dp.logger.Warn("Skipped file exceeding an extraction limit",
+					zap.String("file", file),
+					zap.Error(err))
+			} else if errors.As(err, &infected) {
+				result.Skipped++
+				result.Ignored = append(result.Ignored, scanner.Skipped{Path: file, Reason: scanner.SkipMalware, Error: err.Error()})
+				run.Files = append(run.Files, trace.finish(runs.OutcomeSkipped, err))
+			} else if errors.Is(err, ErrSkipDocument) {
+				result.Skipped++
+				result.Ignored = append(result.Ignored, scanner.Skipped{Path: file, Reason: scanner.SkipFiltered, Error: err.Error()})
+				run.Files = append(run.Files, trace.finish(runs.OutcomeSkipped, err))
+				dp.logger.Info("Skipped file left out by a pipeline hook",
+					zap.String("file", file),
+					zap.Error(err))
+			} else if strings.Contains(err.Error(), "already indexed") {
+				result.Skipped++
+				run.Files = append(run.Files, trace.finish(runs.OutcomeSkipped, err))
 				dp.logger.Info("Skipped already-indexed file", zap.String("file", file))
 			} else {
-				errorCount++
+				result.Failed++
+				result.Failures = append(result.Failures, FileFailure{FilePath: file, Error: err.Error()})
+				run.Files = append(run.Files, trace.finish(runs.OutcomeFailed, err))
 				dp.logger.Error("Failed to process file",
 					zap.String("file", file),
 					zap.Error(err))
 			}
+			progress(file)
 			continue
 		}
 
-		successCount++
+		result.Processed++
+		run.Files = append(run.Files, trace.finish(runs.OutcomeProcessed, nil))
+		dp.recordChange(ctx, run, file, previous)
+		progress(file)
+	}
+	if run.Status == runs.StatusRunning {
+		run.Status = runs.StatusCompleted
 	}
 
+	added, updated := run.Counts()
+	tokens, _ := run.Usage()
 	dp.logger.Info("Directory processing complete",
-		zap.Int("total_files", len(files)),
-		zap.Int("processed", successCount),
-		zap.Int("skipped", skipCount),
-		zap.Int("errors", errorCount))
+		zap.String("run_id", run.ID),
+		zap.String("status", run.Status),
+		zap.Int("total_files", result.Total),
+		zap.Int("processed", result.Processed),
+		zap.Int("skipped", result.Skipped),
+		zap.Int("errors", result.Failed),
+		zap.Int("added", added),
+		zap.Int("updated", updated),
+		zap.Int64("prompt_tokens", tokens.Prompt),
+		zap.Int64("completion_tokens", tokens.Completion))
 
-	return nil
+	// A cancelled context must not lose the record of the run
+	recordCtx := context.WithoutCancel(ctx)
+	dp.summarizeChanges(recordCtx, run)
+	run.FinishedAt = time.Now()
+	run.Processed, run.Skipped, run.Failed = result.Processed, result.Skipped, result.Failed
+	dp.saveRun(recordCtx, run)
+	dp.publishProgress(recordCtx, run, result, "")
+	result.Changes, result.ChangeDigest = run.Changes, run.Digest
+
+	upserts := dp.pineconeClient.UpsertStats()
+	dp.logger.Info("Pinecone upsert throughput",
+		zap.Int64("vectors", upserts.Vectors),
+		zap.Int64("batches", upserts.Batches),
+		zap.Int64("throttled", upserts.Throttled),
+		zap.Float64("vectors_per_second", upserts.VectorsPerSecond),
+		zap.Int("concurrency", upserts.Concurrency))
+
+	return result, nil
 }
 
-// scanDirectory recursively scans a directory for files
-func (dp *DocumentProcessor) scanDirectory(directory string) ([]string, error) {
-	var files []string
+// scanDirectory recursively scans a directory for files. Symlinks and hard
+// links are handled according to the scan configuration.
+func (dp *DocumentProcessor) scanDirectory(directory string) (*scanner.Result, error) {
+	return scanner.Scan(utils.LocalPath(utils.NormalizePath(directory)), scanner.OptionsFromConfig(dp.config.Scan))
+}
 
-	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// processFile runs a single file through the pipeline stages
+func (dp *DocumentProcessor) processFile(ctx context.Context, filePath string, force bool) (err error) {
+	// Registry records, vector metadata and ACL rules all use the normalized path
+	doc := &Document{FilePath: utils.NormalizePath(filePath), Force: force, Options: processors.OptionsFrom(ctx)}
+	traceFrom(ctx).attempt()
+	defer func() {
+		if doc.Content != nil {
+			doc.Content.Close() //nolint:errcheck
 		}
-
-		// Skip directories and hidden files
-		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
-			return nil
+		// Failures after the scan stage starts tracking the document are
+		// recorded against it
+		if err != nil && doc.Record != nil {
+			record := doc.Record
+			reached := record.State
+			record.Error = err.Error()
+			dp.track(ctx, record, models.StateFailed)
+			err = &StageError{DocumentID: record.ID, Stage: reached, Err: err}
 		}
+	}()
 
-		files = append(files, path)
-		return nil
-	})
-
-	return files, err
+	return dp.runStages(ctx, doc, dp.fileSteps())
 }
 
-// processFile processes a single file
-//
-//nolint:gocyclo
-func (dp *DocumentProcessor) processFile(ctx context.Context, filePath string) error {
-	// Calculate file hash
-	fileHash, err := dp.calculateFileHash(filePath)
+// scanStage skips oversized and already indexed files, detects the content
+// type, starts tracking the document and scans it for malware
+func (dp *DocumentProcessor) scanStage(ctx context.Context, doc *Document) error {
+	filePath := doc.FilePath
+
+	// Skip oversized files before reading them at all
+	if err := dp.limits.CheckSize(filePath); err != nil {
+		return err
+	}
+
+	// Calculate file hash, and the hashes matching documents indexed with
+	// other algorithms
+	var err error
+	doc.FileHash, doc.OtherHashes, err = dp.calculateFileHash(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to calculate hash: %w", err)
 	}
 
 	// Check if already indexed
-	if dp.config.App.SkipExistingDocuments {
-		exists, existsErr := dp.pineconeClient.CheckDocumentExists(ctx, fileHash)
+	if dp.config.App.SkipExistingDocuments && !doc.Force {
+		exists, existsErr := dp.documentExists(ctx, doc.FileHash, doc.OtherHashes...)
 		if existsErr != nil {
 			dp.logger.Warn("Failed to check document existence", zap.Error(existsErr))
 		} else if exists {
@@ -164,155 +473,936 @@ func (dp *DocumentProcessor) processFile(ctx context.Context, filePath string) e
 		}
 	}
 
-	// Extract content
-	content, err := dp.extractContent(ctx, filePath)
+	// Route by content rather than trusting the extension alone
+	doc.Detection, err = scanner.Detect(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to extract content: %w", err)
+		return fmt.Errorf("failed to detect content type: %w", err)
+	}
+	if doc.Detection.Mismatch != "" {
+		dp.logger.Warn("File content does not match its extension",
+			zap.String("file", filePath),
+			zap.String("detected_type", doc.Detection.Extension),
+			zap.String("mismatch", doc.Detection.Mismatch))
+	}
+
+	// Generate document ID and track the document from here on
+	doc.Record = dp.newRecord(filePath, uuid.New().String(), doc.FileHash, doc.Detection)
+	doc.Record.Metadata = doc.metadata
+	if info, statErr := os.Stat(filePath); statErr == nil {
+		modified := info.ModTime().UTC()
+		doc.Record.ModifiedAt = &modified
+	}
+	dp.track(ctx, doc.Record, models.StateScanned)
+
+	// Scan for malware before any processor opens the file
+	return dp.scanForMalware(ctx, doc.Record)
+}
+
+// extractStage extracts and normalizes the content, or reuses what was
+// extracted from the same file content before; large content spills to a
+// temp file. A file without content is recorded as failed and not
+// processed further.
+func (dp *DocumentProcessor) extractStage(ctx context.Context, doc *Document) error {
+	filePath, record := doc.FilePath, doc.Record
+
+	// Content extracted with options differs from what the file is
+	// extracted as by default, so it is neither reused nor stored
+	doc.Options = dp.extractOptions(doc)
+	var content *processors.Content
+	var err error
+	if doc.Options.IsZero() {
+		content, err = dp.storedContent(ctx, doc.FileHash, doc.OtherHashes...)
+	}
+	doc.reused = content != nil
+	if !doc.reused {
+		if err != nil {
+			dp.logger.Warn("Failed to read stored content, extracting again",
+				zap.String("file", filePath),
+				zap.Error(err))
+		}
+		content, err = dp.extractContent(processors.WithOptions(ctx, doc.Options), filePath, doc.Detection)
+		if err != nil {
+			return fmt.Errorf("failed to extract content: %w", err)
+		}
+	}
+	doc.Content = content
+	if enc := content.Encoding(); enc != "" && enc != processors.EncodingUTF8 {
+		dp.logger.Info("Decoded content to UTF-8",
+			zap.String("file", filepath.Base(filePath)),
+			zap.String("encoding", enc))
 	}
 
-	if content == "" {
+	if content.Size() == 0 {
 		dp.logger.Warn("No content extracted", zap.String("file", filePath))
-		return nil
+		record.Error = "no content extracted"
+		dp.track(ctx, record, models.StateFailed)
+		return errDocumentDone
+	}
+	dp.track(ctx, record, models.StateExtracted)
+	return nil
+}
+
+// enrichStage adds what is known about the file beyond its text: note,
+// image and dataset metadata, image analysis and formula descriptions,
+// and the summary, each as the record's processing policy allows
+func (dp *DocumentProcessor) enrichStage(ctx context.Context, doc *Document) error {
+	record, content, ext := doc.Record, doc.Content, doc.Detection.Extension
+
+	if isImageType(ext) {
+		dp.describeImage(ctx, record)
+	}
+	if notes.IsMarkdown(ext) {
+		dp.readNote(record, content)
+	}
+	if datafile.IsDataFile(ext) {
+		dp.describeDataset(record)
 	}
 
-	// Analyze image if applicable
-	visualContent := ""
-	if dp.isImageFile(filePath) && dp.visionClient != nil {
-		var visionErr error
-		visualContent, visionErr = dp.visionClient.AnalyzeImage(ctx, filePath)
-		if visionErr != nil {
-			dp.logger.Warn("Failed to analyze image", zap.Error(visionErr))
+	policy := dp.policyFor(record)
+	if doc.reused {
+		// Stored content already includes any image analysis
+		record.ContentStored = true
+		dp.logger.Debug("Reusing stored content", zap.String("file", doc.FilePath))
+	} else {
+		// Analyze image if applicable
+		visualContent := ""
+		if isImageType(ext) && dp.visionClient != nil {
+			if policy.vision {
+				visualContent = dp.analyzeImage(ctx, record)
+			}
+			// Text is detected on extraction in the always OCR mode
+			if policy.ocr && doc.Options.OCRMode != processors.OCRAlways && doc.Options.OCRMode != processors.OCRNever {
+				if text := dp.detectText(ctx, record); text != "" {
+					visualContent = strings.TrimSpace(visualContent + "\n\nExtracted Text:\n" + text)
+				}
+			}
+		}
+
+		// Combine content
+		if visualContent != "" {
+			if _, err := io.WriteString(content, "\n\n"+visualContent); err != nil {
+				return fmt.Errorf("failed to append visual content: %w", err)
+			}
+		}
+		if dp.config.Math.Describe {
+			dp.describeFormulas(ctx, record, content)
+		}
+
+		// Content missing its image analysis is not stored, so the file
+		// is extracted and analyzed again when the document is repaired,
+		// nor is content extracted with options
+		if !slices.Contains(record.NeedsEnrichment, StageVision) && doc.Options.IsZero() {
+			dp.storeContent(ctx, record, content)
 		}
 	}
 
-	// Combine content
-	combinedContent := content
-	if visualContent != "" {
-		combinedContent += "\n\n" + visualContent
+	if policy.summarize {
+		summarized, err := dp.summarize(ctx, record, content)
+		if err != nil {
+			return err
+		}
+		doc.Summarized = summarized
 	}
+	dp.track(ctx, record, models.StateSummarized)
+	return nil
+}
 
-	// Generate summary
-	summary, err := dp.azureClient.GenerateSummary(ctx, combinedContent)
+// summarize sets the record's summary, generated from the start of the
+// content with the summary settings of the record's category. A failed generation is logged and leaves a placeholder, and the
+// record is flagged for repair; while summarization keeps failing it is
+// not tried. The result reports whether a summary was generated.
+func (dp *DocumentProcessor) summarize(ctx context.Context, record *registry.Record, content *processors.Content) (bool, error) {
+	summaryInput, err := content.Prefix(summaryInputBytes)
+	if err != nil {
+		return false, fmt.Errorf("failed to read content: %w", err)
+	}
+	if !dp.summaryBreaker.allow() {
+		record.Summary = summaryFailed
+		skipStage(record, StageSummary)
+		return false, nil
+	}
+	settings := dp.config.Summary.ForCategory(record.Category)
+	summary, err := dp.azureClient.GenerateSummary(ctx, summaryInput, azure.SummaryOptions{
+		MaxTokens:   settings.MaxTokens,
+		Temperature: settings.Temperature,
+		Style:       settings.Style,
+		Language:    settings.Language,
+	})
+	dp.summaryBreaker.record(err)
 	if err != nil {
 		dp.logger.Warn("Failed to generate summary", zap.Error(err))
-		summary = "Summary generation failed"
+		record.Summary = summaryFailed
+		skipStage(record, StageSummary)
+		return false, nil
+	}
+	record.Summary = summary
+	completeStage(record, StageSummary)
+	return true, nil
+}
+
+// chunkStage cuts a document's content into chunks with the chunking
+// settings of its policy. Chunks whose content is already stored, by this
+// document or another, are dropped and a reference to the stored chunk is
+// kept instead. Content above the streaming threshold is not cut here but
+// read chunk by chunk by the embed stage.
+func (dp *DocumentProcessor) chunkStage(ctx context.Context, doc *Document) error {
+	record := doc.Record
+	policy := dp.policyFor(record)
+	chunkTotal, eachChunk, err := chunkContent(doc.Content, policy.chunkSize, policy.chunkOverlap)
+	if err != nil {
+		return fmt.Errorf("failed to read content: %w", err)
+	}
+	doc.chunkTotal, doc.overlap = chunkTotal, policy.chunkOverlap
+	doc.acl = dp.resolveACL(doc.FilePath)
+	acl := doc.acl
+	record.ACL = &acl
+
+	if limit := dp.config.App.StreamChunkingBytes; limit > 0 && doc.Content.Size() > limit {
+		doc.eachChunk = eachChunk
+		dp.logger.Info("Streaming chunks of large document",
+			zap.String("file", filepath.Base(doc.FilePath)),
+			zap.Int64("bytes", doc.Content.Size()),
+			zap.Int("chunks", chunkTotal))
+		dp.track(ctx, record, models.StateChunked)
+		return nil
+	}
+
+	doc.Chunks = make([]Chunk, 0, chunkTotal)
+	seen := make(map[string]bool)
+	dedupCount := 0
+	scope := dp.dedupScope(doc)
+	err = eachChunk(func(i int, text string) error {
+		chunk := Chunk{Index: i, Text: text, ContentHash: dedup.ScopedContentHash(scope, text)}
+		if dp.dropDuplicate(ctx, doc, &chunk, seen) {
+			dedupCount++
+			return nil
+		}
+		doc.Chunks = append(doc.Chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read content: %w", err)
 	}
 
-	// Create chunks
-	chunks := dp.chunkText(combinedContent, dp.config.App.ChunkSize, dp.config.App.ChunkOverlap)
+	record.DedupedChunks = dedupCount
+	dp.track(ctx, record, models.StateChunked)
+	return nil
+}
 
-	// Generate document ID
-	docID := uuid.New().String()
+// dropDuplicate reports whether a chunk is to be dropped because its
+// content is already stored: by the document itself, or by another, which
+// then gets a reference to the document. Chunks seen are added to seen.
+func (dp *DocumentProcessor) dropDuplicate(ctx context.Context, doc *Document, chunk *Chunk, seen map[string]bool) bool {
+	if dp.dedupIndex == nil {
+		return false
+	}
+	if seen[chunk.ContentHash] {
+		return true
+	}
+	seen[chunk.ContentHash] = true
+	chunk.signature = dp.dedupIndex.Signature(chunk.Text)
+	canonicalID, dup := dp.findDuplicateChunk(ctx, doc, chunk.ContentHash, chunk.signature)
+	if !dup {
+		return false
+	}
+	// Stored by an earlier batch of a streamed document
+	if strings.HasPrefix(canonicalID, doc.Record.ID+"-") {
+		return true
+	}
+	if err := dp.addChunkReference(ctx, doc.Record.Category, canonicalID, doc); err != nil {
+		dp.logger.Warn("Failed to record chunk reference",
+			zap.String("vector_id", canonicalID),
+			zap.Error(err))
+		return false
+	}
+	return true
+}
 
-	// Process each chunk
-	vectors := make([]*pinecone.Vector, 0, len(chunks))
-	for i, chunk := range chunks {
+// embedStage embeds each chunk as one or more vectors. A chunk that fails
+// to embed is left out of the index.
+func (dp *DocumentProcessor) embedStage(ctx context.Context, doc *Document) error {
+	if doc.eachChunk != nil {
+		return dp.embedStream(ctx, doc)
+	}
+
+	record := doc.Record
+	logRef, isLog := logReference(doc.FilePath)
+
+	doc.Vectors = make([]*pinecone.Vector, 0, len(doc.Chunks))
+	doc.newEntries = make(map[string]*dedup.Entry)
+	scope := dp.dedupScope(doc)
+	for _, chunk := range doc.Chunks {
+		// A hook may have rewritten the chunk after it was hashed
+		if hash := dedup.ScopedContentHash(scope, chunk.Text); hash != chunk.ContentHash {
+			chunk.ContentHash, chunk.signature = hash, nil
+			if dp.dedupIndex != nil {
+				chunk.signature = dp.dedupIndex.Signature(chunk.Text)
+			}
+		}
+
+		chunkVectors, entry := dp.embedChunkVectors(ctx, doc, chunk, logRef, isLog)
+		if entry == nil {
+			continue
+		}
+		doc.Vectors = append(doc.Vectors, chunkVectors...)
+		doc.newEntries[chunk.ContentHash] = entry
+	}
+
+	record.ChunkCount = len(doc.Vectors)
+	if len(doc.Vectors) > 0 {
+		dp.track(ctx, record, models.StateEmbedded)
+	}
+	return nil
+}
+
+// embedStream embeds the chunks of a streamed document as they are read
+// and stores their vectors a batch of StreamBatchChunks chunks at a time,
+// so no more than one batch is held in memory. Each stored batch is added
+// to the dedup index, where later duplicates of its chunks are found. When
+// the stream fails, the batches it stored are deleted, so the document is
+// not left searchable with part of its chunks.
+func (dp *DocumentProcessor) embedStream(ctx context.Context, doc *Document) error {
+	record := doc.Record
+	logRef, isLog := logReference(doc.FilePath)
+	batchSize := dp.config.App.StreamBatchChunks
+
+	var vectors []*pinecone.Vector
+	var failed *wal.Entry
+	entries := make(map[string]*dedup.Entry)
+	seen := make(map[string]bool)
+	batched, dedupCount := 0, 0
+	flush := func() error {
+		if len(vectors) > 0 {
+			entry := &wal.Entry{DocumentID: record.ID, FilePath: record.FilePath, Category: record.Category, Vectors: vectors, Partial: true}
+			if err := dp.upsertEntry(ctx, entry); err != nil {
+				failed = entry
+				return fmt.Errorf("failed to store in Pinecone: %w", err)
+			}
+			record.ChunkCount += len(vectors)
+		}
+		if dp.dedupIndex != nil {
+			for _, entry := range entries {
+				dp.dedupIndex.Add(entry)
+			}
+		}
+		vectors, entries, seen, batched = nil, make(map[string]*dedup.Entry), make(map[string]bool), 0
+		return nil
+	}
+
+	scope := dp.dedupScope(doc)
+	err := doc.eachChunk(func(i int, text string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		chunk := Chunk{Index: i, Text: text, ContentHash: dedup.ScopedContentHash(scope, text)}
+		if dp.dropDuplicate(ctx, doc, &chunk, seen) {
+			dedupCount++
+			return nil
+		}
+		if chunkVectors, entry := dp.embedChunkVectors(ctx, doc, chunk, logRef, isLog); entry != nil {
+			vectors = append(vectors, chunkVectors...)
+			entries[chunk.ContentHash] = entry
+		}
+		if batched++; batched >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	record.DedupedChunks = dedupCount
+	if err != nil {
+		if record.ChunkCount > 0 || failed != nil {
+			dp.discardStream(ctx, record, failed)
+		}
+		return fmt.Errorf("failed to stream chunks: %w", err)
+	}
+
+	if record.ChunkCount > 0 {
+		dp.track(ctx, record, models.StateEmbedded)
+	}
+	return nil
+}
+
+// discardStream deletes the vectors a failed stream of a document stored,
+// including any of a batch whose upsert failed part way, whose log entry
+// is acknowledged so a replay does not store it again. Vectors other files
+// came to reference meanwhile are handed to them.
+func (dp *DocumentProcessor) discardStream(ctx context.Context, record *registry.Record, failed *wal.Entry) {
+	// The stream may have failed because ctx was cancelled
+	ctx = context.WithoutCancel(ctx)
+	if failed != nil && failed.ID != "" && dp.wal != nil {
+		if err := dp.wal.Ack(ctx, failed.ID); err != nil {
+			dp.logger.Warn("Failed to acknowledge write-ahead log entry",
+				zap.String("document_id", record.ID),
+				zap.Error(err))
+		}
+	}
+	deleted, err := dp.chunkClient(record.Category).DeleteByDocumentID(ctx, record.ID)
+	if err != nil {
+		dp.logger.Warn("Failed to delete vectors of a failed stream",
+			zap.String("document_id", record.ID),
+			zap.Error(err))
+		return
+	}
+	dp.logger.Info("Deleted vectors of a failed stream",
+		zap.String("document_id", record.ID),
+		zap.Int("vectors", deleted))
+	record.ChunkCount = 0
+}
+
+// embedChunkVectors brings a chunk within the embedding model's token
+// limit and embeds it. It returns the chunk's vectors and its dedup entry,
+// nil when the chunk was skipped or failed to embed.
+func (dp *DocumentProcessor) embedChunkVectors(ctx context.Context, doc *Document, chunk Chunk, logRef time.Time, isLog bool) ([]*pinecone.Vector, *dedup.Entry) {
+	inputs, overflow := dp.embeddingInputs(chunk.Index, chunk.Text)
+	if len(inputs) == 0 {
+		doc.Record.SkippedChunks = append(doc.Record.SkippedChunks, chunk.Index)
+		return nil, nil
+	}
+
+	chunkVectors := dp.embedChunk(ctx, doc, chunk, inputs, overflow, logRef, isLog)
+	if len(chunkVectors) == 0 {
+		return nil, nil
+	}
+	return chunkVectors, &dedup.Entry{
+		VectorID:    chunkVectors[0].ID,
+		DocumentID:  doc.Record.ID,
+		FilePath:    doc.FilePath,
+		ContentHash: chunk.ContentHash,
+		Scope:       dp.dedupScope(doc),
+		Signature:   chunk.signature,
+	}
+}
+
+// embedChunk embeds the inputs of a chunk and returns its vectors. A chunk
+// is only indexed when all of its parts are, so nothing is returned when
+// one fails.
+func (dp *DocumentProcessor) embedChunk(ctx context.Context, doc *Document, chunk Chunk, inputs []string, overflow string, logRef time.Time, isLog bool) []*pinecone.Vector {
+	record, detected := doc.Record, doc.Detection
+	docID, filePath, i := record.ID, record.FilePath, chunk.Index
+
+	sparseEncoder := dp.chunkSparse(record.Category)
+	chunkVectors := make([]*pinecone.Vector, 0, len(inputs))
+	for part, input := range inputs {
 		// Generate embedding
-		chunkEmbedding, embErr := dp.azureClient.GenerateEmbedding(ctx, chunk)
+		chunkEmbedding, embErr := dp.chunkEmbedder(record.Category).GenerateEmbedding(ctx, input)
 		if embErr != nil {
 			dp.logger.Error("Failed to generate embedding",
 				zap.Int("chunk", i),
 				zap.Error(embErr))
-			continue
+			return nil
+		}
+
+		// Split chunks are stored as their parts; truncated chunks keep
+		// their full text although only part of it was embedded
+		id, text := models.ChunkVectorID(docID, i), chunk.Text
+		if overflow == embedding.OverflowSplit {
+			id, text = models.ChunkPartVectorID(docID, i, part), input
 		}
 
 		// Create vector
-		vectorID := fmt.Sprintf("%s-chunk-%d", docID, i)
 		vector := &pinecone.Vector{
-			ID:     vectorID,
+			ID:     id,
 			Values: chunkEmbedding,
 			Metadata: map[string]interface{}{
-				"document_id": docID,
-				"file_name":   filepath.Base(filePath),
-				"file_path":   filePath,
-				"file_type":   filepath.Ext(filePath),
-				"file_hash":   fileHash,
-				"chunk_index": i,
-				"chunk_total": len(chunks),
-				"content":     chunk,
-				"summary":     summary,
-				"indexed_at":  time.Now().Unix(),
+				"document_id":    docID,
+				"file_name":      utils.BaseName(filePath),
+				"file_path":      filePath,
+				"file_type":      utils.Ext(filePath),
+				"file_hash":      record.FileHash,
+				"hash_algorithm": utils.HashAlgorithm(record.FileHash),
+				"chunk_index":    i,
+				"chunk_total":    doc.chunkTotal,
+				"chunk_overlap":  doc.overlap,
+				"content":        text,
+				"content_hash":   chunk.ContentHash,
+				"content_type":   detected.MimeType,
+				"token_count":    embedding.CountTokens(input),
+				"indexed_at":     time.Now().Unix(),
 			},
 		}
+		if sparseEncoder != nil {
+			if vector.SparseValues, embErr = sparseEncoder.EncodeDocument(ctx, input); embErr != nil {
+				dp.logger.Error("Failed to generate sparse vector",
+					zap.Int("chunk", i),
+					zap.Error(embErr))
+				return nil
+			}
+		}
+		if overflow != "" {
+			vector.Metadata["embedding_overflow"] = overflow
+		}
+		if overflow == embedding.OverflowSplit {
+			vector.Metadata["chunk_part"] = part
+			vector.Metadata["chunk_parts"] = len(inputs)
+		}
+		if detected.Mismatch != "" {
+			vector.Metadata["type_mismatch"] = detected.Mismatch
+		}
+		if enc := doc.Content.Encoding(); enc != "" {
+			vector.Metadata["source_encoding"] = enc
+		}
+		setACLMetadata(vector.Metadata, doc.acl)
+		setModifiedMetadata(vector.Metadata, record)
+		dp.setRouteMetadata(vector.Metadata, record.Category)
+		setImageMetadata(vector.Metadata, record.Image)
+		setNoteMetadata(vector.Metadata, record)
+		setDatasetMetadata(vector.Metadata, record.Dataset)
+		setMathMetadata(vector.Metadata, text)
+		if isLog {
+			setLogMetadata(vector.Metadata, text, logRef)
+		}
+		setCustomMetadata(vector.Metadata, record.Metadata)
+		if fitErr := dp.fitMetadata(ctx, vector, docID, i, text); fitErr != nil {
+			dp.logger.Error("Chunk metadata exceeds the vector store limit",
+				zap.Int("chunk", i),
+				zap.Error(fitErr))
+			return nil
+		}
 
-		vectors = append(vectors, vector)
+		chunkVectors = append(chunkVectors, vector)
 	}
+	return chunkVectors
+}
 
-	// Store in Pinecone
-	if len(vectors) > 0 {
-		err = dp.pineconeClient.UpsertVectors(ctx, vectors)
-		if err != nil {
-			return fmt.Errorf("failed to store in Pinecone: %w", err)
+// storeStage upserts a document's vectors and supersedes earlier versions
+// of the file. A summarized document also gets a summary vector when those
+// are enabled. A document none of whose chunks could be embedded is
+// recorded as failed.
+func (dp *DocumentProcessor) storeStage(ctx context.Context, doc *Document) error {
+	record := doc.Record
+	docID, filePath := record.ID, record.FilePath
+
+	// Store in Pinecone; the vectors of a streamed document were stored by
+	// the embed stage
+	if len(doc.Vectors) > 0 || doc.eachChunk != nil && record.ChunkCount > 0 {
+		if len(doc.Vectors) > 0 {
+			if err := dp.upsert(ctx, "", record, doc.Vectors); err != nil {
+				return fmt.Errorf("failed to store in Pinecone: %w", err)
+			}
+		}
+
+		// The summary is stored once per document: in the registry record
+		// and, when enabled, as a vector in the summary namespace
+		if ns := dp.pineconeClient.SummaryNamespace(); ns != "" && doc.Summarized {
+			if svErr := dp.indexSummary(ctx, ns, record, doc.Detection, doc.acl); svErr != nil {
+				dp.logger.Warn("Failed to index document summary",
+					zap.String("document_id", docID),
+					zap.Error(svErr))
+			}
+		}
+
+		if dp.imageIndex != nil && imagesearch.Embeddable(record.FileType) {
+			dp.indexImage(ctx, record, doc.Detection, doc.acl)
+		}
+
+		// Keep earlier versions of this file for time-travel queries
+		superseded, supErr := dp.supersedeVersions(ctx, record.Category, filePath, docID)
+		if supErr != nil {
+			dp.logger.Warn("Failed to supersede previous versions",
+				zap.String("file", filePath),
+				zap.Error(supErr))
+		} else if superseded > 0 {
+			dp.logger.Info("Superseded previous document version",
+				zap.String("file", filepath.Base(filePath)),
+				zap.Int("vectors", superseded))
+		}
+
+		if dp.dedupIndex != nil {
+			for _, entry := range doc.newEntries {
+				dp.dedupIndex.Add(entry)
+			}
 		}
 
 		dp.logger.Info("Successfully indexed file",
 			zap.String("file", filepath.Base(filePath)),
-			zap.Int("chunks", len(vectors)))
+			zap.Int("chunks", record.ChunkCount),
+			zap.Int("deduplicated_chunks", record.DedupedChunks))
+	} else if record.DedupedChunks > 0 {
+		dp.logger.Info("All chunks already stored, recorded references only",
+			zap.String("file", filepath.Base(filePath)),
+			zap.Int("deduplicated_chunks", record.DedupedChunks))
+	} else {
+		record.Error = "no chunks could be embedded"
+		dp.track(ctx, record, models.StateFailed)
+		return errDocumentDone
 	}
 
+	indexedAt := time.Now()
+	record.IndexedAt = &indexedAt
+	dp.track(ctx, record, models.StateIndexed)
 	return nil
 }
 
-// extractContent extracts content using appropriate processor
-func (dp *DocumentProcessor) extractContent(ctx context.Context, filePath string) (string, error) {
-	ext := filepath.Ext(filePath)
+// indexSummary embeds a document's summary and upserts it into the summary
+// namespace, where two-stage queries search for relevant documents. It
+// carries the same filterable metadata as the document's chunks.
+func (dp *DocumentProcessor) indexSummary(ctx context.Context, namespace string, record *registry.Record, detected scanner.Detection, acl models.ACL) error {
+	embedding, err := dp.azureClient.GenerateEmbedding(ctx, record.Summary)
+	if err != nil {
+		return fmt.Errorf("failed to embed summary: %w", err)
+	}
 
-	for _, processor := range dp.processors {
-		if processor.CanProcess(ext) {
-			return processor.Extract(ctx, filePath)
-		}
+	vector := &pinecone.Vector{
+		ID:     models.SummaryVectorID(record.ID),
+		Values: embedding,
+		Metadata: map[string]interface{}{
+			"document_id":    record.ID,
+			"file_name":      record.FileName,
+			"file_path":      record.FilePath,
+			"file_type":      record.FileType,
+			"file_hash":      record.FileHash,
+			"hash_algorithm": utils.HashAlgorithm(record.FileHash),
+			"content":        record.Summary,
+			"content_type":   detected.MimeType,
+			"indexed_at":     time.Now().Unix(),
+		},
 	}
+	setACLMetadata(vector.Metadata, acl)
+	setModifiedMetadata(vector.Metadata, record)
+	setImageMetadata(vector.Metadata, record.Image)
+	setNoteMetadata(vector.Metadata, record)
+	setDatasetMetadata(vector.Metadata, record.Dataset)
+	setCustomMetadata(vector.Metadata, record.Metadata)
+	if err := pinecone.FitMetadata(vector.Metadata, dp.config.Pinecone.MetadataLimit); err != nil {
+		return err
+	}
+	return dp.upsert(ctx, namespace, record, []*pinecone.Vector{vector})
+}
 
-	return "", fmt.Errorf("no processor found for file type: %s", ext)
+// embeddingInputs returns the texts to embed for a chunk and the overflow
+// strategy applied, which is empty when the chunk is within the embedding
+// model's token limit. Skipped chunks have no inputs.
+func (dp *DocumentProcessor) embeddingInputs(index int, chunk string) ([]string, string) {
+	limit := dp.config.Embedding.MaxTokens
+	tokens := embedding.CountTokens(chunk)
+	if tokens <= limit {
+		return []string{chunk}, ""
+	}
+
+	strategy := dp.config.Embedding.Overflow
+	inputs := embedding.FitTokens(chunk, limit, strategy)
+	dp.logger.Warn("Chunk exceeds the embedding token limit",
+		zap.Int("chunk", index),
+		zap.Int("tokens", tokens),
+		zap.Int("limit", limit),
+		zap.String("strategy", strategy),
+		zap.Int("inputs", len(inputs)))
+	return inputs, strategy
 }
 
-// isImageFile checks if file is an image
-func (dp *DocumentProcessor) isImageFile(filePath string) bool {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	imageExts := []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".svg"}
-	for _, imgExt := range imageExts {
-		if ext == imgExt {
-			return true
+// fitMetadata keeps vector metadata within the Pinecone size limit. When
+// the chunk content has to be truncated, the full text goes to the chunk
+// store if one is configured and the vector references it by chunk_id.
+func (dp *DocumentProcessor) fitMetadata(ctx context.Context, vector *pinecone.Vector, docID string, index int, chunk string) error {
+	limit := dp.config.Pinecone.MetadataLimit
+	if pinecone.MetadataSize(vector.Metadata) <= limit {
+		return nil
+	}
+
+	// Add the markers first so they are counted against the limit
+	vector.Metadata["content_truncated"] = true
+	chunkID := uuid.New()
+	if dp.chunkStore != nil {
+		vector.Metadata["chunk_id"] = chunkID.String()
+	}
+	if err := pinecone.FitMetadata(vector.Metadata, limit); err != nil {
+		return err
+	}
+
+	if dp.chunkStore == nil {
+		dp.logger.Warn("Truncated chunk content to fit vector metadata",
+			zap.String("vector_id", vector.ID),
+			zap.Int("chunk_bytes", len(chunk)))
+		return nil
+	}
+
+	documentID, err := uuid.Parse(docID)
+	if err != nil {
+		return fmt.Errorf("invalid document ID %s: %w", docID, err)
+	}
+	stored := &models.Chunk{
+		ID:         chunkID,
+		DocumentID: documentID,
+		Content:    chunk,
+		ChunkIndex: index,
+		CreatedAt:  time.Now(),
+	}
+	if err := dp.chunkStore.Create(ctx, stored); err != nil {
+		// The truncated text is still indexed
+		delete(vector.Metadata, "chunk_id")
+		dp.logger.Warn("Failed to store full chunk content",
+			zap.String("vector_id", vector.ID),
+			zap.Error(err))
+	}
+	return nil
+}
+
+// newRecord creates the registry record for a new version of a file. The
+// category follows the detected content type.
+func (dp *DocumentProcessor) newRecord(filePath, docID, fileHash string, detected scanner.Detection) *registry.Record {
+	now := time.Now()
+	return &registry.Record{
+		ID:           docID,
+		FilePath:     filePath,
+		FileName:     utils.BaseName(filePath),
+		FileType:     utils.Ext(filePath),
+		Category:     utils.GetFileCategory(detected.Extension),
+		ContentType:  detected.MimeType,
+		TypeMismatch: detected.Mismatch,
+		FileHash:     fileHash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// track moves a document to a new state in the registry. Registry errors
+// are logged and never fail processing.
+func (dp *DocumentProcessor) track(ctx context.Context, record *registry.Record, state models.ProcessingState) {
+	previous := record.State
+	record.State = state
+	record.UpdatedAt = time.Now()
+	traceFrom(ctx).reached(record, state)
+	dp.publishState(ctx, record, previous)
+	if dp.registry == nil {
+		return
+	}
+	if err := dp.registry.Put(ctx, record); err != nil {
+		dp.logger.Warn("Failed to update document registry",
+			zap.String("document_id", record.ID),
+			zap.String("state", string(state)),
+			zap.Error(err))
+	}
+}
+
+// resolveACL returns the access control for a file from the configured
+// ACL rules
+func (dp *DocumentProcessor) resolveACL(filePath string) models.ACL {
+	return ResolveACL(dp.config, filePath)
+}
+
+// ResolveACL returns the access control for a file from the most specific
+// matching ACL rule, falling back to the configured defaults
+func ResolveACL(cfg *config.Config, filePath string) models.ACL {
+	acl := models.ACL{
+		Owner:      cfg.ACL.DefaultOwner,
+		Groups:     cfg.ACL.DefaultGroups,
+		Visibility: models.Visibility(cfg.ACL.DefaultVisibility),
+	}
+
+	cleanPath := utils.NormalizePath(filePath)
+	longest := -1
+	for _, rule := range cfg.ACL.Rules {
+		prefix := utils.NormalizePath(rule.PathPrefix)
+		if !pathWithin(cleanPath, prefix) || len(prefix) <= longest {
+			continue
+		}
+		longest = len(prefix)
+		acl = models.ACL{Owner: rule.Owner, Groups: rule.Groups, Visibility: models.Visibility(rule.Visibility)}
+		if acl.Visibility == "" {
+			acl.Visibility = models.Visibility(cfg.ACL.DefaultVisibility)
 		}
 	}
-	return false
+
+	return acl
+}
+
+// RecordACL returns the access control of a registry record: the one
+// stored with it, or for records indexed before it was, the one its file
+// gets from the ACL rules
+func RecordACL(cfg *config.Config, record *registry.Record) models.ACL {
+	if record.ACL != nil {
+		return *record.ACL
+	}
+	return ResolveACL(cfg, record.FilePath)
+}
+
+// pathWithin reports whether a normalized path is prefix itself or lies
+// under it, so a rule for /docs/hr leaves /docs/hr-public alone
+func pathWithin(path, prefix string) bool {
+	if path == prefix {
+		return true
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return strings.HasPrefix(path, prefix)
+}
+
+// setACLMetadata stores access control fields in vector metadata
+func setACLMetadata(metadata map[string]interface{}, acl models.ACL) {
+	metadata["acl_visibility"] = string(acl.Visibility)
+	if acl.Owner != "" {
+		metadata["acl_owner"] = acl.Owner
+	}
+	if len(acl.Groups) > 0 {
+		metadata["acl_groups"] = acl.Groups
+	}
+}
+
+// setModifiedMetadata stores when the document's file was last modified,
+// which freshness-weighted ranking ages matches by
+func setModifiedMetadata(metadata map[string]interface{}, record *registry.Record) {
+	if record.ModifiedAt != nil {
+		metadata["modified_at"] = record.ModifiedAt.Unix()
+	}
 }
 
-// calculateFileHash calculates SHA-256 hash of file
-func (dp *DocumentProcessor) calculateFileHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+// findDuplicateChunk returns the ID of an existing vector holding the same
+// or near-identical chunk content. Vectors of earlier versions of the
+// document's own file are not duplicates: they are superseded once the
+// document is stored, taking the content out of current queries.
+func (dp *DocumentProcessor) findDuplicateChunk(ctx context.Context, doc *Document, contentHash string, signature []uint64) (string, bool) {
+	scope := dp.dedupScope(doc)
+	earlierVersion := func(entry *dedup.Entry) bool {
+		return entry.FilePath == doc.FilePath && entry.DocumentID != doc.Record.ID
+	}
+	if entry, ok := dp.dedupIndex.Lookup(contentHash, scope, signature, earlierVersion); ok {
+		return entry.VectorID, true
+	}
+
+	// Fall back to the vector store for chunks indexed by earlier runs
+	match, err := dp.chunkClient(doc.Record.Category).FindByContentHash(ctx, contentHash, doc.FilePath, doc.Record.ID)
 	if err != nil {
-		return "", err
+		dp.logger.Debug("Content hash lookup failed", zap.Error(err))
+		return "", false
 	}
-	defer file.Close()
+	if match == nil {
+		return "", false
+	}
+
+	// Keep the vector's file, so a forced reindex of that file does not
+	// find its own earlier version here
+	dp.dedupIndex.Add(&dedup.Entry{
+		VectorID:    match.ID,
+		DocumentID:  stringMetadata(match.Metadata, "document_id"),
+		FilePath:    stringMetadata(match.Metadata, "file_path"),
+		ContentHash: contentHash,
+		Scope:       scope,
+	})
+	return match.ID, true
+}
 
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
+// addChunkReference records that a document contains the content of an
+// existing vector. The vector is locked while its reference lists are read
+// and written, so documents referencing it at once all stay recorded.
+func (dp *DocumentProcessor) addChunkReference(ctx context.Context, category, vectorID string, doc *Document) error {
+	unlock, err := dp.lockVector(ctx, vectorID)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+	client := dp.chunkClient(category)
+	existing, err := client.FetchVectors(ctx, []string{vectorID})
+	if err != nil {
+		return fmt.Errorf("failed to fetch canonical vector: %w", err)
+	}
+	vector, ok := existing[vectorID]
+	if !ok {
+		return fmt.Errorf("canonical vector %s not found", vectorID)
+	}
+
+	return client.UpdateMetadata(ctx, vectorID, pinecone.AddReference(vector.Metadata, doc.FilePath, doc.FileHash, doc.Record.ID))
 }
 
-// chunkText splits text into overlapping chunks
-func (dp *DocumentProcessor) chunkText(text string, chunkSize, overlap int) []string {
-	if len(text) <= chunkSize {
-		return []string{text}
+// stringMetadata returns a string metadata value, empty when it is missing
+func stringMetadata(metadata map[string]interface{}, key string) string {
+	value, _ := metadata[key].(string)
+	return value
+}
+
+// extractContent extracts content using the processor the registry routes
+// the detected file type or MIME type to, within the configured extraction
+// limits
+func (dp *DocumentProcessor) extractContent(ctx context.Context, filePath string, detected scanner.Detection) (*processors.Content, error) {
+	processor, fileType, ok := dp.processors.Find(detected.Extension, detected.MimeType)
+	if !ok {
+		return nil, fmt.Errorf("no processor found for file type: %s", detected.Extension)
 	}
+	ctx = processors.WithFileType(ctx, fileType)
+	return processors.ExtractFile(ctx, processor, filePath, dp.limits)
+}
 
-	var chunks []string
-	start := 0
+// extractOptions narrows the extraction options requested for a document
+// to those the processor of its file honours
+func (dp *DocumentProcessor) extractOptions(doc *Document) processors.ExtractOptions {
+	processor, _, ok := dp.processors.Find(doc.Detection.Extension, doc.Detection.MimeType)
+	if !ok {
+		return processors.ExtractOptions{}
+	}
+	return doc.Options.Supported(processor)
+}
 
-	for start < len(text) {
-		end := start + chunkSize
-		if end > len(text) {
-			end = len(text)
+// storedContent returns the content extracted earlier from a file with the
+// given hash, or one of the other hashes of the same file, or nil when
+// there is none
+func (dp *DocumentProcessor) storedContent(ctx context.Context, fileHash string, otherHashes ...string) (*processors.Content, error) {
+	if dp.contentStore == nil {
+		return nil, nil
+	}
+	for _, hash := range append([]string{fileHash}, otherHashes...) {
+		r, info, err := dp.contentStore.Get(ctx, hash)
+		if errors.Is(err, contentstore.ErrNotFound) {
+			continue
 		}
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return processors.ReadContent(r, info.Encoding, dp.limits)
+	}
+	return nil, nil
+}
 
-		chunks = append(chunks, text[start:end])
+// storeContent keeps the extracted text in the content store. Failures are
+// logged and never fail processing.
+func (dp *DocumentProcessor) storeContent(ctx context.Context, record *registry.Record, content *processors.Content) {
+	if dp.contentStore == nil || content.Size() == 0 {
+		return
+	}
+	info := contentstore.Info{Size: content.Size(), Encoding: content.Encoding()}
+	if err := dp.contentStore.Put(ctx, record.FileHash, content.Reader(), info); err != nil {
+		dp.logger.Warn("Failed to store extracted content",
+			zap.String("file", record.FilePath),
+			zap.Error(err))
+		return
+	}
+	record.ContentStored = true
+}
 
-		start += chunkSize - overlap
+// isImageType checks if a file type is an image
+func isImageType(ext string) bool {
+	ext = strings.ToLower(ext)
+	imageExts := []string{".png", ".jpg", ".jpeg", ".gif", ".bmp", ".svg"}
+	for _, imgExt := range imageExts {
+		if ext == imgExt {
+			return true
+		}
 	}
+	return false
+}
 
-	return chunks
+// calculateFileHash calculates the hash of a file with the configured
+// algorithm, and its hashes with the algorithms documents are also matched
+// by, in one read. Hashes are prefixed with their algorithm, as in
+// "sha256:9f86d0...".
+func (dp *DocumentProcessor) calculateFileHash(filePath string) (string, []string, error) {
+	algorithms := []string{dp.config.Scan.HashAlgorithm}
+	for _, algorithm := range dp.config.Scan.MatchHashAlgorithms {
+		if !slices.Contains(algorithms, algorithm) {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	sums, err := utils.HashFile(utils.LocalPath(filePath), algorithms...)
+	if err != nil {
+		return "", nil, err
+	}
+	hashes := make([]string, len(sums))
+	for i, sum := range sums {
+		hashes[i] = algorithms[i] + ":" + sum
+	}
+	return hashes[0], hashes[1:], nil
 }
@@ -0,0 +1,102 @@
+// Package popularity tracks how often each document's chunks appear in
+// answers and how those answers are rated. The counts give a popularity
+// between 0 and 1 that can raise the match scores of documents users find
+// useful.
+package popularity
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// answerRetention is how long the documents of an answer are remembered,
+// for ratings of the answer to count towards them
+const answerRetention = 30 * 24 * time.Hour
+
+// Counts is the usage of a document
+type Counts struct {
+	DocumentID string    `json:"document_id"`
+	Answers    int       `json:"answers"` // answers citing the document
+	Up         int       `json:"up"`      // ratings up of those answers
+	Down       int       `json:"down"`    // ratings down of those answers
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// Popularity returns a value from 0 to 1 growing with the answers using a
+// document, reaching half at saturation answers, and scaled by the share
+// of their ratings that are up. Unrated documents count as rated half up.
+func (c Counts) Popularity(saturation int) float64 {
+	if c.Answers <= 0 {
+		return 0
+	}
+	usage := float64(c.Answers) / float64(c.Answers+max(saturation, 1))
+	approval := float64(max(c.Up, 0)+1) / float64(max(c.Up, 0)+max(c.Down, 0)+2)
+	return usage * approval
+}
+
+// Store keeps document usage
+type Store interface {
+	// RecordAnswer counts an answer citing the documents, remembering them
+	// for ratings of the answer
+	RecordAnswer(ctx context.Context, queryID string, documentIDs []string) error
+	// RecordRating adds up and down, which may be negative when a rating is
+	// replaced, to the documents of an answer
+	RecordRating(ctx context.Context, queryID string, up, down int) error
+	// Get returns the counts of the documents used, by document ID
+	Get(ctx context.Context, documentIDs []string) (map[string]Counts, error)
+	// Top returns the documents used in the most answers
+	Top(ctx context.Context, limit int) ([]Counts, error)
+	Close() error
+}
+
+// Compile-time checks that the stores implement the interface
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+// NewStore creates the usage store, beside the document registry in the
+// same backend. It returns nil when popularity is disabled.
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	if !cfg.Popularity.Enabled {
+		return nil, nil
+	}
+	switch cfg.Registry.Backend {
+	case "memory":
+		logger.Info("Document popularity enabled", zap.String("backend", "memory"), zap.Bool("boost", cfg.Popularity.Boost))
+		return NewMemoryStore(), nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Document popularity enabled", zap.String("backend", "redis"), zap.Bool("boost", cfg.Popularity.Boost))
+		return NewRedisStore(client, cfg.Registry.KeyPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown registry backend: %s", cfg.Registry.Backend)
+	}
+}
+
+// mostAnswers orders counts by answers, then most recently used, and
+// applies limit
+func mostAnswers(counts []Counts, limit int) []Counts {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Answers != counts[j].Answers {
+			return counts[i].Answers > counts[j].Answers
+		}
+		if !counts[i].LastUsedAt.Equal(counts[j].LastUsedAt) {
+			return counts[i].LastUsedAt.After(counts[j].LastUsedAt)
+		}
+		return counts[i].DocumentID < counts[j].DocumentID
+	})
+	if limit > 0 && len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts
+}
@@ -0,0 +1,269 @@
+package popularity
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// answer is the documents of an answer, remembered for its ratings
+type answer struct {
+	documentIDs []string
+	at          time.Time
+}
+
+// MemoryStore keeps document usage in process memory
+type MemoryStore struct {
+	mu      sync.Mutex
+	counts  map[string]*Counts
+	answers map[string]answer
+	added   int // answers recorded since expired ones were last dropped
+}
+
+// NewMemoryStore creates an empty in-memory usage store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counts:  make(map[string]*Counts),
+		answers: make(map[string]answer),
+	}
+}
+
+// RecordAnswer counts an answer citing the documents
+func (s *MemoryStore) RecordAnswer(_ context.Context, queryID string, documentIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, id := range documentIDs {
+		c, ok := s.counts[id]
+		if !ok {
+			c = &Counts{DocumentID: id}
+			s.counts[id] = c
+		}
+		c.Answers++
+		c.LastUsedAt = now
+	}
+	s.answers[queryID] = answer{documentIDs: append([]string(nil), documentIDs...), at: now}
+
+	// Drop expired answers now and then rather than on every call
+	if s.added++; s.added >= 1000 {
+		s.added = 0
+		cutoff := now.Add(-answerRetention)
+		for id, a := range s.answers {
+			if a.at.Before(cutoff) {
+				delete(s.answers, id)
+			}
+		}
+	}
+	return nil
+}
+
+// RecordRating adds ratings to the documents of an answer
+func (s *MemoryStore) RecordRating(_ context.Context, queryID string, up, down int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.answers[queryID]
+	if !ok {
+		return nil
+	}
+	for _, id := range a.documentIDs {
+		if c, ok := s.counts[id]; ok {
+			c.Up += up
+			c.Down += down
+		}
+	}
+	return nil
+}
+
+// Get returns the counts of the documents used
+func (s *MemoryStore) Get(_ context.Context, documentIDs []string) (map[string]Counts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]Counts, len(documentIDs))
+	for _, id := range documentIDs {
+		if c, ok := s.counts[id]; ok {
+			counts[id] = *c
+		}
+	}
+	return counts, nil
+}
+
+// Top returns the documents used in the most answers
+func (s *MemoryStore) Top(_ context.Context, limit int) ([]Counts, error) {
+	s.mu.Lock()
+	counts := make([]Counts, 0, len(s.counts))
+	for _, c := range s.counts {
+		counts = append(counts, *c)
+	}
+	s.mu.Unlock()
+	return mostAnswers(counts, limit), nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// Fields of a document in the Redis counts hash, after its ID and a colon
+const (
+	fieldAnswers = "answers"
+	fieldUp      = "up"
+	fieldDown    = "down"
+	fieldLast    = "last"
+)
+
+// RedisStore keeps document usage in a Redis hash with a field per count
+// of each document, and the documents of each answer in a set expiring
+// after answerRetention
+type RedisStore struct {
+	client    *redis.Client
+	counts    string
+	keyPrefix string
+}
+
+// NewRedisStore creates a Redis backed usage store
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	return &RedisStore{
+		client:    client,
+		counts:    keyPrefix + ":popularity",
+		keyPrefix: keyPrefix,
+	}
+}
+
+func (s *RedisStore) answerKey(queryID string) string {
+	return s.keyPrefix + ":popularity:answer:" + queryID
+}
+
+// RecordAnswer counts an answer citing the documents
+func (s *RedisStore) RecordAnswer(ctx context.Context, queryID string, documentIDs []string) error {
+	if len(documentIDs) == 0 {
+		return nil
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	members := make([]interface{}, len(documentIDs))
+	pipe := s.client.TxPipeline()
+	for i, id := range documentIDs {
+		pipe.HIncrBy(ctx, s.counts, id+":"+fieldAnswers, 1)
+		pipe.HSet(ctx, s.counts, id+":"+fieldLast, now)
+		members[i] = id
+	}
+	pipe.SAdd(ctx, s.answerKey(queryID), members...)
+	pipe.Expire(ctx, s.answerKey(queryID), answerRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record document usage: %w", err)
+	}
+	return nil
+}
+
+// RecordRating adds ratings to the documents of an answer
+func (s *RedisStore) RecordRating(ctx context.Context, queryID string, up, down int) error {
+	documentIDs, err := s.client.SMembers(ctx, s.answerKey(queryID)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read answer documents: %w", err)
+	}
+	if len(documentIDs) == 0 {
+		return nil
+	}
+	pipe := s.client.TxPipeline()
+	for _, id := range documentIDs {
+		if up != 0 {
+			pipe.HIncrBy(ctx, s.counts, id+":"+fieldUp, int64(up))
+		}
+		if down != 0 {
+			pipe.HIncrBy(ctx, s.counts, id+":"+fieldDown, int64(down))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record document ratings: %w", err)
+	}
+	return nil
+}
+
+// Get returns the counts of the documents used
+func (s *RedisStore) Get(ctx context.Context, documentIDs []string) (map[string]Counts, error) {
+	counts := make(map[string]Counts, len(documentIDs))
+	if len(documentIDs) == 0 {
+		return counts, nil
+	}
+	fields := make([]string, 0, 4*len(documentIDs))
+	for _, id := range documentIDs {
+		fields = append(fields, id+":"+fieldAnswers, id+":"+fieldUp, id+":"+fieldDown, id+":"+fieldLast)
+	}
+	values, err := s.client.HMGet(ctx, s.counts, fields...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document usage: %w", err)
+	}
+	for i, id := range documentIDs {
+		c := Counts{DocumentID: id}
+		for j, field := range []string{fieldAnswers, fieldUp, fieldDown, fieldLast} {
+			raw, ok := values[4*i+j].(string)
+			if !ok {
+				continue
+			}
+			setField(&c, field, raw)
+		}
+		if c.Answers > 0 {
+			counts[id] = c
+		}
+	}
+	return counts, nil
+}
+
+// Top returns the documents used in the most answers
+func (s *RedisStore) Top(ctx context.Context, limit int) ([]Counts, error) {
+	values, err := s.client.HGetAll(ctx, s.counts).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document usage: %w", err)
+	}
+	byID := make(map[string]*Counts)
+	for key, raw := range values {
+		i := strings.LastIndexByte(key, ':')
+		if i < 0 {
+			continue
+		}
+		id := key[:i]
+		c, ok := byID[id]
+		if !ok {
+			c = &Counts{DocumentID: id}
+			byID[id] = c
+		}
+		setField(c, key[i+1:], raw)
+	}
+	counts := make([]Counts, 0, len(byID))
+	for _, c := range byID {
+		if c.Answers > 0 {
+			counts = append(counts, *c)
+		}
+	}
+	return mostAnswers(counts, limit), nil
+}
+
+// Close closes the Redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// setField sets the count a hash field holds
+func setField(c *Counts, field, raw string) {
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return
+	}
+	switch field {
+	case fieldAnswers:
+		c.Answers = int(n)
+	case fieldUp:
+		c.Up = int(n)
+	case fieldDown:
+		c.Down = int(n)
+	case fieldLast:
+		c.LastUsedAt = time.Unix(n, 0).UTC()
+	}
+}
@@ -0,0 +1,93 @@
+package query
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/embedding"
+	"go.uber.org/zap"
+)
+
+// minTrimmedTokens is the smallest part of a source worth trimming it to;
+// below it the source is left out
+const minTrimmedTokens = 50
+
+// fitContext selects the sources of an answer within the configured
+// prompt token budget
+func (s *Service) fitContext(query *models.Query, results []*models.SearchResult) []*models.SearchResult {
+	return s.fitTokens(query, results, s.config.Retrieval.ContextTokens)
+}
+
+// fitTokens selects the sources of an answer within a prompt token budget.
+// Sources are taken by score, and the first that no longer fits is cut at
+// the last sentence end within the budget, so the prompt keeps the best
+// matches whole rather than a bit of every match. Sources past the budget
+// are left out of the prompt and of the answer's sources. A zero budget
+// keeps every source.
+func (s *Service) fitTokens(query *models.Query, results []*models.SearchResult, budget int) []*models.SearchResult {
+	if budget <= 0 || len(results) == 0 {
+		return results
+	}
+
+	ranked := make([]*models.SearchResult, len(results))
+	copy(ranked, results)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+
+	remaining := budget - embedding.CountTokens("Sources:\n\nQuestion: "+query.Text)
+	fitted := make([]*models.SearchResult, 0, len(ranked))
+	for _, r := range ranked {
+		overhead := embedding.CountTokens(fmt.Sprintf("<source id=\"%d\" file=%q>\n\n</source>\n\n", len(fitted)+1, r.FileName))
+		tokens := overhead + embedding.CountTokens(r.Content)
+		if tokens <= remaining {
+			fitted = append(fitted, r)
+			remaining -= tokens
+			continue
+		}
+		if room := remaining - overhead; room >= minTrimmedTokens {
+			if content := trimToSentence(r.Content, room); content != "" {
+				trimmed := *r
+				trimmed.Content = content
+				trimmed.Metadata = make(map[string]string, len(r.Metadata)+1)
+				maps.Copy(trimmed.Metadata, r.Metadata)
+				trimmed.Metadata["trimmed"] = "true"
+				fitted = append(fitted, &trimmed)
+			}
+		}
+		break
+	}
+
+	if omitted := len(results) - len(fitted); omitted > 0 {
+		s.logger.Debug("Left sources out of the prompt token budget",
+			zap.String("query_id", query.ID.String()),
+			zap.Int("budget", budget),
+			zap.Int("kept", len(fitted)),
+			zap.Int("omitted", omitted))
+	}
+	return fitted
+}
+
+// trimToSentence returns the longest start of text within maxTokens that
+// ends at the end of a sentence, or "" when the first sentence is too long
+func trimToSentence(text string, maxTokens int) string {
+	fits := embedding.FitTokens(text, maxTokens, embedding.OverflowTruncateTail)
+	if len(fits) == 0 {
+		return ""
+	}
+	head := fits[0]
+	for i := len(head) - 1; i >= 0; i-- {
+		switch head[i] {
+		case '.', '!', '?', '\n':
+			// A sentence ends where the text does or whitespace follows
+			if i+1 == len(text) || unicode.IsSpace(rune(text[i+1])) {
+				return strings.TrimSpace(head[:i+1])
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// comparisonSchema is the JSON schema of a comparison answer
+const comparisonSchema = `{"type":"object","properties":{"summary":{"type":"string"},"similarities":{"type":"array","items":{"type":"string"}},"differences":{"type":"array","items":{"type":"object","properties":{"aspect":{"type":"string"},"left":{"type":"string"},"right":{"type":"string"},"sources":{"type":"array","items":{"type":"integer"}}},"required":["aspect","left","right","sources"],"additionalProperties":false}}},"required":["summary","similarities","differences"],"additionalProperties":false}`
+
+// comparisonInstruction tells the model how the sources of a comparison
+// are split between the documents
+const comparisonInstruction = `Sources 1 to %d are from the left document, %s. Sources %d to %d are from the right document, %s.
+Compare the two documents on the question: summarize the comparison, list what they have in common, and list each aspect in which they differ with what each document says about it and the numbers of the sources that say so. Where a document says nothing about an aspect, say so.
+
+`
+
+// Compare answers a question comparing two documents with a structured
+// comparison. Each document is searched on its own, with the query's
+// filters and the caller's access, and gets half of the prompt token
+// budget, so neither crowds the other out of the prompt.
+func (s *Service) Compare(ctx context.Context, query *models.Query, left, right models.ComparisonTarget) (*models.Comparison, error) {
+	queryVerdict, err := s.ModerateQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	leftResults, err := s.searchTarget(ctx, query, left)
+	if err != nil {
+		return nil, err
+	}
+	rightResults, err := s.searchTarget(ctx, query, right)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &models.Comparison{
+		QueryID:      query.ID,
+		Similarities: []string{},
+		Differences:  []models.ComparisonPoint{},
+		LeftSources:  make([]models.SearchResult, 0, len(leftResults)),
+		RightSources: make([]models.SearchResult, 0, len(rightResults)),
+		AsOf:         query.AsOf,
+		Timestamp:    time.Now(),
+	}
+	for _, r := range leftResults {
+		comparison.LeftSources = append(comparison.LeftSources, *r)
+	}
+	for _, r := range rightResults {
+		comparison.RightSources = append(comparison.RightSources, *r)
+	}
+
+	switch {
+	case len(leftResults) == 0 && len(rightResults) == 0:
+		comparison.Summary = "No relevant passages found in either document."
+	case len(leftResults) == 0:
+		comparison.Summary = "No relevant passages found in the left document."
+	case len(rightResults) == 0:
+		comparison.Summary = "No relevant passages found in the right document."
+	}
+	if comparison.Summary != "" {
+		comparison.Trace = withVerdict(nil, queryVerdict)
+		return comparison, nil
+	}
+
+	results := append(append([]*models.SearchResult{}, leftResults...), rightResults...)
+	prompt, trace := s.buildPrompt(query, results)
+	comparison.Trace = withVerdict(trace, queryVerdict)
+	prompt = fmt.Sprintf(comparisonInstruction,
+		len(leftResults), targetName(left, leftResults),
+		len(leftResults)+1, len(results), targetName(right, rightResults)) + prompt
+
+	structured := *query
+	structured.Schema = json.RawMessage(comparisonSchema)
+	answer, err := s.structuredAnswer(ctx, &structured, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(answer), comparison); err != nil {
+		return nil, fmt.Errorf("failed to decode comparison: %w", err)
+	}
+	answerVerdict, err := s.moderate(ctx, models.ModerationAnswer, query, answer)
+	if err != nil {
+		return nil, err
+	}
+	comparison.Trace = withVerdict(comparison.Trace, answerVerdict)
+
+	s.logger.Info("Compared documents",
+		zap.String("query_id", query.ID.String()),
+		zap.Int("left_sources", len(leftResults)),
+		zap.Int("right_sources", len(rightResults)),
+		zap.Int("differences", len(comparison.Differences)))
+	return comparison, nil
+}
+
+// searchTarget searches one document of a comparison
+func (s *Service) searchTarget(ctx context.Context, query *models.Query, target models.ComparisonTarget) ([]*models.SearchResult, error) {
+	sub := *query
+	sub.Filter.Metadata = maps.Clone(query.Filter.Metadata)
+	if sub.Filter.Metadata == nil {
+		sub.Filter.Metadata = make(map[string]string)
+	}
+	switch {
+	case target.DocumentID != "":
+		sub.Filter.Metadata["document_id"] = target.DocumentID
+	case target.FilePath != "":
+		sub.Filter.Metadata["file_path"] = target.FilePath
+	default:
+		return nil, fmt.Errorf("comparison target needs a document_id or file_path")
+	}
+	results, err := s.SearchDocuments(ctx, &sub)
+	if err != nil {
+		return nil, err
+	}
+	return s.fitTokens(query, results, s.config.Retrieval.ContextTokens/2), nil
+}
+
+// targetName names a document of a comparison in the prompt by its path
+func targetName(target models.ComparisonTarget, results []*models.SearchResult) string {
+	if target.FilePath != "" {
+		return target.FilePath
+	}
+	if len(results) > 0 && results[0].FilePath != "" {
+		return results[0].FilePath
+	}
+	return target.DocumentID
+}
@@ -0,0 +1,72 @@
+package query
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// weighFreshness decays the scores of matches from old documents and ranks
+// the matches again. A match keeps 1 - weight of its score whatever its
+// age; the rest halves every half-life since its file was last modified,
+// or since it was indexed when the modification time is unknown. Matches
+// of unknown age keep their score. Euclidean scores are distances, so they
+// grow instead.
+func (s *Service) weighFreshness(query *models.Query, matches []*pinecone.Match) []*pinecone.Match {
+	halfLife := s.config.Retrieval.FreshnessHalfLife
+	if query.FreshnessHalfLife != nil {
+		halfLife = *query.FreshnessHalfLife
+	}
+	weight := s.config.Retrieval.FreshnessWeight
+	if halfLife <= 0 || weight == 0 || len(matches) == 0 {
+		return matches
+	}
+
+	now := time.Now()
+	distance := s.config.Pinecone.Metric == "euclidean"
+	for _, m := range matches {
+		modified, ok := metadataTime(m.Metadata, "modified_at")
+		if !ok {
+			modified, ok = metadataTime(m.Metadata, "indexed_at")
+		}
+		if !ok {
+			continue
+		}
+		days := max(now.Sub(modified).Hours()/24, 0)
+		factor := float32(1 - weight + weight*math.Exp2(-days/float64(halfLife)))
+		if distance {
+			m.Score /= factor
+		} else {
+			m.Score *= factor
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if distance {
+			return matches[i].Score < matches[j].Score
+		}
+		return matches[i].Score > matches[j].Score
+	})
+
+	s.logger.Debug("Weighted matches by freshness",
+		zap.String("query_id", query.ID.String()),
+		zap.Int("half_life_days", halfLife),
+		zap.Float64("weight", weight))
+	return matches
+}
+
+// metadataTime reads a Unix time stored in vector metadata
+func metadataTime(metadata map[string]interface{}, key string) (time.Time, bool) {
+	switch v := metadata[key].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case int64:
+		return time.Unix(v, 0), true
+	case int:
+		return time.Unix(int64(v), 0), true
+	}
+	return time.Time{}, false
+}
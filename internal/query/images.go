@@ -0,0 +1,50 @@
+package query
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// searchImages adds the images most similar to the query text to the
+// results, up to the configured number and above the minimum similarity.
+// Images already among the results through their text are not repeated.
+// The image search applies the query's filter but not the two-stage
+// document restriction, as images are found by what they show rather than
+// by their summary. Failures are logged and leave the results unchanged.
+func (s *Service) searchImages(ctx context.Context, query *models.Query, filter map[string]interface{}, results []*models.SearchResult) []*models.SearchResult {
+	if s.imageIndex == nil {
+		return results
+	}
+
+	matches, err := s.imageIndex.Search(ctx, query.Text, s.config.ImageSearch.TopK, filter)
+	if err != nil {
+		s.logger.Warn("Failed to search images", zap.Error(err))
+		return results
+	}
+
+	seen := make(map[uuid.UUID]bool, len(results))
+	for _, r := range results {
+		seen[r.DocumentID] = true
+	}
+	for _, m := range matches {
+		if float64(m.Score) < s.config.ImageSearch.MinScore {
+			continue
+		}
+		acl := aclFromMetadata(m.Metadata)
+		if !acl.Allows(query.Caller) {
+			s.logger.Warn("Dropped image match not readable by caller", zap.String("vector_id", m.ID))
+			continue
+		}
+		result := toSearchResult(m)
+		if seen[result.DocumentID] {
+			continue
+		}
+		seen[result.DocumentID] = true
+		result.Metadata["match"] = "image"
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,102 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// injectionPattern is a kind of phrase that instructs the model reading a
+// text rather than informing a person reading it
+type injectionPattern struct {
+	name   string
+	weight float64 // how surely the phrase is an injection, from 0 to 1
+	re     *regexp.Regexp
+}
+
+// injectionPatterns are the phrases prompt injections are made of
+var injectionPatterns = []injectionPattern{
+	{"ignore_instructions", 0.9, regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override|bypass)\b[^.\n]{0,40}?\b(previous|prior|above|earlier|preceding|all|your|any|system)\b[^.\n]{0,20}?\b(instructions?|prompts?|rules|directions|guidelines)\b`)},
+	{"role_marker", 0.7, regexp.MustCompile(`(?im)^\s*(#{1,3}\s*)?(system|assistant)\s*:|<\|im_start\|>|<\|system\|>|\[/?INST\]|<</?SYS>>`)},
+	{"new_instructions", 0.6, regexp.MustCompile(`(?i)\b(new|updated|real|actual|additional)\s+(instructions?|system\s+prompt|rules)\s*:`)},
+	{"reveal_prompt", 0.6, regexp.MustCompile(`(?i)\b(reveal|print|show|repeat|output|leak)\b[^.\n]{0,30}?\b(system\s+prompt|your\s+(instructions|prompt)|hidden\s+instructions)\b`)},
+	{"conceal", 0.5, regexp.MustCompile(`(?i)\b(do\s+not|don't|never)\s+(tell|mention|reveal|inform|warn)\s+(the\s+)?(user|human|reader|anyone)\b`)},
+	{"role_change", 0.5, regexp.MustCompile(`(?i)\byou\s+are\s+now\b|\bfrom\s+now\s+on,?\s+you\b|\bpretend\s+(to\s+be|you\s+are)\b`)},
+	{"addressed_to_model", 0.4, regexp.MustCompile(`(?i)\bif\s+you\s+are\s+an?\s+(ai|language\s+model|llm|assistant|chatbot)\b|\b(ai|language\s+model|llm|assistant|chatbot)s?\s+(reading|processing|summari[sz]ing)\s+this\b`)},
+	{"answer_override", 0.4, regexp.MustCompile(`(?i)\b(instead|always)\s+(answer|respond|reply|say)\b|\brespond\s+only\s+with\b`)},
+}
+
+// injectionScore scores how much a text reads like instructions to a
+// model: 0 when none of the phrases is found, approaching 1 as more and
+// stronger ones are. It returns the names of the phrases found.
+func injectionScore(text string) (float64, []string) {
+	clean := 1.0
+	var found []string
+	for _, p := range injectionPatterns {
+		if p.re.MatchString(text) {
+			clean *= 1 - p.weight
+			found = append(found, p.name)
+		}
+	}
+	return math.Round((1-clean)*100) / 100, found
+}
+
+// sanitizeInjections replaces the phrases of a text that instruct a model
+func sanitizeInjections(text string) string {
+	for _, p := range injectionPatterns {
+		text = p.re.ReplaceAllString(text, "[instruction removed]")
+	}
+	return text
+}
+
+// sourceTag matches the tags enclosing sources in the prompt
+var sourceTag = regexp.MustCompile(`(?i)<(/?sources?)\b`)
+
+// escapeSourceTags keeps a text from closing its source tag, which would
+// let what follows pass as outside the retrieved content
+func escapeSourceTags(text string) string {
+	return sourceTag.ReplaceAllString(text, "&lt;$1")
+}
+
+// buildPrompt assembles the user prompt from the question and the
+// retrieved chunks, each enclosed in a source tag the system prompt tells
+// the model to treat as data. Every chunk is scored for prompt injection;
+// those at or above the configured threshold are listed in the trace and,
+// with sanitization enabled, have their instructing phrases removed.
+func (s *Service) buildPrompt(query *models.Query, results []*models.SearchResult) (string, *models.QueryTrace) {
+	trace := &models.QueryTrace{}
+	var sb strings.Builder
+	sb.WriteString("Sources:\n\n")
+	for i, r := range results {
+		content := r.Content
+		score, patterns := injectionScore(content)
+		trace.InjectionScore = max(trace.InjectionScore, score)
+		if len(patterns) > 0 && score >= s.config.Retrieval.InjectionThreshold {
+			finding := models.InjectionFinding{
+				Source:    i + 1,
+				FilePath:  r.FilePath,
+				Score:     score,
+				Patterns:  patterns,
+				Sanitized: s.config.Retrieval.SanitizeInjections,
+			}
+			if finding.Sanitized {
+				content = sanitizeInjections(content)
+			}
+			trace.Injections = append(trace.Injections, finding)
+			s.logger.Warn("Source reads like a prompt injection",
+				zap.String("query_id", query.ID.String()),
+				zap.String("file", r.FilePath),
+				zap.Float64("score", score),
+				zap.Strings("patterns", patterns),
+				zap.Bool("sanitized", finding.Sanitized))
+		}
+		fmt.Fprintf(&sb, "<source id=\"%d\" file=%q>\n%s\n</source>\n\n", i+1, escapeSourceTags(r.FileName), escapeSourceTags(content))
+	}
+	sb.WriteString("Question: ")
+	sb.WriteString(query.Text)
+	return sb.String(), trace
+}
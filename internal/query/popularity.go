@@ -0,0 +1,100 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/popularity"
+	"go.uber.org/zap"
+)
+
+// ErrPopularityDisabled is returned when document usage is not tracked
+var ErrPopularityDisabled = errors.New("document popularity is disabled")
+
+// weighPopularity raises the scores of matches from documents often used
+// in well rated answers, by up to the popularity weight, and ranks the
+// matches again. Unused documents keep their scores. Euclidean scores are
+// distances, so they shrink instead.
+func (s *Service) weighPopularity(ctx context.Context, query *models.Query, matches []*pinecone.Match) []*pinecone.Match {
+	weight := s.config.Popularity.Weight
+	if s.popularity == nil || !s.config.Popularity.Boost || weight == 0 || len(matches) == 0 {
+		return matches
+	}
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, m := range matches {
+		if id := metadataString(m.Metadata, "document_id"); id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	counts, err := s.popularity.Get(ctx, ids)
+	if err != nil {
+		// Ranking by relevance alone beats failing the query
+		s.logger.Warn("Failed to read document popularity", zap.Error(err))
+		return matches
+	}
+	if len(counts) == 0 {
+		return matches
+	}
+
+	distance := s.config.Pinecone.Metric == "euclidean"
+	for _, m := range matches {
+		c, ok := counts[metadataString(m.Metadata, "document_id")]
+		if !ok {
+			continue
+		}
+		factor := float32(1 + weight*c.Popularity(s.config.Popularity.Saturation))
+		if distance {
+			m.Score /= factor
+		} else {
+			m.Score *= factor
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if distance {
+			return matches[i].Score < matches[j].Score
+		}
+		return matches[i].Score > matches[j].Score
+	})
+
+	s.logger.Debug("Weighted matches by popularity",
+		zap.String("query_id", query.ID.String()),
+		zap.Int("popular_documents", len(counts)),
+		zap.Float64("weight", weight))
+	return matches
+}
+
+// PopularDocument is a document with its use in answers
+type PopularDocument struct {
+	popularity.Counts
+	FilePath   string  `json:"file_path,omitempty"`
+	Popularity float64 `json:"popularity"` // 0 to 1
+}
+
+// PopularDocuments returns the documents used in the most answers, with
+// their file paths when the registry is set
+func (s *Service) PopularDocuments(ctx context.Context, limit int) ([]PopularDocument, error) {
+	if s.popularity == nil {
+		return nil, ErrPopularityDisabled
+	}
+	top, err := s.popularity.Top(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	documents := make([]PopularDocument, 0, len(top))
+	for _, c := range top {
+		doc := PopularDocument{Counts: c, Popularity: c.Popularity(s.config.Popularity.Saturation)}
+		if s.registry != nil {
+			if record, err := s.registry.Get(ctx, c.DocumentID); err == nil {
+				doc.FilePath = record.FilePath
+			}
+		}
+		documents = append(documents, doc)
+	}
+	return documents, nil
+}
@@ -0,0 +1,68 @@
+package query
+
+import (
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// Profile returns the retrieval profile of a name, and false when no such
+// profile is configured
+func (s *Service) Profile(name string) (config.RetrievalProfile, bool) {
+	profile, ok := s.config.Retrieval.Profiles[name]
+	return profile, ok
+}
+
+// dropIrrelevant removes the matches scoring below the query's min_score
+// or farther from it than its max_distance, so weak matches are not put in
+// the prompt just to fill top_k. How a score converts to a distance depends
+// on the index metric; dotproduct scores have no distance.
+func (s *Service) dropIrrelevant(query *models.Query, matches []*pinecone.Match) []*pinecone.Match {
+	minScore := s.config.Retrieval.MinScore
+	if query.MinScore != nil {
+		minScore = *query.MinScore
+	}
+	maxDistance := s.config.Retrieval.MaxDistance
+	if query.MaxDistance != nil {
+		maxDistance = *query.MaxDistance
+	}
+	if minScore == 0 && maxDistance == 0 {
+		return matches
+	}
+
+	kept := matches[:0]
+	for _, m := range matches {
+		score := float64(m.Score)
+		if minScore != 0 && score < minScore {
+			continue
+		}
+		if maxDistance != 0 {
+			distance, ok := s.distance(score)
+			if ok && distance > maxDistance {
+				continue
+			}
+		}
+		kept = append(kept, m)
+	}
+	if dropped := len(matches) - len(kept); dropped > 0 {
+		s.logger.Debug("Dropped matches below the relevance threshold",
+			zap.String("query_id", query.ID.String()),
+			zap.Int("dropped", dropped),
+			zap.Float64("min_score", minScore),
+			zap.Float64("max_distance", maxDistance))
+	}
+	return kept
+}
+
+// distance converts a match score to its distance from the query
+func (s *Service) distance(score float64) (float64, bool) {
+	switch s.config.Pinecone.Metric {
+	case "euclidean":
+		return score, true
+	case "dotproduct":
+		return 0, false
+	default:
+		return 1 - score, true
+	}
+}
@@ -0,0 +1,195 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/azure"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// planSystemPrompt asks the chat model to break a question into
+// sub-questions
+const planSystemPrompt = `You plan research in a document knowledge base. Break the user's question into at most %d self-contained sub-questions, each answerable by searching the documents, in the order they should be answered. A simple question needs only one sub-question. Reply with JSON: your reasoning in one or two sentences, and the sub-questions.`
+
+// planSchema is the JSON schema of a research plan
+const planSchema = `{"type":"object","properties":{"reasoning":{"type":"string"},"sub_questions":{"type":"array","items":{"type":"string"}}},"required":["reasoning","sub_questions"],"additionalProperties":false}`
+
+// agentInstruction replaces the citation rules of answers in agent mode
+const agentInstruction = "Answer each sub-question in turn under a heading with the sub-question, citing the sources it rests on by id in square brackets, such as [1] or [1, 3]. Then answer the question itself in a closing section."
+
+// researchCompletionTokens is kept from the token budget for the answer
+const researchCompletionTokens = 1000
+
+// research answers a question in agent mode: the chat model breaks it into
+// sub-questions, each is retrieved for with the query's filters and access,
+// and the answer takes them in turn, citing the sources of each. The model
+// tokens of the plan, the retrieval and the answer are bounded by the
+// configured budget; sub-questions past it are not retrieved for and the
+// sources are cut to what the rest of it allows.
+func (s *Service) research(ctx context.Context, query *models.Query, queryVerdict *models.ModerationVerdict) (*models.QueryResult, error) {
+	meter := &azure.TokenMeter{}
+	ctx = azure.WithTokenMeter(ctx, meter)
+	spent := func() int {
+		usage := meter.Usage()
+		return int(usage.PromptTokens + usage.CompletionTokens)
+	}
+
+	agent := &models.AgentTrace{}
+	var questions []string
+	questions, agent.Plan = s.planResearch(ctx, query)
+
+	var results []*models.SearchResult
+	seen := make(map[uuid.UUID]bool)
+	stepChunks := make([][]uuid.UUID, len(questions))
+	for i, question := range questions {
+		step := models.AgentStep{Question: question, Sources: []int{}}
+		if spent()+researchCompletionTokens >= s.config.Agent.MaxTokens {
+			step.Skipped = true
+			agent.Stopped = "token budget reached"
+			agent.Steps = append(agent.Steps, step)
+			continue
+		}
+		sub := *query
+		sub.Text = question
+		sub.Mode = ""
+		found, err := s.SearchDocuments(ctx, &sub)
+		if err != nil {
+			return nil, fmt.Errorf("failed to research %q: %w", question, err)
+		}
+		for _, r := range found {
+			stepChunks[i] = append(stepChunks[i], r.ChunkID)
+			if !seen[r.ChunkID] {
+				seen[r.ChunkID] = true
+				results = append(results, r)
+			}
+		}
+		agent.Steps = append(agent.Steps, step)
+	}
+
+	budget := max(s.config.Agent.MaxTokens-spent()-researchCompletionTokens, 1)
+	if s.config.Retrieval.ContextTokens > 0 {
+		budget = min(budget, s.config.Retrieval.ContextTokens)
+	}
+	results = s.fitTokens(query, results, budget)
+	number := make(map[uuid.UUID]int, len(results))
+	for i, r := range results {
+		number[r.ChunkID] = i + 1
+	}
+	for i := range agent.Steps {
+		for _, id := range stepChunks[i] {
+			if n, ok := number[id]; ok {
+				agent.Steps[i].Sources = append(agent.Steps[i].Sources, n)
+			}
+		}
+	}
+
+	result := &models.QueryResult{
+		QueryID:   query.ID,
+		Sources:   make([]models.SearchResult, 0, len(results)),
+		AsOf:      query.AsOf,
+		Timestamp: time.Now(),
+	}
+	for _, r := range results {
+		result.Sources = append(result.Sources, *r)
+	}
+	if len(results) == 0 {
+		result.Answer = "No relevant documents found."
+		agent.Tokens = int64(spent())
+		result.Trace = withVerdict(&models.QueryTrace{Agent: agent}, queryVerdict)
+		return result, nil
+	}
+
+	prompt, trace := s.buildPrompt(query, results)
+	result.Trace = withVerdict(trace, queryVerdict)
+	systemPrompt := strings.Replace(answerSystemPrompt, "Cite sources by their file name.", agentInstruction, 1)
+	answer, err := s.azureClient.ChatCompletion(ctx, systemPrompt, researchPrompt(agent.Steps)+prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+	answer, checks := s.verifyCitations(ctx, query, answer, results, true)
+	if checks != nil {
+		result.Trace.Citations = checks
+		result.Faithfulness = models.Faithfulness(checks)
+	}
+	answerVerdict, err := s.moderate(ctx, models.ModerationAnswer, query, answer)
+	if err != nil {
+		return nil, err
+	}
+	result.Answer = answer
+	agent.Tokens = int64(spent())
+	result.Trace.Agent = agent
+	result.Trace = withVerdict(result.Trace, answerVerdict)
+
+	s.logger.Info("Researched question",
+		zap.String("query_id", query.ID.String()),
+		zap.Int("steps", len(agent.Steps)),
+		zap.Int("sources", len(results)),
+		zap.Int64("tokens", agent.Tokens))
+	return result, nil
+}
+
+// planResearch asks the chat model for the sub-questions of a question and
+// its reasoning. A plan that fails or has no sub-questions researches the
+// question itself.
+func (s *Service) planResearch(ctx context.Context, query *models.Query) ([]string, string) {
+	messages := []azure.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf(planSystemPrompt, s.config.Agent.MaxSteps)},
+		{Role: "user", Content: query.Text},
+	}
+	reply, err := s.azureClient.ChatCompletionJSON(ctx, messages, "research_plan", json.RawMessage(planSchema))
+	var plan struct {
+		Reasoning    string   `json:"reasoning"`
+		SubQuestions []string `json:"sub_questions"`
+	}
+	if err == nil {
+		err = json.Unmarshal([]byte(strings.TrimSpace(reply)), &plan)
+	}
+	if err != nil {
+		s.logger.Warn("Failed to plan research, researching the question as asked",
+			zap.String("query_id", query.ID.String()),
+			zap.Error(err))
+		return []string{query.Text}, ""
+	}
+
+	var questions []string
+	for _, q := range plan.SubQuestions {
+		if q = strings.TrimSpace(q); q != "" && len(questions) < s.config.Agent.MaxSteps {
+			questions = append(questions, q)
+		}
+	}
+	if len(questions) == 0 {
+		questions = []string{query.Text}
+	}
+	return questions, plan.Reasoning
+}
+
+// researchPrompt lists the sub-questions and their sources above the
+// sources of the answer prompt
+func researchPrompt(steps []models.AgentStep) string {
+	var sb strings.Builder
+	sb.WriteString("Sub-questions:\n")
+	for i, step := range steps {
+		fmt.Fprintf(&sb, "%d. %s", i+1, step.Question)
+		switch {
+		case step.Skipped:
+			sb.WriteString(" (not researched)")
+		case len(step.Sources) > 0:
+			ids := make([]string, len(step.Sources))
+			for j, n := range step.Sources {
+				ids[j] = fmt.Sprint(n)
+			}
+			fmt.Fprintf(&sb, " (sources %s)", strings.Join(ids, ", "))
+		default:
+			sb.WriteString(" (no sources found)")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
@@ -0,0 +1,102 @@
+package query
+
+import (
+	"context"
+	"sort"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/sparse"
+	"go.uber.org/zap"
+)
+
+// queryChunks returns the chunk vectors most similar to the query: from the
+// Pinecone index and, with category routing, from every route, merged by
+// score and cut to the query's top_k. A route embedded by a deployment of
+// its own embeds the query text again. A route that fails is logged and
+// left out, so one unreachable index does not fail every search. With a
+// sparse encoder the Pinecone index and the routes in it are searched with
+// hybrid queries.
+func (s *Service) queryChunks(ctx context.Context, query *models.Query, embedding []float32, filter map[string]interface{}) ([]*pinecone.Match, error) {
+	sparseQuery := s.sparseQuery(ctx, query)
+	matches, err := s.queryIndex(ctx, s.pineconeClient, query, embedding, sparseQuery, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := s.router.Routes()
+	if len(routes) == 0 {
+		return matches, nil
+	}
+	for _, route := range routes {
+		values := embedding
+		if route.Embedder != nil {
+			values, err = route.Embedder.GenerateEmbedding(ctx, query.Text)
+			if err != nil {
+				s.logger.Warn("Failed to embed query for routed category",
+					zap.String("category", route.Category),
+					zap.Error(err))
+				continue
+			}
+		}
+		routeSparse := sparseQuery
+		if !route.SameIndex {
+			routeSparse = nil
+		}
+		routeMatches, err := s.queryIndex(ctx, route.Pinecone, query, values, routeSparse, filter)
+		if err != nil {
+			s.logger.Warn("Failed to query routed category",
+				zap.String("category", route.Category),
+				zap.Error(err))
+			continue
+		}
+		matches = append(matches, routeMatches...)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > query.TopK {
+		matches = matches[:query.TopK]
+	}
+	return matches, nil
+}
+
+// queryIndex searches the chunk namespace of a client, with a hybrid query
+// weighted by the query's alpha when there is a sparse vector
+func (s *Service) queryIndex(ctx context.Context, client *pinecone.PineconeClient, query *models.Query, embedding []float32, sv *pinecone.SparseValues, filter map[string]interface{}) ([]*pinecone.Match, error) {
+	if sv == nil {
+		return client.QueryVectors(ctx, embedding, query.TopK, filter)
+	}
+	alpha := s.config.Sparse.Alpha
+	if query.Alpha != nil {
+		alpha = *query.Alpha
+	}
+	dense, weighted := sparse.Weigh(embedding, sv, alpha)
+	return client.QueryHybrid(ctx, dense, weighted, query.TopK, filter)
+}
+
+// sparseQuery encodes the query text as a sparse vector. It returns nil
+// without a sparse encoder, for text without terms, and on failure, which
+// is logged and leaves the search dense only.
+func (s *Service) sparseQuery(ctx context.Context, query *models.Query) *pinecone.SparseValues {
+	if s.sparseEncoder == nil {
+		return nil
+	}
+	sv, err := s.sparseEncoder.EncodeQuery(ctx, query.Text)
+	if err != nil {
+		s.logger.Warn("Failed to encode sparse query vector", zap.Error(err))
+		return nil
+	}
+	return sv
+}
+
+// chunkClient returns the Pinecone client holding the chunk vectors of a
+// routed category, or of the Pinecone index when the category is empty or
+// no longer routed
+func (s *Service) chunkClient(category string) *pinecone.PineconeClient {
+	if route := s.router.Route(category); route != nil {
+		return route.Pinecone
+	}
+	return s.pineconeClient
+}
@@ -0,0 +1,189 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+// ErrDocumentNotFound is returned when a document has no chunks the caller
+// may read
+var ErrDocumentNotFound = errors.New("document not found")
+
+// Ways of finding similar documents
+const (
+	SimilarMean   = "mean"   // search with the mean of the document's chunk vectors
+	SimilarSample = "sample" // search with sampled chunk vectors one by one and average the scores
+)
+
+const (
+	// maxSampledChunks caps the chunk vectors of a document read to find
+	// documents like it, taken evenly across the document
+	maxSampledChunks = 32
+	// maxSampleQueries caps the searches of the sample method
+	maxSampleQueries = 8
+	// similarMatchesPerDocument is how many chunk matches are asked for per
+	// document wanted, as several chunks of one document tend to match
+	similarMatchesPerDocument = 5
+)
+
+// SimilarDocuments returns the documents most like a document, best first,
+// from its chunk vectors: either their mean, which finds documents on the
+// same subject as a whole, or a sample of them searched one by one, which
+// also finds documents sharing only a part of it. Only the current versions
+// of documents the caller may read are considered, in the document's own
+// index.
+func (s *Service) SimilarDocuments(ctx context.Context, caller *models.Identity, documentID, method string, limit int) ([]models.SimilarDocument, error) {
+	client, count := s.pineconeClient, maxSampledChunks
+	if s.registry != nil {
+		record, err := s.registry.Get(ctx, documentID)
+		if errors.Is(err, registry.ErrNotFound) {
+			return nil, ErrDocumentNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up document: %w", err)
+		}
+		client, count = s.chunkClient(record.Category), record.ChunkCount
+	}
+
+	vectors, err := s.sampleChunks(ctx, client, caller, documentID, count)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 0 {
+		return nil, ErrDocumentNotFound
+	}
+
+	query := &models.Query{Caller: caller}
+	filter := map[string]interface{}{"$and": []interface{}{
+		BuildFilter(query),
+		map[string]interface{}{"document_id": map[string]interface{}{"$ne": documentID}},
+	}}
+	topK := min(limit*similarMatchesPerDocument, 100)
+
+	var searches [][]float32
+	switch method {
+	case SimilarSample:
+		step := max(len(vectors)/maxSampleQueries, 1)
+		for i := 0; i < len(vectors) && len(searches) < maxSampleQueries; i += step {
+			searches = append(searches, vectors[i])
+		}
+	default:
+		searches = [][]float32{meanVector(vectors)}
+	}
+
+	// A document scores the mean over the searches of its best chunk
+	// match, so with the sample method documents like more of the document
+	// rank higher than those like one part of it
+	byDocument := make(map[string]*models.SimilarDocument)
+	for _, values := range searches {
+		matches, err := client.QueryVectors(ctx, values, topK, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search similar chunks: %w", err)
+		}
+		best := make(map[string]float32)
+		for _, m := range matches {
+			id := metadataString(m.Metadata, "document_id")
+			if id == "" {
+				continue
+			}
+			if _, ok := byDocument[id]; !ok {
+				byDocument[id] = &models.SimilarDocument{
+					DocumentID: id,
+					FileName:   metadataString(m.Metadata, "file_name"),
+					FilePath:   metadataString(m.Metadata, "file_path"),
+				}
+			}
+			byDocument[id].Matches++
+			best[id] = max(best[id], m.Score)
+		}
+		for id, score := range best {
+			byDocument[id].Score += score / float32(len(searches))
+		}
+	}
+
+	similar := make([]models.SimilarDocument, 0, len(byDocument))
+	for _, doc := range byDocument {
+		similar = append(similar, *doc)
+	}
+	sort.SliceStable(similar, func(i, j int) bool {
+		if similar[i].Score != similar[j].Score {
+			return similar[i].Score > similar[j].Score
+		}
+		return similar[i].FilePath < similar[j].FilePath
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+
+	s.logger.Debug("Found similar documents",
+		zap.String("document_id", documentID),
+		zap.String("method", method),
+		zap.Int("chunks", len(vectors)),
+		zap.Int("found", len(similar)))
+	return similar, nil
+}
+
+// sampleChunks fetches up to maxSampledChunks chunk vectors of a document,
+// spread evenly over its chunks, leaving out those the caller may not read
+func (s *Service) sampleChunks(ctx context.Context, client *pinecone.PineconeClient, caller *models.Identity, documentID string, count int) ([][]float32, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+	step := max(count/maxSampledChunks, 1)
+	ids := make([]string, 0, min(count, maxSampledChunks))
+	for i := 0; i < count && len(ids) < maxSampledChunks; i += step {
+		ids = append(ids, models.ChunkVectorID(documentID, i))
+	}
+	fetched, err := client.FetchVectors(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chunk vectors: %w", err)
+	}
+
+	vectors := make([][]float32, 0, len(fetched))
+	for _, id := range ids {
+		v, ok := fetched[id]
+		if !ok || v == nil || len(v.Values) == 0 {
+			continue
+		}
+		if acl := aclFromMetadata(v.Metadata); !acl.Allows(caller) {
+			continue
+		}
+		if _, superseded := v.Metadata["superseded_at"]; superseded {
+			continue
+		}
+		vectors = append(vectors, v.Values)
+	}
+	return vectors, nil
+}
+
+// meanVector returns the normalized mean of vectors
+func meanVector(vectors [][]float32) []float32 {
+	mean := make([]float32, len(vectors[0]))
+	for _, v := range vectors {
+		for i := range mean {
+			if i < len(v) {
+				mean[i] += v[i]
+			}
+		}
+	}
+	var norm float64
+	for _, x := range mean {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return mean
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range mean {
+		mean[i] *= scale
+	}
+	return mean
+}
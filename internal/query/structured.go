@@ -0,0 +1,59 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/azure"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/jsonschema"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidAnswer is returned when the answer of a json_schema query still
+// does not conform to its schema after the repair attempts
+var ErrInvalidAnswer = errors.New("answer does not conform to the schema")
+
+// structuredInstruction replaces the citation rules of prose answers for
+// answers in JSON
+const structuredInstruction = "Reply only with JSON conforming to the requested schema, filled in from the sources. Where the sources do not say, use null or an empty list."
+
+// structuredAnswer generates the JSON answer of a json_schema query. A
+// reply that does not conform to the schema is sent back to the model with
+// its violations, up to the configured number of repair attempts.
+func (s *Service) structuredAnswer(ctx context.Context, query *models.Query, prompt string) (string, error) {
+	schema, err := jsonschema.Parse(query.Schema)
+	if err != nil {
+		return "", fmt.Errorf("invalid schema: %w", err)
+	}
+
+	systemPrompt := strings.Replace(answerSystemPrompt, "Cite sources by their file name.", structuredInstruction, 1)
+	messages := []azure.ChatMessage{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: prompt},
+	}
+	for attempt := 0; ; attempt++ {
+		reply, err := s.azureClient.ChatCompletionJSON(ctx, messages, "answer", query.Schema)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate answer: %w", err)
+		}
+		reply = strings.TrimSpace(reply)
+		invalid := schema.Validate([]byte(reply))
+		if invalid == nil {
+			return reply, nil
+		}
+		if attempt >= s.config.StructuredOutput.RepairRetries {
+			return "", fmt.Errorf("%w: %v", ErrInvalidAnswer, invalid)
+		}
+
+		s.logger.Debug("Repairing answer not conforming to its schema",
+			zap.String("query_id", query.ID.String()),
+			zap.Int("attempt", attempt+1),
+			zap.Error(invalid))
+		messages = append(messages,
+			azure.ChatMessage{Role: "assistant", Content: reply},
+			azure.ChatMessage{Role: "user", Content: fmt.Sprintf("The reply does not conform to the schema: %v. Reply again with the corrected JSON only.", invalid)})
+	}
+}
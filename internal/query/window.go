@@ -0,0 +1,166 @@
+package query
+
+import (
+	"context"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"go.uber.org/zap"
+)
+
+// chunkSpan is a range of chunk indexes of one document, inclusive
+type chunkSpan struct {
+	first, last int
+}
+
+func (s chunkSpan) contains(index int) bool {
+	return index >= s.first && index <= s.last
+}
+
+// contextWindow returns how many neighbouring chunks are added on each side
+// of a matching chunk
+func (s *Service) contextWindow(query *models.Query) int {
+	if query.ContextWindow != nil {
+		return *query.ContextWindow
+	}
+	return s.config.Retrieval.ContextWindow
+}
+
+// expandContext replaces the content of each result with a window of the
+// chunks around it in its document, so answers are not based on sentences
+// cut at chunk boundaries. Results are in score order; a match that falls
+// inside the window of a better match of the same document is dropped, as
+// its text is already part of that window. On errors results are returned
+// unchanged.
+func (s *Service) expandContext(ctx context.Context, query *models.Query, results []*models.SearchResult) []*models.SearchResult {
+	window := s.contextWindow(query)
+	if window <= 0 || len(results) == 0 {
+		return results
+	}
+
+	spans := make([]chunkSpan, len(results))
+	covered := make(map[uuid.UUID][]chunkSpan)
+	keep := make([]bool, len(results))
+	ids := make(map[string][]string) // by the category routing the vectors
+	for i, r := range results {
+		index, err := strconv.Atoi(r.Metadata["chunk_index"])
+		if err != nil || r.DocumentID == uuid.Nil {
+			keep[i] = true
+			continue
+		}
+		if spansContain(covered[r.DocumentID], index) {
+			continue
+		}
+
+		span := chunkSpan{first: index - window, last: index + window}
+		if span.first < 0 {
+			span.first = 0
+		}
+		spans[i] = span
+		covered[r.DocumentID] = append(covered[r.DocumentID], span)
+		keep[i] = true
+		for n := span.first; n <= span.last; n++ {
+			if n != index {
+				ids[r.Metadata["category"]] = append(ids[r.Metadata["category"]], models.ChunkVectorID(r.DocumentID.String(), n))
+			}
+		}
+	}
+
+	vectors := make(map[string]*pinecone.Vector)
+	for category, categoryIDs := range ids {
+		fetched, err := s.chunkClient(category).FetchVectors(ctx, categoryIDs)
+		if err != nil {
+			s.logger.Warn("Failed to fetch neighbouring chunks", zap.Error(err))
+			return results
+		}
+		maps.Copy(vectors, fetched)
+	}
+
+	expanded := make([]*models.SearchResult, 0, len(results))
+	for i, r := range results {
+		if !keep[i] {
+			continue
+		}
+		if spans[i] != (chunkSpan{}) {
+			s.fillWindow(ctx, query, r, spans[i], vectors)
+		}
+		expanded = append(expanded, r)
+	}
+	return expanded
+}
+
+// fillWindow sets a result's content to the chunks of span that exist and
+// are readable by the caller, and records the chunk range used
+func (s *Service) fillWindow(ctx context.Context, query *models.Query, result *models.SearchResult, span chunkSpan, vectors map[string]*pinecone.Vector) {
+	index, _ := strconv.Atoi(result.Metadata["chunk_index"]) //nolint:errcheck // checked by expandContext
+
+	var parts []windowPart
+	for n := span.first; n <= span.last; n++ {
+		if n == index {
+			parts = append(parts, windowPart{index: n, text: result.Content})
+			continue
+		}
+		v, ok := vectors[models.ChunkVectorID(result.DocumentID.String(), n)]
+		if !ok || v == nil {
+			continue
+		}
+		acl := aclFromMetadata(v.Metadata)
+		if !acl.Allows(query.Caller) {
+			continue
+		}
+		neighbour := toSearchResult(&pinecone.Match{ID: v.ID, Metadata: v.Metadata})
+		s.restoreContent(ctx, neighbour, v.Metadata)
+		parts = append(parts, windowPart{index: n, text: neighbour.Content})
+	}
+
+	// Documents chunked under a processing policy record their own overlap
+	overlap := s.config.App.ChunkOverlap
+	if n, err := strconv.Atoi(result.Metadata["chunk_overlap"]); err == nil {
+		overlap = n
+	}
+	result.Content = joinChunks(parts, overlap)
+	result.Metadata["context_start"] = strconv.Itoa(parts[0].index)
+	result.Metadata["context_end"] = strconv.Itoa(parts[len(parts)-1].index)
+}
+
+// windowPart is the text of one chunk in a context window
+type windowPart struct {
+	index int
+	text  string
+}
+
+// joinChunks concatenates chunks in index order. Consecutive chunks repeat
+// the last overlap bytes of the chunk before them, which are dropped; chunks
+// separated by a gap, or whose overlap does not line up, are joined with a
+// blank line.
+func joinChunks(parts []windowPart, overlap int) string {
+	var sb strings.Builder
+	for i, part := range parts {
+		text := part.text
+		if i > 0 {
+			prev := parts[i-1]
+			if part.index == prev.index+1 && overlap > 0 && len(text) >= overlap &&
+				strings.HasSuffix(prev.text, text[:overlap]) {
+				text = text[overlap:]
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(text)
+	}
+	return sb.String()
+}
+
+// spansContain reports whether any span contains the chunk index
+func spansContain(spans []chunkSpan, index int) bool {
+	for _, span := range spans {
+		if span.contains(index) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+// Package quota limits the files each tenant may queue for indexing per
+// UTC day. Usage is counted in Redis shared by the replicas when the
+// registry is, and otherwise in memory, where it starts over when the
+// service restarts.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// contextKeyCharge is the gin context key of the quota a request's files
+// are charged to
+const contextKeyCharge = "quota_charge"
+
+// headerTenant names the tenant of a request. The gateway sets it from the
+// client its API key belongs to, dropping the one the caller sent.
+const headerTenant = "X-Tenant-ID"
+
+// Usage is what a tenant queued in a day
+type Usage struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// ExceededError is returned by Charge when the files of a request do not
+// fit in what is left of the tenant's quota for the day
+type ExceededError struct {
+	Tenant    string
+	Quota     config.IngestQuota
+	Used      Usage
+	Requested Usage
+	ResetsAt  time.Time
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("daily ingestion quota of tenant %q exceeded", e.Tenant)
+}
+
+// Respond answers 429 with the quota, the usage and when it resets
+func (e *ExceededError) Respond(ctx *gin.Context) {
+	ctx.Header("Retry-After", strconv.Itoa(int(time.Until(e.ResetsAt).Seconds())+1))
+	ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":     "daily ingestion quota exceeded",
+		"tenant":    e.Tenant,
+		"limit":     gin.H{"daily_files": e.Quota.DailyFiles, "daily_bytes": e.Quota.DailyBytes},
+		"used":      e.Used,
+		"requested": e.Requested,
+		"resets_at": e.ResetsAt.Format(time.RFC3339),
+	})
+}
+
+// Tracker counts the files each tenant queued today against its quota
+type Tracker struct {
+	quota   config.IngestQuota
+	tenants map[string]config.IngestQuota
+	store   Store
+	logger  *zap.Logger
+}
+
+// New creates a tracker of the configured quotas, counting usage in the
+// registry's backend. It returns nil when no tenant has a quota.
+func New(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*Tracker, error) {
+	c := cfg.Ingest
+	quota := config.IngestQuota{DailyFiles: c.DailyFiles, DailyBytes: c.DailyBytes}
+	if quota == (config.IngestQuota{}) && len(c.Tenants) == 0 {
+		return nil, nil
+	}
+
+	var store Store
+	switch cfg.Registry.Backend {
+	case "memory":
+		store = NewMemoryStore()
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		store = NewRedisStore(client, cfg.Registry.KeyPrefix)
+	default:
+		return nil, fmt.Errorf("unknown registry backend: %s", cfg.Registry.Backend)
+	}
+
+	logger.Info("Ingestion quotas enabled",
+		zap.String("backend", cfg.Registry.Backend),
+		zap.Int("daily_files", quota.DailyFiles),
+		zap.Int64("daily_bytes", quota.DailyBytes),
+		zap.Int("tenant_quotas", len(c.Tenants)))
+	return NewTracker(quota, c.Tenants, store, logger), nil
+}
+
+// NewTracker creates a tracker of a default quota and per-tenant quotas
+// counting usage in store
+func NewTracker(quota config.IngestQuota, tenants map[string]config.IngestQuota, store Store, logger *zap.Logger) *Tracker {
+	return &Tracker{quota: quota, tenants: tenants, store: store, logger: logger}
+}
+
+// Close closes the usage store
+func (t *Tracker) Close() error {
+	if t == nil {
+		return nil
+	}
+	return t.store.Close()
+}
+
+// Quota returns the quota of a tenant and whether the tenant may ingest.
+// With per-tenant quotas configured only the tenants listed may; otherwise
+// every tenant gets the default quota.
+func (t *Tracker) Quota(tenant string) (config.IngestQuota, bool) {
+	if q, ok := t.tenants[tenant]; ok {
+		return q, true
+	}
+	return t.quota, len(t.tenants) == 0
+}
+
+// today returns the start of the current UTC day
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// exceeds reports whether a usage is over a quota; at the quota is not
+func exceeds(u Usage, q config.IngestQuota) bool {
+	return q.DailyFiles > 0 && u.Files > q.DailyFiles || q.DailyBytes > 0 && u.Bytes > q.DailyBytes
+}
+
+// charge is the quota of the tenant a request is charged to
+type charge struct {
+	tracker *Tracker
+	tenant  string
+	quota   config.IngestQuota
+}
+
+// Middleware refuses the requests of tenants without a quota with 403 and
+// answers 429 to those of tenants that used up their quota for the day.
+// Handlers then Charge the files they are about to queue.
+func (t *Tracker) Middleware() gin.HandlerFunc {
+	if t == nil {
+		return func(ctx *gin.Context) { ctx.Next() }
+	}
+
+	return func(ctx *gin.Context) {
+		tenant := ctx.GetHeader(headerTenant)
+		quota, ok := t.Quota(tenant)
+		if !ok {
+			t.logger.Warn("Ingestion refused for tenant without a quota", zap.String("tenant", tenant))
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("tenant %q has no ingestion quota", tenant)})
+			return
+		}
+		if quota == (config.IngestQuota{}) {
+			ctx.Next()
+			return
+		}
+
+		day := today()
+		used, err := t.store.Usage(ctx.Request.Context(), tenant, day)
+		if err != nil {
+			// Charge checks again when the files are known
+			t.logger.Warn("Failed to read ingestion quota usage", zap.String("tenant", tenant), zap.Error(err))
+			used = Usage{}
+		}
+		if exceeds(Usage{Files: used.Files + 1, Bytes: used.Bytes + 1}, quota) {
+			t.logger.Warn("Ingestion quota exceeded",
+				zap.String("tenant", tenant),
+				zap.Int("files", used.Files),
+				zap.Int64("bytes", used.Bytes))
+			(&ExceededError{Tenant: tenant, Quota: quota, Used: used, ResetsAt: day.Add(24 * time.Hour)}).Respond(ctx)
+			return
+		}
+
+		ctx.Set(contextKeyCharge, &charge{tracker: t, tenant: tenant, quota: quota})
+		ctx.Next()
+	}
+}
+
+// Charge takes the files a request is about to queue from its tenant's
+// quota, checking and counting them at once so concurrent requests, on
+// any replica, cannot all pass. It returns an *ExceededError, charging
+// nothing, when they do not all fit. Requests not subject to a quota are
+// not charged.
+func Charge(ctx *gin.Context, paths []string) error {
+	value, ok := ctx.Get(contextKeyCharge)
+	if !ok {
+		return nil
+	}
+	c := value.(*charge)
+	requested := Usage{Files: len(paths), Bytes: size(paths)}
+
+	t := c.tracker
+	day := today()
+	used, added, err := t.store.Add(ctx.Request.Context(), c.tenant, day, requested, c.quota)
+	if err != nil {
+		// Queueing the files uncounted beats refusing them
+		t.logger.Warn("Failed to charge ingestion quota", zap.String("tenant", c.tenant), zap.Error(err))
+		return nil
+	}
+	if !added {
+		t.logger.Warn("Ingestion quota exceeded",
+			zap.String("tenant", c.tenant),
+			zap.Int("files", used.Files),
+			zap.Int("requested_files", requested.Files))
+		return &ExceededError{Tenant: c.tenant, Quota: c.quota, Used: used, Requested: requested, ResetsAt: day.Add(24 * time.Hour)}
+	}
+	return nil
+}
+
+// Refund gives back the charge for files that were not queued after all
+func Refund(ctx *gin.Context, paths []string) {
+	value, ok := ctx.Get(contextKeyCharge)
+	if !ok || len(paths) == 0 {
+		return
+	}
+	c := value.(*charge)
+
+	t := c.tracker
+	refunded := Usage{Files: len(paths), Bytes: size(paths)}
+	if err := t.store.Subtract(ctx.Request.Context(), c.tenant, today(), refunded); err != nil {
+		t.logger.Warn("Failed to refund ingestion quota", zap.String("tenant", c.tenant), zap.Error(err))
+	}
+}
+
+// size returns the total size of the files that can be read
+func size(paths []string) int64 {
+	var total int64
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
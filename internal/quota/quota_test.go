@@ -0,0 +1,84 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// replica serves /ingest charging the files it is sent to a tracker
+// counting usage in store, as an orchestrator replica does
+func replica(store Store, quota config.IngestQuota) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	tracker := NewTracker(quota, nil, store, zap.NewNop())
+	router := gin.New()
+	router.Use(tracker.Middleware())
+	router.POST("/ingest", func(c *gin.Context) {
+		paths := c.QueryArray("path")
+		var exceeded *ExceededError
+		if err := Charge(c, paths); errors.As(err, &exceeded) {
+			exceeded.Respond(c)
+			return
+		}
+		if c.Query("fail") != "" {
+			Refund(c, paths)
+			c.Status(http.StatusServiceUnavailable)
+			return
+		}
+		c.Status(http.StatusAccepted)
+	})
+	return router
+}
+
+func ingest(router *gin.Engine, query string) int {
+	req := httptest.NewRequest(http.MethodPost, "/ingest?"+query, nil)
+	req.Header.Set(headerTenant, "acme")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestReplicasShareQuota(t *testing.T) {
+	store := NewMemoryStore()
+	quota := config.IngestQuota{DailyFiles: 3}
+	a, b := replica(store, quota), replica(store, quota)
+
+	steps := []struct {
+		router *gin.Engine
+		query  string
+		want   int
+	}{
+		{a, "path=/data/1.md&path=/data/2.md", http.StatusAccepted},
+		// The second replica sees the first one's files
+		{b, "path=/data/3.md&path=/data/4.md", http.StatusTooManyRequests},
+		{b, "path=/data/3.md", http.StatusAccepted},
+		{a, "path=/data/4.md", http.StatusTooManyRequests},
+	}
+	for i, step := range steps {
+		if got := ingest(step.router, step.query); got != step.want {
+			t.Fatalf("step %d: status = %d, want %d", i, got, step.want)
+		}
+	}
+}
+
+func TestRefundReturnsUnqueuedFiles(t *testing.T) {
+	store := NewMemoryStore()
+	router := replica(store, config.IngestQuota{DailyFiles: 2})
+
+	if got := ingest(router, "path=/data/1.md&path=/data/2.md&fail=1"); got != http.StatusServiceUnavailable {
+		t.Fatalf("failed ingest = %d, want 503", got)
+	}
+	if got := ingest(router, "path=/data/1.md&path=/data/2.md"); got != http.StatusAccepted {
+		t.Errorf("ingest after refund = %d, want the refunded files to fit", got)
+	}
+	used, err := store.Usage(context.Background(), "acme", today())
+	if err != nil || used.Files != 2 {
+		t.Errorf("usage = %+v, %v; want 2 files", used, err)
+	}
+}
@@ -0,0 +1,180 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// usageTTL keeps a day's usage until the day is over everywhere clocks
+// may drift to
+const usageTTL = 48 * time.Hour
+
+// Store keeps what each tenant queued per UTC day
+type Store interface {
+	// Usage returns what a tenant queued on a day
+	Usage(ctx context.Context, tenant string, day time.Time) (Usage, error)
+	// Add counts requested in a tenant's usage of a day unless the sum
+	// exceeds the quota, checking and counting at once. It returns the
+	// usage before and whether requested was counted.
+	Add(ctx context.Context, tenant string, day time.Time, requested Usage, quota config.IngestQuota) (Usage, bool, error)
+	// Subtract takes refunded files off a tenant's usage of a day, not
+	// going below zero
+	Subtract(ctx context.Context, tenant string, day time.Time, refunded Usage) error
+	Close() error
+}
+
+// MemoryStore keeps usage in process memory, so each replica counts its
+// own and counting starts over when the service restarts
+type MemoryStore struct {
+	mu    sync.Mutex
+	day   time.Time // the UTC day counted
+	usage map[string]*Usage
+}
+
+// NewMemoryStore creates an empty in-memory usage store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{usage: make(map[string]*Usage)}
+}
+
+// Usage returns what a tenant queued on a day
+func (s *MemoryStore) Usage(_ context.Context, tenant string, day time.Time) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.today(tenant, day), nil
+}
+
+// Add counts requested unless the sum exceeds the quota
+func (s *MemoryStore) Add(_ context.Context, tenant string, day time.Time, requested Usage, quota config.IngestQuota) (Usage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.today(tenant, day)
+	used := *u
+	if exceeds(Usage{Files: used.Files + requested.Files, Bytes: used.Bytes + requested.Bytes}, quota) {
+		return used, false, nil
+	}
+	u.Files += requested.Files
+	u.Bytes += requested.Bytes
+	return used, true, nil
+}
+
+// Subtract takes refunded files off a tenant's usage
+func (s *MemoryStore) Subtract(_ context.Context, tenant string, day time.Time, refunded Usage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.today(tenant, day)
+	u.Files = max(u.Files-refunded.Files, 0)
+	u.Bytes = max(u.Bytes-refunded.Bytes, 0)
+	return nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// today returns the usage of a tenant on a day, dropping the usage of the
+// day before once a new one starts. The caller holds s.mu.
+func (s *MemoryStore) today(tenant string, day time.Time) *Usage {
+	if !day.Equal(s.day) {
+		s.day = day
+		s.usage = make(map[string]*Usage)
+	}
+	u, ok := s.usage[tenant]
+	if !ok {
+		u = &Usage{}
+		s.usage[tenant] = u
+	}
+	return u
+}
+
+// addScript counts files and bytes in a usage hash unless the sums exceed
+// the quota, and returns the usage before with whether it counted them.
+// KEYS: the usage hash; ARGV: the files and bytes requested, the daily
+// files and bytes of the quota (0 is unlimited) and the TTL in seconds.
+var addScript = redis.NewScript(`
+local files = tonumber(redis.call("HGET", KEYS[1], "files") or "0")
+local bytes = tonumber(redis.call("HGET", KEYS[1], "bytes") or "0")
+local addFiles, addBytes = tonumber(ARGV[1]), tonumber(ARGV[2])
+local maxFiles, maxBytes = tonumber(ARGV[3]), tonumber(ARGV[4])
+if (maxFiles > 0 and files + addFiles > maxFiles) or (maxBytes > 0 and bytes + addBytes > maxBytes) then
+	return {files, bytes, 0}
+end
+redis.call("HINCRBY", KEYS[1], "files", addFiles)
+redis.call("HINCRBY", KEYS[1], "bytes", addBytes)
+redis.call("EXPIRE", KEYS[1], ARGV[5])
+return {files, bytes, 1}`)
+
+// subtractScript takes files and bytes off a usage hash, not going below
+// zero. KEYS: the usage hash; ARGV: the files and bytes refunded.
+var subtractScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+local files = tonumber(redis.call("HGET", KEYS[1], "files") or "0")
+local bytes = tonumber(redis.call("HGET", KEYS[1], "bytes") or "0")
+redis.call("HSET", KEYS[1], "files", math.max(files - tonumber(ARGV[1]), 0), "bytes", math.max(bytes - tonumber(ARGV[2]), 0))
+return 1`)
+
+// RedisStore keeps each tenant's usage of a day in a Redis hash shared by
+// the replicas, expiring after the day
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a Redis backed usage store
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: keyPrefix + ":quota:"}
+}
+
+func (s *RedisStore) key(tenant string, day time.Time) string {
+	return s.prefix + day.Format("2006-01-02") + ":" + tenant
+}
+
+// Usage returns what a tenant queued on a day
+func (s *RedisStore) Usage(ctx context.Context, tenant string, day time.Time) (Usage, error) {
+	values, err := s.client.HMGet(ctx, s.key(tenant, day), "files", "bytes").Result()
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to read quota usage: %w", err)
+	}
+	var u Usage
+	if v, ok := values[0].(string); ok {
+		u.Files, _ = strconv.Atoi(v)
+	}
+	if v, ok := values[1].(string); ok {
+		u.Bytes, _ = strconv.ParseInt(v, 10, 64)
+	}
+	return u, nil
+}
+
+// Add counts requested unless the sum exceeds the quota
+func (s *RedisStore) Add(ctx context.Context, tenant string, day time.Time, requested Usage, quota config.IngestQuota) (Usage, bool, error) {
+	result, err := addScript.Run(ctx, s.client, []string{s.key(tenant, day)},
+		requested.Files, requested.Bytes, quota.DailyFiles, quota.DailyBytes, int(usageTTL.Seconds())).Int64Slice()
+	if err != nil {
+		return Usage{}, false, fmt.Errorf("failed to count quota usage: %w", err)
+	}
+	if len(result) != 3 {
+		return Usage{}, false, fmt.Errorf("unexpected quota usage reply %v", result)
+	}
+	return Usage{Files: int(result[0]), Bytes: result[1]}, result[2] == 1, nil
+}
+
+// Subtract takes refunded files off a tenant's usage
+func (s *RedisStore) Subtract(ctx context.Context, tenant string, day time.Time, refunded Usage) error {
+	if err := subtractScript.Run(ctx, s.client, []string{s.key(tenant, day)}, refunded.Files, refunded.Bytes).Err(); err != nil {
+		return fmt.Errorf("failed to refund quota usage: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
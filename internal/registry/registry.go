@@ -0,0 +1,125 @@
+// Package registry tracks the processing state of every indexed document.
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/datafile"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/imagemeta"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned when no record matches
+var ErrNotFound = errors.New("document not found")
+
+// Record is the registry entry of a document. Each file path has one
+// record, describing its latest version.
+type Record struct {
+	ID              string                 `json:"id"`
+	FilePath        string                 `json:"file_path"`
+	FileName        string                 `json:"file_name"`
+	FileType        string                 `json:"file_type"`
+	Category        string                 `json:"category"`
+	ContentType     string                 `json:"content_type,omitempty"`  // MIME type sniffed from the content
+	TypeMismatch    string                 `json:"type_mismatch,omitempty"` // extension and content disagree
+	FileHash        string                 `json:"file_hash,omitempty"`
+	Image           *imagemeta.Info        `json:"image,omitempty"`   // dimensions and format of images
+	Dataset         *datafile.Info         `json:"dataset,omitempty"` // tables and columns of data files
+	Title           string                 `json:"title,omitempty"`   // frontmatter of Markdown notes
+	Tags            []string               `json:"tags,omitempty"`
+	Aliases         []string               `json:"aliases,omitempty"`
+	Links           []string               `json:"links,omitempty"`      // wiki-link targets as written
+	Malware         string                 `json:"malware,omitempty"`    // malware found by the scan that rejected the file
+	Quarantine      string                 `json:"quarantine,omitempty"` // where the rejected file was moved
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`   // set by pipeline hooks, added to the document's vectors
+	ACL             *models.ACL            `json:"acl,omitempty"`        // access control stored on the document's vectors
+	State           models.ProcessingState `json:"state"`
+	ChunkCount      int                    `json:"chunk_count"`
+	DedupedChunks   int                    `json:"deduped_chunks,omitempty"`
+	SkippedChunks   []int                  `json:"skipped_chunks,omitempty"` // chunks not embedded for exceeding the token limit
+	Summary         string                 `json:"summary,omitempty"`
+	ContentStored   bool                   `json:"content_stored,omitempty"`   // extracted text is in the content store
+	NeedsEnrichment []string               `json:"needs_enrichment,omitempty"` // optional stages skipped while their dependency failed
+	Error           string                 `json:"error,omitempty"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+	IndexedAt       *time.Time             `json:"indexed_at,omitempty"`
+	ModifiedAt      *time.Time             `json:"modified_at,omitempty"` // modification time of the file when it was read
+}
+
+// Filter selects records from the registry
+type Filter struct {
+	Category        string
+	State           models.ProcessingState
+	PathPrefix      string
+	NeedsEnrichment bool // only records with skipped stages
+	Limit           int
+}
+
+// Matches reports whether a record satisfies the filter
+func (f *Filter) Matches(r *Record) bool {
+	if f.Category != "" && !strings.EqualFold(r.Category, f.Category) {
+		return false
+	}
+	if f.State != "" && !strings.EqualFold(string(r.State), string(f.State)) {
+		return false
+	}
+	if f.PathPrefix != "" && !strings.HasPrefix(r.FilePath, f.PathPrefix) {
+		return false
+	}
+	if f.NeedsEnrichment && len(r.NeedsEnrichment) == 0 {
+		return false
+	}
+	return true
+}
+
+// Store persists registry records
+type Store interface {
+	// Put creates or replaces the record for its file path
+	Put(ctx context.Context, record *Record) error
+	Get(ctx context.Context, id string) (*Record, error)
+	GetByPath(ctx context.Context, filePath string) (*Record, error)
+	// List returns matching records ordered by file path
+	List(ctx context.Context, filter Filter) ([]*Record, error)
+	Close() error
+}
+
+// NewStore creates the registry store selected by configuration
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	switch cfg.Registry.Backend {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Document registry enabled", zap.String("backend", "redis"), zap.String("key_prefix", cfg.Registry.KeyPrefix))
+		return NewRedisStore(client, cfg.Registry.KeyPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown registry backend: %s", cfg.Registry.Backend)
+	}
+}
+
+// filterRecords applies the filter, sorts by path and applies the limit
+func filterRecords(records []*Record, filter Filter) []*Record {
+	matched := make([]*Record, 0, len(records))
+	for _, r := range records {
+		if filter.Matches(r) {
+			matched = append(matched, r)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].FilePath < matched[j].FilePath })
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched
+}
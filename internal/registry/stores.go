@@ -0,0 +1,176 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryStore keeps records in process memory
+type MemoryStore struct {
+	mu     sync.RWMutex
+	byID   map[string]*Record
+	byPath map[string]string
+}
+
+// NewMemoryStore creates an empty in-memory registry
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		byID:   make(map[string]*Record),
+		byPath: make(map[string]string),
+	}
+}
+
+// Put stores a copy of the record, replacing any earlier version of the file
+func (s *MemoryStore) Put(_ context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if oldID, ok := s.byPath[record.FilePath]; ok && oldID != record.ID {
+		delete(s.byID, oldID)
+	}
+	stored := *record
+	s.byID[record.ID] = &stored
+	s.byPath[record.FilePath] = record.ID
+	return nil
+}
+
+// Get returns the record with the given document ID
+func (s *MemoryStore) Get(_ context.Context, id string) (*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.byID[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	result := *record
+	return &result, nil
+}
+
+// GetByPath returns the record of a file
+func (s *MemoryStore) GetByPath(ctx context.Context, filePath string) (*Record, error) {
+	s.mu.RLock()
+	id, ok := s.byPath[filePath]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.Get(ctx, id)
+}
+
+// List returns matching records
+func (s *MemoryStore) List(_ context.Context, filter Filter) ([]*Record, error) {
+	s.mu.RLock()
+	records := make([]*Record, 0, len(s.byID))
+	for _, r := range s.byID {
+		result := *r
+		records = append(records, &result)
+	}
+	s.mu.RUnlock()
+
+	return filterRecords(records, filter), nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// RedisStore keeps records in two Redis hashes: records by document ID and
+// document IDs by file path
+type RedisStore struct {
+	client  *redis.Client
+	records string
+	paths   string
+}
+
+// NewRedisStore creates a Redis backed registry
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	return &RedisStore{
+		client:  client,
+		records: keyPrefix + ":records",
+		paths:   keyPrefix + ":paths",
+	}
+}
+
+// Put stores the record, replacing any earlier version of the file
+func (s *RedisStore) Put(ctx context.Context, record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal record: %w", err)
+	}
+
+	oldID, err := s.client.HGet(ctx, s.paths, record.FilePath).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to read registry: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	if oldID != "" && oldID != record.ID {
+		pipe.HDel(ctx, s.records, oldID)
+	}
+	pipe.HSet(ctx, s.records, record.ID, data)
+	pipe.HSet(ctx, s.paths, record.FilePath, record.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to write registry: %w", err)
+	}
+	return nil
+}
+
+// Get returns the record with the given document ID
+func (s *RedisStore) Get(ctx context.Context, id string) (*Record, error) {
+	data, err := s.client.HGet(ctx, s.records, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry: %w", err)
+	}
+
+	var record Record
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return nil, fmt.Errorf("failed to decode record: %w", err)
+	}
+	return &record, nil
+}
+
+// GetByPath returns the record of a file
+func (s *RedisStore) GetByPath(ctx context.Context, filePath string) (*Record, error) {
+	id, err := s.client.HGet(ctx, s.paths, filePath).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry: %w", err)
+	}
+	return s.Get(ctx, id)
+}
+
+// List returns matching records
+func (s *RedisStore) List(ctx context.Context, filter Filter) ([]*Record, error) {
+	values, err := s.client.HVals(ctx, s.records).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry: %w", err)
+	}
+
+	records := make([]*Record, 0, len(values))
+	for _, data := range values {
+		var record Record
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+
+	return filterRecords(records, filter), nil
+}
+
+// Close closes the Redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,104 @@
+// Package routing sends the chunk vectors of file categories (code,
+// document, diagram, ...) to indexes or namespaces of their own, embedded
+// by a deployment of their own, so the embedding model and dimension can
+// differ by content type. Document summaries stay in the Pinecone index,
+// where two-stage retrieval finds them whatever the document's category.
+package routing
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/azure"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// Route is where the chunk vectors of a category are stored
+type Route struct {
+	Category string
+	Pinecone *pinecone.PineconeClient
+	// Embedder embeds the category's chunks and the queries searching
+	// them; nil when the default deployment does
+	Embedder *azure.OpenAIClient
+	// SameIndex is set when the route is a namespace of the Pinecone
+	// index, whose metric sparse vectors are configured for
+	SameIndex bool
+}
+
+// Router finds the route of a category
+type Router struct {
+	routes map[string]*Route
+	order  []*Route // by category, the order searches fan out in
+}
+
+// New creates the router of the configured category routes. It returns
+// nil without an error when no category is routed.
+func New(cfg *config.Config, logger *zap.Logger) (*Router, error) {
+	if len(cfg.Routing.Categories) == 0 {
+		return nil, nil
+	}
+
+	r := &Router{routes: make(map[string]*Route, len(cfg.Routing.Categories))}
+	for _, category := range slices.Sorted(maps.Keys(cfg.Routing.Categories)) {
+		c := cfg.Routing.Categories[category]
+
+		// Routes share the Pinecone project, and summary vectors belong to
+		// the Pinecone index only
+		routeCfg := *cfg
+		routeCfg.App.SummaryVectors = false
+		if c.Dimension > 0 {
+			routeCfg.Pinecone.Dimension = c.Dimension
+		}
+		if !c.SameIndex(cfg.Pinecone) {
+			routeCfg.Pinecone.Host = c.IndexHost
+			if c.IndexName != "" {
+				routeCfg.Pinecone.IndexName = c.IndexName
+			}
+		}
+		client, err := pinecone.NewPineconeClient(&routeCfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Pinecone client for %s vectors: %w", category, err)
+		}
+		if c.Namespace != "" {
+			client.SetNamespace(c.Namespace)
+		}
+
+		route := &Route{Category: category, Pinecone: client, SameIndex: c.SameIndex(cfg.Pinecone)}
+		if c.EmbeddingsDeployment != "" && c.EmbeddingsDeployment != cfg.Azure.OpenAIEmbeddingsDeployment {
+			routeCfg.Azure.OpenAIEmbeddingsDeployment = c.EmbeddingsDeployment
+			route.Embedder, err = azure.NewOpenAIClient(&routeCfg, logger)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Azure client for %s vectors: %w", category, err)
+			}
+		}
+
+		logger.Info("Routing category to its own vectors",
+			zap.String("category", category),
+			zap.String("index", routeCfg.Pinecone.IndexName),
+			zap.String("namespace", c.Namespace),
+			zap.String("deployment", routeCfg.Azure.OpenAIEmbeddingsDeployment))
+		r.routes[category] = route
+		r.order = append(r.order, route)
+	}
+	return r, nil
+}
+
+// Route returns the route of a category, or nil when the category's
+// vectors stay in the Pinecone index. A nil router routes nothing.
+func (r *Router) Route(category string) *Route {
+	if r == nil {
+		return nil
+	}
+	return r.routes[category]
+}
+
+// Routes returns every route, ordered by category
+func (r *Router) Routes() []*Route {
+	if r == nil {
+		return nil
+	}
+	return r.order
+}
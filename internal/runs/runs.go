@@ -0,0 +1,189 @@
+// Package runs keeps the history of directory indexing runs: what each run
+// processed, which documents it added or updated, and a generated digest
+// of the new content.
+package runs
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// MaxRuns is the number of runs kept; older runs are dropped
+const MaxRuns = 100
+
+// ErrNotFound is returned when no run has the ID
+var ErrNotFound = errors.New("run not found")
+
+// Change kinds
+const (
+	ChangeAdded   = "added"   // first version of a file
+	ChangeUpdated = "updated" // new version of a file indexed before
+)
+
+// File outcomes
+const (
+	OutcomeProcessed = "processed"
+	OutcomeSkipped   = "skipped" // already indexed or above the size limit
+	OutcomeFailed    = "failed"
+)
+
+// Stages are the processing stages timed in file reports, in processing
+// order
+var Stages = []string{"scan", "extract", "vision", "summary", "chunk", "embed", "index"}
+
+// Run statuses
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled" // stopped early; Remaining holds the files left
+)
+
+// Change is a document added or updated by a run
+type Change struct {
+	DocumentID string `json:"document_id"`
+	FilePath   string `json:"file_path"`
+	Kind       string `json:"kind"`
+	PreviousID string `json:"previous_id,omitempty"` // document ID of the superseded version
+	Summary    string `json:"summary,omitempty"`
+}
+
+// TokenUsage counts the Azure OpenAI tokens used for embeddings and
+// summaries
+type TokenUsage struct {
+	Prompt     int64 `json:"prompt"`
+	Completion int64 `json:"completion"`
+}
+
+// Add returns the sum of two usages
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{Prompt: u.Prompt + other.Prompt, Completion: u.Completion + other.Completion}
+}
+
+// FileReport is the outcome of one file of a run. Stage durations are those
+// of the last attempt; tokens are counted over every attempt.
+type FileReport struct {
+	FilePath   string           `json:"file_path"`
+	DocumentID string           `json:"document_id,omitempty"`
+	Outcome    string           `json:"outcome"`
+	Error      string           `json:"error,omitempty"` // failure, or why the file was skipped
+	Attempts   int              `json:"attempts"`
+	DurationMs int64            `json:"duration_ms"`
+	StageMs    map[string]int64 `json:"stage_ms,omitempty"` // milliseconds spent in each of Stages
+	Tokens     TokenUsage       `json:"tokens"`
+}
+
+// Run is an indexing run of a directory, or of a batch of files
+type Run struct {
+	ID         string    `json:"id"`
+	Directory  string    `json:"directory"`
+	Batch      bool      `json:"batch,omitempty"` // processes a list of files rather than a directory
+	Status     string    `json:"status"`
+	Force      bool      `json:"force,omitempty"` // already indexed files are processed again
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Total      int       `json:"total_files"`
+	Processed  int       `json:"processed"`
+	Skipped    int       `json:"skipped"`
+	Failed     int       `json:"failed"`
+	Changes    []Change  `json:"changes"`
+	// Files reports the outcome of each file the run reached
+	Files []FileReport `json:"files,omitempty"`
+	// Remaining lists the files a cancelled run did not reach, so it can
+	// be resumed
+	Remaining []string `json:"remaining,omitempty"`
+	// Digest summarizes the new content of the changed documents; it is
+	// empty when nothing changed or no digest could be generated
+	Digest string `json:"digest,omitempty"`
+}
+
+// Counts returns the number of added and updated documents
+func (r *Run) Counts() (added, updated int) {
+	for _, c := range r.Changes {
+		if c.Kind == ChangeAdded {
+			added++
+		} else {
+			updated++
+		}
+	}
+	return added, updated
+}
+
+// Usage returns the tokens used by the run and the milliseconds its files
+// spent in each stage
+func (r *Run) Usage() (TokenUsage, map[string]int64) {
+	var tokens TokenUsage
+	stages := make(map[string]int64, len(Stages))
+	for _, f := range r.Files {
+		tokens = tokens.Add(f.Tokens)
+		for stage, ms := range f.StageMs {
+			stages[stage] += ms
+		}
+	}
+	return tokens, stages
+}
+
+// WriteCSV writes file reports as CSV with a header row and a column per
+// stage
+func WriteCSV(w io.Writer, files []FileReport) error {
+	header := []string{"file_path", "document_id", "outcome", "attempts", "duration_ms"}
+	for _, stage := range Stages {
+		header = append(header, stage+"_ms")
+	}
+	header = append(header, "prompt_tokens", "completion_tokens", "error")
+
+	cw := csv.NewWriter(w)
+	cw.Write(header) //nolint:errcheck
+	for _, f := range files {
+		row := []string{f.FilePath, f.DocumentID, f.Outcome, strconv.Itoa(f.Attempts), strconv.FormatInt(f.DurationMs, 10)}
+		for _, stage := range Stages {
+			row = append(row, strconv.FormatInt(f.StageMs[stage], 10))
+		}
+		row = append(row, strconv.FormatInt(f.Tokens.Prompt, 10), strconv.FormatInt(f.Tokens.Completion, 10), f.Error)
+		cw.Write(row) //nolint:errcheck
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Store persists runs
+type Store interface {
+	// Save creates or replaces a run, dropping the oldest beyond MaxRuns
+	Save(ctx context.Context, run *Run) error
+	Get(ctx context.Context, id string) (*Run, error)
+	// List returns up to limit runs, newest first; limit 0 returns all
+	List(ctx context.Context, limit int) ([]*Run, error)
+	Close() error
+}
+
+// Compile-time checks that the stores implement the interface
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+// NewStore creates the run store. Runs are kept beside the document
+// registry, in the same backend.
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	switch cfg.Registry.Backend {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Run history enabled", zap.String("backend", "redis"), zap.String("key_prefix", cfg.Registry.KeyPrefix))
+		return NewRedisStore(client, cfg.Registry.KeyPrefix), nil
+	default:
+		return nil, fmt.Errorf("unknown registry backend: %s", cfg.Registry.Backend)
+	}
+}
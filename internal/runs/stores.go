@@ -0,0 +1,185 @@
+package runs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MemoryStore keeps runs in process memory
+type MemoryStore struct {
+	mu   sync.RWMutex
+	runs map[string]*Run
+}
+
+// NewMemoryStore creates an empty in-memory run store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{runs: make(map[string]*Run)}
+}
+
+// Save creates or replaces a run
+func (s *MemoryStore) Save(_ context.Context, run *Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *run
+	stored.Changes = append([]Change(nil), run.Changes...)
+	stored.Files = append([]FileReport(nil), run.Files...)
+	s.runs[run.ID] = &stored
+
+	if len(s.runs) > MaxRuns {
+		all := s.sorted()
+		for _, old := range all[MaxRuns:] {
+			delete(s.runs, old.ID)
+		}
+	}
+	return nil
+}
+
+// Get returns the run with the given ID
+func (s *MemoryStore) Get(_ context.Context, id string) (*Run, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	result := *run
+	return &result, nil
+}
+
+// List returns runs newest first
+func (s *MemoryStore) List(_ context.Context, limit int) ([]*Run, error) {
+	s.mu.RLock()
+	all := s.sorted()
+	s.mu.RUnlock()
+
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+	result := make([]*Run, len(all))
+	for i, run := range all {
+		copied := *run
+		result[i] = &copied
+	}
+	return result, nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// sorted returns the stored runs newest first; callers hold the lock
+func (s *MemoryStore) sorted() []*Run {
+	all := make([]*Run, 0, len(s.runs))
+	for _, run := range s.runs {
+		all = append(all, run)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].StartedAt.After(all[j].StartedAt) })
+	return all
+}
+
+// RedisStore keeps runs in a Redis hash by ID, ordered by a sorted set
+// scored by start time
+type RedisStore struct {
+	client *redis.Client
+	runs   string
+	order  string
+}
+
+// NewRedisStore creates a Redis backed run store
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		runs:   keyPrefix + ":runs",
+		order:  keyPrefix + ":runs:order",
+	}
+}
+
+// Save creates or replaces a run
+func (s *RedisStore) Save(ctx context.Context, run *Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, s.runs, run.ID, data)
+	pipe.ZAdd(ctx, s.order, redis.Z{Score: float64(run.StartedAt.UnixNano()), Member: run.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+
+	// Drop the oldest runs beyond the limit
+	expired, err := s.client.ZRange(ctx, s.order, 0, int64(-MaxRuns-1)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read runs: %w", err)
+	}
+	if len(expired) > 0 {
+		members := make([]interface{}, len(expired))
+		for i, id := range expired {
+			members[i] = id
+		}
+		pipe := s.client.TxPipeline()
+		pipe.HDel(ctx, s.runs, expired...)
+		pipe.ZRem(ctx, s.order, members...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to drop old runs: %w", err)
+		}
+	}
+	return nil
+}
+
+// Get returns the run with the given ID
+func (s *RedisStore) Get(ctx context.Context, id string) (*Run, error) {
+	data, err := s.client.HGet(ctx, s.runs, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run: %w", err)
+	}
+
+	var run Run
+	if err := json.Unmarshal([]byte(data), &run); err != nil {
+		return nil, fmt.Errorf("failed to decode run: %w", err)
+	}
+	return &run, nil
+}
+
+// List returns runs newest first
+func (s *RedisStore) List(ctx context.Context, limit int) ([]*Run, error) {
+	stop := int64(-1)
+	if limit > 0 {
+		stop = int64(limit - 1)
+	}
+	ids, err := s.client.ZRevRange(ctx, s.order, 0, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs: %w", err)
+	}
+
+	result := make([]*Run, 0, len(ids))
+	for _, id := range ids {
+		run, err := s.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue // dropped meanwhile
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, run)
+	}
+	return result, nil
+}
+
+// Close closes the Redis client
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,48 @@
+// Package safeparse reads untrusted XML documents and zip archives within
+// limits, so a crafted file can neither make the service read other files
+// nor exhaust its memory. XML is read without resolving external entities,
+// within bounds on its size, nesting and the text declared entities expand
+// to; archives are checked against their entry count, their total
+// decompressed size and the compression ratio of each entry before any
+// entry is read, and archives with overlapping entries are refused.
+package safeparse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrLimit is wrapped by the errors of content that exceeds a limit or is
+// built to, such as XML entity bombs and zip bombs
+var ErrLimit = errors.New("content exceeds a parsing limit")
+
+// limitError returns an error wrapping ErrLimit
+func limitError(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrLimit}, args...)...)
+}
+
+// limitedReader fails with ErrLimit once more than n bytes are read, where
+// io.LimitReader would end the input early
+type limitedReader struct {
+	r   io.Reader
+	n   int64 // bytes left
+	max int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Input ending exactly at the limit is within it
+		var probe [1]byte
+		if n, err := l.r.Read(probe[:]); n == 0 {
+			return 0, err
+		}
+		return 0, limitError("content is larger than %d bytes", l.max)
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,285 @@
+package safeparse
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+)
+
+// billionLaughs declares each entity as ten references to the one before
+const billionLaughs = `<?xml version="1.0"?>
+<!DOCTYPE lolz [
+ <!ENTITY lol "lol">
+ <!ENTITY lol1 "&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;&lol;">
+ <!ENTITY lol2 "&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;&lol1;">
+ <!ENTITY lol3 "&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;&lol2;">
+ <!ENTITY lol4 "&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;&lol3;">
+ <!ENTITY lol5 "&lol4;&lol4;&lol4;&lol4;&lol4;&lol4;&lol4;&lol4;&lol4;&lol4;">
+ <!ENTITY lol6 "&lol5;&lol5;&lol5;&lol5;&lol5;&lol5;&lol5;&lol5;&lol5;&lol5;">
+ <!ENTITY lol7 "&lol6;&lol6;&lol6;&lol6;&lol6;&lol6;&lol6;&lol6;&lol6;&lol6;">
+ <!ENTITY lol8 "&lol7;&lol7;&lol7;&lol7;&lol7;&lol7;&lol7;&lol7;&lol7;&lol7;">
+ <!ENTITY lol9 "&lol8;&lol8;&lol8;&lol8;&lol8;&lol8;&lol8;&lol8;&lol8;&lol8;">
+]>
+<lolz>&lol9;</lolz>`
+
+// externalEntity references a local file through a SYSTEM entity
+const externalEntity = `<?xml version="1.0"?>
+<!DOCTYPE foo [<!ENTITY xxe SYSTEM "file:///etc/passwd">]>
+<foo>&xxe;</foo>`
+
+// readXML reads every token of a document with a lenient decoder and
+// returns its character data
+func readXML(doc string, limits XMLLimits) (string, error) {
+	d := NewXMLDecoder(strings.NewReader(doc), limits)
+	d.Strict = false
+	var text strings.Builder
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return text.String(), nil
+		}
+		if err != nil {
+			return text.String(), err
+		}
+		if data, ok := token.(xml.CharData); ok {
+			text.Write(data)
+		}
+	}
+}
+
+func TestXMLDecoder(t *testing.T) {
+	repeated := `<!DOCTYPE d [<!ENTITY big "` + strings.Repeat("x", 1024) + `">]><d>` +
+		strings.Repeat("&big;", 2048) + `</d>`
+
+	tests := []struct {
+		name      string
+		doc       string
+		limits    XMLLimits
+		wantLimit bool
+		check     func(t *testing.T, text string)
+	}{
+		{
+			name:   "billion laughs stays small",
+			doc:    billionLaughs,
+			limits: DefaultXMLLimits,
+			check: func(t *testing.T, text string) {
+				if len(text) > len(billionLaughs) {
+					t.Errorf("entities expanded to %d bytes, more than the document", len(text))
+				}
+			},
+		},
+		{
+			name:   "external entity is not resolved",
+			doc:    externalEntity,
+			limits: DefaultXMLLimits,
+			check: func(t *testing.T, text string) {
+				if strings.Contains(text, "root:") {
+					t.Errorf("external entity was resolved: %q", text)
+				}
+			},
+		},
+		{
+			name:      "repeated entity references exceed the expansion limit",
+			doc:       repeated,
+			limits:    XMLLimits{MaxBytes: 1 << 20, MaxDepth: 16, MaxEntityBytes: 1 << 20},
+			wantLimit: true,
+		},
+		{
+			name:      "deep nesting",
+			doc:       strings.Repeat("<a>", 100) + strings.Repeat("</a>", 100),
+			limits:    XMLLimits{MaxBytes: 1 << 20, MaxDepth: 50, MaxEntityBytes: 1 << 20},
+			wantLimit: true,
+		},
+		{
+			name:      "oversized document",
+			doc:       "<a>" + strings.Repeat("x", 4096) + "</a>",
+			limits:    XMLLimits{MaxBytes: 1024, MaxDepth: 16, MaxEntityBytes: 1024},
+			wantLimit: true,
+		},
+		{
+			name:   "plain document",
+			doc:    `<a b="c">text</a>`,
+			limits: DefaultXMLLimits,
+			check: func(t *testing.T, text string) {
+				if text != "text" {
+					t.Errorf("text = %q, want %q", text, "text")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := readXML(tt.doc, tt.limits)
+			if tt.wantLimit {
+				if !errors.Is(err, ErrLimit) {
+					t.Fatalf("err = %v, want ErrLimit", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.check != nil {
+				tt.check(t, text)
+			}
+		})
+	}
+}
+
+func TestXMLDecoderStrictRefusesExternalEntity(t *testing.T) {
+	d := NewXMLDecoder(strings.NewReader(externalEntity), DefaultXMLLimits)
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			t.Fatal("strict decoder read a reference to an external entity")
+		}
+		if err != nil {
+			return
+		}
+		if data, ok := token.(xml.CharData); ok && strings.Contains(string(data), "root:") {
+			t.Fatalf("external entity was resolved: %q", data)
+		}
+	}
+}
+
+// zipOf writes an archive of the given entries
+func zipOf(t *testing.T, method uint16, entries map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range entries {
+		f, err := w.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// overlappingZip writes an archive whose central directory lists the one
+// stored entry n times, as zip bombs without nesting do
+func overlappingZip(n int) []byte {
+	data := []byte("overlapping entry data")
+	crc := crc32.ChecksumIEEE(data)
+	le := binary.LittleEndian
+
+	var buf bytes.Buffer
+	name := []byte("a.txt")
+	local := make([]byte, 30)
+	le.PutUint32(local[0:], 0x04034b50)
+	le.PutUint16(local[4:], 20)
+	le.PutUint32(local[14:], crc)
+	le.PutUint32(local[18:], uint32(len(data)))
+	le.PutUint32(local[22:], uint32(len(data)))
+	le.PutUint16(local[26:], uint16(len(name)))
+	buf.Write(local)
+	buf.Write(name)
+	buf.Write(data)
+
+	cdOffset := buf.Len()
+	for i := 0; i < n; i++ {
+		entryName := []byte(fmt.Sprintf("%d.txt", i))
+		central := make([]byte, 46)
+		le.PutUint32(central[0:], 0x02014b50)
+		le.PutUint16(central[4:], 20)
+		le.PutUint16(central[6:], 20)
+		le.PutUint32(central[16:], crc)
+		le.PutUint32(central[20:], uint32(len(data)))
+		le.PutUint32(central[24:], uint32(len(data)))
+		le.PutUint16(central[28:], uint16(len(entryName)))
+		le.PutUint32(central[42:], 0) // every entry points at the same local header
+		buf.Write(central)
+		buf.Write(entryName)
+	}
+	cdSize := buf.Len() - cdOffset
+
+	end := make([]byte, 22)
+	le.PutUint32(end[0:], 0x06054b50)
+	le.PutUint16(end[8:], uint16(n))
+	le.PutUint16(end[10:], uint16(n))
+	le.PutUint32(end[12:], uint32(cdSize))
+	le.PutUint32(end[16:], uint32(cdOffset))
+	buf.Write(end)
+	return buf.Bytes()
+}
+
+func TestNewZipReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		archive   func(t *testing.T) []byte
+		limits    ZipLimits
+		wantLimit bool
+	}{
+		{
+			name:      "overlapping entries",
+			archive:   func(*testing.T) []byte { return overlappingZip(3) },
+			limits:    DefaultZipLimits,
+			wantLimit: true,
+		},
+		{
+			name: "high compression ratio",
+			archive: func(t *testing.T) []byte {
+				return zipOf(t, zip.Deflate, map[string][]byte{"zeros.bin": make([]byte, 4<<20)})
+			},
+			limits:    DefaultZipLimits,
+			wantLimit: true,
+		},
+		{
+			name: "too many entries",
+			archive: func(t *testing.T) []byte {
+				entries := make(map[string][]byte)
+				for i := 0; i < 5; i++ {
+					entries[fmt.Sprintf("%d.txt", i)] = []byte("entry")
+				}
+				return zipOf(t, zip.Store, entries)
+			},
+			limits:    ZipLimits{MaxEntries: 4, MaxBytes: 1 << 20, MaxRatio: 200},
+			wantLimit: true,
+		},
+		{
+			name: "too large decompressed",
+			archive: func(t *testing.T) []byte {
+				return zipOf(t, zip.Store, map[string][]byte{"a.txt": make([]byte, 2048)})
+			},
+			limits:    ZipLimits{MaxEntries: 10, MaxBytes: 1024, MaxRatio: 200},
+			wantLimit: true,
+		},
+		{
+			name: "plain archive",
+			archive: func(t *testing.T) []byte {
+				return zipOf(t, zip.Deflate, map[string][]byte{"a.txt": []byte("hello"), "b.txt": []byte("world")})
+			},
+			limits: DefaultZipLimits,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.archive(t)
+			_, err := NewZipReader(bytes.NewReader(data), int64(len(data)), tt.limits)
+			if tt.wantLimit {
+				if !errors.Is(err, ErrLimit) {
+					t.Fatalf("err = %v, want ErrLimit", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package safeparse
+
+import (
+	"encoding/xml"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// XMLLimits bound an XML document
+type XMLLimits struct {
+	MaxBytes int64 // size of the document
+	MaxDepth int   // nesting of elements
+	// MaxEntityBytes bounds the text that references to entities declared
+	// in the document type expand to, in the whole document
+	MaxEntityBytes int64
+}
+
+// DefaultXMLLimits are the limits documents are read with
+var DefaultXMLLimits = XMLLimits{
+	MaxBytes:       256 << 20,
+	MaxDepth:       256,
+	MaxEntityBytes: 1 << 20,
+}
+
+var entityDecl = regexp.MustCompile(`<!ENTITY\s+([^\s%]+)\s+"([^"]*)"`)
+
+// XMLDecoder reads the tokens of an XML document within limits.
+//
+// encoding/xml never resolves external entities, so SYSTEM and PUBLIC
+// entities stay unexpanded: strict decoders fail on references to them
+// and lenient ones keep the references as text. Internal entities
+// declared in the document type are expanded by Token, in character data
+// and attribute values, within MaxEntityBytes; they are not given to the
+// underlying decoder, as it would expand them without a bound. Strict
+// decoders fail on references to them before Token can expand them, so
+// only lenient decoders see their values.
+type XMLDecoder struct {
+	*xml.Decoder
+	limits   XMLLimits
+	depth    int
+	entities map[string]string // values of the internal entities declared
+	expanded int64
+}
+
+// NewXMLDecoder creates a decoder reading r within the limits
+func NewXMLDecoder(r io.Reader, limits XMLLimits) *XMLDecoder {
+	return &XMLDecoder{
+		Decoder: xml.NewDecoder(&limitedReader{r: r, n: limits.MaxBytes, max: limits.MaxBytes}),
+		limits:  limits,
+	}
+}
+
+// Token returns the next token, failing with ErrLimit when the document
+// exceeds a limit
+func (d *XMLDecoder) Token() (xml.Token, error) {
+	token, err := d.Decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case xml.StartElement:
+		if d.depth++; d.depth > d.limits.MaxDepth {
+			return nil, limitError("elements are nested deeper than %d", d.limits.MaxDepth)
+		}
+		if d.entities != nil {
+			for i := range t.Attr {
+				if t.Attr[i].Value, err = d.expand(t.Attr[i].Value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case xml.EndElement:
+		d.depth--
+	case xml.CharData:
+		if d.entities != nil {
+			text, err := d.expand(string(t))
+			if err != nil {
+				return nil, err
+			}
+			token = xml.CharData(text)
+		}
+	case xml.Directive:
+		for _, m := range entityDecl.FindAllStringSubmatch(string(t), -1) {
+			if d.entities == nil {
+				d.entities = make(map[string]string)
+			}
+			d.entities[m[1]] = html.UnescapeString(m[2])
+		}
+	}
+	return token, nil
+}
+
+// expand replaces references to declared entities in s with their values
+func (d *XMLDecoder) expand(s string) (string, error) {
+	if !strings.Contains(s, "&") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(s, '&')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], ';')
+		if end < 0 {
+			break
+		}
+		value, ok := d.entities[s[start+1:start+end]]
+		if !ok {
+			b.WriteString(s[:start+end+1])
+			s = s[start+end+1:]
+			continue
+		}
+		if d.expanded += int64(len(value)); d.expanded > d.limits.MaxEntityBytes {
+			return "", limitError("entities expand to more than %d bytes", d.limits.MaxEntityBytes)
+		}
+		b.WriteString(s[:start])
+		b.WriteString(value)
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return b.String(), nil
+}
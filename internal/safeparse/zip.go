@@ -0,0 +1,91 @@
+package safeparse
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ZipLimits bound a zip archive
+type ZipLimits struct {
+	MaxEntries int
+	MaxBytes   int64 // decompressed size of all entries
+	// MaxRatio bounds the decompressed size of an entry over its
+	// compressed size, for entries larger than 1 MiB decompressed
+	MaxRatio uint64
+}
+
+// DefaultZipLimits are the limits archives are opened with
+var DefaultZipLimits = ZipLimits{
+	MaxEntries: 10_000,
+	MaxBytes:   1 << 30,
+	MaxRatio:   200,
+}
+
+// ratioFloor is the decompressed size from which MaxRatio applies; small
+// entries of repeated bytes compress further than documents do
+const ratioFloor = 1 << 20
+
+// OpenZip opens an archive and checks it against the limits
+func OpenZip(path string, limits ZipLimits) (*zip.ReadCloser, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckZip(&archive.Reader, limits); err != nil {
+		archive.Close()
+		return nil, err
+	}
+	return archive, nil
+}
+
+// NewZipReader reads an archive from r and checks it against the limits
+func NewZipReader(r io.ReaderAt, size int64, limits ZipLimits) (*zip.Reader, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckZip(archive, limits); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// CheckZip checks the entries of an archive against the limits, failing
+// with ErrLimit. archive/zip fails reads past the size an entry declares,
+// so checking the declared sizes bounds what reading the entries
+// decompresses. Entries whose compressed data overlap, which let a small
+// archive declare a huge total size without nesting archives, are refused.
+// Archives inside an archive are never opened.
+func CheckZip(archive *zip.Reader, limits ZipLimits) error {
+	if len(archive.File) > limits.MaxEntries {
+		return limitError("archive has more than %d entries", limits.MaxEntries)
+	}
+
+	type span struct{ start, end int64 }
+	spans := make([]span, 0, len(archive.File))
+	var total uint64
+	for _, f := range archive.File {
+		if f.UncompressedSize64 > uint64(limits.MaxBytes)-total {
+			return limitError("archive decompresses to more than %d bytes", limits.MaxBytes)
+		}
+		total += f.UncompressedSize64
+		if f.UncompressedSize64 > ratioFloor && f.UncompressedSize64/max(f.CompressedSize64, 1) > limits.MaxRatio {
+			return limitError("entry %s is compressed more than %d to 1", f.Name, limits.MaxRatio)
+		}
+		offset, err := f.DataOffset()
+		if err != nil {
+			return fmt.Errorf("failed to read archive entry %s: %w", f.Name, err)
+		}
+		spans = append(spans, span{offset, offset + int64(f.CompressedSize64)})
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	for i := 1; i < len(spans); i++ {
+		if spans[i].start < spans[i-1].end {
+			return limitError("archive entries overlap")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// identity returns a key shared by all paths of the same file. Without
+// inode numbers the fully resolved path is used, which detects symlink
+// cycles but not hard links.
+func identity(path string, _ os.FileInfo) (string, bool) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
+	}
+	abs, err := filepath.Abs(resolved)
+	if err != nil {
+		return "", false
+	}
+	return abs, true
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// identity returns a key shared by all paths of the same file: its device
+// and inode numbers
+func identity(_ string, info os.FileInfo) (string, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%d:%d", uint64(stat.Dev), uint64(stat.Ino)), true //nolint:unconvert
+}
@@ -0,0 +1,114 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sort"
+	"time"
+)
+
+// Manifest records the files a scan of a directory found, so that the
+// next scan can report what changed since and reuse the hashes of files
+// whose size and modification time are unchanged
+type Manifest struct {
+	Directory     string                   `json:"directory"`
+	Version       string                   `json:"version"` // ETag of the manifest, see Seal
+	HashAlgorithm string                   `json:"hash_algorithm"`
+	ScannedAt     time.Time                `json:"scanned_at"`
+	Files         map[string]ManifestEntry `json:"files"` // by normalized path
+}
+
+// ManifestEntry is a file recorded in a manifest
+type ManifestEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modified_time"`
+	Hash    string    `json:"hash"`
+}
+
+// NewManifest creates an empty manifest of a directory
+func NewManifest(directory, hashAlgorithm string) *Manifest {
+	return &Manifest{
+		Directory:     directory,
+		HashAlgorithm: hashAlgorithm,
+		ScannedAt:     time.Now().UTC(),
+		Files:         make(map[string]ManifestEntry),
+	}
+}
+
+// Add records a file with its hash
+func (m *Manifest) Add(path string, info os.FileInfo, hash string) {
+	m.Files[path] = ManifestEntry{Size: info.Size(), ModTime: info.ModTime().UTC(), Hash: hash}
+}
+
+// CachedHash returns the hash recorded for a file when it was hashed with
+// algorithm and its size and modification time have not changed since. A
+// nil manifest has no hashes.
+func (m *Manifest) CachedHash(path string, info os.FileInfo, algorithm string) (string, bool) {
+	if m == nil || m.HashAlgorithm != algorithm {
+		return "", false
+	}
+	entry, ok := m.Files[path]
+	if !ok || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		return "", false
+	}
+	return entry.Hash, true
+}
+
+// Seal sets the version of the manifest from its paths and hashes, so two
+// scans of the same content have the same version however often the files
+// were touched in between
+func (m *Manifest) Seal() {
+	paths := make([]string, 0, len(m.Files))
+	for path := range m.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	h := sha256.New()
+	h.Write([]byte(m.HashAlgorithm + "\n"))
+	for _, path := range paths {
+		h.Write([]byte(path + "\x00" + m.Files[path].Hash + "\n"))
+	}
+	m.Version = hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// Changes lists the paths added, modified and deleted between two
+// manifests, each sorted
+type Changes struct {
+	Added     []string `json:"added"`
+	Modified  []string `json:"modified"`
+	Deleted   []string `json:"deleted"`
+	Unchanged int      `json:"unchanged"`
+}
+
+// Compare returns the changes from previous to current. Every file is
+// added when previous is nil. A file is modified when its hash differs;
+// hashes of different algorithms always differ.
+func Compare(previous, current *Manifest) Changes {
+	changes := Changes{Added: []string{}, Modified: []string{}, Deleted: []string{}}
+	var before map[string]ManifestEntry
+	if previous != nil {
+		before = previous.Files
+	}
+	for path, entry := range current.Files {
+		old, ok := before[path]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, path)
+		case old.Hash != entry.Hash || previous.HashAlgorithm != current.HashAlgorithm:
+			changes.Modified = append(changes.Modified, path)
+		default:
+			changes.Unchanged++
+		}
+	}
+	for path := range before {
+		if _, ok := current.Files[path]; !ok {
+			changes.Deleted = append(changes.Deleted, path)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Modified)
+	sort.Strings(changes.Deleted)
+	return changes
+}
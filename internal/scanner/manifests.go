@@ -0,0 +1,215 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// ErrNoManifest is returned when a directory has not been scanned before
+var ErrNoManifest = errors.New("no manifest of the directory")
+
+// ManifestStore keeps the last scan manifest of each directory
+type ManifestStore interface {
+	Get(ctx context.Context, directory string) (*Manifest, error)
+	Put(ctx context.Context, manifest *Manifest) error
+	Close() error
+}
+
+// Compile-time checks that the stores implement the interface
+var (
+	_ ManifestStore = (*MemoryManifestStore)(nil)
+	_ ManifestStore = (*FileManifestStore)(nil)
+	_ ManifestStore = (*RedisManifestStore)(nil)
+)
+
+// NewManifestStore creates the manifest store selected by configuration.
+// It returns nil without an error when the backend is "none".
+func NewManifestStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (ManifestStore, error) {
+	switch cfg.Scan.ManifestBackend {
+	case "none":
+		return nil, nil
+	case "memory":
+		return NewMemoryManifestStore(), nil
+	case "file":
+		store, err := NewFileManifestStore(cfg.Scan.ManifestDir)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Scan manifests enabled", zap.String("backend", "file"), zap.String("directory", cfg.Scan.ManifestDir))
+		return store, nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Scan manifests enabled", zap.String("backend", "redis"), zap.String("key", cfg.Scan.ManifestKey))
+		return NewRedisManifestStore(client, cfg.Scan.ManifestKey), nil
+	default:
+		return nil, fmt.Errorf("unknown scan manifest backend: %s", cfg.Scan.ManifestBackend)
+	}
+}
+
+// MemoryManifestStore keeps manifests in process memory
+type MemoryManifestStore struct {
+	mu        sync.RWMutex
+	manifests map[string]*Manifest
+}
+
+// NewMemoryManifestStore creates an empty in-memory manifest store
+func NewMemoryManifestStore() *MemoryManifestStore {
+	return &MemoryManifestStore{manifests: make(map[string]*Manifest)}
+}
+
+// Get returns the manifest of a directory. Manifests are not modified
+// once stored, so it is shared rather than copied.
+func (s *MemoryManifestStore) Get(_ context.Context, directory string) (*Manifest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	manifest, ok := s.manifests[directory]
+	if !ok {
+		return nil, ErrNoManifest
+	}
+	return manifest, nil
+}
+
+// Put replaces the manifest of its directory
+func (s *MemoryManifestStore) Put(_ context.Context, manifest *Manifest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.manifests[manifest.Directory] = manifest
+	return nil
+}
+
+// Close is a no-op for the memory store
+func (s *MemoryManifestStore) Close() error {
+	return nil
+}
+
+// FileManifestStore keeps each manifest as a JSON file in a directory,
+// named by the hash of the scanned directory's path
+type FileManifestStore struct {
+	dir string
+}
+
+// NewFileManifestStore creates the directory if needed
+func NewFileManifestStore(dir string) (*FileManifestStore, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	return &FileManifestStore{dir: dir}, nil
+}
+
+// Get reads the manifest of a directory
+func (s *FileManifestStore) Get(_ context.Context, directory string) (*Manifest, error) {
+	data, err := os.ReadFile(s.path(directory))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoManifest
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Put writes the manifest to a temporary file and renames it into place,
+// so a crash never leaves a partial manifest
+func (s *FileManifestStore) Put(_ context.Context, manifest *Manifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".manifest-*")
+	if err != nil {
+		return fmt.Errorf("failed to create manifest file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after the rename
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(manifest.Directory)); err != nil {
+		return fmt.Errorf("failed to store manifest: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op for the file store
+func (s *FileManifestStore) Close() error {
+	return nil
+}
+
+func (s *FileManifestStore) path(directory string) string {
+	sum := sha256.Sum256([]byte(directory))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// RedisManifestStore keeps manifests in a Redis hash by directory
+type RedisManifestStore struct {
+	client *redis.Client
+	key    string
+}
+
+// NewRedisManifestStore creates a Redis backed manifest store
+func NewRedisManifestStore(client *redis.Client, key string) *RedisManifestStore {
+	return &RedisManifestStore{client: client, key: key}
+}
+
+// Get returns the manifest of a directory
+func (s *RedisManifestStore) Get(ctx context.Context, directory string) (*Manifest, error) {
+	data, err := s.client.HGet(ctx, s.key, directory).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNoManifest
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Put replaces the manifest of its directory
+func (s *RedisManifestStore) Put(ctx context.Context, manifest *Manifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := s.client.HSet(ctx, s.key, manifest.Directory, data).Err(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis client
+func (s *RedisManifestStore) Close() error {
+	return s.client.Close()
+}
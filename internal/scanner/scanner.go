@@ -0,0 +1,234 @@
+// Package scanner walks directory trees to find files for indexing. It
+// handles symbolic links explicitly, prevents directory cycles and
+// reports hard-linked duplicates so a file is only indexed once.
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+)
+
+// Reasons an entry was left out of a scan
+const (
+	SkipSymlink       = "symlink"          // link not followed
+	SkipBrokenSymlink = "broken_symlink"   // link target does not exist
+	SkipVisited       = "already_visited"  // directory reached again, e.g. through a link cycle
+	SkipDuplicate     = "duplicate"        // another hard link or followed symlink to a listed file
+	SkipUnreadable    = "unreadable"       // directory or entry could not be read
+	SkipTooLarge      = "too_large"        // file above the extraction size limit
+	SkipExcluded      = "excluded"         // directory matching an excluded pattern
+	SkipMalware       = "malware"          // file rejected by the malware scan
+	SkipFiltered      = "filtered"         // file left out by a pipeline hook
+	SkipLimit         = "extraction_limit" // extraction timed out, ran out of memory or exceeded a parsing limit
+)
+
+// Options controls how directories are walked
+type Options struct {
+	// Recursive descends into subdirectories
+	Recursive bool
+	// FollowSymlinks lists symlinked files and descends into symlinked
+	// directories; otherwise links are skipped
+	FollowSymlinks bool
+	// DedupHardlinks lists a file reachable through several hard links
+	// (or followed symlinks) only once
+	DedupHardlinks bool
+	// SkipHidden leaves out hidden files
+	SkipHidden bool
+	// ExcludeDirs leaves out directories whose name or path relative to
+	// the root matches one of these glob patterns
+	ExcludeDirs []string
+}
+
+// OptionsFromConfig returns recursive scan options from the scan configuration
+func OptionsFromConfig(cfg config.ScanConfig) Options {
+	return Options{
+		Recursive:      true,
+		FollowSymlinks: cfg.FollowSymlinks,
+		DedupHardlinks: cfg.DedupHardlinks,
+		SkipHidden:     true,
+		ExcludeDirs:    cfg.ExcludeDirs,
+	}
+}
+
+// Skipped is an entry left out of a scan
+type Skipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	// Original is the first path of the same file or directory, for
+	// already_visited and duplicate entries
+	Original string `json:"original,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// File is a regular file found by a scan
+type File struct {
+	Path string
+	Info os.FileInfo
+}
+
+// Result lists the files found by a scan, in lexical order per directory
+type Result struct {
+	Files   []File
+	Skipped []Skipped
+}
+
+// Paths returns the paths of the files found
+func (r *Result) Paths() []string {
+	paths := make([]string, len(r.Files))
+	for i, f := range r.Files {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+// Scan walks root and returns the files below it. Only an unreadable root
+// is an error; problems further down are reported as skipped entries.
+func Scan(root string, opts Options) (*Result, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	w := &walker{
+		opts:    opts,
+		dirs:    make(map[string]string),
+		files:   make(map[string]string),
+		result:  &Result{},
+		rootDir: root,
+	}
+	w.markDir(root, info)
+	if err := w.walk(root); err != nil {
+		return nil, err
+	}
+	return w.result, nil
+}
+
+type walker struct {
+	opts    Options
+	dirs    map[string]string // identity -> first path of visited directories
+	files   map[string]string // identity -> first path of listed files
+	result  *Result
+	rootDir string
+}
+
+func (w *walker) walk(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if dir == w.rootDir {
+			return fmt.Errorf("failed to read directory %s: %w", dir, err)
+		}
+		w.skip(Skipped{Path: dir, Reason: SkipUnreadable, Error: err.Error()})
+		return nil
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			w.skip(Skipped{Path: path, Reason: SkipUnreadable, Error: err.Error()})
+			continue
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !w.opts.FollowSymlinks {
+				w.skip(Skipped{Path: path, Reason: SkipSymlink})
+				continue
+			}
+			target, err := os.Stat(path)
+			if err != nil {
+				w.skip(Skipped{Path: path, Reason: SkipBrokenSymlink, Error: err.Error()})
+				continue
+			}
+			info = target
+		}
+
+		switch {
+		case info.IsDir():
+			if !w.opts.Recursive {
+				continue
+			}
+			if w.excluded(path) {
+				w.skip(Skipped{Path: path, Reason: SkipExcluded})
+				continue
+			}
+			if first, seen := w.markDir(path, info); seen {
+				w.skip(Skipped{Path: path, Reason: SkipVisited, Original: first})
+				continue
+			}
+			if err := w.walk(path); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			w.addFile(path, info)
+		}
+	}
+	return nil
+}
+
+// markDir records a directory as visited, returning the first path it was
+// visited under when it was seen before
+func (w *walker) markDir(path string, info os.FileInfo) (string, bool) {
+	id, ok := identity(path, info)
+	if !ok {
+		return "", false
+	}
+	if first, seen := w.dirs[id]; seen {
+		return first, true
+	}
+	w.dirs[id] = path
+	return "", false
+}
+
+// excluded reports whether a directory matches an excluded pattern by its
+// name or its slash-separated path relative to the root
+func (w *walker) excluded(dir string) bool {
+	if len(w.opts.ExcludeDirs) == 0 {
+		return false
+	}
+	name := filepath.Base(dir)
+	rel, err := filepath.Rel(w.rootDir, dir)
+	if err != nil {
+		rel = name
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range w.opts.ExcludeDirs {
+		pattern = strings.Trim(filepath.ToSlash(strings.TrimSpace(pattern)), "/")
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *walker) addFile(path string, info os.FileInfo) {
+	// Hidden files are judged by the name they are found under
+	if w.opts.SkipHidden && utils.IsHidden(info) {
+		return
+	}
+	if w.opts.DedupHardlinks {
+		if id, ok := identity(path, info); ok {
+			if first, seen := w.files[id]; seen {
+				w.skip(Skipped{Path: path, Reason: SkipDuplicate, Original: first})
+				return
+			}
+			w.files[id] = path
+		}
+	}
+	w.result.Files = append(w.result.Files, File{Path: path, Info: info})
+}
+
+func (w *walker) skip(s Skipped) {
+	w.result.Skipped = append(w.result.Skipped, s)
+}
@@ -0,0 +1,251 @@
+package scanner
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nadeeshame/rag-knowledge-service/pkg/utils"
+)
+
+// sniffLen is how many leading bytes are inspected, as for http.DetectContentType
+const sniffLen = 512
+
+// signature identifies a file format by the bytes at the start of a file
+type signature struct {
+	magic    []byte
+	mimeType string
+	ext      string
+}
+
+// signatures is checked before http.DetectContentType, for formats it does
+// not know or reports too generally. Zip archives are looked into to tell
+// Office documents apart.
+var signatures = []signature{
+	{[]byte("%PDF-"), "application/pdf", ".pdf"},
+	{[]byte("\x89PNG\r\n\x1a\n"), "image/png", ".png"},
+	{[]byte("\xff\xd8\xff"), "image/jpeg", ".jpg"},
+	{[]byte("GIF87a"), "image/gif", ".gif"},
+	{[]byte("GIF89a"), "image/gif", ".gif"},
+	{[]byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"), "application/x-ole-storage", ".doc"},
+	{[]byte("PK\x03\x04"), "application/zip", ".zip"},
+	{[]byte("\x1f\x8b"), "application/gzip", ".gz"},
+	{[]byte("SQLite format 3\x00"), "application/vnd.sqlite3", ".sqlite"},
+	{[]byte("PAR1"), "application/vnd.apache.parquet", ".parquet"},
+	{[]byte("\x7fELF"), "application/x-executable", ""},
+}
+
+// zipFormats maps a part found in a zip archive to the document format it marks
+var zipFormats = []struct {
+	part     string
+	mimeType string
+	ext      string
+}{
+	{"word/document.xml", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", ".docx"},
+	{"xl/workbook.xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", ".xlsx"},
+	{"ppt/presentation.xml", "application/vnd.openxmlformats-officedocument.presentationml.presentation", ".pptx"},
+	{"content.xml", "application/vnd.oasis.opendocument.text", ".odt"},
+}
+
+// odfFormats maps the mimetype entry OpenDocument files start with to
+// their extension
+var odfFormats = map[string]string{
+	"application/vnd.oasis.opendocument.text":         ".odt",
+	"application/vnd.oasis.opendocument.spreadsheet":  ".ods",
+	"application/vnd.oasis.opendocument.presentation": ".odp",
+	"application/vnd.oasis.opendocument.graphics":     ".odg",
+}
+
+// equivalentExts lists extensions that match content sniffed as another
+// extension of the same format
+var equivalentExts = map[string][]string{
+	".jpg":    {".jpeg"},
+	".doc":    {".xls", ".ppt"}, // all OLE compound files
+	".zip":    {".docx", ".xlsx", ".pptx", ".odt", ".ods", ".odp", ".odg"},
+	".sqlite": {".sqlite3", ".db", ".db3"},
+}
+
+// Detection is the type of a file judged from its content as well as its
+// extension
+type Detection struct {
+	// Extension is the type used to route the file to a processor: the
+	// file's own extension unless its content says otherwise
+	Extension string `json:"extension"`
+	// MimeType is the MIME type sniffed from the content
+	MimeType string `json:"mime_type"`
+	// Mismatch warns that the extension and the content disagree
+	Mismatch string `json:"mismatch,omitempty"`
+}
+
+// Detect sniffs the leading bytes of a file and reconciles them with its
+// extension. A .txt file holding a PDF is detected as .pdf; a file without
+// an extension is detected from its content alone.
+func Detect(filePath string) (Detection, error) {
+	file, err := os.Open(utils.LocalPath(filePath))
+	if err != nil {
+		return Detection{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Detection{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	head = head[:n]
+
+	ext := strings.ToLower(utils.Ext(filePath))
+	if n == 0 {
+		return Detection{Extension: ext, MimeType: utils.GetMimeType(filePath)}, nil
+	}
+
+	mimeType, sniffedExt, text := sniff(head)
+	if sniffedExt == ".zip" {
+		if info, statErr := file.Stat(); statErr == nil {
+			mimeType, sniffedExt = sniffZip(file, info.Size(), mimeType, sniffedExt)
+		}
+	}
+
+	d := Detection{Extension: ext, MimeType: mimeType}
+	switch {
+	case matchesContent(ext, sniffedExt, text):
+		if ext == "" {
+			d.Extension = sniffedExt
+		}
+	case sniffedExt != "":
+		d.Extension = sniffedExt
+		d.Mismatch = fmt.Sprintf("extension %s does not match %s content", ext, mimeType)
+	default:
+		d.Mismatch = fmt.Sprintf("extension %s does not match binary %s content", ext, mimeType)
+	}
+	return d, nil
+}
+
+// sniff returns the MIME type and extension implied by the leading bytes
+// of a file, and whether the content is text. Text is reported as .txt
+// (or .svg for SVG drawings); unrecognized binary content has no extension.
+func sniff(head []byte) (mimeType, ext string, text bool) {
+	for _, sig := range signatures {
+		if bytes.HasPrefix(head, sig.magic) {
+			return sig.mimeType, sig.ext, false
+		}
+	}
+
+	mimeType = http.DetectContentType(head)
+	if mimeType == "image/bmp" && !isBMP(head) {
+		// "BM" alone is a weak signature that plain text can start with
+		mimeType = "application/octet-stream"
+		if utf8.Valid(head) {
+			mimeType = "text/plain; charset=utf-8"
+		}
+	}
+	if strings.HasPrefix(mimeType, "text/") {
+		if bytes.Contains(head, []byte("<svg")) {
+			return "image/svg+xml", ".svg", true
+		}
+		return mimeType, ".txt", true
+	}
+
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 && strings.HasPrefix(mimeType, "image/") {
+		return mimeType, exts[0], false
+	}
+	return mimeType, "", false
+}
+
+// isBMP checks the reserved header bytes that are zero in bitmap files
+func isBMP(head []byte) bool {
+	return len(head) >= 10 && bytes.Equal(head[6:10], []byte{0, 0, 0, 0})
+}
+
+// sniffZip tells Office and OpenDocument files apart from plain zip archives,
+// and OpenDocument texts, spreadsheets, presentations and drawings apart
+func sniffZip(r io.ReaderAt, size int64, mimeType, ext string) (string, string) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return mimeType, ext
+	}
+	for _, f := range archive.File {
+		if f.Name == "mimetype" && f.UncompressedSize64 < 128 {
+			if odfMime, ok := readMimetype(f); ok {
+				if odfExt, ok := odfFormats[odfMime]; ok {
+					return odfMime, odfExt
+				}
+			}
+		}
+	}
+	for _, format := range zipFormats {
+		for _, f := range archive.File {
+			if f.Name == format.part {
+				return format.mimeType, format.ext
+			}
+		}
+	}
+	return mimeType, ext
+}
+
+// readMimetype reads the mimetype entry of an OpenDocument file
+func readMimetype(f *zip.File) (string, bool) {
+	r, err := f.Open()
+	if err != nil {
+		return "", false
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, 128))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// matchesContent reports whether a file extension agrees with the sniffed
+// content. Text content matches any extension not claimed by a binary
+// format, since code, markup and data files all look like plain text.
+func matchesContent(ext, sniffedExt string, text bool) bool {
+	if ext == "" {
+		return true
+	}
+	if text {
+		return !isBinaryExt(ext)
+	}
+	if sniffedExt == "" {
+		return !isBinaryExt(ext) && !utils.IsDocumentFile(ext) && !utils.IsCodeFile(ext) &&
+			!utils.IsStructuredFile(ext) && !utils.IsSpreadsheetFile(ext)
+	}
+	if ext == sniffedExt {
+		return true
+	}
+	for _, e := range equivalentExts[sniffedExt] {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinaryExt reports whether an extension belongs to a binary format
+func isBinaryExt(ext string) bool {
+	for _, sig := range signatures {
+		if sig.ext == ext {
+			return true
+		}
+	}
+	for _, format := range zipFormats {
+		if format.ext == ext {
+			return true
+		}
+	}
+	for _, exts := range equivalentExts {
+		for _, e := range exts {
+			if e == ext {
+				return true
+			}
+		}
+	}
+	return utils.IsImageFile(ext) && ext != ".svg"
+}
@@ -0,0 +1,86 @@
+package slackbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// message is a message posted to Slack, or the answer to a slash command
+type message struct {
+	Channel      string `json:"channel,omitempty"`
+	ThreadTS     string `json:"thread_ts,omitempty"`
+	Text         string `json:"text"`
+	ResponseType string `json:"response_type,omitempty"` // ephemeral or in_channel, for slash commands
+	UnfurlLinks  bool   `json:"unfurl_links"`
+}
+
+// slackAPI calls the Slack Web API
+type slackAPI struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// postMessage posts a message with a bot token and returns its timestamp,
+// which identifies its thread
+func (a *slackAPI) postMessage(ctx context.Context, token string, msg message) (string, error) {
+	var resp struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := a.post(ctx, a.baseURL+"/chat.postMessage", token, msg, &resp); err != nil {
+		return "", err
+	}
+	if !resp.OK {
+		return "", fmt.Errorf("chat.postMessage failed: %s", resp.Error)
+	}
+	return resp.TS, nil
+}
+
+// respond answers a slash command through its response URL, which needs
+// no token
+func (a *slackAPI) respond(ctx context.Context, responseURL string, msg message) error {
+	if responseURL == "" {
+		return fmt.Errorf("no response URL to answer with")
+	}
+	return a.post(ctx, responseURL, "", msg, nil)
+}
+
+func (a *slackAPI) post(ctx context.Context, url, token string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Slack: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read Slack response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack responded %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode Slack response: %w", err)
+	}
+	return nil
+}
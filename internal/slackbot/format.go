@@ -0,0 +1,82 @@
+package slackbot
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+)
+
+// maxMessageChars keeps answers within the length Slack displays in full
+const maxMessageChars = 3500
+
+var (
+	// bold matches Markdown bold, which Slack writes with single asterisks
+	bold = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+	// heading matches Markdown headings, shown in bold instead
+	heading = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`)
+)
+
+// formatAnswer writes an answer as a Slack message, followed by its
+// sources with the citation numbers that refer to each. Sources link to
+// sourceURL, in which {document_id} and {file_path} are replaced; without
+// it their path is shown.
+func formatAnswer(result *models.QueryResult, sourceURL string) string {
+	answer := escape(strings.TrimSpace(result.Answer))
+	answer = bold.ReplaceAllString(answer, "*$1*")
+	answer = heading.ReplaceAllString(answer, "*$1*")
+	if answer == "" {
+		answer = "No answer was found."
+	}
+	if runes := []rune(answer); len(runes) > maxMessageChars {
+		answer = string(runes[:maxMessageChars]) + "…"
+	}
+	if len(result.Sources) == 0 {
+		return answer
+	}
+
+	// A document retrieved as several chunks is listed once
+	var order []string
+	citations := make(map[string][]string)
+	sources := make(map[string]models.SearchResult)
+	for i, source := range result.Sources {
+		id := source.DocumentID.String()
+		if _, seen := sources[id]; !seen {
+			order = append(order, id)
+			sources[id] = source
+		}
+		citations[id] = append(citations[id], fmt.Sprintf("[%d]", i+1))
+	}
+
+	var b strings.Builder
+	b.WriteString(answer)
+	b.WriteString("\n\n*Sources*")
+	for _, id := range order {
+		fmt.Fprintf(&b, "\n%s %s", strings.Join(citations[id], ""), link(sources[id], sourceURL))
+	}
+	return b.String()
+}
+
+// link writes a source as a link to sourceURL, or as its path
+func link(source models.SearchResult, sourceURL string) string {
+	if sourceURL == "" {
+		return "`" + escape(source.FilePath) + "`"
+	}
+	name := source.FileName
+	if name == "" {
+		name = source.FilePath
+	}
+	target := strings.NewReplacer(
+		"{document_id}", source.DocumentID.String(),
+		"{file_path}", (&url.URL{Path: source.FilePath}).EscapedPath(),
+	).Replace(sourceURL)
+	// The link text may not contain the separator of the link
+	return "<" + target + "|" + strings.ReplaceAll(escape(name), "|", "/") + ">"
+}
+
+// escape escapes the characters Slack reads as markup
+func escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
@@ -0,0 +1,330 @@
+// Package slackbot answers questions asked in Slack. The Slack app's
+// slash command and Events API requests are verified with the app's
+// signing secret and acknowledged at once, as Slack expects an answer
+// within three seconds; the question is then sent to the query service and
+// the answer posted in a thread with links to its sources. Each workspace
+// has its own bot token, channels and identity.
+package slackbot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/pkg/client"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxSkew is how old a request's timestamp may be, against replays
+	maxSkew = 5 * time.Minute
+	// maxBodyBytes caps the size of a request from Slack
+	maxBodyBytes = 1 << 20
+	// maxInFlight caps the questions answered at once
+	maxInFlight = 32
+)
+
+// Bot serves the Slack app
+type Bot struct {
+	cfg      config.SlackConfig
+	query    client.Querier
+	api      *slackAPI
+	inFlight chan struct{}
+	logger   *zap.Logger
+}
+
+// New creates the Slack app, asking questions with query and calling the
+// Slack API with httpClient
+func New(cfg config.SlackConfig, query client.Querier, httpClient *http.Client, logger *zap.Logger) *Bot {
+	return &Bot{
+		cfg:      cfg,
+		query:    query,
+		api:      &slackAPI{baseURL: strings.TrimRight(cfg.APIURL, "/"), httpClient: httpClient},
+		inFlight: make(chan struct{}, maxInFlight),
+		logger:   logger,
+	}
+}
+
+// Register serves the slash command at /slack/commands and the Events API
+// at /slack/events; both need a valid Slack signature rather than an API
+// key
+func (b *Bot) Register(router gin.IRouter) {
+	group := router.Group("/slack", b.verify())
+	group.POST("/commands", b.command)
+	group.POST("/events", b.event)
+}
+
+// contextKeyBody holds the verified body of a request
+const contextKeyBody = "slack.body"
+
+// verify checks the signature Slack computes over the timestamp and body
+// of each request with the app's signing secret, refusing old timestamps
+// so a captured request cannot be replayed
+func (b *Bot) verify() gin.HandlerFunc {
+	secret := []byte(b.cfg.SigningSecret)
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodyBytes+1))
+		if err != nil || len(body) > maxBodyBytes {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request"})
+			return
+		}
+		timestamp := c.GetHeader("X-Slack-Request-Timestamp")
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > maxSkew {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "stale or missing request timestamp"})
+			return
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte("v0:" + timestamp + ":"))
+		mac.Write(body)
+		expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(expected), []byte(c.GetHeader("X-Slack-Signature"))) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid Slack signature"})
+			return
+		}
+		c.Set(contextKeyBody, body)
+		c.Next()
+	}
+}
+
+// workspace returns the configuration of a workspace, with the unset
+// fields taken from the app's; ok is false for workspaces the app may not
+// answer in, which are those not configured unless AnyWorkspace is set
+func (b *Bot) workspace(teamID string) (config.SlackWorkspace, bool) {
+	ws := config.SlackWorkspace{TeamID: teamID}
+	found := false
+	for _, configured := range b.cfg.Workspaces {
+		if configured.TeamID == teamID {
+			ws, found = configured, true
+			break
+		}
+	}
+	if !found && !b.cfg.AnyWorkspace {
+		return ws, false
+	}
+	if ws.BotToken == "" {
+		ws.BotToken = b.cfg.BotToken
+	}
+	if ws.TopK == 0 {
+		ws.TopK = b.cfg.TopK
+	}
+	if ws.SourceURL == "" {
+		ws.SourceURL = b.cfg.SourceURL
+	}
+	return ws, true
+}
+
+// answersIn reports whether a workspace answers in a channel
+func answersIn(ws config.SlackWorkspace, channel string) bool {
+	return len(ws.Channels) == 0 || slices.Contains(ws.Channels, channel)
+}
+
+// command answers a slash command such as /repograph How is auth handled?
+// The question is posted in the channel and answered in its thread; when
+// the bot cannot post there, the answer is sent to the command's response
+// URL instead.
+func (b *Bot) command(c *gin.Context) {
+	form, err := url.ParseQuery(string(c.MustGet(contextKeyBody).([]byte)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form body"})
+		return
+	}
+	teamID, channel, user := form.Get("team_id"), form.Get("channel_id"), form.Get("user_id")
+	text := strings.TrimSpace(form.Get("text"))
+
+	ws, ok := b.workspace(teamID)
+	switch {
+	case !ok:
+		c.JSON(http.StatusOK, ephemeral("This workspace is not set up to ask RepoGraph."))
+		return
+	case !answersIn(ws, channel):
+		c.JSON(http.StatusOK, ephemeral("RepoGraph does not answer in this channel."))
+		return
+	case text == "":
+		c.JSON(http.StatusOK, ephemeral("Ask a question, such as `"+form.Get("command")+" How is authentication handled?`"))
+		return
+	}
+	if !b.acquire() {
+		c.JSON(http.StatusOK, ephemeral("RepoGraph is answering too many questions; try again in a minute."))
+		return
+	}
+
+	ask := &question{workspace: ws, channel: channel, user: user, text: text, responseURL: form.Get("response_url")}
+	go func() {
+		defer b.release()
+		b.answerCommand(ask)
+	}()
+	c.JSON(http.StatusOK, ephemeral("Looking that up…"))
+}
+
+// answerCommand posts a slash command's question and answers it in the
+// question's thread
+func (b *Bot) answerCommand(q *question) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.cfg.Timeout)
+	defer cancel()
+
+	ts, err := b.api.postMessage(ctx, q.workspace.BotToken, message{
+		Channel: q.channel,
+		Text:    "<@" + q.user + "> asked: " + escape(q.text),
+	})
+	if err != nil {
+		b.logger.Warn("Failed to post Slack question; answering through the response URL",
+			zap.String("team_id", q.workspace.TeamID), zap.String("channel", q.channel), zap.Error(err))
+		text := b.answer(ctx, q)
+		if err := b.api.respond(ctx, q.responseURL, message{ResponseType: "in_channel", Text: text}); err != nil {
+			b.logger.Error("Failed to send Slack answer", zap.String("team_id", q.workspace.TeamID), zap.Error(err))
+		}
+		return
+	}
+	q.threadTS = ts
+	b.reply(ctx, q)
+}
+
+// eventEnvelope is the body of an Events API request
+type eventEnvelope struct {
+	Type      string `json:"type"`      // url_verification or event_callback
+	Challenge string `json:"challenge"` // echoed to verify the request URL
+	TeamID    string `json:"team_id"`
+	Event     struct {
+		Type        string `json:"type"` // app_mention, or message in a direct message
+		ChannelType string `json:"channel_type"`
+		Subtype     string `json:"subtype"`
+		BotID       string `json:"bot_id"`
+		User        string `json:"user"`
+		Text        string `json:"text"`
+		Channel     string `json:"channel"`
+		TS          string `json:"ts"`
+		ThreadTS    string `json:"thread_ts"`
+	} `json:"event"`
+}
+
+// mention matches mentions of users, such as the bot's own
+var mention = regexp.MustCompile(`<@[A-Z0-9]+(\|[^>]*)?>`)
+
+// event answers the bot being mentioned in a channel or messaged
+// directly, in the thread of the message. Slack retries events it thinks
+// were not received; they were acknowledged already, so retries are
+// ignored.
+func (b *Bot) event(c *gin.Context) {
+	var env eventEnvelope
+	if err := json.Unmarshal(c.MustGet(contextKeyBody).([]byte), &env); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event body"})
+		return
+	}
+	if env.Type == "url_verification" {
+		c.JSON(http.StatusOK, gin.H{"challenge": env.Challenge})
+		return
+	}
+	c.Status(http.StatusOK)
+	if env.Type != "event_callback" || c.GetHeader("X-Slack-Retry-Num") != "" {
+		return
+	}
+
+	ev := env.Event
+	direct := ev.Type == "message" && ev.ChannelType == "im"
+	if (ev.Type != "app_mention" && !direct) || ev.BotID != "" || ev.Subtype != "" {
+		return
+	}
+	ws, ok := b.workspace(env.TeamID)
+	if !ok || (!direct && !answersIn(ws, ev.Channel)) {
+		b.logger.Debug("Ignoring Slack event", zap.String("team_id", env.TeamID), zap.String("channel", ev.Channel))
+		return
+	}
+	text := strings.TrimSpace(mention.ReplaceAllString(ev.Text, ""))
+	if text == "" {
+		return
+	}
+	threadTS := ev.ThreadTS
+	if threadTS == "" {
+		threadTS = ev.TS
+	}
+	q := &question{workspace: ws, channel: ev.Channel, user: ev.User, text: text, threadTS: threadTS}
+
+	if !b.acquire() {
+		b.logger.Warn("Dropping Slack question; too many in flight", zap.String("team_id", env.TeamID))
+		return
+	}
+	go func() {
+		defer b.release()
+		ctx, cancel := context.WithTimeout(context.Background(), b.cfg.Timeout)
+		defer cancel()
+		b.reply(ctx, q)
+	}()
+}
+
+func (b *Bot) acquire() bool {
+	select {
+	case b.inFlight <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *Bot) release() {
+	<-b.inFlight
+}
+
+// question is a question asked in Slack
+type question struct {
+	workspace   config.SlackWorkspace
+	channel     string
+	user        string
+	text        string
+	threadTS    string // thread the answer is posted in
+	responseURL string // of a slash command
+}
+
+// reply answers a question in its thread
+func (b *Bot) reply(ctx context.Context, q *question) {
+	text := b.answer(ctx, q)
+	_, err := b.api.postMessage(ctx, q.workspace.BotToken, message{Channel: q.channel, ThreadTS: q.threadTS, Text: text})
+	if err != nil {
+		b.logger.Error("Failed to post Slack answer",
+			zap.String("team_id", q.workspace.TeamID), zap.String("channel", q.channel), zap.Error(err))
+	}
+}
+
+// answer asks the query service a question for the workspace and formats
+// the answer, or the failure, as a Slack message
+func (b *Bot) answer(ctx context.Context, q *question) string {
+	identity := &models.Identity{
+		UserID: "slack:" + q.workspace.TeamID + ":" + q.user,
+		Groups: q.workspace.Groups,
+		Tenant: q.workspace.Tenant,
+	}
+	req := &client.QueryRequest{Text: q.text, TopK: q.workspace.TopK}
+	req.Filter.Collection = q.workspace.Collection
+
+	start := time.Now()
+	result, err := b.query.Ask(client.WithIdentity(ctx, identity), req)
+	if err != nil {
+		b.logger.Warn("Failed to answer Slack question",
+			zap.String("team_id", q.workspace.TeamID), zap.String("channel", q.channel), zap.Error(err))
+		return "Sorry, RepoGraph could not answer that: " + escape(err.Error())
+	}
+	b.logger.Info("Answered Slack question",
+		zap.String("team_id", q.workspace.TeamID),
+		zap.String("channel", q.channel),
+		zap.String("query_id", result.QueryID.String()),
+		zap.Int("sources", len(result.Sources)),
+		zap.Duration("duration", time.Since(start)))
+	return formatAnswer(result, q.workspace.SourceURL)
+}
+
+func ephemeral(text string) message {
+	return message{ResponseType: "ephemeral", Text: text}
+}
@@ -0,0 +1,36 @@
+package slackbot
+
+import (
+	"testing"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestWorkspace(t *testing.T) {
+	listed := config.SlackWorkspace{TeamID: "T1", Groups: []string{"engineering"}, Tenant: "acme"}
+	tests := []struct {
+		name   string
+		cfg    config.SlackConfig
+		team   string
+		ok     bool
+		token  string
+		tenant string
+	}{
+		{"listed team takes the app token", config.SlackConfig{BotToken: "xoxb-app", Workspaces: []config.SlackWorkspace{listed}}, "T1", true, "xoxb-app", "acme"},
+		{"unlisted team refused despite the app token", config.SlackConfig{BotToken: "xoxb-app", Workspaces: []config.SlackWorkspace{listed}}, "T2", false, "", ""},
+		{"unlisted team answered when opted in", config.SlackConfig{BotToken: "xoxb-app", AnyWorkspace: true, Workspaces: []config.SlackWorkspace{listed}}, "T2", true, "xoxb-app", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot := New(tt.cfg, nil, nil, zap.NewNop())
+			ws, ok := bot.workspace(tt.team)
+			if ok != tt.ok {
+				t.Fatalf("workspace(%q) ok = %v, want %v", tt.team, ok, tt.ok)
+			}
+			if ok && (ws.BotToken != tt.token || ws.Tenant != tt.tenant) {
+				t.Errorf("workspace(%q) = token %q tenant %q, want %q %q", tt.team, ws.BotToken, ws.Tenant, tt.token, tt.tenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package sparse
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+)
+
+// stopWords are English words too common to say anything about a chunk
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "have": true, "how": true, "in": true, "is": true, "it": true,
+	"its": true, "of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true,
+	"was": true, "what": true, "when": true, "where": true, "which": true, "who": true, "why": true,
+	"will": true, "with": true, "does": true, "do": true, "can": true,
+}
+
+// bm25Encoder weights the terms of a document by BM25's saturated term
+// frequency, normalized by the document's length. Terms are hashed to
+// dimensions, so no vocabulary has to be fitted or shared between the
+// services. Without corpus statistics there is no inverse document
+// frequency; query terms are weighted equally instead, and rare terms still
+// count because few chunks contain them.
+type bm25Encoder struct {
+	k1, b     float64
+	avgTokens float64
+}
+
+func newBM25Encoder(k1, b float64, avgTokens int) *bm25Encoder {
+	return &bm25Encoder{k1: k1, b: b, avgTokens: float64(avgTokens)}
+}
+
+func (e *bm25Encoder) Name() string {
+	return "bm25"
+}
+
+// EncodeDocument weights each term by tf*(k1+1) / (tf + k1*(1-b+b*len/avg))
+func (e *bm25Encoder) EncodeDocument(_ context.Context, text string) (*pinecone.SparseValues, error) {
+	tokens := terms(text)
+	counts := make(map[uint32]float64)
+	for _, term := range tokens {
+		counts[termIndex(term)]++
+	}
+
+	norm := e.k1 * (1 - e.b + e.b*float64(len(tokens))/e.avgTokens)
+	weights := make(map[uint32]float32, len(counts))
+	for index, tf := range counts {
+		weights[index] = float32(tf * (e.k1 + 1) / (tf + norm))
+	}
+	return fromWeights(weights), nil
+}
+
+// EncodeQuery weights each distinct term equally, scaled to unit length so
+// the sparse part of a query weighs the same however many terms it has
+func (e *bm25Encoder) EncodeQuery(_ context.Context, text string) (*pinecone.SparseValues, error) {
+	weights := make(map[uint32]float32)
+	for _, term := range terms(text) {
+		weights[termIndex(term)] = 1
+	}
+	if len(weights) > 0 {
+		unit := float32(1 / math.Sqrt(float64(len(weights))))
+		for index := range weights {
+			weights[index] = unit
+		}
+	}
+	return fromWeights(weights), nil
+}
+
+// terms splits text into lower-case terms, leaving out stop words.
+// Identifiers are kept whole and also split into their words, so
+// "parseConfigFile" and "parse_config_file" match "config file" as well as
+// each other's exact spelling.
+func terms(text string) []string {
+	var out []string
+	for _, token := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		token = strings.Trim(token, "_")
+		if token == "" {
+			continue
+		}
+		words := identifierWords(token)
+		if whole := strings.ToLower(token); len(words) > 1 || !stopWords[whole] {
+			out = append(out, whole)
+		}
+		if len(words) > 1 {
+			for _, word := range words {
+				if !stopWords[word] {
+					out = append(out, word)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// identifierWords splits an identifier at underscores and case changes,
+// in lower case: "HTTPServerError" becomes http, server and error
+func identifierWords(token string) []string {
+	var words []string
+	for _, part := range strings.Split(token, "_") {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			boundary := unicode.IsLower(prev) && unicode.IsUpper(cur) ||
+				unicode.IsLetter(prev) != unicode.IsLetter(cur) ||
+				// The last capital of an acronym starts the next word
+				unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if boundary {
+				words = append(words, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			words = append(words, strings.ToLower(string(runes[start:])))
+		}
+	}
+	return words
+}
+
+// termIndex hashes a term to its dimension
+func termIndex(term string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(term)) //nolint:errcheck // hash writes never fail
+	return h.Sum32()
+}
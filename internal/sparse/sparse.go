@@ -0,0 +1,75 @@
+// Package sparse encodes text as sparse vectors of term weights. They are
+// stored beside the dense embeddings of chunks, and hybrid queries weigh
+// both, so exact identifiers, error codes and other rare terms that a dense
+// model blurs still find the chunks that contain them.
+package sparse
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+)
+
+// Encoder turns text into sparse vectors. Documents and queries are
+// encoded differently: a document's terms are weighted by how much they
+// say about it, a query's by how much they matter to the search.
+type Encoder interface {
+	Name() string
+	EncodeDocument(ctx context.Context, text string) (*pinecone.SparseValues, error)
+	EncodeQuery(ctx context.Context, text string) (*pinecone.SparseValues, error)
+}
+
+// New creates the encoder selected by configuration. It returns nil
+// without an error when sparse vectors are disabled.
+func New(cfg *config.Config) (Encoder, error) {
+	c := cfg.Sparse
+	switch c.Encoder {
+	case "none", "":
+		return nil, nil
+	case "bm25":
+		return newBM25Encoder(c.K1, c.B, c.AvgTokens), nil
+	case "splade":
+		return newSPLADEEncoder(c.Endpoint, c.APIKey), nil
+	default:
+		return nil, fmt.Errorf("unknown sparse encoder: %s", c.Encoder)
+	}
+}
+
+// Weigh scales the two parts of a hybrid query: the dense embedding by
+// alpha and the sparse vector by 1 - alpha. Pinecone adds the dot products
+// of the parts, so alpha 1 searches by meaning only and 0 by terms only.
+// The sparse vector is nil when alpha leaves it no weight.
+func Weigh(embedding []float32, sv *pinecone.SparseValues, alpha float64) ([]float32, *pinecone.SparseValues) {
+	dense := make([]float32, len(embedding))
+	for i, v := range embedding {
+		dense[i] = v * float32(alpha)
+	}
+	if sv == nil || alpha >= 1 {
+		return dense, nil
+	}
+	weighted := &pinecone.SparseValues{Indices: sv.Indices, Values: make([]float32, len(sv.Values))}
+	for i, v := range sv.Values {
+		weighted.Values[i] = v * float32(1-alpha)
+	}
+	return dense, weighted
+}
+
+// fromWeights returns the sparse vector of term weights in index order,
+// or nil when there are none, as Pinecone rejects empty sparse values
+func fromWeights(weights map[uint32]float32) *pinecone.SparseValues {
+	if len(weights) == 0 {
+		return nil
+	}
+	sv := &pinecone.SparseValues{
+		Indices: slices.Sorted(maps.Keys(weights)),
+		Values:  make([]float32, 0, len(weights)),
+	}
+	for _, index := range sv.Indices {
+		sv.Values = append(sv.Values, weights[index])
+	}
+	return sv
+}
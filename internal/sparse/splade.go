@@ -0,0 +1,86 @@
+package sparse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+)
+
+// spladeEncoder uses a SPLADE model served by text-embeddings-inference,
+// whose /embed_sparse endpoint returns the weight of each vocabulary term
+// the text activates, expanded to related terms it does not contain.
+// Documents and queries are encoded by the same model.
+type spladeEncoder struct {
+	endpoint   string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newSPLADEEncoder(endpoint, apiKey string) *spladeEncoder {
+	return &spladeEncoder{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (e *spladeEncoder) Name() string {
+	return "splade"
+}
+
+func (e *spladeEncoder) EncodeDocument(ctx context.Context, text string) (*pinecone.SparseValues, error) {
+	return e.encode(ctx, text)
+}
+
+func (e *spladeEncoder) EncodeQuery(ctx context.Context, text string) (*pinecone.SparseValues, error) {
+	return e.encode(ctx, text)
+}
+
+func (e *spladeEncoder) encode(ctx context.Context, text string) (*pinecone.SparseValues, error) {
+	body, err := json.Marshal(map[string]interface{}{"inputs": []string{text}, "truncate": true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+"/embed_sparse", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096)) //nolint:errcheck
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	var out [][]struct {
+		Index uint32  `json:"index"`
+		Value float32 `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no sparse vector in response")
+	}
+
+	weights := make(map[uint32]float32, len(out[0]))
+	for _, term := range out[0] {
+		weights[term.Index] = term.Value
+	}
+	return fromWeights(weights), nil
+}
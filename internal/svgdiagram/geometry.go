@@ -0,0 +1,265 @@
+package svgdiagram
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// matrix is an affine transform [a b c d e f], mapping (x, y) to
+// (a*x + c*y + e, b*x + d*y + f)
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// times returns the transform applying n and then m
+func (m matrix) times(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[2]*n[1],
+		m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3],
+		m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4],
+		m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m matrix) apply(p point) point {
+	return point{m[0]*p.x + m[2]*p.y + m[4], m[1]*p.x + m[3]*p.y + m[5]}
+}
+
+func (m matrix) applyAll(points []point) []point {
+	out := make([]point, len(points))
+	for i, p := range points {
+		out[i] = m.apply(p)
+	}
+	return out
+}
+
+var transformFunc = regexp.MustCompile(`(matrix|translate|scale|rotate|skewX|skewY)\s*\(([^)]*)\)`)
+
+// parseTransform parses a transform attribute; unknown functions are left
+// out
+func parseTransform(value string) matrix {
+	m := identity
+	for _, fn := range transformFunc.FindAllStringSubmatch(value, -1) {
+		args := numbers(fn[2])
+		arg := func(i int, fallback float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return fallback
+		}
+
+		var t matrix
+		switch fn[1] {
+		case "matrix":
+			if len(args) != 6 {
+				continue
+			}
+			copy(t[:], args)
+		case "translate":
+			t = matrix{1, 0, 0, 1, arg(0, 0), arg(1, 0)}
+		case "scale":
+			sx := arg(0, 1)
+			t = matrix{sx, 0, 0, arg(1, sx), 0, 0}
+		case "rotate":
+			rad := arg(0, 0) * math.Pi / 180
+			cos, sin := math.Cos(rad), math.Sin(rad)
+			cx, cy := arg(1, 0), arg(2, 0)
+			t = matrix{1, 0, 0, 1, cx, cy}.
+				times(matrix{cos, sin, -sin, cos, 0, 0}).
+				times(matrix{1, 0, 0, 1, -cx, -cy})
+		case "skewX":
+			t = matrix{1, 0, math.Tan(arg(0, 0) * math.Pi / 180), 1, 0, 0}
+		case "skewY":
+			t = matrix{1, math.Tan(arg(0, 0) * math.Pi / 180), 0, 1, 0, 0}
+		}
+		m = m.times(t)
+	}
+	return m
+}
+
+// number parses a length attribute in user units; units other than px and
+// percentages count as 0
+func number(value string) float64 {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// firstNumber parses the first of a list of coordinates, as text elements
+// take one per character
+func firstNumber(value string) float64 {
+	if n := numbers(value); len(n) > 0 {
+		return n[0]
+	}
+	return 0
+}
+
+// numbers parses a list of numbers separated by whitespace or commas, or
+// packed together as in "10-5.5.5"
+func numbers(value string) []float64 {
+	var out []float64
+	for i := 0; ; {
+		n, next, ok := scanNumber(value, i)
+		if !ok {
+			return out
+		}
+		out = append(out, n)
+		i = next
+	}
+}
+
+// pointList parses the points attribute of a polygon or polyline
+func pointList(value string) []point {
+	n := numbers(value)
+	points := make([]point, 0, len(n)/2)
+	for i := 0; i+1 < len(n); i += 2 {
+		points = append(points, point{n[i], n[i+1]})
+	}
+	return points
+}
+
+func skipSeparators(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == ',' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// scanNumber reads a number starting at i, after any separators
+func scanNumber(s string, i int) (float64, int, bool) {
+	i = skipSeparators(s, i)
+	start := i
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0, start, false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			for i = j; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			}
+		}
+	}
+	n, err := strconv.ParseFloat(s[start:i], 64)
+	if err != nil {
+		return 0, start, false
+	}
+	return n, i, true
+}
+
+// pathArgs is the number of arguments of each path command
+var pathArgs = map[byte]int{
+	'M': 2, 'L': 2, 'H': 1, 'V': 1, 'C': 6, 'S': 4, 'Q': 4, 'T': 2, 'A': 7, 'Z': 0,
+}
+
+// pathPoints returns the end points and control points of the segments of
+// path data in order, and whether the path is closed. Parsing stops at the
+// first malformed command, keeping the points before it.
+func pathPoints(d string) ([]point, bool) {
+	var (
+		points        []point
+		current, init point
+		closed        bool
+		command       byte
+	)
+	for i := 0; ; {
+		i = skipSeparators(d, i)
+		if i >= len(d) {
+			break
+		}
+		if c := d[i]; c >= 'A' && c <= 'z' && c != 'e' && c != 'E' {
+			command = c
+			i++
+		} else if command == 0 {
+			break
+		}
+
+		upper := command &^ 0x20
+		count, ok := pathArgs[upper]
+		if !ok {
+			break
+		}
+		relative := command != upper
+		if upper == 'Z' {
+			current, closed = init, true
+			command = 0
+			continue
+		}
+
+		args := make([]float64, count)
+		for k := range args {
+			var n float64
+			if upper == 'A' && (k == 3 || k == 4) {
+				// Arc flags may be packed without separators
+				i = skipSeparators(d, i)
+				if i >= len(d) || (d[i] != '0' && d[i] != '1') {
+					return points, closed
+				}
+				n, i = float64(d[i]-'0'), i+1
+			} else if n, i, ok = scanNumber(d, i); !ok {
+				return points, closed
+			}
+			args[k] = n
+		}
+
+		at := func(x, y float64) point {
+			if relative {
+				return point{current.x + x, current.y + y}
+			}
+			return point{x, y}
+		}
+		switch upper {
+		case 'M', 'L', 'T':
+			current = at(args[0], args[1])
+		case 'H':
+			if relative {
+				current.x += args[0]
+			} else {
+				current.x = args[0]
+			}
+		case 'V':
+			if relative {
+				current.y += args[0]
+			} else {
+				current.y = args[0]
+			}
+		case 'C':
+			points = append(points, at(args[0], args[1]), at(args[2], args[3]))
+			current = at(args[4], args[5])
+		case 'S', 'Q':
+			points = append(points, at(args[0], args[1]))
+			current = at(args[2], args[3])
+		case 'A':
+			current = at(args[5], args[6])
+		}
+		points = append(points, current)
+
+		if upper == 'M' {
+			init = current
+			// Coordinates after a moveto are implicit linetos
+			command = 'L' | (command & 0x20)
+		}
+	}
+	return points, closed
+}
@@ -0,0 +1,575 @@
+// Package svgdiagram reads what an SVG drawing says: its title and
+// description, the labelled shapes, the lines connecting them and the
+// groups they are arranged in. Connections are inferred from where line and
+// path endpoints meet shapes, so diagrams exported by drawing tools index
+// as components and relationships rather than loose words.
+package svgdiagram
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/safeparse"
+)
+
+const (
+	// endpointTolerance is how far outside a shape a line may end and
+	// still connect to it, in user units
+	endpointTolerance = 15
+	// labelDistance is how far from the middle of a connection its label
+	// may be
+	labelDistance = 30
+	// minShapeSize leaves out closed shapes smaller than this in both
+	// dimensions, which are arrowheads and decorations rather than nodes
+	minShapeSize = 16
+)
+
+// Diagram is the content of an SVG drawing
+type Diagram struct {
+	Title       string
+	Description string
+	Shapes      []*Shape
+	Connections []Connection
+	Groups      []Group
+	// Text is the text not used as a shape or connection label, in
+	// document order
+	Text []string
+
+	lines []line
+	texts []text
+}
+
+// Shape is a closed shape of the drawing: a rectangle, circle, ellipse,
+// polygon or closed path
+type Shape struct {
+	Kind  string
+	Label string
+
+	box       box
+	title     string
+	texts     []string
+	container bool
+}
+
+// Connection is a line or path from one labelled shape to another
+type Connection struct {
+	From          string
+	To            string
+	Label         string
+	Bidirectional bool
+}
+
+// Group is a labelled group of shapes
+type Group struct {
+	Label   string
+	Members []string
+}
+
+// String formats the connection as "from -> to: label"
+func (c Connection) String() string {
+	arrow := " -> "
+	if c.Bidirectional {
+		arrow = " <-> "
+	}
+	s := c.From + arrow + c.To
+	if c.Label != "" {
+		s += ": " + c.Label
+	}
+	return s
+}
+
+type point struct{ x, y float64 }
+
+type box struct{ minX, minY, maxX, maxY float64 }
+
+func boxOf(points []point) box {
+	b := box{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, p := range points {
+		b.minX, b.maxX = math.Min(b.minX, p.x), math.Max(b.maxX, p.x)
+		b.minY, b.maxY = math.Min(b.minY, p.y), math.Max(b.maxY, p.y)
+	}
+	return b
+}
+
+func (b box) contains(p point, margin float64) bool {
+	return p.x >= b.minX-margin && p.x <= b.maxX+margin && p.y >= b.minY-margin && p.y <= b.maxY+margin
+}
+
+// encloses reports whether o lies within b and is smaller
+func (b box) encloses(o box) bool {
+	return o.minX >= b.minX && o.maxX <= b.maxX && o.minY >= b.minY && o.maxY <= b.maxY && o.area() < b.area()
+}
+
+func (b box) area() float64 {
+	return (b.maxX - b.minX) * (b.maxY - b.minY)
+}
+
+// line is an open line, polyline or path; marker-start and marker-end give
+// its direction
+type line struct {
+	points      []point
+	markerStart bool
+	markerEnd   bool
+}
+
+// text is a text element or foreign object label at its anchor point
+type text struct {
+	at      point
+	content string
+	used    bool
+}
+
+// group is a g element as it is parsed
+type group struct {
+	label  string
+	shapes []*Shape
+}
+
+// frame is an open element
+type frame struct {
+	name      string
+	transform matrix
+	shape     *Shape
+	group     *group
+	text      *strings.Builder // text and foreignObject content
+	at        point
+	isSwitch  bool
+	rendered  bool // a switch already has its rendered child
+}
+
+// skippedElements hold definitions and styling rather than drawn content
+var skippedElements = map[string]bool{
+	"defs": true, "marker": true, "symbol": true, "clipPath": true, "mask": true,
+	"pattern": true, "style": true, "script": true, "metadata": true,
+}
+
+// Parse reads an SVG drawing. Parsing is lenient: HTML entities and
+// entities declared in the document type are expanded, and a malformed
+// document yields what was read before the error along with the error.
+// Drawings are read within the safeparse limits; external entities are
+// never resolved.
+func Parse(r io.Reader) (*Diagram, error) {
+	decoder := safeparse.NewXMLDecoder(r, safeparse.DefaultXMLLimits)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	d := &Diagram{}
+	var (
+		stack  []*frame
+		groups []*group
+		skip   int
+		err    error
+	)
+	for {
+		var token xml.Token
+		token, err = decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skip > 0 || skippedElements[t.Name.Local] {
+				skip++
+				continue
+			}
+			parent := &frame{transform: identity}
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			if parent.isSwitch {
+				if parent.rendered {
+					skip++
+					continue
+				}
+				parent.rendered = true
+			}
+
+			attrs := attrMap(t.Attr)
+			f := &frame{name: t.Name.Local, transform: parent.transform.times(parseTransform(attrs["transform"]))}
+			d.start(f, attrs, stack)
+			if f.group != nil {
+				groups = append(groups, f.group)
+			}
+			stack = append(stack, f)
+
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if len(stack) == 0 {
+				continue
+			}
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			d.end(f, stack)
+
+		case xml.CharData:
+			if skip > 0 {
+				continue
+			}
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].text != nil {
+					stack[i].text.WriteString(string(t))
+					stack[i].text.WriteByte(' ')
+					break
+				}
+			}
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	} else if err != nil {
+		err = fmt.Errorf("failed to parse SVG: %w", err)
+	}
+
+	d.resolve(groups)
+	return d, err
+}
+
+// start handles an opening element
+func (d *Diagram) start(f *frame, attrs map[string]string, stack []*frame) {
+	m := f.transform
+	switch f.name {
+	case "g":
+		f.group = &group{label: firstOf(attrs, "label", "aria-label", "data-name")}
+	case "title", "desc", "text":
+		f.text = &strings.Builder{}
+		f.at = m.apply(point{firstNumber(attrs["x"]), firstNumber(attrs["y"])})
+	case "foreignObject":
+		f.text = &strings.Builder{}
+		x, y := number(attrs["x"]), number(attrs["y"])
+		f.at = m.apply(point{x + number(attrs["width"])/2, y + number(attrs["height"])/2})
+	case "switch":
+		f.isSwitch = true
+	case "rect":
+		x, y, w, h := number(attrs["x"]), number(attrs["y"]), number(attrs["width"]), number(attrs["height"])
+		d.addShape(f, stack, "rect", m.applyAll([]point{{x, y}, {x + w, y}, {x, y + h}, {x + w, y + h}}))
+	case "circle", "ellipse":
+		cx, cy := number(attrs["cx"]), number(attrs["cy"])
+		rx, ry := number(attrs["r"]), number(attrs["r"])
+		if f.name == "ellipse" {
+			rx, ry = number(attrs["rx"]), number(attrs["ry"])
+		}
+		d.addShape(f, stack, f.name, m.applyAll([]point{{cx - rx, cy - ry}, {cx + rx, cy - ry}, {cx - rx, cy + ry}, {cx + rx, cy + ry}}))
+	case "polygon":
+		d.addShape(f, stack, "polygon", m.applyAll(pointList(attrs["points"])))
+	case "line":
+		d.addLine(attrs, m.applyAll([]point{
+			{number(attrs["x1"]), number(attrs["y1"])},
+			{number(attrs["x2"]), number(attrs["y2"])},
+		}))
+	case "polyline":
+		d.addLine(attrs, m.applyAll(pointList(attrs["points"])))
+	case "path":
+		points, closed := pathPoints(attrs["d"])
+		if closed {
+			d.addShape(f, stack, "path", m.applyAll(points))
+		} else {
+			d.addLine(attrs, m.applyAll(points))
+		}
+	}
+}
+
+// end handles a closing element
+func (d *Diagram) end(f *frame, stack []*frame) {
+	if f.text == nil {
+		return
+	}
+	content := strings.Join(strings.Fields(strings.ToValidUTF8(f.text.String(), string(utf8.RuneError))), " ")
+	if content == "" {
+		return
+	}
+
+	var parent *frame
+	if len(stack) > 0 {
+		parent = stack[len(stack)-1]
+	}
+	switch f.name {
+	case "title", "desc":
+		switch {
+		case parent == nil || parent.name == "svg":
+			if f.name == "title" && d.Title == "" {
+				d.Title = content
+			} else if f.name == "desc" && d.Description == "" {
+				d.Description = content
+			}
+		case parent.shape != nil && f.name == "title":
+			parent.shape.title = content
+		case parent.group != nil && f.name == "title" && parent.group.label == "":
+			parent.group.label = content
+		}
+	default:
+		d.texts = append(d.texts, text{at: f.at, content: content})
+	}
+}
+
+// addShape records a closed shape in the groups it is drawn in
+func (d *Diagram) addShape(f *frame, stack []*frame, kind string, points []point) {
+	if len(points) == 0 {
+		return
+	}
+	b := boxOf(points)
+	if b.maxX-b.minX < minShapeSize && b.maxY-b.minY < minShapeSize {
+		return
+	}
+	shape := &Shape{Kind: kind, box: b}
+	f.shape = shape
+	d.Shapes = append(d.Shapes, shape)
+	for _, open := range stack {
+		if open.group != nil {
+			open.group.shapes = append(open.group.shapes, shape)
+		}
+	}
+}
+
+func (d *Diagram) addLine(attrs map[string]string, points []point) {
+	if len(points) < 2 {
+		return
+	}
+	style := attrs["style"]
+	d.lines = append(d.lines, line{
+		points:      points,
+		markerStart: attrs["marker-start"] != "" || strings.Contains(style, "marker-start"),
+		markerEnd:   attrs["marker-end"] != "" || strings.Contains(style, "marker-end"),
+	})
+}
+
+// resolve labels shapes with the text drawn inside them, connects the
+// shapes lines end at and labels connections with the text next to them.
+// Shapes drawn around other shapes, like backgrounds and clusters, are
+// containers: they take the text left over and list as groups of the
+// shapes inside them rather than as components.
+func (d *Diagram) resolve(groups []*group) {
+	for _, outer := range d.Shapes {
+		for _, inner := range d.Shapes {
+			if inner != outer && outer.box.encloses(inner.box) {
+				outer.container = true
+				break
+			}
+		}
+	}
+	node := func(s *Shape) bool { return !s.container }
+	labelledNode := func(s *Shape) bool { return !s.container && s.Label != "" }
+	container := func(s *Shape) bool { return s.container }
+
+	d.labelShapes(node)
+	for _, shape := range d.Shapes {
+		shape.Label = strings.Join(shape.texts, " ")
+		if shape.Label == "" {
+			shape.Label = shape.title
+		}
+	}
+	// A group holding a single unlabelled shape, as graph layout tools
+	// draw nodes, names the shape
+	for _, g := range groups {
+		if len(g.shapes) == 1 && g.shapes[0].Label == "" {
+			g.shapes[0].Label = g.label
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, l := range d.lines {
+		from := d.shapeAt(l.points[0], endpointTolerance, labelledNode)
+		to := d.shapeAt(l.points[len(l.points)-1], endpointTolerance, labelledNode)
+		if from == nil || to == nil || from == to {
+			continue
+		}
+		c := Connection{From: from.Label, To: to.Label, Bidirectional: l.markerStart && l.markerEnd}
+		if l.markerStart && !l.markerEnd {
+			c.From, c.To = c.To, c.From
+		}
+		c.Label = d.takeTextNear(midpoint(l.points))
+		if key := c.String(); !seen[key] {
+			seen[key] = true
+			d.Connections = append(d.Connections, c)
+		}
+	}
+
+	for _, g := range groups {
+		if g.label == "" {
+			continue
+		}
+		var members []string
+		for _, shape := range g.shapes {
+			if labelledNode(shape) && shape.Label != g.label {
+				members = append(members, shape.Label)
+			}
+		}
+		d.addGroup(g.label, members)
+	}
+	d.labelShapes(container)
+	for _, outer := range d.Shapes {
+		if !outer.container || len(outer.texts) == 0 {
+			continue
+		}
+		var members []string
+		for _, inner := range d.Shapes {
+			if labelledNode(inner) && outer.box.encloses(inner.box) {
+				members = append(members, inner.Label)
+			}
+		}
+		d.addGroup(strings.Join(outer.texts, " "), members)
+	}
+
+	for _, t := range d.texts {
+		if !t.used {
+			d.Text = append(d.Text, t.content)
+		}
+	}
+}
+
+// labelShapes gives the unused text drawn inside the shapes selected by
+// want to the smallest of them
+func (d *Diagram) labelShapes(want func(*Shape) bool) {
+	for i := range d.texts {
+		if d.texts[i].used {
+			continue
+		}
+		if shape := d.shapeAt(d.texts[i].at, 2, want); shape != nil {
+			shape.texts = append(shape.texts, d.texts[i].content)
+			d.texts[i].used = true
+		}
+	}
+}
+
+func (d *Diagram) addGroup(label string, members []string) {
+	if len(members) >= 2 {
+		d.Groups = append(d.Groups, Group{Label: label, Members: members})
+	}
+}
+
+// shapeAt returns the smallest of the shapes selected by want containing a
+// point, within margin
+func (d *Diagram) shapeAt(p point, margin float64, want func(*Shape) bool) *Shape {
+	var best *Shape
+	for _, shape := range d.Shapes {
+		if !want(shape) {
+			continue
+		}
+		if shape.box.contains(p, margin) && (best == nil || shape.box.area() < best.box.area()) {
+			best = shape
+		}
+	}
+	return best
+}
+
+// takeTextNear returns the unused text closest to a point, within
+// labelDistance, and marks it used
+func (d *Diagram) takeTextNear(p point) string {
+	best, bestDist := -1, float64(labelDistance)
+	for i, t := range d.texts {
+		if t.used {
+			continue
+		}
+		if dist := math.Hypot(t.at.x-p.x, t.at.y-p.y); dist <= bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	d.texts[best].used = true
+	return d.texts[best].content
+}
+
+// midpoint returns the middle of the middle segment of a line
+func midpoint(points []point) point {
+	i := (len(points) - 1) / 2
+	a, b := points[i], points[i+1]
+	return point{(a.x + b.x) / 2, (a.y + b.y) / 2}
+}
+
+// Describe formats the diagram as indexable text: title and description,
+// components, connections, groups and the remaining text
+func (d *Diagram) Describe() string {
+	var b strings.Builder
+	b.WriteString("SVG Diagram")
+	if d.Title != "" {
+		b.WriteString(": " + d.Title)
+	}
+	if d.Description != "" {
+		b.WriteString("\nDescription: " + d.Description)
+	}
+
+	var components []string
+	for _, shape := range d.Shapes {
+		if !shape.container && shape.Label != "" {
+			components = append(components, shape.Label)
+		}
+	}
+	writeList(&b, "Components", components)
+	connections := make([]string, len(d.Connections))
+	for i, c := range d.Connections {
+		connections[i] = c.String()
+	}
+	writeList(&b, "Connections", connections)
+	groups := make([]string, len(d.Groups))
+	for i, g := range d.Groups {
+		groups[i] = g.Label + ": " + strings.Join(g.Members, ", ")
+	}
+	writeList(&b, "Groups", groups)
+	if len(d.Text) > 0 {
+		b.WriteString("\n\nText:\n" + strings.Join(d.Text, "\n"))
+	}
+	return b.String()
+}
+
+// Empty reports whether the drawing has no text or labelled shapes
+func (d *Diagram) Empty() bool {
+	if d.Title != "" || d.Description != "" || len(d.texts) > 0 {
+		return false
+	}
+	for _, shape := range d.Shapes {
+		if shape.Label != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// AllText returns every text element and label of the drawing, in document
+// order, as the words OCR would find in it
+func (d *Diagram) AllText() string {
+	contents := make([]string, len(d.texts))
+	for i, t := range d.texts {
+		contents[i] = t.content
+	}
+	return strings.Join(contents, " ")
+}
+
+func writeList(b *strings.Builder, heading string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	b.WriteString("\n\n" + heading + ":")
+	for _, item := range items {
+		b.WriteString("\n- " + item)
+	}
+}
+
+func attrMap(attrs []xml.Attr) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		m[attr.Name.Local] = attr.Value
+	}
+	return m
+}
+
+func firstOf(attrs map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(attrs[name]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
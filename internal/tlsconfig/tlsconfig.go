@@ -0,0 +1,256 @@
+// Package tlsconfig serves the HTTP servers of the services over TLS and
+// secures the requests they make to one another, optionally with mutual
+// TLS. Certificate, key and CA files are read again when they change, so
+// rotated certificates are used without a restart.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// Certificates holds the certificates and CA bundles configured, reloading
+// them when their files change
+type Certificates struct {
+	cfg        config.TLSConfig
+	minVersion uint16
+	logger     *zap.Logger
+
+	mu         sync.RWMutex
+	checked    time.Time            // when the files were last checked
+	modTimes   map[string]time.Time // of the files when last read
+	cert       *tls.Certificate     // presented by servers
+	clientCert *tls.Certificate     // presented to the services called
+	clientCAs  *x509.CertPool       // verifying client certificates
+	rootCAs    *x509.CertPool       // verifying the services called; nil for the system roots
+}
+
+// New reads the configured certificates. It returns nil without an error
+// when TLS is not configured.
+func New(cfg *config.Config, logger *zap.Logger) (*Certificates, error) {
+	c := cfg.TLS
+	if c.CertFile == "" && c.CAFile == "" && c.ClientCertFile == "" {
+		return nil, nil
+	}
+	if c.ClientCertFile == "" {
+		c.ClientCertFile, c.ClientKeyFile = c.CertFile, c.KeyFile
+	}
+
+	certs := &Certificates{cfg: c, minVersion: tls.VersionTLS12, logger: logger}
+	if c.MinVersion == "1.3" {
+		certs.minVersion = tls.VersionTLS13
+	}
+	if err := certs.load(); err != nil {
+		return nil, err
+	}
+
+	logger.Info("TLS enabled",
+		zap.Bool("server", certs.cert != nil),
+		zap.Bool("mutual", certs.clientCAs != nil),
+		zap.Bool("client_certificate", certs.clientCert != nil),
+		zap.String("min_version", c.MinVersion))
+	return certs, nil
+}
+
+// files lists the files read, skipping those not configured
+func (c *Certificates) files() []string {
+	var files []string
+	for _, f := range []string{c.cfg.CertFile, c.cfg.KeyFile, c.cfg.ClientCertFile, c.cfg.ClientKeyFile, c.cfg.ClientCAFile, c.cfg.CAFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// load reads all files and replaces the certificates and pools held
+func (c *Certificates) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, f := range c.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		modTimes[f] = info.ModTime()
+	}
+
+	var cert, clientCert *tls.Certificate
+	var clientCAs, rootCAs *x509.CertPool
+	var err error
+	if c.cfg.CertFile != "" {
+		if cert, err = loadKeyPair(c.cfg.CertFile, c.cfg.KeyFile); err != nil {
+			return err
+		}
+	}
+	if c.cfg.ClientCertFile != "" {
+		if clientCert, err = loadKeyPair(c.cfg.ClientCertFile, c.cfg.ClientKeyFile); err != nil {
+			return err
+		}
+	}
+	if c.cfg.ClientCAFile != "" {
+		if clientCAs, err = loadPool(c.cfg.ClientCAFile); err != nil {
+			return err
+		}
+	}
+	if c.cfg.CAFile != "" {
+		if rootCAs, err = loadPool(c.cfg.CAFile); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.modTimes = modTimes
+	c.checked = time.Now()
+	c.cert, c.clientCert, c.clientCAs, c.rootCAs = cert, clientCert, clientCAs, rootCAs
+	return nil
+}
+
+func loadKeyPair(certFile, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate %s: %w", certFile, err)
+	}
+	return &cert, nil
+}
+
+func loadPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in CA bundle %s", file)
+	}
+	return pool, nil
+}
+
+// refresh reloads the files when one changed, checking at most once per
+// reload interval. Files that fail to load, such as a certificate written
+// before its key, leave the previous certificates in use until the next
+// check.
+func (c *Certificates) refresh() {
+	c.mu.Lock()
+	if time.Since(c.checked) < c.cfg.ReloadInterval {
+		c.mu.Unlock()
+		return
+	}
+	c.checked = time.Now()
+	changed := false
+	for _, f := range c.files() {
+		if info, err := os.Stat(f); err == nil && !info.ModTime().Equal(c.modTimes[f]) {
+			changed = true
+			break
+		}
+	}
+	c.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if err := c.load(); err != nil {
+		c.logger.Error("Failed to reload TLS certificates, keeping the previous ones", zap.Error(err))
+		return
+	}
+	c.logger.Info("Reloaded TLS certificates")
+}
+
+// serverConfig returns the TLS configuration of a connection to a server,
+// with the current certificate and client CAs
+func (c *Certificates) serverConfig(*tls.ClientHelloInfo) (*tls.Config, error) {
+	c.refresh()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	config := &tls.Config{
+		MinVersion:   c.minVersion,
+		Certificates: []tls.Certificate{*c.cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if c.clientCAs != nil {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		config.ClientCAs = c.clientCAs
+	}
+	return config, nil
+}
+
+// clientCertificate returns the certificate presented to the services
+// called, an empty one when none is configured
+func (c *Certificates) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.refresh()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.clientCert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return c.clientCert, nil
+}
+
+// verifyServer verifies the certificate of a service called against the
+// current CA bundle
+func (c *Certificates) verifyServer(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	c.refresh()
+	c.mu.RLock()
+	roots := c.rootCAs
+	c.mu.RUnlock()
+
+	opts := x509.VerifyOptions{DNSName: state.ServerName, Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, cert := range state.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// ClientConfig returns the TLS configuration of requests to other
+// services. The CA bundle is checked by verifyServer rather than through
+// RootCAs, which could not be reloaded.
+func (c *Certificates) ClientConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:           c.minVersion,
+		GetClientCertificate: c.clientCertificate,
+		InsecureSkipVerify:   true, //nolint:gosec // verified by verifyServer
+		VerifyConnection:     c.verifyServer,
+	}
+}
+
+// Transport returns an HTTP transport for requests to other services
+func (c *Certificates) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = c.ClientConfig()
+	return transport
+}
+
+// HTTPClient returns an HTTP client for requests to other services, or nil
+// for the default client when TLS is not configured
+func HTTPClient(certs *Certificates) *http.Client {
+	if certs == nil {
+		return nil
+	}
+	return &http.Client{Transport: certs.Transport()}
+}
+
+// ListenAndServe serves srv over TLS when a server certificate is
+// configured, and over plain HTTP otherwise
+func ListenAndServe(srv *http.Server, certs *Certificates) error {
+	if certs == nil || certs.cfg.CertFile == "" {
+		return srv.ListenAndServe()
+	}
+	srv.TLSConfig = &tls.Config{
+		MinVersion:         certs.minVersion,
+		GetConfigForClient: certs.serverConfig,
+	}
+	return srv.ListenAndServeTLS("", "")
+}
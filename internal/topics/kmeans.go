@@ -0,0 +1,143 @@
+package topics
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// maxIterations caps the assignment rounds of k-means
+const maxIterations = 50
+
+// clusterCount picks the number of topics for n documents when it is not
+// configured: the square root of half of them, between 2 and 30
+func clusterCount(n int) int {
+	k := int(math.Sqrt(float64(n) / 2))
+	return min(max(k, 2), 30, n)
+}
+
+// kmeans clusters unit vectors into k clusters by cosine similarity, with
+// k-means++ seeding from a fixed seed, so the same corpus gives the same
+// topics. It returns the cluster of each vector and the unit centroids.
+func kmeans(vectors [][]float32, k int) ([]int, [][]float32) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	centroids := seed(vectors, k, rng)
+	assignment := make([]int, len(vectors))
+	for i := range assignment {
+		assignment[i] = -1
+	}
+
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		changed := false
+		for i, v := range vectors {
+			best, bestScore := 0, float32(math.Inf(-1))
+			for c, centroid := range centroids {
+				if score := dot(v, centroid); score > bestScore {
+					best, bestScore = c, score
+				}
+			}
+			if assignment[i] != best {
+				assignment[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sizes := make([]int, k)
+		sums := make([][]float32, k)
+		for c := range sums {
+			sums[c] = make([]float32, len(vectors[0]))
+		}
+		for i, v := range vectors {
+			c := assignment[i]
+			sizes[c]++
+			for d := range v {
+				sums[c][d] += v[d]
+			}
+		}
+		for c := range centroids {
+			if sizes[c] == 0 {
+				// An empty cluster restarts at the vector farthest from its
+				// own centroid
+				far := farthest(vectors, assignment, centroids)
+				centroids[c] = vectors[far]
+				assignment[far] = c
+				continue
+			}
+			centroids[c] = normalize(sums[c])
+		}
+	}
+	return assignment, centroids
+}
+
+// seed picks k initial centroids with k-means++: each next centroid is a
+// vector chosen with probability growing with its distance to the nearest
+// centroid so far
+func seed(vectors [][]float32, k int, rng *rand.Rand) [][]float32 {
+	centroids := [][]float32{vectors[rng.IntN(len(vectors))]}
+	distances := make([]float64, len(vectors))
+	for len(centroids) < k {
+		var total float64
+		for i, v := range vectors {
+			d := 1 - float64(dot(v, centroids[len(centroids)-1]))
+			if len(centroids) == 1 || d < distances[i] {
+				distances[i] = max(d, 0)
+			}
+			total += distances[i] * distances[i]
+		}
+		if total == 0 {
+			// Fewer distinct vectors than clusters
+			centroids = append(centroids, vectors[rng.IntN(len(vectors))])
+			continue
+		}
+		target := rng.Float64() * total
+		next := len(vectors) - 1
+		for i := range vectors {
+			target -= distances[i] * distances[i]
+			if target <= 0 {
+				next = i
+				break
+			}
+		}
+		centroids = append(centroids, vectors[next])
+	}
+	return centroids
+}
+
+// farthest returns the vector least similar to the centroid of its cluster
+func farthest(vectors [][]float32, assignment []int, centroids [][]float32) int {
+	far, farScore := 0, float32(math.Inf(1))
+	for i, v := range vectors {
+		if score := dot(v, centroids[assignment[i]]); score < farScore {
+			far, farScore = i, score
+		}
+	}
+	return far
+}
+
+// dot returns the dot product of two vectors, their cosine similarity when
+// both are unit vectors
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range min(len(a), len(b)) {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// normalize scales a vector to unit length in place and returns it
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return v
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range v {
+		v[i] *= scale
+	}
+	return v
+}
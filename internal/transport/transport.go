@@ -0,0 +1,231 @@
+// Package transport sends the JSON requests of the platform's HTTP clients:
+// the service clients of pkg/client and the public API SDK of pkg/sdk. It
+// owns what they share: attempt timeouts, retries of network errors, 429
+// and 5xx responses with exponential backoff, and reading the error a
+// service returns. Headers and error types stay with each client.
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBody bounds the bytes of an error response that are read
+const maxErrorBody = 1 << 20
+
+// ErrorFunc builds the error of a non-2xx response from its status, the
+// message the service returned and the delay its Retry-After header asks for
+type ErrorFunc func(status int, message string, retryAfter time.Duration) error
+
+// Client sends requests to one base URL. It is safe for concurrent use.
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+// New creates a client of the service at baseURL retrying failed requests
+// up to maxRetries times, after retryBackoff doubled per attempt. A nil
+// httpClient means a default one.
+func New(baseURL string, httpClient *http.Client, maxRetries int, retryBackoff time.Duration) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	return &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		httpClient:   httpClient,
+		maxRetries:   maxRetries,
+		retryBackoff: retryBackoff,
+	}
+}
+
+// Request is one call of a service
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   interface{} // sent as JSON when set
+	// Header is sent with every attempt
+	Header http.Header
+	// Timeout bounds each attempt including the read of its body; zero
+	// leaves attempts to the context
+	Timeout time.Duration
+	// Error builds the error of a non-2xx response
+	Error ErrorFunc
+}
+
+// Response is a 2xx response whose body is still open. Close it to end
+// the attempt's timeout.
+type Response struct {
+	*http.Response
+	cancel context.CancelFunc
+}
+
+// Close closes the body and ends the attempt
+func (r *Response) Close() error {
+	defer r.cancel()
+	return r.Body.Close()
+}
+
+// Do sends a request and decodes the JSON response into out, if not nil
+func (c *Client) Do(ctx context.Context, r Request, out interface{}) error {
+	resp, err := c.Send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Close() //nolint:errcheck
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s %s: failed to read response: %w", r.Method, r.Path, err)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s %s: failed to decode response: %w", r.Method, r.Path, err)
+	}
+	return nil
+}
+
+// Send performs a request with retries until it gets a 2xx response.
+// Retries wait the backoff, or the delay a Retry-After header asked for
+// when that is longer.
+func (c *Client) Send(ctx context.Context, r Request) (*Response, error) {
+	var payload []byte
+	if r.Body != nil {
+		data, err := json.Marshal(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		payload = data
+	}
+
+	var lastErr error
+	var wait time.Duration
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, max(c.backoff(attempt), wait)); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, retry, retryAfter, err := c.attempt(ctx, r, payload)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr, wait = err, retryAfter
+		if !retry {
+			break
+		}
+	}
+	return nil, fmt.Errorf("%s %s failed: %w", r.Method, r.Path, lastErr)
+}
+
+// attempt performs a single request and reports whether a failure is
+// retryable, and after what delay the service asked to be called again
+func (c *Client) attempt(ctx context.Context, r Request, payload []byte) (*Response, bool, time.Duration, error) {
+	reqCtx, cancel := context.WithCancel(ctx)
+	if r.Timeout > 0 {
+		reqCtx, cancel = context.WithTimeout(ctx, r.Timeout)
+	}
+
+	target := c.baseURL + r.Path
+	if len(r.Query) > 0 {
+		target += "?" + r.Query.Encode()
+	}
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(reqCtx, r.Method, target, reader)
+	if err != nil {
+		cancel()
+		return nil, false, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header = r.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		// The caller's context ending is final; per-attempt timeouts are retried
+		return nil, ctx.Err() == nil, 0, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return &Response{Response: resp, cancel: cancel}, false, 0, nil
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody)) //nolint:errcheck
+	resp.Body.Close()                                              //nolint:errcheck
+	cancel()
+	message := errorMessage(data, resp.Status)
+	delay := retryAfter(resp.Header.Get("Retry-After"))
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	if r.Error == nil {
+		return nil, retry, delay, fmt.Errorf("service returned %d: %s", resp.StatusCode, message)
+	}
+	return nil, retry, delay, r.Error(resp.StatusCode, message, delay)
+}
+
+// backoff returns the delay before a retry, with up to 20% jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retryBackoff << (attempt - 1)
+	if delay <= 0 {
+		return 0
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1)) //nolint:gosec
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// errorMessage extracts the "error" field the services return, falling
+// back to the raw body or the status
+func errorMessage(data []byte, status string) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Error != "" {
+		return body.Error
+	}
+	if msg := strings.TrimSpace(string(data)); msg != "" {
+		return msg
+	}
+	return status
+}
@@ -0,0 +1,97 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string { return e.message }
+
+func newStatusError(status int, message string, _ time.Duration) error {
+	return &statusError{status: status, message: message}
+}
+
+// statusServer answers requests with the next of statuses, counting them
+func statusServer(t *testing.T, statuses []int, calls *int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[min(*calls, len(statuses)-1)]
+		*calls++
+		if r.Header.Get("X-Request-ID") != "id" {
+			t.Errorf("X-Request-ID = %q, want the request's header", r.Header.Get("X-Request-ID"))
+		}
+		w.WriteHeader(status)
+		if status >= 300 {
+			w.Write([]byte(`{"error":"failed"}`)) //nolint:errcheck
+			return
+		}
+		w.Write([]byte(`{"ok":true}`)) //nolint:errcheck
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDoRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		calls    int
+		status   int // status of the returned error; zero for success
+	}{
+		{"success", []int{http.StatusOK}, 1, 0},
+		{"server error then success", []int{http.StatusBadGateway, http.StatusOK}, 2, 0},
+		{"rate limited then success", []int{http.StatusTooManyRequests, http.StatusOK}, 2, 0},
+		{"retries used up", []int{http.StatusServiceUnavailable}, 3, http.StatusServiceUnavailable},
+		{"client error not retried", []int{http.StatusBadRequest, http.StatusOK}, 1, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := statusServer(t, tt.statuses, &calls)
+			client := New(server.URL+"/", nil, 2, time.Millisecond)
+
+			var out struct {
+				OK bool `json:"ok"`
+			}
+			err := client.Do(context.Background(), Request{
+				Method: http.MethodPost,
+				Path:   "/ingest",
+				Body:   map[string]string{"file_path": "/data/a.md"},
+				Header: http.Header{"X-Request-ID": {"id"}},
+				Error:  newStatusError,
+			}, &out)
+
+			if calls != tt.calls {
+				t.Errorf("calls = %d, want %d", calls, tt.calls)
+			}
+			if tt.status == 0 {
+				if err != nil || !out.OK {
+					t.Errorf("Do = %v, %+v; want the decoded response", err, out)
+				}
+				return
+			}
+			var statusErr *statusError
+			if !errors.As(err, &statusErr) || statusErr.status != tt.status || statusErr.message != "failed" {
+				t.Errorf("Do = %v, want the error of a %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := map[string]time.Duration{"": 0, "2": 2 * time.Second, " 3 ": 3 * time.Second, "-1": 0, "soon": 0}
+	for value, want := range tests {
+		if got := retryAfter(value); got != want {
+			t.Errorf("retryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
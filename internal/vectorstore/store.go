@@ -0,0 +1,268 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/chunkstore"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/imagesearch"
+	"github.com/nadeeshame/rag-knowledge-service/internal/routing"
+	"go.uber.org/zap"
+)
+
+// Store implements the VectorStore interface on top of Pinecone
+type Store struct {
+	client        *pinecone.PineconeClient
+	chunkStore    chunkstore.Store
+	imageIndex    *imagesearch.Index
+	router        *routing.Router
+	metadataLimit int
+	logger        *zap.Logger
+}
+
+// NewStore creates a new Pinecone-backed vector store
+func NewStore(cfg *config.Config, logger *zap.Logger) (*Store, error) {
+	client, err := pinecone.NewPineconeClient(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Pinecone client: %w", err)
+	}
+	return &Store{client: client, metadataLimit: cfg.Pinecone.MetadataLimit, logger: logger}, nil
+}
+
+// SetChunkStore makes the store keep the full text of chunks whose content
+// is truncated to fit the metadata limit, and delete it with the document
+func (s *Store) SetChunkStore(store chunkstore.Store) {
+	s.chunkStore = store
+}
+
+// SetImageIndex makes the store delete a document's image vector with its
+// other vectors
+func (s *Store) SetImageIndex(index *imagesearch.Index) {
+	s.imageIndex = index
+}
+
+// SetRouter makes the store delete and find documents in the indexes and
+// namespaces of the routed file categories as well
+func (s *Store) SetRouter(router *routing.Router) {
+	s.router = router
+}
+
+// Client returns the underlying Pinecone client
+func (s *Store) Client() *pinecone.PineconeClient {
+	return s.client
+}
+
+// UpsertVectors stores chunk embeddings with their metadata
+func (s *Store) UpsertVectors(ctx context.Context, chunks []*models.Chunk) error {
+	vectors := make([]*pinecone.Vector, 0, len(chunks))
+	for _, chunk := range chunks {
+		if len(chunk.Embedding) == 0 {
+			return fmt.Errorf("chunk %s has no embedding", chunk.ID)
+		}
+		vector := ChunkToVector(chunk)
+		if err := s.fitMetadata(ctx, vector, chunk); err != nil {
+			return fmt.Errorf("chunk %s: %w", chunk.ID, err)
+		}
+		vectors = append(vectors, vector)
+	}
+	return s.client.UpsertVectors(ctx, vectors)
+}
+
+// fitMetadata truncates chunk content that does not fit the metadata
+// limit, keeping the full chunk in the chunk store when one is configured
+func (s *Store) fitMetadata(ctx context.Context, vector *pinecone.Vector, chunk *models.Chunk) error {
+	if s.metadataLimit <= 0 || pinecone.MetadataSize(vector.Metadata) <= s.metadataLimit {
+		return nil
+	}
+
+	vector.Metadata["content_truncated"] = true
+	if err := pinecone.FitMetadata(vector.Metadata, s.metadataLimit); err != nil {
+		return err
+	}
+	if s.chunkStore == nil {
+		s.logger.Warn("Truncated chunk content to fit vector metadata", zap.String("vector_id", vector.ID))
+		return nil
+	}
+	if err := s.chunkStore.Create(ctx, chunk); err != nil {
+		return fmt.Errorf("failed to store full chunk content: %w", err)
+	}
+	return nil
+}
+
+// UpsertRaw stores pre-built vectors in a namespace (empty for the default)
+func (s *Store) UpsertRaw(ctx context.Context, namespace string, vectors []*pinecone.Vector) error {
+	if namespace == "" {
+		return s.client.UpsertVectors(ctx, vectors)
+	}
+	return s.client.UpsertVectorsInNamespace(ctx, namespace, vectors)
+}
+
+// SearchSimilar returns the chunks nearest to the query embedding
+func (s *Store) SearchSimilar(ctx context.Context, queryEmbedding []float32, topK int, namespace string, filter map[string]interface{}) ([]*models.SearchResult, error) {
+	matches, err := s.QueryRaw(ctx, namespace, queryEmbedding, topK, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.SearchResult, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, MatchToSearchResult(m))
+	}
+	return results, nil
+}
+
+// QueryRaw returns raw matches from a namespace (empty for the default)
+func (s *Store) QueryRaw(ctx context.Context, namespace string, queryEmbedding []float32, topK int, filter map[string]interface{}) ([]*pinecone.Match, error) {
+	if namespace == "" {
+		return s.client.QueryVectors(ctx, queryEmbedding, topK, filter)
+	}
+	return s.client.QueryVectorsInNamespace(ctx, namespace, queryEmbedding, topK, filter)
+}
+
+// DeleteByDocumentID removes all vectors of a document
+func (s *Store) DeleteByDocumentID(ctx context.Context, documentID uuid.UUID) error {
+	_, err := s.DeleteDocument(ctx, documentID.String())
+	return err
+}
+
+// DeleteDocument removes all vectors of a document and returns how many were deleted
+func (s *Store) DeleteDocument(ctx context.Context, documentID string) (int, error) {
+	count, err := s.client.DeleteByDocumentID(ctx, documentID)
+	if err != nil {
+		return 0, err
+	}
+	// The document's chunks are in one route at most, but its category
+	// is not known here
+	for _, route := range s.router.Routes() {
+		routed, err := route.Pinecone.DeleteByDocumentID(ctx, documentID)
+		if err != nil {
+			return count, fmt.Errorf("failed to delete %s vectors: %w", route.Category, err)
+		}
+		count += routed
+	}
+	if s.chunkStore != nil {
+		if id, parseErr := uuid.Parse(documentID); parseErr == nil {
+			if err := s.chunkStore.DeleteByDocumentID(ctx, id); err != nil {
+				s.logger.Warn("Failed to delete stored chunk content",
+					zap.String("document_id", documentID),
+					zap.Error(err))
+			}
+		}
+	}
+	if s.imageIndex != nil {
+		if err := s.imageIndex.Delete(ctx, documentID); err != nil {
+			s.logger.Warn("Failed to delete image vector",
+				zap.String("document_id", documentID),
+				zap.Error(err))
+		}
+	}
+	s.logger.Info("Deleted document vectors",
+		zap.String("document_id", documentID),
+		zap.Int("count", count))
+	return count, nil
+}
+
+// GetIndexStats returns index statistics
+func (s *Store) GetIndexStats(ctx context.Context) (map[string]interface{}, error) {
+	return s.client.GetStats(ctx)
+}
+
+// UpsertStats returns the upsert throughput of this store's client
+func (s *Store) UpsertStats() pinecone.UpsertStats {
+	return s.client.UpsertStats()
+}
+
+// CheckDocumentExists reports whether a document with the given hash is indexed
+func (s *Store) CheckDocumentExists(ctx context.Context, documentHash string) (bool, error) {
+	exists, err := s.client.CheckDocumentExists(ctx, documentHash)
+	if err != nil || exists {
+		return exists, err
+	}
+	for _, route := range s.router.Routes() {
+		if exists, err := route.Pinecone.CheckDocumentExists(ctx, documentHash); err != nil || exists {
+			return exists, err
+		}
+	}
+	return false, nil
+}
+
+// ChunkToVector converts a chunk into a Pinecone vector. Chunk metadata is
+// copied as-is next to the standard document fields.
+func ChunkToVector(chunk *models.Chunk) *pinecone.Vector {
+	metadata := make(map[string]interface{}, len(chunk.Metadata)+5)
+	for k, v := range chunk.Metadata {
+		metadata[k] = v
+	}
+	metadata["document_id"] = chunk.DocumentID.String()
+	metadata["chunk_id"] = chunk.ID.String()
+	metadata["chunk_index"] = chunk.ChunkIndex
+	metadata["content"] = chunk.Content
+	if _, ok := metadata["indexed_at"]; !ok {
+		metadata["indexed_at"] = time.Now().Unix()
+	}
+
+	return &pinecone.Vector{
+		ID:       models.ChunkVectorID(chunk.DocumentID.String(), chunk.ChunkIndex),
+		Values:   chunk.Embedding,
+		Metadata: metadata,
+	}
+}
+
+// MatchToSearchResult converts a Pinecone match into a search result
+func MatchToSearchResult(m *pinecone.Match) *models.SearchResult {
+	result := &models.SearchResult{
+		Score:    m.Score,
+		Content:  MetadataString(m.Metadata, "content"),
+		FileName: MetadataString(m.Metadata, "file_name"),
+		FilePath: MetadataString(m.Metadata, "file_path"),
+		FileType: MetadataString(m.Metadata, "file_type"),
+		Metadata: map[string]string{"vector_id": m.ID},
+	}
+
+	if id, err := uuid.Parse(MetadataString(m.Metadata, "document_id")); err == nil {
+		result.DocumentID = id
+	}
+	if id, err := uuid.Parse(MetadataString(m.Metadata, "chunk_id")); err == nil {
+		result.ChunkID = id
+	}
+
+	for key, value := range m.Metadata {
+		switch key {
+		case "content", "file_name", "file_path", "file_type", "document_id", "chunk_id":
+			continue
+		}
+		if formatted, ok := formatMetadataValue(value); ok {
+			result.Metadata[key] = formatted
+		}
+	}
+
+	return result
+}
+
+// MetadataString returns a string metadata value, or empty if absent
+func MetadataString(metadata map[string]interface{}, key string) string {
+	if value, ok := metadata[key].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// formatMetadataValue renders scalar metadata values as strings; lists are skipped
+func formatMetadataValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
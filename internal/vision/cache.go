@@ -0,0 +1,89 @@
+package vision
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// resultCache is an LRU cache of analysis results with a per-entry TTL
+type resultCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int
+	ttl     time.Duration
+	hits    int64
+	misses  int64
+}
+
+type cacheEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// newResultCache creates a cache holding at most size entries. A zero TTL
+// keeps entries until they are evicted.
+func newResultCache(size int, ttl time.Duration) *resultCache {
+	return &resultCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		size:    size,
+		ttl:     ttl,
+	}
+}
+
+func (c *resultCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return "", false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		c.misses++
+		return "", false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits++
+	return entry.value, true
+}
+
+func (c *resultCache) put(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = time.Now().Add(c.ttl)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *resultCache) stats() (entries int, hits, misses int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len(), c.hits, c.misses
+}
@@ -0,0 +1,168 @@
+package vision
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/google"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// Operation identifies the kind of vision request
+type Operation string
+
+const (
+	OperationAnalyze Operation = "analyze"
+	OperationOCR     Operation = "ocr"
+)
+
+// Result is the outcome of a vision request
+type Result struct {
+	Operation Operation `json:"operation"`
+	ImageHash string    `json:"image_hash"`
+	Text      string    `json:"text"`
+	Cached    bool      `json:"cached"`
+}
+
+// Stats describes cache and queue usage
+type Stats struct {
+	CacheEntries  int   `json:"cache_entries"`
+	CacheHits     int64 `json:"cache_hits"`
+	CacheMisses   int64 `json:"cache_misses"`
+	InFlight      int   `json:"in_flight"`
+	MaxConcurrent int   `json:"max_concurrent"`
+}
+
+// call is an in-progress request shared by callers asking for the same image
+type call struct {
+	done chan struct{}
+	text string
+	err  error
+}
+
+// Service wraps the Vision adapter with per-image result caching and a
+// concurrency limit so bursts of images stay within the Vision API quota
+type Service struct {
+	client  *google.VisionClient
+	cache   *resultCache
+	slots   chan struct{}
+	mu      sync.Mutex
+	pending map[string]*call
+	logger  *zap.Logger
+}
+
+// NewService creates a vision service
+func NewService(cfg *config.Config, logger *zap.Logger) (*Service, error) {
+	client, err := google.NewVisionClient(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vision client: %w", err)
+	}
+
+	s := &Service{
+		client:  client,
+		slots:   make(chan struct{}, cfg.Vision.MaxConcurrent),
+		pending: make(map[string]*call),
+		logger:  logger,
+	}
+	if cfg.Vision.CacheSize > 0 {
+		s.cache = newResultCache(cfg.Vision.CacheSize, cfg.Vision.CacheTTL)
+	}
+	return s, nil
+}
+
+// Analyze returns a description of the image
+func (s *Service) Analyze(ctx context.Context, name string, data []byte) (*Result, error) {
+	return s.do(ctx, OperationAnalyze, name, data)
+}
+
+// OCR returns the text detected in the image
+func (s *Service) OCR(ctx context.Context, name string, data []byte) (*Result, error) {
+	return s.do(ctx, OperationOCR, name, data)
+}
+
+// Stats returns cache and queue usage
+func (s *Service) Stats() Stats {
+	stats := Stats{InFlight: len(s.slots), MaxConcurrent: cap(s.slots)}
+	if s.cache != nil {
+		stats.CacheEntries, stats.CacheHits, stats.CacheMisses = s.cache.stats()
+	}
+	return stats
+}
+
+func (s *Service) do(ctx context.Context, op Operation, name string, data []byte) (*Result, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("image data cannot be empty")
+	}
+
+	hash := fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+	// SVGs are parsed as text, so the file type is part of the cache key
+	key := string(op) + "|" + hash + "|" + fileKind(name)
+
+	if s.cache != nil {
+		if text, ok := s.cache.get(key); ok {
+			return &Result{Operation: op, ImageHash: hash, Text: text, Cached: true}, nil
+		}
+	}
+
+	// Concurrent requests for the same image share one Vision call
+	s.mu.Lock()
+	if c, ok := s.pending[key]; ok {
+		s.mu.Unlock()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if c.err != nil {
+			return nil, c.err
+		}
+		return &Result{Operation: op, ImageHash: hash, Text: c.text, Cached: true}, nil
+	}
+	c := &call{done: make(chan struct{})}
+	s.pending[key] = c
+	s.mu.Unlock()
+
+	c.text, c.err = s.run(ctx, op, name, data)
+	if c.err == nil && s.cache != nil {
+		s.cache.put(key, c.text)
+	}
+
+	s.mu.Lock()
+	delete(s.pending, key)
+	s.mu.Unlock()
+	close(c.done)
+
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &Result{Operation: op, ImageHash: hash, Text: c.text}, nil
+}
+
+// run calls the adapter once a concurrency slot is free
+func (s *Service) run(ctx context.Context, op Operation, name string, data []byte) (string, error) {
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-s.slots }()
+
+	switch op {
+	case OperationOCR:
+		return s.client.DetectTextData(ctx, name, data)
+	default:
+		return s.client.AnalyzeImageData(ctx, name, data)
+	}
+}
+
+func fileKind(name string) string {
+	if strings.ToLower(filepath.Ext(name)) == ".svg" {
+		return "svg"
+	}
+	return "raster"
+}
@@ -0,0 +1,381 @@
+package wal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/locks"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// record is one line of the log file: an appended entry or the
+// acknowledgement of one
+type record struct {
+	Op    string `json:"op"` // append or ack
+	ID    string `json:"id"`
+	Entry *Entry `json:"entry,omitempty"`
+}
+
+// FileStore keeps the log in a JSON Lines file. Every write is synced to
+// disk before it returns. The file is compacted to the pending entries when
+// opened and truncated whenever no entry is pending. The file belongs to
+// one process, so only the entries it holds when opened are replayed.
+type FileStore struct {
+	mu        sync.Mutex
+	replaying sync.Mutex
+	path      string
+	file      *os.File
+	pending   map[string]struct{}
+	written   map[string]struct{} // entries appended since opened
+}
+
+// NewFileStore opens (or creates) the log file and compacts it
+func NewFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create write-ahead log directory: %w", err)
+	}
+
+	s := &FileStore{path: path, pending: make(map[string]struct{}), written: make(map[string]struct{})}
+	entries, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.rewrite(entries); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	s.file = file
+	for _, entry := range entries {
+		s.pending[entry.ID] = struct{}{}
+	}
+	return s, nil
+}
+
+// Append writes the entry and syncs it to disk
+func (s *FileStore) Append(_ context.Context, entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = uuid.New().String()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	if err := s.write(&record{Op: "append", ID: entry.ID, Entry: entry}); err != nil {
+		return err
+	}
+	s.pending[entry.ID] = struct{}{}
+	s.written[entry.ID] = struct{}{}
+	return nil
+}
+
+// Ack records the acknowledgement, truncating the file once nothing is pending
+func (s *FileStore) Ack(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pending[id]; !ok {
+		return nil
+	}
+	delete(s.pending, id)
+	delete(s.written, id)
+	if len(s.pending) == 0 {
+		if err := s.file.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate write-ahead log: %w", err)
+		}
+		return s.file.Sync()
+	}
+	return s.write(&record{Op: "ack", ID: id})
+}
+
+// Pending reads a page of the entries that were never acknowledged, other
+// than those appended since the file was opened. The cursor is the ID of
+// the last entry read.
+func (s *FileStore) Pending(_ context.Context, cursor string, limit int) ([]*Entry, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, entries, err := s.scan()
+	if err != nil {
+		return nil, "", err
+	}
+	// A cursor acknowledged since is still in the order; one missing was
+	// truncated away with every entry before it
+	start := 0
+	for i, id := range order {
+		if id == cursor {
+			start = i + 1
+			break
+		}
+	}
+	var page []*Entry
+	for i := start; i < len(order); i++ {
+		entry, ok := entries[order[i]]
+		if !ok {
+			continue
+		}
+		if _, own := s.written[entry.ID]; own {
+			continue
+		}
+		page = append(page, entry)
+		if len(page) == limit {
+			if i == len(order)-1 {
+				return page, "", nil
+			}
+			return page, entry.ID, nil
+		}
+	}
+	return page, "", nil
+}
+
+// LockReplay takes the replay lock of this process
+func (s *FileStore) LockReplay(_ context.Context) (func(), bool, error) {
+	if !s.replaying.TryLock() {
+		return nil, false, nil
+	}
+	return s.replaying.Unlock, true, nil
+}
+
+// Close closes the log file
+func (s *FileStore) Close() error {
+	return s.file.Close()
+}
+
+// write appends a record as a single line and syncs it
+func (s *FileStore) write(rec *record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal write-ahead log record: %w", err)
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write write-ahead log: %w", err)
+	}
+	return s.file.Sync()
+}
+
+// read returns the unacknowledged entries of the file in append order
+func (s *FileStore) read() ([]*Entry, error) {
+	order, entries, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]*Entry, 0, len(entries))
+	for _, id := range order {
+		if entry, ok := entries[id]; ok {
+			pending = append(pending, entry)
+		}
+	}
+	return pending, nil
+}
+
+// scan returns the IDs of the entries appended to the file in order, and
+// the unacknowledged entries by ID. A torn last line left by a crash is
+// ignored.
+func (s *FileStore) scan() ([]string, map[string]*Entry, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	defer file.Close()
+
+	var order []string
+	entries := make(map[string]*Entry)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		switch {
+		case rec.Op == "append" && rec.Entry != nil:
+			order = append(order, rec.ID)
+			entries[rec.ID] = rec.Entry
+		case rec.Op == "ack":
+			delete(entries, rec.ID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read write-ahead log: %w", err)
+	}
+	return order, entries, nil
+}
+
+// rewrite replaces the file with only the given entries
+func (s *FileStore) rewrite(entries []*Entry) error {
+	tmp := s.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to compact write-ahead log: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	for _, entry := range entries {
+		data, err := json.Marshal(&record{Op: "append", ID: entry.ID, Entry: entry})
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to marshal write-ahead log record: %w", err)
+		}
+		w.Write(append(data, '\n')) //nolint:errcheck // reported by Flush
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to compact write-ahead log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to compact write-ahead log: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to compact write-ahead log: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to compact write-ahead log: %w", err)
+	}
+	return nil
+}
+
+// RedisStore keeps the log in a Redis stream, which the processes using
+// it share. Entries are identified by their stream ID and deleted from the
+// stream when acknowledged. Each store holds a writer lock while open,
+// refreshed like the replica locks, so the others replay its entries only
+// once it closed or its process died.
+type RedisStore struct {
+	client *redis.Client
+	stream string
+	locker *locks.Locker
+	writer *locks.Lock
+}
+
+// NewRedisStore creates a Redis stream backed log and takes its writer
+// lock, which outlives a process that died by ttl
+func NewRedisStore(ctx context.Context, client *redis.Client, stream string, ttl time.Duration, logger *zap.Logger) (*RedisStore, error) {
+	s := &RedisStore{
+		client: client,
+		stream: stream,
+		locker: locks.NewLocker(client, stream, ttl, uuid.New().String(), logger),
+	}
+	writer, err := s.locker.Acquire(ctx, writerLock(s.locker.Owner()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register write-ahead log writer: %w", err)
+	}
+	s.writer = writer
+	return s, nil
+}
+
+// writerLock names the lock a writer holds while its store is open
+func writerLock(writer string) string {
+	return "writer:" + writer
+}
+
+// Append adds the entry to the stream
+func (s *RedisStore) Append(ctx context.Context, entry *Entry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	entry.ID = ""
+	entry.Writer = s.locker.Owner()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal write-ahead log entry: %w", err)
+	}
+
+	id, err := s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		Values: map[string]interface{}{"entry": data},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to write write-ahead log: %w", err)
+	}
+	entry.ID = id
+	return nil
+}
+
+// Ack deletes the entry from the stream
+func (s *RedisStore) Ack(ctx context.Context, id string) error {
+	if err := s.client.XDel(ctx, s.stream, id).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge write-ahead log entry: %w", err)
+	}
+	return nil
+}
+
+// Pending reads a page of the stream, leaving out the entries of writers
+// still holding their lock, this one included. The cursor is the stream ID
+// of the last message read.
+func (s *RedisStore) Pending(ctx context.Context, cursor string, limit int) ([]*Entry, string, error) {
+	start := "-"
+	if cursor != "" {
+		// Continue strictly after the newest message of the last page
+		start = "(" + cursor
+	}
+	messages, err := s.client.XRangeN(ctx, s.stream, start, "+", int64(limit)).Result()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read write-ahead log: %w", err)
+	}
+
+	live := make(map[string]bool)
+	var entries []*Entry
+	for _, msg := range messages {
+		raw, ok := msg.Values["entry"].(string)
+		if !ok {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			continue
+		}
+		entry.ID = msg.ID
+		// Entries logged before writers were recorded have none
+		if entry.Writer != "" {
+			alive, ok := live[entry.Writer]
+			if !ok {
+				holder, err := s.locker.Holder(ctx, writerLock(entry.Writer))
+				if err != nil {
+					return nil, "", fmt.Errorf("failed to check write-ahead log writer: %w", err)
+				}
+				alive = holder != ""
+				live[entry.Writer] = alive
+			}
+			if alive {
+				continue
+			}
+		}
+		entries = append(entries, &entry)
+	}
+	if len(messages) < limit {
+		return entries, "", nil
+	}
+	return entries, messages[len(messages)-1].ID, nil
+}
+
+// LockReplay takes the replay lock of the stream
+func (s *RedisStore) LockReplay(ctx context.Context) (func(), bool, error) {
+	lock, err := s.locker.Acquire(ctx, "replay")
+	if errors.Is(err, locks.ErrHeld) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return func() { lock.Release(context.WithoutCancel(ctx)) }, true, nil
+}
+
+// Close releases the writer lock and closes the Redis client
+func (s *RedisStore) Close() error {
+	s.writer.Release(context.Background())
+	return s.client.Close()
+}
@@ -0,0 +1,106 @@
+package wal
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// pendingFiles pages through the pending entries of a store, returning
+// their file paths
+func pendingFiles(t *testing.T, store Store, limit int) []string {
+	t.Helper()
+	var files []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("paging did not end")
+		}
+		entries, next, err := store.Pending(context.Background(), cursor, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			files = append(files, entry.FilePath)
+		}
+		if next == "" {
+			return files
+		}
+		cursor = next
+	}
+}
+
+func TestFileStorePendingPagesEntriesOfClosedWriters(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vectors.wal")
+
+	// A process logs three upserts and stores one before it dies
+	crashed, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var acked string
+	for _, file := range []string{"/data/a.md", "/data/b.md", "/data/c.md"} {
+		entry := &Entry{FilePath: file}
+		if err := crashed.Append(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+		if file == "/data/b.md" {
+			acked = entry.ID
+		}
+	}
+	if err := crashed.Ack(ctx, acked); err != nil {
+		t.Fatal(err)
+	}
+	crashed.Close() //nolint:errcheck
+
+	// The next process is logging an upsert of its own while it replays
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close() //nolint:errcheck
+	if err := store.Append(ctx, &Entry{FilePath: "/data/d.md"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, limit := range []int{1, 2, 100} {
+		got := pendingFiles(t, store, limit)
+		if len(got) != 2 || got[0] != "/data/a.md" || got[1] != "/data/c.md" {
+			t.Errorf("pending with pages of %d = %v, want the crashed process's a and c", limit, got)
+		}
+	}
+
+	// Acknowledging an entry between pages does not lose the cursor
+	entries, next, err := store.Pending(ctx, "", 1)
+	if err != nil || len(entries) != 1 || next == "" {
+		t.Fatalf("first page = %v, %q, %v", entries, next, err)
+	}
+	if err := store.Ack(ctx, entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err = store.Pending(ctx, next, 1)
+	if err != nil || len(entries) != 1 || entries[0].FilePath != "/data/c.md" {
+		t.Fatalf("second page = %v, %v; want c", entries, err)
+	}
+}
+
+func TestFileStoreLockReplay(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "vectors.wal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close() //nolint:errcheck
+
+	unlock, ok, err := store.LockReplay(context.Background())
+	if err != nil || !ok {
+		t.Fatalf("LockReplay = %v, %v; want the lock", ok, err)
+	}
+	if _, ok, _ := store.LockReplay(context.Background()); ok {
+		t.Error("second LockReplay took the held lock")
+	}
+	unlock()
+	if _, ok, _ := store.LockReplay(context.Background()); !ok {
+		t.Error("LockReplay after unlock = false")
+	}
+}
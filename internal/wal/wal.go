@@ -0,0 +1,90 @@
+// Package wal is a write-ahead log for vectors that were embedded but not
+// yet stored. Entries are appended before an upsert and acknowledged once
+// the vector store accepted it, so vectors prepared by a process that died
+// in between are upserted on the next start instead of being lost. A log
+// shared by several processes in Redis has each replay only the entries
+// of writers that stopped, one process at a time.
+package wal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/pinecone"
+	"github.com/nadeeshame/rag-knowledge-service/internal/adapters/redisclient"
+	"github.com/nadeeshame/rag-knowledge-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// Entry is one upsert waiting to be acknowledged
+type Entry struct {
+	ID string `json:"id"`
+	// Writer names the store instance that appended the entry; its entries
+	// are left alone while it is open, as it may still be storing them
+	Writer     string             `json:"writer,omitempty"`
+	DocumentID string             `json:"document_id"`
+	FilePath   string             `json:"file_path"`
+	Category   string             `json:"category,omitempty"`  // file category, which routes chunk vectors
+	Namespace  string             `json:"namespace,omitempty"` // empty for the chunk namespace
+	Vectors    []*pinecone.Vector `json:"vectors"`
+	// Partial is set on each batch of a document stored in several; its
+	// replay stores the batch but does not complete the document
+	Partial   bool      `json:"partial,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store is a durable log of pending upserts
+type Store interface {
+	// Append durably records the entry and sets its ID
+	Append(ctx context.Context, entry *Entry) error
+	// Ack removes an entry once its vectors are stored
+	Ack(ctx context.Context, id string) error
+	// Pending returns a page of the entries that were never acknowledged
+	// and whose writer is closed, oldest first. It reads up to limit
+	// entries after the cursor, empty for the oldest, and returns the
+	// cursor of the next page, empty after the last.
+	Pending(ctx context.Context, cursor string, limit int) ([]*Entry, string, error)
+	// LockReplay takes the log's replay lock, so one process replays it at
+	// a time. It returns false when another process holds it, and a
+	// function releasing it otherwise.
+	LockReplay(ctx context.Context) (func(), bool, error)
+	Close() error
+}
+
+// Compile-time checks that the stores implement the interface
+var (
+	_ Store = (*FileStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+// NewStore creates the write-ahead log selected by configuration. It
+// returns nil without an error when the backend is "none"; vectors are then
+// only held in memory until they are upserted.
+func NewStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (Store, error) {
+	switch cfg.WAL.Backend {
+	case "none":
+		return nil, nil
+	case "file":
+		store, err := NewFileStore(cfg.WAL.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Upsert write-ahead log enabled", zap.String("backend", "file"), zap.String("path", cfg.WAL.FilePath))
+		return store, nil
+	case "redis":
+		client, err := redisclient.Connect(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Upsert write-ahead log enabled", zap.String("backend", "redis"), zap.String("stream", cfg.WAL.Stream))
+		store, err := NewRedisStore(ctx, client, cfg.WAL.Stream, cfg.Locks.TTL, logger)
+		if err != nil {
+			client.Close() //nolint:errcheck
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown write-ahead log backend: %s", cfg.WAL.Backend)
+	}
+}
@@ -0,0 +1,171 @@
+// Package client provides typed HTTP clients for calling the platform
+// services from one another. All clients share retry, timeout,
+// authentication and request tracing behavior; requests are sent by
+// internal/transport, which the public SDK in pkg/sdk uses as well.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/transport"
+)
+
+// Header names sent on every request
+const (
+	HeaderAPIKey     = "X-API-Key"
+	HeaderRequestID  = "X-Request-ID"
+	HeaderUserID     = "X-User-ID"
+	HeaderUserGroups = "X-User-Groups"
+	HeaderTenant     = "X-Tenant-ID"
+	HeaderAdminToken = "X-Admin-Token"
+)
+
+// Options configures a service client
+type Options struct {
+	// Timeout bounds each attempt; zero means 30 seconds
+	Timeout time.Duration
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// RetryBackoff is the base delay between retries, doubled per attempt
+	RetryBackoff time.Duration
+	// APIKey is sent in the X-API-Key header when set
+	APIKey string
+	// AdminToken is sent in the X-Admin-Token header when set; the
+	// services serve their admin endpoints only to requests carrying it
+	AdminToken string
+	// UserAgent identifies the calling service
+	UserAgent string
+	// HTTPClient overrides the underlying HTTP client
+	HTTPClient *http.Client
+}
+
+// DefaultOptions returns options suitable for service-to-service calls
+func DefaultOptions() Options {
+	return Options{
+		Timeout:      30 * time.Second,
+		MaxRetries:   2,
+		RetryBackoff: 200 * time.Millisecond,
+		UserAgent:    "repograph-client/1.0",
+	}
+}
+
+// APIError is returned when a service responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("service returned %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from a service
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	identityKey
+)
+
+// WithRequestID attaches a request ID that is forwarded to downstream services
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID attached to the context, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithIdentity attaches the caller identity forwarded for access control
+func WithIdentity(ctx context.Context, identity *models.Identity) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// base implements the shared request handling of all service clients
+type base struct {
+	transport *transport.Client
+	opts      Options
+}
+
+func newBase(baseURL string, opts Options) *base {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	return &base{
+		transport: transport.New(baseURL, opts.HTTPClient, opts.MaxRetries, opts.RetryBackoff),
+		opts:      opts,
+	}
+}
+
+// Health calls the service's /health endpoint; any 2xx response is healthy
+func (b *base) Health(ctx context.Context) error {
+	return b.do(ctx, http.MethodGet, "/health", nil, nil)
+}
+
+// Ping checks the health of the service at baseURL, for services
+// without a typed client
+func Ping(ctx context.Context, baseURL string, opts Options) error {
+	return newBase(baseURL, opts).Health(ctx)
+}
+
+// do sends a JSON request and decodes the JSON response into out. Network
+// errors, 429 and 5xx responses are retried with exponential backoff.
+func (b *base) do(ctx context.Context, method, path string, body, out interface{}) error {
+	return b.transport.Do(ctx, transport.Request{
+		Method:  method,
+		Path:    path,
+		Body:    body,
+		Header:  b.headers(ctx),
+		Timeout: b.opts.Timeout,
+		Error:   apiError,
+	}, out)
+}
+
+// apiError is the error of a non-2xx response
+func apiError(status int, message string, _ time.Duration) error {
+	return &APIError{StatusCode: status, Message: message}
+}
+
+// headers returns the headers of every attempt of a call
+func (b *base) headers(ctx context.Context) http.Header {
+	h := make(http.Header)
+	requestID := RequestID(ctx)
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	h.Set("Accept", "application/json")
+	h.Set(HeaderRequestID, requestID)
+	if b.opts.UserAgent != "" {
+		h.Set("User-Agent", b.opts.UserAgent)
+	}
+	if b.opts.APIKey != "" {
+		h.Set(HeaderAPIKey, b.opts.APIKey)
+	}
+	if b.opts.AdminToken != "" {
+		h.Set(HeaderAdminToken, b.opts.AdminToken)
+	}
+	if identity, ok := ctx.Value(identityKey).(*models.Identity); ok && !identity.Anonymous() {
+		h.Set(HeaderUserID, identity.UserID)
+		if len(identity.Groups) > 0 {
+			h.Set(HeaderUserGroups, strings.Join(identity.Groups, ","))
+		}
+	}
+	if identity, ok := ctx.Value(identityKey).(*models.Identity); ok && identity != nil && identity.Tenant != "" {
+		h.Set(HeaderTenant, identity.Tenant)
+	}
+	return h
+}
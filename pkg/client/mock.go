@@ -0,0 +1,522 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/analytics"
+	"github.com/nadeeshame/rag-knowledge-service/internal/collections"
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/dlq"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/feedback"
+	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"github.com/nadeeshame/rag-knowledge-service/internal/query"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/topics"
+)
+
+// The mocks below implement the client interfaces with overridable
+// functions. Calling a method whose function is unset returns an error.
+
+// MockScanner is a Scanner for tests
+type MockScanner struct {
+	ScanDirectoryFunc func(ctx context.Context, directory string) (*ScanResult, error)
+	ScanChangesFunc   func(ctx context.Context, directory, since string) (*ScanChanges, error)
+	ComputeHashFunc   func(ctx context.Context, filePath string) (string, error)
+	FileMetadataFunc  func(ctx context.Context, filePath string) (*ScannedFile, error)
+}
+
+func (m *MockScanner) ScanDirectory(ctx context.Context, directory string) (*ScanResult, error) {
+	if m.ScanDirectoryFunc == nil {
+		return nil, notMocked("ScanDirectory")
+	}
+	return m.ScanDirectoryFunc(ctx, directory)
+}
+
+func (m *MockScanner) ScanChanges(ctx context.Context, directory, since string) (*ScanChanges, error) {
+	if m.ScanChangesFunc == nil {
+		return nil, notMocked("ScanChanges")
+	}
+	return m.ScanChangesFunc(ctx, directory, since)
+}
+
+func (m *MockScanner) ComputeHash(ctx context.Context, filePath string) (string, error) {
+	if m.ComputeHashFunc == nil {
+		return "", notMocked("ComputeHash")
+	}
+	return m.ComputeHashFunc(ctx, filePath)
+}
+
+func (m *MockScanner) FileMetadata(ctx context.Context, filePath string) (*ScannedFile, error) {
+	if m.FileMetadataFunc == nil {
+		return nil, notMocked("FileMetadata")
+	}
+	return m.FileMetadataFunc(ctx, filePath)
+}
+
+// MockExtractor is an Extractor for tests
+type MockExtractor struct {
+	ExtractFunc func(ctx context.Context, filePath, fileType string, options processors.ExtractOptions) (*ExtractResult, error)
+	FormatsFunc func(ctx context.Context) ([]Format, error)
+}
+
+func (m *MockExtractor) Extract(ctx context.Context, filePath, fileType string, options processors.ExtractOptions) (*ExtractResult, error) {
+	if m.ExtractFunc == nil {
+		return nil, notMocked("Extract")
+	}
+	return m.ExtractFunc(ctx, filePath, fileType, options)
+}
+
+func (m *MockExtractor) Formats(ctx context.Context) ([]Format, error) {
+	if m.FormatsFunc == nil {
+		return nil, notMocked("Formats")
+	}
+	return m.FormatsFunc(ctx)
+}
+
+// MockEmbedder is an Embedder for tests
+type MockEmbedder struct {
+	EmbedFunc      func(ctx context.Context, text string) ([]float32, error)
+	EmbedBatchFunc func(ctx context.Context, texts []string) ([][]float32, error)
+	DimensionFunc  func(ctx context.Context) (int, error)
+}
+
+func (m *MockEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if m.EmbedFunc == nil {
+		return nil, notMocked("Embed")
+	}
+	return m.EmbedFunc(ctx, text)
+}
+
+func (m *MockEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if m.EmbedBatchFunc == nil {
+		return nil, notMocked("EmbedBatch")
+	}
+	return m.EmbedBatchFunc(ctx, texts)
+}
+
+func (m *MockEmbedder) Dimension(ctx context.Context) (int, error) {
+	if m.DimensionFunc == nil {
+		return 0, notMocked("Dimension")
+	}
+	return m.DimensionFunc(ctx)
+}
+
+// MockVectorStore is a VectorStore for tests
+type MockVectorStore struct {
+	UpsertFunc         func(ctx context.Context, namespace string, vectors []Vector) (int, error)
+	SearchFunc         func(ctx context.Context, req *SearchRequest) ([]Match, error)
+	DeleteDocumentFunc func(ctx context.Context, documentID string) (int, error)
+	StatsFunc          func(ctx context.Context) (map[string]interface{}, error)
+	ExistsFunc         func(ctx context.Context, fileHash string) (bool, error)
+}
+
+func (m *MockVectorStore) Upsert(ctx context.Context, namespace string, vectors []Vector) (int, error) {
+	if m.UpsertFunc == nil {
+		return 0, notMocked("Upsert")
+	}
+	return m.UpsertFunc(ctx, namespace, vectors)
+}
+
+func (m *MockVectorStore) Search(ctx context.Context, req *SearchRequest) ([]Match, error) {
+	if m.SearchFunc == nil {
+		return nil, notMocked("Search")
+	}
+	return m.SearchFunc(ctx, req)
+}
+
+func (m *MockVectorStore) DeleteDocument(ctx context.Context, documentID string) (int, error) {
+	if m.DeleteDocumentFunc == nil {
+		return 0, notMocked("DeleteDocument")
+	}
+	return m.DeleteDocumentFunc(ctx, documentID)
+}
+
+func (m *MockVectorStore) Stats(ctx context.Context) (map[string]interface{}, error) {
+	if m.StatsFunc == nil {
+		return nil, notMocked("Stats")
+	}
+	return m.StatsFunc(ctx)
+}
+
+func (m *MockVectorStore) Exists(ctx context.Context, fileHash string) (bool, error) {
+	if m.ExistsFunc == nil {
+		return false, notMocked("Exists")
+	}
+	return m.ExistsFunc(ctx, fileHash)
+}
+
+// MockQuerier is a Querier for tests
+type MockQuerier struct {
+	AskFunc     func(ctx context.Context, req *QueryRequest) (*models.QueryResult, error)
+	SearchFunc  func(ctx context.Context, req *QueryRequest) ([]models.SearchResult, error)
+	CompareFunc func(ctx context.Context, req *CompareRequest) (*models.Comparison, error)
+	SimilarFunc func(ctx context.Context, id, method string, limit int) ([]models.SimilarDocument, error)
+
+	SendFeedbackFunc  func(ctx context.Context, req *FeedbackRequest) (*feedback.Feedback, error)
+	FeedbackFunc      func(ctx context.Context, filter feedback.Filter) ([]*feedback.Feedback, error)
+	FeedbackStatsFunc func(ctx context.Context, since time.Time) (*feedback.Stats, error)
+	PoorlyRatedFunc   func(ctx context.Context, limit int) ([]*feedback.RatedQuery, error)
+	AnalyticsFunc     func(ctx context.Context, since time.Time, limit int) (*analytics.Report, error)
+	PopularFunc       func(ctx context.Context, limit int) ([]query.PopularDocument, error)
+}
+
+func (m *MockQuerier) Ask(ctx context.Context, req *QueryRequest) (*models.QueryResult, error) {
+	if m.AskFunc == nil {
+		return nil, notMocked("Ask")
+	}
+	return m.AskFunc(ctx, req)
+}
+
+func (m *MockQuerier) Search(ctx context.Context, req *QueryRequest) ([]models.SearchResult, error) {
+	if m.SearchFunc == nil {
+		return nil, notMocked("Search")
+	}
+	return m.SearchFunc(ctx, req)
+}
+
+func (m *MockQuerier) Compare(ctx context.Context, req *CompareRequest) (*models.Comparison, error) {
+	if m.CompareFunc == nil {
+		return nil, notMocked("Compare")
+	}
+	return m.CompareFunc(ctx, req)
+}
+
+func (m *MockQuerier) SimilarDocuments(ctx context.Context, id, method string, limit int) ([]models.SimilarDocument, error) {
+	if m.SimilarFunc == nil {
+		return nil, notMocked("SimilarDocuments")
+	}
+	return m.SimilarFunc(ctx, id, method, limit)
+}
+
+func (m *MockQuerier) SendFeedback(ctx context.Context, req *FeedbackRequest) (*feedback.Feedback, error) {
+	if m.SendFeedbackFunc == nil {
+		return nil, notMocked("SendFeedback")
+	}
+	return m.SendFeedbackFunc(ctx, req)
+}
+
+func (m *MockQuerier) Feedback(ctx context.Context, filter feedback.Filter) ([]*feedback.Feedback, error) {
+	if m.FeedbackFunc == nil {
+		return nil, notMocked("Feedback")
+	}
+	return m.FeedbackFunc(ctx, filter)
+}
+
+func (m *MockQuerier) FeedbackStats(ctx context.Context, since time.Time) (*feedback.Stats, error) {
+	if m.FeedbackStatsFunc == nil {
+		return nil, notMocked("FeedbackStats")
+	}
+	return m.FeedbackStatsFunc(ctx, since)
+}
+
+func (m *MockQuerier) PoorlyRated(ctx context.Context, limit int) ([]*feedback.RatedQuery, error) {
+	if m.PoorlyRatedFunc == nil {
+		return nil, notMocked("PoorlyRated")
+	}
+	return m.PoorlyRatedFunc(ctx, limit)
+}
+
+func (m *MockQuerier) QueryAnalytics(ctx context.Context, since time.Time, limit int) (*analytics.Report, error) {
+	if m.AnalyticsFunc == nil {
+		return nil, notMocked("QueryAnalytics")
+	}
+	return m.AnalyticsFunc(ctx, since, limit)
+}
+
+func (m *MockQuerier) PopularDocuments(ctx context.Context, limit int) ([]query.PopularDocument, error) {
+	if m.PopularFunc == nil {
+		return nil, notMocked("PopularDocuments")
+	}
+	return m.PopularFunc(ctx, limit)
+}
+
+// MockOrchestrator is an Orchestrator for tests
+type MockOrchestrator struct {
+	DocumentsFunc     func(ctx context.Context, filter DocumentFilter) ([]*registry.Record, error)
+	DocumentFunc      func(ctx context.Context, id string) (*registry.Record, error)
+	StaleFunc         func(ctx context.Context, months int, pathPrefix string, limit int) (*StaleReport, error)
+	DocumentLinksFunc func(ctx context.Context, id string) (*DocumentLinks, error)
+	ReindexFunc       func(ctx context.Context, id string) error
+	RechunkFunc       func(ctx context.Context, ids []string) (*RerunResult, error)
+	ResummarizeFunc   func(ctx context.Context, ids []string) (*RerunResult, error)
+
+	CollectionsFunc          func(ctx context.Context) ([]CollectionSummary, error)
+	CreateCollectionFunc     func(ctx context.Context, name, description string) (*collections.Collection, error)
+	CollectionFunc           func(ctx context.Context, name string) (*collections.Collection, error)
+	DeleteCollectionFunc     func(ctx context.Context, name string) error
+	AddToCollectionFunc      func(ctx context.Context, name string, ids []string) (*CollectionUpdate, error)
+	RemoveFromCollectionFunc func(ctx context.Context, name, id string) (*collections.Collection, error)
+
+	DigestsFunc   func(ctx context.Context) ([]DigestReport, error)
+	RunDigestFunc func(ctx context.Context, name string, preview bool) (*DigestRun, error)
+
+	TopicsFunc    func(ctx context.Context) (*topics.Overview, error)
+	RunTopicsFunc func(ctx context.Context) (*topics.Overview, error)
+
+	DuplicatesFunc func(ctx context.Context, threshold float64) (*orchestrator.DuplicateReport, error)
+	EventsFunc     func(ctx context.Context, after string, limit int, types ...string) (*EventPage, error)
+
+	ProcessBatchFunc func(ctx context.Context, items []string, force bool) (*BatchResult, error)
+
+	RunsFunc       func(ctx context.Context, limit int) ([]RunSummary, error)
+	RunFunc        func(ctx context.Context, id string) (*RunReport, error)
+	WaitRunFunc    func(ctx context.Context, id string, wait time.Duration) (*RunReport, error)
+	RunChangesFunc func(ctx context.Context, id string) (*RunChanges, error)
+	CancelRunFunc  func(ctx context.Context, id string) error
+	ResumeRunFunc  func(ctx context.Context, id string) (*RunResume, error)
+
+	IndexingFunc       func(ctx context.Context) (*IndexingStatus, error)
+	PauseIndexingFunc  func(ctx context.Context) (*IndexingStatus, error)
+	ResumeIndexingFunc func(ctx context.Context) (*IndexingStatus, error)
+
+	DeadLettersFunc       func(ctx context.Context, limit int) ([]*dlq.Entry, error)
+	RetryDeadLettersFunc  func(ctx context.Context, req DeadLetterRetry) (*DeadLetterRetryResult, error)
+	DiscardDeadLetterFunc func(ctx context.Context, id string) error
+}
+
+func (m *MockOrchestrator) Documents(ctx context.Context, filter DocumentFilter) ([]*registry.Record, error) {
+	if m.DocumentsFunc == nil {
+		return nil, notMocked("Documents")
+	}
+	return m.DocumentsFunc(ctx, filter)
+}
+
+func (m *MockOrchestrator) Document(ctx context.Context, id string) (*registry.Record, error) {
+	if m.DocumentFunc == nil {
+		return nil, notMocked("Document")
+	}
+	return m.DocumentFunc(ctx, id)
+}
+
+func (m *MockOrchestrator) StaleDocuments(ctx context.Context, months int, pathPrefix string, limit int) (*StaleReport, error) {
+	if m.StaleFunc == nil {
+		return nil, notMocked("StaleDocuments")
+	}
+	return m.StaleFunc(ctx, months, pathPrefix, limit)
+}
+
+func (m *MockOrchestrator) DocumentLinks(ctx context.Context, id string) (*DocumentLinks, error) {
+	if m.DocumentLinksFunc == nil {
+		return nil, notMocked("DocumentLinks")
+	}
+	return m.DocumentLinksFunc(ctx, id)
+}
+
+func (m *MockOrchestrator) Reindex(ctx context.Context, id string) error {
+	if m.ReindexFunc == nil {
+		return notMocked("Reindex")
+	}
+	return m.ReindexFunc(ctx, id)
+}
+
+func (m *MockOrchestrator) Rechunk(ctx context.Context, ids []string) (*RerunResult, error) {
+	if m.RechunkFunc == nil {
+		return nil, notMocked("Rechunk")
+	}
+	return m.RechunkFunc(ctx, ids)
+}
+
+func (m *MockOrchestrator) Resummarize(ctx context.Context, ids []string) (*RerunResult, error) {
+	if m.ResummarizeFunc == nil {
+		return nil, notMocked("Resummarize")
+	}
+	return m.ResummarizeFunc(ctx, ids)
+}
+
+func (m *MockOrchestrator) Collections(ctx context.Context) ([]CollectionSummary, error) {
+	if m.CollectionsFunc == nil {
+		return nil, notMocked("Collections")
+	}
+	return m.CollectionsFunc(ctx)
+}
+
+func (m *MockOrchestrator) CreateCollection(ctx context.Context, name, description string) (*collections.Collection, error) {
+	if m.CreateCollectionFunc == nil {
+		return nil, notMocked("CreateCollection")
+	}
+	return m.CreateCollectionFunc(ctx, name, description)
+}
+
+func (m *MockOrchestrator) Collection(ctx context.Context, name string) (*collections.Collection, error) {
+	if m.CollectionFunc == nil {
+		return nil, notMocked("Collection")
+	}
+	return m.CollectionFunc(ctx, name)
+}
+
+func (m *MockOrchestrator) DeleteCollection(ctx context.Context, name string) error {
+	if m.DeleteCollectionFunc == nil {
+		return notMocked("DeleteCollection")
+	}
+	return m.DeleteCollectionFunc(ctx, name)
+}
+
+func (m *MockOrchestrator) AddToCollection(ctx context.Context, name string, ids []string) (*CollectionUpdate, error) {
+	if m.AddToCollectionFunc == nil {
+		return nil, notMocked("AddToCollection")
+	}
+	return m.AddToCollectionFunc(ctx, name, ids)
+}
+
+func (m *MockOrchestrator) RemoveFromCollection(ctx context.Context, name, id string) (*collections.Collection, error) {
+	if m.RemoveFromCollectionFunc == nil {
+		return nil, notMocked("RemoveFromCollection")
+	}
+	return m.RemoveFromCollectionFunc(ctx, name, id)
+}
+
+func (m *MockOrchestrator) Digests(ctx context.Context) ([]DigestReport, error) {
+	if m.DigestsFunc == nil {
+		return nil, notMocked("Digests")
+	}
+	return m.DigestsFunc(ctx)
+}
+
+func (m *MockOrchestrator) RunDigest(ctx context.Context, name string, preview bool) (*DigestRun, error) {
+	if m.RunDigestFunc == nil {
+		return nil, notMocked("RunDigest")
+	}
+	return m.RunDigestFunc(ctx, name, preview)
+}
+
+func (m *MockOrchestrator) Topics(ctx context.Context) (*topics.Overview, error) {
+	if m.TopicsFunc == nil {
+		return nil, notMocked("Topics")
+	}
+	return m.TopicsFunc(ctx)
+}
+
+func (m *MockOrchestrator) RunTopics(ctx context.Context) (*topics.Overview, error) {
+	if m.RunTopicsFunc == nil {
+		return nil, notMocked("RunTopics")
+	}
+	return m.RunTopicsFunc(ctx)
+}
+
+func (m *MockOrchestrator) Duplicates(ctx context.Context, threshold float64) (*orchestrator.DuplicateReport, error) {
+	if m.DuplicatesFunc == nil {
+		return nil, notMocked("Duplicates")
+	}
+	return m.DuplicatesFunc(ctx, threshold)
+}
+
+func (m *MockOrchestrator) Events(ctx context.Context, after string, limit int, types ...string) (*EventPage, error) {
+	if m.EventsFunc == nil {
+		return nil, notMocked("Events")
+	}
+	return m.EventsFunc(ctx, after, limit, types...)
+}
+
+func (m *MockOrchestrator) ProcessBatch(ctx context.Context, items []string, force bool) (*BatchResult, error) {
+	if m.ProcessBatchFunc == nil {
+		return nil, notMocked("ProcessBatch")
+	}
+	return m.ProcessBatchFunc(ctx, items, force)
+}
+
+func (m *MockOrchestrator) Runs(ctx context.Context, limit int) ([]RunSummary, error) {
+	if m.RunsFunc == nil {
+		return nil, notMocked("Runs")
+	}
+	return m.RunsFunc(ctx, limit)
+}
+
+func (m *MockOrchestrator) WaitRun(ctx context.Context, id string, wait time.Duration) (*RunReport, error) {
+	if m.WaitRunFunc == nil {
+		return nil, notMocked("WaitRun")
+	}
+	return m.WaitRunFunc(ctx, id, wait)
+}
+
+func (m *MockOrchestrator) RunChanges(ctx context.Context, id string) (*RunChanges, error) {
+	if m.RunChangesFunc == nil {
+		return nil, notMocked("RunChanges")
+	}
+	return m.RunChangesFunc(ctx, id)
+}
+
+func (m *MockOrchestrator) Run(ctx context.Context, id string) (*RunReport, error) {
+	if m.RunFunc == nil {
+		return nil, notMocked("Run")
+	}
+	return m.RunFunc(ctx, id)
+}
+
+func (m *MockOrchestrator) CancelRun(ctx context.Context, id string) error {
+	if m.CancelRunFunc == nil {
+		return notMocked("CancelRun")
+	}
+	return m.CancelRunFunc(ctx, id)
+}
+
+func (m *MockOrchestrator) ResumeRun(ctx context.Context, id string) (*RunResume, error) {
+	if m.ResumeRunFunc == nil {
+		return nil, notMocked("ResumeRun")
+	}
+	return m.ResumeRunFunc(ctx, id)
+}
+
+func (m *MockOrchestrator) Indexing(ctx context.Context) (*IndexingStatus, error) {
+	if m.IndexingFunc == nil {
+		return nil, notMocked("Indexing")
+	}
+	return m.IndexingFunc(ctx)
+}
+
+func (m *MockOrchestrator) PauseIndexing(ctx context.Context) (*IndexingStatus, error) {
+	if m.PauseIndexingFunc == nil {
+		return nil, notMocked("PauseIndexing")
+	}
+	return m.PauseIndexingFunc(ctx)
+}
+
+func (m *MockOrchestrator) ResumeIndexing(ctx context.Context) (*IndexingStatus, error) {
+	if m.ResumeIndexingFunc == nil {
+		return nil, notMocked("ResumeIndexing")
+	}
+	return m.ResumeIndexingFunc(ctx)
+}
+
+func (m *MockOrchestrator) DeadLetters(ctx context.Context, limit int) ([]*dlq.Entry, error) {
+	if m.DeadLettersFunc == nil {
+		return nil, notMocked("DeadLetters")
+	}
+	return m.DeadLettersFunc(ctx, limit)
+}
+
+func (m *MockOrchestrator) RetryDeadLetters(ctx context.Context, req DeadLetterRetry) (*DeadLetterRetryResult, error) {
+	if m.RetryDeadLettersFunc == nil {
+		return nil, notMocked("RetryDeadLetters")
+	}
+	return m.RetryDeadLettersFunc(ctx, req)
+}
+
+func (m *MockOrchestrator) DiscardDeadLetter(ctx context.Context, id string) error {
+	if m.DiscardDeadLetterFunc == nil {
+		return notMocked("DiscardDeadLetter")
+	}
+	return m.DiscardDeadLetterFunc(ctx, id)
+}
+
+func notMocked(method string) error {
+	return fmt.Errorf("%s called on mock without an implementation", method)
+}
+
+// Compile-time checks that the HTTP clients and mocks satisfy the interfaces
+var (
+	_ Scanner      = (*ScannerClient)(nil)
+	_ Extractor    = (*ExtractorClient)(nil)
+	_ Embedder     = (*EmbedderClient)(nil)
+	_ VectorStore  = (*VectorStoreClient)(nil)
+	_ Querier      = (*QueryClient)(nil)
+	_ Orchestrator = (*OrchestratorClient)(nil)
+	_ Scanner      = (*MockScanner)(nil)
+	_ Extractor    = (*MockExtractor)(nil)
+	_ Embedder     = (*MockEmbedder)(nil)
+	_ VectorStore  = (*MockVectorStore)(nil)
+	_ Querier      = (*MockQuerier)(nil)
+	_ Orchestrator = (*MockOrchestrator)(nil)
+)
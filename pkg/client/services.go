@@ -0,0 +1,1151 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/analytics"
+	"github.com/nadeeshame/rag-knowledge-service/internal/collections"
+	"github.com/nadeeshame/rag-knowledge-service/internal/content-extractor/processors"
+	"github.com/nadeeshame/rag-knowledge-service/internal/dlq"
+	"github.com/nadeeshame/rag-knowledge-service/internal/domain/models"
+	"github.com/nadeeshame/rag-knowledge-service/internal/events"
+	"github.com/nadeeshame/rag-knowledge-service/internal/feedback"
+	"github.com/nadeeshame/rag-knowledge-service/internal/orchestrator"
+	"github.com/nadeeshame/rag-knowledge-service/internal/query"
+	"github.com/nadeeshame/rag-knowledge-service/internal/registry"
+	"github.com/nadeeshame/rag-knowledge-service/internal/runs"
+	"github.com/nadeeshame/rag-knowledge-service/internal/scanner"
+	"github.com/nadeeshame/rag-knowledge-service/internal/topics"
+)
+
+// ScannedFile describes a file found by the document scanner
+type ScannedFile struct {
+	Path         string    `json:"path"`
+	Name         string    `json:"name"`
+	Extension    string    `json:"extension"`
+	Size         int64     `json:"size"`
+	ModifiedTime time.Time `json:"modified_time"`
+	Hash         string    `json:"hash"`
+	MimeType     string    `json:"mime_type"`
+	Category     string    `json:"category,omitempty"`
+	DetectedType string    `json:"detected_type,omitempty"`
+	TypeMismatch string    `json:"type_mismatch,omitempty"`
+}
+
+// ScanResult is the response of a directory scan
+type ScanResult struct {
+	Directory  string            `json:"directory"`
+	Files      []ScannedFile     `json:"files"`
+	TotalFiles int               `json:"total_files"`
+	TotalSize  int64             `json:"total_size"`
+	Skipped    []scanner.Skipped `json:"skipped,omitempty"`
+}
+
+// ScanChanges is the response of a change scan: the files added,
+// modified and deleted since the last change scan of the directory
+type ScanChanges struct {
+	Directory string            `json:"directory"`
+	Version   string            `json:"version"` // pass as since to the next change scan
+	Since     string            `json:"since,omitempty"`
+	Full      bool              `json:"full"` // every file is listed as added
+	Added     []ScannedFile     `json:"added"`
+	Modified  []ScannedFile     `json:"modified"`
+	Deleted   []string          `json:"deleted"`
+	Unchanged int               `json:"unchanged"`
+	Skipped   []scanner.Skipped `json:"skipped,omitempty"`
+}
+
+// Changed returns the paths of the added and modified files
+func (c *ScanChanges) Changed() []string {
+	paths := make([]string, 0, len(c.Added)+len(c.Modified))
+	for _, files := range [][]ScannedFile{c.Added, c.Modified} {
+		for _, f := range files {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
+
+// Scanner calls the document scanner service
+type Scanner interface {
+	ScanDirectory(ctx context.Context, directory string) (*ScanResult, error)
+	ScanChanges(ctx context.Context, directory, since string) (*ScanChanges, error)
+	ComputeHash(ctx context.Context, filePath string) (string, error)
+	FileMetadata(ctx context.Context, filePath string) (*ScannedFile, error)
+}
+
+// ScannerClient is the HTTP implementation of Scanner
+type ScannerClient struct {
+	*base
+}
+
+// NewScannerClient creates a document scanner client
+func NewScannerClient(baseURL string, opts Options) *ScannerClient {
+	return &ScannerClient{base: newBase(baseURL, opts)}
+}
+
+// ScanDirectory lists the files of a directory
+func (c *ScannerClient) ScanDirectory(ctx context.Context, directory string) (*ScanResult, error) {
+	var result ScanResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/scan/directory", map[string]string{"directory": directory}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ScanChanges lists the files changed since the last change scan of a
+// directory, which becomes the new baseline. since is the version the
+// caller last saw, or empty; when it is not the last version, every file
+// is listed as added.
+func (c *ScannerClient) ScanChanges(ctx context.Context, directory, since string) (*ScanChanges, error) {
+	var result ScanChanges
+	body := map[string]string{"directory": directory, "since": since}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/scan/changes", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FileMetadata returns the metadata of a file. POSIX, Windows drive,
+// UNC and long paths are accepted.
+func (c *ScannerClient) FileMetadata(ctx context.Context, filePath string) (*ScannedFile, error) {
+	var result ScannedFile
+	if err := c.do(ctx, http.MethodPost, "/api/v1/metadata", map[string]string{"file_path": filePath}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ComputeHash returns the hash of a file
+func (c *ScannerClient) ComputeHash(ctx context.Context, filePath string) (string, error) {
+	var result struct {
+		Hash string `json:"hash"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/compute-hash", map[string]string{"file_path": filePath}, &result); err != nil {
+		return "", err
+	}
+	return result.Hash, nil
+}
+
+// ExtractResult is the response of a content extraction
+type ExtractResult struct {
+	Content     string                 `json:"content"`
+	Size        int64                  `json:"size"`
+	Truncated   bool                   `json:"truncated"`          // content was cut at the service's in-memory limit
+	Encoding    string                 `json:"encoding,omitempty"` // source encoding, when the content was normalized
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	ExtractedAt time.Time              `json:"extracted_at"`
+}
+
+// Format lists the extensions supported for a file category
+type Format struct {
+	Category   string   `json:"category"`
+	Extensions []string `json:"extensions"`
+}
+
+// Extractor calls the content extractor service
+type Extractor interface {
+	Extract(ctx context.Context, filePath, fileType string, options processors.ExtractOptions) (*ExtractResult, error)
+	Formats(ctx context.Context) ([]Format, error)
+}
+
+// ExtractorClient is the HTTP implementation of Extractor
+type ExtractorClient struct {
+	*base
+}
+
+// NewExtractorClient creates a content extractor client
+func NewExtractorClient(baseURL string, opts Options) *ExtractorClient {
+	return &ExtractorClient{base: newBase(baseURL, opts)}
+}
+
+// Extract extracts the text content of a file
+func (c *ExtractorClient) Extract(ctx context.Context, filePath, fileType string, options processors.ExtractOptions) (*ExtractResult, error) {
+	req := map[string]interface{}{
+		"file_path": filePath,
+		"file_type": fileType,
+		"options":   options,
+	}
+	var result ExtractResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/extract", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Formats returns the supported file formats
+func (c *ExtractorClient) Formats(ctx context.Context) ([]Format, error) {
+	var result struct {
+		Formats []Format `json:"formats"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/formats", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Formats, nil
+}
+
+// Embedder calls the embedding service
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	Dimension(ctx context.Context) (int, error)
+}
+
+// EmbedderClient is the HTTP implementation of Embedder
+type EmbedderClient struct {
+	*base
+}
+
+// NewEmbedderClient creates an embedding service client
+func NewEmbedderClient(baseURL string, opts Options) *EmbedderClient {
+	return &EmbedderClient{base: newBase(baseURL, opts)}
+}
+
+// Embed creates an embedding for a single text
+func (c *EmbedderClient) Embed(ctx context.Context, text string) ([]float32, error) {
+	var result struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/embed", map[string]string{"text": text}, &result); err != nil {
+		return nil, err
+	}
+	return result.Embedding, nil
+}
+
+// EmbedBatch creates embeddings for multiple texts
+func (c *EmbedderClient) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/embed/batch", map[string][]string{"texts": texts}, &result); err != nil {
+		return nil, err
+	}
+	return result.Embeddings, nil
+}
+
+// Dimension returns the embedding dimension
+func (c *EmbedderClient) Dimension(ctx context.Context) (int, error) {
+	var result struct {
+		Dimension int `json:"dimension"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/dimension", nil, &result); err != nil {
+		return 0, err
+	}
+	return result.Dimension, nil
+}
+
+// Vector is a vector with its metadata
+type Vector struct {
+	ID       string                 `json:"id"`
+	Values   []float32              `json:"values"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Match is a vector search result
+type Match struct {
+	ID       string                 `json:"id"`
+	Score    float32                `json:"score"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// SearchRequest is a vector similarity search
+type SearchRequest struct {
+	QueryVector []float32              `json:"query_vector"`
+	TopK        int                    `json:"top_k,omitempty"`
+	Namespace   string                 `json:"namespace,omitempty"`
+	Filter      map[string]interface{} `json:"filter,omitempty"`
+}
+
+// VectorStore calls the vector store service
+type VectorStore interface {
+	Upsert(ctx context.Context, namespace string, vectors []Vector) (int, error)
+	Search(ctx context.Context, req *SearchRequest) ([]Match, error)
+	DeleteDocument(ctx context.Context, documentID string) (int, error)
+	Stats(ctx context.Context) (map[string]interface{}, error)
+	Exists(ctx context.Context, fileHash string) (bool, error)
+}
+
+// VectorStoreClient is the HTTP implementation of VectorStore
+type VectorStoreClient struct {
+	*base
+}
+
+// NewVectorStoreClient creates a vector store client
+func NewVectorStoreClient(baseURL string, opts Options) *VectorStoreClient {
+	return &VectorStoreClient{base: newBase(baseURL, opts)}
+}
+
+// Upsert stores vectors in a namespace (empty for the default)
+func (c *VectorStoreClient) Upsert(ctx context.Context, namespace string, vectors []Vector) (int, error) {
+	req := map[string]interface{}{"vectors": vectors, "namespace": namespace}
+	var result struct {
+		UpsertedCount int `json:"upserted_count"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/upsert", req, &result); err != nil {
+		return 0, err
+	}
+	return result.UpsertedCount, nil
+}
+
+// Search returns the vectors nearest to the query vector
+func (c *VectorStoreClient) Search(ctx context.Context, req *SearchRequest) ([]Match, error) {
+	var result struct {
+		Results []Match `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/search", req, &result); err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+// DeleteDocument removes all vectors of a document and returns how many were deleted
+func (c *VectorStoreClient) DeleteDocument(ctx context.Context, documentID string) (int, error) {
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/document/"+url.PathEscape(documentID), nil, &result); err != nil {
+		return 0, err
+	}
+	return result.Count, nil
+}
+
+// Stats returns index statistics
+func (c *VectorStoreClient) Stats(ctx context.Context) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/stats", nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Exists reports whether a document with the given file hash is indexed
+func (c *VectorStoreClient) Exists(ctx context.Context, fileHash string) (bool, error) {
+	var result struct {
+		Exists bool `json:"exists"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/exists/"+url.PathEscape(fileHash), nil, &result); err != nil {
+		return false, err
+	}
+	return result.Exists, nil
+}
+
+// QueryRequest is a question or search sent to the query service
+type QueryRequest struct {
+	Text      string        `json:"text"`
+	TopK      int           `json:"top_k,omitempty"`
+	Namespace string        `json:"namespace,omitempty"`
+	Filter    models.Filter `json:"filter,omitempty"`
+	AsOf      string        `json:"as_of,omitempty"`
+	Profile   string        `json:"profile,omitempty"`
+	MinScore  *float64      `json:"min_score,omitempty"`
+	// FreshnessHalfLife in days ranks recently modified documents higher;
+	// 0 turns it off
+	FreshnessHalfLife *int `json:"freshness_half_life,omitempty"`
+	// ResponseFormat json_schema asks for an answer conforming to Schema
+	ResponseFormat string          `json:"response_format,omitempty"`
+	Schema         json.RawMessage `json:"schema,omitempty"`
+}
+
+// CompareRequest is a question comparing two documents, each given by ID
+// or file path
+type CompareRequest struct {
+	Text   string                  `json:"text"`
+	Left   models.ComparisonTarget `json:"left"`
+	Right  models.ComparisonTarget `json:"right"`
+	TopK   int                     `json:"top_k,omitempty"` // passages from each document
+	Filter models.Filter           `json:"filter,omitempty"`
+	AsOf   string                  `json:"as_of,omitempty"`
+}
+
+// Querier calls the query service
+type Querier interface {
+	Ask(ctx context.Context, req *QueryRequest) (*models.QueryResult, error)
+	Search(ctx context.Context, req *QueryRequest) ([]models.SearchResult, error)
+	Compare(ctx context.Context, req *CompareRequest) (*models.Comparison, error)
+	SimilarDocuments(ctx context.Context, id, method string, limit int) ([]models.SimilarDocument, error)
+	SendFeedback(ctx context.Context, req *FeedbackRequest) (*feedback.Feedback, error)
+	Feedback(ctx context.Context, filter feedback.Filter) ([]*feedback.Feedback, error)
+	FeedbackStats(ctx context.Context, since time.Time) (*feedback.Stats, error)
+	PoorlyRated(ctx context.Context, limit int) ([]*feedback.RatedQuery, error)
+	QueryAnalytics(ctx context.Context, since time.Time, limit int) (*analytics.Report, error)
+	PopularDocuments(ctx context.Context, limit int) ([]query.PopularDocument, error)
+}
+
+// FeedbackRequest rates an answer by the query_id of its result
+type FeedbackRequest struct {
+	QueryID string `json:"query_id"`
+	Rating  string `json:"rating"` // up or down
+	Comment string `json:"comment,omitempty"`
+	Query   string `json:"query,omitempty"` // question asked
+}
+
+// QueryClient is the HTTP implementation of Querier
+type QueryClient struct {
+	*base
+}
+
+// NewQueryClient creates a query service client
+func NewQueryClient(baseURL string, opts Options) *QueryClient {
+	return &QueryClient{base: newBase(baseURL, opts)}
+}
+
+// Ask answers a question using retrieved context
+func (c *QueryClient) Ask(ctx context.Context, req *QueryRequest) (*models.QueryResult, error) {
+	var result models.QueryResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/ask", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Search returns the chunks most relevant to the text
+func (c *QueryClient) Search(ctx context.Context, req *QueryRequest) ([]models.SearchResult, error) {
+	var result struct {
+		Results []models.SearchResult `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/search", req, &result); err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+// Compare answers a question comparing two documents
+func (c *QueryClient) Compare(ctx context.Context, req *CompareRequest) (*models.Comparison, error) {
+	var result models.Comparison
+	if err := c.do(ctx, http.MethodPost, "/api/v1/compare", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SimilarDocuments returns the documents most like a document, by the mean
+// (method mean) or a sample (method sample) of its chunk vectors; empty
+// method and zero limit use the service defaults
+func (c *QueryClient) SimilarDocuments(ctx context.Context, id, method string, limit int) ([]models.SimilarDocument, error) {
+	params := url.Values{}
+	if method != "" {
+		params.Set("method", method)
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/api/v1/documents/" + url.PathEscape(id) + "/similar"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	var result struct {
+		Documents []models.SimilarDocument `json:"documents"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Documents, nil
+}
+
+// SendFeedback rates an answer, replacing the caller's earlier rating of it
+func (c *QueryClient) SendFeedback(ctx context.Context, req *FeedbackRequest) (*feedback.Feedback, error) {
+	var result feedback.Feedback
+	if err := c.do(ctx, http.MethodPost, "/api/v1/feedback", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Feedback lists answer ratings, newest first
+func (c *QueryClient) Feedback(ctx context.Context, filter feedback.Filter) ([]*feedback.Feedback, error) {
+	params := url.Values{}
+	if filter.Rating != "" {
+		params.Set("rating", filter.Rating)
+	}
+	if filter.Since != nil {
+		params.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if filter.Limit > 0 {
+		params.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	path := "/api/v1/feedback"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	var result struct {
+		Feedback []*feedback.Feedback `json:"feedback"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Feedback, nil
+}
+
+// FeedbackStats aggregates the answer ratings given since a time; the zero
+// time aggregates all of them
+func (c *QueryClient) FeedbackStats(ctx context.Context, since time.Time) (*feedback.Stats, error) {
+	path := "/api/v1/feedback/stats"
+	if !since.IsZero() {
+		path += "?" + url.Values{"since": {since.Format(time.RFC3339)}}.Encode()
+	}
+	var result feedback.Stats
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PoorlyRated returns the queries rated down more than up, the most down
+// ratings first; zero limit returns all of them
+func (c *QueryClient) PoorlyRated(ctx context.Context, limit int) ([]*feedback.RatedQuery, error) {
+	path := "/api/v1/feedback/poor"
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	var result struct {
+		Queries []*feedback.RatedQuery `json:"queries"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Queries, nil
+}
+
+// QueryAnalytics aggregates the queries run since a time, listing the limit
+// most frequent; the zero time and zero limit use the service defaults
+func (c *QueryClient) QueryAnalytics(ctx context.Context, since time.Time, limit int) (*analytics.Report, error) {
+	params := url.Values{}
+	if !since.IsZero() {
+		params.Set("since", since.Format(time.RFC3339))
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/api/v1/analytics/queries"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+	var result analytics.Report
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PopularDocuments returns the documents used in the most answers; zero
+// limit uses the service default
+func (c *QueryClient) PopularDocuments(ctx context.Context, limit int) ([]query.PopularDocument, error) {
+	path := "/api/v1/documents/popular"
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	var result struct {
+		Documents []query.PopularDocument `json:"documents"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Documents, nil
+}
+
+// DocumentFilter selects documents from the orchestrator's registry
+type DocumentFilter struct {
+	Category        string
+	State           string
+	PathPrefix      string
+	NeedsEnrichment bool // only documents with skipped stages
+	Limit           int
+}
+
+// Orchestrator calls the orchestrator service
+type Orchestrator interface {
+	Documents(ctx context.Context, filter DocumentFilter) ([]*registry.Record, error)
+	Document(ctx context.Context, id string) (*registry.Record, error)
+	StaleDocuments(ctx context.Context, months int, pathPrefix string, limit int) (*StaleReport, error)
+	DocumentLinks(ctx context.Context, id string) (*DocumentLinks, error)
+	Reindex(ctx context.Context, id string) error
+	Rechunk(ctx context.Context, ids []string) (*RerunResult, error)
+	Resummarize(ctx context.Context, ids []string) (*RerunResult, error)
+	Collections(ctx context.Context) ([]CollectionSummary, error)
+	CreateCollection(ctx context.Context, name, description string) (*collections.Collection, error)
+	Collection(ctx context.Context, name string) (*collections.Collection, error)
+	DeleteCollection(ctx context.Context, name string) error
+	AddToCollection(ctx context.Context, name string, ids []string) (*CollectionUpdate, error)
+	RemoveFromCollection(ctx context.Context, name, id string) (*collections.Collection, error)
+	Digests(ctx context.Context) ([]DigestReport, error)
+	RunDigest(ctx context.Context, name string, preview bool) (*DigestRun, error)
+	Topics(ctx context.Context) (*topics.Overview, error)
+	RunTopics(ctx context.Context) (*topics.Overview, error)
+	Duplicates(ctx context.Context, threshold float64) (*orchestrator.DuplicateReport, error)
+	Events(ctx context.Context, after string, limit int, types ...string) (*EventPage, error)
+	ProcessBatch(ctx context.Context, items []string, force bool) (*BatchResult, error)
+	Runs(ctx context.Context, limit int) ([]RunSummary, error)
+	Run(ctx context.Context, id string) (*RunReport, error)
+	WaitRun(ctx context.Context, id string, wait time.Duration) (*RunReport, error)
+	RunChanges(ctx context.Context, id string) (*RunChanges, error)
+	CancelRun(ctx context.Context, id string) error
+	ResumeRun(ctx context.Context, id string) (*RunResume, error)
+	Indexing(ctx context.Context) (*IndexingStatus, error)
+	PauseIndexing(ctx context.Context) (*IndexingStatus, error)
+	ResumeIndexing(ctx context.Context) (*IndexingStatus, error)
+	DeadLetters(ctx context.Context, limit int) ([]*dlq.Entry, error)
+	RetryDeadLetters(ctx context.Context, req DeadLetterRetry) (*DeadLetterRetryResult, error)
+	DiscardDeadLetter(ctx context.Context, id string) error
+}
+
+// StaleReport lists indexed documents whose files were not modified for
+// months, oldest first
+type StaleReport struct {
+	Months    int             `json:"months"`
+	Before    time.Time       `json:"before"`
+	Documents []StaleDocument `json:"documents"`
+	Count     int             `json:"count"`
+}
+
+// StaleDocument is an indexed document not modified for a while
+type StaleDocument struct {
+	DocumentID string     `json:"document_id"`
+	FilePath   string     `json:"file_path"`
+	ModifiedAt *time.Time `json:"modified_at,omitempty"`
+	IndexedAt  *time.Time `json:"indexed_at,omitempty"`
+	AgeDays    int        `json:"age_days"`
+}
+
+// RerunResult reports the documents a rechunk or resummarize request
+// accepted for background processing and why others were rejected
+type RerunResult struct {
+	Status   string            `json:"status"`
+	Accepted []string          `json:"accepted"`
+	Rejected map[string]string `json:"rejected,omitempty"`
+}
+
+// DocumentLink is a document linked to or from a note by a wiki-link.
+// Target is the link as written, for outgoing links.
+type DocumentLink struct {
+	Target     string `json:"target,omitempty"`
+	DocumentID string `json:"document_id"`
+	FilePath   string `json:"file_path"`
+	Title      string `json:"title,omitempty"`
+}
+
+// DocumentLinks lists the documents a note links to, the notes linking to
+// it and the link targets matching no document
+type DocumentLinks struct {
+	DocumentID string         `json:"document_id"`
+	Links      []DocumentLink `json:"links"`
+	Backlinks  []DocumentLink `json:"backlinks"`
+	Unresolved []string       `json:"unresolved,omitempty"`
+}
+
+// CollectionSummary describes a collection without its documents
+type CollectionSummary struct {
+	Name          string    `json:"name"`
+	Description   string    `json:"description,omitempty"`
+	DocumentCount int       `json:"document_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CollectionUpdate reports the documents added to a collection and why
+// others were rejected
+type CollectionUpdate struct {
+	Collection *collections.Collection `json:"collection"`
+	Added      int                     `json:"added"`
+	Rejected   map[string]string       `json:"rejected,omitempty"`
+}
+
+// DigestReport is a scheduled digest report with its schedule state
+type DigestReport struct {
+	Name      string     `json:"name"`
+	Title     string     `json:"title"`
+	Schedule  string     `json:"schedule"`
+	Format    string     `json:"format"`
+	NextRun   *time.Time `json:"next_run,omitempty"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// DigestRun is a digest generated on demand
+type DigestRun struct {
+	Body      string `json:"body"` // rendered Markdown or HTML
+	Delivered bool   `json:"delivered"`
+}
+
+// BatchResult reports the status of each file of a batch and the run
+// processing those accepted
+type BatchResult struct {
+	Status   string                   `json:"status"`
+	RunID    string                   `json:"run_id,omitempty"` // empty when no file was accepted
+	Accepted int                      `json:"accepted"`
+	Skipped  int                      `json:"skipped"`
+	Invalid  int                      `json:"invalid"`
+	Items    []orchestrator.BatchItem `json:"items"`
+}
+
+// RunSummary describes an indexing run without its changes
+type RunSummary struct {
+	ID         string    `json:"id"`
+	Directory  string    `json:"directory"`
+	Batch      bool      `json:"batch,omitempty"` // processes a list of files rather than a directory
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Total      int       `json:"total_files"`
+	Processed  int       `json:"processed"`
+	Skipped    int       `json:"skipped"`
+	Failed     int       `json:"failed"`
+	Added      int       `json:"added"`
+	Updated    int       `json:"updated"`
+	Remaining  int       `json:"remaining,omitempty"`
+	// DurationMs is the run's wall time; StageMs the time its files spent
+	// in each stage
+	DurationMs int64            `json:"duration_ms"`
+	StageMs    map[string]int64 `json:"stage_ms"`
+	Tokens     runs.TokenUsage  `json:"tokens"`
+}
+
+// RunReport is a run with the outcome, stage durations and token usage of
+// each of its files
+type RunReport struct {
+	RunSummary
+	Files []runs.FileReport `json:"files"`
+}
+
+// RunChanges lists the documents an indexing run added or updated, with a
+// digest of their new content
+type RunChanges struct {
+	RunID      string        `json:"run_id"`
+	Directory  string        `json:"directory"`
+	Status     string        `json:"status"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Changes    []runs.Change `json:"changes"`
+	Digest     string        `json:"digest,omitempty"`
+}
+
+// RunResume reports a cancelled run accepted for resuming
+type RunResume struct {
+	Status    string `json:"status"`
+	RunID     string `json:"run_id"`
+	Remaining int    `json:"remaining"`
+}
+
+// IndexingStatus reports whether indexing is paused, which runs are in
+// progress, the state of the ingest queue and which stages are degraded
+type IndexingStatus struct {
+	Paused     bool       `json:"paused"`
+	ActiveRuns []string   `json:"active_runs"`
+	Queue      QueueStats `json:"queue"`
+	// DegradedStages lists the optional stages skipped while their
+	// dependency keeps failing
+	DegradedStages []string `json:"degraded_stages"`
+}
+
+// DeadLetterRetry selects dead-letter entries to queue again: the listed
+// IDs, or every entry when All is set. Priority defaults to normal.
+type DeadLetterRetry struct {
+	IDs      []string `json:"ids,omitempty"`
+	All      bool     `json:"all,omitempty"`
+	Priority string   `json:"priority,omitempty"`
+}
+
+// DeadLetterRetryResult reports the entries queued again and why others
+// were not
+type DeadLetterRetryResult struct {
+	Status   string            `json:"status"`
+	Queued   []string          `json:"queued"`
+	Rejected map[string]string `json:"rejected,omitempty"`
+}
+
+// QueueStats describes the ingest queue. Depth counts the files waiting at
+// each priority (high, normal, low) and OldestWait is the age of the oldest.
+// Shared is set when the orchestrator replicas share the queue, and Claimed
+// counts the files being processed by any of them.
+type QueueStats struct {
+	Depth      map[string]int    `json:"depth"`
+	OldestWait map[string]string `json:"oldest_wait,omitempty"`
+	Capacity   int               `json:"capacity"`
+	Workers    int               `json:"workers"`
+	InFlight   int64             `json:"in_flight"`
+	Completed  int64             `json:"completed"`
+	Skipped    int64             `json:"skipped"`
+	Failed     int64             `json:"failed"`
+	Shared     bool              `json:"shared,omitempty"`
+	Claimed    int               `json:"claimed,omitempty"`
+}
+
+// OrchestratorClient is the HTTP implementation of Orchestrator
+type OrchestratorClient struct {
+	*base
+}
+
+// NewOrchestratorClient creates an orchestrator client
+func NewOrchestratorClient(baseURL string, opts Options) *OrchestratorClient {
+	return &OrchestratorClient{base: newBase(baseURL, opts)}
+}
+
+// Documents lists the registry records matching the filter
+func (c *OrchestratorClient) Documents(ctx context.Context, filter DocumentFilter) ([]*registry.Record, error) {
+	query := url.Values{}
+	if filter.Category != "" {
+		query.Set("category", filter.Category)
+	}
+	if filter.State != "" {
+		query.Set("state", filter.State)
+	}
+	if filter.PathPrefix != "" {
+		query.Set("path", filter.PathPrefix)
+	}
+	if filter.NeedsEnrichment {
+		query.Set("needs_enrichment", "true")
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	path := "/api/v1/documents"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var result struct {
+		Documents []*registry.Record `json:"documents"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Documents, nil
+}
+
+// StaleDocuments lists indexed documents not modified for months, oldest
+// first; zero months uses the server default and zero limit lists all
+func (c *OrchestratorClient) StaleDocuments(ctx context.Context, months int, pathPrefix string, limit int) (*StaleReport, error) {
+	query := url.Values{}
+	if months > 0 {
+		query.Set("months", strconv.Itoa(months))
+	}
+	if pathPrefix != "" {
+		query.Set("path", pathPrefix)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	path := "/api/v1/documents/stale"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var result StaleReport
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Document returns the registry record of a document
+func (c *OrchestratorClient) Document(ctx context.Context, id string) (*registry.Record, error) {
+	var record registry.Record
+	if err := c.do(ctx, http.MethodGet, "/api/v1/documents/"+url.PathEscape(id), nil, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// DocumentLinks returns the resolved wiki-links of a note and its backlinks
+func (c *OrchestratorClient) DocumentLinks(ctx context.Context, id string) (*DocumentLinks, error) {
+	var links DocumentLinks
+	if err := c.do(ctx, http.MethodGet, "/api/v1/documents/"+url.PathEscape(id)+"/links", nil, &links); err != nil {
+		return nil, err
+	}
+	return &links, nil
+}
+
+// Reindex starts processing a document's file again
+func (c *OrchestratorClient) Reindex(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/documents/"+url.PathEscape(id)+"/reindex", nil, nil)
+}
+
+// Rechunk starts chunking and embedding documents again from their stored content
+func (c *OrchestratorClient) Rechunk(ctx context.Context, ids []string) (*RerunResult, error) {
+	return c.rerun(ctx, "rechunk", ids)
+}
+
+// Resummarize starts summarizing documents again from their stored content
+func (c *OrchestratorClient) Resummarize(ctx context.Context, ids []string) (*RerunResult, error) {
+	return c.rerun(ctx, "resummarize", ids)
+}
+
+func (c *OrchestratorClient) rerun(ctx context.Context, stage string, ids []string) (*RerunResult, error) {
+	var result RerunResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/documents/"+stage, map[string][]string{"document_ids": ids}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Collections lists all collections
+func (c *OrchestratorClient) Collections(ctx context.Context) ([]CollectionSummary, error) {
+	var result struct {
+		Collections []CollectionSummary `json:"collections"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/collections", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Collections, nil
+}
+
+// CreateCollection creates an empty collection
+func (c *OrchestratorClient) CreateCollection(ctx context.Context, name, description string) (*collections.Collection, error) {
+	var collection collections.Collection
+	body := map[string]string{"name": name, "description": description}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/collections", body, &collection); err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// Collection returns a collection with the file paths of its documents
+func (c *OrchestratorClient) Collection(ctx context.Context, name string) (*collections.Collection, error) {
+	var collection collections.Collection
+	if err := c.do(ctx, http.MethodGet, "/api/v1/collections/"+url.PathEscape(name), nil, &collection); err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// DeleteCollection removes a collection; its documents are not affected
+func (c *OrchestratorClient) DeleteCollection(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/collections/"+url.PathEscape(name), nil, nil)
+}
+
+// AddToCollection adds documents to a collection by registry ID
+func (c *OrchestratorClient) AddToCollection(ctx context.Context, name string, ids []string) (*CollectionUpdate, error) {
+	var result CollectionUpdate
+	path := "/api/v1/collections/" + url.PathEscape(name) + "/documents"
+	if err := c.do(ctx, http.MethodPost, path, map[string][]string{"document_ids": ids}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RemoveFromCollection removes a document from a collection
+func (c *OrchestratorClient) RemoveFromCollection(ctx context.Context, name, id string) (*collections.Collection, error) {
+	var collection collections.Collection
+	path := "/api/v1/collections/" + url.PathEscape(name) + "/documents/" + url.PathEscape(id)
+	if err := c.do(ctx, http.MethodDelete, path, nil, &collection); err != nil {
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// Digests lists the digest reports
+func (c *OrchestratorClient) Digests(ctx context.Context) ([]DigestReport, error) {
+	var result struct {
+		Reports []DigestReport `json:"reports"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/digests", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Reports, nil
+}
+
+// RunDigest generates a digest report now, delivering it unless preview is set
+func (c *OrchestratorClient) RunDigest(ctx context.Context, name string, preview bool) (*DigestRun, error) {
+	path := "/api/v1/digests/" + url.PathEscape(name) + "/run"
+	if preview {
+		path += "?preview=true"
+	}
+	var result DigestRun
+	if err := c.do(ctx, http.MethodPost, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Topics returns the last generated topic overview of the corpus
+func (c *OrchestratorClient) Topics(ctx context.Context) (*topics.Overview, error) {
+	var result topics.Overview
+	if err := c.do(ctx, http.MethodGet, "/api/v1/topics", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RunTopics generates the topic overview now
+func (c *OrchestratorClient) RunTopics(ctx context.Context) (*topics.Overview, error) {
+	var result topics.Overview
+	if err := c.do(ctx, http.MethodPost, "/api/v1/topics/run", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Duplicates reports documents with identical hashes or summary
+// embeddings at least threshold alike; threshold 0 uses the server default
+func (c *OrchestratorClient) Duplicates(ctx context.Context, threshold float64) (*orchestrator.DuplicateReport, error) {
+	path := "/api/v1/duplicates"
+	if threshold > 0 {
+		path += "?threshold=" + strconv.FormatFloat(threshold, 'f', -1, 64)
+	}
+	var result orchestrator.DuplicateReport
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// EventPage is a page of pipeline events
+type EventPage struct {
+	Events []events.Event `json:"events"`
+	Next   string         `json:"next"` // ID to read on from
+}
+
+// Events reads the pipeline events after a stream ID, oldest first, of
+// the given types or of any; an empty after reads from the oldest event
+// kept and limit 0 uses the service default
+func (c *OrchestratorClient) Events(ctx context.Context, after string, limit int, types ...string) (*EventPage, error) {
+	query := url.Values{}
+	if after != "" {
+		query.Set("after", after)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if len(types) > 0 {
+		query.Set("type", strings.Join(types, ","))
+	}
+	path := "/api/v1/events"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var result EventPage
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Runs lists recent indexing runs, newest first; limit 0 uses the
+// service default
+func (c *OrchestratorClient) Runs(ctx context.Context, limit int) ([]RunSummary, error) {
+	path := "/api/v1/runs"
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	var result struct {
+		Runs []RunSummary `json:"runs"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Runs, nil
+}
+
+// Run returns the report of an indexing run
+func (c *OrchestratorClient) Run(ctx context.Context, id string) (*RunReport, error) {
+	var result RunReport
+	if err := c.do(ctx, http.MethodGet, "/api/v1/runs/"+url.PathEscape(id), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// WaitRun returns the report of a running run once it finishes a file or
+// changes status, or after wait when it does neither; other runs are
+// returned at once. The client's timeout must be longer than wait.
+func (c *OrchestratorClient) WaitRun(ctx context.Context, id string, wait time.Duration) (*RunReport, error) {
+	var result RunReport
+	path := "/api/v1/runs/" + url.PathEscape(id) + "?wait=" + url.QueryEscape(wait.String())
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RunChanges returns the documents an indexing run added or updated
+func (c *OrchestratorClient) RunChanges(ctx context.Context, id string) (*RunChanges, error) {
+	var result RunChanges
+	if err := c.do(ctx, http.MethodGet, "/api/v1/runs/"+url.PathEscape(id)+"/changes", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CancelRun stops a run in progress after its current file
+func (c *OrchestratorClient) CancelRun(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/runs/"+url.PathEscape(id), nil, nil)
+}
+
+// ResumeRun continues a cancelled run in the background
+func (c *OrchestratorClient) ResumeRun(ctx context.Context, id string) (*RunResume, error) {
+	var result RunResume
+	if err := c.do(ctx, http.MethodPost, "/api/v1/runs/"+url.PathEscape(id)+"/resume", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ProcessBatch processes a list of files, as paths or file:// URLs, as one
+// run. Files that are skipped or invalid are reported rather than failing
+// the request.
+func (c *OrchestratorClient) ProcessBatch(ctx context.Context, items []string, force bool) (*BatchResult, error) {
+	var result BatchResult
+	body := map[string]interface{}{"items": items, "force_reprocess": force}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/process/batch", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Indexing reports whether indexing is paused and which runs are in progress
+func (c *OrchestratorClient) Indexing(ctx context.Context) (*IndexingStatus, error) {
+	return c.indexing(ctx, http.MethodGet, "/api/v1/indexing")
+}
+
+// PauseIndexing makes directory runs wait before their next file
+func (c *OrchestratorClient) PauseIndexing(ctx context.Context) (*IndexingStatus, error) {
+	return c.indexing(ctx, http.MethodPost, "/api/v1/indexing/pause")
+}
+
+// ResumeIndexing lets paused directory runs continue
+func (c *OrchestratorClient) ResumeIndexing(ctx context.Context) (*IndexingStatus, error) {
+	return c.indexing(ctx, http.MethodPost, "/api/v1/indexing/resume")
+}
+
+func (c *OrchestratorClient) indexing(ctx context.Context, method, path string) (*IndexingStatus, error) {
+	var status IndexingStatus
+	if err := c.do(ctx, method, path, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// DeadLetters lists the files that failed every retry, most recent failure
+// first; limit 0 returns all
+func (c *OrchestratorClient) DeadLetters(ctx context.Context, limit int) ([]*dlq.Entry, error) {
+	path := "/api/v1/dlq"
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	var result struct {
+		Entries []*dlq.Entry `json:"entries"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Entries, nil
+}
+
+// RetryDeadLetters queues dead-lettered files for processing again
+func (c *OrchestratorClient) RetryDeadLetters(ctx context.Context, req DeadLetterRetry) (*DeadLetterRetryResult, error) {
+	var result DeadLetterRetryResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/dlq/retry", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DiscardDeadLetter removes a dead-letter entry without retrying its file
+func (c *OrchestratorClient) DiscardDeadLetter(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/dlq/"+url.PathEscape(id), nil, nil)
+}
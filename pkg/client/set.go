@@ -0,0 +1,25 @@
+package client
+
+import "github.com/nadeeshame/rag-knowledge-service/internal/config"
+
+// Set holds a client for each internal service
+type Set struct {
+	Scanner      Scanner
+	Extractor    Extractor
+	Embedder     Embedder
+	VectorStore  VectorStore
+	Query        Querier
+	Orchestrator Orchestrator
+}
+
+// NewSet creates clients for the service URLs in the configuration
+func NewSet(services config.ServicesConfig, opts Options) *Set {
+	return &Set{
+		Scanner:      NewScannerClient(services.DocumentScannerURL, opts),
+		Extractor:    NewExtractorClient(services.ContentExtractorURL, opts),
+		Embedder:     NewEmbedderClient(services.EmbeddingServiceURL, opts),
+		VectorStore:  NewVectorStoreClient(services.VectorStoreServiceURL, opts),
+		Query:        NewQueryClient(services.QueryServiceURL, opts),
+		Orchestrator: NewOrchestratorClient(services.OrchestratorServiceURL, opts),
+	}
+}
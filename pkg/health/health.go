@@ -3,8 +3,12 @@ package health
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -16,78 +20,243 @@ type Status struct {
 	Services  map[string]bool   `json:"services"`
 	Timestamp time.Time         `json:"timestamp"`
 	Details   map[string]string `json:"details,omitempty"`
+	Checks    map[string]Check  `json:"checks,omitempty"`
 }
 
-// Checker provides health checking functionality
+// Check is the result of probing one service
+type Check struct {
+	Healthy   bool      `json:"healthy"`
+	LatencyMS float64   `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	Cached    bool      `json:"cached"` // reused from an earlier probe within the cache TTL
+	Error     string    `json:"error,omitempty"`
+}
+
+// DefaultCacheTTL is how long probe results are reused before the
+// dependency is probed again
+const DefaultCacheTTL = 5 * time.Second
+
+// Checker provides health checking functionality. Probes run concurrently
+// and their results are cached for a short TTL, so frequent health checks
+// do not put load on the dependencies.
 type Checker struct {
 	azureEndpoint   string
 	pineconeAPIKey  string
 	googleVisionKey string
 	db              *sql.DB
 	redisClient     *redis.Client
+	ttl             time.Duration
+	probes          []probe
+	cache           map[string]*cachedResult
+}
+
+// probe checks one dependency
+type probe struct {
+	name    string
+	failure string // detail reported when the check fails
+	check   func(ctx context.Context) bool
+}
+
+// cachedResult is the last result of a probe. Its lock is held while the
+// probe runs, so concurrent checks share a single probe.
+type cachedResult struct {
+	mu      sync.Mutex
+	check   Check
+	latency time.Duration
 }
 
 // NewChecker creates a new health checker
 func NewChecker(azureEndpoint, pineconeAPIKey, googleVisionKey string, db *sql.DB, redisClient *redis.Client) *Checker {
-	return &Checker{
+	c := &Checker{
 		azureEndpoint:   azureEndpoint,
 		pineconeAPIKey:  pineconeAPIKey,
 		googleVisionKey: googleVisionKey,
 		db:              db,
 		redisClient:     redisClient,
+		ttl:             DefaultCacheTTL,
 	}
+	c.probes = []probe{
+		{name: "azure_openai", failure: "Unable to reach Azure OpenAI endpoint", check: c.checkAzureOpenAI},
+		{name: "pinecone", failure: "Unable to verify Pinecone connection", check: c.checkPinecone},
+		{name: "google_vision", failure: "Unable to verify Google Vision API", check: c.checkGoogleVision},
+		{name: "database", failure: "Unable to connect to database", check: c.checkDatabase},
+		{name: "redis", failure: "Unable to connect to Redis", check: c.checkRedis},
+	}
+	c.cache = make(map[string]*cachedResult, len(c.probes))
+	for _, p := range c.probes {
+		c.cache[p.name] = &cachedResult{}
+	}
+	return c
+}
+
+// SetCacheTTL sets how long probe results are reused; zero probes on
+// every check. It must be called before the checker is used.
+func (c *Checker) SetCacheTTL(ttl time.Duration) {
+	c.ttl = ttl
 }
 
-// CheckAll checks the health of all services
+// CheckAll checks the health of all services concurrently. Details hold
+// the failure of each unhealthy service and the latency of every probe,
+// keyed by "<service>_latency"; Checks hold the result of every probe.
 func (c *Checker) CheckAll(ctx context.Context) *Status {
-	services := make(map[string]bool)
-	details := make(map[string]string)
+	checks := make([]Check, len(c.probes))
+	latencies := make([]time.Duration, len(c.probes))
+	var wg sync.WaitGroup
+	for i, p := range c.probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i], latencies[i] = c.run(ctx, p, false)
+		}()
+	}
+	wg.Wait()
 
-	// Check Azure OpenAI
-	services["azure_openai"] = c.checkAzureOpenAI(ctx)
-	if !services["azure_openai"] {
-		details["azure_openai"] = "Unable to reach Azure OpenAI endpoint"
+	status := &Status{
+		Healthy:   true,
+		Services:  make(map[string]bool, len(c.probes)),
+		Timestamp: time.Now(),
+		Details:   make(map[string]string, 2*len(c.probes)),
+		Checks:    make(map[string]Check, len(c.probes)),
 	}
+	for i, p := range c.probes {
+		status.Services[p.name] = checks[i].Healthy
+		status.Details[p.name+"_latency"] = latencies[i].Round(time.Microsecond).String()
+		status.Checks[p.name] = checks[i]
+		if !checks[i].Healthy {
+			status.Details[p.name] = p.failure
+			status.Healthy = false
+		}
+	}
+	return status
+}
 
-	// Check Pinecone
-	services["pinecone"] = c.checkPinecone(ctx)
-	if !services["pinecone"] {
-		details["pinecone"] = "Unable to verify Pinecone connection"
+// run returns the result of a probe, probing again when the cached result
+// is older than the TTL or fresh is set. The latency is that of the probe that produced
+// the result. Probes outlive a cancelled caller, as other callers may be
+// waiting for the same result; each probe has its own timeout.
+func (c *Checker) run(ctx context.Context, p probe, fresh bool) (Check, time.Duration) {
+	cached := c.cache[p.name]
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	if !fresh && !cached.check.CheckedAt.IsZero() && time.Since(cached.check.CheckedAt) < c.ttl {
+		result := cached.check
+		result.Cached = true
+		return result, cached.latency
+	}
+	start := time.Now()
+	healthy := p.check(context.WithoutCancel(ctx))
+	cached.latency = time.Since(start)
+	cached.check = Check{
+		Healthy:   healthy,
+		LatencyMS: float64(cached.latency.Microseconds()) / 1000,
+		CheckedAt: time.Now(),
+	}
+	if !healthy {
+		cached.check.Error = p.failure
 	}
+	return cached.check, cached.latency
+}
 
-	// Check Google Vision
-	services["google_vision"] = c.checkGoogleVision(ctx)
-	if !services["google_vision"] {
-		details["google_vision"] = "Unable to verify Google Vision API"
+// AddProbe adds a probe for a service, replacing any probe with the same
+// name. The check reports whether the service is healthy and should apply
+// its own timeout. It must be called before the checker is used.
+func (c *Checker) AddProbe(name, failure string, check func(ctx context.Context) bool) {
+	p := probe{name: name, failure: failure, check: check}
+	c.cache[name] = &cachedResult{}
+	for i := range c.probes {
+		if c.probes[i].name == name {
+			c.probes[i] = p
+			return
+		}
 	}
+	c.probes = append(c.probes, p)
+}
 
-	// Check Database
-	services["database"] = c.checkDatabase(ctx)
-	if !services["database"] {
-		details["database"] = "Unable to connect to database"
+// HTTPProbe returns a check that is healthy when a GET of the URL answers
+// with a 2xx status within 5 seconds
+func HTTPProbe(client *http.Client, url string) func(ctx context.Context) bool {
+	if client == nil {
+		client = http.DefaultClient
 	}
+	return func(ctx context.Context) bool {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
 
-	// Check Redis
-	services["redis"] = c.checkRedis(ctx)
-	if !services["redis"] {
-		details["redis"] = "Unable to connect to Redis"
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return false
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode >= 200 && resp.StatusCode < 300
 	}
+}
 
-	// Overall health is true if all services are healthy
-	healthy := true
-	for _, status := range services {
-		if !status {
-			healthy = false
-			break
+// WaitFor probes the named services until all of them are healthy,
+// backing off from one second up to ten between attempts. It returns an
+// error naming the services still unhealthy when the timeout elapses or
+// the context is cancelled. Waits always probe, ignoring cached results.
+func (c *Checker) WaitFor(ctx context.Context, timeout time.Duration, services ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pending := make([]probe, 0, len(services))
+	for _, name := range services {
+		p, ok := c.probe(name)
+		if !ok {
+			return fmt.Errorf("no health probe for %s", name)
 		}
+		pending = append(pending, p)
 	}
 
-	return &Status{
-		Healthy:   healthy,
-		Services:  services,
-		Timestamp: time.Now(),
-		Details:   details,
+	backoff := time.Second
+	for {
+		unhealthy := pending[:0]
+		for _, p := range pending {
+			if result, _ := c.run(ctx, p, true); !result.Healthy {
+				unhealthy = append(unhealthy, p)
+			}
+		}
+		pending = unhealthy
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			names := make([]string, len(pending))
+			for i, p := range pending {
+				names[i] = p.name
+			}
+			return fmt.Errorf("services not ready after %s: %s", timeout, strings.Join(names, ", "))
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 10*time.Second)
+	}
+}
+
+// probe returns the probe with the given name
+func (c *Checker) probe(name string) (probe, bool) {
+	for _, p := range c.probes {
+		if p.name == name {
+			return p, true
+		}
 	}
+	return probe{}, false
+}
+
+// runNamed runs the probe with the given name
+func (c *Checker) runNamed(ctx context.Context, name string) bool {
+	p, ok := c.probe(name)
+	if !ok {
+		return false
+	}
+	result, _ := c.run(ctx, p, false)
+	return result.Healthy
 }
 
 func (c *Checker) checkAzureOpenAI(ctx context.Context) bool {
@@ -111,8 +280,9 @@ func (c *Checker) checkAzureOpenAI(ctx context.Context) bool {
 	}
 	defer resp.Body.Close()
 
-	// Azure OpenAI may return 401 if no auth, but the endpoint is reachable
-	return resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusOK
+	// Without auth or a deployment path Azure OpenAI answers 401 or 404,
+	// but any answer short of a server error means the endpoint is reachable
+	return resp.StatusCode < http.StatusInternalServerError
 }
 
 func (c *Checker) checkPinecone(_ context.Context) bool {
@@ -157,33 +327,48 @@ func (c *Checker) checkRedis(ctx context.Context) bool {
 
 // CheckAzureOpenAI checks Azure OpenAI service
 func (c *Checker) CheckAzureOpenAI(ctx context.Context) bool {
-	return c.checkAzureOpenAI(ctx)
+	return c.runNamed(ctx, "azure_openai")
 }
 
 // CheckPinecone checks Pinecone service
 func (c *Checker) CheckPinecone(ctx context.Context) bool {
-	return c.checkPinecone(ctx)
+	return c.runNamed(ctx, "pinecone")
 }
 
 // CheckGoogleVision checks Google Vision service
 func (c *Checker) CheckGoogleVision(ctx context.Context) bool {
-	return c.checkGoogleVision(ctx)
+	return c.runNamed(ctx, "google_vision")
 }
 
 // CheckDatabase checks database connection
 func (c *Checker) CheckDatabase(ctx context.Context) bool {
-	return c.checkDatabase(ctx)
+	return c.runNamed(ctx, "database")
 }
 
 // CheckRedis checks Redis connection
 func (c *Checker) CheckRedis(ctx context.Context) bool {
-	return c.checkRedis(ctx)
+	return c.runNamed(ctx, "redis")
 }
 
-// HTTPHandler returns an HTTP handler for health checks
+// HTTPHandler returns an HTTP handler for health checks. The response is
+// the status without the per-service checks unless ?verbose=true is given.
 func (c *Checker) HTTPHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		verbose := false
+		if value := r.URL.Query().Get("verbose"); value != "" {
+			var err error
+			if verbose, err = strconv.ParseBool(value); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "verbose must be true or false"}) //nolint:errcheck
+				return
+			}
+		}
+
 		status := c.CheckAll(r.Context())
+		if !verbose {
+			status.Checks = nil
+		}
 
 		statusCode := http.StatusOK
 		if !status.Healthy {
@@ -192,25 +377,6 @@ func (c *Checker) HTTPHandler() http.HandlerFunc {
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(statusCode)
-
-		// Write JSON response
-		fmt.Fprintf(w, `{"healthy":%v,"timestamp":"%s","services":%v}`,
-			status.Healthy,
-			status.Timestamp.Format(time.RFC3339),
-			marshalServices(status.Services))
-	}
-}
-
-func marshalServices(services map[string]bool) string {
-	result := "{"
-	first := true
-	for k, v := range services {
-		if !first {
-			result += ","
-		}
-		result += fmt.Sprintf(`"%s":%v`, k, v)
-		first = false
+		_ = json.NewEncoder(w).Encode(status) //nolint:errcheck
 	}
-	result += "}"
-	return result
 }
@@ -0,0 +1,44 @@
+package sdk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/transport"
+)
+
+// Document returns an indexed document with its summary and error
+func (c *Client) Document(ctx context.Context, id string) (*Document, error) {
+	var doc Document
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/v1/documents/" + url.PathEscape(id), out: &doc}); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// DocumentContent opens the extracted text of a document, which the caller
+// must close. Documents whose text is not stored are not found.
+func (c *Client) DocumentContent(ctx context.Context, id string) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, request{
+		method: http.MethodGet, path: "/v1/documents/" + url.PathEscape(id) + "/content", accept: "text/plain",
+	}, c.opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &content{resp: resp}, nil
+}
+
+// content is the body of a document's text, ending its request when closed
+type content struct {
+	resp *transport.Response
+}
+
+func (c *content) Read(p []byte) (int, error) {
+	return c.resp.Body.Read(p)
+}
+
+func (c *content) Close() error {
+	return c.resp.Close()
+}
@@ -0,0 +1,145 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrNoRun is returned by IngestBatch when no item of a batch was
+// accepted, alongside the response giving each item's reason
+var ErrNoRun = errors.New("no item of the batch was accepted")
+
+// IngestDocument queues a single file for processing
+func (c *Client) IngestDocument(ctx context.Context, req IngestDocumentRequest) (*IngestResponse, error) {
+	var resp IngestResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/v1/ingest/document", body: req, out: &resp})
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// IngestDirectory queues the files of a directory for processing
+func (c *Client) IngestDirectory(ctx context.Context, req IngestDirectoryRequest) (*IngestResponse, error) {
+	var resp IngestResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/v1/ingest/directory", body: req, out: &resp})
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// IngestBatch processes a list of files, given as local paths or file://
+// URLs, as one run followed with Run and WaitRun. Items that are skipped
+// or invalid do not fail the call; when none is accepted it returns the
+// response with ErrNoRun.
+func (c *Client) IngestBatch(ctx context.Context, items []string, force bool) (*BatchResponse, error) {
+	body := struct {
+		Items          []string `json:"items"`
+		ForceReprocess bool     `json:"force_reprocess,omitempty"`
+	}{Items: items, ForceReprocess: force}
+	var resp BatchResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/v1/ingest/batch", body: body, out: &resp})
+	if err != nil {
+		return nil, err
+	}
+	if resp.RunID == "" {
+		return &resp, ErrNoRun
+	}
+	return &resp, nil
+}
+
+// Status returns the processing status of a document. With a wait, the API
+// holds the call until the state changes, for up to wait or MaxWait,
+// unless the document is already indexed or failed.
+func (c *Client) Status(ctx context.Context, documentID string, wait time.Duration) (*DocumentStatus, error) {
+	query, wait := waitQuery(wait)
+	var status DocumentStatus
+	err := c.do(ctx, request{
+		method: http.MethodGet, path: "/v1/ingest/status/" + url.PathEscape(documentID),
+		query: query, out: &status, wait: wait,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// AwaitDocument waits until a document is indexed or failed, or ctx is
+// done, and returns its last status
+func (c *Client) AwaitDocument(ctx context.Context, documentID string) (*DocumentStatus, error) {
+	status, err := c.Status(ctx, documentID, 0)
+	for err == nil && !status.Done() {
+		status, err = c.Status(ctx, documentID, MaxWait)
+	}
+	return status, err
+}
+
+// Runs lists recent indexing runs, newest first; limit zero means the
+// API's default of 20
+func (c *Client) Runs(ctx context.Context, limit int) ([]RunSummary, error) {
+	var query url.Values
+	if limit > 0 {
+		query = url.Values{"limit": {strconv.Itoa(limit)}}
+	}
+	var resp struct {
+		Runs []RunSummary `json:"runs"`
+	}
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/v1/ingest/runs", query: query, out: &resp}); err != nil {
+		return nil, err
+	}
+	return resp.Runs, nil
+}
+
+// Run returns the report of an indexing run. With a wait, the API holds
+// the call while the run is running until it finishes a file or changes
+// status, for up to wait or MaxWait.
+func (c *Client) Run(ctx context.Context, id string, wait time.Duration) (*Run, error) {
+	query, wait := waitQuery(wait)
+	var run Run
+	err := c.do(ctx, request{
+		method: http.MethodGet, path: "/v1/ingest/runs/" + url.PathEscape(id),
+		query: query, out: &run, wait: wait,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// AwaitRun waits until a run completes or is cancelled, or ctx is done,
+// calling progress, when set, with each report while it runs. It returns
+// the last report.
+func (c *Client) AwaitRun(ctx context.Context, id string, progress func(*Run)) (*Run, error) {
+	run, err := c.Run(ctx, id, 0)
+	for err == nil && !run.Done() {
+		if progress != nil {
+			progress(run)
+		}
+		run, err = c.Run(ctx, id, MaxWait)
+	}
+	return run, err
+}
+
+// CancelRun stops a running run after its current file, keeping the files
+// left so it can be resumed
+func (c *Client) CancelRun(ctx context.Context, id string) (*RunAccepted, error) {
+	var resp RunAccepted
+	if err := c.do(ctx, request{method: http.MethodDelete, path: "/v1/ingest/runs/" + url.PathEscape(id), out: &resp}); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ResumeRun processes the files a cancelled run did not reach
+func (c *Client) ResumeRun(ctx context.Context, id string) (*RunAccepted, error) {
+	var resp RunAccepted
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/v1/ingest/runs/" + url.PathEscape(id) + "/resume", out: &resp}); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,326 @@
+package sdk
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Priority is the ingest queue priority of a request
+type Priority string
+
+// Priorities
+const (
+	PriorityHigh   Priority = "high"   // interactive uploads
+	PriorityNormal Priority = "normal" // the default
+	PriorityLow    Priority = "low"    // bulk backfills
+)
+
+// IngestDocumentRequest queues a single file
+type IngestDocumentRequest struct {
+	FilePath       string   `json:"file_path"`
+	ForceReprocess bool     `json:"force_reprocess,omitempty"`
+	Priority       Priority `json:"priority,omitempty"`
+}
+
+// IngestDirectoryRequest queues the files of a directory
+type IngestDirectoryRequest struct {
+	Directory      string   `json:"directory"`
+	Recursive      bool     `json:"recursive,omitempty"`
+	ForceReprocess bool     `json:"force_reprocess,omitempty"`
+	Priority       Priority `json:"priority,omitempty"`
+	// Incremental queues only the files added or modified since the last
+	// incremental request for the directory
+	Incremental bool `json:"incremental,omitempty"`
+}
+
+// IngestResponse is the answer to a queued document or directory
+type IngestResponse struct {
+	Status   string   `json:"status"`
+	Priority Priority `json:"priority"`
+	Queued   int      `json:"queued"`
+	JobID    string   `json:"job_id,omitempty"` // set when a single file is queued
+	// Deleted lists the files an incremental request found removed
+	Deleted []string `json:"deleted,omitempty"`
+}
+
+// Batch item statuses
+const (
+	BatchAccepted = "accepted" // processed by the batch's run
+	BatchSkipped  = "skipped"  // repeated in the batch, or indexed and unchanged
+	BatchInvalid  = "invalid"  // not a readable file
+)
+
+// BatchItem is the status of one item of a batch
+type BatchItem struct {
+	Item     string `json:"item"`
+	FilePath string `json:"file_path,omitempty"`
+	Status   string `json:"status"`
+	Reason   string `json:"reason,omitempty"` // why the item was skipped or is invalid
+}
+
+// BatchResponse is the answer to a batch; RunID is empty when no item was
+// accepted
+type BatchResponse struct {
+	Status   string      `json:"status"`
+	RunID    string      `json:"run_id,omitempty"`
+	Accepted int         `json:"accepted"`
+	Skipped  int         `json:"skipped"`
+	Invalid  int         `json:"invalid"`
+	Items    []BatchItem `json:"items"` // in request order
+}
+
+// DocumentState is the processing state of a document
+type DocumentState string
+
+// Document states, in processing order
+const (
+	StateScanned    DocumentState = "SCANNED"
+	StateExtracted  DocumentState = "EXTRACTED"
+	StateAnalyzed   DocumentState = "ANALYZED"
+	StateSummarized DocumentState = "SUMMARIZED"
+	StateChunked    DocumentState = "CHUNKED"
+	StateEmbedded   DocumentState = "EMBEDDED"
+	StateIndexed    DocumentState = "INDEXED"
+	StateFailed     DocumentState = "FAILED"
+)
+
+// DocumentStatus is the processing status of a document
+type DocumentStatus struct {
+	DocumentID string        `json:"document_id"`
+	Status     DocumentState `json:"status"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// Done reports whether the document was indexed or failed
+func (s *DocumentStatus) Done() bool {
+	return s.Status == StateIndexed || s.Status == StateFailed
+}
+
+// Run statuses
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunCancelled = "cancelled" // stopped early; it can be resumed
+)
+
+// TokenUsage counts the model tokens used
+type TokenUsage struct {
+	Prompt     int64 `json:"prompt"`
+	Completion int64 `json:"completion"`
+}
+
+// RunSummary is an indexing run of a directory or batch, without its files
+type RunSummary struct {
+	ID         string    `json:"id"`
+	Directory  string    `json:"directory"`
+	Batch      bool      `json:"batch,omitempty"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Total      int       `json:"total_files"`
+	Processed  int       `json:"processed"`
+	Skipped    int       `json:"skipped"`
+	Failed     int       `json:"failed"`
+	Added      int       `json:"added"`
+	Updated    int       `json:"updated"`
+	Remaining  int       `json:"remaining,omitempty"` // files a cancelled run did not reach
+	// DurationMs is the run's wall time; StageMs the time its files spent
+	// in each stage
+	DurationMs int64            `json:"duration_ms"`
+	StageMs    map[string]int64 `json:"stage_ms"`
+	Tokens     TokenUsage       `json:"tokens"`
+}
+
+// Done reports whether the run completed or was cancelled
+func (r *RunSummary) Done() bool {
+	return r.Status != RunRunning
+}
+
+// RunFile is the outcome of one file of a run
+type RunFile struct {
+	FilePath   string           `json:"file_path"`
+	DocumentID string           `json:"document_id,omitempty"`
+	Outcome    string           `json:"outcome"`
+	Error      string           `json:"error,omitempty"` // failure, or why the file was skipped
+	Attempts   int              `json:"attempts"`
+	DurationMs int64            `json:"duration_ms"`
+	StageMs    map[string]int64 `json:"stage_ms,omitempty"`
+	Tokens     TokenUsage       `json:"tokens"`
+}
+
+// Run is the report of an indexing run with the outcome of each file it
+// reached
+type Run struct {
+	RunSummary
+	Files []RunFile `json:"files"`
+}
+
+// RunAccepted is the answer to cancelling or resuming a run
+type RunAccepted struct {
+	Status    string `json:"status"`
+	RunID     string `json:"run_id"`
+	Remaining int    `json:"remaining,omitempty"`
+}
+
+// Retrieval modes
+const (
+	ModeChunks   = "chunks"    // search all chunks directly
+	ModeTwoStage = "two_stage" // find documents by summary, then search their chunks
+	ModeAgent    = "agent"     // break the question into sub-questions; not streamed
+)
+
+// QueryFilter narrows the chunks a query searches
+type QueryFilter struct {
+	FileType   string            `json:"file_type,omitempty"`
+	DateFrom   *time.Time        `json:"date_from,omitempty"`
+	DateTo     *time.Time        `json:"date_to,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Collection string            `json:"collection,omitempty"` // only documents in this collection
+	Tag        string            `json:"tag,omitempty"`        // only notes with this frontmatter tag
+}
+
+// QueryRequest is a question, or the text of a search. Unset fields take
+// the service's defaults.
+type QueryRequest struct {
+	Text      string       `json:"text"`
+	TopK      int          `json:"top_k,omitempty"`
+	Namespace string       `json:"namespace,omitempty"`
+	Filter    *QueryFilter `json:"filter,omitempty"`
+	// AsOf answers from the index as it was then, as YYYY-MM-DD or an
+	// RFC 3339 timestamp
+	AsOf    string `json:"as_of,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	Profile string `json:"profile,omitempty"` // retrieval profile giving the defaults of unset fields
+	// MinScore drops matches scoring below it
+	MinScore *float64 `json:"min_score,omitempty"`
+	// ResponseFormat is json_schema for an answer conforming to Schema,
+	// returned in QueryResult.Data; such answers are not streamed
+	ResponseFormat string          `json:"response_format,omitempty"`
+	Schema         json.RawMessage `json:"schema,omitempty"`
+}
+
+// SearchResult is a chunk matching a query
+type SearchResult struct {
+	DocumentID string            `json:"document_id"`
+	ChunkID    string            `json:"chunk_id"`
+	Score      float32           `json:"score"`
+	Content    string            `json:"content"`
+	FileName   string            `json:"file_name"`
+	FilePath   string            `json:"file_path"`
+	FileType   string            `json:"file_type"`
+	Metadata   map[string]string `json:"metadata"`
+}
+
+// SafetyCategory is a category of harmful content, with its severity from
+// 0 (safe) to 7
+type SafetyCategory struct {
+	Category string `json:"category"`
+	Severity int    `json:"severity"`
+}
+
+// ModerationVerdict is the safety classification of a query or answer
+type ModerationVerdict struct {
+	Subject    string           `json:"subject"` // query or answer
+	Action     string           `json:"action"`  // block, warn or log
+	Categories []SafetyCategory `json:"categories"`
+}
+
+// InjectionFinding is a source whose text reads like instructions to the
+// model
+type InjectionFinding struct {
+	Source    int      `json:"source"` // position in the sources, from 1
+	FilePath  string   `json:"file_path"`
+	Score     float64  `json:"score"`
+	Patterns  []string `json:"patterns"`
+	Sanitized bool     `json:"sanitized,omitempty"`
+}
+
+// CitationCheck is the verification of an answer sentence against the
+// sources it cites
+type CitationCheck struct {
+	Sentence  string  `json:"sentence"`
+	Sources   []int   `json:"sources"` // cited source numbers, from 1
+	Supported bool    `json:"supported"`
+	Overlap   float64 `json:"overlap"`
+	Stripped  bool    `json:"stripped,omitempty"` // removed from the answer
+}
+
+// QueryTrace records how the sources of an answer were given to the model
+type QueryTrace struct {
+	InjectionScore float64             `json:"injection_score"`
+	Injections     []InjectionFinding  `json:"injections,omitempty"`
+	Moderation     []ModerationVerdict `json:"moderation,omitempty"`
+	Citations      []CitationCheck     `json:"citations,omitempty"`
+}
+
+// QueryResult is an answer with the sources it was given
+type QueryResult struct {
+	QueryID string         `json:"query_id"`
+	Answer  string         `json:"answer"`
+	Sources []SearchResult `json:"sources"`
+	AsOf    *time.Time     `json:"as_of,omitempty"`
+	Trace   *QueryTrace    `json:"trace,omitempty"`
+	// Faithfulness is the share of cited sentences their sources support,
+	// when citations are verified
+	Faithfulness *float64        `json:"faithfulness,omitempty"`
+	Data         json.RawMessage `json:"data,omitempty"` // the answer of a json_schema query
+	Timestamp    time.Time       `json:"timestamp"`
+}
+
+// SearchResponse is the chunks matching a search
+type SearchResponse struct {
+	QueryID    string              `json:"query_id"`
+	Results    []SearchResult      `json:"results"`
+	Total      int                 `json:"total"`
+	Moderation []ModerationVerdict `json:"moderation,omitempty"`
+}
+
+// Ratings of an answer
+const (
+	RatingUp   = "up"
+	RatingDown = "down"
+)
+
+// FeedbackRequest rates an answer by its query ID
+type FeedbackRequest struct {
+	QueryID string `json:"query_id"`
+	Rating  string `json:"rating"` // up or down
+	Comment string `json:"comment,omitempty"`
+	// Query is the question asked, kept so poorly rated queries can be
+	// evaluated again
+	Query string `json:"query,omitempty"`
+}
+
+// Feedback is a stored rating
+type Feedback struct {
+	QueryID   string    `json:"query_id"`
+	Rating    string    `json:"rating"`
+	Comment   string    `json:"comment,omitempty"`
+	Query     string    `json:"query,omitempty"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Document is an indexed document with its processing state
+type Document struct {
+	ID            string                 `json:"id"`
+	FilePath      string                 `json:"file_path"`
+	FileName      string                 `json:"file_name"`
+	FileType      string                 `json:"file_type"`
+	Category      string                 `json:"category"`
+	ContentType   string                 `json:"content_type,omitempty"`
+	Title         string                 `json:"title,omitempty"` // frontmatter of Markdown notes
+	Tags          []string               `json:"tags,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	State         DocumentState          `json:"state"`
+	ChunkCount    int                    `json:"chunk_count"`
+	Summary       string                 `json:"summary,omitempty"`
+	ContentStored bool                   `json:"content_stored,omitempty"` // the extracted text can be read with DocumentContent
+	Error         string                 `json:"error,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	IndexedAt     *time.Time             `json:"indexed_at,omitempty"`
+	ModifiedAt    *time.Time             `json:"modified_at,omitempty"` // modification time of the file when it was read
+}
@@ -0,0 +1,35 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+)
+
+// Query answers a question using the indexed documents as context
+func (c *Client) Query(ctx context.Context, req QueryRequest) (*QueryResult, error) {
+	var result QueryResult
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/v1/query", body: req, out: &result}); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Search returns the document chunks most relevant to a text, without an
+// answer
+func (c *Client) Search(ctx context.Context, req QueryRequest) (*SearchResponse, error) {
+	var resp SearchResponse
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/v1/query/search", body: req, out: &resp}); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Feedback rates an answer up or down, replacing the caller's earlier
+// rating of it
+func (c *Client) Feedback(ctx context.Context, req FeedbackRequest) (*Feedback, error) {
+	var fb Feedback
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/v1/feedback", body: req, out: &fb}); err != nil {
+		return nil, err
+	}
+	return &fb, nil
+}
@@ -0,0 +1,266 @@
+// Package sdk is the Go SDK of the RepoGraph public API. It calls the API
+// gateway's /v1 endpoints to ingest documents, follow their processing,
+// ask questions and stream answers, so Go services can embed RepoGraph
+// without writing HTTP code of their own.
+//
+// The SDK depends on no other package of this module but the transport it
+// shares with the service clients: its models are declared here and mirror
+// the JSON the gateway serves. Every call takes a context, and failed calls
+// are retried with backoff where it is safe.
+//
+//	client := sdk.New("http://localhost:8080", sdk.Options{APIKey: os.Getenv("REPOGRAPH_API_KEY")})
+//	result, err := client.Query(ctx, sdk.QueryRequest{Text: "How is auth handled?"})
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nadeeshame/rag-knowledge-service/internal/transport"
+)
+
+// Version is the version of the SDK, sent in its User-Agent
+const Version = "1.0.0"
+
+// Header names of the gateway API
+const (
+	HeaderAPIKey         = "X-API-Key"
+	HeaderRequestID      = "X-Request-ID"
+	HeaderUserID         = "X-User-ID"
+	HeaderUserGroups     = "X-User-Groups"
+	HeaderTenant         = "X-Tenant-ID"
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
+// MaxWait caps the time a status call waits for a change
+const MaxWait = 60 * time.Second
+
+// Options configures a Client
+type Options struct {
+	// APIKey is sent in the X-API-Key header when set
+	APIKey string
+	// Timeout bounds each attempt of a call, not counting the time a
+	// status call waits; zero means 30 seconds. Streams are bounded by
+	// their context alone.
+	Timeout time.Duration
+	// MaxRetries is the number of retries after the first attempt;
+	// negative disables retries, zero means 2
+	MaxRetries int
+	// RetryBackoff is the base delay between retries, doubled per attempt;
+	// zero means 250 milliseconds
+	RetryBackoff time.Duration
+	// UserAgent identifies the calling service; it is sent before the
+	// SDK's own
+	UserAgent string
+	// HTTPClient overrides the underlying HTTP client
+	HTTPClient *http.Client
+}
+
+// Client calls the RepoGraph public API. It is safe for concurrent use.
+type Client struct {
+	transport *transport.Client
+	opts      Options
+}
+
+// New creates a client of the gateway at baseURL, such as
+// http://localhost:8080
+func New(baseURL string, opts Options) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	switch {
+	case opts.MaxRetries < 0:
+		opts.MaxRetries = 0
+	case opts.MaxRetries == 0:
+		opts.MaxRetries = 2
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 250 * time.Millisecond
+	}
+	userAgent := "repograph-sdk-go/" + Version
+	if opts.UserAgent != "" {
+		userAgent = opts.UserAgent + " " + userAgent
+	}
+	opts.UserAgent = userAgent
+	return &Client{
+		transport: transport.New(baseURL, opts.HTTPClient, opts.MaxRetries, opts.RetryBackoff),
+		opts:      opts,
+	}
+}
+
+// APIError is returned when the API responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+	RequestID  string // X-Request-ID of the failed request, for support
+	// RetryAfter is the delay the API asked for before trying again, on
+	// 429 and 503 responses
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("repograph API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the API
+func IsNotFound(err error) bool {
+	return statusOf(err) == http.StatusNotFound
+}
+
+// IsRateLimited reports whether err is a 429 from the API, returned once
+// the retries are used up
+func IsRateLimited(err error) bool {
+	return statusOf(err) == http.StatusTooManyRequests
+}
+
+func statusOf(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// Identity is the end user a call is made for. Documents are filtered by
+// their access control for that user, and quotas are counted per tenant.
+type Identity struct {
+	UserID string
+	Groups []string
+	Tenant string
+}
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	identityKey
+	idempotencyKey
+)
+
+// WithRequestID attaches the request ID sent with calls made with ctx, to
+// trace them through the services; calls without one get a new ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// WithIdentity attaches the end user calls made with ctx are made for
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// WithIdempotencyKey sets the Idempotency-Key of changes made with ctx.
+// Without one, each call that changes something sends a new key, kept
+// across its retries so a retried call is not applied twice; set the key
+// to make a call the caller repeats itself safe as well.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey, key)
+}
+
+// Health checks that the gateway is up
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodGet, path: "/health"})
+}
+
+// request is one call of the API
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+	out    interface{}
+	// accept is the media type asked for; empty means JSON
+	accept string
+	// wait is how long the API may hold the call before answering,
+	// added to the timeout of each attempt
+	wait time.Duration
+}
+
+// do sends a JSON request and decodes the JSON response into out. Network
+// errors, 429 and 5xx responses are retried with exponential backoff, or
+// after the delay a Retry-After header asks for when that is longer.
+func (c *Client) do(ctx context.Context, r request) error {
+	return c.transport.Do(ctx, c.request(ctx, r, c.opts.Timeout+r.wait), r.out)
+}
+
+// send performs a request with retries until it gets a 2xx response, and
+// returns it with its body open. timeout bounds each attempt including the
+// read of its body; zero leaves attempts to the context.
+func (c *Client) send(ctx context.Context, r request, timeout time.Duration) (*transport.Response, error) {
+	return c.transport.Send(ctx, c.request(ctx, r, timeout))
+}
+
+// request returns the transport request of a call
+func (c *Client) request(ctx context.Context, r request, timeout time.Duration) transport.Request {
+	headers := c.headers(ctx, r)
+	requestID := headers.Get(HeaderRequestID)
+	return transport.Request{
+		Method:  r.method,
+		Path:    r.path,
+		Query:   r.query,
+		Body:    r.body,
+		Header:  headers,
+		Timeout: timeout,
+		Error: func(status int, message string, retryAfter time.Duration) error {
+			return &APIError{StatusCode: status, Message: message, RequestID: requestID, RetryAfter: retryAfter}
+		},
+	}
+}
+
+// headers returns the headers of every attempt of a call
+func (c *Client) headers(ctx context.Context, r request) http.Header {
+	h := make(http.Header)
+	accept := r.accept
+	if accept == "" {
+		accept = "application/json"
+	}
+	h.Set("Accept", accept)
+	h.Set("User-Agent", c.opts.UserAgent)
+	if c.opts.APIKey != "" {
+		h.Set(HeaderAPIKey, c.opts.APIKey)
+	}
+
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	h.Set(HeaderRequestID, requestID)
+
+	if identity, ok := ctx.Value(identityKey).(Identity); ok {
+		if identity.UserID != "" {
+			h.Set(HeaderUserID, identity.UserID)
+		}
+		if len(identity.Groups) > 0 {
+			h.Set(HeaderUserGroups, strings.Join(identity.Groups, ","))
+		}
+		if identity.Tenant != "" {
+			h.Set(HeaderTenant, identity.Tenant)
+		}
+	}
+
+	if r.method != http.MethodGet {
+		key, _ := ctx.Value(idempotencyKey).(string)
+		if key == "" {
+			key = uuid.New().String()
+		}
+		h.Set(HeaderIdempotencyKey, key)
+	}
+	return h
+}
+
+// waitQuery returns the query of a status call waiting up to wait, capped
+// at MaxWait
+func waitQuery(wait time.Duration) (url.Values, time.Duration) {
+	if wait <= 0 {
+		return nil, 0
+	}
+	if wait > MaxWait {
+		wait = MaxWait
+	}
+	return url.Values{"wait": {wait.String()}}, wait
+}
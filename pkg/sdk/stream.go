@@ -0,0 +1,173 @@
+package sdk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/nadeeshame/rag-knowledge-service/internal/transport"
+)
+
+// Stream event types, in the order they are sent
+const (
+	EventSources = "sources" // the sources the answer is given, sent once
+	EventDelta   = "delta"   // a fragment of the answer
+	EventDone    = "done"    // the end of the answer, with its trace
+	EventError   = "error"   // the answer failed; it ends the stream
+)
+
+// StreamEvent is one server-sent event of a streamed answer
+type StreamEvent struct {
+	Type    string         `json:"-"`
+	QueryID string         `json:"query_id,omitempty"` // sources and done events
+	Sources []SearchResult `json:"sources,omitempty"`  // sources event
+	Content string         `json:"content,omitempty"`  // delta event
+	// Trace and Faithfulness are set on the done event
+	Trace        *QueryTrace `json:"trace,omitempty"`
+	Faithfulness *float64    `json:"faithfulness,omitempty"`
+	// Error and Moderation are set on the error event; Moderation is the
+	// verdict of a query or answer blocked by moderation
+	Error      string             `json:"error,omitempty"`
+	Moderation *ModerationVerdict `json:"moderation,omitempty"`
+}
+
+// StreamError is the error event ending a stream
+type StreamError struct {
+	Message    string
+	Moderation *ModerationVerdict // set when the answer was blocked
+}
+
+func (e *StreamError) Error() string {
+	return "answer failed: " + e.Message
+}
+
+// Stream is an answer being streamed. Read it with Next until it returns
+// false, then check Err:
+//
+//	stream, err := client.Stream(ctx, sdk.QueryRequest{Text: question})
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		fmt.Print(stream.Event().Content)
+//	}
+//	return stream.Err()
+type Stream struct {
+	resp   *transport.Response
+	reader *bufio.Reader
+	event  StreamEvent
+	result QueryResult
+	done   bool
+	err    error
+}
+
+// Stream asks a question and streams the answer as it is generated.
+// Answers in agent mode or as json_schema cannot be streamed. The stream
+// is bounded by ctx rather than the client's timeout; connecting is
+// retried like other calls.
+func (c *Client) Stream(ctx context.Context, req QueryRequest) (*Stream, error) {
+	resp, err := c.send(ctx, request{
+		method: http.MethodPost, path: "/v1/query/stream", body: req, accept: "text/event-stream",
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{resp: resp, reader: bufio.NewReader(resp.Body)}, nil
+}
+
+// Next reads the next event, and reports false at the end of the stream
+// or on an error
+func (s *Stream) Next() bool {
+	if s.done || s.err != nil {
+		return false
+	}
+	name, data, err := s.read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		s.err = fmt.Errorf("failed to read stream: %w", err)
+		return false
+	}
+
+	event := StreamEvent{}
+	if err := json.Unmarshal(data, &event); err != nil {
+		s.err = fmt.Errorf("failed to decode %s event: %w", name, err)
+		return false
+	}
+	event.Type = name
+	s.event = event
+
+	switch name {
+	case EventSources:
+		s.result.QueryID = event.QueryID
+		s.result.Sources = event.Sources
+	case EventDelta:
+		s.result.Answer += event.Content
+	case EventDone:
+		s.result.Trace = event.Trace
+		s.result.Faithfulness = event.Faithfulness
+		s.done = true
+	case EventError:
+		s.err = &StreamError{Message: event.Error, Moderation: event.Moderation}
+		return false
+	}
+	return true
+}
+
+// Event returns the event read by the last call of Next
+func (s *Stream) Event() StreamEvent {
+	return s.event
+}
+
+// Err returns the error that ended the stream, a *StreamError when the
+// answer failed, or nil when it was streamed to the end
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Result returns the answer streamed so far with its sources, and its
+// trace once the stream is done
+func (s *Stream) Result() *QueryResult {
+	result := s.result
+	return &result
+}
+
+// Close ends the stream
+func (s *Stream) Close() error {
+	return s.resp.Close()
+}
+
+// read returns the name and data of the next event. Comment lines are
+// skipped, and the data lines of an event are joined.
+func (s *Stream) read() (string, []byte, error) {
+	var name string
+	var data []string
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			return "", nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if len(data) > 0 {
+				return name, []byte(strings.Join(data, "\n")), nil
+			}
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
@@ -1,35 +1,23 @@
 package utils
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"fmt"
-	"io"
 	"mime"
 	"os"
-	"path/filepath"
 	"strings"
 )
 
 // ComputeFileHash computes SHA256 hash of a file
 func ComputeFileHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+	hashes, err := HashFile(filePath, HashSHA256)
 	if err != nil {
-		return "", fmt.Errorf("failed to open file: %w", err)
+		return "", err
 	}
-	defer file.Close()
-
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", fmt.Errorf("failed to compute hash: %w", err)
-	}
-
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	return hashes[0], nil
 }
 
 // GetMimeType returns the MIME type of a file
 func GetMimeType(filePath string) string {
-	ext := filepath.Ext(filePath)
+	ext := Ext(filePath)
 	mimeType := mime.TypeByExtension(ext)
 	if mimeType == "" {
 		return "application/octet-stream"
@@ -37,9 +25,10 @@ func GetMimeType(filePath string) string {
 	return mimeType
 }
 
-// GetFileExtension returns the file extension without the dot
+// GetFileExtension returns the file extension without the dot.
+// Both POSIX and Windows paths are accepted.
 func GetFileExtension(filename string) string {
-	ext := filepath.Ext(filename)
+	ext := Ext(filename)
 	if ext != "" {
 		return strings.ToLower(strings.TrimPrefix(ext, "."))
 	}
@@ -72,7 +61,7 @@ func IsDiagramFile(filename string) bool {
 
 // IsDocumentFile checks if a file is a document based on extension
 func IsDocumentFile(filename string) bool {
-	docExtensions := []string{"docx", "pdf", "pptx", "odt", "txt", "md"}
+	docExtensions := []string{"docx", "doc", "pdf", "pptx", "ppt", "odt", "odp", "rtf", "txt", "md"}
 	ext := GetFileExtension(filename)
 	for _, docExt := range docExtensions {
 		if ext == docExt {
@@ -84,7 +73,7 @@ func IsDocumentFile(filename string) bool {
 
 // IsSpreadsheetFile checks if a file is a spreadsheet based on extension
 func IsSpreadsheetFile(filename string) bool {
-	sheetExtensions := []string{"xlsx", "xls", "csv"}
+	sheetExtensions := []string{"xlsx", "xls", "ods", "csv"}
 	ext := GetFileExtension(filename)
 	for _, sheetExt := range sheetExtensions {
 		if ext == sheetExt {
@@ -112,7 +101,7 @@ func IsCodeFile(filename string) bool {
 
 // IsStructuredFile checks if a file is a structured data file based on extension
 func IsStructuredFile(filename string) bool {
-	structuredExtensions := []string{"json", "yaml", "yml", "xml", "toml", "graphql"}
+	structuredExtensions := []string{"json", "yaml", "yml", "xml", "toml", "graphql", "proto", "tf", "tfvars"}
 	ext := GetFileExtension(filename)
 	for _, structExt := range structuredExtensions {
 		if ext == structExt {
@@ -122,6 +111,19 @@ func IsStructuredFile(filename string) bool {
 	return false
 }
 
+// IsDataFile checks if a file is a data file, a database or columnar
+// dataset, based on extension
+func IsDataFile(filename string) bool {
+	dataExtensions := []string{"parquet", "sqlite", "sqlite3", "db", "db3"}
+	ext := GetFileExtension(filename)
+	for _, dataExt := range dataExtensions {
+		if ext == dataExt {
+			return true
+		}
+	}
+	return false
+}
+
 // GetFileCategory returns the category of a file based on its extension
 func GetFileCategory(filename string) string {
 	switch {
@@ -137,6 +139,8 @@ func GetFileCategory(filename string) string {
 		return "code"
 	case IsStructuredFile(filename):
 		return "structured"
+	case IsDataFile(filename):
+		return "data"
 	default:
 		return "unknown"
 	}
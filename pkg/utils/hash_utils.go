@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math/bits"
+	"os"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"lukechampine.com/blake3"
+)
+
+// Algorithms of file hashes. SHA-256 is the default; BLAKE3 and XXH64 hash
+// huge files several times faster, and XXH64 is not cryptographic.
+const (
+	HashSHA256 = "sha256"
+	HashBLAKE3 = "blake3"
+	HashXXH64  = "xxh64"
+)
+
+// Bounds of the average size of content-defined chunks
+const (
+	MinChunkHashSize = 256
+	MaxChunkHashSize = 64 * 1024 * 1024
+)
+
+// NewHash returns a hash of the named algorithm; empty is SHA-256
+func NewHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case HashSHA256, "":
+		return sha256.New(), nil
+	case HashBLAKE3:
+		return blake3.New(32, nil), nil
+	case HashXXH64:
+		return xxhash.New(), nil
+	}
+	return nil, fmt.Errorf("unknown hash algorithm: %s", algorithm)
+}
+
+// HashFile computes the hashes of a file with each of the named
+// algorithms in one read, as hex strings in the order of the algorithms
+func HashFile(filePath string, algorithms ...string) ([]string, error) {
+	hashes := make([]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, len(algorithms))
+	for i, algorithm := range algorithms {
+		h, err := NewHash(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i], writers[i] = h, h
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(io.MultiWriter(writers...), file); err != nil {
+		return nil, fmt.Errorf("failed to compute hash: %w", err)
+	}
+	sums := make([]string, len(hashes))
+	for i, h := range hashes {
+		sums[i] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums, nil
+}
+
+// HashAlgorithm returns the algorithm of a hash written as
+// "algorithm:hex". Hashes without a prefix are SHA-256, the only
+// algorithm before others could be chosen.
+func HashAlgorithm(hash string) string {
+	if algorithm, _, ok := strings.Cut(hash, ":"); ok {
+		return algorithm
+	}
+	return HashSHA256
+}
+
+// ContentChunk is a chunk of a file cut where its content, rather than its
+// offset, says so: an insertion or deletion changes the chunks around it
+// only, so the other chunks keep their hashes and still match those of
+// the file before the edit
+type ContentChunk struct {
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"`
+	Hash   string `json:"hash"`
+}
+
+// gearTable holds the random values of the gear rolling hash that finds
+// chunk boundaries. It is generated from a fixed seed, since chunk hashes
+// only match between runs that cut chunks the same way.
+var gearTable = func() [256]uint64 {
+	var table [256]uint64
+	state := uint64(0x5245504f47524150) // splitmix64
+	for i := range table {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		table[i] = z ^ z>>31
+	}
+	return table
+}()
+
+// HashFileChunks splits a file into content-defined chunks of about
+// avgSize bytes, rounded to a power of two, and hashes each with the named
+// algorithm. Chunks are at least a quarter and at most four times avgSize,
+// except for the last.
+func HashFileChunks(filePath, algorithm string, avgSize int) ([]ContentChunk, error) {
+	if avgSize < MinChunkHashSize || avgSize > MaxChunkHashSize {
+		return nil, fmt.Errorf("chunk size must be between %d and %d bytes", MinChunkHashSize, MaxChunkHashSize)
+	}
+	h, err := NewHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	shift := bits.Len(uint(avgSize)) - 1
+	// The top bits of the gear hash depend on the last 64 bytes read
+	mask := (uint64(1)<<shift - 1) << (64 - shift)
+	minSize, maxSize := int64(1)<<shift/4, int64(1)<<shift*4
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	var chunks []ContentChunk
+	var offset, length int64
+	var fp uint64
+	cut := func() {
+		chunks = append(chunks, ContentChunk{Offset: offset, Length: length, Hash: hex.EncodeToString(h.Sum(nil))})
+		offset += length
+		length, fp = 0, 0
+		h.Reset()
+	}
+
+	buf := make([]byte, 256*1024)
+	for {
+		n, readErr := file.Read(buf)
+		for data := buf[:n]; len(data) > 0; {
+			i, boundary := 0, false
+			for i < len(data) && !boundary {
+				fp = fp<<1 + gearTable[data[i]]
+				i++
+				length++
+				boundary = length >= maxSize || (length >= minSize && fp&mask == 0)
+			}
+			h.Write(data[:i])
+			data = data[i:]
+			if boundary {
+				cut()
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to compute hash: %w", readErr)
+		}
+	}
+	if length > 0 {
+		cut()
+	}
+	return chunks, nil
+}
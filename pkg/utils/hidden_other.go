@@ -0,0 +1,13 @@
+//go:build !windows
+
+package utils
+
+import (
+	"os"
+	"strings"
+)
+
+// IsHidden reports whether a file is hidden, i.e. its name starts with a dot
+func IsHidden(info os.FileInfo) bool {
+	return strings.HasPrefix(info.Name(), ".")
+}
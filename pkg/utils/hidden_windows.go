@@ -0,0 +1,21 @@
+//go:build windows
+
+package utils
+
+import (
+	"os"
+	"strings"
+	"syscall"
+)
+
+// IsHidden reports whether a file is hidden: its name starts with a dot
+// or it carries the Windows hidden attribute
+func IsHidden(info os.FileInfo) bool {
+	if strings.HasPrefix(info.Name(), ".") {
+		return true
+	}
+	if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return data.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Windows long-path prefixes. Paths with these prefixes bypass the
+// MAX_PATH limit; they are stripped when paths are normalized.
+const (
+	longPathPrefix = `\\?\`
+	longUNCPrefix  = `\\?\UNC\`
+)
+
+// IsUNCPath reports whether p is a Windows UNC path (\\server\share\...),
+// including the long form \\?\UNC\server\share\...
+func IsUNCPath(p string) bool {
+	if strings.HasPrefix(p, longUNCPrefix) {
+		return true
+	}
+	return len(p) > 2 && p[0] == '\\' && p[1] == '\\' && p[2] != '\\' && p[2] != '?'
+}
+
+// HasDriveLetter reports whether p starts with a Windows drive letter, as in C:\docs
+func HasDriveLetter(p string) bool {
+	p = strings.TrimPrefix(p, longPathPrefix)
+	return len(p) >= 2 && p[1] == ':' && isASCIILetter(p[0])
+}
+
+// IsWindowsPath reports whether p is a Windows drive, UNC or long path.
+// Such paths are recognized on every platform, so services running on
+// Linux can accept paths sent by Windows clients.
+func IsWindowsPath(p string) bool {
+	return strings.HasPrefix(p, longPathPrefix) || HasDriveLetter(p) || IsUNCPath(p)
+}
+
+// NormalizePath returns the canonical form of a path used for registry keys,
+// metadata and comparisons: long-path prefixes are removed, Windows
+// separators become forward slashes, drive letters are upper-cased and
+// the path is cleaned. UNC paths keep their leading "//".
+func NormalizePath(p string) string {
+	if p == "" {
+		return ""
+	}
+	if !IsWindowsPath(p) {
+		if runtime.GOOS == "windows" {
+			return filepath.ToSlash(filepath.Clean(p))
+		}
+		return path.Clean(p)
+	}
+
+	unc := IsUNCPath(p)
+	switch {
+	case strings.HasPrefix(p, longUNCPrefix):
+		p = strings.TrimPrefix(p, longUNCPrefix)
+	case strings.HasPrefix(p, longPathPrefix):
+		p = strings.TrimPrefix(p, longPathPrefix)
+	}
+	p = strings.ReplaceAll(p, `\`, "/")
+
+	if unc {
+		return "//" + strings.TrimPrefix(path.Clean("/"+strings.TrimLeft(p, "/")), "/")
+	}
+
+	drive := strings.ToUpper(p[:1]) + ":"
+	rest := p[2:]
+	if rest == "" {
+		return drive
+	}
+	cleaned := path.Clean(rest)
+	if cleaned == "." {
+		return drive
+	}
+	return drive + cleaned
+}
+
+// LocalPath converts a normalized path back to the form the local
+// operating system expects
+func LocalPath(p string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.FromSlash(p)
+	}
+	return p
+}
+
+// BaseName returns the last element of a path, treating both / and \ as
+// separators so Windows paths work on every platform
+func BaseName(p string) string {
+	p = strings.TrimRight(p, `/\`)
+	if i := strings.LastIndexAny(p, `/\`); i >= 0 {
+		p = p[i+1:]
+	}
+	if HasDriveLetter(p) && len(p) == 2 {
+		return ""
+	}
+	return p
+}
+
+// Ext returns the extension of the last path element, including the dot
+func Ext(p string) string {
+	return path.Ext(BaseName(p))
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}